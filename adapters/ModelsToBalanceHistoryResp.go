@@ -0,0 +1,26 @@
+package adapters
+
+import (
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// ToBalanceHistoryResp converts the daily closing balances of a wallet into the balance history response.
+func ToBalanceHistoryResp(wallet models.Wallet, from, to time.Time, balances []models.DailyBalance) models.BalanceHistoryResponse {
+	items := make([]models.DailyBalanceResponse, 0, len(balances))
+	for _, b := range balances {
+		items = append(items, models.DailyBalanceResponse{
+			Date:    b.Date.Format(time.DateOnly),
+			Balance: models.MoneyDecimal{Decimal: b.Balance},
+		})
+	}
+
+	return models.BalanceHistoryResponse{
+		WalletID: wallet.ID,
+		Currency: wallet.Currency,
+		From:     from.Format(time.DateOnly),
+		To:       to.Format(time.DateOnly),
+		Balances: items,
+	}
+}
@@ -0,0 +1,68 @@
+package adapters
+
+import (
+	"errors"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
+)
+
+// ToBatchTransferResp reports the outcome of each item of a batch paid from sourceWallet, in request order.
+// The balance after the batch accounts for the fees of the transfers.
+func ToBatchTransferResp(mode string, sourceWallet models.Wallet, items []models.BatchTransferItem) models.BatchTransferResponse {
+	resp := models.BatchTransferResponse{
+		Mode:           mode,
+		SourceWalletID: sourceWallet.ID,
+		Currency:       sourceWallet.Currency,
+		Items:          make([]models.BatchTransferItemResponse, 0, len(items)),
+	}
+
+	total := decimal.Zero
+	totalFees := decimal.Zero
+	for i, item := range items {
+		itemResp := models.BatchTransferItemResponse{
+			Index:  i,
+			Amount: models.MoneyDecimal{Decimal: item.Transfer.SourceAmount},
+		}
+
+		if item.Transfer.TargetWalletId != 0 {
+			targetWalletId := item.Transfer.TargetWalletId
+			itemResp.TargetWalletID = &targetWalletId
+			itemResp.TargetCurrency = item.Transfer.TargetCurrency
+		}
+
+		if item.Succeeded() {
+			transferId := item.Transfer.ID
+			rate := item.Transfer.Rate
+			itemResp.Status = models.BatchItemStatusSucceeded
+			itemResp.TransferID = &transferId
+			itemResp.TargetAmount = &models.MoneyDecimal{Decimal: item.Transfer.TargetAmount}
+			itemResp.Rate = &rate
+			itemResp.Fee = ToFeeResp(item.TransferOut.Fee, sourceWallet.Currency)
+
+			total = total.Add(item.Transfer.SourceAmount)
+			resp.BalanceAfter = &models.MoneyDecimal{Decimal: item.TransferOut.BalanceAfter}
+			if fee := item.TransferOut.Fee; fee != nil {
+				totalFees = totalFees.Add(fee.Amount)
+				resp.BalanceAfter = &models.MoneyDecimal{Decimal: fee.Debit.BalanceAfter}
+			}
+			resp.Succeeded++
+		} else {
+			itemResp.Status = models.BatchItemStatusFailed
+			itemResp.Error = toItemError(item.Err)
+			resp.Failed++
+		}
+		resp.Items = append(resp.Items, itemResp)
+	}
+	resp.TotalAmount = models.MoneyDecimal{Decimal: total}
+	resp.TotalFees = models.MoneyDecimal{Decimal: totalFees}
+	return resp
+}
+
+func toItemError(err error) *models.ErrorResponse {
+	var appErr *models.AppError
+	if !errors.As(err, &appErr) {
+		return &models.ErrorResponse{Code: models.ErrCodeInternal, Message: "internal server error"}
+	}
+	return &models.ErrorResponse{Code: appErr.Code, Message: appErr.Message, Details: appErr.Details}
+}
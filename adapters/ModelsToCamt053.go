@@ -0,0 +1,133 @@
+package adapters
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// camt053BankTxIssuer issues the proprietary bank transaction codes, the transaction types.
+const camt053BankTxIssuer = "CRYPTOWALLET"
+
+// ToCamt053Document converts the statement of a wallet, from its period up to until, into a camt.053
+// statement identified by id. Amounts are written unsigned with a credit or debit indicator.
+func ToCamt053Document(s models.Statement, until time.Time, id string) models.Camt053Document {
+	createdAt := toIsoDateTime(s.GeneratedAt)
+	account := models.IsoAccount{Id: walletAccountId(s.WalletID), Ccy: s.Currency}
+
+	stmt := models.Camt053Statement{
+		Id:      id,
+		CreDtTm: createdAt,
+		FrToDt:  models.Camt053Period{FrDtTm: toIsoDateTime(s.Period), ToDtTm: toIsoDateTime(until)},
+		Acct:    account,
+		Bal: []models.Camt053Balance{
+			toCamt053Balance("OPBD", s.OpeningBalance, s.Currency, s.Period),
+			toCamt053Balance("CLBD", s.ClosingBalance, s.Currency, until),
+		},
+		Ntry: make([]models.Camt053Entry, 0, len(s.Entries)),
+	}
+
+	credits, debits := 0, 0
+	for _, e := range s.Entries {
+		if e.Amount.IsNegative() {
+			debits++
+		} else {
+			credits++
+		}
+		stmt.Ntry = append(stmt.Ntry, toCamt053Entry(e, s.Currency))
+	}
+	stmt.TxsSummry = models.Camt053TxsSummary{
+		TtlNtries:    models.Camt053NumberAndSum{NbOfNtries: strconv.Itoa(len(s.Entries))},
+		TtlCdtNtries: models.Camt053NumberAndSum{NbOfNtries: strconv.Itoa(credits), Sum: s.TotalCredits.StringFixed(models.AmountScale)},
+		TtlDbtNtries: models.Camt053NumberAndSum{NbOfNtries: strconv.Itoa(debits), Sum: s.TotalDebits.StringFixed(models.AmountScale)},
+	}
+
+	return models.Camt053Document{
+		BkToCstmrStmt: models.Camt053BankToCustomerStmt{
+			GrpHdr: models.Camt053GroupHeader{MsgId: id, CreDtTm: createdAt},
+			Stmt:   stmt,
+		},
+	}
+}
+
+func toCamt053Balance(code string, balance decimal.Decimal, ccy string, at time.Time) models.Camt053Balance {
+	b := models.Camt053Balance{
+		Amt:       models.IsoAmount{Ccy: ccy, Value: balance.Abs().StringFixed(models.AmountScale)},
+		CdtDbtInd: creditDebitIndicator(balance),
+		Dt:        models.IsoDateTime{DtTm: toIsoDateTime(at)},
+	}
+	b.Tp.CdOrPrtry.Cd = code
+	return b
+}
+
+// toCamt053Entry converts a statement entry, referenced by its transaction. The counterparty wallet is
+// the debtor of a credit and the creditor of a debit.
+func toCamt053Entry(e models.StatementEntry, ccy string) models.Camt053Entry {
+	ref := strconv.FormatInt(e.TransactionID, 10)
+	amount := models.IsoAmount{Ccy: ccy, Value: e.Amount.Abs().StringFixed(models.AmountScale)}
+	indicator := creditDebitIndicator(e.Amount)
+	at := toIsoDateTime(e.At)
+
+	entry := models.Camt053Entry{
+		NtryRef:     ref,
+		Amt:         amount,
+		CdtDbtInd:   indicator,
+		RvslInd:     e.Reversal,
+		Sts:         models.Camt053EntryStatus{Cd: "BOOK"},
+		BookgDt:     models.IsoDateTime{DtTm: at},
+		ValDt:       models.IsoDateTime{DtTm: at},
+		AcctSvcrRef: ref,
+	}
+	entry.BkTxCd.Prtry.Cd = e.Type
+	entry.BkTxCd.Prtry.Issr = camt053BankTxIssuer
+
+	details := models.Camt053TxDetails{
+		Refs:      models.Camt053Refs{AcctSvcrRef: ref, EndToEndId: e.ExternalReference},
+		Amt:       amount,
+		CdtDbtInd: indicator,
+	}
+	if e.FX != nil {
+		fx := &models.Camt053AmountDetails{}
+		fx.CntrValAmt.Amt = models.IsoAmount{Ccy: e.FX.CounterCcy, Value: e.FX.CounterAmount.StringFixed(models.AmountScale)}
+		fx.CntrValAmt.CcyXchg.SrcCcy, fx.CntrValAmt.CcyXchg.TrgtCcy = ccy, e.FX.CounterCcy
+		if e.Amount.IsNegative() == e.Reversal {
+			// a credit, or the reversal of one, is the target of the exchange
+			fx.CntrValAmt.CcyXchg.SrcCcy, fx.CntrValAmt.CcyXchg.TrgtCcy = e.FX.CounterCcy, ccy
+		}
+		fx.CntrValAmt.CcyXchg.XchgRate = e.FX.Rate.String()
+		details.AmtDtls = fx
+	}
+	if e.CounterpartyWalletID != nil {
+		counterparty := &models.IsoAccount{Id: walletAccountId(*e.CounterpartyWalletID)}
+		if e.Amount.IsNegative() {
+			details.RltdPties = &models.Camt053RelatedParties{CdtrAcct: counterparty}
+		} else {
+			details.RltdPties = &models.Camt053RelatedParties{DbtrAcct: counterparty}
+		}
+	}
+	if e.Description != "" {
+		details.RmtInf = &models.Camt053Remittance{Ustrd: e.Description}
+	}
+	entry.NtryDtls.TxDtls = details
+	return entry
+}
+
+// walletAccountId identifies a wallet by its ID as other identification.
+func walletAccountId(walletId int64) models.IsoAccountId {
+	return models.IsoAccountId{Othr: &models.IsoOtherId{Id: strconv.FormatInt(walletId, 10)}}
+}
+
+func creditDebitIndicator(amount decimal.Decimal) string {
+	if amount.IsNegative() {
+		return "DBIT"
+	}
+	return "CRDT"
+}
+
+// toIsoDateTime formats t as an ISO 20022 date and time, in UTC.
+func toIsoDateTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,31 @@
+package adapters
+
+import (
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// ToCcyRateResp converts a stored rate into its API representation, flagged stale against limits.
+func ToCcyRateResp(conversion models.CcyConversion, limits models.RateAgeLimits) models.CcyRateResponse {
+	var maxAge string
+	if age := limits.MaxAge(conversion.FromCcy, conversion.ToCcy); age > 0 {
+		maxAge = age.String()
+	}
+
+	return models.CcyRateResponse{
+		FromCcy:   conversion.FromCcy,
+		ToCcy:     conversion.ToCcy,
+		Rate:      conversion.Rate,
+		UpdatedAt: conversion.CreatedAt,
+		MaxAge:    maxAge,
+		Stale:     conversion.IsStale(limits),
+	}
+}
+
+// ToCcyRatesResp converts the stored rates into their API representation.
+func ToCcyRatesResp(conversions []models.CcyConversion, limits models.RateAgeLimits) models.CcyRatesResponse {
+	rates := make([]models.CcyRateResponse, 0, len(conversions))
+	for _, c := range conversions {
+		rates = append(rates, ToCcyRateResp(c, limits))
+	}
+	return models.CcyRatesResponse{Rates: rates}
+}
@@ -0,0 +1,33 @@
+package adapters
+
+import (
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// ToConversionDetail describes a conversion as shown in the transaction history.
+func ToConversionDetail(c models.Conversion) *models.ConversionDetail {
+	return &models.ConversionDetail{
+		ID:           c.ID,
+		FromWalletID: c.FromWalletId,
+		FromCurrency: c.FromCurrency,
+		FromAmount:   models.MoneyDecimal{Decimal: c.FromAmount},
+		ToWalletID:   c.ToWalletId,
+		ToCurrency:   c.ToCurrency,
+		ToAmount:     models.MoneyDecimal{Decimal: c.ToAmount},
+		MidRate:      c.MidRate,
+		Rate:         c.Rate,
+		Spread:       c.Spread,
+		SpreadAmount: models.MoneyDecimal{Decimal: c.SpreadAmount},
+	}
+}
+
+// ToConversionResp converts a conversion and both of its transactions into the API representation.
+func ToConversionResp(c models.Conversion, outTxn models.Transaction, inTxn models.Transaction) models.ConversionResponse {
+	return models.ConversionResponse{
+		ConversionDetail: *ToConversionDetail(c),
+		UserID:           c.UserId,
+		CreatedAt:        c.CreatedAt,
+		ConversionOut:    ToTransactionResp(outTxn, c.FromCurrency, &outTxn.BalanceAfter),
+		ConversionIn:     ToTransactionResp(inTxn, c.ToCurrency, &inTxn.BalanceAfter),
+	}
+}
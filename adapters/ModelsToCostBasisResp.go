@@ -0,0 +1,145 @@
+package adapters
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
+)
+
+// ToWalletCostBasisResp converts the cost basis of a wallet into its API representation. What is still
+// held is valued at the rates of current, without a market value when there is no rate.
+func ToWalletCostBasisResp(cb models.CostBasis, current []models.CcyConversion) models.WalletCostBasisResponse {
+	quantity, cost := cb.Holding()
+
+	lots := make([]models.LotResponse, 0, len(cb.Lots))
+	for _, lot := range cb.Lots {
+		lots = append(lots, models.LotResponse{
+			TransactionID: lot.TransactionID,
+			AcquiredAt:    lot.AcquiredAt,
+			Quantity:      lot.Quantity,
+			Remaining:     lot.Remaining,
+			CostBasis:     models.MoneyDecimal{Decimal: lot.Cost},
+			Unpriced:      lot.Unpriced,
+		})
+	}
+
+	realized := decimal.Zero
+	for _, d := range cb.Disposals {
+		realized = realized.Add(d.Gain)
+	}
+
+	var marketValue, unrealized *models.MoneyDecimal
+	if path := models.ResolveCcyRate(current, cb.Currency, cb.ReportingCcy); path != nil {
+		value := quantity.Mul(path.Rate).Round(models.AmountScale)
+		marketValue = &models.MoneyDecimal{Decimal: value}
+		unrealized = &models.MoneyDecimal{Decimal: value.Sub(cost)}
+	}
+
+	return models.WalletCostBasisResponse{
+		WalletID:             cb.WalletID,
+		Currency:             cb.Currency,
+		ReportingCurrency:    cb.ReportingCcy,
+		Method:               cb.Method,
+		Quantity:             quantity,
+		CostBasis:            models.MoneyDecimal{Decimal: cost},
+		MarketValue:          marketValue,
+		UnrealizedGain:       unrealized,
+		RealizedGain:         models.MoneyDecimal{Decimal: realized},
+		Lots:                 lots,
+		UnpricedTransactions: cb.UnpricedTxns,
+	}
+}
+
+// ToGainsReportResp converts the disposals of the wallets made in year into the gains report of the user,
+// oldest first.
+func ToGainsReportResp(user *models.User, year int, reportingCcy string, method string, costBases []models.CostBasis) models.GainsReportResponse {
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	until := from.AddDate(1, 0, 0)
+
+	resp := models.GainsReportResponse{
+		UserInfo: models.UserInfo{
+			ID:   user.ID,
+			Name: user.Name,
+		},
+		Year:              year,
+		ReportingCurrency: reportingCcy,
+		Method:            method,
+		Disposals:         make([]models.DisposalResponse, 0),
+	}
+
+	proceeds, cost, gain := decimal.Zero, decimal.Zero, decimal.Zero
+	for _, cb := range costBases {
+		for _, d := range cb.Disposals {
+			if d.DisposedAt.Before(from) || !d.DisposedAt.Before(until) {
+				continue
+			}
+
+			var acquiredAt *time.Time
+			if !d.AcquiredAt.IsZero() {
+				acquiredAt = &d.AcquiredAt
+			}
+
+			resp.Disposals = append(resp.Disposals, models.DisposalResponse{
+				WalletID:      cb.WalletID,
+				Currency:      cb.Currency,
+				TransactionID: d.TransactionID,
+				Type:          d.Type,
+				AcquiredAt:    acquiredAt,
+				DisposedAt:    d.DisposedAt,
+				Quantity:      d.Quantity,
+				Proceeds:      models.MoneyDecimal{Decimal: d.Proceeds},
+				CostBasis:     models.MoneyDecimal{Decimal: d.CostBasis},
+				Gain:          models.MoneyDecimal{Decimal: d.Gain},
+				Unpriced:      d.Unpriced,
+			})
+			if d.Unpriced {
+				resp.UnpricedTransactions = append(resp.UnpricedTransactions, d.TransactionID)
+			}
+			proceeds = proceeds.Add(d.Proceeds)
+			cost = cost.Add(d.CostBasis)
+			gain = gain.Add(d.Gain)
+		}
+	}
+
+	sort.SliceStable(resp.Disposals, func(i, j int) bool {
+		return resp.Disposals[i].DisposedAt.Before(resp.Disposals[j].DisposedAt)
+	})
+
+	resp.TotalProceeds = models.MoneyDecimal{Decimal: proceeds}
+	resp.TotalCostBasis = models.MoneyDecimal{Decimal: cost}
+	resp.TotalGain = models.MoneyDecimal{Decimal: gain}
+	return resp
+}
+
+// ToGainsReportCSV converts a gains report into CSV records, a header followed by one record per disposal.
+// Amounts are in the reporting currency of the report.
+func ToGainsReportCSV(report models.GainsReportResponse) [][]string {
+	records := [][]string{{
+		"wallet_id", "currency", "transaction_id", "type", "acquired_at", "disposed_at",
+		"quantity", "proceeds", "cost_basis", "gain", "reporting_currency",
+	}}
+
+	for _, d := range report.Disposals {
+		acquiredAt := ""
+		if d.AcquiredAt != nil {
+			acquiredAt = d.AcquiredAt.Format(time.RFC3339)
+		}
+		records = append(records, []string{
+			strconv.FormatInt(d.WalletID, 10),
+			d.Currency,
+			strconv.FormatInt(d.TransactionID, 10),
+			d.Type,
+			acquiredAt,
+			d.DisposedAt.Format(time.RFC3339),
+			d.Quantity.String(),
+			d.Proceeds.StringFixed(models.AmountScale),
+			d.CostBasis.StringFixed(models.AmountScale),
+			d.Gain.StringFixed(models.AmountScale),
+			report.ReportingCurrency,
+		})
+	}
+	return records
+}
@@ -0,0 +1,34 @@
+package adapters
+
+import (
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// ToFeeResp converts the fee of a transaction in currency into its API representation, or nil when there is no fee.
+func ToFeeResp(fee *models.Fee, currency string) *models.FeeResponse {
+	if fee == nil {
+		return nil
+	}
+
+	resp := &models.FeeResponse{
+		FeeRuleID:     fee.Rule.ID,
+		Status:        models.FeeStatusQuoted,
+		Currency:      currency,
+		Amount:        models.MoneyDecimal{Decimal: fee.Amount},
+		FlatFee:       models.MoneyDecimal{Decimal: fee.FlatFee},
+		PercentageFee: models.MoneyDecimal{Decimal: fee.PercentageFee},
+		Adjustment:    models.MoneyDecimal{Decimal: fee.Adjustment},
+	}
+
+	if fee.ID != 0 {
+		resp.Status = models.FeeStatusReserved
+	}
+
+	if fee.Charged() {
+		txnId := fee.Debit.ID
+		resp.Status = models.FeeStatusCharged
+		resp.TransactionID = &txnId
+		resp.Balance = &models.MoneyDecimal{Decimal: fee.Debit.BalanceAfter}
+	}
+	return resp
+}
@@ -0,0 +1,37 @@
+package adapters
+
+import (
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// ToFxQuoteResp converts an FX quote into its API representation.
+func ToFxQuoteResp(quote models.FxQuote) models.FxQuoteResponse {
+	var transferId *int64
+	if quote.TransferId.Valid {
+		transferId = &quote.TransferId.Int64
+	}
+
+	var usedAt *time.Time
+	if quote.UsedAt.Valid {
+		usedAt = &quote.UsedAt.Time
+	}
+
+	return models.FxQuoteResponse{
+		ID:         quote.ID,
+		UserID:     quote.UserId,
+		FromCcy:    quote.FromCcy,
+		ToCcy:      quote.ToCcy,
+		Rate:       quote.Rate,
+		MidRate:    quote.MidRate,
+		Side:       quote.Side,
+		Spread:     quote.Spread,
+		RatePath:   quote.RatePath,
+		Status:     quote.Status,
+		TransferID: transferId,
+		ExpiresAt:  quote.ExpiresAt,
+		CreatedAt:  quote.CreatedAt,
+		UsedAt:     usedAt,
+	}
+}
@@ -0,0 +1,39 @@
+package adapters
+
+import (
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// ToHoldResp converts a hold into its API representation.
+func ToHoldResp(hold models.Hold, currency string) models.HoldResponse {
+	var capturedAmount *models.MoneyDecimal
+	if hold.Status == models.HoldStatusCaptured {
+		capturedAmount = &models.MoneyDecimal{Decimal: hold.CapturedAmount}
+	}
+
+	var txnId *int64
+	if hold.TransactionId.Valid {
+		txnId = &hold.TransactionId.Int64
+	}
+
+	var expiresAt *time.Time
+	if hold.ExpiresAt.Valid {
+		expiresAt = &hold.ExpiresAt.Time
+	}
+
+	return models.HoldResponse{
+		ID:             hold.ID,
+		WalletID:       hold.WalletId,
+		Currency:       currency,
+		Amount:         models.MoneyDecimal{Decimal: hold.Amount},
+		CapturedAmount: capturedAmount,
+		Status:         hold.Status,
+		Description:    hold.Description.String,
+		TransactionID:  txnId,
+		ExpiresAt:      expiresAt,
+		CreatedAt:      hold.CreatedAt,
+		UpdatedAt:      hold.UpdatedAt,
+	}
+}
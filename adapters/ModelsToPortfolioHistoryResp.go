@@ -0,0 +1,44 @@
+package adapters
+
+import (
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// ToPortfolioHistoryResp converts the daily values of the portfolio of a user into the portfolio history response.
+func ToPortfolioHistoryResp(user *models.User, reportingCcy string, from, to time.Time, values []models.PortfolioValue) models.PortfolioHistoryResponse {
+	items := make([]models.PortfolioValueResponse, 0, len(values))
+	for _, v := range values {
+		allocations := make([]models.CcyAllocationResponse, 0, len(v.Allocations))
+		for _, a := range v.Allocations {
+			allocations = append(allocations, models.CcyAllocationResponse{
+				Currency:   a.Currency,
+				Balance:    models.MoneyDecimal{Decimal: a.Balance},
+				Value:      models.MoneyDecimal{Decimal: a.Value},
+				Percentage: models.MoneyDecimal{Decimal: a.Percentage},
+			})
+		}
+
+		items = append(items, models.PortfolioValueResponse{
+			Date:               v.Date.Format(time.DateOnly),
+			Total:              models.MoneyDecimal{Decimal: v.Total},
+			Partial:            len(v.UnpricedCurrencies) > 0,
+			UnpricedCurrencies: v.UnpricedCurrencies,
+			Stale:              len(v.StaleCurrencies) > 0,
+			StaleCurrencies:    v.StaleCurrencies,
+			Allocations:        allocations,
+		})
+	}
+
+	return models.PortfolioHistoryResponse{
+		UserInfo: models.UserInfo{
+			ID:   user.ID,
+			Name: user.Name,
+		},
+		Currency: reportingCcy,
+		From:     from.Format(time.DateOnly),
+		To:       to.Format(time.DateOnly),
+		Values:   items,
+	}
+}
@@ -0,0 +1,75 @@
+package adapters
+
+import (
+	"database/sql"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+func ToScheduledTransferResp(s models.ScheduledTransfer, currency string) models.ScheduledTransferResponse {
+	return models.ScheduledTransferResponse{
+		ID:                  s.ID,
+		SourceWalletID:      s.SourceWalletId,
+		DestinationWalletID: nullInt64(s.DestinationWalletId),
+		DestinationUserID:   nullInt64(s.DestinationUserId),
+		Currency:            currency,
+		Amount:              models.MoneyDecimal{Decimal: s.Amount},
+		Description:         s.Description.String,
+		ExternalReference:   s.ExternalReference.String,
+		Frequency:           s.Frequency,
+		StartAt:             s.StartAt,
+		EndAt:               nullTime(s.EndAt),
+		MaxOccurrences:      nullInt64(s.MaxOccurrences),
+		Occurrences:         s.Occurrences,
+		NextRunAt:           nullTime(s.NextRunAt),
+		RetryAt:             nullTime(s.RetryAt),
+		OnInsufficientFunds: s.OnInsufficientFunds,
+		Status:              s.Status,
+		CreatedAt:           s.CreatedAt,
+		UpdatedAt:           s.UpdatedAt,
+	}
+}
+
+// ToUserScheduledTransfersResp lists the scheduled transfers of a user, with the currency of each source wallet.
+func ToUserScheduledTransfersResp(user *models.User, schedules []models.ScheduledTransfer, currencies map[int64]string) models.UserScheduledTransfersResponse {
+	items := make([]models.ScheduledTransferResponse, 0, len(schedules))
+	for _, s := range schedules {
+		items = append(items, ToScheduledTransferResp(s, currencies[s.SourceWalletId]))
+	}
+
+	return models.UserScheduledTransfersResponse{
+		UserInfo: models.UserInfo{
+			ID:   user.ID,
+			Name: user.Name,
+		},
+		ScheduledTransfers: items,
+	}
+}
+
+func ToScheduledTransferRunsResp(scheduleId int64, runs []models.ScheduledTransferRun) models.ScheduledTransferRunsResponse {
+	items := make([]models.ScheduledTransferRunResponse, 0, len(runs))
+	for _, run := range runs {
+		items = append(items, models.ScheduledTransferRunResponse{
+			ID:           run.ID,
+			ScheduledFor: run.ScheduledFor,
+			Attempt:      run.Attempt,
+			Status:       run.Status,
+			TransferID:   nullInt64(run.TransferId),
+			ErrorCode:    run.ErrorCode.String,
+			ErrorMessage: run.ErrorMessage.String,
+			CreatedAt:    run.CreatedAt,
+		})
+	}
+
+	return models.ScheduledTransferRunsResponse{
+		ScheduledTransferID: scheduleId,
+		Runs:                items,
+	}
+}
+
+func nullInt64(v sql.NullInt64) *int64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Int64
+}
@@ -0,0 +1,18 @@
+package adapters
+
+import (
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// ToSpreadRevenueResp converts the spread revenue totals into their API representation.
+func ToSpreadRevenueResp(totals []models.SpreadRevenueTotal) models.SpreadRevenueResponse {
+	items := make([]models.SpreadRevenueTotalResponse, 0, len(totals))
+	for _, t := range totals {
+		items = append(items, models.SpreadRevenueTotalResponse{
+			Currency: t.Currency,
+			Count:    t.Count,
+			Amount:   models.MoneyDecimal{Decimal: t.Amount},
+		})
+	}
+	return models.SpreadRevenueResponse{Totals: items}
+}
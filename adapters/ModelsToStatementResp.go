@@ -0,0 +1,49 @@
+package adapters
+
+import (
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// ToStatementResp converts a statement into its API representation.
+func ToStatementResp(s models.Statement) models.StatementResponse {
+	entries := make([]models.StatementEntryResponse, 0, len(s.Entries))
+	for _, e := range s.Entries {
+		entry := models.StatementEntryResponse{
+			TransactionID:        e.TransactionID,
+			Type:                 e.Type,
+			At:                   e.At,
+			Description:          e.Description,
+			ExternalReference:    e.ExternalReference,
+			Amount:               models.MoneyDecimal{Decimal: e.Amount},
+			Balance:              models.MoneyDecimal{Decimal: e.Balance},
+			Reversal:             e.Reversal,
+			CounterpartyWalletID: e.CounterpartyWalletID,
+			FeeFor:               e.FeeFor,
+		}
+		if e.FX != nil {
+			entry.FX = &models.StatementFXResponse{
+				Rate:            e.FX.Rate,
+				CounterCurrency: e.FX.CounterCcy,
+				CounterAmount:   models.MoneyDecimal{Decimal: e.FX.CounterAmount},
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	return models.StatementResponse{
+		WalletID:       s.WalletID,
+		Currency:       s.Currency,
+		Period:         s.Period.Format(models.StatementPeriodLayout),
+		From:           s.Period.Format(time.DateOnly),
+		To:             s.Period.AddDate(0, 1, -1).Format(time.DateOnly),
+		OpeningBalance: models.MoneyDecimal{Decimal: s.OpeningBalance},
+		ClosingBalance: models.MoneyDecimal{Decimal: s.ClosingBalance},
+		TotalCredits:   models.MoneyDecimal{Decimal: s.TotalCredits},
+		TotalDebits:    models.MoneyDecimal{Decimal: s.TotalDebits},
+		TotalFees:      models.MoneyDecimal{Decimal: s.TotalFees},
+		Entries:        entries,
+		GeneratedAt:    s.GeneratedAt,
+	}
+}
@@ -0,0 +1,136 @@
+package adapters
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// TransactionCSVHeader is the header of the CSV export of a transaction history.
+var TransactionCSVHeader = []string{
+	"created_at", "completed_at", "wallet_id", "currency", "transaction_id", "type", "status", "amount",
+	"description", "external_reference", "counterparty_wallet_id", "transfer_id", "rate", "tags",
+}
+
+// ofxNameMaxLen is the longest NAME an OFX statement transaction may have.
+const ofxNameMaxLen = 32
+
+// ofxTrnTypes maps the transaction types to OFX transaction types; the others are credits or debits.
+var ofxTrnTypes = map[string]string{
+	models.TxnTypeDeposit:       "DEP",
+	models.TxnTypeTransferIn:    "XFER",
+	models.TxnTypeTransferOut:   "XFER",
+	models.TxnTypeConversionIn:  "XFER",
+	models.TxnTypeConversionOut: "XFER",
+	models.TxnTypeFee:           "FEE",
+}
+
+// csvFormulaPrefixes are the first characters that make a spreadsheet evaluate a cell as a formula.
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// csvText neutralises a user-provided value for spreadsheets: a value that would run as a formula
+// is prefixed with a quote, so it is shown as text.
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune(csvFormulaPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// ToTransactionCSVRecord converts a transaction of wallet into a record of the CSV export, in the order of
+// TransactionCSVHeader. The amount is signed, negative for a debit, and tags are separated by semicolons.
+// Description, external reference and tags are entered by users, so they are never read as a formula.
+func ToTransactionCSVRecord(wallet models.Wallet, t models.Transaction) []string {
+	completedAt := ""
+	if t.CompletedAt.Valid {
+		completedAt = t.CompletedAt.Time.UTC().Format(time.RFC3339)
+	}
+	counterpartyWalletId := ""
+	if t.CounterpartyWalletId.Valid {
+		counterpartyWalletId = strconv.FormatInt(t.CounterpartyWalletId.Int64, 10)
+	}
+	transferId := ""
+	if t.TransferId.Valid {
+		transferId = strconv.FormatInt(t.TransferId.Int64, 10)
+	}
+	rate := ""
+	if t.Rate.Valid {
+		rate = t.Rate.Decimal.String()
+	}
+
+	return []string{
+		t.CreatedAt.UTC().Format(time.RFC3339),
+		completedAt,
+		strconv.FormatInt(wallet.ID, 10),
+		wallet.Currency,
+		strconv.FormatInt(t.ID, 10),
+		t.Type,
+		t.Status,
+		t.SignedAmount().StringFixed(models.AmountScale),
+		csvText(t.Description.String),
+		csvText(t.ExternalReference.String),
+		counterpartyWalletId,
+		transferId,
+		rate,
+		csvText(strings.Join(t.Tags, ";")),
+	}
+}
+
+// ToOfxTransactions converts a transaction into OFX statement transactions: the transaction, posted when it
+// completed, and its reversal when it was reversed before until. Pending and failed transactions never
+// changed the balance and have none.
+func ToOfxTransactions(t models.Transaction, until time.Time) []models.OfxTransaction {
+	if t.Status != models.TxnStatusCompleted && t.Status != models.TxnStatusReversed {
+		return nil
+	}
+
+	amount := t.SignedAmount()
+	trnType, ok := ofxTrnTypes[t.Type]
+	if !ok {
+		trnType = "CREDIT"
+		if amount.IsNegative() {
+			trnType = "DEBIT"
+		}
+	}
+
+	name := t.Type
+	if t.Description.Valid && t.Description.String != "" {
+		name = t.Description.String
+	}
+	if runes := []rune(name); len(runes) > ofxNameMaxLen {
+		name = string(runes[:ofxNameMaxLen])
+	}
+
+	postedAt := t.CreatedAt
+	if t.CompletedAt.Valid {
+		postedAt = t.CompletedAt.Time
+	}
+
+	txns := []models.OfxTransaction{{
+		TrnType:  trnType,
+		DtPosted: ToOfxDateTime(postedAt),
+		TrnAmt:   amount.StringFixed(models.AmountScale),
+		FitID:    strconv.FormatInt(t.ID, 10),
+		Name:     name,
+		Memo:     t.ExternalReference.String,
+	}}
+
+	if t.Status == models.TxnStatusReversed && t.ReversedAt.Valid && t.ReversedAt.Time.Before(until) {
+		txns = append(txns, models.OfxTransaction{
+			TrnType:  trnType,
+			DtPosted: ToOfxDateTime(t.ReversedAt.Time),
+			TrnAmt:   amount.Neg().StringFixed(models.AmountScale),
+			FitID:    strconv.FormatInt(t.ID, 10) + "-R",
+			Name:     name,
+			Memo:     "reversal of transaction " + strconv.FormatInt(t.ID, 10),
+		})
+	}
+	return txns
+}
+
+// ToOfxDateTime formats t as an OFX date and time, in UTC.
+func ToOfxDateTime(t time.Time) string {
+	return t.UTC().Format("20060102150405.000") + "[0:GMT]"
+}
@@ -0,0 +1,62 @@
+package adapters
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
+)
+
+// ToTransactionResp converts a transaction into its API representation.
+// balance is the wallet balance after the transaction and is omitted when nil.
+func ToTransactionResp(txn models.Transaction, currency string, balance *decimal.Decimal) models.TransactionResponse {
+	var counterId *int64
+	if txn.CounterpartyWalletId.Valid {
+		counterId = &txn.CounterpartyWalletId.Int64
+	}
+
+	var transferId *int64
+	if txn.TransferId.Valid {
+		transferId = &txn.TransferId.Int64
+	}
+
+	var balanceResp *models.MoneyDecimal
+	if balance != nil {
+		balanceResp = &models.MoneyDecimal{Decimal: *balance}
+	}
+
+	var rate *decimal.Decimal
+	if txn.Rate.Valid {
+		rate = &txn.Rate.Decimal
+	}
+
+	return models.TransactionResponse{
+		ID:                   txn.ID,
+		WalletID:             txn.WalletId,
+		Type:                 txn.Type,
+		Currency:             currency,
+		Amount:               models.MoneyDecimal{Decimal: txn.Amount},
+		Status:               txn.Status,
+		CounterpartyWalletID: counterId,
+		Balance:              balanceResp,
+		CreatedAt:            txn.CreatedAt,
+		CompletedAt:          nullTime(txn.CompletedAt),
+		FailedAt:             nullTime(txn.FailedAt),
+		ReversedAt:           nullTime(txn.ReversedAt),
+		Description:          txn.Description.String,
+		ExternalReference:    txn.ExternalReference.String,
+		Tags:                 txn.Tags,
+		TransferID:           transferId,
+		Fee:                  ToFeeResp(txn.Fee, currency),
+		EffectiveRate:        rate,
+	}
+}
+
+// nullTime returns a pointer to the time, or nil when it is not set.
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,80 @@
+package adapters
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// ToTransferDetail builds the counterparty enrichment of a transfer transaction as seen by
+// the owner of the transaction's wallet. The counterparty's user ID is only revealed when
+// both sides belong to the same user; otherwise the name is shown according to namePolicy.
+func ToTransferDetail(txn models.Transaction, cp models.TransferCounterparty, ownerUserId int64, namePolicy string) *models.TransferDetail {
+	counterparty := &models.Counterparty{
+		WalletID: cp.WalletID,
+		Currency: cp.Currency,
+	}
+
+	if cp.UserID == ownerUserId {
+		counterparty.UserID = &cp.UserID
+		counterparty.Name = cp.UserName
+	} else {
+		counterparty.Name = applyNamePolicy(cp.UserName, namePolicy)
+	}
+
+	detail := &models.TransferDetail{
+		Counterparty:   counterparty,
+		TransferStatus: cp.TransferStatus.String,
+	}
+
+	if cp.ReversalOf.Valid {
+		detail.ReversalOf = &cp.ReversalOf.Int64
+	}
+
+	if cp.LinkedTransactionID.Valid {
+		detail.LinkedTransactionID = &cp.LinkedTransactionID.Int64
+	}
+
+	if cp.LinkedAmount.Valid {
+		counterparty.Amount = &models.MoneyDecimal{Decimal: cp.LinkedAmount.Decimal}
+
+		// The rate is always expressed as target currency per unit of source currency,
+		// the rate given to the customer is kept on transfers made since it is recorded
+		if txn.Rate.Valid {
+			rate := txn.Rate.Decimal.Round(6)
+			detail.Rate = &rate
+		} else if !txn.Amount.IsZero() && !cp.LinkedAmount.Decimal.IsZero() {
+			rate := cp.LinkedAmount.Decimal.Div(txn.Amount)
+			if txn.Type == models.TxnTypeTransferIn {
+				rate = txn.Amount.Div(cp.LinkedAmount.Decimal)
+			}
+			rate = rate.Round(6)
+			detail.Rate = &rate
+		}
+	}
+
+	return detail
+}
+
+// applyNamePolicy returns the counterparty name to show to another user.
+func applyNamePolicy(name string, namePolicy string) string {
+	switch namePolicy {
+	case models.CounterpartyNameFull:
+		return name
+	case models.CounterpartyNameHidden:
+		return ""
+	default:
+		return maskName(name)
+	}
+}
+
+// maskName keeps the first letter of each word of the name, e.g. "Bob Lee" becomes "B** L**".
+func maskName(name string) string {
+	words := strings.Fields(name)
+	for i, word := range words {
+		first, size := utf8.DecodeRuneInString(word)
+		words[i] = string(first) + strings.Repeat("*", utf8.RuneCountInString(word[size:]))
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,45 @@
+package adapters
+
+import (
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
+)
+
+// ToTransferPreviewResp shows how transfer would be made from sourceWallet without making it.
+// target is nil when the target wallet could not be resolved, and transfer is nil when its rate
+// could not be; errs are the reasons the transfer would be rejected.
+func ToTransferPreviewResp(sourceWallet models.Wallet, amount decimal.Decimal, available decimal.Decimal, target *models.Wallet,
+	transfer *models.Transfer, fee *models.Fee, errs []error) models.TransferPreviewResponse {
+	resp := models.TransferPreviewResponse{
+		Valid:            len(errs) == 0,
+		SourceWalletID:   sourceWallet.ID,
+		Currency:         sourceWallet.Currency,
+		Amount:           models.MoneyDecimal{Decimal: amount},
+		AvailableBalance: models.MoneyDecimal{Decimal: available},
+	}
+
+	if target != nil {
+		resp.TargetWalletID = &target.ID
+		resp.TargetUserID = &target.UserId
+		resp.TargetCurrency = target.Currency
+	}
+
+	if transfer != nil {
+		rate := transfer.Rate
+		resp.Rate = &rate
+		resp.RatePath = transfer.RatePath
+		resp.TargetAmount = &models.MoneyDecimal{Decimal: transfer.TargetAmount}
+		resp.Fee = ToFeeResp(fee, sourceWallet.Currency)
+
+		total := amount
+		if fee != nil {
+			total = total.Add(fee.Amount)
+		}
+		resp.TotalDebit = &models.MoneyDecimal{Decimal: total}
+	}
+
+	for _, err := range errs {
+		resp.Errors = append(resp.Errors, *toItemError(err))
+	}
+	return resp
+}
@@ -0,0 +1,64 @@
+package adapters
+
+import (
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// ToTransferSummary converts a transfer into its API representation.
+func ToTransferSummary(t models.Transfer) models.TransferSummary {
+	var sourceTxnId, targetTxnId *int64
+	if t.SourceTransactionId.Valid {
+		sourceTxnId = &t.SourceTransactionId.Int64
+	}
+	if t.TargetTransactionId.Valid {
+		targetTxnId = &t.TargetTransactionId.Int64
+	}
+
+	var reversalOf *int64
+	if t.ReversalOf.Valid {
+		reversalOf = &t.ReversalOf.Int64
+	}
+
+	var reversedAmount *models.MoneyDecimal
+	if !t.ReversedAmount.IsZero() {
+		reversedAmount = &models.MoneyDecimal{Decimal: t.ReversedAmount}
+	}
+
+	return models.TransferSummary{
+		ID:       t.ID,
+		Status:   t.Status,
+		Rate:     t.Rate,
+		RatePath: t.RatePath,
+		Source: models.TransferSide{
+			WalletID:      t.SourceWalletId,
+			Currency:      t.SourceCurrency,
+			Amount:        models.MoneyDecimal{Decimal: t.SourceAmount},
+			TransactionID: sourceTxnId,
+		},
+		Target: models.TransferSide{
+			WalletID:      t.TargetWalletId,
+			Currency:      t.TargetCurrency,
+			Amount:        models.MoneyDecimal{Decimal: t.TargetAmount},
+			TransactionID: targetTxnId,
+		},
+		ReversalOf:     reversalOf,
+		ReversedAmount: reversedAmount,
+		CreatedAt:      t.CreatedAt,
+	}
+}
+
+// ToUserTransfersResp converts a user's transfers into the response of the transfer list.
+func ToUserTransfersResp(user *models.User, transfers []models.Transfer) models.UserTransfersResponse {
+	summaries := make([]models.TransferSummary, 0, len(transfers))
+	for _, t := range transfers {
+		summaries = append(summaries, ToTransferSummary(t))
+	}
+
+	return models.UserTransfersResponse{
+		UserInfo: models.UserInfo{
+			ID:   user.ID,
+			Name: user.Name,
+		},
+		Transfers: summaries,
+	}
+}
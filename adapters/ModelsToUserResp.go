@@ -0,0 +1,15 @@
+package adapters
+
+import (
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// ToUserResp converts a user into its API representation.
+func ToUserResp(user models.User) models.UserResponse {
+	return models.UserResponse{
+		ID:                user.ID,
+		Name:              user.Name,
+		ReportingCurrency: user.ReportingCurrency,
+		CreatedAt:         user.CreatedAt,
+	}
+}
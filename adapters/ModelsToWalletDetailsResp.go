@@ -1,19 +1,42 @@
 package adapters
 
 import (
+	"slices"
+
 	"github.com/rudithu/CRYPTO-WalletApp/models"
 	"github.com/shopspring/decimal"
 )
 
-func ToWalletDetailsResp(user *models.User, wallets []models.Wallet, txns []models.Transaction, ccyMap map[string]models.CcyRateToBaseCcy) models.WalletBalanceResponse {
+// ToWalletDetailsResp converts the wallets of the user and their transactions into the history response.
+// Both transactions of a conversion are shown as a single conversion item, under the from wallet
+// unless only the to wallet is listed. Wallet balances are valued in reportingCcy at the rates of
+// ccyMap; wallets without a rate are left out of the total, which is then flagged as partial.
+func ToWalletDetailsResp(user *models.User, wallets []models.Wallet, txns []models.Transaction, ccyMap map[string]models.CcyRateToReportingCcy,
+	reportingCcy string, counterparties map[int64]models.TransferCounterparty, conversions map[int64]models.Conversion, namePolicy string) models.WalletBalanceResponse {
 	var walletDetails []models.WalletDetail
 	totalBalance := decimal.NewFromInt(0)
 
 	grouped := make(map[int64][]models.TransactionSummaryItem)
-	missingRate := false
+	var staleCcys, unpricedCcys []string
+	var unpricedWallets []int64
+
+	listed := make(map[int64]bool, len(txns))
+	for _, tx := range txns {
+		listed[tx.ID] = true
+	}
 
 	for _, tx := range txns {
 
+		txnType := tx.Type
+		var conversion *models.ConversionDetail
+		if c, ok := conversions[tx.ID]; ok {
+			if tx.ID == c.InTransactionId && listed[c.OutTransactionId] {
+				continue
+			}
+			txnType = models.TxnTypeConversion
+			conversion = ToConversionDetail(c)
+		}
+
 		var counterId *int64
 		if tx.CounterpartyWalletId.Valid {
 			counterId = &tx.CounterpartyWalletId.Int64
@@ -21,53 +44,92 @@ func ToWalletDetailsResp(user *models.User, wallets []models.Wallet, txns []mode
 			counterId = nil
 		}
 
+		var transferId *int64
+		if tx.TransferId.Valid {
+			transferId = &tx.TransferId.Int64
+		}
+
+		var detail *models.TransferDetail
+		if cp, ok := counterparties[tx.ID]; ok {
+			detail = ToTransferDetail(tx, cp, user.ID, namePolicy)
+		}
+
 		grouped[tx.WalletId] = append(grouped[tx.WalletId], models.TransactionSummaryItem{
 			ID:                   tx.ID,
-			Type:                 tx.Type,
-			Amount:               models.MoneyDecimal{tx.Amount},
+			Type:                 txnType,
+			Amount:               models.MoneyDecimal{Decimal: tx.Amount},
+			Status:               tx.Status,
 			Time:                 tx.CreatedAt,
 			CounterpartyWalletID: counterId,
+			Description:          tx.Description.String,
+			ExternalReference:    tx.ExternalReference.String,
+			Tags:                 tx.Tags,
+			TransferID:           transferId,
+			TransferDetail:       detail,
+			Conversion:           conversion,
 		})
 	}
 
 	for _, w := range wallets {
 
-		walletDetails = append(walletDetails, models.WalletDetail{
-			ID:           w.ID,
-			IsDefault:    w.IsDefault,
-			Currency:     w.Currency,
-			Type:         w.Type,
-			Balance:      models.MoneyDecimal{w.Balance},
-			Transactions: grouped[w.ID],
-		})
+		var available, pendingIn, pendingOut *models.MoneyDecimal
+		if w.AvailableBalance.Valid {
+			available = &models.MoneyDecimal{Decimal: w.AvailableBalance.Decimal}
+		}
+		if w.PendingIncoming.Valid {
+			pendingIn = &models.MoneyDecimal{Decimal: w.PendingIncoming.Decimal}
+		}
+		if w.PendingOutgoing.Valid {
+			pendingOut = &models.MoneyDecimal{Decimal: w.PendingOutgoing.Decimal}
+		}
 
-		var currentAmount decimal.Decimal
-		if w.Currency != models.BaseCcy {
+		var reportingBalance *models.MoneyDecimal
+		if w.Currency != reportingCcy {
 			val, ok := ccyMap[w.Currency]
 			if ok {
-				currentAmount = w.Balance.Div(val.Rate)
+				reportingBalance = &models.MoneyDecimal{Decimal: w.Balance.Mul(val.Rate)}
+				if val.Stale {
+					staleCcys = append(staleCcys, w.Currency)
+				}
 			} else {
-				missingRate = true
+				unpricedWallets = append(unpricedWallets, w.ID)
+				if !slices.Contains(unpricedCcys, w.Currency) {
+					unpricedCcys = append(unpricedCcys, w.Currency)
+				}
 			}
 		} else {
-			currentAmount = w.Balance
+			reportingBalance = &models.MoneyDecimal{Decimal: w.Balance}
+		}
+		if reportingBalance != nil {
+			totalBalance = totalBalance.Add(reportingBalance.Decimal)
 		}
-		totalBalance = totalBalance.Add(currentAmount)
+
+		walletDetails = append(walletDetails, models.WalletDetail{
+			ID:               w.ID,
+			IsDefault:        w.IsDefault,
+			Currency:         w.Currency,
+			Type:             w.Type,
+			Balance:          models.MoneyDecimal{Decimal: w.Balance},
+			Available:        available,
+			PendingIn:        pendingIn,
+			PendingOut:       pendingOut,
+			ReportingBalance: reportingBalance,
+			Transactions:     grouped[w.ID],
+		})
 	}
 
 	if walletDetails == nil {
 		walletDetails = make([]models.WalletDetail, 0)
 	}
 
-	var total *models.Total
-	if missingRate {
-		total = nil
-	} else {
-		total = &models.Total{
-			Currency: models.BaseCcy,
-			Amount:   models.MoneyDecimal{totalBalance},
-		}
-
+	total := &models.Total{
+		Currency:           reportingCcy,
+		Amount:             models.MoneyDecimal{Decimal: totalBalance},
+		Stale:              len(staleCcys) > 0,
+		StaleCurrencies:    staleCcys,
+		Partial:            len(unpricedWallets) > 0,
+		UnpricedCurrencies: unpricedCcys,
+		UnpricedWallets:    unpricedWallets,
 	}
 	return models.WalletBalanceResponse{
 		UserInfo: models.UserInfo{
@@ -21,6 +21,23 @@ const (
 	DB_PORT  = "database.port"
 	DB_NAME  = "database.name"
 	APP_PORT = "app.port"
+
+	PRIVACY_COUNTERPARTY_NAME = "privacy.counterparty_name"
+	TRANSFER_REVERSAL_RATE    = "transfers.reversal_rate_policy"
+	HOLD_DEFAULT_EXPIRY       = "holds.default_expiry_seconds"
+	HOLD_EXPIRY_INTERVAL      = "holds.expiry_interval"
+	SCHEDULER_INTERVAL        = "scheduler.interval"
+	SCHEDULER_RETRY_INTERVAL  = "scheduler.retry_interval"
+	SCHEDULER_MAX_RETRIES     = "scheduler.max_retries"
+	SCHEDULER_ON_INSUFFICIENT = "scheduler.on_insufficient_funds"
+	BALANCE_SNAPSHOT_INTERVAL = "balances.snapshot_interval"
+	STATEMENT_INTERVAL        = "statements.interval"
+	FEE_HOUSE_USER_ID         = "fees.house_user_id"
+	FX_QUOTE_TTL              = "fx.quote_ttl_seconds"
+	FX_MAX_RATE_AGE           = "fx.max_rate_age"
+	FX_MAX_RATE_AGE_BY_CCY    = "fx.max_rate_age_by_ccy"
+	COST_BASIS_METHOD         = "costbasis.method"
+	COST_BASIS_CRYPTO_CCYS    = "costbasis.crypto_currencies"
 )
 
 func GetConfig() (map[string]string, error) {
@@ -43,3 +60,16 @@ func GetConfig() (map[string]string, error) {
 	})
 	return configMap, configErr
 }
+
+// GetOrDefault returns the configured value for key, or def when the key is not set
+// or the configuration cannot be loaded.
+func GetOrDefault(key string, def string) string {
+	conf, err := GetConfig()
+	if err != nil {
+		return def
+	}
+	if val, ok := conf[key]; ok && val != "" {
+		return val
+	}
+	return def
+}
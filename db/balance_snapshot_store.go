@@ -0,0 +1,109 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
+)
+
+// balanceEventsQuery lists the balance events of every wallet. A completed or reversed transaction changed
+// the balance when it completed, a credit adding its amount and a debit taking it; a reversed one changed
+// it back when it was reversed, flagged as a reversal. Pending and failed transactions never touched the balance.
+const balanceEventsQuery = `
+	SELECT id, wallet_id, COALESCE(completed_at, created_at) AS at,
+		CASE WHEN type IN ('deposit', 'transfer-in', 'conversion-in', 'fee-income') THEN amount ELSE -amount END AS delta,
+		FALSE AS reversal
+	FROM transactions
+	WHERE status IN ('completed', 'reversed')
+	UNION ALL
+	SELECT id, wallet_id, reversed_at,
+		CASE WHEN type IN ('deposit', 'transfer-in', 'conversion-in', 'fee-income') THEN -amount ELSE amount END,
+		TRUE
+	FROM transactions
+	WHERE status = 'reversed'`
+
+// balanceAtQuery selects the balance of the wallets, aliased w, at the time in $1: their latest snapshot
+// no later than that time plus the balance events from the snapshot up to, and excluding, that time.
+const balanceAtQuery = `
+	SELECT w.id AS wallet_id, COALESCE(s.balance, 0) + COALESCE((
+		SELECT SUM(e.delta)
+		FROM (` + balanceEventsQuery + `) e
+		WHERE e.wallet_id = w.id AND e.at >= COALESCE(s.snapshot_at, '-infinity') AND e.at < $1
+	), 0) AS balance
+	FROM wallets w
+	LEFT JOIN LATERAL (
+		SELECT snapshot_at, balance
+		FROM wallet_balance_snapshots
+		WHERE wallet_id = w.id AND snapshot_at <= $1
+		ORDER BY snapshot_at DESC
+		LIMIT 1
+	) s ON TRUE`
+
+// GetWalletBalanceAt returns the ledger balance of the wallet at the given time, made of the transactions
+// completed or reversed before it, or nil when the wallet does not exist.
+func GetWalletBalanceAt(db *sql.DB, walletId int64, at time.Time) (*decimal.Decimal, error) {
+	query := balanceAtQuery + `
+	WHERE w.id = $2`
+
+	var id int64
+	var balance decimal.Decimal
+	err := db.QueryRow(query, at, walletId).Scan(&id, &balance)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &balance, nil
+}
+
+// GetBalanceEvents returns the balance events of the wallet from from up to, and excluding, until, oldest first.
+func GetBalanceEvents(db *sql.DB, walletId int64, from time.Time, until time.Time) ([]models.BalanceEvent, error) {
+	query := `
+		SELECT e.at, e.delta
+		FROM (` + balanceEventsQuery + `) e
+		WHERE e.wallet_id = $1 AND e.at >= $2 AND e.at < $3
+		ORDER BY e.at
+	`
+
+	rows, err := db.Query(query, walletId, from, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.BalanceEvent
+	for rows.Next() {
+		var e models.BalanceEvent
+		if err := rows.Scan(&e.At, &e.Delta); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// CreateBalanceSnapshots records the balance of every wallet at the given time, skipping the wallets
+// already snapshotted at that time, and returns the number of snapshots recorded.
+// Later balances are computed from the snapshots, so at must be far enough in the past that no
+// transaction completing before it is still to be committed.
+func CreateBalanceSnapshots(db *sql.DB, at time.Time) (int64, error) {
+	query := `
+		INSERT INTO wallet_balance_snapshots (wallet_id, snapshot_at, balance)
+		SELECT b.wallet_id, $1::TIMESTAMP, b.balance
+		FROM (` + balanceAtQuery + `) b
+		ON CONFLICT (wallet_id, snapshot_at) DO NOTHING
+	`
+	result, err := db.Exec(query, at)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
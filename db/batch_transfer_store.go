@@ -0,0 +1,272 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
+)
+
+// BatchTransferUpdate applies the transfers of a batch paid from one source wallet within a DB transaction,
+// inserting the transfers, their transactions and their fees in bulk. Items with Err set are left out.
+// The available balance of the source wallet is checked once: with allOrNothing it must cover
+// the total of the batch including fees, otherwise the items are transferred in order while it
+// covers them and their fees and the others fail with INSUFFICIENT_FUNDS.
+// The items are updated with the created records and the resulting balances.
+// A batch from a payment file, with msgId set, records the file as processed for the source wallet along
+// with its transfers, and fails with DUPLICATE_PAYMENT_FILE when the file already was.
+func BatchTransferUpdate(db *sql.DB, sourceWalletId int64, items []models.BatchTransferItem, allOrNothing bool, msgId string) error {
+	return withTx(db, func(tx *sql.Tx) error {
+		balance, err := getWalletBalance(tx, sourceWalletId)
+		if err != nil {
+			log.Printf("ERROR: failed to get balance for wallet Id: %d", sourceWalletId)
+			return fmt.Errorf("failed to get balance: %w", err)
+		}
+
+		if balance == nil {
+			log.Printf("ERROR: wallet Id: %d not found", sourceWalletId)
+			return models.Errorf(models.ErrCodeWalletNotFound, "wallet %d not found", sourceWalletId)
+		}
+
+		// The balance is locked, so the same file uploaded twice at once is recorded by one batch only
+		if msgId != "" {
+			if err = recordPaymentFile(tx, sourceWalletId, msgId); err != nil {
+				log.Printf("ERROR: payment file %s not recorded for wallet Id: %d", msgId, sourceWalletId)
+				return err
+			}
+		}
+
+		var executed []*models.BatchTransferItem
+		total := decimal.Zero
+		for i := range items {
+			item := &items[i]
+			if item.Err != nil {
+				continue
+			}
+
+			amount := item.TransferOut.Amount.Add(feeAmount(&item.TransferOut))
+			if !allOrNothing && balance.AvailableBalance.Sub(total).LessThan(amount) {
+				item.Err = models.Errorf(models.ErrCodeInsufficientFunds, "wallet %d does not have enough balance left for this item", sourceWalletId).
+					WithDetails(map[string]string{
+						"available_balance": balance.AvailableBalance.Sub(total).String(),
+						"requested":         amount.String(),
+					})
+				continue
+			}
+			executed = append(executed, item)
+			total = total.Add(amount)
+		}
+
+		if balance.AvailableBalance.LessThan(total) {
+			log.Printf("ERROR: wallet Id: %d does not have enough balance for the batch", sourceWalletId)
+			return models.Errorf(models.ErrCodeInsufficientFunds, "wallet %d does not have enough balance for the batch", sourceWalletId).
+				WithDetails(map[string]string{
+					"balance":           balance.Balance.String(),
+					"available_balance": balance.AvailableBalance.String(),
+					"requested":         total.String(),
+				})
+		}
+
+		if len(executed) == 0 {
+			return nil
+		}
+
+		err = createTransfers(tx, executed)
+		if err != nil {
+			log.Printf("ERROR: failed to create batch transfers from wallet Id: %d", sourceWalletId)
+			return fmt.Errorf("failed to create transfers: %w", err)
+		}
+
+		legs := make([]*models.Transaction, 0, 2*len(executed))
+		var charged, exchanged []*models.Transaction
+		for _, item := range executed {
+			transferId := sql.NullInt64{Int64: item.Transfer.ID, Valid: true}
+			item.TransferOut.TransferId = transferId
+			item.TransferIn.TransferId = transferId
+			legs = append(legs, &item.TransferOut)
+			if item.TransferOut.Fee != nil {
+				charged = append(charged, &item.TransferOut)
+			}
+			if item.TransferOut.SpreadRevenue != nil {
+				exchanged = append(exchanged, &item.TransferOut)
+			}
+		}
+		for _, item := range executed {
+			legs = append(legs, &item.TransferIn)
+		}
+
+		err = createTransactions(tx, legs)
+		if err != nil {
+			log.Printf("ERROR: failed to create batch transactions from wallet Id: %d", sourceWalletId)
+			return fmt.Errorf("failed to create transactions: %w", err)
+		}
+
+		if len(exchanged) > 0 {
+			err = createSpreadRevenues(tx, exchanged)
+			if err != nil {
+				log.Printf("ERROR: failed to record batch spread revenue from wallet Id: %d", sourceWalletId)
+				return fmt.Errorf("failed to record spread revenue: %w", err)
+			}
+		}
+
+		// The fee transactions name the transfer-out legs they are charged for, so they are created once the legs have IDs
+		credited := make([]*models.Transaction, 0, len(executed)+len(charged))
+		for _, item := range executed {
+			credited = append(credited, &item.TransferIn)
+		}
+		if len(charged) > 0 {
+			feeLegs := make([]*models.Transaction, 0, 2*len(charged))
+			for _, txn := range charged {
+				txn.Fee.Debit, txn.Fee.Credit = feeTransactions(txn)
+				feeLegs = append(feeLegs, &txn.Fee.Debit)
+			}
+			for _, txn := range charged {
+				feeLegs = append(feeLegs, &txn.Fee.Credit)
+				credited = append(credited, &txn.Fee.Credit)
+			}
+
+			err = createTransactions(tx, feeLegs)
+			if err != nil {
+				log.Printf("ERROR: failed to create batch fee transactions from wallet Id: %d", sourceWalletId)
+				return fmt.Errorf("failed to create fee transactions: %w", err)
+			}
+
+			err = createFees(tx, charged)
+			if err != nil {
+				log.Printf("ERROR: failed to record batch fees from wallet Id: %d", sourceWalletId)
+				return fmt.Errorf("failed to record fees: %w", err)
+			}
+		}
+
+		// Debit the source wallet once, each transfer-out leg and fee records the balance left after it
+		sourceBalance := balance.Balance
+		for _, item := range executed {
+			sourceBalance = sourceBalance.Sub(item.TransferOut.Amount)
+			item.TransferOut.BalanceAfter = sourceBalance
+			if fee := item.TransferOut.Fee; fee != nil {
+				sourceBalance = sourceBalance.Sub(fee.Amount)
+				fee.Debit.BalanceAfter = sourceBalance
+			}
+		}
+
+		err = updateBalanceByWalletID(tx, sourceWalletId, sourceBalance)
+		if err != nil {
+			log.Printf("ERROR: failed to update balance on batch transfer for wallet Id: %d", sourceWalletId)
+			return fmt.Errorf("failed to update outgoing-balance: %w", err)
+		}
+
+		// Credit each target and house wallet once with the sum of its transfer-in and fee-income legs
+		credits := make(map[int64]decimal.Decimal)
+		for _, txn := range credited {
+			credits[txn.WalletId] = credits[txn.WalletId].Add(txn.Amount)
+		}
+
+		balances, err := creditBalancesByWalletIDs(tx, credits)
+		if err != nil {
+			log.Printf("ERROR: failed to update balances on batch transfer from wallet Id: %d", sourceWalletId)
+			return fmt.Errorf("failed to update incoming-balances: %w", err)
+		}
+
+		// Walk back from the resulting balances so each credited leg records the balance right after it
+		for i := len(credited) - 1; i >= 0; i-- {
+			txn := credited[i]
+			after, ok := balances[txn.WalletId]
+			if !ok {
+				log.Printf("ERROR: wallet Id: %d not found", txn.WalletId)
+				return models.Errorf(models.ErrCodeWalletNotFound, "wallet %d not found", txn.WalletId)
+			}
+			txn.BalanceAfter = after
+			balances[txn.WalletId] = after.Sub(txn.Amount)
+		}
+
+		log.Printf("batch of %d transfer(s) from [wallet Id: %d] completed", len(executed), sourceWalletId)
+		return nil
+	})
+}
+
+// createTransfers inserts the transfers of the items in a single statement.
+func createTransfers(tx *sql.Tx, items []*models.BatchTransferItem) error {
+	ids, err := reserveIDs(tx, "transfers", len(items))
+	if err != nil {
+		return err
+	}
+
+	placeholders := make([]string, len(items))
+	args := make([]interface{}, 0, 9*len(items))
+	byId := make(map[int64]*models.Transfer, len(items))
+
+	for i, item := range items {
+		t := &item.Transfer
+		t.ID = ids[i]
+		byId[t.ID] = t
+		n := 9 * i
+		placeholders[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9)
+		args = append(args, t.ID, t.SourceWalletId, t.TargetWalletId, t.SourceAmount, t.TargetAmount, t.Rate, t.RatePath, t.Status, t.ReversalOf)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO transfers (id, source_wallet_id, target_wallet_id, source_amount, target_amount, rate, rate_path, status, reversal_of)
+		VALUES %s
+		RETURNING id, created_at
+	`, strings.Join(placeholders, ", "))
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var createdAt time.Time
+		if err := rows.Scan(&id, &createdAt); err != nil {
+			return err
+		}
+		t, ok := byId[id]
+		if !ok {
+			return fmt.Errorf("transfer %d returned but not inserted", id)
+		}
+		t.CreatedAt = createdAt
+		delete(byId, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	if len(byId) != 0 {
+		return fmt.Errorf("%d transfers inserted but not returned", len(byId))
+	}
+	return nil
+}
+
+// reserveIDs takes n values from the sequence of the id column of table. A multi-row INSERT does not
+// return its rows in a documented order, so bulk inserts set the ids of the rows and match the
+// returned rows on them.
+func reserveIDs(tx *sql.Tx, table string, n int) ([]int64, error) {
+	rows, err := tx.Query(`SELECT nextval(pg_get_serial_sequence($1, 'id')) FROM generate_series(1, $2)`, table, n)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve %s ids: %w", table, err)
+	}
+	defer rows.Close()
+
+	ids := make([]int64, 0, n)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) != n {
+		return nil, fmt.Errorf("%d %s ids reserved, %d needed", len(ids), table, n)
+	}
+	return ids, nil
+}
@@ -3,94 +3,155 @@ package db
 import (
 	"database/sql"
 	"fmt"
-	"strings"
+	"log"
+	"time"
 
 	"github.com/rudithu/CRYPTO-WalletApp/models"
 	"github.com/shopspring/decimal"
 )
 
-func GetCcyRateToBaseCcy(db *sql.DB, ccys []string) ([]models.CcyRateToBaseCcy, error) {
-	placeholders := make([]string, len(ccys))
-	args := make([]interface{}, len(ccys))
-
-	for i, ccy := range ccys {
-		placeholders[i] = fmt.Sprintf("$%d", i+1)
-		args[i] = ccy
-	}
+// ccyConversionColumns are followed by the age of the rate in seconds, so that staleness is
+// decided by the database clock as for holds.
+const ccyConversionColumns = `from_ccy, to_ccy, rate, created_at, FLOOR(EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - created_at))::BIGINT`
+
+// scanCcyConversion reads a rate selected with ccyConversionColumns.
+func scanCcyConversion(row rowScanner) (models.CcyConversion, error) {
+	var c models.CcyConversion
+	var ageSeconds int64
+	err := row.Scan(&c.FromCcy, &c.ToCcy, &c.Rate, &c.CreatedAt, &ageSeconds)
+	c.Age = time.Duration(ageSeconds) * time.Second
+	return c, err
+}
 
-	query := fmt.Sprintf(`
-		SELECT to_ccy, rate
-		FROM ccy_conversion
-		WHERE from_ccy = '%s' AND to_ccy IN (%s)
-		`, models.BaseCcy, strings.Join(placeholders, ", "))
+// GetCcyConversions returns every stored conversion rate, the graph rates are resolved over.
+func GetCcyConversions(db *sql.DB) ([]models.CcyConversion, error) {
+	query := fmt.Sprintf(`SELECT %s FROM ccy_conversion ORDER BY from_ccy, to_ccy`, ccyConversionColumns)
 
-	rows, err := db.Query(query, args...)
+	rows, err := db.Query(query)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	var ccyRates []models.CcyRateToBaseCcy
+	var conversions []models.CcyConversion
 	for rows.Next() {
-		var cr models.CcyRateToBaseCcy
-		err = rows.Scan(&cr.Ccy, &cr.Rate)
+		c, err := scanCcyConversion(rows)
 		if err != nil {
 			return nil, err
 		}
-		ccyRates = append(ccyRates, cr)
+		conversions = append(conversions, c)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
+	return conversions, nil
+}
+
+// GetCcyRatesToReportingCcy returns the rate from each of ccys to reportingCcy, resolved as by GetCcyRatePath.
+// Currencies without a rate are left out, and rates resolved through a rate older than limits allow are marked stale.
+func GetCcyRatesToReportingCcy(db *sql.DB, reportingCcy string, ccys []string, limits models.RateAgeLimits) ([]models.CcyRateToReportingCcy, error) {
+	conversions, err := GetCcyConversions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var ccyRates []models.CcyRateToReportingCcy
+	for _, ccy := range ccys {
+		path := models.ResolveFreshCcyRate(conversions, ccy, reportingCcy, limits)
+		if path == nil {
+			continue
+		}
+		ccyRates = append(ccyRates, models.CcyRateToReportingCcy{Ccy: ccy, Rate: path.Rate, Stale: path.StaleHop(limits) != nil})
+	}
 	return ccyRates, nil
 }
 
-func GetCcyRate(db *sql.DB, fromCcy string, toCcy string) (decimal.Decimal, error) {
+// GetCcyRatePath resolves the rate from fromCcy to toCcy over the stored rates, used directly, inverted
+// or chained through other currencies; see models.ResolveCcyRate for how the path is chosen. Rates older
+// than limits allow are only used when there is no path without them, see models.ResolveFreshCcyRate.
+// The chosen path is logged for auditing.
+func GetCcyRatePath(db *sql.DB, fromCcy string, toCcy string, limits models.RateAgeLimits) (*models.CcyRatePath, error) {
+	conversions, err := GetCcyConversions(db)
+	if err != nil {
+		return nil, err
+	}
 
-	query := `
-		SELECT to_ccy, rate 
-		FROM ccy_conversion
-		WHERE from_ccy = $1 AND to_ccy in ($2, $3)
-	`
+	path := models.ResolveFreshCcyRate(conversions, fromCcy, toCcy, limits)
+	if path == nil {
+		return nil, models.Errorf(models.ErrCodeRateUnavailable, "missing conversion rate for %s or %s", fromCcy, toCcy)
+	}
+
+	log.Printf("rate from %s to %s resolved through %s: %s", fromCcy, toCcy, path, path.Rate)
+	return path, nil
+}
 
-	rows, err := db.Query(query, models.BaseCcy, fromCcy, toCcy)
+// GetCcyRate returns the rate from fromCcy to toCcy, resolved as by GetCcyRatePath whatever the age of the rates.
+func GetCcyRate(db *sql.DB, fromCcy string, toCcy string) (decimal.Decimal, error) {
+	path, err := GetCcyRatePath(db, fromCcy, toCcy, models.RateAgeLimits{})
 	if err != nil {
 		return decimal.Zero, err
 	}
-	defer rows.Close()
-
-	var toRate decimal.Decimal
-	var fromRate decimal.Decimal
+	return path.Rate, nil
+}
 
-	for rows.Next() {
-		var rate decimal.Decimal
-		var toCurrency string
+// UpsertCcyConversion stores the rate from conversion.FromCcy to conversion.ToCcy, replacing the rate
+// of the pair if there is one, and adds it to the rate history. The rate is dated now.
+func UpsertCcyConversion(db *sql.DB, conversion *models.CcyConversion) error {
+	query := `
+		WITH upserted AS (
+			INSERT INTO ccy_conversion (from_ccy, to_ccy, rate, created_at)
+			VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+			ON CONFLICT (from_ccy, to_ccy) DO UPDATE SET rate = EXCLUDED.rate, created_at = EXCLUDED.created_at
+			RETURNING from_ccy, to_ccy, rate, created_at
+		)
+		INSERT INTO ccy_conversion_history (from_ccy, to_ccy, rate, created_at)
+		SELECT from_ccy, to_ccy, rate, created_at FROM upserted
+		RETURNING created_at
+	`
+	err := db.QueryRow(query, conversion.FromCcy, conversion.ToCcy, conversion.Rate).Scan(&conversion.CreatedAt)
+	if err != nil {
+		log.Printf("ERROR: failed to set conversion rate from %s to %s", conversion.FromCcy, conversion.ToCcy)
+		return fmt.Errorf("failed to set conversion rate: %w", err)
+	}
 
-		err := rows.Scan(&toCurrency, &rate)
+	conversion.Age = 0
+	log.Printf("conversion rate from %s to %s set to %s", conversion.FromCcy, conversion.ToCcy, conversion.Rate)
+	return nil
+}
 
-		if err != nil {
-			return decimal.Zero, err
-		}
+// GetCcyRateHistory returns the rates needed to know the rates in effect from from up to, and excluding,
+// until: the rates set in that period and the last rate of each pair set before it, oldest first.
+func GetCcyRateHistory(db *sql.DB, from time.Time, until time.Time) (models.CcyRateHistory, error) {
+	query := `
+		SELECT h.from_ccy, h.to_ccy, h.rate, h.created_at
+		FROM ccy_conversion_history h
+		WHERE h.created_at < $2
+		AND (h.created_at >= $1 OR h.created_at = (
+			SELECT MAX(p.created_at)
+			FROM ccy_conversion_history p
+			WHERE p.from_ccy = h.from_ccy AND p.to_ccy = h.to_ccy AND p.created_at < $1
+		))
+		ORDER BY h.created_at, h.id
+	`
 
-		if toCurrency == toCcy {
-			toRate = rate
-		} else if toCurrency == fromCcy {
-			fromRate = rate
-		}
+	rows, err := db.Query(query, from, until)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	if models.BaseCcy == toCcy {
-		toRate = decimal.NewFromInt(1)
-	} else if models.BaseCcy == fromCcy {
-		fromRate = decimal.NewFromInt(1)
+	var history models.CcyRateHistory
+	for rows.Next() {
+		var c models.CcyConversion
+		if err := rows.Scan(&c.FromCcy, &c.ToCcy, &c.Rate, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, c)
 	}
 
-	if toRate.IsZero() || fromRate.IsZero() {
-		return decimal.Zero, fmt.Errorf("missing conversion rate for %s or %s", fromCcy, toCcy)
+	if err = rows.Err(); err != nil {
+		return nil, err
 	}
-
-	finalRate := toRate.Div(fromRate)
-
-	return finalRate, nil
-
+	return history, nil
 }
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// ConversionUpdate performs the conversion atomically within a DB transaction: the from amount and
+// the fee are withdrawn from the from wallet through outTxn, the to amount is deposited to the to
+// wallet through inTxn and the conversion is recorded with both transactions.
+func ConversionUpdate(db *sql.DB, conversion *models.Conversion, outTxn *models.Transaction, inTxn *models.Transaction) error {
+	return withTx(db, func(tx *sql.Tx) error {
+		err := withdrawInternal(tx, outTxn)
+		if err != nil {
+			return err
+		}
+
+		err = depositInternal(tx, inTxn)
+		if err != nil {
+			return err
+		}
+
+		err = recordSpreadRevenue(tx, outTxn)
+		if err != nil {
+			return err
+		}
+
+		conversion.OutTransactionId = outTxn.ID
+		conversion.InTransactionId = inTxn.ID
+		err = createConversion(tx, conversion)
+		if err != nil {
+			log.Printf("ERROR: failed to record conversion from wallet Id: %d to wallet Id: %d", conversion.FromWalletId, conversion.ToWalletId)
+			return fmt.Errorf("failed to record conversion: %w", err)
+		}
+
+		log.Printf("conversion Id: %d from [wallet Id: %d] to [wallet Id: %d] completed", conversion.ID, conversion.FromWalletId, conversion.ToWalletId)
+		return nil
+	})
+}
+
+func createConversion(tx *sql.Tx, c *models.Conversion) error {
+	query := `
+		INSERT INTO conversions (user_id, from_wallet_id, to_wallet_id, from_amount, to_amount, mid_rate, rate, spread, spread_amount,
+			out_transaction_id, in_transaction_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at
+	`
+	return tx.QueryRow(query, c.UserId, c.FromWalletId, c.ToWalletId, c.FromAmount, c.ToAmount, c.MidRate, c.Rate, c.Spread, c.SpreadAmount,
+		c.OutTransactionId, c.InTransactionId).Scan(&c.ID, &c.CreatedAt)
+}
+
+// GetConversionsByTransactionIDs returns the conversions the transactions with txnIDs belong to,
+// keyed by the ID of both of their transactions. Transactions of no conversion are not part of the result.
+func GetConversionsByTransactionIDs(db *sql.DB, txnIDs []int64) (map[int64]models.Conversion, error) {
+
+	if len(txnIDs) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(txnIDs))
+	args := make([]interface{}, len(txnIDs))
+
+	for i, id := range txnIDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+	in := strings.Join(placeholders, ", ")
+
+	query := fmt.Sprintf(`
+		SELECT c.id, c.user_id, c.from_wallet_id, c.to_wallet_id, c.from_amount, c.to_amount, c.mid_rate, c.rate, c.spread, c.spread_amount,
+			c.out_transaction_id, c.in_transaction_id, c.created_at, fw.currency, tw.currency
+		FROM conversions c
+		JOIN wallets fw ON fw.id = c.from_wallet_id
+		JOIN wallets tw ON tw.id = c.to_wallet_id
+		WHERE c.out_transaction_id IN (%s) OR c.in_transaction_id IN (%s)
+		`, in, in)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conversions := make(map[int64]models.Conversion)
+	for rows.Next() {
+		var c models.Conversion
+		err := rows.Scan(&c.ID, &c.UserId, &c.FromWalletId, &c.ToWalletId, &c.FromAmount, &c.ToAmount, &c.MidRate, &c.Rate, &c.Spread, &c.SpreadAmount,
+			&c.OutTransactionId, &c.InTransactionId, &c.CreatedAt, &c.FromCurrency, &c.ToCurrency)
+		if err != nil {
+			return nil, err
+		}
+		conversions[c.OutTransactionId] = c
+		conversions[c.InTransactionId] = c
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return conversions, nil
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// GetCostBasisTransactions returns the completed transactions of the wallet in the order they completed,
+// with the currency of their counterparty wallet. Pending, failed and reversed transactions are left out,
+// a reversed transaction having no lasting effect on the wallet.
+func GetCostBasisTransactions(db *sql.DB, walletId int64) ([]models.CostBasisTxn, error) {
+	query := `
+		SELECT t.id, t.type, t.amount, t.rate, COALESCE(cw.currency, ''), COALESCE(t.completed_at, t.created_at) AS at
+		FROM transactions t
+		LEFT JOIN wallets cw ON cw.id = t.counterparty_wallet_id
+		WHERE t.wallet_id = $1 AND t.status = 'completed'
+		ORDER BY at, t.id
+	`
+
+	rows, err := db.Query(query, walletId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var txns []models.CostBasisTxn
+	for rows.Next() {
+		var t models.CostBasisTxn
+		if err := rows.Scan(&t.ID, &t.Type, &t.Amount, &t.Rate, &t.CounterCcy, &t.At); err != nil {
+			return nil, err
+		}
+		txns = append(txns, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return txns, nil
+}
@@ -0,0 +1,209 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
+)
+
+// pendingFeeCondition selects the fees, aliased f, reserved by a pending transaction aliased p.
+const pendingFeeCondition = `f.transaction_id = p.id AND f.debit_transaction_id IS NULL`
+
+const feeColumns = `id, transaction_id, fee_rule_id, amount, flat_fee, percentage_fee, house_wallet_id, created_at`
+
+// GetFeeRules returns the rules of the fee schedule for feeType.
+func GetFeeRules(db *sql.DB, feeType string) ([]models.FeeRule, error) {
+	query := `
+		SELECT id, fee_type, from_ccy, to_ccy, wallet_type, min_amount, flat_fee, percentage, min_fee, max_fee
+		FROM fee_rules
+		WHERE fee_type = $1
+		ORDER BY id
+	`
+
+	rows, err := db.Query(query, feeType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []models.FeeRule
+	for rows.Next() {
+		var r models.FeeRule
+		err = rows.Scan(&r.ID, &r.FeeType, &r.FromCcy, &r.ToCcy, &r.WalletType, &r.MinAmount, &r.FlatFee, &r.Percentage, &r.MinFee, &r.MaxFee)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// getPendingFee returns the fee reserved by the pending transaction with txnId, or nil when it has none.
+func getPendingFee(tx *sql.Tx, txnId int64) (*models.Fee, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM fees
+		WHERE transaction_id = $1 AND debit_transaction_id IS NULL
+		FOR UPDATE
+	`, feeColumns)
+
+	var f models.Fee
+	err := tx.QueryRow(query, txnId).Scan(&f.ID, &f.TransactionId, &f.Rule.ID, &f.Amount, &f.FlatFee, &f.PercentageFee, &f.HouseWalletId, &f.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	f.Adjustment = f.Amount.Sub(f.FlatFee).Sub(f.PercentageFee)
+	return &f, nil
+}
+
+// createFee records the fee of txn, with its fee transactions once they are created.
+func createFee(tx *sql.Tx, txn *models.Transaction) error {
+	f := txn.Fee
+	f.TransactionId = txn.ID
+
+	query := `
+		INSERT INTO fees (transaction_id, fee_rule_id, amount, flat_fee, percentage_fee, house_wallet_id, debit_transaction_id, credit_transaction_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`
+	return tx.QueryRow(query, f.TransactionId, f.Rule.ID, f.Amount, f.FlatFee, f.PercentageFee, f.HouseWalletId,
+		nullID(f.Debit.ID), nullID(f.Credit.ID)).Scan(&f.ID, &f.CreatedAt)
+}
+
+// createFees records the fees of txns in a single statement.
+func createFees(tx *sql.Tx, txns []*models.Transaction) error {
+	ids, err := reserveIDs(tx, "fees", len(txns))
+	if err != nil {
+		return err
+	}
+
+	placeholders := make([]string, len(txns))
+	args := make([]interface{}, 0, 9*len(txns))
+	byId := make(map[int64]*models.Fee, len(txns))
+
+	for i, t := range txns {
+		f := t.Fee
+		f.ID = ids[i]
+		f.TransactionId = t.ID
+		byId[f.ID] = f
+		n := 9 * i
+		placeholders[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9)
+		args = append(args, f.ID, f.TransactionId, f.Rule.ID, f.Amount, f.FlatFee, f.PercentageFee, f.HouseWalletId, nullID(f.Debit.ID), nullID(f.Credit.ID))
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO fees (id, transaction_id, fee_rule_id, amount, flat_fee, percentage_fee, house_wallet_id, debit_transaction_id, credit_transaction_id)
+		VALUES %s
+		RETURNING id, created_at
+	`, strings.Join(placeholders, ", "))
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var createdAt time.Time
+		if err := rows.Scan(&id, &createdAt); err != nil {
+			return err
+		}
+		f, ok := byId[id]
+		if !ok {
+			return fmt.Errorf("fee %d returned but not inserted", id)
+		}
+		f.CreatedAt = createdAt
+		delete(byId, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	if len(byId) != 0 {
+		return fmt.Errorf("%d fees inserted but not returned", len(byId))
+	}
+	return nil
+}
+
+// markFeeCharged links a fee recorded while its transaction was pending to its fee transactions.
+func markFeeCharged(tx *sql.Tx, f *models.Fee) error {
+	query := `UPDATE fees SET debit_transaction_id = $1, credit_transaction_id = $2 WHERE id = $3`
+
+	_, err := tx.Exec(query, f.Debit.ID, f.Credit.ID, f.ID)
+	return err
+}
+
+// feeTransactions returns the fee transactions of txn: the fee taken from its wallet and the
+// fee-income paid to the house wallet.
+func feeTransactions(txn *models.Transaction) (debit models.Transaction, credit models.Transaction) {
+	f := txn.Fee
+	description := models.NullString(fmt.Sprintf("fee for %s transaction %d", txn.Type, txn.ID))
+
+	debit = models.Transaction{
+		WalletId:             txn.WalletId,
+		Type:                 models.TxnTypeFee,
+		Amount:               f.Amount,
+		CounterpartyWalletId: sql.NullInt64{Int64: f.HouseWalletId, Valid: true},
+		Description:          description,
+	}
+	credit = models.Transaction{
+		WalletId:             f.HouseWalletId,
+		Type:                 models.TxnTypeFeeIncome,
+		Amount:               f.Amount,
+		CounterpartyWalletId: sql.NullInt64{Int64: txn.WalletId, Valid: true},
+		Description:          description,
+	}
+	return debit, credit
+}
+
+// chargeFeeInternal posts the fee of txn, already taken from the balance of its wallet leaving
+// balanceAfter, as a fee transaction on the wallet and credits it to the house wallet.
+// The fee is recorded, or marked as charged when it was recorded while txn was pending.
+func chargeFeeInternal(tx *sql.Tx, txn *models.Transaction, balanceAfter decimal.Decimal) error {
+	f := txn.Fee
+	f.Debit, f.Credit = feeTransactions(txn)
+
+	err := createTransaction(tx, &f.Debit)
+	if err != nil {
+		log.Printf("ERROR: failed to create fee transaction for transaction Id: %d", txn.ID)
+		return fmt.Errorf("failed to create fee transaction: %w", err)
+	}
+	f.Debit.BalanceAfter = balanceAfter
+
+	err = depositInternal(tx, &f.Credit)
+	if err != nil {
+		return err
+	}
+
+	if f.ID == 0 {
+		err = createFee(tx, txn)
+	} else {
+		err = markFeeCharged(tx, f)
+	}
+	if err != nil {
+		log.Printf("ERROR: failed to record fee for transaction Id: %d", txn.ID)
+		return fmt.Errorf("failed to record fee: %w", err)
+	}
+
+	log.Printf("fee of %s charged for transaction Id: %d", f.Amount, txn.ID)
+	return nil
+}
+
+// nullID converts the ID of a record that may not be created into a nullable column value.
+func nullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
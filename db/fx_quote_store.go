@@ -0,0 +1,84 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// fxQuoteColumns are followed by whether the quote is past its expiry, so that expiry is
+// decided by the database clock as for holds.
+const fxQuoteColumns = `id, user_id, from_ccy, to_ccy, rate, mid_rate, side, spread, rate_path, status, transfer_id, expires_at, created_at, used_at, expires_at <= CURRENT_TIMESTAMP`
+
+// scanFxQuote reads a quote selected with fxQuoteColumns. An open quote past its expiry is read as expired.
+func scanFxQuote(row rowScanner) (models.FxQuote, error) {
+	var q models.FxQuote
+	var expired bool
+	err := row.Scan(&q.ID, &q.UserId, &q.FromCcy, &q.ToCcy, &q.Rate, &q.MidRate, &q.Side, &q.Spread, &q.RatePath, &q.Status, &q.TransferId, &q.ExpiresAt, &q.CreatedAt, &q.UsedAt, &expired)
+	if err == nil && expired && q.Status == models.FxQuoteStatusOpen {
+		q.Status = models.FxQuoteStatusExpired
+	}
+	return q, err
+}
+
+func GetFxQuoteById(db *sql.DB, id int64) (*models.FxQuote, error) {
+	query := fmt.Sprintf(`SELECT %s FROM fx_quotes WHERE id = $1`, fxQuoteColumns)
+
+	q, err := scanFxQuote(db.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &q, nil
+}
+
+// CreateFxQuote records quote, locking its rate for ttlSeconds after creation.
+func CreateFxQuote(db *sql.DB, quote *models.FxQuote, ttlSeconds int64) error {
+	quote.Status = models.FxQuoteStatusOpen
+	query := `
+		INSERT INTO fx_quotes (user_id, from_ccy, to_ccy, rate, mid_rate, side, spread, rate_path, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, CURRENT_TIMESTAMP + make_interval(secs => $10))
+		RETURNING id, expires_at, created_at
+	`
+	err := db.QueryRow(query, quote.UserId, quote.FromCcy, quote.ToCcy, quote.Rate, quote.MidRate, quote.Side, quote.Spread, quote.RatePath, quote.Status, ttlSeconds).
+		Scan(&quote.ID, &quote.ExpiresAt, &quote.CreatedAt)
+	if err != nil {
+		log.Printf("ERROR: failed to create fx quote from %s to %s for user Id: %d", quote.FromCcy, quote.ToCcy, quote.UserId)
+		return fmt.Errorf("failed to create fx quote: %w", err)
+	}
+
+	log.Printf("fx quote Id: %d created from %s to %s", quote.ID, quote.FromCcy, quote.ToCcy)
+	return nil
+}
+
+// useFxQuote marks the quote of transfer as used by it. The quote is locked first, so that
+// concurrent transfers cannot both use it, and must still be open.
+func useFxQuote(tx *sql.Tx, transfer *models.Transfer) error {
+	query := fmt.Sprintf(`SELECT %s FROM fx_quotes WHERE id = $1 FOR UPDATE`, fxQuoteColumns)
+
+	quote, err := scanFxQuote(tx.QueryRow(query, transfer.QuoteId.Int64))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Errorf(models.ErrCodeQuoteNotFound, "fx quote %d not found", transfer.QuoteId.Int64)
+		}
+		return fmt.Errorf("failed to get fx quote: %w", err)
+	}
+
+	if err = quote.CheckUsable(); err != nil {
+		log.Printf("ERROR: fx quote Id: %d cannot be used by transfer Id: %d", quote.ID, transfer.ID)
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE fx_quotes SET status = $1, transfer_id = $2, used_at = CURRENT_TIMESTAMP WHERE id = $3`,
+		models.FxQuoteStatusUsed, transfer.ID, quote.ID)
+	if err != nil {
+		log.Printf("ERROR: failed to use fx quote Id: %d", quote.ID)
+		return fmt.Errorf("failed to update fx quote: %w", err)
+	}
+	return nil
+}
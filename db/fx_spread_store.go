@@ -0,0 +1,157 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// GetFxSpreadRules returns the rules of the FX spread schedule applying to the tier of the user with
+// userId: the rules of that tier and the rules of any tier. The default tier applies when there is no
+// such user.
+func GetFxSpreadRules(db *sql.DB, userId int64) ([]models.FxSpreadRule, error) {
+	query := `
+		SELECT id, base_ccy, quote_ccy, tier, bid_spread, ask_spread
+		FROM fx_spreads
+		WHERE tier IS NULL OR tier = COALESCE((SELECT tier FROM users WHERE id = $1), $2)
+		ORDER BY id
+	`
+
+	rows, err := db.Query(query, userId, models.DefaultFxTier)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []models.FxSpreadRule
+	for rows.Next() {
+		var r models.FxSpreadRule
+		err = rows.Scan(&r.ID, &r.BaseCcy, &r.QuoteCcy, &r.Tier, &r.BidSpread, &r.AskSpread)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// GetSpreadRevenueTotals returns the FX spread revenue recorded so far, summed per currency.
+func GetSpreadRevenueTotals(db *sql.DB) ([]models.SpreadRevenueTotal, error) {
+	query := `
+		SELECT currency, COUNT(*), SUM(amount)
+		FROM fx_spread_revenue
+		GROUP BY currency
+		ORDER BY currency
+	`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []models.SpreadRevenueTotal
+	for rows.Next() {
+		var t models.SpreadRevenueTotal
+		if err = rows.Scan(&t.Currency, &t.Count, &t.Amount); err != nil {
+			return nil, err
+		}
+		totals = append(totals, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// recordSpreadRevenue records the spread revenue of the exchange txn pays for, if any.
+func recordSpreadRevenue(tx *sql.Tx, txn *models.Transaction) error {
+	if txn.SpreadRevenue == nil {
+		return nil
+	}
+
+	err := createSpreadRevenue(tx, txn)
+	if err != nil {
+		log.Printf("ERROR: failed to record spread revenue for transaction Id: %d", txn.ID)
+		return fmt.Errorf("failed to record spread revenue: %w", err)
+	}
+	return nil
+}
+
+// createSpreadRevenue records the spread revenue of the exchange txn pays for.
+func createSpreadRevenue(tx *sql.Tx, txn *models.Transaction) error {
+	r := txn.SpreadRevenue
+	r.TransactionId = txn.ID
+
+	query := `
+		INSERT INTO fx_spread_revenue (transaction_id, currency, mid_rate, rate, amount)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	return tx.QueryRow(query, r.TransactionId, r.Currency, r.MidRate, r.Rate, r.Amount).Scan(&r.ID, &r.CreatedAt)
+}
+
+// createSpreadRevenues records the spread revenue of the exchanges txns pay for in a single statement.
+func createSpreadRevenues(tx *sql.Tx, txns []*models.Transaction) error {
+	ids, err := reserveIDs(tx, "fx_spread_revenue", len(txns))
+	if err != nil {
+		return err
+	}
+
+	placeholders := make([]string, len(txns))
+	args := make([]interface{}, 0, 6*len(txns))
+	byId := make(map[int64]*models.SpreadRevenue, len(txns))
+
+	for i, t := range txns {
+		r := t.SpreadRevenue
+		r.ID = ids[i]
+		r.TransactionId = t.ID
+		byId[r.ID] = r
+		n := 6 * i
+		placeholders[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6)
+		args = append(args, r.ID, r.TransactionId, r.Currency, r.MidRate, r.Rate, r.Amount)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO fx_spread_revenue (id, transaction_id, currency, mid_rate, rate, amount)
+		VALUES %s
+		RETURNING id, created_at
+	`, strings.Join(placeholders, ", "))
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var createdAt time.Time
+		if err := rows.Scan(&id, &createdAt); err != nil {
+			return err
+		}
+		r, ok := byId[id]
+		if !ok {
+			return fmt.Errorf("spread revenue %d returned but not inserted", id)
+		}
+		r.CreatedAt = createdAt
+		delete(byId, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	if len(byId) != 0 {
+		return fmt.Errorf("%d spread revenue inserted but not returned", len(byId))
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
+)
+
+// activeHoldCondition selects the holds, aliased h, that still reserve funds.
+// Holds past their expiry stop counting right away, even before ExpireHolds marks them as expired.
+const activeHoldCondition = `h.status = 'active' AND (h.expires_at IS NULL OR h.expires_at > CURRENT_TIMESTAMP)`
+
+const holdColumns = `id, wallet_id, amount, captured_amount, status, description, transaction_id, expires_at, created_at, updated_at`
+
+func scanHold(row rowScanner) (models.Hold, error) {
+	var h models.Hold
+	err := row.Scan(
+		&h.ID,
+		&h.WalletId,
+		&h.Amount,
+		&h.CapturedAmount,
+		&h.Status,
+		&h.Description,
+		&h.TransactionId,
+		&h.ExpiresAt,
+		&h.CreatedAt,
+		&h.UpdatedAt,
+	)
+	return h, err
+}
+
+func GetHoldById(db *sql.DB, id int64) (*models.Hold, error) {
+	query := fmt.Sprintf(`SELECT %s FROM holds WHERE id = $1`, holdColumns)
+
+	h, err := scanHold(db.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &h, nil
+}
+
+// GetHeldBalancesByWalletIDs returns the amount reserved by active holds, keyed by wallet ID.
+// Wallets without active holds are not part of the result.
+func GetHeldBalancesByWalletIDs(db *sql.DB, walletIDs []int64) (map[int64]decimal.Decimal, error) {
+
+	if len(walletIDs) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(walletIDs))
+	args := make([]interface{}, len(walletIDs))
+
+	for i, id := range walletIDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+
+	query := fmt.Sprintf(`
+		SELECT h.wallet_id, SUM(h.amount)
+		FROM holds h
+		WHERE h.wallet_id IN (%s) AND %s
+		GROUP BY h.wallet_id
+	`, strings.Join(placeholders, ", "), activeHoldCondition)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	held := make(map[int64]decimal.Decimal)
+	for rows.Next() {
+		var walletId int64
+		var amount decimal.Decimal
+		if err := rows.Scan(&walletId, &amount); err != nil {
+			return nil, err
+		}
+		held[walletId] = amount
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return held, nil
+}
+
+// CreateHold reserves the hold amount on the wallet if its available balance is sufficient.
+// The hold expires expiresInSeconds after creation.
+func CreateHold(db *sql.DB, hold *models.Hold, expiresInSeconds int64) error {
+	return withTx(db, func(tx *sql.Tx) error {
+		balance, err := getWalletBalance(tx, hold.WalletId)
+		if err != nil {
+			log.Printf("ERROR: failed to get balance for wallet Id: %d", hold.WalletId)
+			return fmt.Errorf("failed to get balance: %w", err)
+		}
+
+		if balance == nil {
+			log.Printf("ERROR: wallet Id: %d not found", hold.WalletId)
+			return models.Errorf(models.ErrCodeWalletNotFound, "wallet %d not found", hold.WalletId)
+		}
+
+		if balance.AvailableBalance.LessThan(hold.Amount) {
+			log.Printf("ERROR: wallet Id: %d does not have enough balance", hold.WalletId)
+			return models.Errorf(models.ErrCodeInsufficientFunds, "wallet %d does not have enough balance", hold.WalletId).
+				WithDetails(map[string]string{
+					"balance":           balance.Balance.String(),
+					"available_balance": balance.AvailableBalance.String(),
+					"requested":         hold.Amount.String(),
+				})
+		}
+
+		hold.Status = models.HoldStatusActive
+		query := `
+			INSERT INTO holds (wallet_id, amount, status, description, expires_at)
+			VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP + make_interval(secs => $5))
+			RETURNING id, expires_at, created_at, updated_at
+		`
+		err = tx.QueryRow(query, hold.WalletId, hold.Amount, hold.Status, hold.Description, expiresInSeconds).
+			Scan(&hold.ID, &hold.ExpiresAt, &hold.CreatedAt, &hold.UpdatedAt)
+		if err != nil {
+			log.Printf("ERROR: failed to create hold for wallet Id: %d", hold.WalletId)
+			return fmt.Errorf("failed to create hold: %w", err)
+		}
+
+		log.Printf("hold Id: %d created for wallet Id: %d", hold.ID, hold.WalletId)
+		return nil
+	})
+}
+
+// CaptureHold captures amount of an active hold: the hold is closed, the captured amount is
+// withdrawn from the wallet through txn and the rest of the hold is released.
+// hold is updated with the result.
+func CaptureHold(db *sql.DB, hold *models.Hold, amount decimal.Decimal, txn *models.Transaction) error {
+	return withTx(db, func(tx *sql.Tx) error {
+		err := lockActiveHold(tx, hold)
+		if err != nil {
+			return err
+		}
+
+		if amount.GreaterThan(hold.Amount) {
+			return models.Errorf(models.ErrCodeValidationFailed, "amount must not be greater than the held amount").
+				WithDetails(map[string]string{"held": hold.Amount.String(), "requested": amount.String()})
+		}
+
+		// Close the hold first so its funds count as available for the withdrawal below
+		err = updateHoldStatus(tx, hold, models.HoldStatusCaptured, amount)
+		if err != nil {
+			return err
+		}
+
+		txn.WalletId = hold.WalletId
+		txn.Amount = amount
+		err = withdrawInternal(tx, txn)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`UPDATE holds SET transaction_id = $1 WHERE id = $2`, txn.ID, hold.ID)
+		if err != nil {
+			log.Printf("ERROR: failed to link transaction to hold Id: %d", hold.ID)
+			return fmt.Errorf("failed to update hold: %w", err)
+		}
+		hold.TransactionId = sql.NullInt64{Int64: txn.ID, Valid: true}
+
+		log.Printf("hold Id: %d captured for wallet Id: %d", hold.ID, hold.WalletId)
+		return nil
+	})
+}
+
+// ReleaseHold releases an active hold without moving any funds. hold is updated with the result.
+func ReleaseHold(db *sql.DB, hold *models.Hold) error {
+	return withTx(db, func(tx *sql.Tx) error {
+		err := lockActiveHold(tx, hold)
+		if err != nil {
+			return err
+		}
+
+		err = updateHoldStatus(tx, hold, models.HoldStatusReleased, decimal.Zero)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("hold Id: %d released for wallet Id: %d", hold.ID, hold.WalletId)
+		return nil
+	})
+}
+
+// ExpireHolds marks the active holds past their expiry as expired and returns how many were expired.
+func ExpireHolds(db *sql.DB) (int64, error) {
+	query := `
+		UPDATE holds
+		SET status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE status = $2 AND expires_at <= CURRENT_TIMESTAMP
+	`
+	result, err := db.Exec(query, models.HoldStatusExpired, models.HoldStatusActive)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// lockActiveHold reloads the hold with a row lock for the rest of the DB transaction and
+// makes sure it can still be captured or released.
+func lockActiveHold(tx *sql.Tx, hold *models.Hold) error {
+	query := fmt.Sprintf(`
+		SELECT %s, (expires_at IS NOT NULL AND expires_at <= CURRENT_TIMESTAMP)
+		FROM holds
+		WHERE id = $1
+		FOR UPDATE
+	`, holdColumns)
+
+	var expired bool
+	err := tx.QueryRow(query, hold.ID).Scan(
+		&hold.ID,
+		&hold.WalletId,
+		&hold.Amount,
+		&hold.CapturedAmount,
+		&hold.Status,
+		&hold.Description,
+		&hold.TransactionId,
+		&hold.ExpiresAt,
+		&hold.CreatedAt,
+		&hold.UpdatedAt,
+		&expired,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Errorf(models.ErrCodeHoldNotFound, "hold %d not found", hold.ID)
+		}
+		return fmt.Errorf("failed to get hold: %w", err)
+	}
+
+	if hold.Status != models.HoldStatusActive {
+		return models.Errorf(models.ErrCodeHoldNotActive, "hold %d is %s", hold.ID, hold.Status)
+	}
+
+	if expired {
+		return models.Errorf(models.ErrCodeHoldNotActive, "hold %d has expired", hold.ID)
+	}
+	return nil
+}
+
+func updateHoldStatus(tx *sql.Tx, hold *models.Hold, status string, capturedAmount decimal.Decimal) error {
+	query := `
+		UPDATE holds
+		SET status = $1, captured_amount = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3
+		RETURNING updated_at
+	`
+	err := tx.QueryRow(query, status, capturedAmount, hold.ID).Scan(&hold.UpdatedAt)
+	if err != nil {
+		log.Printf("ERROR: failed to update status of hold Id: %d", hold.ID)
+		return fmt.Errorf("failed to update hold: %w", err)
+	}
+	hold.Status = status
+	hold.CapturedAmount = capturedAmount
+	return nil
+}
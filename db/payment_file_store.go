@@ -0,0 +1,45 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// GetPaymentFileProcessedAt returns when the payment file with msgId was processed for the wallet,
+// or nil when it has not been.
+func GetPaymentFileProcessedAt(db *sql.DB, walletId int64, msgId string) (*time.Time, error) {
+	var processedAt time.Time
+	err := db.QueryRow(`SELECT processed_at FROM payment_files WHERE wallet_id = $1 AND msg_id = $2`, walletId, msgId).Scan(&processedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &processedAt, nil
+}
+
+// recordPaymentFile records the payment file with msgId as processed for the wallet, failing with
+// DUPLICATE_PAYMENT_FILE when it already was.
+func recordPaymentFile(tx *sql.Tx, walletId int64, msgId string) error {
+	query := `
+		INSERT INTO payment_files (wallet_id, msg_id)
+		VALUES ($1, $2)
+		ON CONFLICT (wallet_id, msg_id) DO NOTHING
+		RETURNING processed_at
+	`
+
+	var processedAt time.Time
+	err := tx.QueryRow(query, walletId, msgId).Scan(&processedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Errorf(models.ErrCodeDuplicateFile, "payment file %s has already been processed for wallet %d", msgId, walletId)
+		}
+		return fmt.Errorf("failed to record payment file: %w", err)
+	}
+	return nil
+}
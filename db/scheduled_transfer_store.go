@@ -0,0 +1,350 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// ErrScheduleChanged is returned when a scheduled transfer run is recorded for an occurrence or attempt
+// that is no longer due, e.g. because the schedule was paused or another scheduler handled it first.
+var ErrScheduleChanged = errors.New("scheduled transfer changed since it was read")
+
+const scheduledTransferColumns = `st.id, st.source_wallet_id, st.destination_wallet_id, st.destination_user_id, st.amount,
+	st.description, st.external_reference, st.frequency, st.start_at, st.end_at, st.max_occurrences, st.occurrences,
+	st.next_run_at, st.retry_at, st.attempts, st.on_insufficient_funds, st.status, st.created_at, st.updated_at`
+
+func scanScheduledTransfer(row rowScanner) (models.ScheduledTransfer, error) {
+	var s models.ScheduledTransfer
+	err := row.Scan(
+		&s.ID,
+		&s.SourceWalletId,
+		&s.DestinationWalletId,
+		&s.DestinationUserId,
+		&s.Amount,
+		&s.Description,
+		&s.ExternalReference,
+		&s.Frequency,
+		&s.StartAt,
+		&s.EndAt,
+		&s.MaxOccurrences,
+		&s.Occurrences,
+		&s.NextRunAt,
+		&s.RetryAt,
+		&s.Attempts,
+		&s.OnInsufficientFunds,
+		&s.Status,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+	)
+	return s, err
+}
+
+func GetScheduledTransferById(db *sql.DB, id int64) (*models.ScheduledTransfer, error) {
+	query := fmt.Sprintf(`SELECT %s FROM scheduled_transfers st WHERE st.id = $1`, scheduledTransferColumns)
+
+	s, err := scanScheduledTransfer(db.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetScheduledTransfersByUserID returns the scheduled transfers paid from the wallets of the user, newest first.
+func GetScheduledTransfersByUserID(db *sql.DB, userId int64) ([]models.ScheduledTransfer, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM scheduled_transfers st
+		JOIN wallets w ON w.id = st.source_wallet_id
+		WHERE w.user_id = $1
+		ORDER BY st.created_at DESC, st.id DESC
+	`, scheduledTransferColumns)
+
+	return queryScheduledTransfers(db, query, userId)
+}
+
+// GetDueScheduledTransfers returns up to limit active scheduled transfers whose next occurrence,
+// or retry, is due at now, the most overdue first.
+func GetDueScheduledTransfers(db *sql.DB, now time.Time, limit int) ([]models.ScheduledTransfer, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM scheduled_transfers st
+		WHERE st.status = $1 AND COALESCE(st.retry_at, st.next_run_at) <= $2
+		ORDER BY COALESCE(st.retry_at, st.next_run_at), st.id
+		LIMIT $3
+	`, scheduledTransferColumns)
+
+	return queryScheduledTransfers(db, query, models.ScheduleStatusActive, now, limit)
+}
+
+func queryScheduledTransfers(db *sql.DB, query string, args ...interface{}) ([]models.ScheduledTransfer, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []models.ScheduledTransfer
+	for rows.Next() {
+		s, err := scanScheduledTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+func CreateScheduledTransfer(db *sql.DB, s *models.ScheduledTransfer) error {
+	query := `
+		INSERT INTO scheduled_transfers (source_wallet_id, destination_wallet_id, destination_user_id, amount, description,
+			external_reference, frequency, start_at, end_at, max_occurrences, next_run_at, on_insufficient_funds, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id, created_at, updated_at
+	`
+	return db.QueryRow(
+		query,
+		s.SourceWalletId,
+		s.DestinationWalletId,
+		s.DestinationUserId,
+		s.Amount,
+		s.Description,
+		s.ExternalReference,
+		s.Frequency,
+		s.StartAt,
+		s.EndAt,
+		s.MaxOccurrences,
+		s.NextRunAt,
+		s.OnInsufficientFunds,
+		s.Status,
+	).Scan(&s.ID, &s.CreatedAt, &s.UpdatedAt)
+}
+
+// UpdateScheduledTransfer locks the scheduled transfer, lets apply change it and saves the result
+// within a DB transaction, so the change cannot interleave with a run of the scheduler.
+// The updated scheduled transfer is returned.
+func UpdateScheduledTransfer(db *sql.DB, id int64, apply func(s *models.ScheduledTransfer) error) (*models.ScheduledTransfer, error) {
+	var updated *models.ScheduledTransfer
+	err := withTx(db, func(tx *sql.Tx) error {
+		s, err := lockScheduledTransfer(tx, id)
+		if err != nil {
+			return fmt.Errorf("failed to get scheduled transfer: %w", err)
+		}
+
+		if s == nil {
+			return models.Errorf(models.ErrCodeScheduleNotFound, "scheduled transfer %d not found", id)
+		}
+
+		if err = apply(s); err != nil {
+			return err
+		}
+
+		if err = saveScheduledTransfer(tx, s); err != nil {
+			log.Printf("ERROR: failed to update scheduled transfer Id: %d", id)
+			return fmt.Errorf("failed to update scheduled transfer: %w", err)
+		}
+		updated = s
+		return nil
+	})
+	return updated, err
+}
+
+// RunScheduledTransfer executes the due occurrence of a scheduled transfer through the same path as
+// TransferUpdate, records the successful run and moves the schedule to its next occurrence,
+// all within one DB transaction. ErrScheduleChanged is returned when the occurrence is no longer due.
+func RunScheduledTransfer(db *sql.DB, s *models.ScheduledTransfer, run *models.ScheduledTransferRun,
+	transfer *models.Transfer, srcTxn *models.Transaction, targetTxn *models.Transaction) error {
+	next := *s
+	err := withTx(db, func(tx *sql.Tx) error {
+		err := checkScheduledRun(tx, run)
+		if err != nil {
+			return err
+		}
+
+		err = transferInternal(tx, transfer, srcTxn, targetTxn)
+		if err != nil {
+			return err
+		}
+
+		run.Status = models.RunStatusSucceeded
+		run.TransferId = sql.NullInt64{Int64: transfer.ID, Valid: true}
+		if err = createScheduledTransferRun(tx, run); err != nil {
+			return fmt.Errorf("failed to record scheduled transfer run: %w", err)
+		}
+
+		next.Advance()
+		if err = saveScheduledTransfer(tx, &next); err != nil {
+			return fmt.Errorf("failed to update scheduled transfer: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	*s = next
+	return nil
+}
+
+// RecordScheduledTransferRun records a run that did not transfer anything together with the schedule,
+// which the caller has already moved to a retry or to the next occurrence.
+// ErrScheduleChanged is returned when the occurrence is no longer due.
+func RecordScheduledTransferRun(db *sql.DB, s *models.ScheduledTransfer, run *models.ScheduledTransferRun) error {
+	return withTx(db, func(tx *sql.Tx) error {
+		err := checkScheduledRun(tx, run)
+		if err != nil {
+			return err
+		}
+
+		if err = createScheduledTransferRun(tx, run); err != nil {
+			return fmt.Errorf("failed to record scheduled transfer run: %w", err)
+		}
+
+		if err = saveScheduledTransfer(tx, s); err != nil {
+			return fmt.Errorf("failed to update scheduled transfer: %w", err)
+		}
+		return nil
+	})
+}
+
+// SkipMissedScheduledTransferOccurrences saves s with the occurrences missed before the latest one due
+// at now skipped, see models.ScheduledTransfer.SkipMissedDue, and returns the number skipped. Nothing is
+// saved when no occurrence is skipped. It fails with ErrScheduleChanged when s is no longer the one read.
+func SkipMissedScheduledTransferOccurrences(db *sql.DB, s *models.ScheduledTransfer, now time.Time) (int, error) {
+	next := *s
+	skipped := next.SkipMissedDue(now)
+	if skipped == 0 {
+		return 0, nil
+	}
+
+	err := withTx(db, func(tx *sql.Tx) error {
+		// The occurrence and attempt due must be the ones read, as for a run of it
+		err := checkScheduledRun(tx, &models.ScheduledTransferRun{ScheduledTransferId: s.ID, ScheduledFor: s.NextRunAt.Time, Attempt: s.Attempts + 1})
+		if err != nil {
+			return err
+		}
+
+		if err = saveScheduledTransfer(tx, &next); err != nil {
+			return fmt.Errorf("failed to update scheduled transfer: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	*s = next
+	return skipped, nil
+}
+
+// GetScheduledTransferRuns returns the runs of a scheduled transfer, newest first.
+func GetScheduledTransferRuns(db *sql.DB, scheduleId int64) ([]models.ScheduledTransferRun, error) {
+	query := `
+		SELECT id, scheduled_transfer_id, scheduled_for, attempt, status, transfer_id, error_code, error_message, created_at
+		FROM scheduled_transfer_runs
+		WHERE scheduled_transfer_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+	rows, err := db.Query(query, scheduleId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []models.ScheduledTransferRun
+	for rows.Next() {
+		var r models.ScheduledTransferRun
+		err := rows.Scan(&r.ID, &r.ScheduledTransferId, &r.ScheduledFor, &r.Attempt, &r.Status, &r.TransferId,
+			&r.ErrorCode, &r.ErrorMessage, &r.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// checkScheduledRun locks the scheduled transfer of run and makes sure the occurrence and attempt
+// of run are still the ones due.
+func checkScheduledRun(tx *sql.Tx, run *models.ScheduledTransferRun) error {
+	s, err := lockScheduledTransfer(tx, run.ScheduledTransferId)
+	if err != nil {
+		return fmt.Errorf("failed to get scheduled transfer: %w", err)
+	}
+
+	if s == nil || s.Status != models.ScheduleStatusActive || !s.NextRunAt.Valid ||
+		!s.NextRunAt.Time.Equal(run.ScheduledFor) || s.Attempts+1 != run.Attempt {
+		return ErrScheduleChanged
+	}
+	return nil
+}
+
+func lockScheduledTransfer(tx *sql.Tx, id int64) (*models.ScheduledTransfer, error) {
+	query := fmt.Sprintf(`SELECT %s FROM scheduled_transfers st WHERE st.id = $1 FOR UPDATE`, scheduledTransferColumns)
+
+	s, err := scanScheduledTransfer(tx.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func saveScheduledTransfer(tx *sql.Tx, s *models.ScheduledTransfer) error {
+	query := `
+		UPDATE scheduled_transfers
+		SET amount = $1, description = $2, external_reference = $3, end_at = $4, max_occurrences = $5,
+			occurrences = $6, next_run_at = $7, retry_at = $8, attempts = $9, on_insufficient_funds = $10,
+			status = $11, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $12
+		RETURNING updated_at
+	`
+	return tx.QueryRow(
+		query,
+		s.Amount,
+		s.Description,
+		s.ExternalReference,
+		s.EndAt,
+		s.MaxOccurrences,
+		s.Occurrences,
+		s.NextRunAt,
+		s.RetryAt,
+		s.Attempts,
+		s.OnInsufficientFunds,
+		s.Status,
+		s.ID,
+	).Scan(&s.UpdatedAt)
+}
+
+func createScheduledTransferRun(tx *sql.Tx, run *models.ScheduledTransferRun) error {
+	query := `
+		INSERT INTO scheduled_transfer_runs (scheduled_transfer_id, scheduled_for, attempt, status, transfer_id, error_code, error_message)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+	return tx.QueryRow(
+		query,
+		run.ScheduledTransferId,
+		run.ScheduledFor,
+		run.Attempt,
+		run.Status,
+		run.TransferId,
+		run.ErrorCode,
+		run.ErrorMessage,
+	).Scan(&run.ID, &run.CreatedAt)
+}
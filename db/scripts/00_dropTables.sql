@@ -1,4 +1,19 @@
+DROP TABLE IF EXISTS fx_spread_revenue;
+DROP TABLE IF EXISTS fx_spreads;
+DROP TABLE IF EXISTS conversions;
+DROP TABLE IF EXISTS fx_quotes;
+DROP TABLE IF EXISTS fees;
+DROP TABLE IF EXISTS fee_rules;
+DROP TABLE IF EXISTS scheduled_transfer_runs;
+DROP TABLE IF EXISTS scheduled_transfers;
+DROP TABLE IF EXISTS holds;
+DROP TABLE IF EXISTS transaction_tags;
+DROP TABLE IF EXISTS wallet_statements;
+DROP TABLE IF EXISTS payment_files;
+DROP TABLE IF EXISTS wallet_balance_snapshots;
 DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS transfers;
 DROP TABLE IF EXISTS wallets;
 DROP TABLE IF EXISTS users;
-DROP TABLE IF EXISTS ccy_conversion;
\ No newline at end of file
+DROP TABLE IF EXISTS ccy_conversion;
+DROP TABLE IF EXISTS ccy_conversion_history;
\ No newline at end of file
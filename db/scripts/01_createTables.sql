@@ -1,6 +1,8 @@
 CREATE TABLE IF NOT EXISTS users (
     id SERIAL PRIMARY KEY,
     name VARCHAR(100) NOT NULL,
+    tier VARCHAR(20) NOT NULL DEFAULT 'standard',  -- customer tier pricing FX spreads, e.g., standard, premium
+    reporting_currency TEXT NOT NULL DEFAULT 'USD', -- currency the total of the user's wallets is shown in
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -22,19 +24,242 @@ CREATE UNIQUE INDEX IF NOT EXISTS one_default_wallet_per_user
 ON wallets(user_id)
 WHERE is_default = TRUE;
 
+CREATE TABLE IF NOT EXISTS transfers (
+    id SERIAL PRIMARY KEY,
+    source_wallet_id INT NOT NULL REFERENCES wallets(id),
+    target_wallet_id INT NOT NULL REFERENCES wallets(id),
+    source_amount NUMERIC(20, 2) NOT NULL,
+    target_amount NUMERIC(20, 2) NOT NULL,
+    rate NUMERIC(20, 10) NOT NULL,  -- target amount per unit of source amount
+    rate_path TEXT NOT NULL DEFAULT '',  -- currencies the rate was resolved through, e.g., EUR>USD>BTC; empty when none was resolved
+    status VARCHAR(20) NOT NULL,    -- completed, partially_reversed, reversed
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    reversal_of INT REFERENCES transfers(id),           -- set when this transfer reverses another one
+    reversed_amount NUMERIC(20, 2) NOT NULL DEFAULT 0   -- source amount reversed so far
+);
+
+CREATE INDEX IF NOT EXISTS transfers_source_wallet_id ON transfers(source_wallet_id);
+CREATE INDEX IF NOT EXISTS transfers_target_wallet_id ON transfers(target_wallet_id);
+
 CREATE TABLE IF NOT EXISTS transactions (
     id SERIAL PRIMARY KEY,
     wallet_id INT REFERENCES wallets(id),
     type VARCHAR(20), -- deposit, withdrawal, transfer
     amount NUMERIC(20, 2),
     counterparty_wallet_id INT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    description VARCHAR(255),       -- free text memo entered by the user
+    external_reference VARCHAR(64), -- e.g., invoice number, partner reference
+    transfer_id INT REFERENCES transfers(id), -- set on both legs of a transfer
+    status VARCHAR(20) NOT NULL DEFAULT 'completed', -- pending, completed, failed, reversed
+    completed_at TIMESTAMP,
+    failed_at TIMESTAMP,
+    reversed_at TIMESTAMP,
+    rate NUMERIC(20, 10)            -- rate given to the customer on both legs of an exchange between currencies
+);
+
+CREATE INDEX IF NOT EXISTS transactions_transfer_id ON transactions(transfer_id);
+CREATE INDEX IF NOT EXISTS transactions_wallet_id_pending ON transactions(wallet_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS transactions_wallet_id ON transactions(wallet_id);
+
+-- Balance of each wallet at the start of a day, made of the transactions completed or reversed before it.
+-- Historical balances start from the latest snapshot instead of summing the whole history.
+CREATE TABLE IF NOT EXISTS wallet_balance_snapshots (
+    wallet_id INT NOT NULL REFERENCES wallets(id),
+    snapshot_at TIMESTAMP NOT NULL,
+    balance NUMERIC(20, 2) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (wallet_id, snapshot_at)
+);
+
+-- Monthly statement of each wallet, stored as generated once the month has ended.
+CREATE TABLE IF NOT EXISTS wallet_statements (
+    wallet_id INT NOT NULL REFERENCES wallets(id),
+    period DATE NOT NULL,                   -- first day of the month
+    opening_balance NUMERIC(20, 2) NOT NULL,
+    closing_balance NUMERIC(20, 2) NOT NULL,
+    statement JSONB NOT NULL,               -- the statement with its entries
+    generated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (wallet_id, period)
+);
+
+-- pain.001 payment files processed for each debtor wallet, so that a file is not paid twice.
+CREATE TABLE IF NOT EXISTS payment_files (
+    wallet_id INT NOT NULL REFERENCES wallets(id),
+    msg_id TEXT NOT NULL,                   -- GrpHdr/MsgId of the file
+    processed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (wallet_id, msg_id)
+);
+
+CREATE TABLE IF NOT EXISTS transaction_tags (
+    transaction_id INT NOT NULL REFERENCES transactions(id),
+    tag VARCHAR(32) NOT NULL,       -- lower case, e.g., payroll, rent
+    PRIMARY KEY (transaction_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS transaction_tags_tag ON transaction_tags(tag);
+
+CREATE TABLE IF NOT EXISTS holds (
+    id SERIAL PRIMARY KEY,
+    wallet_id INT NOT NULL REFERENCES wallets(id),
+    amount NUMERIC(20, 2) NOT NULL,
+    captured_amount NUMERIC(20, 2) NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL,            -- active, captured, released, expired
+    description VARCHAR(255),
+    transaction_id INT REFERENCES transactions(id), -- withdraw transaction created on capture
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS holds_wallet_id_active ON holds(wallet_id) WHERE status = 'active';
+
+CREATE TABLE IF NOT EXISTS scheduled_transfers (
+    id SERIAL PRIMARY KEY,
+    source_wallet_id INT NOT NULL REFERENCES wallets(id),
+    destination_wallet_id INT REFERENCES wallets(id),   -- one of destination_wallet_id
+    destination_user_id INT REFERENCES users(id),       -- or destination_user_id is set
+    amount NUMERIC(20, 2) NOT NULL,
+    description VARCHAR(255),
+    external_reference VARCHAR(64),
+    frequency VARCHAR(20) NOT NULL,                     -- once, daily, weekly, monthly
+    start_at TIMESTAMP NOT NULL,
+    end_at TIMESTAMP,
+    max_occurrences INT,
+    occurrences INT NOT NULL DEFAULT 0,                 -- occurrences handled so far, executed or skipped
+    next_run_at TIMESTAMP,                              -- next occurrence, NULL once completed
+    retry_at TIMESTAMP,                                 -- set while the next occurrence waits for a retry
+    attempts INT NOT NULL DEFAULT 0,                    -- failed attempts of the next occurrence
+    on_insufficient_funds VARCHAR(10) NOT NULL,         -- retry, skip
+    status VARCHAR(20) NOT NULL,                        -- active, paused, completed, cancelled
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS scheduled_transfers_due ON scheduled_transfers(COALESCE(retry_at, next_run_at)) WHERE status = 'active';
+
+CREATE TABLE IF NOT EXISTS scheduled_transfer_runs (
+    id SERIAL PRIMARY KEY,
+    scheduled_transfer_id INT NOT NULL REFERENCES scheduled_transfers(id),
+    scheduled_for TIMESTAMP NOT NULL,   -- the occurrence this run executed
+    attempt INT NOT NULL,
+    status VARCHAR(20) NOT NULL,        -- succeeded, retry_scheduled, skipped, failed
+    transfer_id INT REFERENCES transfers(id),
+    error_code VARCHAR(64),
+    error_message TEXT,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
-CREATE TABLE IF NOT EXISTS ccy_conversion (
+CREATE INDEX IF NOT EXISTS scheduled_transfer_runs_schedule ON scheduled_transfer_runs(scheduled_transfer_id);
+
+CREATE TABLE IF NOT EXISTS fee_rules (
+    id SERIAL PRIMARY KEY,
+    fee_type VARCHAR(20) NOT NULL,                  -- withdraw, transfer, conversion
+    from_ccy TEXT,                                  -- NULL matches any source currency
+    to_ccy TEXT,                                    -- NULL matches any target currency
+    wallet_type TEXT,                               -- NULL matches any source wallet type
+    min_amount NUMERIC(20, 2) NOT NULL DEFAULT 0,   -- tier: the rule applies from this amount up
+    flat_fee NUMERIC(20, 2) NOT NULL DEFAULT 0,
+    percentage NUMERIC(10, 4) NOT NULL DEFAULT 0,   -- of the amount, e.g. 0.5 for 0.5%
+    min_fee NUMERIC(20, 2),
+    max_fee NUMERIC(20, 2),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    -- amounts are in the source currency, a rule matching any source currency can only be a percentage
+    CONSTRAINT fee_rules_amount_currency CHECK (
+        from_ccy IS NOT NULL OR (min_amount = 0 AND flat_fee = 0 AND min_fee IS NULL AND max_fee IS NULL))
+);
+
+CREATE TABLE IF NOT EXISTS fees (
+    id SERIAL PRIMARY KEY,
+    transaction_id INT NOT NULL REFERENCES transactions(id),   -- the transaction the fee is charged for
+    fee_rule_id INT NOT NULL REFERENCES fee_rules(id),
+    amount NUMERIC(20, 2) NOT NULL,
+    flat_fee NUMERIC(20, 2) NOT NULL,
+    percentage_fee NUMERIC(20, 2) NOT NULL,
+    house_wallet_id INT NOT NULL REFERENCES wallets(id),
+    debit_transaction_id INT REFERENCES transactions(id),      -- fee on the paying wallet, NULL until charged
+    credit_transaction_id INT REFERENCES transactions(id),     -- fee-income on the house wallet, NULL until charged
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS fees_transaction ON fees(transaction_id);
+
+CREATE TABLE IF NOT EXISTS fx_quotes (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id),     -- the user the quote is priced for, the only one who can use it
     from_ccy TEXT NOT NULL,
     to_ccy TEXT NOT NULL,
-    rate NUMERIC(20, 6) NOT NULL,
+    rate NUMERIC(20, 10) NOT NULL,                 -- to_ccy amount per unit of from_ccy, locked until expires_at
+    mid_rate NUMERIC(20, 10) NOT NULL,             -- mid rate the spread was taken from
+    side VARCHAR(10) NOT NULL,                     -- bid, ask or mid when no spread applies
+    spread NUMERIC(10, 4) NOT NULL,                -- percentage of the mid rate
+    rate_path TEXT NOT NULL,                       -- currencies the mid rate was resolved through, e.g., EUR>USD>BTC
+    status VARCHAR(20) NOT NULL DEFAULT 'open',    -- open, used
+    transfer_id INT REFERENCES transfers(id),      -- the transfer that used the quote
+    expires_at TIMESTAMP NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    used_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS conversions (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id),
+    from_wallet_id INT NOT NULL REFERENCES wallets(id),
+    to_wallet_id INT NOT NULL REFERENCES wallets(id),
+    from_amount NUMERIC(20, 2) NOT NULL,
+    to_amount NUMERIC(20, 2) NOT NULL,
+    mid_rate NUMERIC(20, 10) NOT NULL,
+    rate NUMERIC(20, 10) NOT NULL,                              -- mid rate less the spread, given to the user
+    spread NUMERIC(10, 4) NOT NULL,                             -- percentage of the mid rate, bid or ask
+    spread_amount NUMERIC(20, 2) NOT NULL,                      -- taken by the spread, in the to currency
+    out_transaction_id INT NOT NULL REFERENCES transactions(id), -- conversion-out on the from wallet
+    in_transaction_id INT NOT NULL REFERENCES transactions(id),  -- conversion-in on the to wallet
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS conversions_out_transaction ON conversions(out_transaction_id);
+CREATE INDEX IF NOT EXISTS conversions_in_transaction ON conversions(in_transaction_id);
+
+-- A customer selling base_ccy for quote_ccy gets the bid, the mid rate less bid_spread percent;
+-- a customer buying base_ccy with quote_ccy pays the ask, the mid rate plus ask_spread percent.
+-- A NULL selector matches any value, the rule with the most selectors set wins.
+CREATE TABLE IF NOT EXISTS fx_spreads (
+    id SERIAL PRIMARY KEY,
+    base_ccy TEXT,                  -- NULL for any currency
+    quote_ccy TEXT,                 -- NULL for any currency
+    tier VARCHAR(20),               -- NULL for any customer tier
+    bid_spread NUMERIC(10, 4) NOT NULL,
+    ask_spread NUMERIC(10, 4) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS fx_spread_revenue (
+    id SERIAL PRIMARY KEY,
+    transaction_id INT NOT NULL REFERENCES transactions(id),   -- transfer-out or conversion-out leg of the exchange
+    currency TEXT NOT NULL,                                     -- currency received by the customer
+    mid_rate NUMERIC(20, 10) NOT NULL,
+    rate NUMERIC(20, 10) NOT NULL,
+    amount NUMERIC(20, 2) NOT NULL,                             -- value at the mid rate less the amount received
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS fx_spread_revenue_transaction ON fx_spread_revenue(transaction_id);
+CREATE INDEX IF NOT EXISTS fx_spread_revenue_created_at ON fx_spread_revenue(created_at);
+
+CREATE TABLE IF NOT EXISTS ccy_conversion (
+    from_ccy TEXT NOT NULL,
+    to_ccy TEXT NOT NULL,
+    rate NUMERIC(20, 10) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,  -- when the rate was last set, see fx.max_rate_age
     PRIMARY KEY (from_ccy, to_ccy)
-);
\ No newline at end of file
+);
+
+-- Every rate set in ccy_conversion, to value balances at the rates known in the past.
+CREATE TABLE IF NOT EXISTS ccy_conversion_history (
+    id SERIAL PRIMARY KEY,
+    from_ccy TEXT NOT NULL,
+    to_ccy TEXT NOT NULL,
+    rate NUMERIC(20, 10) NOT NULL,
+    created_at TIMESTAMP NOT NULL       -- when the rate was set
+);
+
+CREATE INDEX IF NOT EXISTS ccy_conversion_history_pair ON ccy_conversion_history(from_ccy, to_ccy, created_at);
\ No newline at end of file
@@ -9,12 +9,16 @@ VALUES
   ('USD', 'CAD', 1.36),
   ('USD', 'BTC', 0.000010);
 
-INSERT INTO users (id, name)
+INSERT INTO ccy_conversion_history (from_ccy, to_ccy, rate, created_at)
+SELECT from_ccy, to_ccy, rate, created_at FROM ccy_conversion;
+
+INSERT INTO users (id, name, tier, reporting_currency)
 VALUES
-    (1, 'Alice'),
-    (2, 'Bob'),
-    (3, 'Charlie'),
-    (4, 'Danny');
+    (1, 'Alice', 'standard', 'USD'),
+    (2, 'Bob', 'premium', 'USD'),
+    (3, 'Charlie', 'standard', 'JPY'),
+    (4, 'Danny', 'standard', 'SGD'),
+    (5, 'House', 'house', 'USD');
 
 INSERT INTO wallets (user_id, balance, currency, type, is_default)
 VALUES
@@ -26,5 +30,46 @@ VALUES
     (3, 0, 'JPY', 'saving', false),
     (3, 0, 'USD', 'saving', true),
     (4, 0, 'USD', 'saving', false),
-    (4, 0, 'SGD', 'trading', true);
-    
\ No newline at end of file
+    (4, 0, 'SGD', 'trading', true),
+    (5, 0, 'USD', 'house', true),
+    (5, 0, 'SGD', 'house', false),
+    (5, 0, 'AUD', 'house', false),
+    (5, 0, 'JPY', 'house', false),
+    (5, 0, 'BTC', 'house', false),
+    (5, 0, 'EUR', 'house', false),
+    (5, 0, 'GBP', 'house', false),
+    (5, 0, 'CHF', 'house', false),
+    (5, 0, 'CAD', 'house', false);
+
+INSERT INTO fee_rules (fee_type, from_ccy, to_ccy, wallet_type, min_amount, flat_fee, percentage, min_fee, max_fee)
+VALUES
+    ('withdraw', NULL, NULL, NULL, 0, 0, 0.1, NULL, NULL),
+    ('withdraw', 'USD', NULL, NULL, 0, 1.00, 0, NULL, NULL),
+    ('withdraw', 'SGD', NULL, NULL, 0, 1.35, 0, NULL, NULL),
+    ('withdraw', 'EUR', NULL, NULL, 0, 0.92, 0, NULL, NULL),
+    ('withdraw', 'JPY', NULL, NULL, 0, 155, 0, NULL, NULL),
+    ('withdraw', 'GBP', NULL, NULL, 0, 0.79, 0, NULL, NULL),
+    ('withdraw', 'AUD', NULL, NULL, 0, 1.52, 0, NULL, NULL),
+    ('withdraw', 'CHF', NULL, NULL, 0, 0.91, 0, NULL, NULL),
+    ('withdraw', 'CAD', NULL, NULL, 0, 1.36, 0, NULL, NULL),
+    ('withdraw', 'USD', NULL, 'trading', 0, 0, 0.5, 1.00, 25.00),
+    ('withdraw', 'USD', NULL, 'trading', 10000, 0, 0.25, NULL, 25.00),
+    ('transfer', 'USD', 'SGD', NULL, 0, 0, 0.75, 0.50, NULL),
+    ('conversion', NULL, NULL, NULL, 0, 0, 1.0, NULL, NULL),
+    ('conversion', 'USD', NULL, NULL, 0, 0, 1.0, 0.50, NULL),
+    ('conversion', 'SGD', NULL, NULL, 0, 0, 1.0, 0.68, NULL),
+    ('conversion', 'EUR', NULL, NULL, 0, 0, 1.0, 0.46, NULL),
+    ('conversion', 'JPY', NULL, NULL, 0, 0, 1.0, 78, NULL),
+    ('conversion', 'GBP', NULL, NULL, 0, 0, 1.0, 0.40, NULL),
+    ('conversion', 'AUD', NULL, NULL, 0, 0, 1.0, 0.76, NULL),
+    ('conversion', 'CHF', NULL, NULL, 0, 0, 1.0, 0.46, NULL),
+    ('conversion', 'CAD', NULL, NULL, 0, 0, 1.0, 0.68, NULL),
+    ('conversion', 'USD', 'BTC', NULL, 0, 0, 1.5, 1.00, 100.00);
+
+INSERT INTO fx_spreads (base_ccy, quote_ccy, tier, bid_spread, ask_spread)
+VALUES
+    (NULL, NULL, NULL, 0.5, 0.5),
+    (NULL, NULL, 'premium', 0.25, 0.25),
+    (NULL, NULL, 'house', 0, 0),
+    ('BTC', NULL, NULL, 1.0, 1.5),
+    ('BTC', NULL, 'premium', 0.5, 0.75);
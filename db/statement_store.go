@@ -0,0 +1,131 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// GetStatementEntries returns the balance events of the wallet from from up to, and excluding, until, oldest
+// first, with the details of their transaction: the currency of the counterparty wallet and the rate of an
+// exchange, and the transaction a fee was charged for.
+func GetStatementEntries(db *sql.DB, walletId int64, from time.Time, until time.Time) ([]models.StatementEntry, error) {
+	query := `
+		SELECT e.id, t.type, e.at, e.delta, e.reversal, COALESCE(t.description, ''), COALESCE(t.external_reference, ''),
+			t.counterparty_wallet_id, COALESCE(cw.currency, ''), t.rate, f.transaction_id
+		FROM (` + balanceEventsQuery + `) e
+		JOIN transactions t ON t.id = e.id
+		LEFT JOIN wallets cw ON cw.id = t.counterparty_wallet_id
+		LEFT JOIN fees f ON t.id IN (f.debit_transaction_id, f.credit_transaction_id)
+		WHERE e.wallet_id = $1 AND e.at >= $2 AND e.at < $3
+		ORDER BY e.at, e.id, e.reversal
+	`
+
+	rows, err := db.Query(query, walletId, from, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.StatementEntry
+	for rows.Next() {
+		var e models.StatementEntry
+		var counterpartyWalletId, feeFor sql.NullInt64
+		if err := rows.Scan(&e.TransactionID, &e.Type, &e.At, &e.Amount, &e.Reversal, &e.Description, &e.ExternalReference,
+			&counterpartyWalletId, &e.CounterCcy, &e.Rate, &feeFor); err != nil {
+			return nil, err
+		}
+		if counterpartyWalletId.Valid {
+			e.CounterpartyWalletID = &counterpartyWalletId.Int64
+		}
+		if feeFor.Valid {
+			e.FeeFor = &feeFor.Int64
+		}
+		entries = append(entries, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetStatement returns the statement of the wallet stored for the month starting at period, or nil when none is.
+func GetStatement(db *sql.DB, walletId int64, period time.Time) (*models.Statement, error) {
+	query := `SELECT statement FROM wallet_statements WHERE wallet_id = $1 AND period = $2`
+
+	var content []byte
+	err := db.QueryRow(query, walletId, period).Scan(&content)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var s models.Statement
+	if err := json.Unmarshal(content, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// SaveStatement stores the statement unless one is already stored for the same wallet and month,
+// and reports whether it was stored.
+func SaveStatement(db *sql.DB, s models.Statement) (bool, error) {
+	content, err := json.Marshal(s)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		INSERT INTO wallet_statements (wallet_id, period, opening_balance, closing_balance, statement, generated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (wallet_id, period) DO NOTHING
+	`
+	result, err := db.Exec(query, s.WalletID, s.Period, s.OpeningBalance, s.ClosingBalance, content, s.GeneratedAt)
+	if err != nil {
+		return false, err
+	}
+
+	stored, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return stored == 1, nil
+}
+
+// GetWalletsWithoutStatement returns the wallets created before until that have no statement stored for the
+// month starting at period, until being the end of that month.
+func GetWalletsWithoutStatement(db *sql.DB, period time.Time, until time.Time) ([]models.Wallet, error) {
+	query := `
+		SELECT w.id, w.user_id, w.balance, w.currency, w.type, w.is_default, w.created_at
+		FROM wallets w
+		WHERE w.created_at < $2
+		AND NOT EXISTS (SELECT 1 FROM wallet_statements s WHERE s.wallet_id = w.id AND s.period = $1)
+		ORDER BY w.id
+	`
+
+	rows, err := db.Query(query, period, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var wallets []models.Wallet
+	for rows.Next() {
+		var w models.Wallet
+		if err := rows.Scan(&w.ID, &w.UserId, &w.Balance, &w.Currency, &w.Type, &w.IsDefault, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		wallets = append(wallets, w)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return wallets, nil
+}
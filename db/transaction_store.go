@@ -5,16 +5,118 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/rudithu/CRYPTO-WalletApp/models"
 )
 
-func GetTransactionsByWalletIDs(db *sql.DB, walletIDs []int64) ([]models.Transaction, error) {
+// transactionColumns is the column list read by scanTransaction.
+// Tags are aggregated into a comma separated list, tags never contain commas.
+const transactionColumns = `id, wallet_id, type, amount, counterparty_wallet_id, created_at, description, external_reference, transfer_id,
+	status, completed_at, failed_at, reversed_at, rate,
+	(SELECT string_agg(tt.tag, ',' ORDER BY tt.tag) FROM transaction_tags tt WHERE tt.transaction_id = transactions.id) AS tags`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTransaction(row rowScanner) (models.Transaction, error) {
+	var t models.Transaction
+	var tags sql.NullString
+	err := row.Scan(
+		&t.ID,
+		&t.WalletId,
+		&t.Type,
+		&t.Amount,
+		&t.CounterpartyWalletId,
+		&t.CreatedAt,
+		&t.Description,
+		&t.ExternalReference,
+		&t.TransferId,
+		&t.Status,
+		&t.CompletedAt,
+		&t.FailedAt,
+		&t.ReversedAt,
+		&t.Rate,
+		&tags,
+	)
+	if err != nil {
+		return t, err
+	}
+	if tags.Valid && tags.String != "" {
+		t.Tags = strings.Split(tags.String, ",")
+	}
+	return t, nil
+}
+
+// GetTransactionsByWalletIDs returns the transactions of the given wallets, newest first,
+// narrowed down by the optional filter.
+func GetTransactionsByWalletIDs(db *sql.DB, walletIDs []int64, filter models.TransactionFilter) ([]models.Transaction, error) {
 
 	if walletIDs == nil {
 		return nil, nil
 	}
 
+	query, args := transactionsByWalletIDsQuery(walletIDs, filter, "created_at DESC")
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var transactions []models.Transaction
+
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+// StreamTransactionsByWalletIDs passes the transactions of the given wallets, oldest first, narrowed down by
+// the optional filter, to fn as they are read, so that long histories are never held in memory at once.
+// It stops at the first error returned by fn and returns it.
+func StreamTransactionsByWalletIDs(db *sql.DB, walletIDs []int64, filter models.TransactionFilter, fn func(models.Transaction) error) error {
+	if walletIDs == nil {
+		return nil
+	}
+
+	query, args := transactionsByWalletIDsQuery(walletIDs, filter, "created_at, id")
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// likeEscaper escapes the LIKE wildcards and the escape character, so a search matches them literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// transactionsByWalletIDsQuery builds the query selecting the transactions of the given wallets that match filter, sorted by order.
+func transactionsByWalletIDsQuery(walletIDs []int64, filter models.TransactionFilter, order string) (string, []interface{}) {
 	placeholders := make([]string, len(walletIDs))
 	args := make([]interface{}, len(walletIDs))
 
@@ -23,53 +125,256 @@ func GetTransactionsByWalletIDs(db *sql.DB, walletIDs []int64) ([]models.Transac
 		args[i] = id
 	}
 
+	var conditions []string
+	if filter.Query != "" {
+		args = append(args, "%"+likeEscaper.Replace(filter.Query)+"%")
+		conditions = append(conditions, fmt.Sprintf(`AND (description ILIKE $%d ESCAPE '\' OR external_reference ILIKE $%d ESCAPE '\')`, len(args), len(args)))
+	}
+	for _, tag := range filter.Tags {
+		args = append(args, tag)
+		conditions = append(conditions, fmt.Sprintf("AND EXISTS (SELECT 1 FROM transaction_tags f WHERE f.transaction_id = transactions.id AND f.tag = $%d)", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("AND status = $%d", len(args)))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		conditions = append(conditions, fmt.Sprintf("AND created_at >= $%d", len(args)))
+	}
+	if !filter.Until.IsZero() {
+		args = append(args, filter.Until)
+		conditions = append(conditions, fmt.Sprintf("AND created_at < $%d", len(args)))
+	}
+
 	query := fmt.Sprintf(`
-        SELECT id, wallet_id, type, amount, counterparty_wallet_id, created_at
+        SELECT %s
         FROM transactions
         WHERE wallet_id in (%s)
-        ORDER BY created_at DESC
-		`, strings.Join(placeholders, ", "))
+        %s
+        ORDER BY %s
+		`, transactionColumns, strings.Join(placeholders, ", "), strings.Join(conditions, " "), order)
+	return query, args
+}
 
-	rows, err := db.Query(query, args...)
+func GetTransactionById(db *sql.DB, id int64) (*models.Transaction, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM transactions
+		WHERE id = $1
+	`, transactionColumns)
+
+	t, err := scanTransaction(db.QueryRow(query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
 		return nil, err
 	}
+	return &t, nil
+}
+
+// lockTransaction reads the transaction with a row lock for the rest of the DB transaction.
+func lockTransaction(tx *sql.Tx, id int64) (*models.Transaction, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM transactions
+		WHERE id = $1
+		FOR UPDATE
+	`, transactionColumns)
+
+	t, err := scanTransaction(tx.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// txnStatusTimestampColumns maps a status to the column recording when the transaction reached it.
+var txnStatusTimestampColumns = map[string]string{
+	models.TxnStatusCompleted: "completed_at",
+	models.TxnStatusFailed:    "failed_at",
+	models.TxnStatusReversed:  "reversed_at",
+}
+
+// updateTransactionStatus sets the status of the transaction and stamps the time of the transition.
+func updateTransactionStatus(tx *sql.Tx, t *models.Transaction, status string) error {
+	column, ok := txnStatusTimestampColumns[status]
+	if !ok {
+		return fmt.Errorf("unknown transaction status %q", status)
+	}
+
+	query := fmt.Sprintf(`UPDATE transactions SET status = $1, %[1]s = CURRENT_TIMESTAMP WHERE id = $2 RETURNING %[1]s`, column)
+
+	var at sql.NullTime
+	err := tx.QueryRow(query, status, t.ID).Scan(&at)
+	if err != nil {
+		return err
+	}
+
+	t.Status = status
+	switch status {
+	case models.TxnStatusCompleted:
+		t.CompletedAt = at
+	case models.TxnStatusFailed:
+		t.FailedAt = at
+	case models.TxnStatusReversed:
+		t.ReversedAt = at
+	}
+	return nil
+}
+
+// UpdateTransactionNotes replaces the description and/or the tags of a transaction.
+// Fields left nil in notes are not changed.
+func UpdateTransactionNotes(db *sql.DB, id int64, notes models.TransactionNotesRequest) error {
+	return withTx(db, func(tx *sql.Tx) error {
+		if notes.Description != nil {
+			_, err := tx.Exec(`UPDATE transactions SET description = $1 WHERE id = $2`, models.NullString(*notes.Description), id)
+			if err != nil {
+				return fmt.Errorf("failed to update description: %w", err)
+			}
+		}
+
+		if notes.Tags != nil {
+			_, err := tx.Exec(`DELETE FROM transaction_tags WHERE transaction_id = $1`, id)
+			if err != nil {
+				return fmt.Errorf("failed to delete tags: %w", err)
+			}
+			if err = createTransactionTags(tx, id, *notes.Tags); err != nil {
+				return fmt.Errorf("failed to create tags: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetPendingBalancesByWalletIDs returns the totals of the pending deposits and withdrawals, keyed by wallet ID.
+// Wallets without pending transactions are not part of the result.
+func GetPendingBalancesByWalletIDs(db *sql.DB, walletIDs []int64) (map[int64]models.PendingBalance, error) {
+
+	if len(walletIDs) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(walletIDs))
+	args := make([]interface{}, len(walletIDs))
+
+	for i, id := range walletIDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
 
+	query := fmt.Sprintf(`
+		SELECT wallet_id,
+			COALESCE(SUM(amount) FILTER (WHERE type = 'deposit'), 0),
+			COALESCE(SUM(amount) FILTER (WHERE type = 'withdraw'), 0)
+		FROM transactions
+		WHERE wallet_id IN (%s) AND status = 'pending'
+		GROUP BY wallet_id
+	`, strings.Join(placeholders, ", "))
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
-	var transactions []models.Transaction
+	pending := make(map[int64]models.PendingBalance)
+	for rows.Next() {
+		var walletId int64
+		var p models.PendingBalance
+		if err := rows.Scan(&walletId, &p.Incoming, &p.Outgoing); err != nil {
+			return nil, err
+		}
+		pending[walletId] = p
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// GetTransferCounterparties returns, for each given transfer transaction, the counterparty
+// wallet with its owner, the opposite leg and the status of the transfer, keyed by transaction ID.
+// Transactions without a counterparty wallet are not part of the result.
+func GetTransferCounterparties(db *sql.DB, txnIDs []int64) (map[int64]models.TransferCounterparty, error) {
+
+	if len(txnIDs) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(txnIDs))
+	args := make([]interface{}, len(txnIDs))
+
+	for i, id := range txnIDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+
+	// Both legs of a transfer reference the same row in transfers,
+	// which is used to find the opposite leg.
+	query := fmt.Sprintf(`
+		SELECT t.id, o.id, o.amount, w.id, w.currency, u.id, u.name, tr.status, tr.reversal_of
+		FROM transactions t
+		JOIN wallets w ON w.id = t.counterparty_wallet_id
+		JOIN users u ON u.id = w.user_id
+		LEFT JOIN transfers tr ON tr.id = t.transfer_id
+		LEFT JOIN transactions o ON o.transfer_id = t.transfer_id
+			AND o.wallet_id = t.counterparty_wallet_id
+			AND o.type <> t.type
+		WHERE t.id IN (%s)
+		ORDER BY t.id, o.id
+		`, strings.Join(placeholders, ", "))
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
+	counterparties := make(map[int64]models.TransferCounterparty)
 	for rows.Next() {
-		var t models.Transaction
+		var cp models.TransferCounterparty
 		err := rows.Scan(
-			&t.ID,
-			&t.WalletId,
-			&t.Type,
-			&t.Amount,
-			&t.CounterpartyWalletId,
-			&t.CreatedAt,
+			&cp.TransactionID,
+			&cp.LinkedTransactionID,
+			&cp.LinkedAmount,
+			&cp.WalletID,
+			&cp.Currency,
+			&cp.UserID,
+			&cp.UserName,
+			&cp.TransferStatus,
+			&cp.ReversalOf,
 		)
 		if err != nil {
 			return nil, err
 		}
-		transactions = append(transactions, t)
+		// keep the first match when several legs qualify
+		if _, ok := counterparties[cp.TransactionID]; !ok {
+			counterparties[cp.TransactionID] = cp
+		}
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
-
-	return transactions, nil
+	return counterparties, nil
 }
 
+// createTransaction inserts the transaction, as completed unless its status says otherwise.
 func createTransaction(tx *sql.Tx, t *models.Transaction) error {
+	if t.Status == "" {
+		t.Status = models.TxnStatusCompleted
+	}
+
 	query := `
-		INSERT INTO transactions (wallet_id, type, amount, counterparty_wallet_id)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at
+		INSERT INTO transactions (wallet_id, type, amount, counterparty_wallet_id, description, external_reference, transfer_id, status, rate, completed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, CASE WHEN $8 = 'completed' THEN CURRENT_TIMESTAMP END)
+		RETURNING id, created_at, completed_at
 	`
 	err := tx.QueryRow(
 		query,
@@ -77,7 +382,95 @@ func createTransaction(tx *sql.Tx, t *models.Transaction) error {
 		t.Type,
 		t.Amount,
 		t.CounterpartyWalletId,
-	).Scan(&t.ID, &t.CreatedAt)
+		t.Description,
+		t.ExternalReference,
+		t.TransferId,
+		t.Status,
+		t.Rate,
+	).Scan(&t.ID, &t.CreatedAt, &t.CompletedAt)
+	if err != nil {
+		return err
+	}
+
+	return createTransactionTags(tx, t.ID, t.Tags)
+}
+
+// createTransactions inserts the transactions in a single statement, as completed unless their status says otherwise.
+// Tags are not inserted.
+func createTransactions(tx *sql.Tx, txns []*models.Transaction) error {
+	ids, err := reserveIDs(tx, "transactions", len(txns))
+	if err != nil {
+		return err
+	}
+
+	placeholders := make([]string, len(txns))
+	args := make([]interface{}, 0, 10*len(txns))
+	byId := make(map[int64]*models.Transaction, len(txns))
+
+	for i, t := range txns {
+		if t.Status == "" {
+			t.Status = models.TxnStatusCompleted
+		}
+		t.ID = ids[i]
+		byId[t.ID] = t
+		n := 10 * i
+		placeholders[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, CASE WHEN $%d = 'completed' THEN CURRENT_TIMESTAMP END)",
+			n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9, n+10, n+9)
+		args = append(args, t.ID, t.WalletId, t.Type, t.Amount, t.CounterpartyWalletId, t.Description, t.ExternalReference, t.TransferId, t.Status, t.Rate)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO transactions (id, wallet_id, type, amount, counterparty_wallet_id, description, external_reference, transfer_id, status, rate, completed_at)
+		VALUES %s
+		RETURNING id, created_at, completed_at
+	`, strings.Join(placeholders, ", "))
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var createdAt time.Time
+		var completedAt sql.NullTime
+		if err := rows.Scan(&id, &createdAt, &completedAt); err != nil {
+			return err
+		}
+		t, ok := byId[id]
+		if !ok {
+			return fmt.Errorf("transaction %d returned but not inserted", id)
+		}
+		t.CreatedAt = createdAt
+		t.CompletedAt = completedAt
+		delete(byId, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	if len(byId) != 0 {
+		return fmt.Errorf("%d transactions inserted but not returned", len(byId))
+	}
+	return nil
+}
+
+func createTransactionTags(tx *sql.Tx, txnID int64, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(tags))
+	args := make([]interface{}, 0, len(tags)+1)
+	args = append(args, txnID)
+
+	for i, tag := range tags {
+		placeholders[i] = fmt.Sprintf("($1, $%d)", i+2)
+		args = append(args, tag)
+	}
 
+	query := fmt.Sprintf(`INSERT INTO transaction_tags (transaction_id, tag) VALUES %s`, strings.Join(placeholders, ", "))
+	_, err := tx.Exec(query, args...)
 	return err
 }
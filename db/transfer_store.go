@@ -0,0 +1,150 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
+)
+
+// transferColumns is the column list read by scanTransfer, from transfers joined
+// with the source wallet (sw) and the target wallet (tw).
+const transferColumns = `t.id, t.source_wallet_id, t.target_wallet_id, t.source_amount, t.target_amount, t.rate, t.rate_path, t.status, t.created_at,
+	t.reversal_of, t.reversed_amount, sw.currency, tw.currency,
+	(SELECT o.id FROM transactions o WHERE o.transfer_id = t.id AND o.type = 'transfer-out') AS source_transaction_id,
+	(SELECT i.id FROM transactions i WHERE i.transfer_id = t.id AND i.type = 'transfer-in') AS target_transaction_id`
+
+func scanTransfer(row rowScanner) (models.Transfer, error) {
+	var t models.Transfer
+	err := row.Scan(
+		&t.ID,
+		&t.SourceWalletId,
+		&t.TargetWalletId,
+		&t.SourceAmount,
+		&t.TargetAmount,
+		&t.Rate,
+		&t.RatePath,
+		&t.Status,
+		&t.CreatedAt,
+		&t.ReversalOf,
+		&t.ReversedAmount,
+		&t.SourceCurrency,
+		&t.TargetCurrency,
+		&t.SourceTransactionId,
+		&t.TargetTransactionId,
+	)
+	return t, err
+}
+
+func GetTransferById(db *sql.DB, id int64) (*models.Transfer, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM transfers t
+		JOIN wallets sw ON sw.id = t.source_wallet_id
+		JOIN wallets tw ON tw.id = t.target_wallet_id
+		WHERE t.id = $1
+	`, transferColumns)
+
+	t, err := scanTransfer(db.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetTransfersByWalletIDs returns the transfers sent from or received by any of the given wallets, newest first.
+func GetTransfersByWalletIDs(db *sql.DB, walletIDs []int64) ([]models.Transfer, error) {
+
+	if len(walletIDs) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(walletIDs))
+	args := make([]interface{}, len(walletIDs))
+
+	for i, id := range walletIDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM transfers t
+		JOIN wallets sw ON sw.id = t.source_wallet_id
+		JOIN wallets tw ON tw.id = t.target_wallet_id
+		WHERE t.source_wallet_id IN (%s) OR t.target_wallet_id IN (%s)
+		ORDER BY t.created_at DESC, t.id DESC
+	`, transferColumns, strings.Join(placeholders, ", "), strings.Join(placeholders, ", "))
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transfers []models.Transfer
+	for rows.Next() {
+		t, err := scanTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+func createTransfer(tx *sql.Tx, t *models.Transfer) error {
+	query := `
+		INSERT INTO transfers (source_wallet_id, target_wallet_id, source_amount, target_amount, rate, rate_path, status, reversal_of)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`
+	return tx.QueryRow(
+		query,
+		t.SourceWalletId,
+		t.TargetWalletId,
+		t.SourceAmount,
+		t.TargetAmount,
+		t.Rate,
+		t.RatePath,
+		t.Status,
+		t.ReversalOf,
+	).Scan(&t.ID, &t.CreatedAt)
+}
+
+// getReversibleAmount locks the transfer for the rest of the DB transaction and returns
+// the part of its source amount that has not been reversed yet.
+// It returns nil when the transfer does not exist.
+func getReversibleAmount(tx *sql.Tx, transferId int64) (*decimal.Decimal, error) {
+	var amount decimal.Decimal
+	err := tx.QueryRow(`SELECT source_amount - reversed_amount FROM transfers WHERE id = $1 FOR UPDATE`, transferId).Scan(&amount)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &amount, nil
+}
+
+// addReversedAmount adds amount to the reversed amount of the transfer and updates its status accordingly.
+func addReversedAmount(tx *sql.Tx, transferId int64, amount decimal.Decimal) error {
+	query := `
+		UPDATE transfers
+		SET reversed_amount = reversed_amount + $1,
+			status = CASE WHEN reversed_amount + $1 >= source_amount THEN $2 ELSE $3 END
+		WHERE id = $4
+	`
+	_, err := tx.Exec(query, amount, models.TransferStatusReversed, models.TransferStatusPartiallyReversed, transferId)
+	return err
+}
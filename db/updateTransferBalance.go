@@ -6,6 +6,7 @@ import (
 	"log"
 
 	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
 )
 
 // DepositUpdate handles the deposit transaction by wrapping depositInternal within a DB transaction.
@@ -24,28 +25,200 @@ func WithdrawUpdate(db *sql.DB, txn *models.Transaction) error {
 
 // TransferUpdate handles the transfer transaction, performing a withdrawal from source wallet
 // and deposit to target wallet atomically within a DB transaction.
-func TransferUpdate(db *sql.DB, srcTxn *models.Transaction, targetTxn *models.Transaction) error {
+// The transfer record is created first and both transactions are linked to it.
+func TransferUpdate(db *sql.DB, transfer *models.Transfer, srcTxn *models.Transaction, targetTxn *models.Transaction) error {
 	return withTx(db, func(tx *sql.Tx) error {
-		// Withdraw from source wallet
-		err := withdrawInternal(tx, srcTxn)
+		return transferInternal(tx, transfer, srcTxn, targetTxn)
+	})
+}
+
+// ReverseTransferUpdate performs the reversal transfer and adds its target amount to the
+// reversed amount of the original transfer atomically within a DB transaction.
+// The original transfer is locked so concurrent reversals cannot refund more than was sent.
+func ReverseTransferUpdate(db *sql.DB, originalId int64, reversal *models.Transfer, srcTxn *models.Transaction, targetTxn *models.Transaction) error {
+	return withTx(db, func(tx *sql.Tx) error {
+		reversible, err := getReversibleAmount(tx, originalId)
+		if err != nil {
+			log.Printf("ERROR: failed to get reversible amount for transfer Id: %d", originalId)
+			return fmt.Errorf("failed to get reversible amount: %w", err)
+		}
+
+		if reversible == nil {
+			return models.Errorf(models.ErrCodeTransferNotFound, "transfer %d not found", originalId)
+		}
+
+		if reversible.LessThan(reversal.TargetAmount) {
+			log.Printf("ERROR: transfer Id: %d does not have enough amount left to reverse", originalId)
+			return models.Errorf(models.ErrCodeNotReversible, "transfer %d does not have enough amount left to reverse", originalId).
+				WithDetails(map[string]string{"reversible": reversible.String(), "requested": reversal.TargetAmount.String()})
+		}
+
+		reversal.ReversalOf = sql.NullInt64{Int64: originalId, Valid: true}
+		err = transferInternal(tx, reversal, srcTxn, targetTxn)
 		if err != nil {
 			return err
 		}
 
-		// Deposit to target wallet
-		err = depositInternal(tx, targetTxn)
+		err = addReversedAmount(tx, originalId, reversal.TargetAmount)
+		if err != nil {
+			log.Printf("ERROR: failed to update reversed amount for transfer Id: %d", originalId)
+			return fmt.Errorf("failed to update reversed amount: %w", err)
+		}
+		return nil
+	})
+}
+
+// UpdateTransactionStatus moves a deposit or withdrawal to status and applies its balance effect
+// atomically within a DB transaction:
+//   - pending to completed credits a deposit or debits a withdrawal and charges its fee,
+//   - pending to failed releases the amount reserved by a pending withdrawal,
+//   - completed to reversed undoes the balance effect of the transaction.
+//
+// Only deposits and withdrawals can change status. Transfer transactions cannot change status on their own,
+// the transfer is reversed instead; conversion and fee legs never change status, since each is one side of a pair.
+// txn is updated with the result; BalanceAfter is set when the balance changed.
+func UpdateTransactionStatus(db *sql.DB, txn *models.Transaction, status string) error {
+	return withTx(db, func(tx *sql.Tx) error {
+		locked, err := lockTransaction(tx, txn.ID)
+		if err != nil {
+			log.Printf("ERROR: failed to get transaction Id: %d", txn.ID)
+			return fmt.Errorf("failed to get transaction: %w", err)
+		}
+
+		if locked == nil {
+			return models.Errorf(models.ErrCodeTxnNotFound, "transaction %d not found", txn.ID)
+		}
+		*txn = *locked
+
+		if txn.TransferId.Valid {
+			return models.Errorf(models.ErrCodeInvalidTransition, "transaction %d belongs to transfer %d, reverse the transfer instead", txn.ID, txn.TransferId.Int64)
+		}
+
+		if txn.Type != models.TxnTypeDeposit && txn.Type != models.TxnTypeWithdraw {
+			return models.Errorf(models.ErrCodeInvalidTransition, "transaction %d is a %s, only deposits and withdrawals can change status", txn.ID, txn.Type)
+		}
+
+		if !models.CanTransitionTxnStatus(txn.Status, status) {
+			return models.Errorf(models.ErrCodeInvalidTransition, "transaction %d cannot move from %s to %s", txn.ID, txn.Status, status).
+				WithDetails(map[string]string{"from": txn.Status, "to": status})
+		}
+
+		// Completing a deposit or reversing a withdrawal adds to the balance,
+		// completing a withdrawal or reversing a deposit takes from it
+		credit := (status == models.TxnStatusCompleted) == (txn.Type == models.TxnTypeDeposit)
+
+		// The fee reserved by a pending withdrawal is charged when it completes
+		if status == models.TxnStatusCompleted && !credit {
+			txn.Fee, err = getPendingFee(tx, txn.ID)
+			if err != nil {
+				log.Printf("ERROR: failed to get fee of transaction Id: %d", txn.ID)
+				return fmt.Errorf("failed to get fee: %w", err)
+			}
+		}
+
+		switch {
+		case status == models.TxnStatusFailed:
+			// A pending transaction has not touched the balance
+		case credit:
+			err = creditInternal(tx, txn)
+		default:
+			err = debitInternal(tx, txn, status == models.TxnStatusCompleted)
+		}
 		if err != nil {
 			return err
 		}
 
-		log.Printf("transfer from [walled Id: %d] to [wallet Id: %d] completed", srcTxn.WalletId, targetTxn.WalletId)
+		err = updateTransactionStatus(tx, txn, status)
+		if err != nil {
+			log.Printf("ERROR: failed to update status of transaction Id: %d", txn.ID)
+			return fmt.Errorf("failed to update transaction status: %w", err)
+		}
+
+		log.Printf("transaction Id: %d is %s", txn.ID, txn.Status)
 		return nil
 	})
 }
 
+// debitInternal subtracts the transaction amount and its fee from the wallet balance and records the resulting balance.
+// reserved tells whether the amount and the fee are already excluded from the available balance, as for a pending withdrawal;
+// otherwise the available balance must cover them.
+func debitInternal(tx *sql.Tx, txn *models.Transaction, reserved bool) error {
+	balance, err := getWalletBalance(tx, txn.WalletId)
+	if err != nil {
+		log.Printf("ERROR: failed to get balance for wallet Id: %d", txn.WalletId)
+		return fmt.Errorf("failed to get balance: %w", err)
+	}
+
+	if balance == nil {
+		log.Printf("ERROR: wallet Id: %d not found", txn.WalletId)
+		return models.Errorf(models.ErrCodeWalletNotFound, "wallet %d not found", txn.WalletId)
+	}
+
+	required := txn.Amount.Add(feeAmount(txn))
+	available := balance.AvailableBalance
+	if reserved {
+		available = available.Add(required)
+	}
+
+	if available.LessThan(required) {
+		log.Printf("ERROR: wallet Id: %d does not have enough balance", txn.WalletId)
+		return models.Errorf(models.ErrCodeInsufficientFunds, "wallet %d does not have enough balance", txn.WalletId).
+			WithDetails(map[string]string{
+				"balance":           balance.Balance.String(),
+				"available_balance": balance.AvailableBalance.String(),
+				"requested":         required.String(),
+			})
+	}
+
+	return applyDebit(tx, txn, balance.Balance)
+}
+
+// transferInternal performs the core transfer logic:
+// 1. Creates the transfer record and links both transactions to it.
+// 2. Withdraws from the source wallet.
+// 3. Deposits to the target wallet.
+func transferInternal(tx *sql.Tx, transfer *models.Transfer, srcTxn *models.Transaction, targetTxn *models.Transaction) error {
+	err := createTransfer(tx, transfer)
+	if err != nil {
+		log.Printf("ERROR: failed to create transfer from wallet Id: %d to wallet Id: %d", transfer.SourceWalletId, transfer.TargetWalletId)
+		return fmt.Errorf("failed to create transfer: %w", err)
+	}
+	srcTxn.TransferId = sql.NullInt64{Int64: transfer.ID, Valid: true}
+	targetTxn.TransferId = sql.NullInt64{Int64: transfer.ID, Valid: true}
+
+	// The quoted rate only applies while the quote is open, it cannot be used again afterwards
+	if transfer.QuoteId.Valid {
+		err = useFxQuote(tx, transfer)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Withdraw from source wallet
+	err = withdrawInternal(tx, srcTxn)
+	if err != nil {
+		return err
+	}
+
+	// Deposit to target wallet
+	err = depositInternal(tx, targetTxn)
+	if err != nil {
+		return err
+	}
+
+	err = recordSpreadRevenue(tx, srcTxn)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("transfer from [walled Id: %d] to [wallet Id: %d] completed", srcTxn.WalletId, targetTxn.WalletId)
+	return nil
+}
+
 // depositInternal performs the core deposit logic:
 // 1. Creates a deposit transaction record.
-// 2. Increments the wallet balance by the deposit amount.
+// 2. Increments the wallet balance by the deposit amount and records the resulting balance.
+// A pending deposit leaves the balance unchanged until it is completed.
 func depositInternal(tx *sql.Tx, txn *models.Transaction) error {
 	err := createTransaction(tx, txn)
 	if err != nil {
@@ -53,29 +226,58 @@ func depositInternal(tx *sql.Tx, txn *models.Transaction) error {
 		return fmt.Errorf("failed to create incoming-transaction: %w", err)
 	}
 
-	err = incrementBalanceByWalletID(tx, txn.WalletId, txn.Amount)
+	if txn.Status == models.TxnStatusPending {
+		log.Printf("pending %s transaction created for wallet Id: %d", txn.Type, txn.WalletId)
+		return nil
+	}
+
+	return creditInternal(tx, txn)
+}
+
+// creditInternal increments the wallet balance by the transaction amount and records the resulting balance.
+func creditInternal(tx *sql.Tx, txn *models.Transaction) error {
+	balance, err := incrementBalanceByWalletID(tx, txn.WalletId, txn.Amount)
 	if err != nil {
 		log.Printf("ERROR: failed to update balance on %s transaction for wallet Id: %d", txn.Type, txn.WalletId)
 		return fmt.Errorf("failed to update incoming-balance: %w", err)
 	}
+
+	if balance == nil {
+		log.Printf("ERROR: wallet Id: %d not found", txn.WalletId)
+		return models.Errorf(models.ErrCodeWalletNotFound, "wallet %d not found", txn.WalletId)
+	}
+	txn.BalanceAfter = *balance
 	log.Printf("%s transaction updated for wallet Id: %d", txn.Type, txn.WalletId)
 	return nil
 }
 
 // withdrawInternal performs the core withdrawal logic:
-// 1. Checks current wallet balance to ensure sufficient funds.
+// 1. Checks the available wallet balance, i.e. the balance not reserved by holds or pending withdrawals, to ensure sufficient funds
+// for the amount and the fee.
 // 2. Creates a withdrawal transaction record.
-// 3. Updates the wallet balance by subtracting the withdrawal amount.
+// 3. Updates the wallet balance by subtracting the withdrawal amount and the fee, records the resulting balance and charges the fee.
+// A pending withdrawal leaves the balance unchanged but reserves the amount and the fee until it is completed or fails.
 func withdrawInternal(tx *sql.Tx, txn *models.Transaction) error {
 	balance, err := getWalletBalance(tx, txn.WalletId)
-	if err != nil || balance == nil {
+	if err != nil {
 		log.Printf("ERROR: failed to get balance for wallet Id: %d", txn.WalletId)
-		return fmt.Errorf("failed to get balance")
+		return fmt.Errorf("failed to get balance: %w", err)
 	}
 
-	if balance.LessThan(txn.Amount) {
+	if balance == nil {
+		log.Printf("ERROR: wallet Id: %d not found", txn.WalletId)
+		return models.Errorf(models.ErrCodeWalletNotFound, "wallet %d not found", txn.WalletId)
+	}
+
+	required := txn.Amount.Add(feeAmount(txn))
+	if balance.AvailableBalance.LessThan(required) {
 		log.Printf("ERROR: wallet Id: %d does not have enough balance", txn.WalletId)
-		return fmt.Errorf("not enough balance")
+		return models.Errorf(models.ErrCodeInsufficientFunds, "wallet %d does not have enough balance", txn.WalletId).
+			WithDetails(map[string]string{
+				"balance":           balance.Balance.String(),
+				"available_balance": balance.AvailableBalance.String(),
+				"requested":         required.String(),
+			})
 	}
 
 	err = createTransaction(tx, txn)
@@ -84,15 +286,54 @@ func withdrawInternal(tx *sql.Tx, txn *models.Transaction) error {
 		return fmt.Errorf("failed to create outgoing-transaction: %w", err)
 	}
 
-	err = updateBalanceByWalletID(tx, txn.WalletId, balance.Sub(txn.Amount))
+	if txn.Status == models.TxnStatusPending {
+		// The fee is recorded to be reserved, and charged when the transaction completes
+		if txn.Fee != nil {
+			err = createFee(tx, txn)
+			if err != nil {
+				log.Printf("ERROR: failed to record fee for transaction Id: %d", txn.ID)
+				return fmt.Errorf("failed to record fee: %w", err)
+			}
+		}
+		log.Printf("pending %s transaction created for wallet Id: %d", txn.Type, txn.WalletId)
+		return nil
+	}
+
+	err = applyDebit(tx, txn, balance.Balance)
 	if err != nil {
-		log.Printf("ERROR: failed to update balance on %s transaction for wallet Id: %d", txn.Type, txn.WalletId)
-		return fmt.Errorf("failed to update outgoing-balance: %w", err)
+		return err
 	}
 	log.Printf("%s transaction updated for wallet Id: %d", txn.Type, txn.WalletId)
 	return nil
 }
 
+// applyDebit takes the transaction amount and its fee from balance in a single update, records
+// the balance right after the transaction and charges the fee.
+func applyDebit(tx *sql.Tx, txn *models.Transaction, balance decimal.Decimal) error {
+	newBalance := balance.Sub(txn.Amount)
+	finalBalance := newBalance.Sub(feeAmount(txn))
+
+	err := updateBalanceByWalletID(tx, txn.WalletId, finalBalance)
+	if err != nil {
+		log.Printf("ERROR: failed to update balance on %s transaction for wallet Id: %d", txn.Type, txn.WalletId)
+		return fmt.Errorf("failed to update outgoing-balance: %w", err)
+	}
+	txn.BalanceAfter = newBalance
+
+	if txn.Fee == nil {
+		return nil
+	}
+	return chargeFeeInternal(tx, txn, finalBalance)
+}
+
+// feeAmount returns the fee charged for the transaction, zero when it has none.
+func feeAmount(txn *models.Transaction) decimal.Decimal {
+	if txn.Fee == nil {
+		return decimal.Zero
+	}
+	return txn.Fee.Amount
+}
+
 func withTx(db *sql.DB, fn func(tx *sql.Tx) error) (err error) {
 	tx, err := db.Begin()
 	if err != nil {
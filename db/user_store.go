@@ -3,13 +3,16 @@ package db
 import (
 	"database/sql"
 	"errors"
+	"fmt"
+	"log"
 
 	"github.com/rudithu/CRYPTO-WalletApp/models"
 )
 
 func GetUserById(db *sql.DB, id int64) (*models.User, error) {
 	var user models.User
-	err := db.QueryRow("SELECT id, name, created_at FROM users where id=$1", id).Scan(&user.ID, &user.Name, &user.CreatedAt)
+	err := db.QueryRow("SELECT id, name, reporting_currency, created_at FROM users where id=$1", id).
+		Scan(&user.ID, &user.Name, &user.ReportingCurrency, &user.CreatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -18,3 +21,25 @@ func GetUserById(db *sql.DB, id int64) (*models.User, error) {
 	}
 	return &user, nil
 }
+
+// UpdateUserReportingCurrency sets the reporting currency of the user with id and returns the updated
+// user, or nil when there is no such user.
+func UpdateUserReportingCurrency(db *sql.DB, id int64, ccy string) (*models.User, error) {
+	query := `
+		UPDATE users SET reporting_currency = $1 WHERE id = $2
+		RETURNING id, name, reporting_currency, created_at
+	`
+
+	var user models.User
+	err := db.QueryRow(query, ccy, id).Scan(&user.ID, &user.Name, &user.ReportingCurrency, &user.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		log.Printf("ERROR: failed to update reporting currency of user Id: %d", id)
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	log.Printf("user Id: %d reports in %s", id, ccy)
+	return &user, nil
+}
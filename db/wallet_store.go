@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/rudithu/CRYPTO-WalletApp/models"
@@ -149,11 +150,179 @@ func GetWalletByUserIDs(db *sql.DB, userIDs []int64) ([]models.Wallet, error) {
 	return wallets, nil
 }
 
-func getWalletBalance(tx *sql.Tx, walletId int64) (*decimal.Decimal, error) {
-	query := `SELECT balance FROM wallets WHERE id = $1`
+// GetWalletsByIDs returns the wallets with the given IDs; IDs without a wallet are left out.
+func GetWalletsByIDs(db *sql.DB, walletIDs []int64) ([]models.Wallet, error) {
 
-	var balance decimal.Decimal
-	err := tx.QueryRow(query, walletId).Scan(&balance)
+	if len(walletIDs) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(walletIDs))
+	args := make([]interface{}, len(walletIDs))
+
+	for i, id := range walletIDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, user_id, balance, currency, type, is_default, created_at
+		FROM wallets
+		WHERE id IN (%s)
+	`, strings.Join(placeholders, ", "))
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var wallets []models.Wallet
+
+	for rows.Next() {
+		var w models.Wallet
+		err := rows.Scan(
+			&w.ID,
+			&w.UserId,
+			&w.Balance,
+			&w.Currency,
+			&w.Type,
+			&w.IsDefault,
+			&w.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		wallets = append(wallets, w)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return wallets, nil
+}
+
+// creditBalancesByWalletIDs adds the amount of each wallet to its balance in a single statement
+// and returns the resulting balances. Wallets that do not exist are missing from the result.
+func creditBalancesByWalletIDs(tx *sql.Tx, amounts map[int64]decimal.Decimal) (map[int64]decimal.Decimal, error) {
+	walletIDs := make([]int64, 0, len(amounts))
+	for id := range amounts {
+		walletIDs = append(walletIDs, id)
+	}
+	sort.Slice(walletIDs, func(i, j int) bool { return walletIDs[i] < walletIDs[j] })
+
+	placeholders := make([]string, len(walletIDs))
+	args := make([]interface{}, 0, 2*len(walletIDs))
+
+	for i, id := range walletIDs {
+		placeholders[i] = fmt.Sprintf("($%d::int, $%d::numeric)", 2*i+1, 2*i+2)
+		args = append(args, id, amounts[id])
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE wallets SET balance = wallets.balance + v.amount
+		FROM (VALUES %s) AS v(id, amount)
+		WHERE wallets.id = v.id
+		RETURNING wallets.id, wallets.balance
+	`, strings.Join(placeholders, ", "))
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances := make(map[int64]decimal.Decimal)
+	for rows.Next() {
+		var id int64
+		var balance decimal.Decimal
+		if err := rows.Scan(&id, &balance); err != nil {
+			return nil, err
+		}
+		balances[id] = balance
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return balances, nil
+}
+
+// availableBalanceExpr is the available balance of a row of wallets: the ledger balance less active holds,
+// and pending withdrawals, which reserve their amount and their fee.
+var availableBalanceExpr = fmt.Sprintf(`balance
+		- COALESCE((SELECT SUM(h.amount) FROM holds h WHERE h.wallet_id = wallets.id AND %s), 0)
+		- COALESCE((SELECT SUM(p.amount) FROM transactions p WHERE p.wallet_id = wallets.id AND p.status = 'pending' AND p.type = 'withdraw'), 0)
+		- COALESCE((SELECT SUM(f.amount) FROM transactions p JOIN fees f ON %s WHERE p.wallet_id = wallets.id AND p.status = 'pending'), 0)`,
+	activeHoldCondition, pendingFeeCondition)
+
+// walletBalanceQuery selects the ledger and the available balance of the wallet with ID $1.
+var walletBalanceQuery = fmt.Sprintf(`
+	SELECT balance, %s
+	FROM wallets
+	WHERE id = $1
+`, availableBalanceExpr)
+
+// GetWalletBalance returns the ledger and the available balance of the wallet, or nil when it does not exist.
+// The wallet is not locked, so the balance may have changed by the time funds are spent.
+func GetWalletBalance(db *sql.DB, walletId int64) (*models.WalletBalance, error) {
+	return scanWalletBalance(db.QueryRow(walletBalanceQuery, walletId))
+}
+
+// getWalletBalance returns the ledger and the available balance of the wallet, locking the
+// wallet row for the rest of the DB transaction so concurrent withdrawals and holds cannot
+// spend the same funds.
+func getWalletBalance(tx *sql.Tx, walletId int64) (*models.WalletBalance, error) {
+	return scanWalletBalance(tx.QueryRow(walletBalanceQuery+" FOR UPDATE", walletId))
+}
+
+// GetAvailableBalancesByWalletIDs returns the available balance of the wallets, keyed by wallet ID,
+// as GetWalletBalance computes it. Wallets that do not exist are not part of the result.
+func GetAvailableBalancesByWalletIDs(db *sql.DB, walletIDs []int64) (map[int64]decimal.Decimal, error) {
+
+	if len(walletIDs) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(walletIDs))
+	args := make([]interface{}, len(walletIDs))
+
+	for i, id := range walletIDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+
+	query := fmt.Sprintf(`
+	SELECT id, %s
+	FROM wallets
+	WHERE id IN (%s)
+`, availableBalanceExpr, strings.Join(placeholders, ", "))
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	available := make(map[int64]decimal.Decimal)
+	for rows.Next() {
+		var walletId int64
+		var amount decimal.Decimal
+		if err := rows.Scan(&walletId, &amount); err != nil {
+			return nil, err
+		}
+		available[walletId] = amount
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return available, nil
+}
+
+func scanWalletBalance(row rowScanner) (*models.WalletBalance, error) {
+	var balance models.WalletBalance
+	err := row.Scan(&balance.Balance, &balance.AvailableBalance)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -163,10 +332,18 @@ func getWalletBalance(tx *sql.Tx, walletId int64) (*decimal.Decimal, error) {
 	return &balance, nil
 }
 
-func incrementBalanceByWalletID(tx *sql.Tx, walletID int64, delta decimal.Decimal) error {
-	query := `UPDATE wallets SET balance = balance + $1 WHERE id = $2`
-	_, err := tx.Exec(query, delta, walletID)
-	return err
+func incrementBalanceByWalletID(tx *sql.Tx, walletID int64, delta decimal.Decimal) (*decimal.Decimal, error) {
+	query := `UPDATE wallets SET balance = balance + $1 WHERE id = $2 RETURNING balance`
+
+	var balance decimal.Decimal
+	err := tx.QueryRow(query, delta, walletID).Scan(&balance)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &balance, nil
 }
 
 func updateBalanceByWalletID(tx *sql.Tx, walletID int64, newBalance decimal.Decimal) error {
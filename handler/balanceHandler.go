@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"encoding/json"
 	"net/http"
 	"strconv"
 
@@ -10,10 +9,13 @@ import (
 	"github.com/rudithu/CRYPTO-WalletApp/adapters"
 	"github.com/rudithu/CRYPTO-WalletApp/db"
 	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
 )
 
 // HandleBalance handles the GET request to retrieve a user's wallet balance.
-// It supports optional filtering by a specific wallet ID via query parameters.
+// Each wallet shows its ledger balance and its available balance, which excludes funds reserved by holds.
+// It supports optional filtering by a specific wallet ID via query parameters. The total is in the
+// reporting currency of the user unless the reporting_ccy query parameter sets another one.
 func (h *HandlerDB) HandleBalance(w http.ResponseWriter, r *http.Request) {
 	// Extract user ID from the URL path
 	vars := mux.Vars(r)
@@ -24,7 +26,7 @@ func (h *HandlerDB) HandleBalance(w http.ResponseWriter, r *http.Request) {
 
 	userId, err := strconv.ParseInt(userIdStr, 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid User ID", http.StatusBadRequest)
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid user id"))
 		return
 	}
 
@@ -32,15 +34,26 @@ func (h *HandlerDB) HandleBalance(w http.ResponseWriter, r *http.Request) {
 	if walletIdStr != "" {
 		walletId, err = strconv.ParseInt(walletIdStr, 10, 64)
 		if err != nil {
-			http.Error(w, "Invalid Wallet ID", http.StatusBadRequest)
+			writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid wallet id"))
 			return
 		}
 	}
 
+	reportingCcy, err := reportingCcyParam(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
 	// Retrieve user information from the database
 	userInfo, err := db.GetUserById(h.DB, userId)
 	if err != nil {
-		http.Error(w, "Error Getting Wallet Info", http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
+	}
+
+	if userInfo == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeUserNotFound, "user %d not found", userId))
 		return
 	}
 
@@ -51,48 +64,68 @@ func (h *HandlerDB) HandleBalance(w http.ResponseWriter, r *http.Request) {
 	if walletIdStr == "" {
 		// If no wallet ID specified, fetch all wallets for the user
 		selectedWallets, err = db.GetWalletByUserIDs(h.DB, userIds)
-		if err != nil || selectedWallets == nil {
-			http.Error(w, "Error Getting Wallet Info", http.StatusInternalServerError)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if selectedWallets == nil {
+			writeError(w, r, models.Errorf(models.ErrCodeWalletNotFound, "user %d has no wallet", userId))
 			return
 		}
 	} else {
 		// If wallet ID specified, fetch only that wallet
 		wallet, err := db.GetWalletById(h.DB, walletId)
-		if err != nil || wallet == nil {
-			http.Error(w, "Error Getting Wallet Info", http.StatusInternalServerError)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if wallet == nil || wallet.UserId != userId {
+			writeError(w, r, models.Errorf(models.ErrCodeWalletNotFound, "wallet %d not found for user %d", walletId, userId))
 			return
 		}
 		selectedWallets = []models.Wallet{*wallet}
 	}
 
-	// Collect non-base currencies to get conversion rates
-	var ccys []string
+	// Retrieve the available balance next to the ledger balance, as withdrawals and transfers check it
+	var walletIds []int64
 	for _, sw := range selectedWallets {
-		if sw.Currency != models.BaseCcy {
-			ccys = append(ccys, sw.Currency)
-		}
+		walletIds = append(walletIds, sw.ID)
 	}
 
-	// Retrieve currency conversion rates if needed
-	ccyMap := make(map[string]models.CcyRateToBaseCcy)
-	if len(ccys) > 0 {
-		rates, err := db.GetCcyRateToBaseCcy(h.DB, ccys)
-		if err != nil {
-			http.Error(w, "error to get currency rate", http.StatusInternalServerError)
-			return
-		}
+	available, err := db.GetAvailableBalancesByWalletIDs(h.DB, walletIds)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 
-		for _, rate := range rates {
-			ccyMap[rate.Ccy] = rate
-		}
+	pending, err := db.GetPendingBalancesByWalletIDs(h.DB, walletIds)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	for i := range selectedWallets {
+		p := pending[selectedWallets[i].ID]
+		selectedWallets[i].AvailableBalance = decimal.NewNullDecimal(available[selectedWallets[i].ID])
+		selectedWallets[i].PendingIncoming = decimal.NewNullDecimal(p.Incoming)
+		selectedWallets[i].PendingOutgoing = decimal.NewNullDecimal(p.Outgoing)
+	}
+
+	// Retrieve the conversion rates to the reporting currency
+	if reportingCcy == "" {
+		reportingCcy = userInfo.ReportingCurrency
+	}
+	ccyMap, err := h.ratesToReportingCcy(selectedWallets, reportingCcy)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
 
 	txns := make([]models.Transaction, 0)
 	// Convert and format the wallet response
-	resp := adapters.ToWalletDetailsResp(userInfo, selectedWallets, txns, ccyMap)
+	resp := adapters.ToWalletDetailsResp(userInfo, selectedWallets, txns, ccyMap, reportingCcy, nil, nil, "")
 
 	// Send the response as JSON
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	writeJSON(w, http.StatusOK, resp)
 
 }
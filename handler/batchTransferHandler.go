@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rudithu/CRYPTO-WalletApp/adapters"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/services"
+)
+
+// HandleBatchTransfer handles paying many destinations from one wallet in a single request.
+// In all_or_nothing mode the batch is rejected with the error of the first item that cannot be
+// transferred, or when the available balance does not cover the total. In best_effort mode every
+// item that can be transferred is, and the others are reported as failed.
+// The outcome of each item is returned, with 201 Created when at least one transfer was made.
+func (h *HandlerDB) HandleBatchTransfer(w http.ResponseWriter, r *http.Request) {
+	// Extract wallet ID from the URL path variables
+	vars := mux.Vars(r)
+	walletIdStr := vars["id"]
+
+	walletId, err := strconv.ParseInt(walletIdStr, 10, 64)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid wallet id"))
+		return
+	}
+
+	// Decode the JSON request body into BatchTransferRequest struct
+	var msg models.BatchTransferRequest
+	err = json.NewDecoder(r.Body).Decode(&msg)
+	if err != nil {
+		writeError(w, r, models.WrapError(models.ErrCodeMalformedRequest, err, "request body is not valid JSON"))
+		return
+	}
+
+	if err = msg.ValidateRequest(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.executeBatchTransfer(w, r, walletId, msg)
+}
+
+// executeBatchTransfer performs a validated batch of transfers from the wallet and writes the outcome of each item.
+func (h *HandlerDB) executeBatchTransfer(w http.ResponseWriter, r *http.Request, walletId int64, msg models.BatchTransferRequest) {
+	// Resolve the source wallet, all destinations and the conversion rates
+	plan, err := services.PrepareBatchTransfer(h.DB, walletId, msg)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	allOrNothing := msg.Mode == models.BatchModeAllOrNothing
+	if allOrNothing {
+		for _, item := range plan.Items {
+			if item.Err != nil {
+				writeError(w, r, item.Err)
+				return
+			}
+		}
+	}
+
+	// Perform all transfers of the batch atomically in the database
+	err = db.BatchTransferUpdate(h.DB, walletId, plan.Items, allOrNothing, msg.MsgId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp := adapters.ToBatchTransferResp(msg.Mode, plan.SourceWallet, plan.Items)
+	status := http.StatusOK
+	if resp.Succeeded > 0 {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, resp)
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rudithu/CRYPTO-WalletApp/adapters"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/services"
+)
+
+// HandleListCcyRates handles the request to list the stored conversion rates, each flagged
+// stale when it is older than the configured maximum age.
+func (h *HandlerDB) HandleListCcyRates(w http.ResponseWriter, r *http.Request) {
+	conversions, err := db.GetCcyConversions(h.DB)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, adapters.ToCcyRatesResp(conversions, services.RateAgeLimits()))
+}
+
+// HandleUpsertCcyRate handles the PUT request to set the stored rate between two currencies,
+// replacing the rate of the pair if there is one. The rate is dated now.
+func (h *HandlerDB) HandleUpsertCcyRate(w http.ResponseWriter, r *http.Request) {
+	// Decode the JSON request body into CcyRateRequest struct
+	var msg models.CcyRateRequest
+	err := json.NewDecoder(r.Body).Decode(&msg)
+	if err != nil {
+		writeError(w, r, models.WrapError(models.ErrCodeMalformedRequest, err, "request body is not valid JSON"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	msg.FromCcy = vars["from"]
+	msg.ToCcy = vars["to"]
+
+	if err = msg.ValidateRequest(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	conversion := models.CcyConversion{FromCcy: msg.FromCcy, ToCcy: msg.ToCcy, Rate: msg.Rate}
+	err = db.UpsertCcyConversion(h.DB, &conversion)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, adapters.ToCcyRateResp(conversion, services.RateAgeLimits()))
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rudithu/CRYPTO-WalletApp/adapters"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/services"
+)
+
+// HandleCreateConversion handles converting funds between two wallets of the same user in
+// different currencies, selling an amount of the from currency or buying an amount of the to currency.
+// The conversion with both of its transactions is returned with 201 Created.
+func (h *HandlerDB) HandleCreateConversion(w http.ResponseWriter, r *http.Request) {
+	// Extract user ID from URL path variables
+	vars := mux.Vars(r)
+	userIdStr := vars["id"]
+
+	userId, err := strconv.ParseInt(userIdStr, 10, 64)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid user id"))
+		return
+	}
+
+	// Decode the JSON request body into ConversionRequest struct
+	var msg models.ConversionRequest
+	err = json.NewDecoder(r.Body).Decode(&msg)
+	if err != nil {
+		writeError(w, r, models.WrapError(models.ErrCodeMalformedRequest, err, "request body is not valid JSON"))
+		return
+	}
+
+	if err = msg.ValidateRequest(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Resolve both wallets, the rate and both amounts
+	plan, err := services.PrepareConversion(h.DB, userId, msg)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Perform the conversion atomically in the database
+	err = db.ConversionUpdate(h.DB, &plan.Conversion, &plan.ConversionOut, &plan.ConversionIn)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, adapters.ToConversionResp(plan.Conversion, plan.ConversionOut, plan.ConversionIn))
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rudithu/CRYPTO-WalletApp/adapters"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/services"
+)
+
+// HandleWalletCostBasis handles the request to fetch the cost basis of a crypto wallet: the lots still held,
+// the gains realized so far and the gain not realized yet at the current rates. The method query parameter
+// overrides the configured cost-basis method and reporting_ccy the reporting currency of the owner.
+func (h *HandlerDB) HandleWalletCostBasis(w http.ResponseWriter, r *http.Request) {
+	method, err := costBasisMethodParam(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	reportingCcy, err := reportingCcyParam(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	wallet, ok := h.loadWallet(w, r)
+	if !ok {
+		return
+	}
+
+	if !services.IsCryptoCcy(wallet.Currency) {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "cost basis is only tracked for crypto wallets, wallet %d is in %s", wallet.ID, wallet.Currency))
+		return
+	}
+
+	if reportingCcy == "" {
+		owner, err := db.GetUserById(h.DB, wallet.UserId)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if owner == nil {
+			writeError(w, r, models.Errorf(models.ErrCodeUserNotFound, "user %d not found", wallet.UserId))
+			return
+		}
+		reportingCcy = owner.ReportingCurrency
+	}
+
+	cb, current, err := services.TrackWalletCostBasis(h.DB, *wallet, reportingCcy, method, time.Now().UTC())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, adapters.ToWalletCostBasisResp(*cb, current))
+}
+
+// HandleGainsReport handles the request for the yearly report of the gains realized on the crypto wallets
+// of a user, the current year unless the year query parameter says otherwise. The report is returned as
+// CSV when the format query parameter is csv. method and reporting_ccy are as for the cost basis of a wallet.
+func (h *HandlerDB) HandleGainsReport(w http.ResponseWriter, r *http.Request) {
+	userId, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid user id"))
+		return
+	}
+
+	now := time.Now().UTC()
+	year := now.Year()
+	if yearStr := r.URL.Query().Get("year"); yearStr != "" {
+		year, err = strconv.Atoi(yearStr)
+		if err != nil || year < 1970 || year > now.Year() {
+			writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "year must be a year up to %d", now.Year()))
+			return
+		}
+	}
+
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format != "" && format != "json" && format != "csv" {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "format must be either json or csv"))
+		return
+	}
+
+	method, err := costBasisMethodParam(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	reportingCcy, err := reportingCcyParam(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	user, err := db.GetUserById(h.DB, userId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if user == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeUserNotFound, "user %d not found", userId))
+		return
+	}
+
+	if reportingCcy == "" {
+		reportingCcy = user.ReportingCurrency
+	}
+
+	wallets, err := db.GetWalletByUserIDs(h.DB, []int64{userId})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	var costBases []models.CostBasis
+	for _, wallet := range wallets {
+		if !services.IsCryptoCcy(wallet.Currency) {
+			continue
+		}
+		cb, _, err := services.TrackWalletCostBasis(h.DB, wallet, reportingCcy, method, now)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		costBases = append(costBases, *cb)
+	}
+
+	report := adapters.ToGainsReportResp(user, year, reportingCcy, method, costBases)
+	if format == "csv" {
+		writeCSV(w, fmt.Sprintf("gains-%d-%d.csv", userId, year), adapters.ToGainsReportCSV(report))
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// costBasisMethodParam reads the method query parameter, the configured cost-basis method when it is not set.
+func costBasisMethodParam(r *http.Request) (string, error) {
+	method := strings.ToLower(r.URL.Query().Get("method"))
+	if method == "" {
+		return services.CostBasisMethod(), nil
+	}
+	if !models.IsCostBasisMethod(method) {
+		return "", models.Errorf(models.ErrCodeValidationFailed, "method must be one of %s, %s or %s",
+			models.CostBasisFIFO, models.CostBasisLIFO, models.CostBasisAverage)
+	}
+	return method, nil
+}
@@ -6,13 +6,15 @@ import (
 	"strconv"
 
 	"github.com/gorilla/mux"
+	"github.com/rudithu/CRYPTO-WalletApp/adapters"
 	"github.com/rudithu/CRYPTO-WalletApp/db"
 	"github.com/rudithu/CRYPTO-WalletApp/models"
 )
 
 // HandleDepositMoney processes a deposit request to add money to a specific wallet.
 // It validates the wallet ID, parses the request body, validates the transaction request,
-// and updates the wallet balance accordingly.
+// and updates the wallet balance accordingly. The created transaction is returned with 201 Created.
+// A pending deposit only updates the balance once it is completed.
 func (h *HandlerDB) HandleDepositMoney(w http.ResponseWriter, r *http.Request) {
 	// Extract wallet ID from URL path variables
 	vars := mux.Vars(r)
@@ -20,7 +22,7 @@ func (h *HandlerDB) HandleDepositMoney(w http.ResponseWriter, r *http.Request) {
 
 	walletId, err := strconv.ParseInt(walletIdStr, 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid Wallet Id", http.StatusBadRequest)
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid wallet id"))
 		return
 	}
 
@@ -28,27 +30,45 @@ func (h *HandlerDB) HandleDepositMoney(w http.ResponseWriter, r *http.Request) {
 	var msg models.TransactionRequest
 	err = json.NewDecoder(r.Body).Decode(&msg)
 	if err != nil {
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+		writeError(w, r, models.WrapError(models.ErrCodeMalformedRequest, err, "request body is not valid JSON"))
 		return
 	}
 
 	if err = msg.ValidateRequest(models.TxnTypeDeposit); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
+		return
+	}
+
+	// Retrieve wallet details from database by wallet ID
+	wallet, err := db.GetWalletById(h.DB, walletId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if wallet == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeWalletNotFound, "wallet %d not found", walletId))
 		return
 	}
 
 	t := models.Transaction{
-		WalletId: walletId,
-		Amount:   msg.Amount,
-		Type:     models.TxnTypeDeposit,
+		WalletId:          walletId,
+		Amount:            msg.Amount,
+		Type:              models.TxnTypeDeposit,
+		Description:       models.NullString(msg.Description),
+		ExternalReference: models.NullString(msg.ExternalReference),
+		Tags:              msg.Tags,
+		Status:            msg.Status,
 	}
 
 	// Perform the deposit update in the database
 	err = db.DepositUpdate(h.DB, &t)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
 	}
 
-	// Return HTTP 204 No Content to indicate success without body content
-	w.WriteHeader(http.StatusNoContent)
+	// Return HTTP 201 Created with the created transaction and the resulting balance
+	w.Header().Set("Location", transactionLocation(t.ID))
+	writeJSON(w, http.StatusCreated, adapters.ToTransactionResp(t, wallet.Currency, settledBalance(t)))
 }
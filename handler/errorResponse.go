@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// errorStatus maps each error code to the HTTP status returned to the client.
+var errorStatus = map[string]int{
+	models.ErrCodeValidationFailed:  http.StatusBadRequest,
+	models.ErrCodeMalformedRequest:  http.StatusBadRequest,
+	models.ErrCodeUserNotFound:      http.StatusNotFound,
+	models.ErrCodeWalletNotFound:    http.StatusNotFound,
+	models.ErrCodeTxnNotFound:       http.StatusNotFound,
+	models.ErrCodeTransferNotFound:  http.StatusNotFound,
+	models.ErrCodeNotReversible:     http.StatusConflict,
+	models.ErrCodeHoldNotFound:      http.StatusNotFound,
+	models.ErrCodeHoldNotActive:     http.StatusConflict,
+	models.ErrCodeInvalidTransition: http.StatusConflict,
+	models.ErrCodeScheduleNotFound:  http.StatusNotFound,
+	models.ErrCodeStatementNotFound: http.StatusNotFound,
+	models.ErrCodeQuoteNotFound:     http.StatusNotFound,
+	models.ErrCodeQuoteExpired:      http.StatusConflict,
+	models.ErrCodeQuoteUsed:         http.StatusConflict,
+	models.ErrCodeInsufficientFunds: http.StatusUnprocessableEntity,
+	models.ErrCodeRateUnavailable:   http.StatusUnprocessableEntity,
+	models.ErrCodeRateStale:         http.StatusUnprocessableEntity,
+	models.ErrCodeFeeNotConfigured:  http.StatusUnprocessableEntity,
+	models.ErrCodeDuplicateFile:     http.StatusConflict,
+	models.ErrCodeInternal:          http.StatusInternalServerError,
+}
+
+// writeError writes err to the client as a JSON ErrorResponse.
+// Errors that are not *models.AppError are reported as INTERNAL_ERROR without
+// exposing their message, since they usually come straight from the database driver.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var appErr *models.AppError
+	if !errors.As(err, &appErr) {
+		log.Printf("ERROR: unexpected error on %s %s: %v", r.Method, r.URL.Path, err)
+		appErr = models.Errorf(models.ErrCodeInternal, "internal server error")
+	} else if appErr.Err != nil {
+		log.Printf("ERROR: %s on %s %s: %v", appErr.Code, r.Method, r.URL.Path, appErr.Err)
+	}
+
+	status, ok := errorStatus[appErr.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	writeJSON(w, status, models.ErrorResponse{
+		Code:      appErr.Code,
+		Message:   appErr.Message,
+		Details:   appErr.Details,
+		RequestID: requestIDFrom(r),
+	})
+}
+
+// writeJSON serializes body as JSON with the given HTTP status.
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeCSV serializes records as a CSV attachment named filename with status 200 OK.
+func writeCSV(w http.ResponseWriter, filename string, records [][]string) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	csv.NewWriter(w).WriteAll(records)
+}
+
+// writeHTML renders tmpl with data as an HTML page with status 200 OK. The page is rendered before
+// anything is written, so that a template failing is still reported as an error.
+func writeHTML(w http.ResponseWriter, r *http.Request, tmpl *template.Template, data interface{}) {
+	var page bytes.Buffer
+	if err := tmpl.Execute(&page, data); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(page.Bytes())
+}
+
+// writeXML serializes body as an XML attachment named filename with status 200 OK. The document is
+// serialized before anything is written, so that a failure is still reported as an error.
+func writeXML(w http.ResponseWriter, r *http.Request, filename string, body interface{}) {
+	doc, err := xml.MarshalIndent(body, "", "  ")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(xml.Header))
+	w.Write(doc)
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rudithu/CRYPTO-WalletApp/adapters"
+	"github.com/rudithu/CRYPTO-WalletApp/config"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/services"
+)
+
+// HandleCreateFxQuote handles the POST request to lock the current conversion rate between two currencies,
+// the mid rate less the FX spread for the tier of the user the quote is for. A transfer between wallets of that user in these currencies executes at the quoted rate
+// when it passes the quote id before the quote expires.
+func (h *HandlerDB) HandleCreateFxQuote(w http.ResponseWriter, r *http.Request) {
+	// Decode the JSON request body into FxQuoteRequest struct
+	var msg models.FxQuoteRequest
+	err := json.NewDecoder(r.Body).Decode(&msg)
+	if err != nil {
+		writeError(w, r, models.WrapError(models.ErrCodeMalformedRequest, err, "request body is not valid JSON"))
+		return
+	}
+
+	if err = msg.ValidateRequest(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	userInfo, err := db.GetUserById(h.DB, msg.UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if userInfo == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeUserNotFound, "user %d not found", msg.UserID))
+		return
+	}
+
+	price, err := services.QuoteFxPrice(h.DB, msg.UserID, msg.FromCcy, msg.ToCcy)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	quote := models.FxQuote{
+		UserId:   msg.UserID,
+		FromCcy:  msg.FromCcy,
+		ToCcy:    msg.ToCcy,
+		Rate:     price.Rate,
+		MidRate:  price.MidRate,
+		Side:     price.Side,
+		Spread:   price.Spread,
+		RatePath: price.RatePath,
+	}
+
+	err = db.CreateFxQuote(h.DB, &quote, fxQuoteTTL())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fxQuoteLocation(quote.ID))
+	writeJSON(w, http.StatusCreated, adapters.ToFxQuoteResp(quote))
+}
+
+// HandleGetFxQuote handles the request to fetch a single FX quote.
+func (h *HandlerDB) HandleGetFxQuote(w http.ResponseWriter, r *http.Request) {
+	// Extract quote ID from URL path variables
+	vars := mux.Vars(r)
+	quoteIdStr := vars["id"]
+
+	quoteId, err := strconv.ParseInt(quoteIdStr, 10, 64)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid quote id"))
+		return
+	}
+
+	quote, err := db.GetFxQuoteById(h.DB, quoteId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if quote == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeQuoteNotFound, "fx quote %d not found", quoteId))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, adapters.ToFxQuoteResp(*quote))
+}
+
+// fxQuoteTTL returns the configured number of seconds a quote locks its rate.
+func fxQuoteTTL() int64 {
+	val := config.GetOrDefault(config.FX_QUOTE_TTL, "")
+	seconds, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || seconds <= 0 {
+		return models.DefaultFxQuoteTTLSeconds
+	}
+	return seconds
+}
+
+// fxQuoteLocation returns the URL path of the FX quote resource.
+func fxQuoteLocation(quoteId int64) string {
+	return fmt.Sprintf("/fx/quotes/%d", quoteId)
+}
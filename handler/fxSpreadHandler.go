@@ -0,0 +1,20 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/rudithu/CRYPTO-WalletApp/adapters"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+)
+
+// HandleSpreadRevenue handles the request to report the revenue taken by FX spreads on transfers
+// and conversions between currencies, summed per currency received by customers.
+func (h *HandlerDB) HandleSpreadRevenue(w http.ResponseWriter, r *http.Request) {
+	totals, err := db.GetSpreadRevenueTotals(h.DB)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, adapters.ToSpreadRevenueResp(totals))
+}
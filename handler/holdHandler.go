@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rudithu/CRYPTO-WalletApp/adapters"
+	"github.com/rudithu/CRYPTO-WalletApp/config"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// HandleCreateHold handles the POST request to reserve funds on a wallet.
+// The hold reduces the available balance of the wallet until it is captured, released or expires.
+func (h *HandlerDB) HandleCreateHold(w http.ResponseWriter, r *http.Request) {
+	// Extract wallet ID from URL path variables
+	vars := mux.Vars(r)
+	walletIdStr := vars["id"]
+
+	walletId, err := strconv.ParseInt(walletIdStr, 10, 64)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid wallet id"))
+		return
+	}
+
+	// Decode the JSON request body into HoldRequest struct
+	var msg models.HoldRequest
+	err = json.NewDecoder(r.Body).Decode(&msg)
+	if err != nil {
+		writeError(w, r, models.WrapError(models.ErrCodeMalformedRequest, err, "request body is not valid JSON"))
+		return
+	}
+
+	if err = msg.ValidateRequest(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	wallet, err := db.GetWalletById(h.DB, walletId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if wallet == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeWalletNotFound, "wallet %d not found", walletId))
+		return
+	}
+
+	expiresIn := defaultHoldExpiry()
+	if msg.ExpiresInSeconds != nil {
+		expiresIn = *msg.ExpiresInSeconds
+	}
+
+	hold := models.Hold{
+		WalletId:    walletId,
+		Amount:      msg.Amount,
+		Description: models.NullString(msg.Description),
+	}
+
+	err = db.CreateHold(h.DB, &hold, expiresIn)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", holdLocation(hold.ID))
+	writeJSON(w, http.StatusCreated, adapters.ToHoldResp(hold, wallet.Currency))
+}
+
+// HandleGetHold handles the request to fetch a single hold.
+func (h *HandlerDB) HandleGetHold(w http.ResponseWriter, r *http.Request) {
+	hold, wallet, ok := h.loadHold(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, adapters.ToHoldResp(*hold, wallet.Currency))
+}
+
+// HandleCaptureHold handles capturing an active hold in full or in part.
+// The captured amount is withdrawn from the wallet and the rest of the hold is released.
+func (h *HandlerDB) HandleCaptureHold(w http.ResponseWriter, r *http.Request) {
+	// Decode the optional JSON request body, an empty body captures the full amount
+	var msg models.HoldCaptureRequest
+	err := json.NewDecoder(r.Body).Decode(&msg)
+	if err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, r, models.WrapError(models.ErrCodeMalformedRequest, err, "request body is not valid JSON"))
+		return
+	}
+
+	if err = msg.ValidateRequest(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	hold, wallet, ok := h.loadHold(w, r)
+	if !ok {
+		return
+	}
+
+	amount := hold.Amount
+	if msg.Amount != nil {
+		amount = *msg.Amount
+	}
+
+	description := hold.Description
+	if !description.Valid {
+		description = models.NullString(fmt.Sprintf("capture of hold %d", hold.ID))
+	}
+
+	t := models.Transaction{
+		Type:        models.TxnTypeWithdraw,
+		Description: description,
+	}
+
+	err = db.CaptureHold(h.DB, hold, amount, &t)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", transactionLocation(t.ID))
+	writeJSON(w, http.StatusCreated, models.HoldCaptureResponse{
+		Hold:        adapters.ToHoldResp(*hold, wallet.Currency),
+		Transaction: adapters.ToTransactionResp(t, wallet.Currency, &t.BalanceAfter),
+	})
+}
+
+// HandleReleaseHold handles releasing an active hold, making its funds available again.
+func (h *HandlerDB) HandleReleaseHold(w http.ResponseWriter, r *http.Request) {
+	hold, wallet, ok := h.loadHold(w, r)
+	if !ok {
+		return
+	}
+
+	err := db.ReleaseHold(h.DB, hold)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, adapters.ToHoldResp(*hold, wallet.Currency))
+}
+
+// loadHold reads the hold from the {id} path variable together with its wallet.
+// It writes the error response and returns false when either cannot be loaded.
+func (h *HandlerDB) loadHold(w http.ResponseWriter, r *http.Request) (*models.Hold, *models.Wallet, bool) {
+	vars := mux.Vars(r)
+	holdIdStr := vars["id"]
+
+	holdId, err := strconv.ParseInt(holdIdStr, 10, 64)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid hold id"))
+		return nil, nil, false
+	}
+
+	hold, err := db.GetHoldById(h.DB, holdId)
+	if err != nil {
+		writeError(w, r, err)
+		return nil, nil, false
+	}
+
+	if hold == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeHoldNotFound, "hold %d not found", holdId))
+		return nil, nil, false
+	}
+
+	wallet, err := db.GetWalletById(h.DB, hold.WalletId)
+	if err != nil {
+		writeError(w, r, err)
+		return nil, nil, false
+	}
+
+	if wallet == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeWalletNotFound, "wallet %d not found", hold.WalletId))
+		return nil, nil, false
+	}
+	return hold, wallet, true
+}
+
+// defaultHoldExpiry returns the configured hold expiry in seconds.
+func defaultHoldExpiry() int64 {
+	val := config.GetOrDefault(config.HOLD_DEFAULT_EXPIRY, "")
+	seconds, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || seconds <= 0 {
+		return models.DefaultHoldExpirySeconds
+	}
+	return seconds
+}
+
+// holdLocation returns the URL path of the hold resource.
+func holdLocation(holdId int64) string {
+	return fmt.Sprintf("/holds/%d", holdId)
+}
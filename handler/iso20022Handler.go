@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rudithu/CRYPTO-WalletApp/adapters"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/services"
+)
+
+// HandleDailyStatement handles the request for the end of day statement of a wallet as a camt.053
+// document, for a UTC day that has ended. The statement is built from the transactions on each request.
+func (h *HandlerDB) HandleDailyStatement(w http.ResponseWriter, r *http.Request) {
+	day, err := time.Parse(time.DateOnly, mux.Vars(r)["date"])
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "date must be a date as YYYY-MM-DD"))
+		return
+	}
+
+	now := time.Now().UTC()
+	until := day.AddDate(0, 0, 1)
+	if until.After(now) {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "the statement for %s is only available once the day has ended",
+			day.Format(time.DateOnly)))
+		return
+	}
+
+	wallet, ok := h.loadWallet(w, r)
+	if !ok {
+		return
+	}
+
+	if !wallet.CreatedAt.Before(until) {
+		writeError(w, r, models.Errorf(models.ErrCodeStatementNotFound, "wallet %d has no statement for %s, it was created later",
+			wallet.ID, day.Format(time.DateOnly)))
+		return
+	}
+
+	statement, err := services.BuildDailyStatement(h.DB, *wallet, day, now)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	id := fmt.Sprintf("%d-%s", wallet.ID, day.Format("20060102"))
+	writeXML(w, r, fmt.Sprintf("camt053-%s.xml", id), adapters.ToCamt053Document(*statement, until, id))
+}
+
+// HandlePain001Upload handles a pain.001 payment file paying other wallets from the wallet. The credit
+// transfers of the file are validated against the wallet and made as a batch of transfers, all or
+// nothing unless the mode query parameter is best_effort. The outcome is that of a batch of transfers.
+// The file may be up to models.MaxPaymentFileBytes with up to models.MaxBatchTransferItems transactions.
+// A file is processed once per wallet: another file with the same GrpHdr/MsgId is rejected as a duplicate.
+func (h *HandlerDB) HandlePain001Upload(w http.ResponseWriter, r *http.Request) {
+	wallet, ok := h.loadWallet(w, r)
+	if !ok {
+		return
+	}
+
+	var doc models.Pain001Document
+	body := http.MaxBytesReader(w, r.Body, models.MaxPaymentFileBytes)
+	if err := xml.NewDecoder(body).Decode(&doc); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "payment file must not be larger than %d bytes", tooLarge.Limit))
+			return
+		}
+		writeError(w, r, models.WrapError(models.ErrCodeMalformedRequest, err, "request body is not valid XML"))
+		return
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	msg, err := doc.ToBatchTransferRequest(*wallet, r.URL.Query().Get("mode"), today)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	processedAt, err := db.GetPaymentFileProcessedAt(h.DB, wallet.ID, msg.MsgId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if processedAt != nil {
+		writeError(w, r, models.Errorf(models.ErrCodeDuplicateFile, "payment file %s has already been processed for wallet %d", msg.MsgId, wallet.ID).
+			WithDetails(map[string]string{"processed_at": processedAt.Format(time.RFC3339)}))
+		return
+	}
+
+	h.executeBatchTransfer(w, r, wallet.ID, msg)
+}
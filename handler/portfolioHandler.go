@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rudithu/CRYPTO-WalletApp/adapters"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/services"
+	"github.com/shopspring/decimal"
+)
+
+// HandlePortfolioHistory handles the request to value the wallets of a user at the close of every day
+// between the from and to query parameters, as for the balance history of a wallet. Each day combines
+// the closing balances with the rates known at that time, in the reporting currency of the user unless
+// the reporting_ccy query parameter sets another one.
+func (h *HandlerDB) HandlePortfolioHistory(w http.ResponseWriter, r *http.Request) {
+	userId, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid user id"))
+		return
+	}
+
+	reportingCcy, err := reportingCcyParam(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	today := models.StartOfDay(time.Now().UTC())
+	from, to, err := balanceHistoryRange(r, today)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	user, err := db.GetUserById(h.DB, userId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if user == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeUserNotFound, "user %d not found", userId))
+		return
+	}
+
+	if reportingCcy == "" {
+		reportingCcy = user.ReportingCurrency
+	}
+
+	wallets, err := db.GetWalletByUserIDs(h.DB, []int64{userId})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	until := to.AddDate(0, 0, 1)
+
+	// closing balances by day, then by currency
+	balances := make([]map[string]decimal.Decimal, int(until.Sub(from).Hours()/24))
+	for i := range balances {
+		balances[i] = make(map[string]decimal.Decimal)
+	}
+	for _, wallet := range wallets {
+		opening, err := db.GetWalletBalanceAt(h.DB, wallet.ID, from)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if opening == nil {
+			continue
+		}
+
+		events, err := db.GetBalanceEvents(h.DB, wallet.ID, from, until)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		for i, day := range models.DailyClosingBalances(*opening, from, to, events) {
+			balances[i][wallet.Currency] = balances[i][wallet.Currency].Add(day.Balance)
+		}
+	}
+
+	history, err := db.GetCcyRateHistory(h.DB, from, until)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	limits := services.RateAgeLimits()
+	values := make([]models.PortfolioValue, 0, len(balances))
+	for i, dayBalances := range balances {
+		day := from.AddDate(0, 0, i)
+		conversions := history.At(day.AddDate(0, 0, 1))
+		values = append(values, models.ValuePortfolio(day, dayBalances, conversions, reportingCcy, limits))
+	}
+
+	writeJSON(w, http.StatusOK, adapters.ToPortfolioHistoryResp(user, reportingCcy, from, to, values))
+}
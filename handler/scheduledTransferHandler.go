@@ -0,0 +1,315 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rudithu/CRYPTO-WalletApp/adapters"
+	"github.com/rudithu/CRYPTO-WalletApp/config"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/services"
+)
+
+// HandleCreateScheduledTransfer handles the POST request to schedule a transfer from a wallet.
+// The destination is checked when the transfer is scheduled; the funds and the conversion rate
+// are only checked when the scheduler executes each occurrence.
+func (h *HandlerDB) HandleCreateScheduledTransfer(w http.ResponseWriter, r *http.Request) {
+	// Extract wallet ID from URL path variables
+	vars := mux.Vars(r)
+	walletIdStr := vars["id"]
+
+	walletId, err := strconv.ParseInt(walletIdStr, 10, 64)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid wallet id"))
+		return
+	}
+
+	// Decode the JSON request body into ScheduledTransferRequest struct
+	var msg models.ScheduledTransferRequest
+	err = json.NewDecoder(r.Body).Decode(&msg)
+	if err != nil {
+		writeError(w, r, models.WrapError(models.ErrCodeMalformedRequest, err, "request body is not valid JSON"))
+		return
+	}
+
+	if err = msg.ValidateRequest(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	wallet, err := db.GetWalletById(h.DB, walletId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if wallet == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeWalletNotFound, "source wallet %d not found", walletId))
+		return
+	}
+
+	if _, err = services.ResolveTargetWallet(h.DB, *wallet, msg.DestinationWalletID, msg.DestinationUserID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	s := models.ScheduledTransfer{
+		SourceWalletId:      walletId,
+		Amount:              msg.Amount,
+		Description:         models.NullString(msg.Description),
+		ExternalReference:   models.NullString(msg.ExternalReference),
+		Frequency:           msg.Frequency,
+		StartAt:             time.Now().UTC(),
+		OnInsufficientFunds: msg.OnInsufficientFunds,
+		Status:              models.ScheduleStatusActive,
+	}
+	if msg.DestinationWalletID != nil {
+		s.DestinationWalletId.Int64, s.DestinationWalletId.Valid = *msg.DestinationWalletID, true
+	}
+	if msg.DestinationUserID != nil {
+		s.DestinationUserId.Int64, s.DestinationUserId.Valid = *msg.DestinationUserID, true
+	}
+	if msg.StartAt != nil {
+		s.StartAt = msg.StartAt.UTC()
+	}
+	if msg.EndAt != nil {
+		s.EndAt.Time, s.EndAt.Valid = msg.EndAt.UTC(), true
+	}
+	if msg.MaxOccurrences != nil {
+		s.MaxOccurrences.Int64, s.MaxOccurrences.Valid = *msg.MaxOccurrences, true
+	}
+	if s.OnInsufficientFunds == "" {
+		s.OnInsufficientFunds = defaultInsufficientFundsPolicy()
+	}
+	s.Reschedule()
+
+	err = db.CreateScheduledTransfer(h.DB, &s)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", scheduledTransferLocation(s.ID))
+	writeJSON(w, http.StatusCreated, adapters.ToScheduledTransferResp(s, wallet.Currency))
+}
+
+// HandleUserScheduledTransfers handles the request to list the scheduled transfers paid from
+// any wallet of a user, newest first.
+func (h *HandlerDB) HandleUserScheduledTransfers(w http.ResponseWriter, r *http.Request) {
+	// Extract user ID from URL path variables
+	vars := mux.Vars(r)
+	userIdStr := vars["id"]
+
+	userId, err := strconv.ParseInt(userIdStr, 10, 64)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid user id"))
+		return
+	}
+
+	userInfo, err := db.GetUserById(h.DB, userId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if userInfo == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeUserNotFound, "user %d not found", userId))
+		return
+	}
+
+	wallets, err := db.GetWalletByUserIDs(h.DB, []int64{userId})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	currencies := make(map[int64]string)
+	for _, w := range wallets {
+		currencies[w.ID] = w.Currency
+	}
+
+	schedules, err := db.GetScheduledTransfersByUserID(h.DB, userId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, adapters.ToUserScheduledTransfersResp(userInfo, schedules, currencies))
+}
+
+// HandleGetScheduledTransfer handles the request to fetch a single scheduled transfer.
+func (h *HandlerDB) HandleGetScheduledTransfer(w http.ResponseWriter, r *http.Request) {
+	s, wallet, ok := h.loadScheduledTransfer(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, adapters.ToScheduledTransferResp(*s, wallet.Currency))
+}
+
+// HandleUpdateScheduledTransfer handles changing, pausing or resuming a scheduled transfer.
+// Occurrences missed while a recurring transfer was paused are skipped when it is resumed.
+// A completed or cancelled scheduled transfer cannot be changed.
+func (h *HandlerDB) HandleUpdateScheduledTransfer(w http.ResponseWriter, r *http.Request) {
+	// Decode the JSON request body into ScheduledTransferUpdateRequest struct
+	var msg models.ScheduledTransferUpdateRequest
+	err := json.NewDecoder(r.Body).Decode(&msg)
+	if err != nil {
+		writeError(w, r, models.WrapError(models.ErrCodeMalformedRequest, err, "request body is not valid JSON"))
+		return
+	}
+
+	if err = msg.ValidateRequest(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	s, wallet, ok := h.loadScheduledTransfer(w, r)
+	if !ok {
+		return
+	}
+
+	updated, err := db.UpdateScheduledTransfer(h.DB, s.ID, func(s *models.ScheduledTransfer) error {
+		if s.Status == models.ScheduleStatusCompleted || s.Status == models.ScheduleStatusCancelled {
+			return models.Errorf(models.ErrCodeInvalidTransition, "scheduled transfer %d is %s and cannot be changed", s.ID, s.Status).
+				WithDetails(map[string]string{"status": s.Status})
+		}
+
+		if msg.EndAt != nil {
+			if s.Frequency == models.ScheduleFrequencyOnce {
+				return models.Errorf(models.ErrCodeValidationFailed, "end_at only applies to recurring transfers")
+			}
+			if msg.EndAt.Before(s.StartAt) {
+				return models.Errorf(models.ErrCodeValidationFailed, "end_at must not be before start_at")
+			}
+			s.EndAt.Time, s.EndAt.Valid = msg.EndAt.UTC(), true
+		}
+		if msg.MaxOccurrences != nil {
+			if s.Frequency == models.ScheduleFrequencyOnce {
+				return models.Errorf(models.ErrCodeValidationFailed, "max_occurrences only applies to recurring transfers")
+			}
+			s.MaxOccurrences.Int64, s.MaxOccurrences.Valid = *msg.MaxOccurrences, true
+		}
+		if msg.Amount != nil {
+			s.Amount = *msg.Amount
+		}
+		if msg.Description != nil {
+			s.Description = models.NullString(*msg.Description)
+		}
+		if msg.OnInsufficientFunds != nil {
+			s.OnInsufficientFunds = *msg.OnInsufficientFunds
+		}
+
+		if msg.Status != nil && *msg.Status != s.Status {
+			s.Status = *msg.Status
+			if s.Status == models.ScheduleStatusActive {
+				s.SkipMissed(time.Now().UTC())
+			}
+		}
+		s.Reschedule()
+		return nil
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, adapters.ToScheduledTransferResp(*updated, wallet.Currency))
+}
+
+// HandleCancelScheduledTransfer handles the DELETE request cancelling a scheduled transfer.
+// The scheduled transfer and its runs are kept; no further occurrence is executed.
+func (h *HandlerDB) HandleCancelScheduledTransfer(w http.ResponseWriter, r *http.Request) {
+	s, _, ok := h.loadScheduledTransfer(w, r)
+	if !ok {
+		return
+	}
+
+	_, err := db.UpdateScheduledTransfer(h.DB, s.ID, func(s *models.ScheduledTransfer) error {
+		if s.Status == models.ScheduleStatusCompleted {
+			return models.Errorf(models.ErrCodeInvalidTransition, "scheduled transfer %d is already completed", s.ID).
+				WithDetails(map[string]string{"status": s.Status})
+		}
+		s.Status = models.ScheduleStatusCancelled
+		s.Reschedule()
+		return nil
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleScheduledTransferRuns handles the request to list the runs of a scheduled transfer, newest first.
+func (h *HandlerDB) HandleScheduledTransferRuns(w http.ResponseWriter, r *http.Request) {
+	s, _, ok := h.loadScheduledTransfer(w, r)
+	if !ok {
+		return
+	}
+
+	runs, err := db.GetScheduledTransferRuns(h.DB, s.ID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, adapters.ToScheduledTransferRunsResp(s.ID, runs))
+}
+
+// loadScheduledTransfer reads the scheduled transfer from the {id} path variable together with its source wallet.
+// It writes the error response and returns false when either cannot be loaded.
+func (h *HandlerDB) loadScheduledTransfer(w http.ResponseWriter, r *http.Request) (*models.ScheduledTransfer, *models.Wallet, bool) {
+	vars := mux.Vars(r)
+	scheduleIdStr := vars["id"]
+
+	scheduleId, err := strconv.ParseInt(scheduleIdStr, 10, 64)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid scheduled transfer id"))
+		return nil, nil, false
+	}
+
+	s, err := db.GetScheduledTransferById(h.DB, scheduleId)
+	if err != nil {
+		writeError(w, r, err)
+		return nil, nil, false
+	}
+
+	if s == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeScheduleNotFound, "scheduled transfer %d not found", scheduleId))
+		return nil, nil, false
+	}
+
+	wallet, err := db.GetWalletById(h.DB, s.SourceWalletId)
+	if err != nil {
+		writeError(w, r, err)
+		return nil, nil, false
+	}
+
+	if wallet == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeWalletNotFound, "wallet %d not found", s.SourceWalletId))
+		return nil, nil, false
+	}
+	return s, wallet, true
+}
+
+// defaultInsufficientFundsPolicy returns the configured policy for scheduled transfers the source wallet cannot cover.
+func defaultInsufficientFundsPolicy() string {
+	policy := config.GetOrDefault(config.SCHEDULER_ON_INSUFFICIENT, models.InsufficientFundsRetry)
+	if policy != models.InsufficientFundsRetry && policy != models.InsufficientFundsSkip {
+		return models.InsufficientFundsRetry
+	}
+	return policy
+}
+
+// scheduledTransferLocation returns the URL path of the scheduled transfer resource.
+func scheduledTransferLocation(scheduleId int64) string {
+	return fmt.Sprintf("/scheduled-transfers/%d", scheduleId)
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rudithu/CRYPTO-WalletApp/adapters"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/services"
+)
+
+// statementTemplate renders a statement as a printable page.
+var statementTemplate = template.Must(template.New("statement").Funcs(template.FuncMap{
+	"money": func(d models.MoneyDecimal) string { return d.StringFixed(models.AmountScale) },
+	"time":  func(t time.Time) string { return t.Format("2006-01-02 15:04") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Statement {{.Period}} - wallet {{.WalletID}}</title>
+<style>
+  body { font-family: sans-serif; font-size: 12px; margin: 2em; }
+  table { border-collapse: collapse; width: 100%; }
+  th, td { border-bottom: 1px solid #ccc; padding: 4px 6px; text-align: left; }
+  .amount { text-align: right; white-space: nowrap; }
+  .summary td { border: none; }
+  @media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>Statement {{.Period}}</h1>
+<p>Wallet {{.WalletID}} ({{.Currency}}), {{.From}} to {{.To}}</p>
+<table class="summary">
+  <tr><td>Opening balance</td><td class="amount">{{money .OpeningBalance}} {{.Currency}}</td></tr>
+  <tr><td>Total credits</td><td class="amount">{{money .TotalCredits}} {{.Currency}}</td></tr>
+  <tr><td>Total debits</td><td class="amount">{{money .TotalDebits}} {{.Currency}}</td></tr>
+  <tr><td>of which fees</td><td class="amount">{{money .TotalFees}} {{.Currency}}</td></tr>
+  <tr><td>Closing balance</td><td class="amount">{{money .ClosingBalance}} {{.Currency}}</td></tr>
+</table>
+<h2>Transactions</h2>
+<table>
+  <tr><th>Date (UTC)</th><th>Transaction</th><th>Type</th><th>Details</th><th class="amount">Amount</th><th class="amount">Balance</th></tr>
+  {{- range .Entries}}
+  <tr>
+    <td>{{time .At}}</td>
+    <td>{{.TransactionID}}</td>
+    <td>{{.Type}}{{if .Reversal}} (reversal){{end}}</td>
+    <td>
+      {{- .Description}}{{if .ExternalReference}} [{{.ExternalReference}}]{{end}}
+      {{- if .CounterpartyWalletID}} wallet {{.CounterpartyWalletID}}{{end}}
+      {{- if .FeeFor}} fee for transaction {{.FeeFor}}{{end}}
+      {{- with .FX}} {{money .CounterAmount}} {{.CounterCurrency}} at {{.Rate}}{{end -}}
+    </td>
+    <td class="amount">{{money .Amount}}</td>
+    <td class="amount">{{money .Balance}}</td>
+  </tr>
+  {{- else}}
+  <tr><td colspan="6">No transactions</td></tr>
+  {{- end}}
+</table>
+<p>Generated {{time .GeneratedAt}} UTC</p>
+</body>
+</html>
+`))
+
+// HandleWalletStatement handles the request for the statement of a wallet for a month that has ended,
+// as JSON or, depending on the format query parameter, as a printable page (html) or a camt.053 document
+// (camt053). Statements are generated by a job once the month has ended and models.BalanceSnapshotDelay
+// has passed; one the job has not generated yet is generated and stored here after the same delay.
+func (h *HandlerDB) HandleWalletStatement(w http.ResponseWriter, r *http.Request) {
+	period, err := time.Parse(models.StatementPeriodLayout, mux.Vars(r)["period"])
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "period must be a month as YYYY-MM"))
+		return
+	}
+
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format != "" && format != "json" && format != "html" && format != "camt053" {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "format must be one of json, html or camt053"))
+		return
+	}
+
+	now := time.Now().UTC()
+	until := period.AddDate(0, 1, 0)
+	if availableAt := models.StatementAvailableAt(period); availableAt.After(now) {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "the statement for %s is only available once the month has ended",
+			period.Format(models.StatementPeriodLayout)).
+			WithDetails(map[string]string{"available_at": availableAt.Format(time.RFC3339)}))
+		return
+	}
+
+	wallet, ok := h.loadWallet(w, r)
+	if !ok {
+		return
+	}
+
+	if !wallet.CreatedAt.Before(until) {
+		writeError(w, r, models.Errorf(models.ErrCodeStatementNotFound, "wallet %d has no statement for %s, it was created later",
+			wallet.ID, period.Format(models.StatementPeriodLayout)))
+		return
+	}
+
+	statement, err := db.GetStatement(h.DB, wallet.ID, period)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if statement == nil {
+		statement, err = services.GenerateStatement(h.DB, *wallet, period, now)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+
+	if format == "camt053" {
+		id := fmt.Sprintf("%d-%s", wallet.ID, period.Format("200601"))
+		writeXML(w, r, fmt.Sprintf("camt053-%s.xml", id), adapters.ToCamt053Document(*statement, until, id))
+		return
+	}
+
+	resp := adapters.ToStatementResp(*statement)
+	if format == "html" {
+		writeHTML(w, r, statementTemplate, resp)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
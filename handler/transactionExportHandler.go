@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/adapters"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
+)
+
+// exportFlushRows is how many transactions are exported between two flushes to the client.
+const exportFlushRows = 500
+
+// ofxBankID identifies the wallet service as the bank of the accounts in OFX statements.
+const ofxBankID = "CRYPTOWALLET"
+
+const ofxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS><DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE></SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1>
+`
+
+const ofxStatementStart = `<STMTTRNRS><TRNUID>%d</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>
+<STMTRS><CURDEF>%s</CURDEF><BANKACCTFROM><BANKID>%s</BANKID><ACCTID>%d</ACCTID><ACCTTYPE>%s</ACCTTYPE></BANKACCTFROM>
+<BANKTRANLIST><DTSTART>%s</DTSTART><DTEND>%s</DTEND>
+`
+
+const ofxStatementEnd = `
+</BANKTRANLIST><LEDGERBAL><BALAMT>%s</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL></STMTRS></STMTTRNRS>
+`
+
+const ofxFooter = `</BANKMSGSRSV1>
+</OFX>
+`
+
+// exportWriter writes a streamed export to the client and records whether anything was sent yet:
+// until then an error can still be reported as an error response.
+type exportWriter struct {
+	w       http.ResponseWriter
+	started bool
+}
+
+// newExportWriter sets the headers of an export downloaded as filename. The status, 200 OK, is only sent
+// with the first bytes of the export.
+func newExportWriter(w http.ResponseWriter, contentType string, filename string) *exportWriter {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	return &exportWriter{w: w}
+}
+
+func (e *exportWriter) Write(p []byte) (int, error) {
+	e.started = true
+	return e.w.Write(p)
+}
+
+// flush sends what was written so far to the client.
+func (e *exportWriter) flush() {
+	http.NewResponseController(e.w).Flush()
+}
+
+// fail reports err as an error response when nothing was sent yet. Otherwise the status has gone out
+// already, so the export is cut short and err logged.
+func (e *exportWriter) fail(r *http.Request, err error) {
+	if !e.started {
+		e.w.Header().Del("Content-Disposition")
+		writeError(e.w, r, err)
+		return
+	}
+	log.Printf("ERROR: export on %s %s aborted: %v", r.Method, r.URL.Path, err)
+}
+
+// txHistoryFormat reads the format of the transaction history, json, csv or ofx, from the format query
+// parameter or, when it is not set, from the Accept header.
+func txHistoryFormat(r *http.Request) (string, error) {
+	format := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format")))
+	if format == "" {
+		accept := r.Header.Get("Accept")
+		switch {
+		case strings.Contains(accept, "text/csv"):
+			format = "csv"
+		case strings.Contains(accept, "application/x-ofx"):
+			format = "ofx"
+		default:
+			format = "json"
+		}
+	}
+
+	switch format {
+	case "json", "csv", "ofx":
+		return format, nil
+	}
+	return "", models.Errorf(models.ErrCodeValidationFailed, "format must be one of json, csv or ofx")
+}
+
+// exportTxHistoryCSV streams the transactions of wallets matching filter as CSV, oldest first, one record
+// per transaction.
+func (h *HandlerDB) exportTxHistoryCSV(w http.ResponseWriter, r *http.Request, user *models.User, wallets []models.Wallet, filter models.TransactionFilter) {
+	walletsById := make(map[int64]models.Wallet, len(wallets))
+	walletIds := make([]int64, 0, len(wallets))
+	for _, wallet := range wallets {
+		walletsById[wallet.ID] = wallet
+		walletIds = append(walletIds, wallet.ID)
+	}
+
+	out := newExportWriter(w, "text/csv", fmt.Sprintf("transactions-%d.csv", user.ID))
+	cw := csv.NewWriter(out)
+	cw.Write(adapters.TransactionCSVHeader)
+
+	rows := 0
+	err := db.StreamTransactionsByWalletIDs(h.DB, walletIds, filter, func(t models.Transaction) error {
+		if err := cw.Write(adapters.ToTransactionCSVRecord(walletsById[t.WalletId], t)); err != nil {
+			return err
+		}
+		if rows++; rows%exportFlushRows == 0 {
+			cw.Flush()
+			out.flush()
+		}
+		return cw.Error()
+	})
+	if err != nil {
+		out.fail(r, err)
+		return
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		out.fail(r, err)
+	}
+}
+
+// exportTxHistoryOFX streams the transactions of wallets matching filter as an OFX 2.2 document with one
+// bank statement per wallet. Only completed and reversed transactions are listed, see adapters.ToOfxTransactions.
+func (h *HandlerDB) exportTxHistoryOFX(w http.ResponseWriter, r *http.Request, user *models.User, wallets []models.Wallet, filter models.TransactionFilter) {
+	now := time.Now().UTC()
+	until := now
+	if !filter.Until.IsZero() {
+		until = filter.Until
+	}
+
+	// the ledger balances are read first, so that failing to read them is reported as an error
+	balances := make([]decimal.Decimal, len(wallets))
+	for i, wallet := range wallets {
+		balances[i] = wallet.Balance
+		if filter.Until.IsZero() {
+			continue
+		}
+		balance, err := db.GetWalletBalanceAt(h.DB, wallet.ID, until)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if balance != nil {
+			balances[i] = *balance
+		}
+	}
+
+	out := newExportWriter(w, "application/x-ofx", fmt.Sprintf("transactions-%d.ofx", user.ID))
+	bw := bufio.NewWriter(out)
+	enc := xml.NewEncoder(bw)
+	fmt.Fprintf(bw, ofxHeader, adapters.ToOfxDateTime(now))
+
+	rows := 0
+	for i, wallet := range wallets {
+		start := wallet.CreatedAt
+		if !filter.From.IsZero() {
+			start = filter.From
+		}
+		accountType := "CHECKING"
+		if wallet.Type == "saving" {
+			accountType = "SAVINGS"
+		}
+		fmt.Fprintf(bw, ofxStatementStart, i+1, xmlEscape(wallet.Currency), ofxBankID, wallet.ID, accountType,
+			adapters.ToOfxDateTime(start), adapters.ToOfxDateTime(until))
+
+		err := db.StreamTransactionsByWalletIDs(h.DB, []int64{wallet.ID}, filter, func(t models.Transaction) error {
+			for _, o := range adapters.ToOfxTransactions(t, until) {
+				if err := enc.Encode(o); err != nil {
+					return err
+				}
+			}
+			if rows++; rows%exportFlushRows == 0 {
+				if err := bw.Flush(); err != nil {
+					return err
+				}
+				out.flush()
+			}
+			return nil
+		})
+		if err != nil {
+			out.fail(r, err)
+			return
+		}
+
+		fmt.Fprintf(bw, ofxStatementEnd, balances[i].StringFixed(models.AmountScale), adapters.ToOfxDateTime(until))
+	}
+
+	fmt.Fprint(bw, ofxFooter)
+	if err := bw.Flush(); err != nil {
+		out.fail(r, err)
+	}
+}
+
+// xmlEscape escapes s to be written as XML text.
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
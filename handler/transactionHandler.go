@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/rudithu/CRYPTO-WalletApp/adapters"
+	"github.com/rudithu/CRYPTO-WalletApp/config"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// HandleGetTransaction handles the GET request to retrieve a single transaction by its ID.
+// Transfers are enriched with the counterparty, the opposite leg and the rate applied.
+func (h *HandlerDB) HandleGetTransaction(w http.ResponseWriter, r *http.Request) {
+	// Extract transaction ID from URL path variables
+	vars := mux.Vars(r)
+	txnIdStr := vars["id"]
+
+	txnId, err := strconv.ParseInt(txnIdStr, 10, 64)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid transaction id"))
+		return
+	}
+
+	txn, err := db.GetTransactionById(h.DB, txnId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if txn == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeTxnNotFound, "transaction %d not found", txnId))
+		return
+	}
+
+	// Retrieve the wallet owning the transaction to report its currency
+	wallet, err := db.GetWalletById(h.DB, txn.WalletId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if wallet == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeWalletNotFound, "wallet %d not found", txn.WalletId))
+		return
+	}
+
+	resp := adapters.ToTransactionResp(*txn, wallet.Currency, nil)
+
+	if txn.CounterpartyWalletId.Valid {
+		counterparties, err := db.GetTransferCounterparties(h.DB, []int64{txn.ID})
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		if cp, ok := counterparties[txn.ID]; ok {
+			namePolicy := config.GetOrDefault(config.PRIVACY_COUNTERPARTY_NAME, models.CounterpartyNameMasked)
+			resp.TransferDetail = adapters.ToTransferDetail(*txn, cp, wallet.UserId, namePolicy)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// HandleUpdateTransactionNotes handles the PATCH request to edit the description and tags
+// of an existing transaction. The updated transaction is returned.
+func (h *HandlerDB) HandleUpdateTransactionNotes(w http.ResponseWriter, r *http.Request) {
+	// Extract transaction ID from URL path variables
+	vars := mux.Vars(r)
+	txnIdStr := vars["id"]
+
+	txnId, err := strconv.ParseInt(txnIdStr, 10, 64)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid transaction id"))
+		return
+	}
+
+	// Decode the JSON request body into TransactionNotesRequest struct
+	var msg models.TransactionNotesRequest
+	err = json.NewDecoder(r.Body).Decode(&msg)
+	if err != nil {
+		writeError(w, r, models.WrapError(models.ErrCodeMalformedRequest, err, "request body is not valid JSON"))
+		return
+	}
+
+	if err = msg.ValidateRequest(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	txn, err := db.GetTransactionById(h.DB, txnId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if txn == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeTxnNotFound, "transaction %d not found", txnId))
+		return
+	}
+
+	wallet, err := db.GetWalletById(h.DB, txn.WalletId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if wallet == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeWalletNotFound, "wallet %d not found", txn.WalletId))
+		return
+	}
+
+	err = db.UpdateTransactionNotes(h.DB, txnId, msg)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if msg.Description != nil {
+		txn.Description = models.NullString(*msg.Description)
+	}
+	if msg.Tags != nil {
+		txn.Tags = *msg.Tags
+	}
+
+	writeJSON(w, http.StatusOK, adapters.ToTransactionResp(*txn, wallet.Currency, nil))
+}
+
+// HandleUpdateTransactionStatus handles the POST request to move a deposit or withdrawal
+// to its next status. The balance effect of the transition is applied together with the status change
+// and the updated transaction is returned.
+func (h *HandlerDB) HandleUpdateTransactionStatus(w http.ResponseWriter, r *http.Request) {
+	// Extract transaction ID from URL path variables
+	vars := mux.Vars(r)
+	txnIdStr := vars["id"]
+
+	txnId, err := strconv.ParseInt(txnIdStr, 10, 64)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid transaction id"))
+		return
+	}
+
+	// Decode the JSON request body into TransactionStatusRequest struct
+	var msg models.TransactionStatusRequest
+	err = json.NewDecoder(r.Body).Decode(&msg)
+	if err != nil {
+		writeError(w, r, models.WrapError(models.ErrCodeMalformedRequest, err, "request body is not valid JSON"))
+		return
+	}
+
+	if err = msg.ValidateRequest(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	txn := models.Transaction{ID: txnId}
+	err = db.UpdateTransactionStatus(h.DB, &txn, msg.Status)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	wallet, err := db.GetWalletById(h.DB, txn.WalletId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if wallet == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeWalletNotFound, "wallet %d not found", txn.WalletId))
+		return
+	}
+
+	var balance *decimal.Decimal
+	if txn.Status != models.TxnStatusFailed {
+		balance = &txn.BalanceAfter
+	}
+
+	writeJSON(w, http.StatusOK, adapters.ToTransactionResp(txn, wallet.Currency, balance))
+}
+
+// settledBalance returns the balance after a transaction created in the current request,
+// or nil when the transaction is pending and has not changed the balance.
+func settledBalance(txn models.Transaction) *decimal.Decimal {
+	if txn.Status == models.TxnStatusPending {
+		return nil
+	}
+	return &txn.BalanceAfter
+}
+
+// transactionLocation returns the URL path of the transaction resource.
+func transactionLocation(txnId int64) string {
+	return fmt.Sprintf("/transactions/%d", txnId)
+}
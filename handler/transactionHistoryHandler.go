@@ -1,19 +1,24 @@
 package handler
 
 import (
-	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 
 	"github.com/rudithu/CRYPTO-WalletApp/adapters"
+	"github.com/rudithu/CRYPTO-WalletApp/config"
 	"github.com/rudithu/CRYPTO-WalletApp/db"
 	"github.com/rudithu/CRYPTO-WalletApp/models"
 )
 
 // HandleTxHistory handles the request to fetch a user's wallet transaction history.
-// It supports optional filtering by wallet ID via query parameter.
+// It supports optional filtering by wallet ID, by a search text (q) matched against the
+// description and external reference, by one or more tags, by status and by creation date via query parameters.
+// The total is in the reporting currency of the user unless the reporting_ccy query parameter sets another one.
+// The history is exported as CSV or OFX instead of JSON when the format query parameter or the Accept header asks for it.
 func (h *HandlerDB) HandleTxHistory(w http.ResponseWriter, r *http.Request) {
 	// Extract user ID from URL path variables
 	vars := mux.Vars(r)
@@ -21,7 +26,7 @@ func (h *HandlerDB) HandleTxHistory(w http.ResponseWriter, r *http.Request) {
 
 	userId, err := strconv.ParseInt(userIdStr, 10, 64)
 	if err != nil {
-		http.Error(w, "invalid user id", http.StatusBadRequest)
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid user id"))
 		return
 	}
 
@@ -31,15 +36,69 @@ func (h *HandlerDB) HandleTxHistory(w http.ResponseWriter, r *http.Request) {
 	if walletIdStr != "" {
 		walletId, err = strconv.ParseInt(walletIdStr, 10, 64)
 		if err != nil {
-			http.Error(w, "invalid wallet id", http.StatusBadRequest)
+			writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid wallet id"))
 			return
 		}
 	}
 
+	reportingCcy, err := reportingCcyParam(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Get optional search filters
+	filter := models.TransactionFilter{
+		Query: strings.TrimSpace(r.URL.Query().Get("q")),
+	}
+	for _, tag := range r.URL.Query()["tag"] {
+		filter.Tags = append(filter.Tags, strings.ToLower(strings.TrimSpace(tag)))
+	}
+
+	filter.Status = strings.ToLower(strings.TrimSpace(r.URL.Query().Get("status")))
+	switch filter.Status {
+	case "", models.TxnStatusPending, models.TxnStatusCompleted, models.TxnStatusFailed, models.TxnStatusReversed:
+	default:
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid status"))
+		return
+	}
+
+	// Get optional creation date range, both dates included
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		filter.From, err = time.Parse(time.DateOnly, fromStr)
+		if err != nil {
+			writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "from must be a date as YYYY-MM-DD"))
+			return
+		}
+	}
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		to, err := time.Parse(time.DateOnly, toStr)
+		if err != nil {
+			writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "to must be a date as YYYY-MM-DD"))
+			return
+		}
+		filter.Until = to.AddDate(0, 0, 1)
+	}
+	if !filter.From.IsZero() && !filter.Until.IsZero() && !filter.From.Before(filter.Until) {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "from must not be after to"))
+		return
+	}
+
+	format, err := txHistoryFormat(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
 	// Fetch user information from the database
 	userInfo, err := db.GetUserById(h.DB, userId)
 	if err != nil {
-		http.Error(w, "error getting user info", http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
+	}
+
+	if userInfo == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeUserNotFound, "user %d not found", userId))
 		return
 	}
 
@@ -47,20 +106,38 @@ func (h *HandlerDB) HandleTxHistory(w http.ResponseWriter, r *http.Request) {
 	if walletIdStr == "" {
 		// If wallet ID not specified, get all wallets for the user
 		selectedWallets, err = db.GetWalletByUserIDs(h.DB, []int64{userId})
-		if err != nil || selectedWallets == nil {
-			http.Error(w, "error getting wallet info", http.StatusInternalServerError)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if selectedWallets == nil {
+			writeError(w, r, models.Errorf(models.ErrCodeWalletNotFound, "user %d has no wallet", userId))
 			return
 		}
 	} else {
 		// If wallet ID specified, fetch that specific wallet
 		wallet, err := db.GetWalletById(h.DB, walletId)
-		if err != nil || wallet == nil {
-			http.Error(w, "error getting wallet info", http.StatusInternalServerError)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if wallet == nil || wallet.UserId != userId {
+			writeError(w, r, models.Errorf(models.ErrCodeWalletNotFound, "wallet %d not found for user %d", walletId, userId))
 			return
 		}
 		selectedWallets = []models.Wallet{*wallet}
 	}
 
+	// Exports stream the transactions as they are read
+	switch format {
+	case "csv":
+		h.exportTxHistoryCSV(w, r, userInfo, selectedWallets, filter)
+		return
+	case "ofx":
+		h.exportTxHistoryOFX(w, r, userInfo, selectedWallets, filter)
+		return
+	}
+
 	// Collect all wallet IDs to query transactions
 	var walletIds []int64
 	for _, w := range selectedWallets {
@@ -70,42 +147,52 @@ func (h *HandlerDB) HandleTxHistory(w http.ResponseWriter, r *http.Request) {
 	// Retrieve transactions for the selected wallets
 	var txns []models.Transaction
 	if walletIds != nil {
-		transactions, err := db.GetTransactionsByWalletIDs(h.DB, walletIds)
+		transactions, err := db.GetTransactionsByWalletIDs(h.DB, walletIds, filter)
 		if err != nil {
-			http.Error(w, "Error Getting Transaction Details", http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 		txns = transactions
 	}
 
-	// Collect currencies from selected wallets that are not the base currency
-	var ccys []string
-	for _, sw := range selectedWallets {
-		if sw.Currency != models.BaseCcy {
-			ccys = append(ccys, sw.Currency)
+	// Collect transfer transactions to enrich them with counterparty details,
+	// and conversion transactions to show each conversion as a single item
+	var transferTxnIds, conversionTxnIds []int64
+	for _, t := range txns {
+		if t.Type == models.TxnTypeConversionOut || t.Type == models.TxnTypeConversionIn {
+			conversionTxnIds = append(conversionTxnIds, t.ID)
+		} else if t.CounterpartyWalletId.Valid {
+			transferTxnIds = append(transferTxnIds, t.ID)
 		}
 	}
 
-	ccyMap := make(map[string]models.CcyRateToBaseCcy)
-	if len(ccys) > 0 {
-		// Fetch currency rates from the database
-		rates, err := db.GetCcyRateToBaseCcy(h.DB, ccys)
-		if err != nil {
-			http.Error(w, "error to get currency rate", http.StatusInternalServerError)
-			return
-		}
+	counterparties, err := db.GetTransferCounterparties(h.DB, transferTxnIds)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 
-		for _, rate := range rates {
-			ccyMap[rate.Ccy] = rate
-		}
+	conversions, err := db.GetConversionsByTransactionIDs(h.DB, conversionTxnIds)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 
+	// Fetch the conversion rates to the reporting currency
+	if reportingCcy == "" {
+		reportingCcy = userInfo.ReportingCurrency
+	}
+	ccyMap, err := h.ratesToReportingCcy(selectedWallets, reportingCcy)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
 
 	// Prepare the response using adapter to convert DB models into response format
-	resp := adapters.ToWalletDetailsResp(userInfo, selectedWallets, txns, ccyMap)
+	namePolicy := config.GetOrDefault(config.PRIVACY_COUNTERPARTY_NAME, models.CounterpartyNameMasked)
+	resp := adapters.ToWalletDetailsResp(userInfo, selectedWallets, txns, ccyMap, reportingCcy, counterparties, conversions, namePolicy)
 
 	// Set response content type to JSON and write the response
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	writeJSON(w, http.StatusOK, resp)
 
 }
@@ -1,20 +1,20 @@
 package handler
 
 import (
-	"database/sql"
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/gorilla/mux"
+	"github.com/rudithu/CRYPTO-WalletApp/adapters"
 	"github.com/rudithu/CRYPTO-WalletApp/db"
 	"github.com/rudithu/CRYPTO-WalletApp/models"
-	"github.com/shopspring/decimal"
+	"github.com/rudithu/CRYPTO-WalletApp/services"
 )
 
 // HandleTransferMoney handles transferring money from one wallet to another.
 // It supports transfers either by specifying a destination wallet ID or a destination user ID.
+// Both legs of the transfer and the rate used are returned with 201 Created.
 func (h *HandlerDB) HandleTransferMoney(w http.ResponseWriter, r *http.Request) {
 	// Extract wallet ID from the URL path variables
 	vars := mux.Vars(r)
@@ -23,7 +23,7 @@ func (h *HandlerDB) HandleTransferMoney(w http.ResponseWriter, r *http.Request)
 	// Validate and parse wallet ID to int64
 	walletId, err := strconv.ParseInt(walletIdStr, 10, 64)
 	if err != nil {
-		http.Error(w, "invalid wallet id", http.StatusBadRequest)
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid wallet id"))
 		return
 	}
 
@@ -31,111 +31,86 @@ func (h *HandlerDB) HandleTransferMoney(w http.ResponseWriter, r *http.Request)
 	var msg models.TransactionRequest
 	err = json.NewDecoder(r.Body).Decode(&msg)
 	if err != nil {
-		http.Error(w, "bad request", http.StatusBadRequest)
+		writeError(w, r, models.WrapError(models.ErrCodeMalformedRequest, err, "request body is not valid JSON"))
 		return
 	}
 
 	// Validate the request payload according to transfer out transaction type
 	if err = msg.ValidateRequest(models.TxnTypeTransferOut); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
-	// Retrieve the source wallet from database
-	sourceWallet, err := db.GetWalletById(h.DB, walletId)
+	// Resolve both wallets and the conversion rate
+	plan, err := services.PrepareTransfer(h.DB, walletId, msg)
 	if err != nil {
-		http.Error(w, "failed to get source wallet info", http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
-	// Check if source wallet exists
-	if sourceWallet == nil {
-		http.Error(w, "source wallet not found", http.StatusNotFound)
+	// Perform the transfer update atomically in the database
+	err = db.TransferUpdate(h.DB, &plan.Transfer, &plan.TransferOut, &plan.TransferIn)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	// Validate that source wallet has enough balance for the transfer amount
-	if sourceWallet.Balance.LessThan(msg.Amount) {
-		http.Error(w, "transferred is not allowed", http.StatusBadRequest)
-		return
+	var quoteId *int64
+	if plan.Transfer.QuoteId.Valid {
+		quoteId = &plan.Transfer.QuoteId.Int64
 	}
 
-	var targetWallet models.Wallet
-
-	// Determine the target wallet based on provided request data
-	if msg.DestinationUserID != nil {
-		// Transfer to another user's wallet
-
-		// Prevent transferring to own wallet using user ID (must use wallet ID instead)
-		if sourceWallet.UserId == *msg.DestinationUserID {
-			http.Error(w, "please use destination_wallet_id to transfer for the same user", http.StatusBadRequest)
-			return
-		}
-
-		// Get default wallet(s) or wallets with matching currency for the target user
-		targetWallets, err := db.GetDefaultWalletOrCurrencyByUserID(h.DB, *msg.DestinationUserID, sourceWallet.Currency)
-		if err != nil {
-			http.Error(w, "failed to get target wallet", http.StatusBadRequest)
-			return
-		}
-
-		// Pick the wallet with matching currency or the default wallet if none match
-		for _, w := range targetWallets {
-			if sourceWallet.Currency == w.Currency {
-				targetWallet = w
-				break
-			} else if w.IsDefault {
-				targetWallet = w
-			}
-		}
-
-	} else if msg.DestinationWalletID != nil {
-		// Transfer to a specific wallet by wallet ID
-		tWallet, err := db.GetWalletById(h.DB, *msg.DestinationWalletID)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to find target wallet %d", msg.DestinationWalletID), http.StatusBadRequest)
-			return
-		}
-		targetWallet = *tWallet
-	}
+	// Respond with both legs of the transfer, pointing Location at the transfer
+	w.Header().Set("Location", transferLocation(plan.Transfer.ID))
+	writeJSON(w, http.StatusCreated, models.TransferResponse{
+		ID:          plan.Transfer.ID,
+		Status:      plan.Transfer.Status,
+		Rate:        plan.Transfer.Rate,
+		RatePath:    plan.Transfer.RatePath,
+		QuoteID:     quoteId,
+		TransferOut: adapters.ToTransactionResp(plan.TransferOut, plan.SourceWallet.Currency, &plan.TransferOut.BalanceAfter),
+		TransferIn:  adapters.ToTransactionResp(plan.TransferIn, plan.TargetWallet.Currency, &plan.TransferIn.BalanceAfter),
+	})
+}
 
-	// Create transaction record for transfer out from source wallet
-	txnOut := models.Transaction{
-		WalletId:             walletId,
-		Type:                 models.TxnTypeTransferOut,
-		Amount:               msg.Amount,
-		CounterpartyWalletId: sql.NullInt64{Int64: targetWallet.ID, Valid: true},
-	}
+// HandlePreviewTransfer handles previewing a transfer from a wallet without making it.
+// The target wallet is resolved and the rate and the fee are applied as by HandleTransferMoney.
+// The preview is returned with 200 OK, listing every reason the transfer would be rejected;
+// only a malformed request or an unknown source wallet fail the request itself.
+func (h *HandlerDB) HandlePreviewTransfer(w http.ResponseWriter, r *http.Request) {
+	// Extract wallet ID from the URL path variables
+	vars := mux.Vars(r)
+	walletIdStr := vars["id"]
 
-	var targetAmount decimal.Decimal
-
-	// Calculate target amount considering currency conversion if necessary
-	if sourceWallet.Currency == targetWallet.Currency {
-		targetAmount = msg.Amount
-	} else {
-		rate, err := db.GetCcyRate(h.DB, sourceWallet.Currency, targetWallet.Currency)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-		targetAmount = msg.Amount.Mul(rate)
+	walletId, err := strconv.ParseInt(walletIdStr, 10, 64)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid wallet id"))
+		return
 	}
 
-	// Create transaction record for transfer in to target wallet
-	txnIn := models.Transaction{
-		WalletId:             targetWallet.ID,
-		Type:                 models.TxnTypeTransferIn,
-		Amount:               targetAmount,
-		CounterpartyWalletId: sql.NullInt64{Int64: walletId, Valid: true},
+	var msg models.TransactionRequest
+	err = json.NewDecoder(r.Body).Decode(&msg)
+	if err != nil {
+		writeError(w, r, models.WrapError(models.ErrCodeMalformedRequest, err, "request body is not valid JSON"))
+		return
 	}
 
-	// Perform the transfer update atomically in the database
-	err = db.TransferUpdate(h.DB, &txnOut, &txnIn)
+	preview, err := services.PreviewTransfer(h.DB, walletId, msg)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
 	}
 
-	// Respond with no content status indicating success
-	w.WriteHeader(http.StatusNoContent)
+	var target *models.Wallet
+	if preview.TargetResolved {
+		target = &preview.TargetWallet
+	}
+
+	var transfer *models.Transfer
+	if preview.RateResolved {
+		transfer = &preview.Transfer
+	}
 
+	writeJSON(w, http.StatusOK, adapters.ToTransferPreviewResp(preview.SourceWallet, msg.Amount, preview.AvailableBalance,
+		target, transfer, preview.TransferOut.Fee, preview.Errors))
 }
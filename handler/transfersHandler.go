@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rudithu/CRYPTO-WalletApp/adapters"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/services"
+)
+
+// HandleGetTransfer handles the request to fetch a single transfer with both of its legs.
+func (h *HandlerDB) HandleGetTransfer(w http.ResponseWriter, r *http.Request) {
+	// Extract transfer ID from URL path variables
+	vars := mux.Vars(r)
+	transferIdStr := vars["id"]
+
+	transferId, err := strconv.ParseInt(transferIdStr, 10, 64)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid transfer id"))
+		return
+	}
+
+	transfer, err := db.GetTransferById(h.DB, transferId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if transfer == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeTransferNotFound, "transfer %d not found", transferId))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, adapters.ToTransferSummary(*transfer))
+}
+
+// HandleUserTransfers handles the request to list the transfers sent or received by
+// any wallet of a user, newest first.
+func (h *HandlerDB) HandleUserTransfers(w http.ResponseWriter, r *http.Request) {
+	// Extract user ID from URL path variables
+	vars := mux.Vars(r)
+	userIdStr := vars["id"]
+
+	userId, err := strconv.ParseInt(userIdStr, 10, 64)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid user id"))
+		return
+	}
+
+	userInfo, err := db.GetUserById(h.DB, userId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if userInfo == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeUserNotFound, "user %d not found", userId))
+		return
+	}
+
+	wallets, err := db.GetWalletByUserIDs(h.DB, []int64{userId})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	var walletIds []int64
+	for _, w := range wallets {
+		walletIds = append(walletIds, w.ID)
+	}
+
+	transfers, err := db.GetTransfersByWalletIDs(h.DB, walletIds)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, adapters.ToUserTransfersResp(userInfo, transfers))
+}
+
+// transferLocation returns the URL path of the transfer resource.
+func transferLocation(transferId int64) string {
+	return fmt.Sprintf("/transfers/%d", transferId)
+}
+
+// HandleReverseTransfer handles reversing a transfer in full or in part, as prepared by services.PrepareReversal.
+func (h *HandlerDB) HandleReverseTransfer(w http.ResponseWriter, r *http.Request) {
+	// Extract transfer ID from URL path variables
+	vars := mux.Vars(r)
+	transferIdStr := vars["id"]
+
+	transferId, err := strconv.ParseInt(transferIdStr, 10, 64)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid transfer id"))
+		return
+	}
+
+	// Decode the optional JSON request body, an empty body reverses the full amount
+	var msg models.TransferReversalRequest
+	err = json.NewDecoder(r.Body).Decode(&msg)
+	if err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, r, models.WrapError(models.ErrCodeMalformedRequest, err, "request body is not valid JSON"))
+		return
+	}
+
+	if err = msg.ValidateRequest(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	plan, err := services.PrepareReversal(h.DB, transferId, msg)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Perform the reversal atomically in the database
+	err = db.ReverseTransferUpdate(h.DB, plan.Original.ID, &plan.Transfer, &plan.TransferOut, &plan.TransferIn)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", transferLocation(plan.Transfer.ID))
+	writeJSON(w, http.StatusCreated, models.TransferResponse{
+		ID:          plan.Transfer.ID,
+		Status:      plan.Transfer.Status,
+		ReversalOf:  &plan.Original.ID,
+		Rate:        plan.Transfer.Rate,
+		RatePath:    plan.Transfer.RatePath,
+		TransferOut: adapters.ToTransactionResp(plan.TransferOut, plan.Original.TargetCurrency, &plan.TransferOut.BalanceAfter),
+		TransferIn:  adapters.ToTransactionResp(plan.TransferIn, plan.Original.SourceCurrency, &plan.TransferIn.BalanceAfter),
+	})
+}
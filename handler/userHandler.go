@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rudithu/CRYPTO-WalletApp/adapters"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/services"
+)
+
+// HandleUpdateUser handles the PATCH request to update the preferences of a user, the currency
+// the totals of the user's wallets are reported in.
+func (h *HandlerDB) HandleUpdateUser(w http.ResponseWriter, r *http.Request) {
+	// Extract user ID from the URL path
+	vars := mux.Vars(r)
+	userId, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid user id"))
+		return
+	}
+
+	// Decode the JSON request body into UserUpdateRequest struct
+	var msg models.UserUpdateRequest
+	err = json.NewDecoder(r.Body).Decode(&msg)
+	if err != nil {
+		writeError(w, r, models.WrapError(models.ErrCodeMalformedRequest, err, "request body is not valid JSON"))
+		return
+	}
+
+	if err = msg.ValidateRequest(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	user, err := db.UpdateUserReportingCurrency(h.DB, userId, msg.ReportingCurrency)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if user == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeUserNotFound, "user %d not found", userId))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, adapters.ToUserResp(*user))
+}
+
+// reportingCcyParam returns the reporting_ccy query parameter, empty when it is not set.
+func reportingCcyParam(r *http.Request) (string, error) {
+	ccy := r.URL.Query().Get("reporting_ccy")
+	if ccy == "" {
+		return "", nil
+	}
+	return models.NormalizeCcy(ccy)
+}
+
+// ratesToReportingCcy returns the rate from the currency of each wallet to reportingCcy, by currency.
+// Currencies without a rate are left out.
+func (h *HandlerDB) ratesToReportingCcy(wallets []models.Wallet, reportingCcy string) (map[string]models.CcyRateToReportingCcy, error) {
+	var ccys []string
+	for _, w := range wallets {
+		if w.Currency != reportingCcy {
+			ccys = append(ccys, w.Currency)
+		}
+	}
+
+	ccyMap := make(map[string]models.CcyRateToReportingCcy)
+	if len(ccys) == 0 {
+		return ccyMap, nil
+	}
+
+	rates, err := db.GetCcyRatesToReportingCcy(h.DB, reportingCcy, ccys, services.RateAgeLimits())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rate := range rates {
+		ccyMap[rate.Ccy] = rate
+	}
+	return ccyMap, nil
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rudithu/CRYPTO-WalletApp/adapters"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// HandleWalletBalance handles the request to fetch the balance of a wallet. Without the as_of query
+// parameter the current ledger and available balances are returned; with it the ledger balance at that
+// time is rebuilt from the transaction history.
+func (h *HandlerDB) HandleWalletBalance(w http.ResponseWriter, r *http.Request) {
+	wallet, ok := h.loadWallet(w, r)
+	if !ok {
+		return
+	}
+
+	asOfStr := r.URL.Query().Get("as_of")
+	if asOfStr == "" {
+		balance, err := db.GetWalletBalance(h.DB, wallet.ID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if balance == nil {
+			writeError(w, r, models.Errorf(models.ErrCodeWalletNotFound, "wallet %d not found", wallet.ID))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, models.WalletBalanceAtResponse{
+			WalletID:         wallet.ID,
+			Currency:         wallet.Currency,
+			Balance:          models.MoneyDecimal{Decimal: balance.Balance},
+			AvailableBalance: &models.MoneyDecimal{Decimal: balance.AvailableBalance},
+			AsOf:             time.Now().UTC(),
+		})
+		return
+	}
+
+	asOf, err := parseAsOf(asOfStr)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if asOf.After(time.Now().UTC()) {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "as_of must not be in the future"))
+		return
+	}
+
+	balance, err := db.GetWalletBalanceAt(h.DB, wallet.ID, asOf)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if balance == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeWalletNotFound, "wallet %d not found", wallet.ID))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, models.WalletBalanceAtResponse{
+		WalletID: wallet.ID,
+		Currency: wallet.Currency,
+		Balance:  models.MoneyDecimal{Decimal: *balance},
+		AsOf:     asOf,
+	})
+}
+
+// HandleWalletBalanceHistory handles the request to fetch the closing balance of a wallet on every day
+// between the from and to query parameters, both dates included. to defaults to today.
+func (h *HandlerDB) HandleWalletBalanceHistory(w http.ResponseWriter, r *http.Request) {
+	wallet, ok := h.loadWallet(w, r)
+	if !ok {
+		return
+	}
+
+	today := models.StartOfDay(time.Now().UTC())
+	from, to, err := balanceHistoryRange(r, today)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	opening, err := db.GetWalletBalanceAt(h.DB, wallet.ID, from)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if opening == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeWalletNotFound, "wallet %d not found", wallet.ID))
+		return
+	}
+
+	events, err := db.GetBalanceEvents(h.DB, wallet.ID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	balances := models.DailyClosingBalances(*opening, from, to, events)
+	writeJSON(w, http.StatusOK, adapters.ToBalanceHistoryResp(*wallet, from, to, balances))
+}
+
+// loadWallet reads the wallet of the id path variable, writing the error response when it is invalid or not found.
+func (h *HandlerDB) loadWallet(w http.ResponseWriter, r *http.Request) (*models.Wallet, bool) {
+	walletId, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid wallet id"))
+		return nil, false
+	}
+
+	wallet, err := db.GetWalletById(h.DB, walletId)
+	if err != nil {
+		writeError(w, r, err)
+		return nil, false
+	}
+	if wallet == nil {
+		writeError(w, r, models.Errorf(models.ErrCodeWalletNotFound, "wallet %d not found", walletId))
+		return nil, false
+	}
+	return wallet, true
+}
+
+// parseAsOf reads an as_of query parameter, either an RFC 3339 timestamp or a date standing for the end of that day.
+func parseAsOf(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	if d, err := time.Parse(time.DateOnly, s); err == nil {
+		return d.AddDate(0, 0, 1), nil
+	}
+	return time.Time{}, models.Errorf(models.ErrCodeValidationFailed, "as_of must be an RFC 3339 timestamp or a date as YYYY-MM-DD")
+}
+
+// balanceHistoryRange reads the from and to dates of a balance history request, to defaulting to today.
+// The range may not end after today or span more than MaxBalanceHistoryDays days.
+func balanceHistoryRange(r *http.Request, today time.Time) (time.Time, time.Time, error) {
+	query := r.URL.Query()
+
+	if query.Get("from") == "" {
+		return time.Time{}, time.Time{}, models.Errorf(models.ErrCodeValidationFailed, "from is mandatory")
+	}
+	from, err := time.Parse(time.DateOnly, query.Get("from"))
+	if err != nil {
+		return time.Time{}, time.Time{}, models.Errorf(models.ErrCodeValidationFailed, "from must be a date as YYYY-MM-DD")
+	}
+
+	to := today
+	if query.Get("to") != "" {
+		to, err = time.Parse(time.DateOnly, query.Get("to"))
+		if err != nil {
+			return time.Time{}, time.Time{}, models.Errorf(models.ErrCodeValidationFailed, "to must be a date as YYYY-MM-DD")
+		}
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, models.Errorf(models.ErrCodeValidationFailed, "from must not be after to")
+	}
+	if to.After(today) {
+		return time.Time{}, time.Time{}, models.Errorf(models.ErrCodeValidationFailed, "to must not be after today")
+	}
+	if days := int(to.Sub(from).Hours()/24) + 1; days > models.MaxBalanceHistoryDays {
+		return time.Time{}, time.Time{}, models.Errorf(models.ErrCodeValidationFailed, "the range must not span more than %d days", models.MaxBalanceHistoryDays).
+			WithDetails(map[string]int{"days": days})
+	}
+	return from, to, nil
+}
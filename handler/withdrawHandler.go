@@ -6,13 +6,16 @@ import (
 	"strconv"
 
 	"github.com/gorilla/mux"
+	"github.com/rudithu/CRYPTO-WalletApp/adapters"
 	"github.com/rudithu/CRYPTO-WalletApp/db"
 	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/services"
 )
 
 // HandleWithdrawMoney handles withdrawal requests from a specific wallet.
-// It validates the wallet ID, parses the withdrawal amount, checks the wallet balance,
-// and updates the wallet balance accordingly.
+// It validates the wallet ID, parses the withdrawal amount, checks the wallet balance, quotes the fee
+// and updates the wallet balance accordingly. The created transaction and its fee are returned with 201 Created.
+// A pending withdrawal reserves the amount and the fee and only updates the balance once it is completed.
 func (h *HandlerDB) HandleWithdrawMoney(w http.ResponseWriter, r *http.Request) {
 	// Extract wallet ID from URL path variables
 	vars := mux.Vars(r)
@@ -20,7 +23,7 @@ func (h *HandlerDB) HandleWithdrawMoney(w http.ResponseWriter, r *http.Request)
 
 	walletId, err := strconv.ParseInt(walletIdStr, 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid Wallet Id", http.StatusBadRequest)
+		writeError(w, r, models.Errorf(models.ErrCodeValidationFailed, "invalid wallet id"))
 		return
 	}
 
@@ -28,47 +31,61 @@ func (h *HandlerDB) HandleWithdrawMoney(w http.ResponseWriter, r *http.Request)
 	var msg models.TransactionRequest
 	err = json.NewDecoder(r.Body).Decode(&msg)
 	if err != nil {
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+		writeError(w, r, models.WrapError(models.ErrCodeMalformedRequest, err, "request body is not valid JSON"))
 		return
 	}
 
 	// Validate the transaction request for withdrawal type
 	if err = msg.ValidateRequest(models.TxnTypeWithdraw); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
 	// Retrieve wallet details from database by wallet ID
 	wallet, err := db.GetWalletById(h.DB, walletId)
 	if err != nil {
-		http.Error(w, "failed to get wallet info", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
 	if wallet == nil {
-		http.Error(w, "wallet not found", http.StatusNotFound)
+		writeError(w, r, models.Errorf(models.ErrCodeWalletNotFound, "wallet %d not found", walletId))
 		return
 	}
 
-	// Check if wallet balance is sufficient for the withdrawal amount
+	// Fail fast when even the ledger balance is not sufficient for the withdrawal amount,
+	// the available balance is checked when the withdrawal is applied
 	if wallet.Balance.LessThan(msg.Amount) {
-		http.Error(w, "withdrawal is not allowed", http.StatusBadRequest)
+		writeError(w, r, models.Errorf(models.ErrCodeInsufficientFunds, "wallet %d does not have enough balance", walletId).
+			WithDetails(map[string]string{"balance": wallet.Balance.String(), "requested": msg.Amount.String()}))
+		return
+	}
+
+	fee, err := services.QuoteFee(h.DB, models.FeeTypeWithdraw, *wallet, wallet.Currency, msg.Amount)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	t := models.Transaction{
-		WalletId: walletId,
-		Amount:   msg.Amount,
-		Type:     models.TxnTypeWithdraw,
+		WalletId:          walletId,
+		Amount:            msg.Amount,
+		Type:              models.TxnTypeWithdraw,
+		Description:       models.NullString(msg.Description),
+		ExternalReference: models.NullString(msg.ExternalReference),
+		Tags:              msg.Tags,
+		Status:            msg.Status,
+		Fee:               fee,
 	}
 
 	// Perform the withdrawal update on the database
 	err = db.WithdrawUpdate(h.DB, &t)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
 	}
 
-	// Send HTTP status 204 No Content indicating success with no response body
-	w.WriteHeader(http.StatusNoContent)
-
+	// Send HTTP status 201 Created with the created transaction and the resulting balance
+	w.Header().Set("Location", transactionLocation(t.ID))
+	writeJSON(w, http.StatusCreated, adapters.ToTransactionResp(t, wallet.Currency, settledBalance(t)))
 }
@@ -0,0 +1,46 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// StartBalanceSnapshots records the balance of every wallet at the start of the day every interval until ctx is done.
+func StartBalanceSnapshots(ctx context.Context, database *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("balance snapshot job started, running every %s", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("balance snapshot job stopped")
+			return
+		case <-ticker.C:
+			if _, err := TakeBalanceSnapshots(database, time.Now().UTC()); err != nil {
+				log.Printf("ERROR: failed to take balance snapshots: %v", err)
+			}
+		}
+	}
+}
+
+// TakeBalanceSnapshots records the balance of every wallet at the start of the latest day begun
+// models.BalanceSnapshotDelay before now. Wallets already snapshotted for that day are skipped, so the
+// job can run any number of times a day. It returns the number of snapshots recorded.
+func TakeBalanceSnapshots(database *sql.DB, now time.Time) (int64, error) {
+	at := models.StartOfDay(now.Add(-models.BalanceSnapshotDelay))
+
+	recorded, err := db.CreateBalanceSnapshots(database, at)
+	if err != nil {
+		return 0, err
+	}
+	if recorded > 0 {
+		log.Printf("%d balance snapshot(s) recorded at %s", recorded, at.Format(time.DateOnly))
+	}
+	return recorded, nil
+}
@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+)
+
+// StartHoldExpiry marks holds past their expiry as expired every interval until ctx is done.
+// Expired holds already stop reserving funds at their expiry time; the job keeps their status in line.
+func StartHoldExpiry(ctx context.Context, database *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("hold expiry job started, running every %s", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("hold expiry job stopped")
+			return
+		case <-ticker.C:
+			expired, err := db.ExpireHolds(database)
+			if err != nil {
+				log.Printf("ERROR: failed to expire holds: %v", err)
+				continue
+			}
+			if expired > 0 {
+				log.Printf("%d hold(s) expired", expired)
+			}
+		}
+	}
+}
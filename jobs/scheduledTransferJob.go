@@ -0,0 +1,150 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/config"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/services"
+)
+
+// dueScheduledTransfersBatch is the maximum number of scheduled transfers executed per tick.
+const dueScheduledTransfersBatch = 100
+
+// StartScheduledTransfers executes the due scheduled transfers every interval until ctx is done.
+func StartScheduledTransfers(ctx context.Context, database *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("scheduled transfer job started, running every %s", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("scheduled transfer job stopped")
+			return
+		case <-ticker.C:
+			executed, err := RunDueScheduledTransfers(database, time.Now().UTC())
+			if err != nil {
+				log.Printf("ERROR: failed to run scheduled transfers: %v", err)
+				continue
+			}
+			if executed > 0 {
+				log.Printf("%d scheduled transfer run(s) recorded", executed)
+			}
+		}
+	}
+}
+
+// RunDueScheduledTransfers executes every scheduled transfer due at now and records the outcome of each run.
+// Recurring occurrences missed before the latest one due, e.g. while the job was not running, are skipped
+// without a run: only the latest occurrence is executed.
+// A transfer that fails with an unexpected error is not recorded and is tried again on the next call.
+// It returns the number of runs recorded.
+func RunDueScheduledTransfers(database *sql.DB, now time.Time) (int, error) {
+	schedules, err := db.GetDueScheduledTransfers(database, now, dueScheduledTransfersBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	recorded := 0
+	for i := range schedules {
+		skipped, err := db.SkipMissedScheduledTransferOccurrences(database, &schedules[i], now)
+		if err == nil {
+			if skipped > 0 {
+				log.Printf("scheduled transfer Id: %d skipped %d missed occurrence(s)", schedules[i].ID, skipped)
+			}
+			err = runScheduledTransfer(database, &schedules[i], now)
+		}
+		if errors.Is(err, db.ErrScheduleChanged) {
+			continue
+		}
+		if err != nil {
+			log.Printf("ERROR: failed to run scheduled transfer Id: %d: %v", schedules[i].ID, err)
+			continue
+		}
+		recorded++
+	}
+	return recorded, nil
+}
+
+// runScheduledTransfer executes the due occurrence of s through the same path as a transfer request.
+// When the transfer is refused, the occurrence is retried or skipped for insufficient funds,
+// according to the schedule, and marked as failed otherwise.
+func runScheduledTransfer(database *sql.DB, s *models.ScheduledTransfer, now time.Time) error {
+	run := models.ScheduledTransferRun{
+		ScheduledTransferId: s.ID,
+		ScheduledFor:        s.NextRunAt.Time,
+		Attempt:             s.Attempts + 1,
+	}
+
+	msg := models.TransactionRequest{
+		Amount:            s.Amount,
+		Description:       s.Description.String,
+		ExternalReference: s.ExternalReference.String,
+	}
+	if s.DestinationWalletId.Valid {
+		msg.DestinationWalletID = &s.DestinationWalletId.Int64
+	}
+	if s.DestinationUserId.Valid {
+		msg.DestinationUserID = &s.DestinationUserId.Int64
+	}
+
+	plan, err := services.PrepareTransfer(database, s.SourceWalletId, msg)
+	if err == nil {
+		err = db.RunScheduledTransfer(database, s, &run, &plan.Transfer, &plan.TransferOut, &plan.TransferIn)
+		if err == nil {
+			log.Printf("scheduled transfer Id: %d executed as transfer Id: %d", s.ID, plan.Transfer.ID)
+			return nil
+		}
+	}
+
+	var appErr *models.AppError
+	if !errors.As(err, &appErr) {
+		return err
+	}
+
+	run.ErrorCode = sql.NullString{String: appErr.Code, Valid: true}
+	run.ErrorMessage = sql.NullString{String: appErr.Message, Valid: true}
+
+	switch {
+	case appErr.Code == models.ErrCodeInsufficientFunds && s.OnInsufficientFunds == models.InsufficientFundsRetry &&
+		run.Attempt <= scheduleMaxRetries():
+		run.Status = models.RunStatusRetryScheduled
+		s.Attempts = run.Attempt
+		s.RetryAt = sql.NullTime{Time: now.Add(scheduleRetryInterval()), Valid: true}
+	case appErr.Code == models.ErrCodeInsufficientFunds:
+		run.Status = models.RunStatusSkipped
+		s.Advance()
+	default:
+		run.Status = models.RunStatusFailed
+		s.Advance()
+	}
+
+	log.Printf("scheduled transfer Id: %d attempt %d %s: %s", s.ID, run.Attempt, run.Status, appErr.Message)
+	return db.RecordScheduledTransferRun(database, s, &run)
+}
+
+// scheduleMaxRetries returns the configured number of retries of an occurrence refused for insufficient funds.
+func scheduleMaxRetries() int64 {
+	val := config.GetOrDefault(config.SCHEDULER_MAX_RETRIES, "")
+	retries, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || retries < 0 {
+		return models.DefaultScheduleMaxRetries
+	}
+	return retries
+}
+
+// scheduleRetryInterval returns the configured wait before an occurrence refused for insufficient funds is retried.
+func scheduleRetryInterval() time.Duration {
+	interval, err := time.ParseDuration(config.GetOrDefault(config.SCHEDULER_RETRY_INTERVAL, models.DefaultScheduleRetryInterval))
+	if err != nil || interval <= 0 {
+		interval, _ = time.ParseDuration(models.DefaultScheduleRetryInterval)
+	}
+	return interval
+}
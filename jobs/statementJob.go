@@ -0,0 +1,58 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/services"
+)
+
+// StartStatements generates the statements of the month just ended every interval until ctx is done.
+func StartStatements(ctx context.Context, database *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("statement job started, running every %s", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("statement job stopped")
+			return
+		case <-ticker.C:
+			if _, err := GenerateStatements(database, time.Now().UTC()); err != nil {
+				log.Printf("ERROR: failed to generate statements: %v", err)
+			}
+		}
+	}
+}
+
+// GenerateStatements generates the statement of every wallet for the last month ended models.BalanceSnapshotDelay
+// before now, skipping the wallets created after it and those whose statement is already stored, so the job
+// can run any number of times a month. A wallet failing is logged and left for the next run. It returns the
+// number of statements generated.
+func GenerateStatements(database *sql.DB, now time.Time) (int, error) {
+	until := models.StartOfMonth(now.Add(-models.BalanceSnapshotDelay))
+	period := until.AddDate(0, -1, 0)
+
+	wallets, err := db.GetWalletsWithoutStatement(database, period, until)
+	if err != nil {
+		return 0, err
+	}
+
+	generated := 0
+	for _, wallet := range wallets {
+		if _, err := services.GenerateStatement(database, wallet, period, now); err != nil {
+			log.Printf("ERROR: failed to generate the %s statement of wallet %d: %v", period.Format(models.StatementPeriodLayout), wallet.ID, err)
+			continue
+		}
+		generated++
+	}
+	if generated > 0 {
+		log.Printf("%d statement(s) generated for %s", generated, period.Format(models.StatementPeriodLayout))
+	}
+	return generated, nil
+}
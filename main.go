@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/rudithu/CRYPTO-WalletApp/config"
 	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/jobs"
 	"github.com/rudithu/CRYPTO-WalletApp/routes"
 )
 
@@ -27,6 +30,34 @@ func main() {
 		return
 	}
 
+	holdExpiryInterval, err := time.ParseDuration(config.GetOrDefault(config.HOLD_EXPIRY_INTERVAL, "1m"))
+	if err != nil {
+		log.Fatal("invalid hold expiry interval")
+		return
+	}
+	go jobs.StartHoldExpiry(context.Background(), database, holdExpiryInterval)
+
+	schedulerInterval, err := time.ParseDuration(config.GetOrDefault(config.SCHEDULER_INTERVAL, "1m"))
+	if err != nil {
+		log.Fatal("invalid scheduler interval")
+		return
+	}
+	go jobs.StartScheduledTransfers(context.Background(), database, schedulerInterval)
+
+	snapshotInterval, err := time.ParseDuration(config.GetOrDefault(config.BALANCE_SNAPSHOT_INTERVAL, "1h"))
+	if err != nil {
+		log.Fatal("invalid balance snapshot interval")
+		return
+	}
+	go jobs.StartBalanceSnapshots(context.Background(), database, snapshotInterval)
+
+	statementInterval, err := time.ParseDuration(config.GetOrDefault(config.STATEMENT_INTERVAL, "1h"))
+	if err != nil {
+		log.Fatal("invalid statement interval")
+		return
+	}
+	go jobs.StartStatements(context.Background(), database, statementInterval)
+
 	r := mux.NewRouter()
 	routes.Route(database, r)
 
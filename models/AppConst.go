@@ -5,5 +5,181 @@ const (
 	TxnTypeTransferIn  = "transfer-in"
 	TxnTypeWithdraw    = "withdraw"
 	TxnTypeDeposit     = "deposit"
+	TxnTypeFee         = "fee"
+	TxnTypeFeeIncome   = "fee-income"
 	BaseCcy            = "USD"
 )
+
+// Transaction types of a conversion between wallets of the same user. Both legs are shown
+// as a single conversion item in the transaction history.
+const (
+	TxnTypeConversion    = "conversion"
+	TxnTypeConversionOut = "conversion-out"
+	TxnTypeConversionIn  = "conversion-in"
+)
+
+// Sides of an FX price: the bid given to a customer selling the base currency of the spread rule,
+// the ask paid by a customer buying it, or the mid rate when no rule applies.
+const (
+	FxSideBid = "bid"
+	FxSideAsk = "ask"
+	FxSideMid = "mid"
+)
+
+// DefaultFxTier is the customer tier FX spreads are priced for when there is no customer.
+const DefaultFxTier = "standard"
+
+// AmountScale is the number of decimals wallet balances and transaction amounts are stored with,
+// for every currency.
+const AmountScale = 2
+
+// RateScale is the number of decimals conversion rates are stored with.
+const RateScale = 10
+
+// Limits on the notes attached to a transaction.
+const (
+	MaxDescriptionLength       = 255
+	MaxExternalReferenceLength = 64
+	MaxTagsPerTransaction      = 10
+	MaxTagLength               = 32
+)
+
+// Policies for revealing the name of a counterparty owned by another user.
+const (
+	CounterpartyNameFull   = "full"
+	CounterpartyNameMasked = "masked"
+	CounterpartyNameHidden = "hidden"
+)
+
+// Status of a transaction. A transaction is created as pending or completed and moves on
+// through the transitions allowed by CanTransitionTxnStatus.
+const (
+	TxnStatusPending   = "pending"
+	TxnStatusCompleted = "completed"
+	TxnStatusFailed    = "failed"
+	TxnStatusReversed  = "reversed"
+)
+
+// Status of a transfer.
+const (
+	TransferStatusCompleted         = "completed"
+	TransferStatusPartiallyReversed = "partially_reversed"
+	TransferStatusReversed          = "reversed"
+)
+
+// Status of a hold.
+const (
+	HoldStatusActive   = "active"
+	HoldStatusCaptured = "captured"
+	HoldStatusReleased = "released"
+	HoldStatusExpired  = "expired"
+)
+
+// Status of an FX quote.
+const (
+	FxQuoteStatusOpen    = "open"
+	FxQuoteStatusUsed    = "used"
+	FxQuoteStatusExpired = "expired"
+)
+
+// DefaultFxQuoteTTLSeconds is how long a quote locks its rate when the configuration does not set it.
+const DefaultFxQuoteTTLSeconds = 60
+
+// DefaultMaxRateAge is how old a stored rate may be when the configuration does not set it.
+const DefaultMaxRateAge = "24h"
+
+// DefaultHoldExpirySeconds is used when neither the request nor the configuration sets the hold expiry.
+const DefaultHoldExpirySeconds = 7 * 24 * 60 * 60
+
+// How often a scheduled transfer is executed.
+const (
+	ScheduleFrequencyOnce    = "once"
+	ScheduleFrequencyDaily   = "daily"
+	ScheduleFrequencyWeekly  = "weekly"
+	ScheduleFrequencyMonthly = "monthly"
+)
+
+// Status of a scheduled transfer. Completed and cancelled schedules are final.
+const (
+	ScheduleStatusActive    = "active"
+	ScheduleStatusPaused    = "paused"
+	ScheduleStatusCompleted = "completed"
+	ScheduleStatusCancelled = "cancelled"
+)
+
+// What the scheduler does when the source wallet cannot cover a scheduled transfer.
+const (
+	InsufficientFundsRetry = "retry"
+	InsufficientFundsSkip  = "skip"
+)
+
+// Outcome of a scheduled transfer run.
+const (
+	RunStatusSucceeded      = "succeeded"
+	RunStatusRetryScheduled = "retry_scheduled"
+	RunStatusSkipped        = "skipped"
+	RunStatusFailed         = "failed"
+)
+
+// Defaults for the scheduler when the configuration does not set them.
+const (
+	DefaultScheduleMaxRetries    = 3
+	DefaultScheduleRetryInterval = "1h"
+)
+
+// How a batch of transfers is applied: all of its items or none, or each item that can be.
+const (
+	BatchModeAllOrNothing = "all_or_nothing"
+	BatchModeBestEffort   = "best_effort"
+)
+
+// Outcome of an item of a batch of transfers.
+const (
+	BatchItemStatusSucceeded = "succeeded"
+	BatchItemStatusFailed    = "failed"
+)
+
+// MaxBatchTransferItems is the maximum number of transfers in a single batch.
+const MaxBatchTransferItems = 500
+
+// MaxPaymentFileBytes is the maximum size of an uploaded payment file.
+const MaxPaymentFileBytes = 2 << 20
+
+// Policies for the FX rate applied when a transfer is reversed.
+const (
+	ReversalRateOriginal = "original"
+	ReversalRateCurrent  = "current"
+)
+
+// Transaction types of the fee schedule. A transfer between wallets of the same user in
+// different currencies is a conversion.
+const (
+	FeeTypeWithdraw   = "withdraw"
+	FeeTypeTransfer   = "transfer"
+	FeeTypeConversion = "conversion"
+)
+
+// Status of a fee: quoted for a transaction not made yet, reserved by a pending transaction or charged.
+const (
+	FeeStatusQuoted   = "quoted"
+	FeeStatusReserved = "reserved"
+	FeeStatusCharged  = "charged"
+)
+
+// DefaultHouseUserId owns the house revenue wallets when the configuration does not set it.
+const DefaultHouseUserId = 5
+
+// Methods matching the disposals of a crypto wallet with the lots it acquired: first in first out,
+// last in first out, or the average cost of all the lots held.
+const (
+	CostBasisFIFO    = "fifo"
+	CostBasisLIFO    = "lifo"
+	CostBasisAverage = "average"
+)
+
+// Defaults for cost-basis tracking when the configuration does not set them: the method, and the
+// comma separated currencies of the wallets tracked, other currencies being fiat.
+const (
+	DefaultCostBasisMethod = CostBasisFIFO
+	DefaultCryptoCcys      = "BTC,ETH"
+)
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Error codes returned to API clients in the "code" field of an ErrorResponse.
+// These values are part of the public contract and must not be renamed.
+const (
+	ErrCodeValidationFailed  = "VALIDATION_FAILED"
+	ErrCodeMalformedRequest  = "MALFORMED_REQUEST"
+	ErrCodeUserNotFound      = "USER_NOT_FOUND"
+	ErrCodeWalletNotFound    = "WALLET_NOT_FOUND"
+	ErrCodeTxnNotFound       = "TRANSACTION_NOT_FOUND"
+	ErrCodeTransferNotFound  = "TRANSFER_NOT_FOUND"
+	ErrCodeNotReversible     = "TRANSFER_NOT_REVERSIBLE"
+	ErrCodeHoldNotFound      = "HOLD_NOT_FOUND"
+	ErrCodeHoldNotActive     = "HOLD_NOT_ACTIVE"
+	ErrCodeInvalidTransition = "INVALID_STATUS_TRANSITION"
+	ErrCodeScheduleNotFound  = "SCHEDULED_TRANSFER_NOT_FOUND"
+	ErrCodeStatementNotFound = "STATEMENT_NOT_FOUND"
+	ErrCodeQuoteNotFound     = "FX_QUOTE_NOT_FOUND"
+	ErrCodeQuoteExpired      = "FX_QUOTE_EXPIRED"
+	ErrCodeQuoteUsed         = "FX_QUOTE_USED"
+	ErrCodeInsufficientFunds = "INSUFFICIENT_FUNDS"
+	ErrCodeRateUnavailable   = "RATE_UNAVAILABLE"
+	ErrCodeRateStale         = "RATE_STALE"
+	ErrCodeFeeNotConfigured  = "FEE_NOT_CONFIGURED"
+	ErrCodeDuplicateFile     = "DUPLICATE_PAYMENT_FILE"
+	ErrCodeInternal          = "INTERNAL_ERROR"
+)
+
+// AppError is a typed error carrying a machine-readable code alongside a
+// human-readable message. Handlers translate it into an ErrorResponse.
+type AppError struct {
+	Code    string
+	Message string
+	Details interface{}
+	Err     error
+}
+
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// WithDetails attaches additional structured information to the error.
+func (e *AppError) WithDetails(details interface{}) *AppError {
+	e.Details = details
+	return e
+}
+
+// Errorf creates an AppError with the given code and a formatted message.
+func Errorf(code string, format string, args ...interface{}) *AppError {
+	return &AppError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// WrapError creates an AppError with the given code that wraps an underlying error.
+func WrapError(code string, err error, message string) *AppError {
+	return &AppError{
+		Code:    code,
+		Message: message,
+		Err:     err,
+	}
+}
+
+// ForBatchItem prefixes the message of an AppError with the index of the batch item it belongs to
+// and adds the index to its details. Other errors are returned unchanged.
+func ForBatchItem(err error, index int) error {
+	var appErr *AppError
+	if !errors.As(err, &appErr) {
+		return err
+	}
+
+	details := map[string]string{}
+	if d, ok := appErr.Details.(map[string]string); ok {
+		for k, v := range d {
+			details[k] = v
+		}
+	}
+	details["item"] = strconv.Itoa(index)
+
+	return &AppError{
+		Code:    appErr.Code,
+		Message: fmt.Sprintf("items[%d]: %s", index, appErr.Message),
+		Details: details,
+		Err:     appErr.Err,
+	}
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// MaxBalanceHistoryDays is the longest range of daily closing balances returned by one request.
+const MaxBalanceHistoryDays = 366
+
+// BalanceEvent is a change of a wallet balance at a point in time: a transaction adds its amount
+// when it completes, or takes it when it is a debit, and undoes that when it is reversed.
+type BalanceEvent struct {
+	At    time.Time
+	Delta decimal.Decimal
+}
+
+// BalanceSnapshot is the balance of a wallet at the start of At, made of the balance events before it.
+// Snapshots spare historical balances from summing the whole transaction history of a wallet.
+type BalanceSnapshot struct {
+	WalletId int64
+	At       time.Time
+	Balance  decimal.Decimal
+}
+
+// DailyBalance is the closing balance of a wallet on Date.
+type DailyBalance struct {
+	Date    time.Time
+	Balance decimal.Decimal
+}
+
+// BalanceSnapshotDelay is how long after the end of a day its balances are taken as final, so that the
+// transactions completing just before midnight are committed by then. Balance snapshots and stored
+// statements wait for it.
+const BalanceSnapshotDelay = 5 * time.Minute
+
+// StartOfDay returns midnight at the start of the day of t, in the location of t.
+func StartOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// DailyClosingBalances rolls opening, the balance at the start of from, forward through events, ordered by time,
+// and returns the closing balance of every day from from to to. Events after the end of to are ignored.
+func DailyClosingBalances(opening decimal.Decimal, from, to time.Time, events []BalanceEvent) []DailyBalance {
+	var balances []DailyBalance
+
+	balance := opening
+	i := 0
+	for day := StartOfDay(from); !day.After(to); day = day.AddDate(0, 0, 1) {
+		next := day.AddDate(0, 0, 1)
+		for ; i < len(events) && events[i].At.Before(next); i++ {
+			balance = balance.Add(events[i].Delta)
+		}
+		balances = append(balances, DailyBalance{Date: day, Balance: balance})
+	}
+	return balances
+}
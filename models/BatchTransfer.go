@@ -0,0 +1,16 @@
+package models
+
+// BatchTransferItem is one transfer of a batch paid from a single source wallet, with both of its legs.
+// Err is set when the item is not transferred, e.g. because its destination cannot be resolved
+// or, in best-effort mode, because the source wallet could not cover it.
+type BatchTransferItem struct {
+	Transfer    Transfer
+	TransferOut Transaction
+	TransferIn  Transaction
+	Err         error
+}
+
+// Succeeded tells whether the item was transferred.
+func (i *BatchTransferItem) Succeeded() bool {
+	return i.Err == nil && i.Transfer.ID != 0
+}
@@ -1,19 +1,226 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"github.com/shopspring/decimal"
 )
 
+// CcyConversion is a stored rate: Rate units of ToCcy per unit of FromCcy. CreatedAt is when the rate
+// was last set and Age how long ago that was, by the database clock.
 type CcyConversion struct {
 	FromCcy   string          `json:"from_ccy"`
 	ToCcy     string          `json:"to_ccy"`
 	Rate      decimal.Decimal `json:"rate"`
 	CreatedAt time.Time       `json:"created_at"`
+	Age       time.Duration   `json:"-"`
 }
 
-type CcyRateToBaseCcy struct {
-	Ccy  string
-	Rate decimal.Decimal
+// IsStale tells whether the rate is older than limits allow.
+func (c *CcyConversion) IsStale(limits RateAgeLimits) bool {
+	maxAge := limits.MaxAge(c.FromCcy, c.ToCcy)
+	return maxAge > 0 && c.Age > maxAge
+}
+
+// CcyRateHistory is every rate ever set, oldest first, to find the rates known at a point in the past.
+type CcyRateHistory []CcyConversion
+
+// At returns the latest rate of each currency pair set before t, in the order the pairs were first set,
+// with Age as of t. Pairs without a rate before t are left out.
+func (h CcyRateHistory) At(t time.Time) []CcyConversion {
+	var conversions []CcyConversion
+	index := make(map[[2]string]int)
+	for _, c := range h {
+		if !c.CreatedAt.Before(t) {
+			break
+		}
+		c.Age = t.Sub(c.CreatedAt)
+		pair := [2]string{c.FromCcy, c.ToCcy}
+		if i, ok := index[pair]; ok {
+			conversions[i] = c
+			continue
+		}
+		index[pair] = len(conversions)
+		conversions = append(conversions, c)
+	}
+	return conversions
+}
+
+// CcyRateToReportingCcy is the rate from Ccy to the currency a total is reported in. Stale is set
+// when a rate it was resolved through is older than allowed.
+type CcyRateToReportingCcy struct {
+	Ccy   string
+	Rate  decimal.Decimal
+	Stale bool
+}
+
+// RateAgeLimits are the maximum ages of stored rates: Default, unless ByCcy sets one for a currency.
+// A zero age is no limit.
+type RateAgeLimits struct {
+	Default time.Duration
+	ByCcy   map[string]time.Duration
+}
+
+// MaxAge returns the maximum age of a rate between fromCcy and toCcy, the stricter limit of both
+// currencies, or zero when neither is limited.
+func (l RateAgeLimits) MaxAge(fromCcy string, toCcy string) time.Duration {
+	maxAge := time.Duration(0)
+	for _, ccy := range []string{fromCcy, toCcy} {
+		age, ok := l.ByCcy[ccy]
+		if !ok {
+			age = l.Default
+		}
+		if age > 0 && (maxAge == 0 || age < maxAge) {
+			maxAge = age
+		}
+	}
+	return maxAge
+}
+
+// RateHop is one step of a rate path. Inverse is set when the hop uses the stored rate from ToCcy
+// to FromCcy, in which case Rate is the reciprocal of the stored rate. AsOf and Age are those of the stored rate.
+type RateHop struct {
+	FromCcy string
+	ToCcy   string
+	Rate    decimal.Decimal
+	Inverse bool
+	AsOf    time.Time
+	Age     time.Duration
+}
+
+// CcyRatePath is the chain of stored rates a rate from FromCcy to ToCcy was resolved through.
+// Rate is the product of the rates of its hops and AsOf is the time of its oldest hop.
+// A path between a currency and itself has no hops and a rate of 1.
+type CcyRatePath struct {
+	FromCcy string
+	ToCcy   string
+	Rate    decimal.Decimal
+	AsOf    time.Time
+	Hops    []RateHop
+}
+
+// String returns the currencies the path goes through, e.g. EUR>USD>BTC.
+func (p *CcyRatePath) String() string {
+	ccys := []string{p.FromCcy}
+	for _, hop := range p.Hops {
+		ccys = append(ccys, hop.ToCcy)
+	}
+	return strings.Join(ccys, ">")
+}
+
+// ResolveCcyRate finds the rate from fromCcy to toCcy over the graph of stored rates, where each stored
+// rate can be used in its own direction or inverted. The path with the fewest hops is chosen and, among
+// those, the one whose oldest hop is the most recent; remaining ties go to direct rates, then to the
+// order of conversions. It returns nil when the currencies are not connected.
+func ResolveCcyRate(conversions []CcyConversion, fromCcy string, toCcy string) *CcyRatePath {
+	if fromCcy == toCcy {
+		return &CcyRatePath{FromCcy: fromCcy, ToCcy: toCcy, Rate: decimal.NewFromInt(1)}
+	}
+
+	// Direct edges come first so that they win ties against inverse ones
+	edges := make(map[string][]RateHop)
+	for _, c := range conversions {
+		if c.Rate.IsPositive() && c.FromCcy != c.ToCcy {
+			edges[c.FromCcy] = append(edges[c.FromCcy], RateHop{FromCcy: c.FromCcy, ToCcy: c.ToCcy, Rate: c.Rate, AsOf: c.CreatedAt, Age: c.Age})
+		}
+	}
+	for _, c := range conversions {
+		if c.Rate.IsPositive() && c.FromCcy != c.ToCcy {
+			edges[c.ToCcy] = append(edges[c.ToCcy], RateHop{FromCcy: c.ToCcy, ToCcy: c.FromCcy,
+				Rate: decimal.NewFromInt(1).Div(c.Rate), Inverse: true, AsOf: c.CreatedAt, Age: c.Age})
+		}
+	}
+
+	// Search breadth first, one layer of hops at a time, keeping the freshest path to each currency.
+	// The freshest path to a currency of one layer always extends a freshest path of the layer before.
+	best := map[string]*CcyRatePath{fromCcy: {FromCcy: fromCcy, ToCcy: fromCcy, Rate: decimal.NewFromInt(1)}}
+	layer := []string{fromCcy}
+	for len(layer) > 0 {
+		next := make(map[string]*CcyRatePath)
+		var nextLayer []string
+		for _, ccy := range layer {
+			path := best[ccy]
+			for _, hop := range edges[ccy] {
+				if _, visited := best[hop.ToCcy]; visited {
+					continue
+				}
+				candidate := path.extend(hop)
+				current, ok := next[hop.ToCcy]
+				if !ok {
+					nextLayer = append(nextLayer, hop.ToCcy)
+				}
+				if !ok || candidate.AsOf.After(current.AsOf) {
+					next[hop.ToCcy] = candidate
+				}
+			}
+		}
+		if found, ok := next[toCcy]; ok {
+			return found
+		}
+		for ccy, path := range next {
+			best[ccy] = path
+		}
+		layer = nextLayer
+	}
+	return nil
+}
+
+// ResolveFreshCcyRate finds the rate from fromCcy to toCcy as ResolveCcyRate does, over the rates that are
+// not older than limits allow, so that a fresh path wins over a stale one with fewer hops. When the
+// currencies are only connected through stale rates, the path over every rate is returned, for the
+// caller to report it as stale.
+func ResolveFreshCcyRate(conversions []CcyConversion, fromCcy string, toCcy string, limits RateAgeLimits) *CcyRatePath {
+	fresh := make([]CcyConversion, 0, len(conversions))
+	for i := range conversions {
+		if !conversions[i].IsStale(limits) {
+			fresh = append(fresh, conversions[i])
+		}
+	}
+
+	if path := ResolveCcyRate(fresh, fromCcy, toCcy); path != nil {
+		return path
+	}
+	return ResolveCcyRate(conversions, fromCcy, toCcy)
+}
+
+// StaleHop returns the first hop of the path whose rate is older than limits allow, or nil.
+func (p *CcyRatePath) StaleHop(limits RateAgeLimits) *RateHop {
+	for i, hop := range p.Hops {
+		if maxAge := limits.MaxAge(hop.FromCcy, hop.ToCcy); maxAge > 0 && hop.Age > maxAge {
+			return &p.Hops[i]
+		}
+	}
+	return nil
+}
+
+// CheckFresh returns an error when a rate the path goes through is older than limits allow.
+func (p *CcyRatePath) CheckFresh(limits RateAgeLimits) error {
+	hop := p.StaleHop(limits)
+	if hop == nil {
+		return nil
+	}
+
+	from, to := hop.FromCcy, hop.ToCcy
+	if hop.Inverse {
+		from, to = to, from
+	}
+	return Errorf(ErrCodeRateStale, "conversion rate from %s to %s is too old to convert %s to %s", from, to, p.FromCcy, p.ToCcy).
+		WithDetails(map[string]string{
+			"rate_path": p.String(),
+			"as_of":     hop.AsOf.Format(time.RFC3339),
+			"max_age":   limits.MaxAge(hop.FromCcy, hop.ToCcy).String(),
+		})
+}
+
+// extend returns a copy of the path followed by hop.
+func (p *CcyRatePath) extend(hop RateHop) *CcyRatePath {
+	hops := make([]RateHop, len(p.Hops), len(p.Hops)+1)
+	copy(hops, p.Hops)
+
+	asOf := hop.AsOf
+	if len(p.Hops) > 0 && p.AsOf.Before(asOf) {
+		asOf = p.AsOf
+	}
+	return &CcyRatePath{FromCcy: p.FromCcy, ToCcy: hop.ToCcy, Rate: p.Rate.Mul(hop.Rate), AsOf: asOf, Hops: append(hops, hop)}
 }
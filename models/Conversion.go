@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Conversion exchanges funds between two wallets of the same user in different currencies.
+// It is recorded as a conversion-out transaction on the from wallet and a conversion-in
+// transaction on the to wallet. Rate is the rate given to the user: MidRate less Spread, the
+// bid or ask spread of the FX spread schedule in percent of the mid rate. SpreadAmount is what the spread took from the user, in the
+// currency of the to wallet.
+type Conversion struct {
+	ID               int64
+	UserId           int64
+	FromWalletId     int64
+	ToWalletId       int64
+	FromAmount       decimal.Decimal
+	ToAmount         decimal.Decimal
+	MidRate          decimal.Decimal
+	Rate             decimal.Decimal
+	Spread           decimal.Decimal
+	SpreadAmount     decimal.Decimal
+	OutTransactionId int64
+	InTransactionId  int64
+	CreatedAt        time.Time
+	// The currencies are read from the wallets table and are not stored on the conversion itself.
+	FromCurrency string
+	ToCurrency   string
+}
+
+// ApplyPrice sets the rate given to the user from the price of the exchange.
+func (c *Conversion) ApplyPrice(price FxPrice) {
+	c.MidRate = price.MidRate
+	c.Spread = price.Spread
+	c.Rate = price.Rate
+}
+
+// Sell sets the amounts of a conversion of fromAmount. The amount received is rounded down to AmountScale decimals.
+func (c *Conversion) Sell(fromAmount decimal.Decimal) {
+	c.FromAmount = fromAmount
+	c.ToAmount = fromAmount.Mul(c.Rate).RoundFloor(AmountScale)
+	c.SpreadAmount = c.spreadAmount()
+}
+
+// Buy sets the amounts of a conversion receiving toAmount. The amount paid is rounded up to AmountScale decimals.
+func (c *Conversion) Buy(toAmount decimal.Decimal) {
+	c.ToAmount = toAmount
+	c.FromAmount = toAmount.Div(c.Rate).RoundCeil(AmountScale)
+	c.SpreadAmount = c.spreadAmount()
+}
+
+// spreadAmount returns the difference between the amount the from amount is worth at the mid rate
+// and the amount received.
+func (c *Conversion) spreadAmount() decimal.Decimal {
+	return c.FromAmount.Mul(c.MidRate).Sub(c.ToAmount).Round(AmountScale)
+}
@@ -0,0 +1,209 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CostBasisTxn is a completed transaction of a crypto wallet replayed to track its cost basis.
+// CounterCcy is the currency of the counterparty wallet of a transfer or conversion, and Rate
+// the rate recorded on both legs of an exchange between currencies, target per unit of source.
+type CostBasisTxn struct {
+	ID         int64
+	Type       string
+	Amount     decimal.Decimal
+	Rate       decimal.NullDecimal
+	CounterCcy string
+	At         time.Time
+}
+
+// IsAcquisition tells whether the transaction adds to the wallet, acquiring a lot, rather than disposing of part of it.
+func (t CostBasisTxn) IsAcquisition() bool {
+	return IsCreditTxnType(t.Type)
+}
+
+// Lot is the quantity acquired by a transaction. Remaining is the part not disposed of yet and
+// Cost what it cost, in the reporting currency. Unpriced is set when no rate could value it,
+// its cost then being zero.
+type Lot struct {
+	TransactionID int64
+	AcquiredAt    time.Time
+	Quantity      decimal.Decimal
+	Remaining     decimal.Decimal
+	Cost          decimal.Decimal
+	Unpriced      bool
+}
+
+// Disposal is the quantity a transaction took from the wallet, matched with the lots it came from.
+// AcquiredAt is when the oldest of them was acquired. Proceeds, CostBasis and Gain are in the
+// reporting currency; a quantity beyond the lots held has no cost.
+type Disposal struct {
+	TransactionID int64
+	Type          string
+	DisposedAt    time.Time
+	AcquiredAt    time.Time
+	Quantity      decimal.Decimal
+	Proceeds      decimal.Decimal
+	CostBasis     decimal.Decimal
+	Gain          decimal.Decimal
+	Unpriced      bool
+}
+
+// CostBasis is the result of replaying the transactions of a wallet in Currency: the lots still
+// held and every disposal, valued in ReportingCcy. UnpricedTxns are the transactions no rate
+// could value, making the figures incomplete.
+type CostBasis struct {
+	WalletID     int64
+	Currency     string
+	ReportingCcy string
+	Method       string
+	Lots         []Lot
+	Disposals    []Disposal
+	UnpricedTxns []int64
+}
+
+// IsCostBasisMethod tells whether method is a known cost-basis method.
+func IsCostBasisMethod(method string) bool {
+	switch method {
+	case CostBasisFIFO, CostBasisLIFO, CostBasisAverage:
+		return true
+	}
+	return false
+}
+
+// TrackCostBasis replays txns, oldest first, on the wallet and matches each disposal with the
+// lots acquired before it using method. Every transaction is valued in reportingCcy at the rates of
+// history known when it completed: an exchange through the amount of the other currency at its
+// recorded rate, any other transaction at the market rate of the wallet currency.
+func TrackCostBasis(wallet Wallet, reportingCcy string, method string, txns []CostBasisTxn, history CcyRateHistory) CostBasis {
+	cb := CostBasis{WalletID: wallet.ID, Currency: wallet.Currency, ReportingCcy: reportingCcy, Method: method}
+
+	for _, t := range txns {
+		value, ok := txnValue(t, wallet.Currency, reportingCcy, history)
+		if !ok {
+			cb.UnpricedTxns = append(cb.UnpricedTxns, t.ID)
+		}
+
+		if t.IsAcquisition() {
+			cb.Lots = append(cb.Lots, Lot{
+				TransactionID: t.ID,
+				AcquiredAt:    t.At,
+				Quantity:      t.Amount,
+				Remaining:     t.Amount,
+				Cost:          value,
+				Unpriced:      !ok,
+			})
+			continue
+		}
+
+		disposal := Disposal{
+			TransactionID: t.ID,
+			Type:          t.Type,
+			DisposedAt:    t.At,
+			Quantity:      t.Amount,
+			Proceeds:      value,
+			Unpriced:      !ok,
+		}
+		disposal.CostBasis, disposal.AcquiredAt = cb.dispose(t.Amount)
+		disposal.Gain = disposal.Proceeds.Sub(disposal.CostBasis)
+		cb.Disposals = append(cb.Disposals, disposal)
+	}
+	return cb
+}
+
+// Holding returns the quantity still held and what it cost.
+func (cb *CostBasis) Holding() (decimal.Decimal, decimal.Decimal) {
+	quantity, cost := decimal.Zero, decimal.Zero
+	for _, lot := range cb.Lots {
+		quantity = quantity.Add(lot.Remaining)
+		cost = cost.Add(lot.Cost)
+	}
+	return quantity, cost
+}
+
+// dispose takes quantity from the open lots according to the method and returns its cost and when
+// the oldest lot it came from was acquired. Lots disposed of entirely are dropped.
+func (cb *CostBasis) dispose(quantity decimal.Decimal) (decimal.Decimal, time.Time) {
+	cost := decimal.Zero
+	var acquiredAt time.Time
+
+	if cb.Method == CostBasisAverage {
+		held, heldCost := cb.Holding()
+		if held.IsZero() {
+			return cost, acquiredAt
+		}
+		acquiredAt = cb.Lots[0].AcquiredAt
+		if !quantity.LessThan(held) {
+			cb.Lots = nil
+			return heldCost, acquiredAt
+		}
+
+		// every lot keeps its share of what is left, at the same average cost
+		left, leftCost := held.Sub(quantity), decimal.Zero
+		for i := range cb.Lots {
+			cb.Lots[i].Remaining = cb.Lots[i].Remaining.Mul(left).Div(held)
+			cb.Lots[i].Cost = cb.Lots[i].Cost.Mul(left).Div(held).Round(AmountScale)
+			leftCost = leftCost.Add(cb.Lots[i].Cost)
+		}
+		return heldCost.Sub(leftCost), acquiredAt
+	}
+
+	left := quantity
+	for left.IsPositive() && len(cb.Lots) > 0 {
+		i := 0
+		if cb.Method == CostBasisLIFO {
+			i = len(cb.Lots) - 1
+		}
+		lot := &cb.Lots[i]
+
+		taken := decimal.Min(left, lot.Remaining)
+		takenCost := lot.Cost
+		if taken.LessThan(lot.Remaining) {
+			takenCost = lot.Cost.Mul(taken).Div(lot.Remaining).Round(AmountScale)
+		}
+		if acquiredAt.IsZero() || lot.AcquiredAt.Before(acquiredAt) {
+			acquiredAt = lot.AcquiredAt
+		}
+
+		cost = cost.Add(takenCost)
+		lot.Remaining = lot.Remaining.Sub(taken)
+		lot.Cost = lot.Cost.Sub(takenCost)
+		left = left.Sub(taken)
+		cb.dropEmptyLots()
+	}
+	return cost, acquiredAt
+}
+
+func (cb *CostBasis) dropEmptyLots() {
+	lots := cb.Lots[:0]
+	for _, lot := range cb.Lots {
+		if lot.Remaining.IsPositive() {
+			lots = append(lots, lot)
+		}
+	}
+	cb.Lots = lots
+}
+
+// txnValue values the transaction in reportingCcy, reporting false when no rate is known.
+func txnValue(t CostBasisTxn, ccy string, reportingCcy string, history CcyRateHistory) (decimal.Decimal, bool) {
+	amount, amountCcy := t.Amount, ccy
+	if t.Rate.Valid && t.CounterCcy != "" && t.CounterCcy != ccy && !t.Rate.Decimal.IsZero() {
+		amountCcy = t.CounterCcy
+		if t.IsAcquisition() {
+			// the wallet received the target amount of the exchange
+			amount = t.Amount.Div(t.Rate.Decimal)
+		} else {
+			amount = t.Amount.Mul(t.Rate.Decimal)
+		}
+	}
+
+	if amountCcy == reportingCcy {
+		return amount.Round(AmountScale), true
+	}
+	path := ResolveCcyRate(history.At(t.At), amountCcy, reportingCcy)
+	if path == nil {
+		return decimal.Zero, false
+	}
+	return amount.Mul(path.Rate).Round(AmountScale), true
+}
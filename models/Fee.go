@@ -0,0 +1,109 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// FeeRule is an entry of the fee schedule. It applies to a transaction of its fee type whose
+// currencies and source wallet type match its own, a NULL selector matching any value, and whose
+// amount is at least MinAmount. Rules with the same selectors and different MinAmount are the
+// tiers of a tiered fee.
+type FeeRule struct {
+	ID         int64
+	FeeType    string
+	FromCcy    sql.NullString
+	ToCcy      sql.NullString
+	WalletType sql.NullString
+	MinAmount  decimal.Decimal
+	FlatFee    decimal.Decimal
+	// Percentage of the amount, e.g. 0.5 for 0.5%
+	Percentage decimal.Decimal
+	MinFee     decimal.NullDecimal
+	MaxFee     decimal.NullDecimal
+}
+
+// FeeBreakdown shows how a fee is made up. Adjustment is what the min or max fee of the rule
+// added to or took from the flat and percentage fees.
+type FeeBreakdown struct {
+	FlatFee       decimal.Decimal
+	PercentageFee decimal.Decimal
+	Adjustment    decimal.Decimal
+	Amount        decimal.Decimal
+}
+
+// Fee is charged for a transaction and paid from the wallet of the transaction to the house
+// revenue wallet in the same currency. It is posted as a fee transaction on the paying wallet
+// and a fee-income transaction on the house wallet, which are only set once the fee is charged.
+type Fee struct {
+	ID            int64
+	TransactionId int64
+	Rule          FeeRule
+	HouseWalletId int64
+	FeeBreakdown
+	Debit     Transaction
+	Credit    Transaction
+	CreatedAt time.Time
+}
+
+// Charged reports whether the fee has been posted to the paying and the house wallets.
+func (f *Fee) Charged() bool {
+	return f.Debit.ID != 0
+}
+
+// Calculate returns the fee for amount: the flat fee plus the percentage of amount, kept within
+// the min and max fee of the rule and rounded to cents.
+func (r FeeRule) Calculate(amount decimal.Decimal) FeeBreakdown {
+	b := FeeBreakdown{
+		FlatFee:       r.FlatFee,
+		PercentageFee: amount.Mul(r.Percentage).Div(decimal.NewFromInt(100)).Round(AmountScale),
+	}
+
+	fee := b.FlatFee.Add(b.PercentageFee)
+	if r.MinFee.Valid && fee.LessThan(r.MinFee.Decimal) {
+		fee = r.MinFee.Decimal
+	}
+	if r.MaxFee.Valid && fee.GreaterThan(r.MaxFee.Decimal) {
+		fee = r.MaxFee.Decimal
+	}
+
+	b.Amount = fee
+	b.Adjustment = fee.Sub(b.FlatFee).Sub(b.PercentageFee)
+	return b
+}
+
+// MatchFeeRule returns the rule of rules charging a transaction of feeType moving amount from a
+// wallet of walletType in fromCcy to toCcy, or nil when none applies. The rule with the most
+// selectors set wins, then the highest tier reached by amount.
+func MatchFeeRule(rules []FeeRule, feeType, fromCcy, toCcy, walletType string, amount decimal.Decimal) *FeeRule {
+	var match *FeeRule
+	for i, r := range rules {
+		if r.FeeType != feeType || amount.LessThan(r.MinAmount) ||
+			!matchesSelector(r.FromCcy, fromCcy) || !matchesSelector(r.ToCcy, toCcy) || !matchesSelector(r.WalletType, walletType) {
+			continue
+		}
+
+		if match == nil || r.specificity() > match.specificity() ||
+			(r.specificity() == match.specificity() && r.MinAmount.GreaterThan(match.MinAmount)) {
+			match = &rules[i]
+		}
+	}
+	return match
+}
+
+// specificity returns the number of selectors set on the rule.
+func (r FeeRule) specificity() int {
+	n := 0
+	for _, s := range []sql.NullString{r.FromCcy, r.ToCcy, r.WalletType} {
+		if s.Valid {
+			n++
+		}
+	}
+	return n
+}
+
+func matchesSelector(selector sql.NullString, value string) bool {
+	return !selector.Valid || selector.String == value
+}
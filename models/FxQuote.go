@@ -0,0 +1,49 @@
+package models
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// FxQuote locks the conversion rate from FromCcy to ToCcy for the user with UserId until ExpiresAt. A single
+// transfer from a wallet of that user between wallets in these currencies can execute at the quoted rate while the quote is open;
+// the quote is then used and linked to the transfer. Rate is MidRate less the Spread of Side, and
+// RatePath the currencies MidRate was resolved through.
+type FxQuote struct {
+	ID       int64           `json:"id"`
+	UserId   int64           `json:"user_id"`
+	FromCcy  string          `json:"from_ccy"`
+	ToCcy    string          `json:"to_ccy"`
+	Rate     decimal.Decimal `json:"rate"`
+	MidRate  decimal.Decimal `json:"mid_rate"`
+	Side     string          `json:"side"`
+	Spread   decimal.Decimal `json:"spread"`
+	RatePath string          `json:"rate_path"`
+	// Status is open, used or expired. Expired is not stored, an open quote past ExpiresAt is read as expired.
+	Status     string        `json:"status"`
+	TransferId sql.NullInt64 `json:"transfer_id"`
+	ExpiresAt  time.Time     `json:"expires_at"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UsedAt     sql.NullTime  `json:"used_at"`
+}
+
+// Price returns the price locked by the quote.
+func (q *FxQuote) Price() FxPrice {
+	return FxPrice{Side: q.Side, MidRate: q.MidRate, Spread: q.Spread, Rate: q.Rate, RatePath: q.RatePath}
+}
+
+// CheckUsable returns an error when the quote has already been used or has expired.
+func (q *FxQuote) CheckUsable() error {
+	switch q.Status {
+	case FxQuoteStatusUsed:
+		return Errorf(ErrCodeQuoteUsed, "fx quote %d has already been used", q.ID).
+			WithDetails(map[string]string{"transfer_id": strconv.FormatInt(q.TransferId.Int64, 10)})
+	case FxQuoteStatusExpired:
+		return Errorf(ErrCodeQuoteExpired, "fx quote %d has expired", q.ID).
+			WithDetails(map[string]string{"expires_at": q.ExpiresAt.Format(time.RFC3339)})
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// FxSpreadRule is an entry of the FX spread schedule quoting BaseCcy in QuoteCcy to customers of Tier,
+// a NULL selector matching any value. A customer selling the base currency gets the bid, the mid rate
+// less BidSpread; a customer buying it pays the ask, the mid rate plus AskSpread. Spreads are
+// percentages of the mid rate, e.g. 0.5 for 0.5%.
+type FxSpreadRule struct {
+	ID        int64
+	BaseCcy   sql.NullString
+	QuoteCcy  sql.NullString
+	Tier      sql.NullString
+	BidSpread decimal.Decimal
+	AskSpread decimal.Decimal
+}
+
+// FxPrice is the rate given to a customer exchanging one currency for another: the mid rate less the
+// spread of the side the customer takes. Side is FxSideMid and Spread zero when no rule applies.
+// RatePath is the path the mid rate was resolved through, see CcyRatePath.String.
+type FxPrice struct {
+	Side     string
+	MidRate  decimal.Decimal
+	Spread   decimal.Decimal
+	Rate     decimal.Decimal
+	RatePath string
+}
+
+// SpreadRevenue is what the spread of an exchange took from the customer: the amount exchanged valued
+// at the mid rate less the amount received, in the currency received. It is recorded against the
+// transaction paying for the exchange and only set once that transaction is created.
+type SpreadRevenue struct {
+	ID            int64
+	TransactionId int64
+	Currency      string
+	MidRate       decimal.Decimal
+	Rate          decimal.Decimal
+	Amount        decimal.Decimal
+	CreatedAt     time.Time
+}
+
+// SpreadRevenueTotal sums the spread revenue recorded in one currency.
+type SpreadRevenueTotal struct {
+	Currency string
+	Count    int64
+	Amount   decimal.Decimal
+}
+
+// MidPrice returns the price of an exchange at midRate, without spread.
+func MidPrice(midRate decimal.Decimal) FxPrice {
+	return FxPrice{Side: FxSideMid, MidRate: midRate, Spread: decimal.Zero, Rate: midRate}
+}
+
+// PriceFx returns the price of exchanging fromCcy for toCcy at midRate for a customer, rules being the
+// rules of the customer's tier and of any tier. The matching rule with the most selectors set applies,
+// the first one listed on a tie. The customer takes the bid when the rule quotes fromCcy and the ask
+// when it quotes toCcy, a rule for any base currency being read as quoting fromCcy.
+func PriceFx(rules []FxSpreadRule, fromCcy, toCcy string, midRate decimal.Decimal) FxPrice {
+	var match *FxSpreadRule
+	side := FxSideMid
+	for i, r := range rules {
+		var ruleSide string
+		if matchesSelector(r.BaseCcy, fromCcy) && matchesSelector(r.QuoteCcy, toCcy) {
+			ruleSide = FxSideBid
+		} else if matchesSelector(r.BaseCcy, toCcy) && matchesSelector(r.QuoteCcy, fromCcy) {
+			ruleSide = FxSideAsk
+		} else {
+			continue
+		}
+
+		if match == nil || r.specificity() > match.specificity() {
+			match = &rules[i]
+			side = ruleSide
+		}
+	}
+
+	hundred := decimal.NewFromInt(100)
+	switch side {
+	case FxSideBid:
+		return FxPrice{Side: side, MidRate: midRate, Spread: match.BidSpread,
+			Rate: midRate.Mul(hundred.Sub(match.BidSpread)).Div(hundred)}
+	case FxSideAsk:
+		// The ask is a price of toCcy in fromCcy, the rate is its inverse
+		return FxPrice{Side: side, MidRate: midRate, Spread: match.AskSpread,
+			Rate: midRate.Mul(hundred).Div(hundred.Add(match.AskSpread))}
+	default:
+		return MidPrice(midRate)
+	}
+}
+
+// Revenue returns the spread revenue of exchanging fromAmount for toAmount in currency at the price,
+// or nil when the spread took nothing.
+func (p FxPrice) Revenue(currency string, fromAmount, toAmount decimal.Decimal) *SpreadRevenue {
+	amount := fromAmount.Mul(p.MidRate).Sub(toAmount).Round(AmountScale)
+	if !amount.IsPositive() {
+		return nil
+	}
+	return &SpreadRevenue{Currency: currency, MidRate: p.MidRate, Rate: p.Rate, Amount: amount}
+}
+
+// specificity returns the number of selectors set on the rule.
+func (r FxSpreadRule) specificity() int {
+	n := 0
+	for _, s := range []sql.NullString{r.BaseCcy, r.QuoteCcy, r.Tier} {
+		if s.Valid {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Hold reserves part of a wallet balance, e.g. for a pending card payment or an in-flight withdrawal.
+// An active hold that has not expired reduces the available balance of the wallet without
+// changing its ledger balance. Capturing the hold withdraws the captured amount from the wallet.
+type Hold struct {
+	ID             int64           `json:"id"`
+	WalletId       int64           `json:"wallet_id"`
+	Amount         decimal.Decimal `json:"amount"`
+	CapturedAmount decimal.Decimal `json:"captured_amount"`
+	Status         string          `json:"status"`
+	Description    sql.NullString  `json:"description"`
+	TransactionId  sql.NullInt64   `json:"transaction_id"`
+	ExpiresAt      sql.NullTime    `json:"expires_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
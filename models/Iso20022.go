@@ -0,0 +1,339 @@
+package models
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Pain001NotProvided is the end to end identification of a payment the debtor did not identify.
+const Pain001NotProvided = "NOTPROVIDED"
+
+// IsoAmount is an amount with its currency, e.g. <Amt Ccy="USD">10.00</Amt>.
+type IsoAmount struct {
+	Ccy   string `xml:"Ccy,attr"`
+	Value string `xml:",chardata"`
+}
+
+// IsoAccount identifies a wallet as an account, by its ID as other identification. An IBAN is only read.
+type IsoAccount struct {
+	Id  IsoAccountId `xml:"Id"`
+	Ccy string       `xml:"Ccy,omitempty"`
+}
+
+type IsoAccountId struct {
+	IBAN string      `xml:"IBAN,omitempty"`
+	Othr *IsoOtherId `xml:"Othr,omitempty"`
+}
+
+type IsoOtherId struct {
+	Id string `xml:"Id"`
+}
+
+// IsoDateTime is a date and time choice; only DtTm is written.
+type IsoDateTime struct {
+	DtTm string `xml:"DtTm"`
+}
+
+// Camt053Document is a camt.053.001.08 bank to customer statement with a single account statement.
+type Camt053Document struct {
+	XMLName       xml.Name                  `xml:"urn:iso:std:iso:20022:tech:xsd:camt.053.001.08 Document"`
+	BkToCstmrStmt Camt053BankToCustomerStmt `xml:"BkToCstmrStmt"`
+}
+
+type Camt053BankToCustomerStmt struct {
+	GrpHdr Camt053GroupHeader `xml:"GrpHdr"`
+	Stmt   Camt053Statement   `xml:"Stmt"`
+}
+
+type Camt053GroupHeader struct {
+	MsgId   string `xml:"MsgId"`
+	CreDtTm string `xml:"CreDtTm"`
+}
+
+// Camt053Statement is the statement of one wallet, from FrDtTm up to ToDtTm.
+type Camt053Statement struct {
+	Id        string            `xml:"Id"`
+	CreDtTm   string            `xml:"CreDtTm"`
+	FrToDt    Camt053Period     `xml:"FrToDt"`
+	Acct      IsoAccount        `xml:"Acct"`
+	Bal       []Camt053Balance  `xml:"Bal"`
+	TxsSummry Camt053TxsSummary `xml:"TxsSummry"`
+	Ntry      []Camt053Entry    `xml:"Ntry"`
+}
+
+type Camt053Period struct {
+	FrDtTm string `xml:"FrDtTm"`
+	ToDtTm string `xml:"ToDtTm"`
+}
+
+// Camt053Balance is the opening (OPBD) or closing (CLBD) booked balance. Amt is never negative,
+// CdtDbtInd is DBIT for a negative balance.
+type Camt053Balance struct {
+	Tp        Camt053BalanceType `xml:"Tp"`
+	Amt       IsoAmount          `xml:"Amt"`
+	CdtDbtInd string             `xml:"CdtDbtInd"`
+	Dt        IsoDateTime        `xml:"Dt"`
+}
+
+type Camt053BalanceType struct {
+	CdOrPrtry struct {
+		Cd string `xml:"Cd"`
+	} `xml:"CdOrPrtry"`
+}
+
+type Camt053TxsSummary struct {
+	TtlNtries    Camt053NumberAndSum `xml:"TtlNtries"`
+	TtlCdtNtries Camt053NumberAndSum `xml:"TtlCdtNtries"`
+	TtlDbtNtries Camt053NumberAndSum `xml:"TtlDbtNtries"`
+}
+
+type Camt053NumberAndSum struct {
+	NbOfNtries string `xml:"NbOfNtries"`
+	Sum        string `xml:"Sum,omitempty"`
+}
+
+// Camt053Entry is a booked entry of the statement. RvslInd is set on the reversal of a transaction;
+// CdtDbtInd is then the direction of the reversal itself.
+type Camt053Entry struct {
+	NtryRef     string              `xml:"NtryRef"`
+	Amt         IsoAmount           `xml:"Amt"`
+	CdtDbtInd   string              `xml:"CdtDbtInd"`
+	RvslInd     bool                `xml:"RvslInd,omitempty"`
+	Sts         Camt053EntryStatus  `xml:"Sts"`
+	BookgDt     IsoDateTime         `xml:"BookgDt"`
+	ValDt       IsoDateTime         `xml:"ValDt"`
+	AcctSvcrRef string              `xml:"AcctSvcrRef"`
+	BkTxCd      Camt053BankTxCode   `xml:"BkTxCd"`
+	NtryDtls    Camt053EntryDetails `xml:"NtryDtls"`
+}
+
+type Camt053EntryStatus struct {
+	Cd string `xml:"Cd"`
+}
+
+// Camt053BankTxCode is the transaction type as a proprietary bank transaction code.
+type Camt053BankTxCode struct {
+	Prtry struct {
+		Cd   string `xml:"Cd"`
+		Issr string `xml:"Issr"`
+	} `xml:"Prtry"`
+}
+
+type Camt053EntryDetails struct {
+	TxDtls Camt053TxDetails `xml:"TxDtls"`
+}
+
+type Camt053TxDetails struct {
+	Refs      Camt053Refs            `xml:"Refs"`
+	Amt       IsoAmount              `xml:"Amt"`
+	CdtDbtInd string                 `xml:"CdtDbtInd"`
+	AmtDtls   *Camt053AmountDetails  `xml:"AmtDtls,omitempty"`
+	RltdPties *Camt053RelatedParties `xml:"RltdPties,omitempty"`
+	RmtInf    *Camt053Remittance     `xml:"RmtInf,omitempty"`
+}
+
+type Camt053Refs struct {
+	AcctSvcrRef string `xml:"AcctSvcrRef"`
+	EndToEndId  string `xml:"EndToEndId,omitempty"`
+}
+
+// Camt053AmountDetails is the counter value of an exchange between currencies.
+type Camt053AmountDetails struct {
+	CntrValAmt struct {
+		Amt     IsoAmount `xml:"Amt"`
+		CcyXchg struct {
+			SrcCcy   string `xml:"SrcCcy"`
+			TrgtCcy  string `xml:"TrgtCcy"`
+			XchgRate string `xml:"XchgRate"`
+		} `xml:"CcyXchg"`
+	} `xml:"CntrValAmt"`
+}
+
+// Camt053RelatedParties is the counterparty wallet: the debtor account of a credit, the creditor account of a debit.
+type Camt053RelatedParties struct {
+	DbtrAcct *IsoAccount `xml:"DbtrAcct,omitempty"`
+	CdtrAcct *IsoAccount `xml:"CdtrAcct,omitempty"`
+}
+
+type Camt053Remittance struct {
+	Ustrd string `xml:"Ustrd"`
+}
+
+// Pain001Document is a pain.001 customer credit transfer initiation. The elements are matched by
+// name in any namespace, so that the versions with the same structure are all read.
+type Pain001Document struct {
+	XMLName          xml.Name           `xml:"Document"`
+	CstmrCdtTrfInitn *Pain001Initiation `xml:"CstmrCdtTrfInitn"`
+}
+
+type Pain001Initiation struct {
+	GrpHdr Pain001GroupHeader   `xml:"GrpHdr"`
+	PmtInf []Pain001PaymentInfo `xml:"PmtInf"`
+}
+
+type Pain001GroupHeader struct {
+	MsgId   string `xml:"MsgId"`
+	CreDtTm string `xml:"CreDtTm"`
+	NbOfTxs string `xml:"NbOfTxs"`
+	CtrlSum string `xml:"CtrlSum"`
+}
+
+// Pain001PaymentInfo is a set of credit transfers from one debtor account.
+type Pain001PaymentInfo struct {
+	PmtInfId    string                  `xml:"PmtInfId"`
+	PmtMtd      string                  `xml:"PmtMtd"`
+	NbOfTxs     string                  `xml:"NbOfTxs"`
+	CtrlSum     string                  `xml:"CtrlSum"`
+	ReqdExctnDt Pain001Date             `xml:"ReqdExctnDt"`
+	DbtrAcct    IsoAccount              `xml:"DbtrAcct"`
+	CdtTrfTxInf []Pain001CreditTransfer `xml:"CdtTrfTxInf"`
+}
+
+// Pain001Date is the requested execution date: the text of the element up to version 3,
+// its Dt or DtTm element since version 8.
+type Pain001Date struct {
+	Dt    string `xml:"Dt"`
+	DtTm  string `xml:"DtTm"`
+	Value string `xml:",chardata"`
+}
+
+type Pain001CreditTransfer struct {
+	PmtId struct {
+		InstrId    string `xml:"InstrId"`
+		EndToEndId string `xml:"EndToEndId"`
+	} `xml:"PmtId"`
+	Amt struct {
+		InstdAmt IsoAmount `xml:"InstdAmt"`
+	} `xml:"Amt"`
+	CdtrAcct IsoAccount `xml:"CdtrAcct"`
+	RmtInf   struct {
+		Ustrd []string `xml:"Ustrd"`
+	} `xml:"RmtInf"`
+}
+
+// date returns the requested execution date, the zero time when it is not set.
+func (d Pain001Date) date() (time.Time, error) {
+	switch {
+	case d.Dt != "":
+		return time.Parse(time.DateOnly, strings.TrimSpace(d.Dt))
+	case d.DtTm != "":
+		return time.Parse(time.RFC3339, strings.TrimSpace(d.DtTm))
+	case strings.TrimSpace(d.Value) != "":
+		return time.Parse(time.DateOnly, strings.TrimSpace(d.Value))
+	}
+	return time.Time{}, nil
+}
+
+// ToBatchTransferRequest validates the payment file as credit transfers from wallet and turns it into
+// a batch in mode. Every payment must debit the wallet, in its currency, and credit another wallet
+// given by its ID; the payments are executed right away, so none may be requested for after today,
+// the UTC day starting at today.
+// The number of transactions and the control sums are checked where they are given.
+// The remittance information becomes the description and the end to end identification the external reference.
+// The batch itself is validated with BatchTransferRequest.ValidateRequest.
+func (d Pain001Document) ToBatchTransferRequest(wallet Wallet, mode string, today time.Time) (BatchTransferRequest, error) {
+	msg := BatchTransferRequest{Mode: mode}
+	if d.CstmrCdtTrfInitn == nil {
+		return msg, Errorf(ErrCodeValidationFailed, "the document is not a pain.001 customer credit transfer initiation")
+	}
+	msg.MsgId = strings.TrimSpace(d.CstmrCdtTrfInitn.GrpHdr.MsgId)
+	if msg.MsgId == "" {
+		return msg, Errorf(ErrCodeValidationFailed, "GrpHdr/MsgId is mandatory")
+	}
+
+	// Counted before anything is built, a file is paid as a single batch
+	count := 0
+	for _, pmtInf := range d.CstmrCdtTrfInitn.PmtInf {
+		count += len(pmtInf.CdtTrfTxInf)
+	}
+	if count > MaxBatchTransferItems {
+		return msg, Errorf(ErrCodeValidationFailed, "a payment file must not have more than %d transactions", MaxBatchTransferItems).
+			WithDetails(map[string]string{"transactions": strconv.Itoa(count)})
+	}
+
+	total := decimal.Zero
+	for _, pmtInf := range d.CstmrCdtTrfInitn.PmtInf {
+		if pmtInf.PmtMtd != "TRF" {
+			return msg, Errorf(ErrCodeValidationFailed, "payment %s: PmtMtd must be TRF", pmtInf.PmtInfId)
+		}
+		if pmtInf.DbtrAcct.Id.Othr == nil || strings.TrimSpace(pmtInf.DbtrAcct.Id.Othr.Id) != strconv.FormatInt(wallet.ID, 10) {
+			return msg, Errorf(ErrCodeValidationFailed, "payment %s: DbtrAcct must be wallet %d", pmtInf.PmtInfId, wallet.ID)
+		}
+		if pmtInf.DbtrAcct.Ccy != "" && pmtInf.DbtrAcct.Ccy != wallet.Currency {
+			return msg, Errorf(ErrCodeValidationFailed, "payment %s: DbtrAcct currency must be %s", pmtInf.PmtInfId, wallet.Currency)
+		}
+
+		executionDate, err := pmtInf.ReqdExctnDt.date()
+		if err != nil {
+			return msg, Errorf(ErrCodeValidationFailed, "payment %s: ReqdExctnDt is not a valid date", pmtInf.PmtInfId)
+		}
+		if !executionDate.Before(today.AddDate(0, 0, 1)) {
+			return msg, Errorf(ErrCodeValidationFailed, "payment %s: ReqdExctnDt must not be after today, use scheduled transfers for later payments", pmtInf.PmtInfId)
+		}
+
+		sum := decimal.Zero
+		for _, tx := range pmtInf.CdtTrfTxInf {
+			endToEndId := strings.TrimSpace(tx.PmtId.EndToEndId)
+			amount, err := decimal.NewFromString(strings.TrimSpace(tx.Amt.InstdAmt.Value))
+			if err != nil {
+				return msg, Errorf(ErrCodeValidationFailed, "payment %s, transaction %s: InstdAmt is not a valid amount", pmtInf.PmtInfId, endToEndId)
+			}
+			if tx.Amt.InstdAmt.Ccy != wallet.Currency {
+				return msg, Errorf(ErrCodeValidationFailed, "payment %s, transaction %s: InstdAmt must be in %s", pmtInf.PmtInfId, endToEndId, wallet.Currency)
+			}
+			if tx.CdtrAcct.Id.Othr == nil {
+				return msg, Errorf(ErrCodeValidationFailed, "payment %s, transaction %s: CdtrAcct must be a wallet ID as other identification", pmtInf.PmtInfId, endToEndId)
+			}
+			destination, err := strconv.ParseInt(strings.TrimSpace(tx.CdtrAcct.Id.Othr.Id), 10, 64)
+			if err != nil {
+				return msg, Errorf(ErrCodeValidationFailed, "payment %s, transaction %s: CdtrAcct is not a wallet ID", pmtInf.PmtInfId, endToEndId)
+			}
+
+			item := BatchTransferItemRequest{
+				Amount:              amount,
+				DestinationWalletID: &destination,
+				Description:         strings.Join(tx.RmtInf.Ustrd, " "),
+			}
+			if endToEndId != Pain001NotProvided {
+				item.ExternalReference = endToEndId
+			}
+			msg.Items = append(msg.Items, item)
+			sum = sum.Add(amount)
+		}
+
+		if err := checkPain001Totals("payment "+pmtInf.PmtInfId, pmtInf.NbOfTxs, pmtInf.CtrlSum, false, len(pmtInf.CdtTrfTxInf), sum); err != nil {
+			return msg, err
+		}
+		total = total.Add(sum)
+	}
+
+	grpHdr := d.CstmrCdtTrfInitn.GrpHdr
+	if err := checkPain001Totals("GrpHdr", grpHdr.NbOfTxs, grpHdr.CtrlSum, true, len(msg.Items), total); err != nil {
+		return msg, err
+	}
+
+	return msg, msg.ValidateRequest()
+}
+
+// checkPain001Totals checks the number of transactions and the control sum declared in the block named
+// where against those counted. The control sum is optional, and so is the number unless mandatory.
+func checkPain001Totals(where string, nbOfTxs string, ctrlSum string, mandatory bool, count int, sum decimal.Decimal) error {
+	nbOfTxs, ctrlSum = strings.TrimSpace(nbOfTxs), strings.TrimSpace(ctrlSum)
+	if nbOfTxs != "" || mandatory {
+		declared, err := strconv.Atoi(nbOfTxs)
+		if err != nil || declared != count {
+			return Errorf(ErrCodeValidationFailed, "%s: NbOfTxs must be the number of transactions, %d", where, count)
+		}
+	}
+	if ctrlSum != "" {
+		declaredSum, err := decimal.NewFromString(ctrlSum)
+		if err != nil || !declaredSum.Equal(sum) {
+			return Errorf(ErrCodeValidationFailed, "%s: CtrlSum must be the sum of the amounts, %s", where, sum.String())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CcyAllocation is the part of a portfolio held in Currency: Balance, worth Value in the reporting
+// currency, which is Percentage of the total value.
+type CcyAllocation struct {
+	Currency   string
+	Balance    decimal.Decimal
+	Value      decimal.Decimal
+	Percentage decimal.Decimal
+}
+
+// PortfolioValue is the value of the wallets of a user on Date in Currency. Currencies without a rate
+// to Currency are left out of Total and listed in UnpricedCurrencies; StaleCurrencies were valued at
+// rates older than allowed.
+type PortfolioValue struct {
+	Date               time.Time
+	Currency           string
+	Total              decimal.Decimal
+	Allocations        []CcyAllocation
+	UnpricedCurrencies []string
+	StaleCurrencies    []string
+}
+
+// ValuePortfolio values balances, keyed by currency, in reportingCcy over conversions, resolving each rate
+// as ResolveCcyRate does. Zero balances are left out. Allocations are sorted by currency and their
+// percentages rounded to two decimals.
+func ValuePortfolio(date time.Time, balances map[string]decimal.Decimal, conversions []CcyConversion, reportingCcy string, limits RateAgeLimits) PortfolioValue {
+	ccys := make([]string, 0, len(balances))
+	for ccy, balance := range balances {
+		if !balance.IsZero() {
+			ccys = append(ccys, ccy)
+		}
+	}
+	slices.Sort(ccys)
+
+	value := PortfolioValue{Date: date, Currency: reportingCcy, Total: decimal.Zero}
+	for _, ccy := range ccys {
+		path := ResolveCcyRate(conversions, ccy, reportingCcy)
+		if path == nil {
+			value.UnpricedCurrencies = append(value.UnpricedCurrencies, ccy)
+			continue
+		}
+		if path.StaleHop(limits) != nil {
+			value.StaleCurrencies = append(value.StaleCurrencies, ccy)
+		}
+
+		allocation := CcyAllocation{Currency: ccy, Balance: balances[ccy], Value: balances[ccy].Mul(path.Rate)}
+		value.Allocations = append(value.Allocations, allocation)
+		value.Total = value.Total.Add(allocation.Value)
+	}
+
+	for i := range value.Allocations {
+		if value.Total.IsZero() {
+			value.Allocations[i].Percentage = decimal.Zero
+			continue
+		}
+		value.Allocations[i].Percentage = value.Allocations[i].Value.Div(value.Total).Mul(decimal.NewFromInt(100)).Round(AmountScale)
+	}
+	return value
+}
@@ -1,8 +1,12 @@
 package models
 
 import (
+	"encoding/xml"
 	"fmt"
+	"regexp"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/shopspring/decimal"
 )
@@ -11,6 +15,121 @@ type TransactionRequest struct {
 	Amount              decimal.Decimal `json:"amount"`
 	DestinationWalletID *int64          `json:"destination_wallet_id,omitempty"`
 	DestinationUserID   *int64          `json:"destination_user_id,omitempty"`
+	Description         string          `json:"description,omitempty"`
+	ExternalReference   string          `json:"external_reference,omitempty"`
+	Tags                []string        `json:"tags,omitempty"`
+	// Status is pending or completed, the default. Only deposits and withdrawals can be pending.
+	Status string `json:"status,omitempty"`
+	// QuoteID executes a transfer at the rate of an open FX quote between the currencies of both wallets.
+	QuoteID *int64 `json:"quote_id,omitempty"`
+}
+
+// ConversionRequest converts funds between two wallets of the same user. Exactly one of the amounts
+// is set: FromAmount sells that amount of the from currency, ToAmount buys that amount of the to currency.
+type ConversionRequest struct {
+	FromWalletID int64            `json:"from_wallet_id"`
+	ToWalletID   int64            `json:"to_wallet_id"`
+	FromAmount   *decimal.Decimal `json:"from_amount,omitempty"`
+	ToAmount     *decimal.Decimal `json:"to_amount,omitempty"`
+	Description  string           `json:"description,omitempty"`
+}
+
+// FxQuoteRequest locks the current conversion rate from FromCcy to ToCcy, priced for the tier of
+// the user with UserID. Only a wallet of that user can use the quote.
+type FxQuoteRequest struct {
+	FromCcy string `json:"from_ccy"`
+	ToCcy   string `json:"to_ccy"`
+	UserID  int64  `json:"user_id"`
+}
+
+// UserUpdateRequest updates the preferences of a user.
+type UserUpdateRequest struct {
+	ReportingCurrency string `json:"reporting_currency"`
+}
+
+// CcyRateRequest sets the stored rate from FromCcy to ToCcy, which are taken from the URL path.
+type CcyRateRequest struct {
+	FromCcy string          `json:"-"`
+	ToCcy   string          `json:"-"`
+	Rate    decimal.Decimal `json:"rate"`
+}
+
+// TransactionStatusRequest moves a transaction to the next status.
+type TransactionStatusRequest struct {
+	Status string `json:"status"`
+}
+
+// TransferReversalRequest reverses a transfer. Amount is in the source currency of the
+// original transfer and defaults to the part that has not been reversed yet.
+type TransferReversalRequest struct {
+	Amount      *decimal.Decimal `json:"amount,omitempty"`
+	Description string           `json:"description,omitempty"`
+}
+
+// TransactionNotesRequest edits the notes of an existing transaction.
+// Fields that are not provided are left unchanged; an empty description or
+// an empty tag list clears the corresponding field.
+type TransactionNotesRequest struct {
+	Description *string   `json:"description,omitempty"`
+	Tags        *[]string `json:"tags,omitempty"`
+}
+
+// HoldRequest creates a hold. The hold expires after ExpiresInSeconds,
+// or after the configured default when it is not provided.
+type HoldRequest struct {
+	Amount           decimal.Decimal `json:"amount"`
+	Description      string          `json:"description,omitempty"`
+	ExpiresInSeconds *int64          `json:"expires_in_seconds,omitempty"`
+}
+
+// HoldCaptureRequest captures a hold. Amount defaults to the full amount of the hold;
+// the part that is not captured is released.
+type HoldCaptureRequest struct {
+	Amount *decimal.Decimal `json:"amount,omitempty"`
+}
+
+// BatchTransferRequest pays many destinations from one wallet. Mode defaults to all_or_nothing.
+// MsgId is set when the batch comes from a payment file, to the message identification of the file.
+type BatchTransferRequest struct {
+	Mode  string                     `json:"mode,omitempty"`
+	Items []BatchTransferItemRequest `json:"items"`
+	MsgId string                     `json:"-"`
+}
+
+// BatchTransferItemRequest is one transfer of a batch, to a destination wallet or user.
+type BatchTransferItemRequest struct {
+	Amount              decimal.Decimal `json:"amount"`
+	DestinationWalletID *int64          `json:"destination_wallet_id,omitempty"`
+	DestinationUserID   *int64          `json:"destination_user_id,omitempty"`
+	Description         string          `json:"description,omitempty"`
+	ExternalReference   string          `json:"external_reference,omitempty"`
+}
+
+// ScheduledTransferRequest schedules a transfer from a wallet, once at StartAt or repeatedly at
+// the given frequency until EndAt or MaxOccurrences is reached. StartAt defaults to now and
+// OnInsufficientFunds to the configured policy.
+type ScheduledTransferRequest struct {
+	Amount              decimal.Decimal `json:"amount"`
+	DestinationWalletID *int64          `json:"destination_wallet_id,omitempty"`
+	DestinationUserID   *int64          `json:"destination_user_id,omitempty"`
+	Description         string          `json:"description,omitempty"`
+	ExternalReference   string          `json:"external_reference,omitempty"`
+	Frequency           string          `json:"frequency"`
+	StartAt             *time.Time      `json:"start_at,omitempty"`
+	EndAt               *time.Time      `json:"end_at,omitempty"`
+	MaxOccurrences      *int64          `json:"max_occurrences,omitempty"`
+	OnInsufficientFunds string          `json:"on_insufficient_funds,omitempty"`
+}
+
+// ScheduledTransferUpdateRequest changes a scheduled transfer. Only the fields provided are changed;
+// Status pauses or resumes the schedule.
+type ScheduledTransferUpdateRequest struct {
+	Amount              *decimal.Decimal `json:"amount,omitempty"`
+	Description         *string          `json:"description,omitempty"`
+	EndAt               *time.Time       `json:"end_at,omitempty"`
+	MaxOccurrences      *int64           `json:"max_occurrences,omitempty"`
+	OnInsufficientFunds *string          `json:"on_insufficient_funds,omitempty"`
+	Status              *string          `json:"status,omitempty"`
 }
 
 type UserInfo struct {
@@ -18,28 +137,487 @@ type UserInfo struct {
 	Name string `json:"name"`
 }
 
+type UserResponse struct {
+	ID                int64     `json:"id"`
+	Name              string    `json:"name"`
+	ReportingCurrency string    `json:"reporting_currency"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// WalletDetail is a wallet in the balance and history responses. ReportingBalance is the balance
+// valued in the currency of the total, unset when there is no rate.
 type WalletDetail struct {
-	ID           int64                    `json:"id"`
-	IsDefault    bool                     `json:"is_default"`
-	Type         string                   `json:"type"`
-	Currency     string                   `json:"currency"`
-	Balance      MoneyDecimal             `json:"balance"`
-	Transactions []TransactionSummaryItem `json:"transactions,omitempty"`
+	ID               int64                    `json:"id"`
+	IsDefault        bool                     `json:"is_default"`
+	Type             string                   `json:"type"`
+	Currency         string                   `json:"currency"`
+	Balance          MoneyDecimal             `json:"balance"`
+	Available        *MoneyDecimal            `json:"available_balance,omitempty"`
+	PendingIn        *MoneyDecimal            `json:"pending_incoming,omitempty"`
+	PendingOut       *MoneyDecimal            `json:"pending_outgoing,omitempty"`
+	ReportingBalance *MoneyDecimal            `json:"reporting_balance,omitempty"`
+	Transactions     []TransactionSummaryItem `json:"transactions,omitempty"`
 }
 
 type TransactionSummaryItem struct {
 	ID                   int64        `json:"id"`
 	Type                 string       `json:"type"`
 	Amount               MoneyDecimal `json:"amount"`
+	Status               string       `json:"status"`
 	CounterpartyWalletID *int64       `json:"counterparty_wallet_id,omitempty"`
 	Time                 time.Time    `json:"time"`
+	Description          string       `json:"description,omitempty"`
+	ExternalReference    string       `json:"external_reference,omitempty"`
+	Tags                 []string     `json:"tags,omitempty"`
+	TransferID           *int64       `json:"transfer_id,omitempty"`
+	*TransferDetail
+	Conversion *ConversionDetail `json:"conversion,omitempty"`
 }
 
-type Total struct {
+// ConversionDetail describes a conversion between wallets of the same user. Spread is a percentage
+// of the mid rate and SpreadAmount what it took, in the to currency.
+type ConversionDetail struct {
+	ID           int64           `json:"id"`
+	FromWalletID int64           `json:"from_wallet_id"`
+	FromCurrency string          `json:"from_currency"`
+	FromAmount   MoneyDecimal    `json:"from_amount"`
+	ToWalletID   int64           `json:"to_wallet_id"`
+	ToCurrency   string          `json:"to_currency"`
+	ToAmount     MoneyDecimal    `json:"to_amount"`
+	MidRate      decimal.Decimal `json:"mid_rate"`
+	Rate         decimal.Decimal `json:"rate"`
+	Spread       decimal.Decimal `json:"spread"`
+	SpreadAmount MoneyDecimal    `json:"spread_amount"`
+}
+
+type ConversionResponse struct {
+	ConversionDetail
+	UserID        int64               `json:"user_id"`
+	CreatedAt     time.Time           `json:"created_at"`
+	ConversionOut TransactionResponse `json:"conversion_out"`
+	ConversionIn  TransactionResponse `json:"conversion_in"`
+}
+
+// TransferDetail enriches a transfer transaction with information about the other side.
+type TransferDetail struct {
+	Counterparty        *Counterparty    `json:"counterparty,omitempty"`
+	LinkedTransactionID *int64           `json:"linked_transaction_id,omitempty"`
+	Rate                *decimal.Decimal `json:"rate,omitempty"`
+	TransferStatus      string           `json:"transfer_status,omitempty"`
+	ReversalOf          *int64           `json:"reversal_of,omitempty"`
+}
+
+// Counterparty is the other side of a transfer as shown to the wallet owner.
+// UserID is only revealed when both sides belong to the same user, and Name follows
+// the configured privacy policy otherwise.
+type Counterparty struct {
+	UserID   *int64        `json:"user_id,omitempty"`
+	Name     string        `json:"name,omitempty"`
+	WalletID int64         `json:"wallet_id"`
+	Currency string        `json:"currency"`
+	Amount   *MoneyDecimal `json:"amount,omitempty"`
+}
+
+type TransactionResponse struct {
+	ID                   int64         `json:"id"`
+	WalletID             int64         `json:"wallet_id"`
+	Type                 string        `json:"type"`
+	Currency             string        `json:"currency"`
+	Amount               MoneyDecimal  `json:"amount"`
+	Status               string        `json:"status"`
+	CounterpartyWalletID *int64        `json:"counterparty_wallet_id,omitempty"`
+	Balance              *MoneyDecimal `json:"balance,omitempty"`
+	CreatedAt            time.Time     `json:"created_at"`
+	CompletedAt          *time.Time    `json:"completed_at,omitempty"`
+	FailedAt             *time.Time    `json:"failed_at,omitempty"`
+	ReversedAt           *time.Time    `json:"reversed_at,omitempty"`
+	Description          string        `json:"description,omitempty"`
+	ExternalReference    string        `json:"external_reference,omitempty"`
+	Tags                 []string      `json:"tags,omitempty"`
+	TransferID           *int64        `json:"transfer_id,omitempty"`
+	Fee                  *FeeResponse  `json:"fee,omitempty"`
+	// EffectiveRate is the rate given to the customer on an exchange between currencies, spread included.
+	EffectiveRate *decimal.Decimal `json:"effective_rate,omitempty"`
+	*TransferDetail
+}
+
+// FeeResponse is the breakdown of the fee charged for a transaction, in the currency of the transaction.
+// Adjustment is what the min or max fee of the rule added to or took from the flat and percentage fees.
+// The fee transaction and the balance after it are only set once the fee is charged.
+type FeeResponse struct {
+	FeeRuleID     int64         `json:"fee_rule_id"`
+	Status        string        `json:"status"`
+	Currency      string        `json:"currency"`
+	Amount        MoneyDecimal  `json:"amount"`
+	FlatFee       MoneyDecimal  `json:"flat_fee"`
+	PercentageFee MoneyDecimal  `json:"percentage_fee"`
+	Adjustment    MoneyDecimal  `json:"adjustment"`
+	TransactionID *int64        `json:"transaction_id,omitempty"`
+	Balance       *MoneyDecimal `json:"balance,omitempty"`
+}
+
+type TransferResponse struct {
+	ID          int64               `json:"id"`
+	Status      string              `json:"status"`
+	ReversalOf  *int64              `json:"reversal_of,omitempty"`
+	Rate        decimal.Decimal     `json:"rate"`
+	RatePath    string              `json:"rate_path,omitempty"`
+	QuoteID     *int64              `json:"quote_id,omitempty"`
+	TransferOut TransactionResponse `json:"transfer_out"`
+	TransferIn  TransactionResponse `json:"transfer_in"`
+}
+
+// TransferPreviewResponse shows how a transfer would be made without making it. The target, the rate
+// and the fee are only set as far as they could be resolved; Errors lists every reason the transfer
+// would be rejected.
+type TransferPreviewResponse struct {
+	Valid            bool             `json:"valid"`
+	SourceWalletID   int64            `json:"source_wallet_id"`
+	Currency         string           `json:"currency"`
+	Amount           MoneyDecimal     `json:"amount"`
+	AvailableBalance MoneyDecimal     `json:"available_balance"`
+	TargetWalletID   *int64           `json:"target_wallet_id,omitempty"`
+	TargetUserID     *int64           `json:"target_user_id,omitempty"`
+	TargetCurrency   string           `json:"target_currency,omitempty"`
+	Rate             *decimal.Decimal `json:"rate,omitempty"`
+	RatePath         string           `json:"rate_path,omitempty"`
+	TargetAmount     *MoneyDecimal    `json:"target_amount,omitempty"`
+	Fee              *FeeResponse     `json:"fee,omitempty"`
+	TotalDebit       *MoneyDecimal    `json:"total_debit,omitempty"`
+	Errors           []ErrorResponse  `json:"errors,omitempty"`
+}
+
+// TransferSide is the source or the target of a transfer.
+type TransferSide struct {
+	WalletID      int64        `json:"wallet_id"`
+	Currency      string       `json:"currency"`
+	Amount        MoneyDecimal `json:"amount"`
+	TransactionID *int64       `json:"transaction_id,omitempty"`
+}
+
+type TransferSummary struct {
+	ID             int64           `json:"id"`
+	Status         string          `json:"status"`
+	Rate           decimal.Decimal `json:"rate"`
+	RatePath       string          `json:"rate_path,omitempty"`
+	Source         TransferSide    `json:"source"`
+	Target         TransferSide    `json:"target"`
+	ReversalOf     *int64          `json:"reversal_of,omitempty"`
+	ReversedAmount *MoneyDecimal   `json:"reversed_amount,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// Req: userID
+type UserTransfersResponse struct {
+	UserInfo  UserInfo          `json:"user_info"`
+	Transfers []TransferSummary `json:"transfers"`
+}
+
+type HoldResponse struct {
+	ID             int64         `json:"id"`
+	WalletID       int64         `json:"wallet_id"`
+	Currency       string        `json:"currency"`
+	Amount         MoneyDecimal  `json:"amount"`
+	CapturedAmount *MoneyDecimal `json:"captured_amount,omitempty"`
+	Status         string        `json:"status"`
+	Description    string        `json:"description,omitempty"`
+	TransactionID  *int64        `json:"transaction_id,omitempty"`
+	ExpiresAt      *time.Time    `json:"expires_at,omitempty"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
+
+// WalletBalanceAtResponse is the ledger balance of a wallet at AsOf.
+// AvailableBalance is only set for the current balance.
+type WalletBalanceAtResponse struct {
+	WalletID         int64         `json:"wallet_id"`
+	Currency         string        `json:"currency"`
+	Balance          MoneyDecimal  `json:"balance"`
+	AvailableBalance *MoneyDecimal `json:"available_balance,omitempty"`
+	AsOf             time.Time     `json:"as_of"`
+}
+
+// BalanceHistoryResponse is the closing balance of a wallet on every day from From to To, both dates included.
+type BalanceHistoryResponse struct {
+	WalletID int64                  `json:"wallet_id"`
+	Currency string                 `json:"currency"`
+	From     string                 `json:"from"`
+	To       string                 `json:"to"`
+	Balances []DailyBalanceResponse `json:"balances"`
+}
+
+type DailyBalanceResponse struct {
+	Date    string       `json:"date"`
+	Balance MoneyDecimal `json:"balance"`
+}
+
+// PortfolioHistoryResponse is the value of the wallets of a user at the close of every day from From to To,
+// both dates included, in Currency.
+type PortfolioHistoryResponse struct {
+	UserInfo UserInfo                 `json:"user_info"`
+	Currency string                   `json:"currency"`
+	From     string                   `json:"from"`
+	To       string                   `json:"to"`
+	Values   []PortfolioValueResponse `json:"values"`
+}
+
+// PortfolioValueResponse is the value of a portfolio on Date. Partial is set when some currencies had no
+// rate and are left out of Total, Stale when some were valued at rates older than allowed.
+type PortfolioValueResponse struct {
+	Date               string                  `json:"date"`
+	Total              MoneyDecimal            `json:"total"`
+	Partial            bool                    `json:"partial,omitempty"`
+	UnpricedCurrencies []string                `json:"unpriced_currencies,omitempty"`
+	Stale              bool                    `json:"stale,omitempty"`
+	StaleCurrencies    []string                `json:"stale_currencies,omitempty"`
+	Allocations        []CcyAllocationResponse `json:"allocations"`
+}
+
+type CcyAllocationResponse struct {
+	Currency   string       `json:"currency"`
+	Balance    MoneyDecimal `json:"balance"`
+	Value      MoneyDecimal `json:"value"`
+	Percentage MoneyDecimal `json:"percentage"`
+}
+
+// WalletCostBasisResponse is the cost basis of a crypto wallet in ReportingCurrency. MarketValue and
+// UnrealizedGain value what is held at the current rates, unset when there is no rate.
+type WalletCostBasisResponse struct {
+	WalletID             int64           `json:"wallet_id"`
+	Currency             string          `json:"currency"`
+	ReportingCurrency    string          `json:"reporting_currency"`
+	Method               string          `json:"method"`
+	Quantity             decimal.Decimal `json:"quantity"`
+	CostBasis            MoneyDecimal    `json:"cost_basis"`
+	MarketValue          *MoneyDecimal   `json:"market_value,omitempty"`
+	UnrealizedGain       *MoneyDecimal   `json:"unrealized_gain,omitempty"`
+	RealizedGain         MoneyDecimal    `json:"realized_gain"`
+	Lots                 []LotResponse   `json:"lots"`
+	UnpricedTransactions []int64         `json:"unpriced_transactions,omitempty"`
+}
+
+// LotResponse is a lot still held. Quantities are in the wallet currency, CostBasis in the reporting currency.
+type LotResponse struct {
+	TransactionID int64           `json:"transaction_id"`
+	AcquiredAt    time.Time       `json:"acquired_at"`
+	Quantity      decimal.Decimal `json:"quantity"`
+	Remaining     decimal.Decimal `json:"remaining"`
+	CostBasis     MoneyDecimal    `json:"cost_basis"`
+	Unpriced      bool            `json:"unpriced,omitempty"`
+}
+
+// GainsReportResponse is the gains realized by the disposals from the crypto wallets of a user in Year,
+// in ReportingCurrency.
+type GainsReportResponse struct {
+	UserInfo             UserInfo           `json:"user_info"`
+	Year                 int                `json:"year"`
+	ReportingCurrency    string             `json:"reporting_currency"`
+	Method               string             `json:"method"`
+	Disposals            []DisposalResponse `json:"disposals"`
+	TotalProceeds        MoneyDecimal       `json:"total_proceeds"`
+	TotalCostBasis       MoneyDecimal       `json:"total_cost_basis"`
+	TotalGain            MoneyDecimal       `json:"total_gain"`
+	UnpricedTransactions []int64            `json:"unpriced_transactions,omitempty"`
+}
+
+// DisposalResponse is a disposal in a gains report. AcquiredAt is when the oldest lot it came from was
+// acquired, unset when the wallet held nothing.
+type DisposalResponse struct {
+	WalletID      int64           `json:"wallet_id"`
+	Currency      string          `json:"currency"`
+	TransactionID int64           `json:"transaction_id"`
+	Type          string          `json:"type"`
+	AcquiredAt    *time.Time      `json:"acquired_at,omitempty"`
+	DisposedAt    time.Time       `json:"disposed_at"`
+	Quantity      decimal.Decimal `json:"quantity"`
+	Proceeds      MoneyDecimal    `json:"proceeds"`
+	CostBasis     MoneyDecimal    `json:"cost_basis"`
+	Gain          MoneyDecimal    `json:"gain"`
+	Unpriced      bool            `json:"unpriced,omitempty"`
+}
+
+// StatementResponse is the monthly statement of a wallet for Period, from From to To, both dates included.
+// TotalDebits and TotalFees are positive; fees are part of the debits.
+type StatementResponse struct {
+	WalletID       int64                    `json:"wallet_id"`
+	Currency       string                   `json:"currency"`
+	Period         string                   `json:"period"`
+	From           string                   `json:"from"`
+	To             string                   `json:"to"`
+	OpeningBalance MoneyDecimal             `json:"opening_balance"`
+	ClosingBalance MoneyDecimal             `json:"closing_balance"`
+	TotalCredits   MoneyDecimal             `json:"total_credits"`
+	TotalDebits    MoneyDecimal             `json:"total_debits"`
+	TotalFees      MoneyDecimal             `json:"total_fees"`
+	Entries        []StatementEntryResponse `json:"entries"`
+	GeneratedAt    time.Time                `json:"generated_at"`
+}
+
+// StatementEntryResponse is an entry of a statement. Amount is negative for a debit and Balance is
+// the balance right after the entry.
+type StatementEntryResponse struct {
+	TransactionID        int64                `json:"transaction_id"`
+	Type                 string               `json:"type"`
+	At                   time.Time            `json:"at"`
+	Description          string               `json:"description,omitempty"`
+	ExternalReference    string               `json:"external_reference,omitempty"`
+	Amount               MoneyDecimal         `json:"amount"`
+	Balance              MoneyDecimal         `json:"balance"`
+	Reversal             bool                 `json:"reversal,omitempty"`
+	CounterpartyWalletID *int64               `json:"counterparty_wallet_id,omitempty"`
+	FeeFor               *int64               `json:"fee_for,omitempty"`
+	FX                   *StatementFXResponse `json:"fx,omitempty"`
+}
+
+type StatementFXResponse struct {
+	Rate            decimal.Decimal `json:"rate"`
+	CounterCurrency string          `json:"counter_currency"`
+	CounterAmount   MoneyDecimal    `json:"counter_amount"`
+}
+
+// OfxTransaction is a transaction in an OFX bank statement. TrnAmt is negative for a debit.
+type OfxTransaction struct {
+	XMLName  xml.Name `xml:"STMTTRN"`
+	TrnType  string   `xml:"TRNTYPE"`
+	DtPosted string   `xml:"DTPOSTED"`
+	TrnAmt   string   `xml:"TRNAMT"`
+	FitID    string   `xml:"FITID"`
+	Name     string   `xml:"NAME"`
+	Memo     string   `xml:"MEMO,omitempty"`
+}
+
+type FxQuoteResponse struct {
+	ID         int64           `json:"id"`
+	UserID     int64           `json:"user_id"`
+	FromCcy    string          `json:"from_ccy"`
+	ToCcy      string          `json:"to_ccy"`
+	Rate       decimal.Decimal `json:"rate"`
+	MidRate    decimal.Decimal `json:"mid_rate"`
+	Side       string          `json:"side"`
+	Spread     decimal.Decimal `json:"spread"`
+	RatePath   string          `json:"rate_path"`
+	Status     string          `json:"status"`
+	TransferID *int64          `json:"transfer_id,omitempty"`
+	ExpiresAt  time.Time       `json:"expires_at"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UsedAt     *time.Time      `json:"used_at,omitempty"`
+}
+
+// CcyRateResponse is a stored rate. MaxAge is the maximum age of a rate between both currencies,
+// empty when it is not limited, and Stale is set when the rate is older.
+type CcyRateResponse struct {
+	FromCcy   string          `json:"from_ccy"`
+	ToCcy     string          `json:"to_ccy"`
+	Rate      decimal.Decimal `json:"rate"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	MaxAge    string          `json:"max_age,omitempty"`
+	Stale     bool            `json:"stale"`
+}
+
+type CcyRatesResponse struct {
+	Rates []CcyRateResponse `json:"rates"`
+}
+
+// SpreadRevenueResponse is the FX spread revenue recorded so far, one total per currency received by customers.
+type SpreadRevenueResponse struct {
+	Totals []SpreadRevenueTotalResponse `json:"totals"`
+}
+
+type SpreadRevenueTotalResponse struct {
 	Currency string       `json:"currency"`
+	Count    int64        `json:"count"`
 	Amount   MoneyDecimal `json:"amount"`
 }
 
+type HoldCaptureResponse struct {
+	Hold        HoldResponse        `json:"hold"`
+	Transaction TransactionResponse `json:"transaction"`
+}
+
+type BatchTransferResponse struct {
+	Mode           string                      `json:"mode"`
+	SourceWalletID int64                       `json:"source_wallet_id"`
+	Currency       string                      `json:"currency"`
+	TotalAmount    MoneyDecimal                `json:"total_amount"`
+	TotalFees      MoneyDecimal                `json:"total_fees"`
+	Succeeded      int                         `json:"succeeded"`
+	Failed         int                         `json:"failed"`
+	BalanceAfter   *MoneyDecimal               `json:"balance_after,omitempty"`
+	Items          []BatchTransferItemResponse `json:"items"`
+}
+
+type BatchTransferItemResponse struct {
+	Index          int              `json:"index"`
+	Status         string           `json:"status"`
+	Amount         MoneyDecimal     `json:"amount"`
+	TransferID     *int64           `json:"transfer_id,omitempty"`
+	TargetWalletID *int64           `json:"target_wallet_id,omitempty"`
+	TargetCurrency string           `json:"target_currency,omitempty"`
+	TargetAmount   *MoneyDecimal    `json:"target_amount,omitempty"`
+	Rate           *decimal.Decimal `json:"rate,omitempty"`
+	Fee            *FeeResponse     `json:"fee,omitempty"`
+	Error          *ErrorResponse   `json:"error,omitempty"`
+}
+
+type ScheduledTransferResponse struct {
+	ID                  int64        `json:"id"`
+	SourceWalletID      int64        `json:"source_wallet_id"`
+	DestinationWalletID *int64       `json:"destination_wallet_id,omitempty"`
+	DestinationUserID   *int64       `json:"destination_user_id,omitempty"`
+	Currency            string       `json:"currency"`
+	Amount              MoneyDecimal `json:"amount"`
+	Description         string       `json:"description,omitempty"`
+	ExternalReference   string       `json:"external_reference,omitempty"`
+	Frequency           string       `json:"frequency"`
+	StartAt             time.Time    `json:"start_at"`
+	EndAt               *time.Time   `json:"end_at,omitempty"`
+	MaxOccurrences      *int64       `json:"max_occurrences,omitempty"`
+	Occurrences         int64        `json:"occurrences"`
+	NextRunAt           *time.Time   `json:"next_run_at,omitempty"`
+	RetryAt             *time.Time   `json:"retry_at,omitempty"`
+	OnInsufficientFunds string       `json:"on_insufficient_funds"`
+	Status              string       `json:"status"`
+	CreatedAt           time.Time    `json:"created_at"`
+	UpdatedAt           time.Time    `json:"updated_at"`
+}
+
+// Req: userID
+type UserScheduledTransfersResponse struct {
+	UserInfo           UserInfo                    `json:"user_info"`
+	ScheduledTransfers []ScheduledTransferResponse `json:"scheduled_transfers"`
+}
+
+type ScheduledTransferRunResponse struct {
+	ID           int64     `json:"id"`
+	ScheduledFor time.Time `json:"scheduled_for"`
+	Attempt      int64     `json:"attempt"`
+	Status       string    `json:"status"`
+	TransferID   *int64    `json:"transfer_id,omitempty"`
+	ErrorCode    string    `json:"error_code,omitempty"`
+	ErrorMessage string    `json:"error_message,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Req: scheduledTransferID
+type ScheduledTransferRunsResponse struct {
+	ScheduledTransferID int64                          `json:"scheduled_transfer_id"`
+	Runs                []ScheduledTransferRunResponse `json:"runs"`
+}
+
+// Total is the value of the wallets in Currency. Stale is set when it was computed with rates older
+// than allowed, StaleCurrencies being the currencies of the wallets converted at such rates. Partial is
+// set when some wallets have no rate to Currency: Amount then only covers the priced wallets, and the
+// others are listed in UnpricedWallets and UnpricedCurrencies.
+type Total struct {
+	Currency           string       `json:"currency"`
+	Amount             MoneyDecimal `json:"amount"`
+	Stale              bool         `json:"stale,omitempty"`
+	StaleCurrencies    []string     `json:"stale_currencies,omitempty"`
+	Partial            bool         `json:"partial,omitempty"`
+	UnpricedCurrencies []string     `json:"unpriced_currencies,omitempty"`
+	UnpricedWallets    []int64      `json:"unpriced_wallets,omitempty"`
+}
+
 // Req: userID
 type WalletBalanceResponse struct {
 	UserInfo UserInfo       `json:"user_info"`
@@ -47,22 +625,361 @@ type WalletBalanceResponse struct {
 	Balance  *Total         `json:"total,omitempty"`
 }
 
+type ErrorResponse struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
 func (tr *TransactionRequest) ValidateRequest(txnType string) error {
 	if tr.Amount.LessThanOrEqual(decimal.Zero) {
-		return fmt.Errorf("amount field is mandatory and it must be greater than zero")
+		return Errorf(ErrCodeValidationFailed, "amount field is mandatory and it must be greater than zero")
+	}
+	if !IsStorableAmount(tr.Amount) {
+		return Errorf(ErrCodeValidationFailed, "amount must not have more than %d decimals", AmountScale)
 	}
 
 	if txnType == TxnTypeTransferOut || txnType == TxnTypeTransferIn {
+		if tr.Status != "" && tr.Status != TxnStatusCompleted {
+			return Errorf(ErrCodeValidationFailed, "transfers can only be created as completed")
+		}
 		if tr.DestinationUserID != nil && tr.DestinationWalletID != nil {
-			return fmt.Errorf("please specify only one of destination_user_id or destination_wallet_id, not both")
+			return Errorf(ErrCodeValidationFailed, "please specify only one of destination_user_id or destination_wallet_id, not both")
 		}
 		if tr.DestinationUserID == nil && tr.DestinationWalletID == nil {
-			return fmt.Errorf("please specify either destination_user_id or destination_wallet_id")
+			return Errorf(ErrCodeValidationFailed, "please specify either destination_user_id or destination_wallet_id")
+		}
+	} else if tr.QuoteID != nil {
+		return Errorf(ErrCodeValidationFailed, "quote_id is only accepted for transfers")
+	}
+
+	if err := validateDescription(tr.Description); err != nil {
+		return err
+	}
+
+	if len(tr.ExternalReference) > MaxExternalReferenceLength {
+		return Errorf(ErrCodeValidationFailed, "external_reference must not be longer than %d characters", MaxExternalReferenceLength)
+	}
+
+	if tr.Status != "" && tr.Status != TxnStatusPending && tr.Status != TxnStatusCompleted {
+		return Errorf(ErrCodeValidationFailed, "status must be either %s or %s", TxnStatusPending, TxnStatusCompleted)
+	}
+
+	tags, err := normalizeTags(tr.Tags)
+	if err != nil {
+		return err
+	}
+	tr.Tags = tags
+	return nil
+}
+
+// ValidateRequest checks that the requested status is a known transaction status.
+// Whether the transaction can move to it is checked against its current status.
+func (sr *TransactionStatusRequest) ValidateRequest() error {
+	switch sr.Status {
+	case TxnStatusCompleted, TxnStatusFailed, TxnStatusReversed:
+		return nil
+	case "":
+		return Errorf(ErrCodeValidationFailed, "status field is mandatory")
+	}
+	return Errorf(ErrCodeValidationFailed, "status must be one of %s, %s or %s", TxnStatusCompleted, TxnStatusFailed, TxnStatusReversed)
+}
+
+// ValidateRequest checks the notes and normalizes the tags.
+func (nr *TransactionNotesRequest) ValidateRequest() error {
+	if nr.Description == nil && nr.Tags == nil {
+		return Errorf(ErrCodeValidationFailed, "please specify description or tags")
+	}
+
+	if nr.Description != nil {
+		if err := validateDescription(*nr.Description); err != nil {
+			return err
+		}
+	}
+
+	if nr.Tags != nil {
+		tags, err := normalizeTags(*nr.Tags)
+		if err != nil {
+			return err
+		}
+		nr.Tags = &tags
+	}
+	return nil
+}
+
+func (rr *TransferReversalRequest) ValidateRequest() error {
+	if rr.Amount != nil && rr.Amount.LessThanOrEqual(decimal.Zero) {
+		return Errorf(ErrCodeValidationFailed, "amount must be greater than zero")
+	}
+	if rr.Amount != nil && !IsStorableAmount(*rr.Amount) {
+		return Errorf(ErrCodeValidationFailed, "amount must not have more than %d decimals", AmountScale)
+	}
+	return validateDescription(rr.Description)
+}
+
+func (cr *ConversionRequest) ValidateRequest() error {
+	if cr.FromWalletID <= 0 || cr.ToWalletID <= 0 {
+		return Errorf(ErrCodeValidationFailed, "from_wallet_id and to_wallet_id fields are mandatory")
+	}
+	if cr.FromWalletID == cr.ToWalletID {
+		return Errorf(ErrCodeValidationFailed, "from_wallet_id and to_wallet_id must be different wallets")
+	}
+	if (cr.FromAmount == nil) == (cr.ToAmount == nil) {
+		return Errorf(ErrCodeValidationFailed, "please specify either from_amount or to_amount")
+	}
+	if cr.FromAmount != nil && cr.FromAmount.LessThanOrEqual(decimal.Zero) {
+		return Errorf(ErrCodeValidationFailed, "from_amount must be greater than zero")
+	}
+	if cr.ToAmount != nil && cr.ToAmount.LessThanOrEqual(decimal.Zero) {
+		return Errorf(ErrCodeValidationFailed, "to_amount must be greater than zero")
+	}
+	if cr.FromAmount != nil && !IsStorableAmount(*cr.FromAmount) {
+		return Errorf(ErrCodeValidationFailed, "from_amount must not have more than %d decimals", AmountScale)
+	}
+	if cr.ToAmount != nil && !IsStorableAmount(*cr.ToAmount) {
+		return Errorf(ErrCodeValidationFailed, "to_amount must not have more than %d decimals", AmountScale)
+	}
+	return validateDescription(cr.Description)
+}
+
+// IsStorableAmount tells whether amount is stored as is, without more decimals than AmountScale.
+func IsStorableAmount(amount decimal.Decimal) bool {
+	return amount.Equal(amount.Truncate(AmountScale))
+}
+
+// ValidateRequest checks both currencies and the user, and normalizes the currencies to upper case.
+func (ur *UserUpdateRequest) ValidateRequest() error {
+	ccy, err := NormalizeCcy(ur.ReportingCurrency)
+	if err != nil {
+		return err
+	}
+	ur.ReportingCurrency = ccy
+	return nil
+}
+
+func (cr *CcyRateRequest) ValidateRequest() error {
+	cr.FromCcy = strings.ToUpper(strings.TrimSpace(cr.FromCcy))
+	cr.ToCcy = strings.ToUpper(strings.TrimSpace(cr.ToCcy))
+
+	if cr.FromCcy == "" || cr.ToCcy == "" {
+		return Errorf(ErrCodeValidationFailed, "from and to currencies are mandatory")
+	}
+	if cr.FromCcy == cr.ToCcy {
+		return Errorf(ErrCodeValidationFailed, "from and to currencies must be different")
+	}
+	if !cr.Rate.IsPositive() {
+		return Errorf(ErrCodeValidationFailed, "rate field is mandatory and it must be greater than zero")
+	}
+	// The rate is stored as given rather than rounded, possibly to zero
+	if !cr.Rate.Equal(cr.Rate.Truncate(RateScale)) {
+		return Errorf(ErrCodeValidationFailed, "rate must not have more than %d decimals", RateScale)
+	}
+	return nil
+}
+
+func (qr *FxQuoteRequest) ValidateRequest() error {
+	qr.FromCcy = strings.ToUpper(strings.TrimSpace(qr.FromCcy))
+	qr.ToCcy = strings.ToUpper(strings.TrimSpace(qr.ToCcy))
+
+	if qr.FromCcy == "" || qr.ToCcy == "" {
+		return Errorf(ErrCodeValidationFailed, "from_ccy and to_ccy fields are mandatory")
+	}
+	if qr.FromCcy == qr.ToCcy {
+		return Errorf(ErrCodeValidationFailed, "from_ccy and to_ccy must be different currencies")
+	}
+	if qr.UserID <= 0 {
+		return Errorf(ErrCodeValidationFailed, "user_id is mandatory and must be a positive number")
+	}
+	return nil
+}
+
+func (hr *HoldRequest) ValidateRequest() error {
+	if hr.Amount.LessThanOrEqual(decimal.Zero) {
+		return Errorf(ErrCodeValidationFailed, "amount field is mandatory and it must be greater than zero")
+	}
+	if !IsStorableAmount(hr.Amount) {
+		return Errorf(ErrCodeValidationFailed, "amount must not have more than %d decimals", AmountScale)
+	}
+	if hr.ExpiresInSeconds != nil && *hr.ExpiresInSeconds <= 0 {
+		return Errorf(ErrCodeValidationFailed, "expires_in_seconds must be greater than zero")
+	}
+	return validateDescription(hr.Description)
+}
+
+func (cr *HoldCaptureRequest) ValidateRequest() error {
+	if cr.Amount != nil && cr.Amount.LessThanOrEqual(decimal.Zero) {
+		return Errorf(ErrCodeValidationFailed, "amount must be greater than zero")
+	}
+	if cr.Amount != nil && !IsStorableAmount(*cr.Amount) {
+		return Errorf(ErrCodeValidationFailed, "amount must not have more than %d decimals", AmountScale)
+	}
+	return nil
+}
+
+// ValidateRequest checks the mode and every item as a transfer request. A validation error
+// of an item names its index.
+func (br *BatchTransferRequest) ValidateRequest() error {
+	if br.Mode == "" {
+		br.Mode = BatchModeAllOrNothing
+	}
+	if br.Mode != BatchModeAllOrNothing && br.Mode != BatchModeBestEffort {
+		return Errorf(ErrCodeValidationFailed, "mode must be either %s or %s", BatchModeAllOrNothing, BatchModeBestEffort)
+	}
+
+	if len(br.Items) == 0 {
+		return Errorf(ErrCodeValidationFailed, "items field is mandatory")
+	}
+	if len(br.Items) > MaxBatchTransferItems {
+		return Errorf(ErrCodeValidationFailed, "a batch must not have more than %d items", MaxBatchTransferItems)
+	}
+
+	for i, item := range br.Items {
+		tr := item.TransactionRequest()
+		if err := tr.ValidateRequest(TxnTypeTransferOut); err != nil {
+			return ForBatchItem(err, i)
 		}
 	}
 	return nil
 }
 
+// TransactionRequest returns the item as the request of a single transfer.
+func (bi BatchTransferItemRequest) TransactionRequest() TransactionRequest {
+	return TransactionRequest{
+		Amount:              bi.Amount,
+		DestinationWalletID: bi.DestinationWalletID,
+		DestinationUserID:   bi.DestinationUserID,
+		Description:         bi.Description,
+		ExternalReference:   bi.ExternalReference,
+	}
+}
+
+func (sr *ScheduledTransferRequest) ValidateRequest() error {
+	if sr.Amount.LessThanOrEqual(decimal.Zero) {
+		return Errorf(ErrCodeValidationFailed, "amount field is mandatory and it must be greater than zero")
+	}
+	if !IsStorableAmount(sr.Amount) {
+		return Errorf(ErrCodeValidationFailed, "amount must not have more than %d decimals", AmountScale)
+	}
+	if sr.DestinationUserID != nil && sr.DestinationWalletID != nil {
+		return Errorf(ErrCodeValidationFailed, "please specify only one of destination_user_id or destination_wallet_id, not both")
+	}
+	if sr.DestinationUserID == nil && sr.DestinationWalletID == nil {
+		return Errorf(ErrCodeValidationFailed, "please specify either destination_user_id or destination_wallet_id")
+	}
+
+	switch sr.Frequency {
+	case ScheduleFrequencyOnce:
+		if sr.EndAt != nil || sr.MaxOccurrences != nil {
+			return Errorf(ErrCodeValidationFailed, "end_at and max_occurrences only apply to recurring transfers")
+		}
+	case ScheduleFrequencyDaily, ScheduleFrequencyWeekly, ScheduleFrequencyMonthly:
+	case "":
+		return Errorf(ErrCodeValidationFailed, "frequency field is mandatory")
+	default:
+		return Errorf(ErrCodeValidationFailed, "frequency must be one of %s, %s, %s or %s",
+			ScheduleFrequencyOnce, ScheduleFrequencyDaily, ScheduleFrequencyWeekly, ScheduleFrequencyMonthly)
+	}
+
+	if sr.StartAt != nil && sr.StartAt.Before(time.Now()) {
+		return Errorf(ErrCodeValidationFailed, "start_at must not be in the past")
+	}
+	if sr.EndAt != nil && sr.StartAt != nil && sr.EndAt.Before(*sr.StartAt) {
+		return Errorf(ErrCodeValidationFailed, "end_at must not be before start_at")
+	}
+	if sr.MaxOccurrences != nil && *sr.MaxOccurrences <= 0 {
+		return Errorf(ErrCodeValidationFailed, "max_occurrences must be greater than zero")
+	}
+
+	if err := validateInsufficientFundsPolicy(sr.OnInsufficientFunds); err != nil {
+		return err
+	}
+
+	if err := validateDescription(sr.Description); err != nil {
+		return err
+	}
+
+	if len(sr.ExternalReference) > MaxExternalReferenceLength {
+		return Errorf(ErrCodeValidationFailed, "external_reference must not be longer than %d characters", MaxExternalReferenceLength)
+	}
+	return nil
+}
+
+func (ur *ScheduledTransferUpdateRequest) ValidateRequest() error {
+	if ur.Amount == nil && ur.Description == nil && ur.EndAt == nil && ur.MaxOccurrences == nil &&
+		ur.OnInsufficientFunds == nil && ur.Status == nil {
+		return Errorf(ErrCodeValidationFailed, "please specify at least one field to update")
+	}
+	if ur.Amount != nil && ur.Amount.LessThanOrEqual(decimal.Zero) {
+		return Errorf(ErrCodeValidationFailed, "amount must be greater than zero")
+	}
+	if ur.Amount != nil && !IsStorableAmount(*ur.Amount) {
+		return Errorf(ErrCodeValidationFailed, "amount must not have more than %d decimals", AmountScale)
+	}
+	if ur.MaxOccurrences != nil && *ur.MaxOccurrences <= 0 {
+		return Errorf(ErrCodeValidationFailed, "max_occurrences must be greater than zero")
+	}
+	if ur.OnInsufficientFunds != nil && *ur.OnInsufficientFunds != InsufficientFundsRetry && *ur.OnInsufficientFunds != InsufficientFundsSkip {
+		return Errorf(ErrCodeValidationFailed, "on_insufficient_funds must be either %s or %s", InsufficientFundsRetry, InsufficientFundsSkip)
+	}
+	if ur.Status != nil && *ur.Status != ScheduleStatusActive && *ur.Status != ScheduleStatusPaused {
+		return Errorf(ErrCodeValidationFailed, "status must be either %s or %s", ScheduleStatusActive, ScheduleStatusPaused)
+	}
+	if ur.Description != nil {
+		return validateDescription(*ur.Description)
+	}
+	return nil
+}
+
+func validateInsufficientFundsPolicy(policy string) error {
+	if policy != "" && policy != InsufficientFundsRetry && policy != InsufficientFundsSkip {
+		return Errorf(ErrCodeValidationFailed, "on_insufficient_funds must be either %s or %s", InsufficientFundsRetry, InsufficientFundsSkip)
+	}
+	return nil
+}
+
+func validateDescription(description string) error {
+	if utf8.RuneCountInString(description) > MaxDescriptionLength {
+		return Errorf(ErrCodeValidationFailed, "description must not be longer than %d characters", MaxDescriptionLength)
+	}
+	return nil
+}
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+var ccyPattern = regexp.MustCompile(`^[A-Z0-9]{3,10}$`)
+
+// NormalizeCcy upper-cases ccy and validates its format, 3 to 10 letters or digits.
+func NormalizeCcy(ccy string) (string, error) {
+	ccy = strings.ToUpper(strings.TrimSpace(ccy))
+	if !ccyPattern.MatchString(ccy) {
+		return "", Errorf(ErrCodeValidationFailed, "invalid currency %q: currencies are 3 to 10 letters or digits", ccy)
+	}
+	return ccy, nil
+}
+
+// normalizeTags lower-cases and de-duplicates tags, and validates their format.
+func normalizeTags(tags []string) ([]string, error) {
+	var normalized []string
+	seen := make(map[string]bool)
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if len(tag) > MaxTagLength || !tagPattern.MatchString(tag) {
+			return nil, Errorf(ErrCodeValidationFailed, "invalid tag %q: tags must be up to %d letters, digits, '-' or '_'", tag, MaxTagLength)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+
+	if len(normalized) > MaxTagsPerTransaction {
+		return nil, Errorf(ErrCodeValidationFailed, "a transaction can have at most %d tags", MaxTagsPerTransaction)
+	}
+	return normalized, nil
+}
+
 // Custom type that wraps decimal.Decimal
 type MoneyDecimal struct {
 	decimal.Decimal
@@ -71,5 +988,5 @@ type MoneyDecimal struct {
 // MarshalJSON limits to 2 decimal places when marshaling to JSON
 func (d MoneyDecimal) MarshalJSON() ([]byte, error) {
 	// Format to 2 decimal places and quote it as a string
-	return []byte(fmt.Sprintf("\"%s\"", d.Decimal.StringFixed(2))), nil
+	return []byte(fmt.Sprintf("\"%s\"", d.Decimal.StringFixed(AmountScale))), nil
 }
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ScheduledTransfer is a transfer executed by the scheduler, once at StartAt or
+// repeatedly at the given frequency until EndAt or MaxOccurrences is reached.
+// Occurrences counts the occurrences already handled, whether the transfer succeeded or was skipped.
+// NextRunAt is the next occurrence; RetryAt is set while an occurrence waits for a retry.
+type ScheduledTransfer struct {
+	ID                  int64           `json:"id"`
+	SourceWalletId      int64           `json:"source_wallet_id"`
+	DestinationWalletId sql.NullInt64   `json:"destination_wallet_id"`
+	DestinationUserId   sql.NullInt64   `json:"destination_user_id"`
+	Amount              decimal.Decimal `json:"amount"`
+	Description         sql.NullString  `json:"description"`
+	ExternalReference   sql.NullString  `json:"external_reference"`
+	Frequency           string          `json:"frequency"`
+	StartAt             time.Time       `json:"start_at"`
+	EndAt               sql.NullTime    `json:"end_at"`
+	MaxOccurrences      sql.NullInt64   `json:"max_occurrences"`
+	Occurrences         int64           `json:"occurrences"`
+	NextRunAt           sql.NullTime    `json:"next_run_at"`
+	RetryAt             sql.NullTime    `json:"retry_at"`
+	Attempts            int64           `json:"attempts"`
+	OnInsufficientFunds string          `json:"on_insufficient_funds"`
+	Status              string          `json:"status"`
+	CreatedAt           time.Time       `json:"created_at"`
+	UpdatedAt           time.Time       `json:"updated_at"`
+}
+
+// ScheduledTransferRun is the outcome of one attempt to execute an occurrence of a scheduled transfer.
+type ScheduledTransferRun struct {
+	ID                  int64          `json:"id"`
+	ScheduledTransferId int64          `json:"scheduled_transfer_id"`
+	ScheduledFor        time.Time      `json:"scheduled_for"`
+	Attempt             int64          `json:"attempt"`
+	Status              string         `json:"status"`
+	TransferId          sql.NullInt64  `json:"transfer_id"`
+	ErrorCode           sql.NullString `json:"error_code"`
+	ErrorMessage        sql.NullString `json:"error_message"`
+	CreatedAt           time.Time      `json:"created_at"`
+}
+
+// OccurrenceAt returns the time of the n-th occurrence, counting from 0 at StartAt.
+// Monthly occurrences keep the day of month of StartAt, or the last day of shorter months.
+func (s ScheduledTransfer) OccurrenceAt(n int64) time.Time {
+	switch s.Frequency {
+	case ScheduleFrequencyDaily:
+		return s.StartAt.AddDate(0, 0, int(n))
+	case ScheduleFrequencyWeekly:
+		return s.StartAt.AddDate(0, 0, 7*int(n))
+	case ScheduleFrequencyMonthly:
+		first := time.Date(s.StartAt.Year(), s.StartAt.Month()+time.Month(n), 1,
+			s.StartAt.Hour(), s.StartAt.Minute(), s.StartAt.Second(), s.StartAt.Nanosecond(), s.StartAt.Location())
+		lastDay := first.AddDate(0, 1, -1).Day()
+		day := s.StartAt.Day()
+		if day > lastDay {
+			day = lastDay
+		}
+		return first.AddDate(0, 0, day-1)
+	}
+	return s.StartAt
+}
+
+// Advance moves the schedule past its current occurrence, executed or skipped.
+func (s *ScheduledTransfer) Advance() {
+	s.Occurrences++
+	s.Attempts = 0
+	s.RetryAt = sql.NullTime{}
+	s.Reschedule()
+}
+
+// Reschedule sets NextRunAt to the occurrence following the ones already handled, or completes
+// the schedule once its frequency or end conditions allow no more occurrences.
+func (s *ScheduledTransfer) Reschedule() {
+	if s.Status == ScheduleStatusCompleted || s.Status == ScheduleStatusCancelled {
+		s.NextRunAt = sql.NullTime{}
+		s.RetryAt = sql.NullTime{}
+		return
+	}
+
+	next := s.OccurrenceAt(s.Occurrences)
+	if (s.Frequency == ScheduleFrequencyOnce && s.Occurrences > 0) ||
+		(s.MaxOccurrences.Valid && s.Occurrences >= s.MaxOccurrences.Int64) ||
+		(s.EndAt.Valid && next.After(s.EndAt.Time)) {
+		s.NextRunAt = sql.NullTime{}
+		s.RetryAt = sql.NullTime{}
+		s.Status = ScheduleStatusCompleted
+		return
+	}
+	s.NextRunAt = sql.NullTime{Time: next, Valid: true}
+}
+
+// SkipMissed skips the recurring occurrences before now, e.g. the ones missed while the schedule was paused.
+// A one-off transfer is kept and runs as soon as the schedule is active.
+func (s *ScheduledTransfer) SkipMissed(now time.Time) {
+	if s.Frequency == ScheduleFrequencyOnce {
+		return
+	}
+	for s.Status == ScheduleStatusActive && s.NextRunAt.Valid && s.NextRunAt.Time.Before(now) {
+		s.Advance()
+	}
+}
+
+// SkipMissedDue skips the recurring occurrences due at now but the latest one, e.g. the ones missed while
+// the scheduler was down, so that a single missed occurrence is caught up rather than all of them in a row.
+// An occurrence is only skipped when the one after it is due too. It returns the number of occurrences skipped.
+func (s *ScheduledTransfer) SkipMissedDue(now time.Time) int {
+	if s.Frequency == ScheduleFrequencyOnce {
+		return 0
+	}
+	skipped := 0
+	for s.Status == ScheduleStatusActive && s.NextRunAt.Valid {
+		next := *s
+		next.Advance()
+		if next.Status != ScheduleStatusActive || !next.NextRunAt.Valid || next.NextRunAt.Time.After(now) {
+			break
+		}
+		*s = next
+		skipped++
+	}
+	return skipped
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// StatementPeriodLayout is the layout of the month of a statement, e.g. 2025-05.
+const StatementPeriodLayout = "2006-01"
+
+// StatementFX is the other side of an exchange between currencies: CounterAmount in CounterCcy
+// was exchanged at Rate, target per unit of source, for the amount of the entry.
+type StatementFX struct {
+	Rate          decimal.Decimal `json:"rate"`
+	CounterCcy    string          `json:"counter_currency"`
+	CounterAmount decimal.Decimal `json:"counter_amount"`
+}
+
+// StatementEntry is a change of the wallet balance listed on a statement: a transaction completing,
+// or a completed one being reversed when Reversal is set. Amount is signed, a debit being negative,
+// and Balance is the balance right after it. FeeFor is the transaction a fee was charged for.
+type StatementEntry struct {
+	TransactionID        int64           `json:"transaction_id"`
+	Type                 string          `json:"type"`
+	At                   time.Time       `json:"at"`
+	Amount               decimal.Decimal `json:"amount"`
+	Balance              decimal.Decimal `json:"balance"`
+	Reversal             bool            `json:"reversal,omitempty"`
+	Description          string          `json:"description,omitempty"`
+	ExternalReference    string          `json:"external_reference,omitempty"`
+	CounterpartyWalletID *int64          `json:"counterparty_wallet_id,omitempty"`
+	FeeFor               *int64          `json:"fee_for,omitempty"`
+	FX                   *StatementFX    `json:"fx,omitempty"`
+	// Rate and CounterCcy are read with the entry to work out FX
+	Rate       decimal.NullDecimal `json:"-"`
+	CounterCcy string              `json:"-"`
+}
+
+// Statement is the monthly statement of a wallet for the month starting at Period, in UTC, or the end of
+// day statement for the day starting at Period. Monthly statements are stored as generated, so it is kept as JSON. TotalDebits and TotalFees are positive; fees are
+// part of the debits.
+type Statement struct {
+	WalletID       int64            `json:"wallet_id"`
+	UserID         int64            `json:"user_id"`
+	Currency       string           `json:"currency"`
+	Period         time.Time        `json:"period"`
+	OpeningBalance decimal.Decimal  `json:"opening_balance"`
+	ClosingBalance decimal.Decimal  `json:"closing_balance"`
+	TotalCredits   decimal.Decimal  `json:"total_credits"`
+	TotalDebits    decimal.Decimal  `json:"total_debits"`
+	TotalFees      decimal.Decimal  `json:"total_fees"`
+	Entries        []StatementEntry `json:"entries"`
+	GeneratedAt    time.Time        `json:"generated_at"`
+}
+
+// StartOfMonth returns midnight at the start of the month of t, in the location of t.
+func StartOfMonth(t time.Time) time.Time {
+	y, m, _ := t.Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+}
+
+// StatementAvailableAt returns when the statement for period can be generated: once the month has
+// ended and BalanceSnapshotDelay has passed.
+func StatementAvailableAt(period time.Time) time.Time {
+	return period.AddDate(0, 1, 0).Add(BalanceSnapshotDelay)
+}
+
+// BuildStatement rolls opening, the balance at the start of period, forward through entries, ordered by
+// time, setting the balance after each of them and the FX details of exchanges, and sums up the month.
+func BuildStatement(wallet Wallet, period time.Time, opening decimal.Decimal, entries []StatementEntry, generatedAt time.Time) Statement {
+	s := Statement{
+		WalletID:       wallet.ID,
+		UserID:         wallet.UserId,
+		Currency:       wallet.Currency,
+		Period:         period,
+		OpeningBalance: opening,
+		TotalCredits:   decimal.Zero,
+		TotalDebits:    decimal.Zero,
+		TotalFees:      decimal.Zero,
+		Entries:        make([]StatementEntry, 0, len(entries)),
+		GeneratedAt:    generatedAt,
+	}
+
+	balance := opening
+	for _, e := range entries {
+		balance = balance.Add(e.Amount)
+		e.Balance = balance
+
+		if e.Amount.IsNegative() {
+			s.TotalDebits = s.TotalDebits.Sub(e.Amount)
+		} else {
+			s.TotalCredits = s.TotalCredits.Add(e.Amount)
+		}
+		if e.Type == TxnTypeFee {
+			s.TotalFees = s.TotalFees.Sub(e.Amount)
+		}
+
+		if e.Rate.Valid && e.CounterCcy != "" && e.CounterCcy != wallet.Currency && e.Rate.Decimal.IsPositive() {
+			fx := StatementFX{Rate: e.Rate.Decimal, CounterCcy: e.CounterCcy}
+			if e.Amount.IsNegative() == e.Reversal {
+				// a credit, or the reversal of one, is the target amount of the exchange
+				fx.CounterAmount = e.Amount.Abs().Div(e.Rate.Decimal).Round(AmountScale)
+			} else {
+				fx.CounterAmount = e.Amount.Abs().Mul(e.Rate.Decimal).Round(AmountScale)
+			}
+			e.FX = &fx
+		}
+		s.Entries = append(s.Entries, e)
+	}
+	s.ClosingBalance = balance
+	return s
+}
@@ -14,4 +14,89 @@ type Transaction struct {
 	Amount               decimal.Decimal `json:"amount"`
 	CounterpartyWalletId sql.NullInt64   `json:"counterparty_wallet_id"`
 	CreatedAt            time.Time       `json:"created_at"`
+	Description          sql.NullString  `json:"description"`
+	ExternalReference    sql.NullString  `json:"external_reference"`
+	Tags                 []string        `json:"tags"`
+	TransferId           sql.NullInt64   `json:"transfer_id"`
+	Status               string          `json:"status"`
+	CompletedAt          sql.NullTime    `json:"completed_at"`
+	FailedAt             sql.NullTime    `json:"failed_at"`
+	ReversedAt           sql.NullTime    `json:"reversed_at"`
+	// Rate is the rate given to the customer, set on both legs of an exchange between currencies.
+	Rate decimal.NullDecimal `json:"rate"`
+	// BalanceAfter is the wallet balance right after this transaction was applied.
+	// It is only populated on transactions created in the current request.
+	BalanceAfter decimal.Decimal `json:"balance_after"`
+	// Fee is the fee charged for this transaction, set before it is applied.
+	// It is only populated on transactions created or updated in the current request.
+	Fee *Fee `json:"-"`
+	// SpreadRevenue is what the FX spread took on the exchange this transaction pays for, set before it is applied.
+	// It is only populated on transactions created in the current request.
+	SpreadRevenue *SpreadRevenue `json:"-"`
+}
+
+// IsCreditTxnType tells whether a transaction of type txnType adds its amount to the wallet balance rather than taking it.
+func IsCreditTxnType(txnType string) bool {
+	switch txnType {
+	case TxnTypeDeposit, TxnTypeTransferIn, TxnTypeConversionIn, TxnTypeFeeIncome:
+		return true
+	}
+	return false
+}
+
+// SignedAmount returns the amount of the transaction as it changes the wallet balance, negative for a debit.
+func (t Transaction) SignedAmount() decimal.Decimal {
+	if IsCreditTxnType(t.Type) {
+		return t.Amount
+	}
+	return t.Amount.Neg()
+}
+
+// txnStatusTransitions lists, per status, the statuses a transaction can move to.
+// Failed and reversed transactions are final.
+var txnStatusTransitions = map[string][]string{
+	TxnStatusPending:   {TxnStatusCompleted, TxnStatusFailed},
+	TxnStatusCompleted: {TxnStatusReversed},
+}
+
+// CanTransitionTxnStatus reports whether a transaction in status from can move to status to.
+func CanTransitionTxnStatus(from, to string) bool {
+	for _, next := range txnStatusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransferCounterparty describes the other side of a transfer transaction:
+// the counterparty wallet, its owner and the opposite leg of the transfer.
+type TransferCounterparty struct {
+	TransactionID       int64
+	LinkedTransactionID sql.NullInt64
+	LinkedAmount        decimal.NullDecimal
+	WalletID            int64
+	Currency            string
+	UserID              int64
+	UserName            string
+	TransferStatus      sql.NullString
+	ReversalOf          sql.NullInt64
+}
+
+// TransactionFilter narrows down a transaction history query.
+// Query is matched against the description and the external reference,
+// and every tag in Tags must be present on the transaction.
+// Status, when set, only keeps transactions in that status, and From and Until, when set,
+// the transactions created from From up to, and excluding, Until.
+type TransactionFilter struct {
+	Query  string
+	Tags   []string
+	Status string
+	From   time.Time
+	Until  time.Time
+}
+
+// NullString converts an optional string into a sql.NullString, treating "" as NULL.
+func NullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
 }
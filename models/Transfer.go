@@ -0,0 +1,42 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Transfer links the transfer-out and transfer-in transactions of a single transfer.
+// Rate is the target amount per unit of the source amount.
+// A reversal is a transfer in the opposite direction with ReversalOf set to the original transfer,
+// whose ReversedAmount accumulates the source amount refunded so far.
+type Transfer struct {
+	ID             int64           `json:"id"`
+	SourceWalletId int64           `json:"source_wallet_id"`
+	TargetWalletId int64           `json:"target_wallet_id"`
+	SourceAmount   decimal.Decimal `json:"source_amount"`
+	TargetAmount   decimal.Decimal `json:"target_amount"`
+	Rate           decimal.Decimal `json:"rate"`
+	Status         string          `json:"status"`
+	CreatedAt      time.Time       `json:"created_at"`
+	ReversalOf     sql.NullInt64   `json:"reversal_of"`
+	ReversedAmount decimal.Decimal `json:"reversed_amount"`
+	// QuoteId is set when the transfer executes at the rate of an FX quote, which is used by the transfer.
+	// It is stored on the quote rather than on the transfer.
+	QuoteId sql.NullInt64 `json:"quote_id"`
+	// RatePath is the path Rate was resolved through between currencies, e.g., EUR>USD>BTC.
+	// It is empty when no rate was resolved: between wallets in the same currency, and for a reversal at the original rate.
+	RatePath string `json:"rate_path"`
+	// The fields below are read from the wallets and transactions tables
+	// and are not stored on the transfer itself.
+	SourceCurrency      string        `json:"source_currency"`
+	TargetCurrency      string        `json:"target_currency"`
+	SourceTransactionId sql.NullInt64 `json:"source_transaction_id"`
+	TargetTransactionId sql.NullInt64 `json:"target_transaction_id"`
+}
+
+// ReversibleAmount returns the part of the source amount that has not been reversed yet.
+func (t *Transfer) ReversibleAmount() decimal.Decimal {
+	return t.SourceAmount.Sub(t.ReversedAmount)
+}
@@ -4,8 +4,10 @@ import (
 	"time"
 )
 
+// User owns wallets. ReportingCurrency is the currency the total of the user's wallets is shown in.
 type User struct {
-	ID        int64     `json:"id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
+	ID                int64     `json:"id"`
+	Name              string    `json:"name"`
+	ReportingCurrency string    `json:"reporting_currency"`
+	CreatedAt         time.Time `json:"created_at"`
 }
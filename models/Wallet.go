@@ -14,4 +14,24 @@ type Wallet struct {
 	Currency  string          `json:"currency"`
 	Balance   decimal.Decimal `json:"balance"`
 	CreatedAt time.Time       `json:"created_at"`
+	// AvailableBalance is the balance minus the active holds and the pending withdrawals.
+	// It is only populated where the available balance is shown.
+	AvailableBalance decimal.NullDecimal `json:"available_balance"`
+	// PendingIncoming and PendingOutgoing are the totals of the pending deposits and withdrawals.
+	// They are only populated where the available balance is shown.
+	PendingIncoming decimal.NullDecimal `json:"pending_incoming"`
+	PendingOutgoing decimal.NullDecimal `json:"pending_outgoing"`
+}
+
+// PendingBalance is the total of the pending deposits and withdrawals of a wallet.
+type PendingBalance struct {
+	Incoming decimal.Decimal
+	Outgoing decimal.Decimal
+}
+
+// WalletBalance is the ledger balance of a wallet together with its available balance,
+// which excludes the funds reserved by active holds and pending withdrawals.
+type WalletBalance struct {
+	Balance          decimal.Decimal
+	AvailableBalance decimal.Decimal
 }
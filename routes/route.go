@@ -10,9 +10,44 @@ import (
 func Route(database *sql.DB, r *mux.Router) {
 	dbHandler := handler.HandlerDB{DB: database}
 
+	r.Use(handler.RequestIDMiddleware)
+
+	r.HandleFunc("/users/{id}", dbHandler.HandleUpdateUser).Methods("PATCH")
 	r.HandleFunc("/users/{id}/wallets/balance", dbHandler.HandleBalance).Methods("GET")
 	r.HandleFunc("/users/{id}/wallets/transactions", dbHandler.HandleTxHistory).Methods("GET")
+	r.HandleFunc("/users/{id}/portfolio/history", dbHandler.HandlePortfolioHistory).Methods("GET")
+	r.HandleFunc("/users/{id}/gains", dbHandler.HandleGainsReport).Methods("GET")
+	r.HandleFunc("/users/{id}/transfers", dbHandler.HandleUserTransfers).Methods("GET")
+	r.HandleFunc("/users/{id}/scheduled-transfers", dbHandler.HandleUserScheduledTransfers).Methods("GET")
+	r.HandleFunc("/users/{id}/conversions", dbHandler.HandleCreateConversion).Methods("POST")
+	r.HandleFunc("/wallets/{id}/balance", dbHandler.HandleWalletBalance).Methods("GET")
+	r.HandleFunc("/wallets/{id}/balance/history", dbHandler.HandleWalletBalanceHistory).Methods("GET")
+	r.HandleFunc("/wallets/{id}/cost-basis", dbHandler.HandleWalletCostBasis).Methods("GET")
+	r.HandleFunc("/wallets/{id}/statements/{period}", dbHandler.HandleWalletStatement).Methods("GET")
+	r.HandleFunc("/wallets/{id}/statements/daily/{date}", dbHandler.HandleDailyStatement).Methods("GET")
 	r.HandleFunc("/wallets/{id}/deposit", dbHandler.HandleDepositMoney).Methods("POST")
 	r.HandleFunc("/wallets/{id}/withdraw", dbHandler.HandleWithdrawMoney).Methods("POST")
 	r.HandleFunc("/wallets/{id}/transfer", dbHandler.HandleTransferMoney).Methods("POST")
+	r.HandleFunc("/wallets/{id}/transfer/preview", dbHandler.HandlePreviewTransfer).Methods("POST")
+	r.HandleFunc("/wallets/{id}/batch-transfers", dbHandler.HandleBatchTransfer).Methods("POST")
+	r.HandleFunc("/wallets/{id}/batch-transfers/pain001", dbHandler.HandlePain001Upload).Methods("POST")
+	r.HandleFunc("/wallets/{id}/holds", dbHandler.HandleCreateHold).Methods("POST")
+	r.HandleFunc("/wallets/{id}/scheduled-transfers", dbHandler.HandleCreateScheduledTransfer).Methods("POST")
+	r.HandleFunc("/transactions/{id}", dbHandler.HandleGetTransaction).Methods("GET")
+	r.HandleFunc("/transactions/{id}", dbHandler.HandleUpdateTransactionNotes).Methods("PATCH")
+	r.HandleFunc("/transactions/{id}/status", dbHandler.HandleUpdateTransactionStatus).Methods("POST")
+	r.HandleFunc("/transfers/{id}", dbHandler.HandleGetTransfer).Methods("GET")
+	r.HandleFunc("/transfers/{id}/reverse", dbHandler.HandleReverseTransfer).Methods("POST")
+	r.HandleFunc("/holds/{id}", dbHandler.HandleGetHold).Methods("GET")
+	r.HandleFunc("/holds/{id}/capture", dbHandler.HandleCaptureHold).Methods("POST")
+	r.HandleFunc("/holds/{id}/release", dbHandler.HandleReleaseHold).Methods("POST")
+	r.HandleFunc("/scheduled-transfers/{id}", dbHandler.HandleGetScheduledTransfer).Methods("GET")
+	r.HandleFunc("/scheduled-transfers/{id}", dbHandler.HandleUpdateScheduledTransfer).Methods("PATCH")
+	r.HandleFunc("/scheduled-transfers/{id}", dbHandler.HandleCancelScheduledTransfer).Methods("DELETE")
+	r.HandleFunc("/scheduled-transfers/{id}/runs", dbHandler.HandleScheduledTransferRuns).Methods("GET")
+	r.HandleFunc("/fx/quotes", dbHandler.HandleCreateFxQuote).Methods("POST")
+	r.HandleFunc("/fx/quotes/{id}", dbHandler.HandleGetFxQuote).Methods("GET")
+	r.HandleFunc("/fx/spread-revenue", dbHandler.HandleSpreadRevenue).Methods("GET")
+	r.HandleFunc("/fx/rates", dbHandler.HandleListCcyRates).Methods("GET")
+	r.HandleFunc("/fx/rates/{from}/{to}", dbHandler.HandleUpsertCcyRate).Methods("PUT")
 }
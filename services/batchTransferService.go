@@ -0,0 +1,212 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
+)
+
+// BatchTransferPlan is a batch of transfers ready to be applied with db.BatchTransferUpdate.
+type BatchTransferPlan struct {
+	SourceWallet models.Wallet
+	Items        []models.BatchTransferItem
+}
+
+// PrepareBatchTransfer builds the transfers of a batch paid from the source wallet.
+// All destination wallets and all destination users are resolved with one query each, and each
+// exchange is priced and the fee rules of each fee type are looked up once. An item whose destination or rate cannot be resolved gets
+// its Err set, naming the item; other items are not affected.
+// msg is expected to be validated already.
+func PrepareBatchTransfer(database *sql.DB, walletId int64, msg models.BatchTransferRequest) (*BatchTransferPlan, error) {
+	sourceWallet, err := db.GetWalletById(database, walletId)
+	if err != nil {
+		return nil, err
+	}
+
+	if sourceWallet == nil {
+		return nil, models.Errorf(models.ErrCodeWalletNotFound, "source wallet %d not found", walletId)
+	}
+
+	var walletIds, userIds []int64
+	for _, item := range msg.Items {
+		if item.DestinationWalletID != nil {
+			walletIds = append(walletIds, *item.DestinationWalletID)
+		}
+		if item.DestinationUserID != nil {
+			userIds = append(userIds, *item.DestinationUserID)
+		}
+	}
+
+	wallets, err := db.GetWalletsByIDs(database, uniqueIDs(walletIds))
+	if err != nil {
+		return nil, err
+	}
+
+	walletsById := make(map[int64]models.Wallet)
+	for _, w := range wallets {
+		walletsById[w.ID] = w
+	}
+
+	var userWallets []models.Wallet
+	if len(userIds) > 0 {
+		userWallets, err = db.GetWalletByUserIDs(database, uniqueIDs(userIds))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	walletsByUser := make(map[int64][]models.Wallet)
+	for _, w := range userWallets {
+		walletsByUser[w.UserId] = append(walletsByUser[w.UserId], w)
+	}
+
+	prices := newPriceCache(database, sourceWallet.UserId, sourceWallet.Currency)
+	fees := newFeeSchedule(database)
+
+	plan := &BatchTransferPlan{SourceWallet: *sourceWallet}
+	for i, req := range msg.Items {
+		item, err := prepareBatchItem(*sourceWallet, req, walletsById, walletsByUser, prices, fees)
+		if err != nil {
+			var appErr *models.AppError
+			if !errors.As(err, &appErr) {
+				return nil, err
+			}
+			item.Err = models.ForBatchItem(err, i)
+		}
+		plan.Items = append(plan.Items, item)
+	}
+	return plan, nil
+}
+
+// prepareBatchItem builds a single transfer of a batch and quotes its fee. As for a single transfer, an exchange
+// between currencies is priced for the tier of the sender, and the rate given and the spread revenue are kept.
+// The item is returned with its amounts even when its destination or rate cannot be resolved.
+func prepareBatchItem(sourceWallet models.Wallet, req models.BatchTransferItemRequest, walletsById map[int64]models.Wallet,
+	walletsByUser map[int64][]models.Wallet, prices *priceCache, fees *feeSchedule) (models.BatchTransferItem, error) {
+	item := models.BatchTransferItem{
+		Transfer:    models.Transfer{SourceWalletId: sourceWallet.ID, SourceAmount: req.Amount, SourceCurrency: sourceWallet.Currency},
+		TransferOut: models.Transaction{WalletId: sourceWallet.ID, Type: models.TxnTypeTransferOut, Amount: req.Amount},
+	}
+
+	var targetWallet *models.Wallet
+	if req.DestinationUserID != nil {
+		// Prevent transferring to own wallet using user ID (must use wallet ID instead)
+		if sourceWallet.UserId == *req.DestinationUserID {
+			return item, models.Errorf(models.ErrCodeValidationFailed, "please use destination_wallet_id to transfer for the same user")
+		}
+
+		targetWallet = pickTargetWallet(walletsByUser[*req.DestinationUserID], sourceWallet.Currency)
+		if targetWallet == nil {
+			return item, models.Errorf(models.ErrCodeWalletNotFound, "user %d has no wallet to receive the transfer", *req.DestinationUserID)
+		}
+	} else {
+		w, ok := walletsById[*req.DestinationWalletID]
+		if !ok {
+			return item, models.Errorf(models.ErrCodeWalletNotFound, "target wallet %d not found", *req.DestinationWalletID)
+		}
+		targetWallet = &w
+	}
+
+	if targetWallet.ID == sourceWallet.ID {
+		return item, models.Errorf(models.ErrCodeValidationFailed, "destination must be a different wallet than the source wallet")
+	}
+
+	price, err := prices.get(targetWallet.Currency)
+	if err != nil {
+		return item, err
+	}
+	rate := price.Rate
+	// Rounded to what the wallet stores, the balance credited must add up to its transactions
+	targetAmount := req.Amount.Mul(rate).Round(models.AmountScale)
+	if !targetAmount.IsPositive() {
+		return item, models.Errorf(models.ErrCodeValidationFailed, "amount is too small to transfer").
+			WithDetails(map[string]string{"target_amount": targetAmount.String(), "rate": rate.String()})
+	}
+
+	var txnRate decimal.NullDecimal
+	if sourceWallet.Currency != targetWallet.Currency {
+		txnRate = decimal.NullDecimal{Decimal: rate, Valid: true}
+		item.TransferOut.SpreadRevenue = price.Revenue(targetWallet.Currency, req.Amount, targetAmount)
+	}
+
+	fee, err := fees.quote(TransferFeeType(sourceWallet, *targetWallet), sourceWallet, targetWallet.Currency, req.Amount)
+	if err != nil {
+		return item, err
+	}
+
+	item.Transfer.TargetWalletId = targetWallet.ID
+	item.Transfer.TargetAmount = targetAmount
+	item.Transfer.TargetCurrency = targetWallet.Currency
+	item.Transfer.Rate = rate
+	item.Transfer.Status = models.TransferStatusCompleted
+	item.Transfer.RatePath = price.RatePath
+
+	item.TransferOut.CounterpartyWalletId = sql.NullInt64{Int64: targetWallet.ID, Valid: true}
+	item.TransferOut.Description = models.NullString(req.Description)
+	item.TransferOut.ExternalReference = models.NullString(req.ExternalReference)
+	item.TransferOut.Fee = fee
+	item.TransferOut.Rate = txnRate
+
+	item.TransferIn = models.Transaction{
+		WalletId:             targetWallet.ID,
+		Type:                 models.TxnTypeTransferIn,
+		Amount:               targetAmount,
+		CounterpartyWalletId: sql.NullInt64{Int64: sourceWallet.ID, Valid: true},
+		Description:          models.NullString(req.Description),
+		ExternalReference:    models.NullString(req.ExternalReference),
+		Rate:                 txnRate,
+	}
+	return item, nil
+}
+
+// priceCache prices the exchange from one currency to each target currency once, for the tier of the user paying.
+type priceCache struct {
+	database *sql.DB
+	userId   int64
+	from     string
+	prices   map[string]models.FxPrice
+	errs     map[string]error
+}
+
+func newPriceCache(database *sql.DB, userId int64, from string) *priceCache {
+	return &priceCache{
+		database: database,
+		userId:   userId,
+		from:     from,
+		prices:   map[string]models.FxPrice{from: models.MidPrice(decimal.NewFromInt(1))},
+		errs:     make(map[string]error),
+	}
+}
+
+func (c *priceCache) get(to string) (models.FxPrice, error) {
+	if price, ok := c.prices[to]; ok {
+		return price, nil
+	}
+	if err, ok := c.errs[to]; ok {
+		return models.FxPrice{}, err
+	}
+
+	price, err := QuoteFxPrice(c.database, c.userId, c.from, to)
+	if err != nil {
+		c.errs[to] = err
+		return models.FxPrice{}, err
+	}
+	c.prices[to] = price
+	return price, nil
+}
+
+// uniqueIDs returns ids without duplicates, in their original order.
+func uniqueIDs(ids []int64) []int64 {
+	seen := make(map[int64]bool)
+	var unique []int64
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
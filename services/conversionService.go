@@ -0,0 +1,118 @@
+package services
+
+import (
+	"database/sql"
+
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
+)
+
+// ConversionPlan is a conversion ready to be applied with db.ConversionUpdate:
+// the wallets on both sides, the conversion record and its two legs, the fee set on the conversion-out leg.
+type ConversionPlan struct {
+	FromWallet    models.Wallet
+	ToWallet      models.Wallet
+	Conversion    models.Conversion
+	ConversionOut models.Transaction
+	ConversionIn  models.Transaction
+}
+
+// PrepareConversion builds the conversion described by msg between two wallets of the user.
+// It prices the exchange for the tier of the user, derives the amount on the side not given in msg,
+// quotes the conversion fee paid by the from wallet and fails fast when even the ledger balance of
+// the from wallet cannot cover the amount.
+// msg is expected to be validated already.
+func PrepareConversion(database *sql.DB, userId int64, msg models.ConversionRequest) (*ConversionPlan, error) {
+	wallets, err := db.GetWalletsByIDs(database, []int64{msg.FromWalletID, msg.ToWalletID})
+	if err != nil {
+		return nil, err
+	}
+
+	fromWallet := findUserWallet(wallets, msg.FromWalletID, userId)
+	if fromWallet == nil {
+		return nil, models.Errorf(models.ErrCodeWalletNotFound, "wallet %d not found for user %d", msg.FromWalletID, userId)
+	}
+
+	toWallet := findUserWallet(wallets, msg.ToWalletID, userId)
+	if toWallet == nil {
+		return nil, models.Errorf(models.ErrCodeWalletNotFound, "wallet %d not found for user %d", msg.ToWalletID, userId)
+	}
+
+	if fromWallet.Currency == toWallet.Currency {
+		return nil, models.Errorf(models.ErrCodeValidationFailed, "both wallets are in %s, please use a transfer instead", fromWallet.Currency)
+	}
+
+	price, err := QuoteFxPrice(database, userId, fromWallet.Currency, toWallet.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	conversion := models.Conversion{
+		UserId:       userId,
+		FromWalletId: fromWallet.ID,
+		ToWalletId:   toWallet.ID,
+		FromCurrency: fromWallet.Currency,
+		ToCurrency:   toWallet.Currency,
+	}
+	conversion.ApplyPrice(price)
+
+	if msg.FromAmount != nil {
+		conversion.Sell(*msg.FromAmount)
+	} else {
+		conversion.Buy(*msg.ToAmount)
+	}
+
+	// Both amounts are rounded to what the wallets store, neither side may end up empty
+	if !conversion.FromAmount.IsPositive() || !conversion.ToAmount.IsPositive() {
+		return nil, models.Errorf(models.ErrCodeValidationFailed, "amount is too small to convert").
+			WithDetails(map[string]string{"from_amount": conversion.FromAmount.String(), "to_amount": conversion.ToAmount.String(),
+				"rate": conversion.Rate.String()})
+	}
+
+	// Fail fast when even the ledger balance is not sufficient for the amount,
+	// the available balance and the fee are checked when the conversion is applied
+	if fromWallet.Balance.LessThan(conversion.FromAmount) {
+		return nil, models.Errorf(models.ErrCodeInsufficientFunds, "wallet %d does not have enough balance", fromWallet.ID).
+			WithDetails(map[string]string{"balance": fromWallet.Balance.String(), "requested": conversion.FromAmount.String()})
+	}
+
+	fee, err := QuoteFee(database, models.FeeTypeConversion, *fromWallet, toWallet.Currency, conversion.FromAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConversionPlan{
+		FromWallet: *fromWallet,
+		ToWallet:   *toWallet,
+		Conversion: conversion,
+		ConversionOut: models.Transaction{
+			WalletId:             fromWallet.ID,
+			Type:                 models.TxnTypeConversionOut,
+			Amount:               conversion.FromAmount,
+			CounterpartyWalletId: sql.NullInt64{Int64: toWallet.ID, Valid: true},
+			Description:          models.NullString(msg.Description),
+			Rate:                 decimal.NullDecimal{Decimal: conversion.Rate, Valid: true},
+			Fee:                  fee,
+			SpreadRevenue:        price.Revenue(toWallet.Currency, conversion.FromAmount, conversion.ToAmount),
+		},
+		ConversionIn: models.Transaction{
+			WalletId:             toWallet.ID,
+			Type:                 models.TxnTypeConversionIn,
+			Amount:               conversion.ToAmount,
+			CounterpartyWalletId: sql.NullInt64{Int64: fromWallet.ID, Valid: true},
+			Description:          models.NullString(msg.Description),
+			Rate:                 decimal.NullDecimal{Decimal: conversion.Rate, Valid: true},
+		},
+	}, nil
+}
+
+// findUserWallet returns the wallet with walletId among wallets when it belongs to the user, nil otherwise.
+func findUserWallet(wallets []models.Wallet, walletId int64, userId int64) *models.Wallet {
+	for i, w := range wallets {
+		if w.ID == walletId && w.UserId == userId {
+			return &wallets[i]
+		}
+	}
+	return nil
+}
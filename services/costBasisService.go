@@ -0,0 +1,54 @@
+package services
+
+import (
+	"database/sql"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/config"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// CostBasisMethod returns the configured cost-basis method, costbasis.method, FIFO when it is not valid.
+func CostBasisMethod() string {
+	method := strings.ToLower(config.GetOrDefault(config.COST_BASIS_METHOD, models.DefaultCostBasisMethod))
+	if !models.IsCostBasisMethod(method) {
+		log.Printf("ERROR: invalid cost basis method %q, using %s", method, models.DefaultCostBasisMethod)
+		return models.DefaultCostBasisMethod
+	}
+	return method
+}
+
+// IsCryptoCcy tells whether the cost basis of wallets in ccy is tracked, ccy being one of costbasis.crypto_currencies.
+func IsCryptoCcy(ccy string) bool {
+	for _, crypto := range strings.Split(config.GetOrDefault(config.COST_BASIS_CRYPTO_CCYS, models.DefaultCryptoCcys), ",") {
+		if strings.EqualFold(strings.TrimSpace(crypto), ccy) {
+			return true
+		}
+	}
+	return false
+}
+
+// TrackWalletCostBasis replays the completed transactions of the wallet with models.TrackCostBasis.
+// It also returns the rates known at until, to value what is still held.
+func TrackWalletCostBasis(database *sql.DB, wallet models.Wallet, reportingCcy string, method string, until time.Time) (*models.CostBasis, []models.CcyConversion, error) {
+	txns, err := db.GetCostBasisTransactions(database, wallet.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	from := until
+	if len(txns) > 0 && txns[0].At.Before(until) {
+		from = txns[0].At
+	}
+
+	history, err := db.GetCcyRateHistory(database, from, until)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cb := models.TrackCostBasis(wallet, reportingCcy, method, txns, history)
+	return &cb, history.At(until), nil
+}
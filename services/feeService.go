@@ -0,0 +1,110 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/rudithu/CRYPTO-WalletApp/config"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
+)
+
+// QuoteFee returns the fee charged for a transaction of feeType moving amount out of wallet
+// into toCcy, or nil when no rule of the fee schedule applies or the fee is zero.
+func QuoteFee(database *sql.DB, feeType string, wallet models.Wallet, toCcy string, amount decimal.Decimal) (*models.Fee, error) {
+	return newFeeSchedule(database).quote(feeType, wallet, toCcy, amount)
+}
+
+// TransferFeeType returns the fee type of a transfer between two wallets: a conversion between
+// wallets of the same user in different currencies, a transfer otherwise.
+func TransferFeeType(source models.Wallet, target models.Wallet) string {
+	if source.UserId == target.UserId && source.Currency != target.Currency {
+		return models.FeeTypeConversion
+	}
+	return models.FeeTypeTransfer
+}
+
+// feeAmount returns the amount of fee, zero when there is no fee.
+func feeAmount(fee *models.Fee) decimal.Decimal {
+	if fee == nil {
+		return decimal.Zero
+	}
+	return fee.Amount
+}
+
+// feeSchedule quotes fees, reading the rules of each fee type and the house wallets once.
+type feeSchedule struct {
+	database     *sql.DB
+	rules        map[string][]models.FeeRule
+	houseWallets []models.Wallet
+	houseLoaded  bool
+}
+
+func newFeeSchedule(database *sql.DB) *feeSchedule {
+	return &feeSchedule{database: database, rules: make(map[string][]models.FeeRule)}
+}
+
+func (s *feeSchedule) quote(feeType string, wallet models.Wallet, toCcy string, amount decimal.Decimal) (*models.Fee, error) {
+	rules, ok := s.rules[feeType]
+	if !ok {
+		var err error
+		rules, err = db.GetFeeRules(s.database, feeType)
+		if err != nil {
+			return nil, err
+		}
+		s.rules[feeType] = rules
+	}
+
+	rule := models.MatchFeeRule(rules, feeType, wallet.Currency, toCcy, wallet.Type, amount)
+	if rule == nil {
+		return nil, nil
+	}
+
+	breakdown := rule.Calculate(amount)
+	if !breakdown.Amount.IsPositive() {
+		return nil, nil
+	}
+
+	house, err := s.houseWallet(wallet.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	// The house does not pay fees to itself
+	if house.ID == wallet.ID {
+		return nil, nil
+	}
+
+	return &models.Fee{
+		Rule:          *rule,
+		HouseWalletId: house.ID,
+		FeeBreakdown:  breakdown,
+	}, nil
+}
+
+// houseWallet returns the house revenue wallet collecting fees in currency. A fee cannot be charged
+// in a currency the house has no wallet in.
+func (s *feeSchedule) houseWallet(currency string) (*models.Wallet, error) {
+	if !s.houseLoaded {
+		houseUserId, err := strconv.ParseInt(config.GetOrDefault(config.FEE_HOUSE_USER_ID, strconv.Itoa(models.DefaultHouseUserId)), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", config.FEE_HOUSE_USER_ID, err)
+		}
+
+		s.houseWallets, err = db.GetWalletByUserIDs(s.database, []int64{houseUserId})
+		if err != nil {
+			return nil, err
+		}
+		s.houseLoaded = true
+	}
+
+	for i, w := range s.houseWallets {
+		if w.Currency == currency {
+			return &s.houseWallets[i], nil
+		}
+	}
+	return nil, models.Errorf(models.ErrCodeFeeNotConfigured, "no house wallet to collect fees in %s", currency).
+		WithDetails(map[string]string{"currency": currency})
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"database/sql"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/config"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// QuoteFxPrice returns the price given to the user with userId exchanging fromCcy for toCcy: the mid
+// rate between both currencies less the spread set for the user's tier by the FX spread schedule.
+// The price keeps the path the mid rate was resolved through.
+func QuoteFxPrice(database *sql.DB, userId int64, fromCcy string, toCcy string) (models.FxPrice, error) {
+	path, err := ResolveCcyRate(database, fromCcy, toCcy)
+	if err != nil {
+		return models.FxPrice{}, err
+	}
+
+	rules, err := db.GetFxSpreadRules(database, userId)
+	if err != nil {
+		return models.FxPrice{}, err
+	}
+
+	price := models.PriceFx(rules, fromCcy, toCcy, path.Rate)
+	price.RatePath = path.String()
+	return price, nil
+}
+
+// ResolveCcyRate resolves the rate from fromCcy to toCcy over the stored rates for an exchange.
+// Paths through fresh rates are preferred; it fails with RATE_STALE when the currencies are only connected
+// through a rate older than the configured maximum age.
+func ResolveCcyRate(database *sql.DB, fromCcy string, toCcy string) (*models.CcyRatePath, error) {
+	limits := RateAgeLimits()
+	path, err := db.GetCcyRatePath(database, fromCcy, toCcy, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = path.CheckFresh(limits); err != nil {
+		log.Printf("ERROR: rate from %s to %s through %s is stale", fromCcy, toCcy, path)
+		return nil, err
+	}
+	return path, nil
+}
+
+// RateAgeLimits returns the configured maximum ages of stored rates: fx.max_rate_age for any
+// currency, overridden per currency under fx.max_rate_age_by_ccy.
+func RateAgeLimits() models.RateAgeLimits {
+	limits := models.RateAgeLimits{ByCcy: make(map[string]time.Duration)}
+
+	maxAge, err := time.ParseDuration(config.GetOrDefault(config.FX_MAX_RATE_AGE, models.DefaultMaxRateAge))
+	if err != nil {
+		maxAge, _ = time.ParseDuration(models.DefaultMaxRateAge)
+	}
+	limits.Default = maxAge
+
+	conf, err := config.GetConfig()
+	if err != nil {
+		return limits
+	}
+
+	// Configuration keys are lower case, currencies upper case
+	prefix := config.FX_MAX_RATE_AGE_BY_CCY + "."
+	for key, val := range conf {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		ccy := strings.ToUpper(strings.TrimPrefix(key, prefix))
+		age, err := time.ParseDuration(val)
+		if err != nil {
+			log.Printf("ERROR: invalid maximum rate age %q for %s", val, ccy)
+			continue
+		}
+		limits.ByCcy[ccy] = age
+	}
+	return limits
+}
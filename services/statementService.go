@@ -0,0 +1,53 @@
+package services
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+// GenerateStatement builds the statement of the wallet for the month starting at period and stores it.
+// Statements never change once stored, so when one was stored meanwhile for the same month it is
+// returned instead. The month must have ended.
+func GenerateStatement(database *sql.DB, wallet models.Wallet, period time.Time, now time.Time) (*models.Statement, error) {
+	statement, err := buildStatement(database, wallet, period, period.AddDate(0, 1, 0), now)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := db.SaveStatement(database, *statement)
+	if err != nil {
+		return nil, err
+	}
+	if !stored {
+		return db.GetStatement(database, wallet.ID, period)
+	}
+	return statement, nil
+}
+
+// BuildDailyStatement builds the end of day statement of the wallet for the UTC day starting at day.
+// Daily statements are not stored: the day must have ended, so building one again gives the same entries.
+func BuildDailyStatement(database *sql.DB, wallet models.Wallet, day time.Time, now time.Time) (*models.Statement, error) {
+	return buildStatement(database, wallet, day, day.AddDate(0, 0, 1), now)
+}
+
+// buildStatement builds the statement of the wallet from from up to until.
+func buildStatement(database *sql.DB, wallet models.Wallet, from time.Time, until time.Time, now time.Time) (*models.Statement, error) {
+	opening, err := db.GetWalletBalanceAt(database, wallet.ID, from)
+	if err != nil {
+		return nil, err
+	}
+	if opening == nil {
+		return nil, models.Errorf(models.ErrCodeWalletNotFound, "wallet %d not found", wallet.ID)
+	}
+
+	entries, err := db.GetStatementEntries(database, wallet.ID, from, until)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := models.BuildStatement(wallet, from, *opening, entries, now)
+	return &statement, nil
+}
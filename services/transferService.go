@@ -0,0 +1,390 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/rudithu/CRYPTO-WalletApp/config"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
+)
+
+// TransferPlan is a transfer ready to be applied with db.TransferUpdate:
+// the wallets on both sides, the transfer record and its two legs, the fee set on the transfer-out leg.
+type TransferPlan struct {
+	SourceWallet models.Wallet
+	TargetWallet models.Wallet
+	Transfer     models.Transfer
+	TransferOut  models.Transaction
+	TransferIn   models.Transaction
+}
+
+// PrepareTransfer builds the transfer described by msg from the source wallet.
+// It resolves the target wallet, applies the conversion rate between both currencies, quotes the
+// fee paid by the source wallet and fails fast when even the ledger balance of the source wallet
+// cannot cover the amount.
+// msg is expected to be validated already.
+func PrepareTransfer(database *sql.DB, walletId int64, msg models.TransactionRequest) (*TransferPlan, error) {
+	// Retrieve the source wallet from database
+	sourceWallet, err := db.GetWalletById(database, walletId)
+	if err != nil {
+		return nil, err
+	}
+
+	if sourceWallet == nil {
+		return nil, models.Errorf(models.ErrCodeWalletNotFound, "source wallet %d not found", walletId)
+	}
+
+	// Fail fast when even the ledger balance is not sufficient for the transfer amount,
+	// the available balance is checked when the transfer is applied
+	if sourceWallet.Balance.LessThan(msg.Amount) {
+		return nil, models.Errorf(models.ErrCodeInsufficientFunds, "source wallet %d does not have enough balance", walletId).
+			WithDetails(map[string]string{"balance": sourceWallet.Balance.String(), "requested": msg.Amount.String()})
+	}
+
+	targetWallet, err := ResolveTargetWallet(database, *sourceWallet, msg.DestinationWalletID, msg.DestinationUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return planTransfer(database, *sourceWallet, *targetWallet, msg)
+}
+
+// ReversalPlan is a reversal ready to be applied with db.ReverseTransferUpdate: the original transfer,
+// the reversal transfer record and its two legs.
+type ReversalPlan struct {
+	Original    models.Transfer
+	Transfer    models.Transfer
+	TransferOut models.Transaction
+	TransferIn  models.Transaction
+}
+
+// PrepareReversal builds the reversal of the transfer with transferId described by msg.
+// The reversal is a new transfer from the original target wallet back to the original source wallet,
+// refunding the requested amount in the source currency, the rest of the reversible amount by default.
+// The amount debited from the recipient is converted with the original rate or the current rate,
+// depending on the configured policy. The fee paid for the original transfer is not refunded, and
+// the reversal is not charged a fee.
+// msg is expected to be validated already.
+func PrepareReversal(database *sql.DB, transferId int64, msg models.TransferReversalRequest) (*ReversalPlan, error) {
+	original, err := db.GetTransferById(database, transferId)
+	if err != nil {
+		return nil, err
+	}
+
+	if original == nil {
+		return nil, models.Errorf(models.ErrCodeTransferNotFound, "transfer %d not found", transferId)
+	}
+
+	if original.ReversalOf.Valid {
+		return nil, models.Errorf(models.ErrCodeNotReversible, "transfer %d is a reversal and cannot be reversed", transferId)
+	}
+
+	reversible := original.ReversibleAmount()
+	if original.Status == models.TransferStatusReversed || !reversible.IsPositive() {
+		return nil, models.Errorf(models.ErrCodeNotReversible, "transfer %d is already reversed", transferId)
+	}
+
+	amount := reversible
+	if msg.Amount != nil {
+		amount = *msg.Amount
+	}
+
+	if amount.GreaterThan(reversible) {
+		return nil, models.Errorf(models.ErrCodeValidationFailed, "amount must not be greater than the reversible amount").
+			WithDetails(map[string]string{"reversible": reversible.String(), "requested": amount.String()})
+	}
+
+	// Rate of the original direction, target currency per unit of source currency
+	rate := original.Rate
+	var ratePath string
+	policy := config.GetOrDefault(config.TRANSFER_REVERSAL_RATE, models.ReversalRateOriginal)
+	if policy == models.ReversalRateCurrent && original.SourceCurrency != original.TargetCurrency {
+		path, err := ResolveCcyRate(database, original.SourceCurrency, original.TargetCurrency)
+		if err != nil {
+			return nil, err
+		}
+		rate = path.Rate
+		// Resolved in the original direction, the reversal rate being its inverse
+		ratePath = path.String()
+	}
+	debitAmount := amount.Mul(rate).Round(models.AmountScale)
+
+	description := msg.Description
+	if description == "" {
+		description = fmt.Sprintf("reversal of transfer %d", original.ID)
+	}
+
+	return &ReversalPlan{
+		Original: *original,
+		// The reversal goes the opposite direction, so its rate is the inverse of the applied rate
+		Transfer: models.Transfer{
+			SourceWalletId: original.TargetWalletId,
+			TargetWalletId: original.SourceWalletId,
+			SourceAmount:   debitAmount,
+			TargetAmount:   amount,
+			Rate:           decimal.NewFromInt(1).DivRound(rate, models.RateScale),
+			RatePath:       ratePath,
+			Status:         models.TransferStatusCompleted,
+		},
+		// Transfer out from the recipient's wallet
+		TransferOut: models.Transaction{
+			WalletId:             original.TargetWalletId,
+			Type:                 models.TxnTypeTransferOut,
+			Amount:               debitAmount,
+			CounterpartyWalletId: sql.NullInt64{Int64: original.SourceWalletId, Valid: true},
+			Description:          models.NullString(description),
+		},
+		// Transfer in back to the sender's wallet
+		TransferIn: models.Transaction{
+			WalletId:             original.SourceWalletId,
+			Type:                 models.TxnTypeTransferIn,
+			Amount:               amount,
+			CounterpartyWalletId: sql.NullInt64{Int64: original.TargetWalletId, Valid: true},
+			Description:          models.NullString(description),
+		},
+	}, nil
+}
+
+// TransferPreview is a transfer resolved as by PrepareTransfer without being applied, with every
+// reason it would be rejected. The plan is only complete as far as it could be resolved.
+type TransferPreview struct {
+	TransferPlan
+	TargetResolved   bool
+	RateResolved     bool
+	AvailableBalance decimal.Decimal
+	Errors           []error
+}
+
+// PreviewTransfer resolves the transfer described by msg from the source wallet as PrepareTransfer does,
+// without applying it. Validation failures, an unresolvable target wallet or rate and an available
+// balance not covering the amount and the fee are collected in Errors rather than returned.
+func PreviewTransfer(database *sql.DB, walletId int64, msg models.TransactionRequest) (*TransferPreview, error) {
+	sourceWallet, err := db.GetWalletById(database, walletId)
+	if err != nil {
+		return nil, err
+	}
+
+	if sourceWallet == nil {
+		return nil, models.Errorf(models.ErrCodeWalletNotFound, "source wallet %d not found", walletId)
+	}
+
+	balance, err := db.GetWalletBalance(database, walletId)
+	if err != nil {
+		return nil, err
+	}
+
+	if balance == nil {
+		return nil, models.Errorf(models.ErrCodeWalletNotFound, "source wallet %d not found", walletId)
+	}
+
+	preview := &TransferPreview{
+		TransferPlan:     TransferPlan{SourceWallet: *sourceWallet},
+		AvailableBalance: balance.AvailableBalance,
+	}
+
+	if err = msg.ValidateRequest(models.TxnTypeTransferOut); err != nil {
+		return preview, preview.addError(err)
+	}
+
+	targetWallet, err := ResolveTargetWallet(database, *sourceWallet, msg.DestinationWalletID, msg.DestinationUserID)
+	if err != nil {
+		return preview, preview.addError(err)
+	}
+	preview.TargetWallet = *targetWallet
+	preview.TargetResolved = true
+
+	plan, err := planTransfer(database, *sourceWallet, *targetWallet, msg)
+	if err != nil {
+		return preview, preview.addError(err)
+	}
+	preview.TransferPlan = *plan
+	preview.RateResolved = true
+
+	required := msg.Amount.Add(feeAmount(plan.TransferOut.Fee))
+	if balance.AvailableBalance.LessThan(required) {
+		return preview, preview.addError(models.Errorf(models.ErrCodeInsufficientFunds, "source wallet %d does not have enough balance", walletId).
+			WithDetails(map[string]string{
+				"balance":           balance.Balance.String(),
+				"available_balance": balance.AvailableBalance.String(),
+				"requested":         required.String(),
+			}))
+	}
+	return preview, nil
+}
+
+// addError collects err when it is a reason to reject the transfer and returns other errors.
+func (p *TransferPreview) addError(err error) error {
+	var appErr *models.AppError
+	if !errors.As(err, &appErr) {
+		return err
+	}
+	p.Errors = append(p.Errors, err)
+	return nil
+}
+
+// planTransfer builds the transfer of msg from sourceWallet to targetWallet, pricing the exchange
+// between both currencies for the tier of the sender and quoting the fee paid by the source wallet.
+// The rate given to the sender is kept on both legs and the spread revenue on the transfer-out leg.
+func planTransfer(database *sql.DB, sourceWallet models.Wallet, targetWallet models.Wallet, msg models.TransactionRequest) (*TransferPlan, error) {
+	var err error
+	var quoteId sql.NullInt64
+	price := models.MidPrice(decimal.NewFromInt(1))
+
+	// Calculate target amount considering currency conversion if necessary
+	if msg.QuoteID != nil {
+		price, err = quotedPrice(database, *msg.QuoteID, sourceWallet, targetWallet)
+		if err != nil {
+			return nil, err
+		}
+		quoteId = sql.NullInt64{Int64: *msg.QuoteID, Valid: true}
+	} else if sourceWallet.Currency != targetWallet.Currency {
+		price, err = QuoteFxPrice(database, sourceWallet.UserId, sourceWallet.Currency, targetWallet.Currency)
+		if err != nil {
+			return nil, err
+		}
+	}
+	rate := price.Rate
+	// Rounded to what the wallet stores, the balance credited must add up to its transactions
+	targetAmount := msg.Amount.Mul(rate).Round(models.AmountScale)
+	if !targetAmount.IsPositive() {
+		return nil, models.Errorf(models.ErrCodeValidationFailed, "amount is too small to transfer").
+			WithDetails(map[string]string{"target_amount": targetAmount.String(), "rate": rate.String()})
+	}
+
+	var txnRate decimal.NullDecimal
+	var revenue *models.SpreadRevenue
+	if sourceWallet.Currency != targetWallet.Currency {
+		txnRate = decimal.NullDecimal{Decimal: rate, Valid: true}
+		revenue = price.Revenue(targetWallet.Currency, msg.Amount, targetAmount)
+	}
+
+	fee, err := QuoteFee(database, TransferFeeType(sourceWallet, targetWallet), sourceWallet, targetWallet.Currency, msg.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TransferPlan{
+		SourceWallet: sourceWallet,
+		TargetWallet: targetWallet,
+		// Create the transfer record linking both legs
+		Transfer: models.Transfer{
+			SourceWalletId: sourceWallet.ID,
+			TargetWalletId: targetWallet.ID,
+			SourceAmount:   msg.Amount,
+			TargetAmount:   targetAmount,
+			Rate:           rate,
+			Status:         models.TransferStatusCompleted,
+			QuoteId:        quoteId,
+			RatePath:       price.RatePath,
+		},
+		// Tags are the sender's own labels and are only kept on the transfer-out leg
+		TransferOut: models.Transaction{
+			WalletId:             sourceWallet.ID,
+			Type:                 models.TxnTypeTransferOut,
+			Amount:               msg.Amount,
+			CounterpartyWalletId: sql.NullInt64{Int64: targetWallet.ID, Valid: true},
+			Description:          models.NullString(msg.Description),
+			ExternalReference:    models.NullString(msg.ExternalReference),
+			Tags:                 msg.Tags,
+			Rate:                 txnRate,
+			Fee:                  fee,
+			SpreadRevenue:        revenue,
+		},
+		TransferIn: models.Transaction{
+			WalletId:             targetWallet.ID,
+			Type:                 models.TxnTypeTransferIn,
+			Amount:               targetAmount,
+			CounterpartyWalletId: sql.NullInt64{Int64: sourceWallet.ID, Valid: true},
+			Description:          models.NullString(msg.Description),
+			ExternalReference:    models.NullString(msg.ExternalReference),
+			Rate:                 txnRate,
+		},
+	}, nil
+}
+
+// quotedPrice returns the price locked by the quote with quoteId for a transfer from sourceWallet to
+// targetWallet. The quote must be open, for the owner of sourceWallet and between the currencies of both wallets; it is only used
+// when the transfer is applied.
+func quotedPrice(database *sql.DB, quoteId int64, sourceWallet models.Wallet, targetWallet models.Wallet) (models.FxPrice, error) {
+	quote, err := db.GetFxQuoteById(database, quoteId)
+	if err != nil {
+		return models.FxPrice{}, err
+	}
+
+	if quote == nil {
+		return models.FxPrice{}, models.Errorf(models.ErrCodeQuoteNotFound, "fx quote %d not found", quoteId)
+	}
+
+	if quote.UserId != sourceWallet.UserId {
+		return models.FxPrice{}, models.Errorf(models.ErrCodeValidationFailed, "fx quote %d is not for user %d", quoteId, sourceWallet.UserId).
+			WithDetails(map[string]string{"user_id": strconv.FormatInt(quote.UserId, 10)})
+	}
+
+	if quote.FromCcy != sourceWallet.Currency || quote.ToCcy != targetWallet.Currency {
+		return models.FxPrice{}, models.Errorf(models.ErrCodeValidationFailed, "fx quote %d is not for a transfer from %s to %s", quoteId, sourceWallet.Currency, targetWallet.Currency).
+			WithDetails(map[string]string{"from_ccy": quote.FromCcy, "to_ccy": quote.ToCcy})
+	}
+
+	if err = quote.CheckUsable(); err != nil {
+		return models.FxPrice{}, err
+	}
+	return quote.Price(), nil
+}
+
+// ResolveTargetWallet returns the wallet receiving a transfer from sourceWallet.
+// A destination wallet is used as is. For a destination user, the user's wallet in the source
+// currency is picked, or the user's default wallet when there is none.
+func ResolveTargetWallet(database *sql.DB, sourceWallet models.Wallet, destinationWalletId *int64, destinationUserId *int64) (*models.Wallet, error) {
+	if destinationUserId != nil {
+		// Prevent transferring to own wallet using user ID (must use wallet ID instead)
+		if sourceWallet.UserId == *destinationUserId {
+			return nil, models.Errorf(models.ErrCodeValidationFailed, "please use destination_wallet_id to transfer for the same user")
+		}
+
+		// Get default wallet(s) or wallets with matching currency for the target user
+		targetWallets, err := db.GetDefaultWalletOrCurrencyByUserID(database, *destinationUserId, sourceWallet.Currency)
+		if err != nil {
+			return nil, err
+		}
+
+		targetWallet := pickTargetWallet(targetWallets, sourceWallet.Currency)
+		if targetWallet == nil {
+			return nil, models.Errorf(models.ErrCodeWalletNotFound, "user %d has no wallet to receive the transfer", *destinationUserId)
+		}
+		return targetWallet, nil
+	}
+
+	if destinationWalletId == nil {
+		return nil, models.Errorf(models.ErrCodeValidationFailed, "please specify either destination_user_id or destination_wallet_id")
+	}
+
+	// Transfer to a specific wallet by wallet ID
+	targetWallet, err := db.GetWalletById(database, *destinationWalletId)
+	if err != nil {
+		return nil, err
+	}
+
+	if targetWallet == nil {
+		return nil, models.Errorf(models.ErrCodeWalletNotFound, "target wallet %d not found", *destinationWalletId)
+	}
+	return targetWallet, nil
+}
+
+// pickTargetWallet picks the wallet of a destination user receiving a transfer in currency:
+// the first wallet in that currency, or the default wallet if none match.
+// It returns nil when the user has neither.
+func pickTargetWallet(wallets []models.Wallet, currency string) *models.Wallet {
+	var targetWallet *models.Wallet
+	for i, w := range wallets {
+		if currency == w.Currency {
+			return &wallets[i]
+		} else if w.IsDefault {
+			targetWallet = &wallets[i]
+		}
+	}
+	return targetWallet
+}
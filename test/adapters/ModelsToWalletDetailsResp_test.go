@@ -15,7 +15,7 @@ import (
 func TestToWalletDetailsResp(t *testing.T) {
 
 	// Call the function
-	resp := adapters.ToWalletDetailsResp(testutils.MockUser(), testutils.MockWallets(), testutils.MockTxns(), testutils.MockCcyMapWithRate())
+	resp := adapters.ToWalletDetailsResp(testutils.MockUser(), testutils.MockWallets(), testutils.MockTxns(), testutils.MockCcyMapWithRate(), models.BaseCcy, nil, nil, "")
 
 	// Assertions
 	assert.Equal(t, testutils.MockUser().ID, resp.UserInfo.ID)
@@ -23,18 +23,18 @@ func TestToWalletDetailsResp(t *testing.T) {
 
 	assert.NotNil(t, resp.Wallets[0].Transactions)
 	assert.Equal(t, "USD", resp.Wallets[0].Currency)
-	assert.Equal(t, models.MoneyDecimal{decimal.NewFromFloat(100.00)}, resp.Wallets[0].Balance)
+	assert.Equal(t, models.MoneyDecimal{Decimal: decimal.NewFromFloat(100.00)}, resp.Wallets[0].Balance)
 	assert.NotNil(t, resp.Balance)
 
-	// Total balance should be USD 100 + (EUR 50 / 0.5) = 100 + 100 = 200
+	// Total balance should be USD 100 + (EUR 50 * 2) = 100 + 100 = 200
 	expectedTotal := decimal.NewFromFloat(200.00)
 	assert.True(t, resp.Balance.Amount.Equal(expectedTotal))
 	assert.Equal(t, models.BaseCcy, resp.Balance.Currency)
 }
 
-func TestToWalletDetailsResp_NoTotalBalance(t *testing.T) {
+func TestToWalletDetailsResp_PartialTotalBalance(t *testing.T) {
 	// Call the function
-	resp := adapters.ToWalletDetailsResp(testutils.MockUser(), testutils.MockWallets(), testutils.MockTxns(), map[string]models.CcyRateToBaseCcy{})
+	resp := adapters.ToWalletDetailsResp(testutils.MockUser(), testutils.MockWallets(), testutils.MockTxns(), map[string]models.CcyRateToReportingCcy{}, models.BaseCcy, nil, nil, "")
 
 	// Assertions
 	assert.Equal(t, testutils.MockUser().ID, resp.UserInfo.ID)
@@ -42,8 +42,16 @@ func TestToWalletDetailsResp_NoTotalBalance(t *testing.T) {
 
 	assert.Nil(t, resp.Wallets[1].Transactions)
 	assert.Equal(t, "EUR", resp.Wallets[1].Currency)
-	assert.Equal(t, models.MoneyDecimal{decimal.NewFromFloat(50.00)}, resp.Wallets[1].Balance)
-	assert.Nil(t, resp.Balance)
+	assert.Equal(t, models.MoneyDecimal{Decimal: decimal.NewFromFloat(50.00)}, resp.Wallets[1].Balance)
+	assert.Nil(t, resp.Wallets[1].ReportingBalance)
+
+	// Only the USD wallet is priced
+	if assert.NotNil(t, resp.Balance) {
+		assert.True(t, resp.Balance.Amount.Equal(decimal.NewFromFloat(100.00)))
+		assert.True(t, resp.Balance.Partial)
+		assert.Equal(t, []string{"EUR"}, resp.Balance.UnpricedCurrencies)
+		assert.Equal(t, []int64{resp.Wallets[1].ID}, resp.Balance.UnpricedWallets)
+	}
 }
 
 func NullInt64(val int64, valid bool) sql.NullInt64 {
@@ -52,3 +60,56 @@ func NullInt64(val int64, valid bool) sql.NullInt64 {
 		Valid: valid,
 	}
 }
+
+func TestToWalletDetailsResp_ConversionIsSingleItem(t *testing.T) {
+	c := testutils.MockConversion()
+	conversions := map[int64]models.Conversion{c.OutTransactionId: c, c.InTransactionId: c}
+
+	resp := adapters.ToWalletDetailsResp(testutils.MockUser(), testutils.MockWallets(), testutils.MockConversionTxns(c), testutils.MockCcyMapWithRate(), models.BaseCcy,
+		nil, conversions, "")
+
+	assert.Equal(t, 1, len(resp.Wallets[0].Transactions))
+	assert.Nil(t, resp.Wallets[1].Transactions)
+
+	item := resp.Wallets[0].Transactions[0]
+	assert.Equal(t, c.OutTransactionId, item.ID)
+	assert.Equal(t, models.TxnTypeConversion, item.Type)
+	if assert.NotNil(t, item.Conversion) {
+		assert.Equal(t, c.ID, item.Conversion.ID)
+		assert.Equal(t, "EUR", item.Conversion.ToCurrency)
+		assert.True(t, item.Conversion.ToAmount.Equal(c.ToAmount))
+		assert.True(t, item.Conversion.Spread.Equal(decimal.NewFromFloat(0.5)))
+	}
+}
+
+func TestToWalletDetailsResp_ConversionToWalletOnly(t *testing.T) {
+	c := testutils.MockConversion()
+	conversions := map[int64]models.Conversion{c.InTransactionId: c}
+
+	resp := adapters.ToWalletDetailsResp(testutils.MockUser(), testutils.MockWallets()[1:], testutils.MockConversionTxns(c)[1:], testutils.MockCcyMapWithRate(), models.BaseCcy,
+		nil, conversions, "")
+
+	assert.Equal(t, 1, len(resp.Wallets[0].Transactions))
+	item := resp.Wallets[0].Transactions[0]
+	assert.Equal(t, c.InTransactionId, item.ID)
+	assert.Equal(t, models.TxnTypeConversion, item.Type)
+	assert.NotNil(t, item.Conversion)
+}
+
+func TestToWalletDetailsResp_ReportingCcy(t *testing.T) {
+	ccyMap := map[string]models.CcyRateToReportingCcy{"USD": {Ccy: "USD", Rate: decimal.NewFromFloat(0.5)}}
+
+	resp := adapters.ToWalletDetailsResp(testutils.MockUser(), testutils.MockWallets(), nil, ccyMap, "EUR", nil, nil, "")
+
+	// Total balance should be (USD 100 * 0.5) + EUR 50 = 100
+	if assert.NotNil(t, resp.Balance) {
+		assert.Equal(t, "EUR", resp.Balance.Currency)
+		assert.True(t, resp.Balance.Amount.Equal(decimal.NewFromFloat(100)))
+	}
+	if assert.NotNil(t, resp.Wallets[0].ReportingBalance) {
+		assert.True(t, resp.Wallets[0].ReportingBalance.Equal(decimal.NewFromFloat(50)))
+	}
+	if assert.NotNil(t, resp.Wallets[1].ReportingBalance) {
+		assert.True(t, resp.Wallets[1].ReportingBalance.Equal(decimal.NewFromFloat(50)))
+	}
+}
@@ -0,0 +1,80 @@
+package db_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetWalletBalanceAt_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		at := time.Date(2025, 5, 31, 0, 0, 0, 0, time.UTC)
+		testutils.MockGetWalletBalanceAt(mock, int64(1), at, decimal.NewFromFloat(120.50))
+
+		balance, err := db.GetWalletBalanceAt(dbTest, int64(1), at)
+
+		assert.Nil(t, err)
+		if assert.NotNil(t, balance) {
+			assert.True(t, balance.Equal(decimal.NewFromFloat(120.50)))
+		}
+	})
+}
+
+func TestGetWalletBalanceAt_NotFound(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		at := time.Date(2025, 5, 31, 0, 0, 0, 0, time.UTC)
+		mock.ExpectQuery("SELECT w.id AS wallet_id, .+ WHERE w.id = \\$2").
+			WithArgs(at, int64(99)).
+			WillReturnRows(sqlmock.NewRows([]string{"wallet_id", "balance"}))
+
+		balance, err := db.GetWalletBalanceAt(dbTest, int64(99), at)
+
+		assert.Nil(t, err)
+		assert.Nil(t, balance)
+	})
+}
+
+func TestGetBalanceEvents_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		from := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+		until := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+		events := []models.BalanceEvent{
+			{At: from.Add(time.Hour), Delta: decimal.NewFromFloat(100)},
+			{At: from.Add(26 * time.Hour), Delta: decimal.NewFromFloat(-40)},
+		}
+		testutils.MockGetBalanceEvents(mock, int64(1), from, until, events...)
+
+		got, err := db.GetBalanceEvents(dbTest, int64(1), from, until)
+
+		assert.Nil(t, err)
+		if assert.Len(t, got, 2) {
+			assert.Equal(t, events[0].At, got[0].At)
+			assert.True(t, got[1].Delta.Equal(decimal.NewFromFloat(-40)))
+		}
+	})
+}
+
+func TestCreateBalanceSnapshots_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		at := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+		mock.ExpectExec("INSERT INTO wallet_balance_snapshots \\(wallet_id, snapshot_at, balance\\) SELECT .+ ON CONFLICT \\(wallet_id, snapshot_at\\) DO NOTHING").
+			WithArgs(at).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+
+		recorded, err := db.CreateBalanceSnapshots(dbTest, at)
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(3), recorded)
+	})
+}
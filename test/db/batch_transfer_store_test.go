@@ -0,0 +1,173 @@
+package db_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func batchItem(targetWalletId int64, amount float64) models.BatchTransferItem {
+	value := decimal.NewFromFloat(amount)
+	return models.BatchTransferItem{
+		Transfer: models.Transfer{SourceWalletId: 1, TargetWalletId: targetWalletId, SourceAmount: value, TargetAmount: value,
+			Rate: decimal.NewFromInt(1), Status: models.TransferStatusCompleted},
+		TransferOut: models.Transaction{WalletId: 1, Type: models.TxnTypeTransferOut, Amount: value,
+			CounterpartyWalletId: sql.NullInt64{Int64: targetWalletId, Valid: true}},
+		TransferIn: models.Transaction{WalletId: targetWalletId, Type: models.TxnTypeTransferIn, Amount: value,
+			CounterpartyWalletId: sql.NullInt64{Int64: 1, Valid: true}},
+	}
+}
+
+func TestBatchTransferUpdate_BestEffort(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		items := []models.BatchTransferItem{batchItem(2, 60), batchItem(3, 50), batchItem(2, 30)}
+		now := time.Now()
+
+		mock.ExpectBegin()
+
+		testutils.MockGetBalance(mock, decimal.NewFromFloat(100), 1)
+
+		// The second item does not fit in what is left after the first one
+		testutils.MockReserveIDs(mock, "transfers", 55, 56)
+		mock.ExpectQuery("INSERT INTO transfers \\(id, .+ VALUES \\(\\$1, .+\\), \\(\\$10, .+\\) RETURNING id, created_at").
+			WithArgs(int64(55), int64(1), int64(2), decimal.NewFromFloat(60), decimal.NewFromFloat(60), decimal.NewFromInt(1), "", models.TransferStatusCompleted, sql.NullInt64{},
+				int64(56), int64(1), int64(2), decimal.NewFromFloat(30), decimal.NewFromFloat(30), decimal.NewFromInt(1), "", models.TransferStatusCompleted, sql.NullInt64{}).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(55, now).AddRow(56, now))
+
+		testutils.MockReserveIDs(mock, "transactions", 101, 102, 103, 104)
+		mock.ExpectQuery("INSERT INTO transactions \\(id, .+ VALUES \\(\\$1, .+\\), \\(\\$11, .+\\), \\(\\$21, .+\\), \\(\\$31, .+\\) RETURNING id").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "completed_at"}).
+				AddRow(101, now, now).AddRow(102, now, now).AddRow(103, now, now).AddRow(104, now, now))
+
+		testutils.MockUpdateBalanceByWalletID(mock, decimal.NewFromFloat(10), 1)
+
+		mock.ExpectQuery("UPDATE wallets SET balance = wallets.balance \\+ v.amount FROM \\(VALUES \\(\\$1::int, \\$2::numeric\\)\\)").
+			WithArgs(int64(2), decimal.NewFromFloat(90)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "balance"}).AddRow(2, decimal.NewFromFloat(190)))
+
+		mock.ExpectCommit()
+
+		err := db.BatchTransferUpdate(dbTest, 1, items, false, "")
+
+		assert.Nil(t, err)
+		assert.True(t, items[0].Succeeded())
+		assert.Equal(t, int64(55), items[0].Transfer.ID)
+		assert.Equal(t, int64(101), items[0].TransferOut.ID)
+		assert.Equal(t, int64(103), items[0].TransferIn.ID)
+		assert.Equal(t, int64(55), items[0].TransferIn.TransferId.Int64)
+		assert.True(t, items[0].TransferOut.BalanceAfter.Equal(decimal.NewFromFloat(40)))
+		assert.True(t, items[0].TransferIn.BalanceAfter.Equal(decimal.NewFromFloat(160)))
+
+		var appErr *models.AppError
+		assert.False(t, items[1].Succeeded())
+		assert.True(t, errors.As(items[1].Err, &appErr))
+		assert.Equal(t, models.ErrCodeInsufficientFunds, appErr.Code)
+
+		assert.Equal(t, int64(56), items[2].Transfer.ID)
+		assert.True(t, items[2].TransferOut.BalanceAfter.Equal(decimal.NewFromFloat(10)))
+		assert.True(t, items[2].TransferIn.BalanceAfter.Equal(decimal.NewFromFloat(190)))
+	})
+}
+
+func TestBatchTransferUpdate_RowsReturnedOutOfOrder(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		items := []models.BatchTransferItem{batchItem(2, 60), batchItem(3, 40)}
+		first := time.Now().Add(-time.Second)
+		second := time.Now()
+
+		mock.ExpectBegin()
+		testutils.MockGetBalance(mock, decimal.NewFromFloat(100), 1)
+
+		// The rows of a multi-row INSERT come back in no particular order, they are matched on their ids
+		testutils.MockReserveIDs(mock, "transfers", 55, 56)
+		mock.ExpectQuery("INSERT INTO transfers").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(56, second).AddRow(55, first))
+		testutils.MockReserveIDs(mock, "transactions", 101, 102, 103, 104)
+		mock.ExpectQuery("INSERT INTO transactions").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "completed_at"}).
+				AddRow(104, second, second).AddRow(103, second, second).AddRow(102, second, second).AddRow(101, first, first))
+
+		testutils.MockUpdateBalanceByWalletID(mock, decimal.Zero, 1)
+		mock.ExpectQuery("UPDATE wallets SET balance = wallets.balance \\+ v.amount").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "balance"}).AddRow(3, decimal.NewFromFloat(40)).AddRow(2, decimal.NewFromFloat(60)))
+		mock.ExpectCommit()
+
+		err := db.BatchTransferUpdate(dbTest, 1, items, true, "")
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(55), items[0].Transfer.ID)
+		assert.Equal(t, first, items[0].Transfer.CreatedAt)
+		assert.Equal(t, int64(56), items[1].Transfer.ID)
+		assert.Equal(t, second, items[1].Transfer.CreatedAt)
+		assert.Equal(t, int64(101), items[0].TransferOut.ID)
+		assert.Equal(t, first, items[0].TransferOut.CreatedAt)
+		assert.Equal(t, int64(104), items[1].TransferIn.ID)
+		assert.Equal(t, int64(56), items[1].TransferIn.TransferId.Int64)
+	})
+}
+
+func TestBatchTransferUpdate_RowNotInserted(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		items := []models.BatchTransferItem{batchItem(2, 60)}
+
+		mock.ExpectBegin()
+		testutils.MockGetBalance(mock, decimal.NewFromFloat(100), 1)
+		testutils.MockReserveIDs(mock, "transfers", 55)
+		mock.ExpectQuery("INSERT INTO transfers").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(77, time.Now()))
+		mock.ExpectRollback()
+
+		err := db.BatchTransferUpdate(dbTest, 1, items, true, "")
+
+		assert.ErrorContains(t, err, "transfer 77 returned but not inserted")
+	})
+}
+
+func TestBatchTransferUpdate_AllOrNothingInsufficientFunds(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		items := []models.BatchTransferItem{batchItem(2, 60), batchItem(3, 50)}
+
+		mock.ExpectBegin()
+		testutils.MockGetBalance(mock, decimal.NewFromFloat(100), 1)
+		mock.ExpectRollback()
+
+		err := db.BatchTransferUpdate(dbTest, 1, items, true, "")
+
+		var appErr *models.AppError
+		assert.True(t, errors.As(err, &appErr))
+		assert.Equal(t, models.ErrCodeInsufficientFunds, appErr.Code)
+		assert.Equal(t, "110", appErr.Details.(map[string]string)["requested"])
+		assert.False(t, items[0].Succeeded())
+	})
+}
+
+func TestBatchTransferUpdate_DuplicatePaymentFile(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		items := []models.BatchTransferItem{batchItem(2, 60)}
+
+		mock.ExpectBegin()
+		testutils.MockGetBalance(mock, decimal.NewFromFloat(100), 1)
+		testutils.MockRecordPaymentFile(mock, 1, "MSG-1", false)
+		mock.ExpectRollback()
+
+		err := db.BatchTransferUpdate(dbTest, 1, items, true, "MSG-1")
+
+		var appErr *models.AppError
+		assert.True(t, errors.As(err, &appErr))
+		assert.Equal(t, models.ErrCodeDuplicateFile, appErr.Code)
+		assert.False(t, items[0].Succeeded())
+	})
+}
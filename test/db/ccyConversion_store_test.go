@@ -2,7 +2,9 @@ package db_test
 
 import (
 	"database/sql"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/rudithu/CRYPTO-WalletApp/db"
@@ -12,68 +14,122 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func TestGetCcyRateToBaseCcy_Success(t *testing.T) {
+func TestGetCcyRatesToReportingCcy_Success(t *testing.T) {
 	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
-		ccys := []string{"USD", "EUR"}
-		expectedRates := []models.CcyRateToBaseCcy{
-			{Ccy: "USD", Rate: decimal.NewFromFloat(1.2)},
-			{Ccy: "EUR", Rate: decimal.NewFromFloat(1.5)},
-		}
-
-		rows := sqlmock.NewRows([]string{"to_ccy", "rate"}).
-			AddRow("USD", expectedRates[0].Rate).
-			AddRow("EUR", expectedRates[1].Rate)
-
-		mock.ExpectQuery("SELECT to_ccy, rate FROM ccy_conversion").
-			WithArgs("USD", "EUR").
-			WillReturnRows(rows)
+		ccys := []string{"USD", "EUR", "JPY"}
+		testutils.MockGetCcyConversions(mock, testutils.MockCcyConversions()...)
 
-		rates, err := db.GetCcyRateToBaseCcy(dbTest, ccys)
+		rates, err := db.GetCcyRatesToReportingCcy(dbTest, "SGD", ccys, models.RateAgeLimits{Default: time.Hour})
 		require.NoError(t, err)
+
+		// JPY has no rate and is left out, EUR is converted through the base currency
 		require.Len(t, rates, 2)
-		require.Equal(t, expectedRates, rates)
+		require.Equal(t, "USD", rates[0].Ccy)
+		require.True(t, rates[0].Rate.Equal(decimal.NewFromFloat(1.35)))
+		require.Equal(t, "EUR", rates[1].Ccy)
+		require.True(t, rates[1].Rate.Equal(decimal.NewFromInt(1).Div(decimal.NewFromFloat(0.92)).Mul(decimal.NewFromFloat(1.35))))
 	})
 }
 
 func TestGetCcyRate_Success(t *testing.T) {
 	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
-		fromCcy := "JPY"
-		toCcy := "EUR"
-		baseCcy := models.BaseCcy
-
 		fromRate := decimal.NewFromFloat(110.0) // Base -> JPY
 		toRate := decimal.NewFromFloat(0.9)     // Base -> EUR
 
-		rows := sqlmock.NewRows([]string{"to_ccy", "rate"}).
-			AddRow(fromCcy, fromRate).
-			AddRow(toCcy, toRate)
+		testutils.MockGetCcyConversions(mock,
+			models.CcyConversion{FromCcy: models.BaseCcy, ToCcy: "JPY", Rate: fromRate, CreatedAt: time.Now()},
+			models.CcyConversion{FromCcy: models.BaseCcy, ToCcy: "EUR", Rate: toRate, CreatedAt: time.Now()})
 
-		mock.ExpectQuery("SELECT to_ccy, rate").
-			WithArgs(baseCcy, fromCcy, toCcy).
-			WillReturnRows(rows)
-
-		result, err := db.GetCcyRate(dbTest, fromCcy, toCcy)
+		result, err := db.GetCcyRate(dbTest, "JPY", "EUR")
 		require.NoError(t, err)
 
-		expected := toRate.Div(fromRate)
+		expected := decimal.NewFromInt(1).Div(fromRate).Mul(toRate)
 		require.True(t, result.Equal(expected))
 	})
 }
 
-func TestGetCcyRate_MissingRate(t *testing.T) {
+func TestGetCcyRatePath_DirectRate(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+		direct := models.CcyConversion{FromCcy: "EUR", ToCcy: "BTC", Rate: decimal.NewFromFloat(0.000011), CreatedAt: time.Now()}
+		testutils.MockGetCcyConversions(mock, append(testutils.MockCcyConversions(), direct)...)
+
+		path, err := db.GetCcyRatePath(dbTest, "EUR", "BTC", models.RateAgeLimits{})
+		require.NoError(t, err)
+		require.Equal(t, "EUR>BTC", path.String())
+		require.True(t, path.Rate.Equal(direct.Rate))
+	})
+}
+
+func TestGetCcyRatePath_SkipsStaleDirectRate(t *testing.T) {
 	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
-		fromCcy := "JPY"
-		toCcy := "EUR"
+		direct := models.CcyConversion{FromCcy: "EUR", ToCcy: "BTC", Rate: decimal.NewFromFloat(0.000011),
+			CreatedAt: time.Now().Add(-48 * time.Hour), Age: 48 * time.Hour}
+		btc := models.CcyConversion{FromCcy: models.BaseCcy, ToCcy: "BTC", Rate: decimal.NewFromFloat(0.00001), CreatedAt: time.Now(), Age: time.Minute}
+		testutils.MockGetCcyConversions(mock, append(testutils.MockCcyConversions(), direct, btc)...)
 
-		rows := sqlmock.NewRows([]string{"to_ccy", "rate"})
+		path, err := db.GetCcyRatePath(dbTest, "EUR", "BTC", models.RateAgeLimits{Default: 24 * time.Hour})
+		require.NoError(t, err)
+		require.Equal(t, "EUR>USD>BTC", path.String())
+	})
+}
 
-		mock.ExpectQuery("SELECT to_ccy, rate").
-			WithArgs(models.BaseCcy, fromCcy, toCcy).
-			WillReturnRows(rows)
+func TestGetCcyRate_MissingRate(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+		testutils.MockGetCcyConversions(mock, testutils.MockCcyConversions()...)
 
-		result, err := db.GetCcyRate(dbTest, fromCcy, toCcy)
+		result, err := db.GetCcyRate(dbTest, "JPY", "EUR")
 		require.Error(t, err)
 		require.True(t, result.IsZero())
 
+		var appErr *models.AppError
+		require.True(t, errors.As(err, &appErr))
+		require.Equal(t, models.ErrCodeRateUnavailable, appErr.Code)
+	})
+}
+
+func TestUpsertCcyConversion_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+		now := time.Now()
+		conversion := models.CcyConversion{FromCcy: "USD", ToCcy: "SGD", Rate: decimal.NewFromFloat(1.36)}
+
+		mock.ExpectQuery("INSERT INTO ccy_conversion .+ INSERT INTO ccy_conversion_history").
+			WithArgs("USD", "SGD", conversion.Rate).
+			WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(now))
+
+		err := db.UpsertCcyConversion(dbTest, &conversion)
+		require.NoError(t, err)
+		require.Equal(t, now, conversion.CreatedAt)
+	})
+}
+
+func TestGetCcyRatesToReportingCcy_MarksStaleRates(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+		conversions := testutils.MockCcyConversions()
+		conversions[1].Age = 2 * time.Hour
+		testutils.MockGetCcyConversions(mock, conversions...)
+
+		rates, err := db.GetCcyRatesToReportingCcy(dbTest, models.BaseCcy, []string{"EUR", "SGD"}, models.RateAgeLimits{Default: time.Hour})
+		require.NoError(t, err)
+		require.Len(t, rates, 2)
+		require.False(t, rates[0].Stale)
+		require.True(t, rates[1].Stale)
+	})
+}
+
+func TestGetCcyRateHistory_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+		from := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+		until := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+		history := []models.CcyConversion{
+			{FromCcy: "USD", ToCcy: "SGD", Rate: decimal.NewFromFloat(1.35), CreatedAt: from.AddDate(0, 0, -3)},
+			{FromCcy: "USD", ToCcy: "SGD", Rate: decimal.NewFromFloat(1.36), CreatedAt: from.AddDate(0, 0, 10)},
+		}
+		testutils.MockGetCcyRateHistory(mock, from, until, history...)
+
+		got, err := db.GetCcyRateHistory(dbTest, from, until)
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+		require.True(t, got[1].Rate.Equal(decimal.NewFromFloat(1.36)))
+		require.Equal(t, history[0].CreatedAt, got[0].CreatedAt)
 	})
 }
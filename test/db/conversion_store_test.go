@@ -0,0 +1,43 @@
+package db_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetConversionsByTransactionIDs_KeyedByBothLegs(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		c := testutils.MockConversion()
+
+		mock.ExpectQuery("SELECT c.id, .+ FROM conversions c .+ WHERE c.out_transaction_id IN \\(\\$1\\) OR c.in_transaction_id IN \\(\\$1\\)").
+			WithArgs(c.OutTransactionId).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "from_wallet_id", "to_wallet_id", "from_amount", "to_amount", "mid_rate", "rate",
+				"spread", "spread_amount", "out_transaction_id", "in_transaction_id", "created_at", "currency", "currency"}).
+				AddRow(c.ID, c.UserId, c.FromWalletId, c.ToWalletId, c.FromAmount, c.ToAmount, c.MidRate, c.Rate,
+					c.Spread, c.SpreadAmount, c.OutTransactionId, c.InTransactionId, c.CreatedAt, c.FromCurrency, c.ToCurrency))
+
+		conversions, err := db.GetConversionsByTransactionIDs(dbTest, []int64{c.OutTransactionId})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(conversions))
+		assert.Equal(t, c.ID, conversions[c.InTransactionId].ID)
+		assert.Equal(t, "EUR", conversions[c.OutTransactionId].ToCurrency)
+		assert.True(t, c.ToAmount.Equal(conversions[c.OutTransactionId].ToAmount))
+	})
+}
+
+func TestGetConversionsByTransactionIDs_Empty(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		conversions, err := db.GetConversionsByTransactionIDs(dbTest, nil)
+
+		assert.Nil(t, err)
+		assert.Nil(t, conversions)
+	})
+}
@@ -0,0 +1,54 @@
+package db_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCostBasisTransactions_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		at := time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)
+		txns := []models.CostBasisTxn{
+			{ID: 1, Type: models.TxnTypeDeposit, Amount: decimal.NewFromFloat(0.5), At: at},
+			{ID: 2, Type: models.TxnTypeConversionIn, Amount: decimal.NewFromFloat(0.01), Rate: decimal.NewNullDecimal(decimal.NewFromFloat(0.00002)),
+				CounterCcy: "USD", At: at.Add(time.Hour)},
+		}
+		testutils.MockGetCostBasisTransactions(mock, int64(2), txns...)
+
+		got, err := db.GetCostBasisTransactions(dbTest, int64(2))
+
+		assert.Nil(t, err)
+		if assert.Len(t, got, 2) {
+			assert.Equal(t, models.TxnTypeDeposit, got[0].Type)
+			assert.False(t, got[0].Rate.Valid)
+			assert.Equal(t, "", got[0].CounterCcy)
+			assert.True(t, got[1].Rate.Decimal.Equal(decimal.NewFromFloat(0.00002)))
+			assert.Equal(t, "USD", got[1].CounterCcy)
+			assert.Equal(t, at.Add(time.Hour), got[1].At)
+		}
+	})
+}
+
+func TestGetCostBasisTransactions_DBError(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		mock.ExpectQuery("SELECT t.id, t.type, t.amount, t.rate").
+			WithArgs(int64(2)).
+			WillReturnError(errors.New("db down"))
+
+		got, err := db.GetCostBasisTransactions(dbTest, int64(2))
+
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+}
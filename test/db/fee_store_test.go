@@ -0,0 +1,108 @@
+package db_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func withdrawFee(amount decimal.Decimal) *models.Fee {
+	rule := testutils.MockFeeRule()
+	return &models.Fee{Rule: rule, HouseWalletId: testutils.MockHouseWallet().ID, FeeBreakdown: rule.Calculate(amount)}
+}
+
+func TestGetFeeRules_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		testutils.MockGetFeeRules(mock, models.FeeTypeWithdraw, testutils.MockFeeRule())
+
+		rules, err := db.GetFeeRules(dbTest, models.FeeTypeWithdraw)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(rules))
+		assert.Equal(t, int64(71), rules[0].ID)
+		assert.False(t, rules[0].FromCcy.Valid)
+		assert.True(t, rules[0].MaxFee.Decimal.Equal(decimal.NewFromInt(5)))
+	})
+}
+
+func TestWithdrawUpdate_ChargesFee(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		amount := decimal.NewFromInt(50)
+		house := testutils.MockHouseWallet()
+		txn := &models.Transaction{WalletId: 1, Type: models.TxnTypeWithdraw, Amount: amount, Fee: withdrawFee(amount)}
+
+		mock.ExpectBegin()
+		testutils.MockGetBalance(mock, decimal.NewFromInt(100), 1)
+		testutils.MockCreateTransaction(mock, models.Transaction{ID: 123, WalletId: 1, Type: models.TxnTypeWithdraw, Amount: amount})
+		// The amount and the fee are taken in a single update
+		testutils.MockUpdateBalanceByWalletID(mock, decimal.NewFromInt(48), 1)
+		testutils.MockCreateTransaction(mock, models.Transaction{ID: 124, WalletId: 1, Type: models.TxnTypeFee, Amount: decimal.NewFromInt(2)})
+		testutils.MockCreateTransaction(mock, models.Transaction{ID: 125, WalletId: house.ID, Type: models.TxnTypeFeeIncome, Amount: decimal.NewFromInt(2)})
+		testutils.MockIncrementBalanceByWalletID(mock, decimal.NewFromInt(2), house.ID, decimal.NewFromInt(2))
+		mock.ExpectQuery("INSERT INTO fees").
+			WithArgs(int64(123), int64(71), decimal.NewFromInt(2), decimal.NewFromInt(1), decimal.NewFromFloat(0.5), house.ID,
+				sql.NullInt64{Int64: 124, Valid: true}, sql.NullInt64{Int64: 125, Valid: true}).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(81, time.Now()))
+		mock.ExpectCommit()
+
+		err := db.WithdrawUpdate(dbTest, txn)
+
+		assert.Nil(t, err)
+		assert.True(t, txn.BalanceAfter.Equal(decimal.NewFromInt(50)))
+		assert.True(t, txn.Fee.Charged())
+		assert.Equal(t, int64(81), txn.Fee.ID)
+		assert.True(t, txn.Fee.Debit.BalanceAfter.Equal(decimal.NewFromInt(48)))
+		assert.Equal(t, "fee for withdraw transaction 123", txn.Fee.Debit.Description.String)
+	})
+}
+
+func TestWithdrawUpdate_FeeNotCovered(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		amount := decimal.NewFromInt(50)
+		txn := &models.Transaction{WalletId: 1, Type: models.TxnTypeWithdraw, Amount: amount, Fee: withdrawFee(amount)}
+
+		mock.ExpectBegin()
+		testutils.MockGetBalance(mock, decimal.NewFromInt(51), 1)
+		mock.ExpectRollback()
+
+		err := db.WithdrawUpdate(dbTest, txn)
+
+		var appErr *models.AppError
+		assert.True(t, errors.As(err, &appErr))
+		assert.Equal(t, models.ErrCodeInsufficientFunds, appErr.Code)
+		assert.Equal(t, "52", appErr.Details.(map[string]string)["requested"])
+	})
+}
+
+func TestWithdrawUpdate_PendingReservesFee(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		amount := decimal.NewFromInt(50)
+		txn := &models.Transaction{WalletId: 1, Type: models.TxnTypeWithdraw, Amount: amount, Status: models.TxnStatusPending, Fee: withdrawFee(amount)}
+
+		mock.ExpectBegin()
+		testutils.MockGetBalance(mock, decimal.NewFromInt(100), 1)
+		testutils.MockCreateTransaction(mock, models.Transaction{ID: 123, WalletId: 1, Type: models.TxnTypeWithdraw, Amount: amount})
+		mock.ExpectQuery("INSERT INTO fees").
+			WithArgs(int64(123), int64(71), decimal.NewFromInt(2), sqlmock.AnyArg(), sqlmock.AnyArg(), testutils.MockHouseWallet().ID, sql.NullInt64{}, sql.NullInt64{}).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(81, time.Now()))
+		mock.ExpectCommit()
+
+		err := db.WithdrawUpdate(dbTest, txn)
+
+		assert.Nil(t, err)
+		assert.False(t, txn.Fee.Charged())
+		assert.Equal(t, int64(81), txn.Fee.ID)
+	})
+}
@@ -0,0 +1,95 @@
+package db_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetFxQuoteById_Expired(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		expected := testutils.MockFxQuote()
+		testutils.MockGetFxQuoteById(mock, expected, true)
+
+		quote, err := db.GetFxQuoteById(dbTest, expected.ID)
+
+		assert.Nil(t, err)
+		require.NotNil(t, quote)
+		assert.Equal(t, models.FxQuoteStatusExpired, quote.Status)
+		assert.True(t, expected.Rate.Equal(quote.Rate))
+	})
+}
+
+func TestGetFxQuoteById_NotFound(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		testutils.MockGetFxQuoteByIdNoRecord(mock, int64(99))
+
+		quote, err := db.GetFxQuoteById(dbTest, int64(99))
+
+		assert.Nil(t, err)
+		assert.Nil(t, quote)
+	})
+}
+
+func TestCreateFxQuote_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		quote := &models.FxQuote{UserId: 101, FromCcy: "SGD", ToCcy: "USD", Rate: decimal.NewFromFloat(0.75), MidRate: decimal.NewFromFloat(0.76),
+			Side: models.FxSideBid, Spread: decimal.NewFromFloat(1.3158)}
+		now := time.Now()
+
+		mock.ExpectQuery("INSERT INTO fx_quotes").
+			WithArgs(quote.UserId, quote.FromCcy, quote.ToCcy, quote.Rate, quote.MidRate, quote.Side, quote.Spread, quote.RatePath, models.FxQuoteStatusOpen, int64(60)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "expires_at", "created_at"}).AddRow(81, now.Add(time.Minute), now))
+
+		err := db.CreateFxQuote(dbTest, quote, 60)
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(81), quote.ID)
+		assert.Equal(t, models.FxQuoteStatusOpen, quote.Status)
+	})
+}
+
+func TestTransferUpdate_QuoteAlreadyUsed(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		quote := testutils.MockFxQuote()
+		quote.Status = models.FxQuoteStatusUsed
+		quote.TransferId = sql.NullInt64{Int64: 30, Valid: true}
+
+		transfer := &models.Transfer{
+			SourceWalletId: 101,
+			TargetWalletId: 102,
+			SourceAmount:   decimal.NewFromFloat(100),
+			TargetAmount:   decimal.NewFromFloat(75),
+			Rate:           quote.Rate,
+			Status:         models.TransferStatusCompleted,
+			QuoteId:        sql.NullInt64{Int64: quote.ID, Valid: true},
+		}
+
+		mock.ExpectBegin()
+		testutils.MockCreateTransfer(mock, models.Transfer{ID: 55, SourceWalletId: 101, TargetWalletId: 102,
+			SourceAmount: transfer.SourceAmount, Status: models.TransferStatusCompleted, CreatedAt: time.Now()})
+		mock.ExpectQuery(testutils.FxQuoteSelectQuery + " FROM fx_quotes WHERE id = \\$1 FOR UPDATE").
+			WithArgs(quote.ID).
+			WillReturnRows(testutils.AddFxQuoteRow(testutils.FxQuoteRows(), quote, false))
+		mock.ExpectRollback()
+
+		err := db.TransferUpdate(dbTest, transfer, &models.Transaction{WalletId: 101}, &models.Transaction{WalletId: 102})
+
+		var appErr *models.AppError
+		require.True(t, errors.As(err, &appErr))
+		assert.Equal(t, models.ErrCodeQuoteUsed, appErr.Code)
+	})
+}
@@ -0,0 +1,79 @@
+package db_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetFxSpreadRules_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		premium := models.FxSpreadRule{ID: 92, Tier: sql.NullString{String: "premium", Valid: true},
+			BidSpread: decimal.NewFromFloat(0.25), AskSpread: decimal.NewFromFloat(0.25)}
+		testutils.MockGetFxSpreadRules(mock, 2, testutils.MockFxSpreadRule(), premium)
+
+		rules, err := db.GetFxSpreadRules(dbTest, 2)
+
+		assert.Nil(t, err)
+		require.Equal(t, 2, len(rules))
+		assert.False(t, rules[0].Tier.Valid)
+		assert.Equal(t, "premium", rules[1].Tier.String)
+		assert.True(t, rules[1].BidSpread.Equal(decimal.NewFromFloat(0.25)))
+	})
+}
+
+func TestGetSpreadRevenueTotals_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		mock.ExpectQuery("SELECT currency, COUNT\\(\\*\\), SUM\\(amount\\) FROM fx_spread_revenue GROUP BY currency").
+			WillReturnRows(sqlmock.NewRows([]string{"currency", "count", "sum"}).
+				AddRow("SGD", 3, decimal.NewFromFloat(2.04)).
+				AddRow("USD", 1, decimal.NewFromInt(1)))
+
+		totals, err := db.GetSpreadRevenueTotals(dbTest)
+
+		assert.Nil(t, err)
+		require.Equal(t, 2, len(totals))
+		assert.Equal(t, "SGD", totals[0].Currency)
+		assert.Equal(t, int64(3), totals[0].Count)
+		assert.True(t, totals[0].Amount.Equal(decimal.NewFromFloat(2.04)))
+	})
+}
+
+func TestTransferUpdate_RecordsSpreadRevenue(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		rate := decimal.NullDecimal{Decimal: decimal.NewFromFloat(1.34325), Valid: true}
+		revenue := &models.SpreadRevenue{Currency: "SGD", MidRate: decimal.NewFromFloat(1.35), Rate: rate.Decimal, Amount: decimal.NewFromFloat(0.68)}
+		txnOut := &models.Transaction{WalletId: 101, Type: models.TxnTypeTransferOut, Amount: decimal.NewFromInt(100), Rate: rate, SpreadRevenue: revenue}
+		txnIn := &models.Transaction{WalletId: 102, Type: models.TxnTypeTransferIn, Amount: decimal.NewFromFloat(134.32), Rate: rate}
+		transfer := &models.Transfer{SourceWalletId: 101, TargetWalletId: 102, SourceAmount: txnOut.Amount, TargetAmount: txnIn.Amount,
+			Rate: rate.Decimal, Status: models.TransferStatusCompleted}
+
+		mock.ExpectBegin()
+		testutils.MockCreateTransfer(mock, models.Transfer{ID: 55, SourceWalletId: 101, TargetWalletId: 102,
+			SourceAmount: transfer.SourceAmount, Status: models.TransferStatusCompleted, CreatedAt: time.Now()})
+		testutils.MockGetBalance(mock, decimal.NewFromInt(500), 101)
+		testutils.MockCreateTransaction(mock, models.Transaction{ID: 401, WalletId: 101, Type: models.TxnTypeTransferOut, Amount: txnOut.Amount})
+		testutils.MockUpdateBalanceByWalletID(mock, decimal.NewFromInt(400), 101)
+		testutils.MockCreateTransaction(mock, models.Transaction{ID: 402, WalletId: 102, Type: models.TxnTypeTransferIn, Amount: txnIn.Amount})
+		testutils.MockIncrementBalanceByWalletID(mock, txnIn.Amount, 102, txnIn.Amount)
+		testutils.MockCreateSpreadRevenue(mock, 401, *revenue)
+		mock.ExpectCommit()
+
+		err := db.TransferUpdate(dbTest, transfer, txnOut, txnIn)
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(401), revenue.TransactionId)
+		assert.Equal(t, int64(95), revenue.ID)
+	})
+}
@@ -0,0 +1,246 @@
+package db_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetHoldById_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		expected := testutils.MockHold()
+		testutils.MockGetHoldById(mock, expected)
+
+		hold, err := db.GetHoldById(dbTest, expected.ID)
+
+		assert.Nil(t, err)
+		assert.NotNil(t, hold)
+		assert.Equal(t, expected.ID, hold.ID)
+		assert.Equal(t, models.HoldStatusActive, hold.Status)
+		assert.True(t, expected.Amount.Equal(hold.Amount))
+	})
+}
+
+func TestGetHoldById_NotFound(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		testutils.MockGetHoldByIdNoRecord(mock, int64(99))
+
+		hold, err := db.GetHoldById(dbTest, int64(99))
+
+		assert.Nil(t, err)
+		assert.Nil(t, hold)
+	})
+}
+
+func TestGetHeldBalancesByWalletIDs_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		mock.ExpectQuery("SELECT h.wallet_id, SUM\\(h.amount\\) FROM holds h WHERE h.wallet_id IN \\(\\$1, \\$2\\) AND h.status = 'active'").
+			WithArgs(int64(1), int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"wallet_id", "sum"}).AddRow(int64(1), decimal.NewFromFloat(40)))
+
+		held, err := db.GetHeldBalancesByWalletIDs(dbTest, []int64{1, 2})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(held))
+		assert.True(t, held[1].Equal(decimal.NewFromFloat(40)))
+	})
+}
+
+func TestCreateHold_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		hold := &models.Hold{
+			WalletId: 1,
+			Amount:   decimal.NewFromFloat(40),
+		}
+		now := time.Now()
+
+		mock.ExpectBegin()
+
+		mock.ExpectQuery("SELECT balance, .+ FROM wallets WHERE id = \\$1 FOR UPDATE").
+			WithArgs(hold.WalletId).
+			WillReturnRows(sqlmock.NewRows([]string{"balance", "available_balance"}).AddRow(decimal.NewFromFloat(100), decimal.NewFromFloat(50)))
+
+		mock.ExpectQuery("INSERT INTO holds").
+			WithArgs(hold.WalletId, hold.Amount, models.HoldStatusActive, hold.Description, int64(3600)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "expires_at", "created_at", "updated_at"}).AddRow(51, now.Add(time.Hour), now, now))
+
+		mock.ExpectCommit()
+
+		err := db.CreateHold(dbTest, hold, 3600)
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(51), hold.ID)
+		assert.Equal(t, models.HoldStatusActive, hold.Status)
+		assert.True(t, hold.ExpiresAt.Valid)
+	})
+}
+
+func TestCreateHold_InsufficientAvailableBalance(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		hold := &models.Hold{
+			WalletId: 1,
+			Amount:   decimal.NewFromFloat(60),
+		}
+
+		mock.ExpectBegin()
+
+		// The ledger balance covers the hold but 50 is already reserved by other holds
+		mock.ExpectQuery("SELECT balance, .+ FROM wallets WHERE id = \\$1 FOR UPDATE").
+			WithArgs(hold.WalletId).
+			WillReturnRows(sqlmock.NewRows([]string{"balance", "available_balance"}).AddRow(decimal.NewFromFloat(100), decimal.NewFromFloat(50)))
+
+		mock.ExpectRollback()
+
+		err := db.CreateHold(dbTest, hold, 3600)
+
+		var appErr *models.AppError
+		assert.True(t, errors.As(err, &appErr))
+		assert.Equal(t, models.ErrCodeInsufficientFunds, appErr.Code)
+		assert.Equal(t, "50", appErr.Details.(map[string]string)["available_balance"])
+	})
+}
+
+func TestCaptureHold_Partial(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		stored := testutils.MockHold()
+		hold := &models.Hold{ID: stored.ID}
+		amount := decimal.NewFromFloat(25)
+		txn := &models.Transaction{Type: models.TxnTypeWithdraw}
+
+		mock.ExpectBegin()
+
+		testutils.MockLockActiveHold(mock, stored, false)
+
+		mock.ExpectQuery("UPDATE holds SET status = \\$1, captured_amount = \\$2").
+			WithArgs(models.HoldStatusCaptured, amount, stored.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+
+		mock.ExpectQuery("SELECT balance, .+ FROM wallets WHERE id = \\$1 FOR UPDATE").
+			WithArgs(stored.WalletId).
+			WillReturnRows(sqlmock.NewRows([]string{"balance", "available_balance"}).AddRow(decimal.NewFromFloat(100), decimal.NewFromFloat(100)))
+
+		testutils.MockCreateTransaction(mock, models.Transaction{ID: 123, WalletId: stored.WalletId, Type: models.TxnTypeWithdraw, Amount: amount})
+		testutils.MockUpdateBalanceByWalletID(mock, decimal.NewFromFloat(75), stored.WalletId)
+
+		mock.ExpectExec("UPDATE holds SET transaction_id = \\$1 WHERE id = \\$2").
+			WithArgs(int64(123), stored.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectCommit()
+
+		err := db.CaptureHold(dbTest, hold, amount, txn)
+
+		assert.Nil(t, err)
+		assert.Equal(t, models.HoldStatusCaptured, hold.Status)
+		assert.True(t, hold.CapturedAmount.Equal(amount))
+		assert.Equal(t, int64(123), hold.TransactionId.Int64)
+		assert.True(t, txn.BalanceAfter.Equal(decimal.NewFromFloat(75)))
+	})
+}
+
+func TestCaptureHold_AmountExceedsHold(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		stored := testutils.MockHold()
+		hold := &models.Hold{ID: stored.ID}
+
+		mock.ExpectBegin()
+		testutils.MockLockActiveHold(mock, stored, false)
+		mock.ExpectRollback()
+
+		err := db.CaptureHold(dbTest, hold, decimal.NewFromFloat(41), &models.Transaction{Type: models.TxnTypeWithdraw})
+
+		var appErr *models.AppError
+		assert.True(t, errors.As(err, &appErr))
+		assert.Equal(t, models.ErrCodeValidationFailed, appErr.Code)
+	})
+}
+
+func TestReleaseHold_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		stored := testutils.MockHold()
+		hold := &models.Hold{ID: stored.ID}
+
+		mock.ExpectBegin()
+
+		testutils.MockLockActiveHold(mock, stored, false)
+
+		mock.ExpectQuery("UPDATE holds SET status = \\$1, captured_amount = \\$2").
+			WithArgs(models.HoldStatusReleased, decimal.Zero, stored.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+
+		mock.ExpectCommit()
+
+		err := db.ReleaseHold(dbTest, hold)
+
+		assert.Nil(t, err)
+		assert.Equal(t, models.HoldStatusReleased, hold.Status)
+	})
+}
+
+func TestReleaseHold_NotActive(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		stored := testutils.MockHold()
+		stored.Status = models.HoldStatusCaptured
+		hold := &models.Hold{ID: stored.ID}
+
+		mock.ExpectBegin()
+		testutils.MockLockActiveHold(mock, stored, false)
+		mock.ExpectRollback()
+
+		err := db.ReleaseHold(dbTest, hold)
+
+		var appErr *models.AppError
+		assert.True(t, errors.As(err, &appErr))
+		assert.Equal(t, models.ErrCodeHoldNotActive, appErr.Code)
+	})
+}
+
+func TestReleaseHold_Expired(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		stored := testutils.MockHold()
+		hold := &models.Hold{ID: stored.ID}
+
+		// Still active in the table because the expiry job has not run yet
+		mock.ExpectBegin()
+		testutils.MockLockActiveHold(mock, stored, true)
+		mock.ExpectRollback()
+
+		err := db.ReleaseHold(dbTest, hold)
+
+		var appErr *models.AppError
+		assert.True(t, errors.As(err, &appErr))
+		assert.Equal(t, models.ErrCodeHoldNotActive, appErr.Code)
+	})
+}
+
+func TestExpireHolds_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		mock.ExpectExec("UPDATE holds SET status = \\$1, updated_at = CURRENT_TIMESTAMP WHERE status = \\$2 AND expires_at <= CURRENT_TIMESTAMP").
+			WithArgs(models.HoldStatusExpired, models.HoldStatusActive).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+
+		count, err := db.ExpireHolds(dbTest)
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(3), count)
+	})
+}
@@ -0,0 +1,144 @@
+package db_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetScheduledTransferById_NotFound(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		testutils.MockGetScheduledTransferByIdNoRecord(mock, int64(99))
+
+		s, err := db.GetScheduledTransferById(dbTest, int64(99))
+
+		assert.Nil(t, err)
+		assert.Nil(t, s)
+	})
+}
+
+func TestGetDueScheduledTransfers_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		expected := testutils.MockScheduledTransfer()
+		now := time.Now()
+
+		mock.ExpectQuery(testutils.ScheduledTransferSelectQuery+".+ WHERE st.status = \\$1 AND COALESCE\\(st.retry_at, st.next_run_at\\) <= \\$2").
+			WithArgs(models.ScheduleStatusActive, now, 100).
+			WillReturnRows(testutils.AddScheduledTransferRow(testutils.ScheduledTransferRows(), expected))
+
+		schedules, err := db.GetDueScheduledTransfers(dbTest, now, 100)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(schedules))
+		assert.Equal(t, expected.ID, schedules[0].ID)
+		assert.True(t, expected.Amount.Equal(schedules[0].Amount))
+	})
+}
+
+func TestRunScheduledTransfer_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		s := testutils.MockScheduledTransfer()
+		run := &models.ScheduledTransferRun{ScheduledTransferId: s.ID, ScheduledFor: s.NextRunAt.Time, Attempt: 1}
+		amount := s.Amount
+		transfer := &models.Transfer{SourceWalletId: 1, TargetWalletId: 2, SourceAmount: amount, TargetAmount: amount,
+			Rate: decimal.NewFromInt(1), Status: models.TransferStatusCompleted}
+		txnOut := &models.Transaction{WalletId: 1, Type: models.TxnTypeTransferOut, Amount: amount}
+		txnIn := &models.Transaction{WalletId: 2, Type: models.TxnTypeTransferIn, Amount: amount}
+
+		mock.ExpectBegin()
+		testutils.MockLockScheduledTransfer(mock, s)
+		testutils.MockCreateTransfer(mock, models.Transfer{ID: 55, SourceWalletId: 1, TargetWalletId: 2, SourceAmount: amount, Status: models.TransferStatusCompleted})
+		testutils.MockGetBalance(mock, decimal.NewFromFloat(100), 1)
+		testutils.MockCreateTransaction(mock, models.Transaction{ID: 123, WalletId: 1, Type: models.TxnTypeTransferOut, Amount: amount})
+		testutils.MockUpdateBalanceByWalletID(mock, decimal.NewFromFloat(75), 1)
+		testutils.MockCreateTransaction(mock, models.Transaction{ID: 124, WalletId: 2, Type: models.TxnTypeTransferIn, Amount: amount})
+		mock.ExpectQuery("UPDATE wallets SET balance = balance \\+").
+			WithArgs(amount, int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(amount))
+		testutils.MockCreateScheduledTransferRun(mock, s, 1, models.RunStatusSucceeded)
+		testutils.MockSaveScheduledTransfer(mock, s, models.ScheduleStatusActive, 1, 0)
+		mock.ExpectCommit()
+
+		err := db.RunScheduledTransfer(dbTest, &s, run, transfer, txnOut, txnIn)
+
+		assert.Nil(t, err)
+		assert.Equal(t, models.RunStatusSucceeded, run.Status)
+		assert.Equal(t, int64(55), run.TransferId.Int64)
+		assert.Equal(t, int64(1), s.Occurrences)
+		assert.Equal(t, s.StartAt.AddDate(0, 1, 0), s.NextRunAt.Time)
+	})
+}
+
+func TestRunScheduledTransfer_InsufficientFunds(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		s := testutils.MockScheduledTransfer()
+		run := &models.ScheduledTransferRun{ScheduledTransferId: s.ID, ScheduledFor: s.NextRunAt.Time, Attempt: 1}
+		transfer := &models.Transfer{SourceWalletId: 1, TargetWalletId: 2, SourceAmount: s.Amount, Status: models.TransferStatusCompleted}
+		txnOut := &models.Transaction{WalletId: 1, Type: models.TxnTypeTransferOut, Amount: s.Amount}
+		txnIn := &models.Transaction{WalletId: 2, Type: models.TxnTypeTransferIn, Amount: s.Amount}
+
+		mock.ExpectBegin()
+		testutils.MockLockScheduledTransfer(mock, s)
+		testutils.MockCreateTransfer(mock, models.Transfer{ID: 55, SourceWalletId: 1, TargetWalletId: 2, SourceAmount: s.Amount, Status: models.TransferStatusCompleted})
+		testutils.MockGetBalance(mock, decimal.NewFromFloat(10), 1)
+		mock.ExpectRollback()
+
+		err := db.RunScheduledTransfer(dbTest, &s, run, transfer, txnOut, txnIn)
+
+		var appErr *models.AppError
+		assert.True(t, errors.As(err, &appErr))
+		assert.Equal(t, models.ErrCodeInsufficientFunds, appErr.Code)
+		// The schedule is left on the occurrence that failed
+		assert.Equal(t, int64(0), s.Occurrences)
+	})
+}
+
+func TestRecordScheduledTransferRun_ScheduleChanged(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		s := testutils.MockScheduledTransfer()
+		run := &models.ScheduledTransferRun{ScheduledTransferId: s.ID, ScheduledFor: s.NextRunAt.Time, Attempt: 1,
+			Status: models.RunStatusSkipped}
+
+		// Paused after the scheduler read it
+		stored := s
+		stored.Status = models.ScheduleStatusPaused
+
+		mock.ExpectBegin()
+		testutils.MockLockScheduledTransfer(mock, stored)
+		mock.ExpectRollback()
+
+		err := db.RecordScheduledTransferRun(dbTest, &s, run)
+
+		assert.True(t, errors.Is(err, db.ErrScheduleChanged))
+	})
+}
+
+func TestUpdateScheduledTransfer_NotFound(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(testutils.ScheduledTransferSelectQuery + ".+ FOR UPDATE").
+			WithArgs(int64(99)).
+			WillReturnRows(testutils.ScheduledTransferRows())
+		mock.ExpectRollback()
+
+		_, err := db.UpdateScheduledTransfer(dbTest, int64(99), func(s *models.ScheduledTransfer) error { return nil })
+
+		var appErr *models.AppError
+		assert.True(t, errors.As(err, &appErr))
+		assert.Equal(t, models.ErrCodeScheduleNotFound, appErr.Code)
+	})
+}
@@ -0,0 +1,108 @@
+package db_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetStatementEntries_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		from := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+		until := from.AddDate(0, 1, 0)
+		counterparty, withdrawId := int64(2), int64(11)
+		testutils.MockGetStatementEntries(mock, int64(1), from, until,
+			models.StatementEntry{TransactionID: 10, Type: models.TxnTypeTransferOut, At: from.Add(time.Hour), Amount: decimal.NewFromInt(-100),
+				Description: "rent", CounterpartyWalletID: &counterparty, CounterCcy: "EUR", Rate: decimal.NewNullDecimal(decimal.NewFromFloat(0.92))},
+			models.StatementEntry{TransactionID: 12, Type: models.TxnTypeFee, At: from.Add(2 * time.Hour), Amount: decimal.NewFromInt(-1), FeeFor: &withdrawId},
+		)
+
+		entries, err := db.GetStatementEntries(dbTest, int64(1), from, until)
+
+		assert.Nil(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "rent", entries[0].Description)
+		require.NotNil(t, entries[0].CounterpartyWalletID)
+		assert.Equal(t, counterparty, *entries[0].CounterpartyWalletID)
+		assert.Equal(t, "EUR", entries[0].CounterCcy)
+		assert.True(t, entries[0].Rate.Valid)
+		assert.Nil(t, entries[0].FeeFor)
+		assert.Nil(t, entries[1].CounterpartyWalletID)
+		require.NotNil(t, entries[1].FeeFor)
+		assert.Equal(t, withdrawId, *entries[1].FeeFor)
+	})
+}
+
+func TestGetStatement_Stored(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		period := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+		stored := models.Statement{WalletID: 1, Currency: "USD", Period: period, OpeningBalance: decimal.NewFromInt(100),
+			ClosingBalance: decimal.NewFromInt(150), Entries: []models.StatementEntry{
+				{TransactionID: 10, Type: models.TxnTypeDeposit, At: period.Add(time.Hour), Amount: decimal.NewFromInt(50), Balance: decimal.NewFromInt(150)},
+			}}
+		testutils.MockGetStatement(mock, int64(1), period, &stored)
+
+		statement, err := db.GetStatement(dbTest, int64(1), period)
+
+		assert.Nil(t, err)
+		require.NotNil(t, statement)
+		assert.Equal(t, period, statement.Period)
+		assert.True(t, statement.ClosingBalance.Equal(decimal.NewFromInt(150)))
+		require.Len(t, statement.Entries, 1)
+		assert.True(t, statement.Entries[0].Balance.Equal(decimal.NewFromInt(150)))
+	})
+}
+
+func TestGetStatement_NotStored(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		period := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+		testutils.MockGetStatement(mock, int64(1), period, nil)
+
+		statement, err := db.GetStatement(dbTest, int64(1), period)
+
+		assert.Nil(t, err)
+		assert.Nil(t, statement)
+	})
+}
+
+func TestSaveStatement_AlreadyStored(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		period := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+		testutils.MockSaveStatement(mock, int64(1), period, false)
+
+		stored, err := db.SaveStatement(dbTest, models.Statement{WalletID: 1, Period: period})
+
+		assert.Nil(t, err)
+		assert.False(t, stored)
+	})
+}
+
+func TestGetWalletsWithoutStatement(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		period := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+		until := period.AddDate(0, 1, 0)
+		mock.ExpectQuery("SELECT w.id, w.user_id, .+ FROM wallets w WHERE w.created_at < \\$2 AND NOT EXISTS \\(SELECT 1 FROM wallet_statements s").
+			WithArgs(period, until).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "balance", "currency", "type", "is_default", "created_at"}).
+				AddRow(int64(1), int64(3), decimal.NewFromInt(150), "USD", "saving", true, period.AddDate(0, -2, 0)))
+
+		wallets, err := db.GetWalletsWithoutStatement(dbTest, period, until)
+
+		assert.Nil(t, err)
+		require.Len(t, wallets, 1)
+		assert.Equal(t, int64(1), wallets[0].ID)
+	})
+}
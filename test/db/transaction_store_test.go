@@ -4,10 +4,13 @@ import (
 	"database/sql"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
 	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,14 +20,13 @@ func TestGetTransactionsByWalletIDs_Success(t *testing.T) {
 
 		expectedTxn := testutils.MockTxns()[0]
 
-		rows := sqlmock.NewRows([]string{"id", "wallet_id", "type", "amount", "counterparty_wallet_id", "created_at"}).
-			AddRow(expectedTxn.ID, expectedTxn.WalletId, expectedTxn.Type, expectedTxn.Amount, expectedTxn.CounterpartyWalletId, expectedTxn.CreatedAt)
+		rows := testutils.AddTxnRow(testutils.TxnRows(), expectedTxn)
 
-		mock.ExpectQuery("SELECT id, wallet_id, type, amount, counterparty_wallet_id, created_at FROM transactions WHERE wallet_id in \\(.+\\) ORDER BY created_at DESC").
+		mock.ExpectQuery(testutils.TxnSelectQuery + "WHERE wallet_id in \\(.+\\) ORDER BY created_at DESC").
 			WithArgs(expectedTxn.WalletId).
 			WillReturnRows(rows)
 
-		txns, err := db.GetTransactionsByWalletIDs(dbTest, []int64{expectedTxn.WalletId})
+		txns, err := db.GetTransactionsByWalletIDs(dbTest, []int64{expectedTxn.WalletId}, models.TransactionFilter{})
 
 		assert.Nil(t, err)
 		assert.NotNil(t, txns)
@@ -39,13 +41,13 @@ func TestGetTransactionsByWalletIDs_NotFound(t *testing.T) {
 	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
 		walletId := int64(23)
 
-		rows := sqlmock.NewRows([]string{"id", "wallet_id", "type", "amount", "counterparty_wallet_id", "created_at"})
+		rows := testutils.TxnRows()
 
-		mock.ExpectQuery("SELECT id, wallet_id, type, amount, counterparty_wallet_id, created_at FROM transactions WHERE wallet_id in \\(.+\\) ORDER BY created_at DESC").
+		mock.ExpectQuery(testutils.TxnSelectQuery + "WHERE wallet_id in \\(.+\\) ORDER BY created_at DESC").
 			WithArgs(walletId).
 			WillReturnRows(rows)
 
-		txns, err := db.GetTransactionsByWalletIDs(dbTest, []int64{walletId})
+		txns, err := db.GetTransactionsByWalletIDs(dbTest, []int64{walletId}, models.TransactionFilter{})
 
 		assert.Nil(t, err)
 		assert.Nil(t, txns)
@@ -56,11 +58,11 @@ func TestGetTransactionsByWalletIDs_DBError(t *testing.T) {
 	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
 		walletId := int64(23)
 
-		mock.ExpectQuery("SELECT id, wallet_id, type, amount, counterparty_wallet_id, created_at FROM transactions WHERE wallet_id in \\(.+\\) ORDER BY created_at DESC").
+		mock.ExpectQuery(testutils.TxnSelectQuery + "WHERE wallet_id in \\(.+\\) ORDER BY created_at DESC").
 			WithArgs(walletId).
 			WillReturnError(errors.New("db failed"))
 
-		txns, err := db.GetTransactionsByWalletIDs(dbTest, []int64{walletId})
+		txns, err := db.GetTransactionsByWalletIDs(dbTest, []int64{walletId}, models.TransactionFilter{})
 
 		assert.NotNil(t, err)
 		assert.Equal(t, "db failed", err.Error())
@@ -68,3 +70,186 @@ func TestGetTransactionsByWalletIDs_DBError(t *testing.T) {
 	})
 
 }
+
+func TestGetTransactionById_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		expectedTxn := testutils.MockTxns()[0]
+
+		rows := testutils.AddTxnRow(testutils.TxnRows(), expectedTxn)
+
+		mock.ExpectQuery(testutils.TxnSelectQuery + "WHERE id = \\$1").
+			WithArgs(expectedTxn.ID).
+			WillReturnRows(rows)
+
+		txn, err := db.GetTransactionById(dbTest, expectedTxn.ID)
+
+		assert.Nil(t, err)
+		assert.NotNil(t, txn)
+		assert.Equal(t, expectedTxn.ID, txn.ID)
+		assert.Equal(t, expectedTxn.WalletId, txn.WalletId)
+	})
+}
+
+func TestGetTransactionById_NotFound(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		mock.ExpectQuery(testutils.TxnSelectQuery + "WHERE id = \\$1").
+			WithArgs(int64(99)).
+			WillReturnRows(testutils.TxnRows())
+
+		txn, err := db.GetTransactionById(dbTest, 99)
+
+		assert.Nil(t, err)
+		assert.Nil(t, txn)
+	})
+}
+
+func TestGetTransferCounterparties_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		rows := sqlmock.NewRows([]string{"id", "linked_id", "linked_amount", "wallet_id", "currency", "user_id", "name", "transfer_status", "reversal_of"}).
+			AddRow(int64(11), int64(12), decimal.NewFromFloat(74.07), int64(210), "USD", int64(2), "Bob", models.TransferStatusReversed, nil).
+			AddRow(int64(11), int64(15), decimal.NewFromFloat(74.07), int64(210), "USD", int64(2), "Bob", models.TransferStatusReversed, nil)
+
+		mock.ExpectQuery("SELECT t.id, o.id, o.amount, w.id, w.currency, u.id, u.name, tr.status, tr.reversal_of FROM transactions t").
+			WithArgs(int64(11)).
+			WillReturnRows(rows)
+
+		cps, err := db.GetTransferCounterparties(dbTest, []int64{11})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(cps))
+		assert.Equal(t, int64(12), cps[11].LinkedTransactionID.Int64)
+		assert.Equal(t, "Bob", cps[11].UserName)
+		assert.Equal(t, models.TransferStatusReversed, cps[11].TransferStatus.String)
+		assert.False(t, cps[11].ReversalOf.Valid)
+	})
+}
+
+func TestGetTransferCounterparties_NoIds(t *testing.T) {
+	cps, err := db.GetTransferCounterparties(nil, nil)
+
+	assert.Nil(t, err)
+	assert.Nil(t, cps)
+}
+
+func TestGetTransactionsByWalletIDs_WithFilter(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		expectedTxn := testutils.MockTxns()[0]
+		expectedTxn.Description = models.NullString("May rent")
+		expectedTxn.Tags = []string{"housing", "rent"}
+
+		mock.ExpectQuery(testutils.TxnSelectQuery+"WHERE wallet_id in \\(\\$1\\) AND \\(description ILIKE \\$2 ESCAPE '\\\\' OR external_reference ILIKE \\$2 ESCAPE '\\\\'\\) AND EXISTS \\(.+ f.tag = \\$3\\) ORDER BY created_at DESC").
+			WithArgs(expectedTxn.WalletId, "%rent%", "housing").
+			WillReturnRows(testutils.AddTxnRow(testutils.TxnRows(), expectedTxn))
+
+		filter := models.TransactionFilter{Query: "rent", Tags: []string{"housing"}}
+		txns, err := db.GetTransactionsByWalletIDs(dbTest, []int64{expectedTxn.WalletId}, filter)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(txns))
+		assert.Equal(t, "May rent", txns[0].Description.String)
+		assert.Equal(t, []string{"housing", "rent"}, txns[0].Tags)
+	})
+}
+
+func TestGetTransactionsByWalletIDs_FilterEscapesWildcards(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		mock.ExpectQuery(testutils.TxnSelectQuery+"WHERE wallet_id in \\(\\$1\\) AND \\(description ILIKE \\$2 ESCAPE '\\\\' OR external_reference ILIKE \\$2 ESCAPE '\\\\'\\) ORDER BY created_at DESC").
+			WithArgs(int64(1), `%50\%\_off\\%`).
+			WillReturnRows(testutils.TxnRows())
+
+		filter := models.TransactionFilter{Query: `50%_off\`}
+		txns, err := db.GetTransactionsByWalletIDs(dbTest, []int64{1}, filter)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(txns))
+	})
+}
+
+func TestStreamTransactionsByWalletIDs_DateRange(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		from := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+		until := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+		first, second := testutils.MockTxns()[0], testutils.MockTxns()[0]
+		second.ID = 2
+
+		mock.ExpectQuery(testutils.TxnSelectQuery+"WHERE wallet_id in \\(\\$1\\) AND created_at >= \\$2 AND created_at < \\$3 ORDER BY created_at, id").
+			WithArgs(first.WalletId, from, until).
+			WillReturnRows(testutils.AddTxnRow(testutils.AddTxnRow(testutils.TxnRows(), first), second))
+
+		var ids []int64
+		filter := models.TransactionFilter{From: from, Until: until}
+		err := db.StreamTransactionsByWalletIDs(dbTest, []int64{first.WalletId}, filter, func(t models.Transaction) error {
+			ids = append(ids, t.ID)
+			return nil
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []int64{1, 2}, ids)
+	})
+}
+
+func TestStreamTransactionsByWalletIDs_CallbackErrorStops(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		first, second := testutils.MockTxns()[0], testutils.MockTxns()[0]
+		second.ID = 2
+
+		mock.ExpectQuery(testutils.TxnSelectQuery + "WHERE wallet_id in \\(\\$1\\) ORDER BY created_at, id").
+			WithArgs(first.WalletId).
+			WillReturnRows(testutils.AddTxnRow(testutils.AddTxnRow(testutils.TxnRows(), first), second))
+
+		calls := 0
+		err := db.StreamTransactionsByWalletIDs(dbTest, []int64{first.WalletId}, models.TransactionFilter{}, func(t models.Transaction) error {
+			calls++
+			return errors.New("client gone")
+		})
+
+		assert.EqualError(t, err, "client gone")
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestUpdateTransactionNotes_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		description := "dinner with Bob"
+		tags := []string{"food", "shared"}
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE transactions SET description = \\$1 WHERE id = \\$2").
+			WithArgs(models.NullString(description), int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM transaction_tags WHERE transaction_id = \\$1").
+			WithArgs(int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO transaction_tags \\(transaction_id, tag\\) VALUES \\(\\$1, \\$2\\), \\(\\$1, \\$3\\)").
+			WithArgs(int64(5), "food", "shared").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		err := db.UpdateTransactionNotes(dbTest, 5, models.TransactionNotesRequest{Description: &description, Tags: &tags})
+		assert.Nil(t, err)
+	})
+}
+
+func TestUpdateTransactionNotes_ClearTags(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		tags := []string{}
+
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM transaction_tags WHERE transaction_id = \\$1").
+			WithArgs(int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := db.UpdateTransactionNotes(dbTest, 5, models.TransactionNotesRequest{Tags: &tags})
+		assert.Nil(t, err)
+	})
+}
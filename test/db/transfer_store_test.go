@@ -0,0 +1,81 @@
+package db_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rudithu/CRYPTO-WalletApp/db"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTransferById_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		expected := testutils.MockTransfer()
+		testutils.MockGetTransferById(mock, expected)
+
+		transfer, err := db.GetTransferById(dbTest, expected.ID)
+
+		assert.Nil(t, err)
+		assert.NotNil(t, transfer)
+		assert.Equal(t, expected.ID, transfer.ID)
+		assert.Equal(t, "SGD", transfer.SourceCurrency)
+		assert.Equal(t, "USD", transfer.TargetCurrency)
+		assert.True(t, expected.Rate.Equal(transfer.Rate))
+		assert.Equal(t, int64(401), transfer.SourceTransactionId.Int64)
+		assert.Equal(t, int64(402), transfer.TargetTransactionId.Int64)
+	})
+}
+
+func TestGetTransferById_NotFound(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		testutils.MockGetTransferByIdNoRecord(mock, int64(99))
+
+		transfer, err := db.GetTransferById(dbTest, int64(99))
+
+		assert.Nil(t, err)
+		assert.Nil(t, transfer)
+	})
+}
+
+func TestGetTransfersByWalletIDs_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		expected := testutils.MockTransfer()
+
+		mock.ExpectQuery(testutils.TransferSelectQuery+".+ WHERE t.source_wallet_id IN \\(\\$1, \\$2\\) OR t.target_wallet_id IN \\(\\$1, \\$2\\) ORDER BY t.created_at DESC").
+			WithArgs(int64(201), int64(202)).
+			WillReturnRows(testutils.AddTransferRow(testutils.TransferRows(), expected))
+
+		transfers, err := db.GetTransfersByWalletIDs(dbTest, []int64{201, 202})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(transfers))
+		assert.Equal(t, expected.ID, transfers[0].ID)
+	})
+}
+
+func TestGetTransfersByWalletIDs_NoWallets(t *testing.T) {
+	transfers, err := db.GetTransfersByWalletIDs(nil, nil)
+
+	assert.Nil(t, err)
+	assert.Nil(t, transfers)
+}
+
+func TestGetTransfersByWalletIDs_DBError(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		mock.ExpectQuery(testutils.TransferSelectQuery).
+			WithArgs(int64(201)).
+			WillReturnError(errors.New("db failed"))
+
+		transfers, err := db.GetTransfersByWalletIDs(dbTest, []int64{201})
+
+		assert.NotNil(t, err)
+		assert.Nil(t, transfers)
+	})
+}
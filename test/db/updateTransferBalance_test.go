@@ -29,17 +29,53 @@ func TestDepositUpdate_Success(t *testing.T) {
 		mock.ExpectBegin()
 
 		mock.ExpectQuery("INSERT INTO transactions").
-			WithArgs(txn.WalletId, txn.Type, txn.Amount, txn.CounterpartyWalletId).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(123, time.Now()))
+			WithArgs(txn.WalletId, txn.Type, txn.Amount, txn.CounterpartyWalletId, txn.Description, txn.ExternalReference, txn.TransferId, models.TxnStatusCompleted, txn.Rate).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "completed_at"}).AddRow(123, time.Now(), time.Now()))
 
-		mock.ExpectExec("UPDATE wallets SET balance = balance \\+").
+		mock.ExpectQuery("UPDATE wallets SET balance = balance \\+").
 			WithArgs(txn.Amount, txn.WalletId).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(decimal.NewFromFloat(150.0)))
+
+		mock.ExpectCommit()
+
+		err := db.DepositUpdate(sqlDB, txn)
+		assert.Nil(t, err)
+		assert.True(t, txn.BalanceAfter.Equal(decimal.NewFromFloat(150.0)))
+	})
+}
+
+func TestDepositUpdate_WithNotes(t *testing.T) {
+
+	testutils.WithDBMock(t, func(sqlDB *sql.DB, mock sqlmock.Sqlmock) {
+		txn := &models.Transaction{
+			ID:                9,
+			WalletId:          1,
+			Type:              models.TxnTypeDeposit,
+			Amount:            decimal.NewFromFloat(100.0),
+			Description:       models.NullString("salary"),
+			ExternalReference: models.NullString("INV-2025-001"),
+			Tags:              []string{"payroll"},
+		}
+
+		mock.ExpectBegin()
+
+		mock.ExpectQuery("INSERT INTO transactions").
+			WithArgs(txn.WalletId, txn.Type, txn.Amount, txn.CounterpartyWalletId, txn.Description, txn.ExternalReference, txn.TransferId, models.TxnStatusCompleted, txn.Rate).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "completed_at"}).AddRow(123, time.Now(), time.Now()))
+
+		mock.ExpectExec("INSERT INTO transaction_tags").
+			WithArgs(int64(123), "payroll").
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
+		mock.ExpectQuery("UPDATE wallets SET balance = balance \\+").
+			WithArgs(txn.Amount, txn.WalletId).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(decimal.NewFromFloat(100.0)))
+
 		mock.ExpectCommit()
 
 		err := db.DepositUpdate(sqlDB, txn)
 		assert.Nil(t, err)
+		assert.Equal(t, int64(123), txn.ID)
 	})
 }
 
@@ -52,7 +88,7 @@ func TestDepositUpdate_FailCreateTxn(t *testing.T) {
 		mock.ExpectBegin()
 
 		mock.ExpectQuery("INSERT INTO transactions").
-			WithArgs(txn.WalletId, txn.Type, txn.Amount, txn.CounterpartyWalletId).
+			WithArgs(txn.WalletId, txn.Type, txn.Amount, txn.CounterpartyWalletId, txn.Description, txn.ExternalReference, txn.TransferId, models.TxnStatusCompleted, txn.Rate).
 			WillReturnError(errors.New("update failed"))
 
 		mock.ExpectRollback()
@@ -79,13 +115,13 @@ func TestWithdrawUpdate_Success(t *testing.T) {
 
 		mock.ExpectBegin()
 
-		mock.ExpectQuery("SELECT balance FROM wallets WHERE id = \\$1").
+		mock.ExpectQuery("SELECT balance, .+ FROM wallets WHERE id = \\$1 FOR UPDATE").
 			WithArgs(txn.WalletId).
-			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(initialBalance))
+			WillReturnRows(sqlmock.NewRows([]string{"balance", "available_balance"}).AddRow(initialBalance, initialBalance))
 
 		mock.ExpectQuery("INSERT INTO transactions").
-			WithArgs(txn.WalletId, txn.Type, txn.Amount, txn.CounterpartyWalletId).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(123, time.Now()))
+			WithArgs(txn.WalletId, txn.Type, txn.Amount, txn.CounterpartyWalletId, txn.Description, txn.ExternalReference, txn.TransferId, models.TxnStatusCompleted, txn.Rate).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "completed_at"}).AddRow(123, time.Now(), time.Now()))
 
 		mock.ExpectExec("UPDATE wallets SET balance = \\$1").
 			WithArgs(initialBalance.Sub(txn.Amount), txn.WalletId).
@@ -95,6 +131,7 @@ func TestWithdrawUpdate_Success(t *testing.T) {
 
 		err := db.WithdrawUpdate(sqlDB, txn)
 		assert.Nil(t, err)
+		assert.True(t, txn.BalanceAfter.Equal(initialBalance.Sub(txn.Amount)))
 	})
 }
 
@@ -112,13 +149,13 @@ func TestWithdrawUpdate_FailToUpdateBalance(t *testing.T) {
 
 		mock.ExpectBegin()
 
-		mock.ExpectQuery("SELECT balance FROM wallets WHERE id = \\$1").
+		mock.ExpectQuery("SELECT balance, .+ FROM wallets WHERE id = \\$1 FOR UPDATE").
 			WithArgs(txn.WalletId).
-			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(initialBalance))
+			WillReturnRows(sqlmock.NewRows([]string{"balance", "available_balance"}).AddRow(initialBalance, initialBalance))
 
 		mock.ExpectQuery("INSERT INTO transactions").
-			WithArgs(txn.WalletId, txn.Type, txn.Amount, txn.CounterpartyWalletId).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(123, time.Now()))
+			WithArgs(txn.WalletId, txn.Type, txn.Amount, txn.CounterpartyWalletId, txn.Description, txn.ExternalReference, txn.TransferId, models.TxnStatusCompleted, txn.Rate).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "completed_at"}).AddRow(123, time.Now(), time.Now()))
 
 		mock.ExpectExec("UPDATE wallets SET balance = \\$1").
 			WithArgs(initialBalance.Sub(txn.Amount), txn.WalletId).
@@ -134,6 +171,54 @@ func TestWithdrawUpdate_FailToUpdateBalance(t *testing.T) {
 	})
 }
 
+func TestWithdrawUpdate_InsufficientFunds(t *testing.T) {
+	testutils.WithDBMock(t, func(sqlDB *sql.DB, mock sqlmock.Sqlmock) {
+		txn := &models.Transaction{
+			WalletId: 1,
+			Type:     models.TxnTypeWithdraw,
+			Amount:   decimal.NewFromFloat(300.0),
+		}
+
+		mock.ExpectBegin()
+
+		mock.ExpectQuery("SELECT balance, .+ FROM wallets WHERE id = \\$1 FOR UPDATE").
+			WithArgs(txn.WalletId).
+			WillReturnRows(sqlmock.NewRows([]string{"balance", "available_balance"}).AddRow(decimal.NewFromFloat(212.00), decimal.NewFromFloat(212.00)))
+
+		mock.ExpectRollback()
+
+		err := db.WithdrawUpdate(sqlDB, txn)
+
+		var appErr *models.AppError
+		assert.True(t, errors.As(err, &appErr))
+		assert.Equal(t, models.ErrCodeInsufficientFunds, appErr.Code)
+	})
+}
+
+func TestWithdrawUpdate_WalletNotFound(t *testing.T) {
+	testutils.WithDBMock(t, func(sqlDB *sql.DB, mock sqlmock.Sqlmock) {
+		txn := &models.Transaction{
+			WalletId: 9,
+			Type:     models.TxnTypeWithdraw,
+			Amount:   decimal.NewFromFloat(10.0),
+		}
+
+		mock.ExpectBegin()
+
+		mock.ExpectQuery("SELECT balance, .+ FROM wallets WHERE id = \\$1 FOR UPDATE").
+			WithArgs(txn.WalletId).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}))
+
+		mock.ExpectRollback()
+
+		err := db.WithdrawUpdate(sqlDB, txn)
+
+		var appErr *models.AppError
+		assert.True(t, errors.As(err, &appErr))
+		assert.Equal(t, models.ErrCodeWalletNotFound, appErr.Code)
+	})
+}
+
 func TestTransferUpdate_Success(t *testing.T) {
 	testutils.WithDBMock(t, func(sqlDB *sql.DB, mock sqlmock.Sqlmock) {
 
@@ -152,32 +237,51 @@ func TestTransferUpdate_Success(t *testing.T) {
 			CounterpartyWalletId: sql.NullInt64{Valid: true, Int64: 101},
 		}
 
+		transfer := &models.Transfer{
+			SourceWalletId: 101,
+			TargetWalletId: 102,
+			SourceAmount:   txnOut.Amount,
+			TargetAmount:   txnIn.Amount,
+			Rate:           decimal.NewFromInt(1),
+			Status:         models.TransferStatusCompleted,
+		}
+		transferId := sql.NullInt64{Int64: 55, Valid: true}
+
 		mock.ExpectBegin()
 
-		mock.ExpectQuery("SELECT balance FROM wallets WHERE id = \\$1").
+		mock.ExpectQuery("INSERT INTO transfers").
+			WithArgs(transfer.SourceWalletId, transfer.TargetWalletId, transfer.SourceAmount, transfer.TargetAmount, transfer.Rate, transfer.RatePath, transfer.Status, transfer.ReversalOf).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(transferId.Int64, time.Now()))
+
+		mock.ExpectQuery("SELECT balance, .+ FROM wallets WHERE id = \\$1 FOR UPDATE").
 			WithArgs(txnOut.WalletId).
-			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(initialBalance))
+			WillReturnRows(sqlmock.NewRows([]string{"balance", "available_balance"}).AddRow(initialBalance, initialBalance))
 
 		mock.ExpectQuery("INSERT INTO transactions").
-			WithArgs(txnOut.WalletId, txnOut.Type, txnOut.Amount, txnOut.CounterpartyWalletId).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(123, time.Now()))
+			WithArgs(txnOut.WalletId, txnOut.Type, txnOut.Amount, txnOut.CounterpartyWalletId, txnOut.Description, txnOut.ExternalReference, transferId, models.TxnStatusCompleted, txnOut.Rate).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "completed_at"}).AddRow(123, time.Now(), time.Now()))
 
 		mock.ExpectExec("UPDATE wallets SET balance = \\$1").
 			WithArgs(initialBalance.Sub(txnOut.Amount), txnOut.WalletId).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
 		mock.ExpectQuery("INSERT INTO transactions").
-			WithArgs(txnIn.WalletId, txnIn.Type, txnIn.Amount, txnIn.CounterpartyWalletId).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(123, time.Now()))
+			WithArgs(txnIn.WalletId, txnIn.Type, txnIn.Amount, txnIn.CounterpartyWalletId, txnIn.Description, txnIn.ExternalReference, transferId, models.TxnStatusCompleted, txnIn.Rate).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "completed_at"}).AddRow(123, time.Now(), time.Now()))
 
-		mock.ExpectExec("UPDATE wallets SET balance = balance \\+").
+		mock.ExpectQuery("UPDATE wallets SET balance = balance \\+").
 			WithArgs(txnIn.Amount, txnIn.WalletId).
-			WillReturnResult(sqlmock.NewResult(1, 1))
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(txnIn.Amount))
 
 		mock.ExpectCommit()
 
-		err := db.TransferUpdate(sqlDB, txnOut, txnIn)
+		err := db.TransferUpdate(sqlDB, transfer, txnOut, txnIn)
 		assert.Nil(t, err)
+		assert.True(t, txnOut.BalanceAfter.Equal(initialBalance.Sub(txnOut.Amount)))
+		assert.True(t, txnIn.BalanceAfter.Equal(txnIn.Amount))
+		assert.Equal(t, int64(55), transfer.ID)
+		assert.Equal(t, transferId, txnOut.TransferId)
+		assert.Equal(t, transferId, txnIn.TransferId)
 	})
 }
 
@@ -198,23 +302,373 @@ func TestTransferUpdate_FailToUpdateWithdrawBalance(t *testing.T) {
 			CounterpartyWalletId: sql.NullInt64{Valid: true, Int64: 101},
 		}
 
+		transfer := &models.Transfer{
+			SourceWalletId: 101,
+			TargetWalletId: 102,
+			SourceAmount:   txnOut.Amount,
+			TargetAmount:   txnIn.Amount,
+			Rate:           decimal.NewFromInt(1),
+			Status:         models.TransferStatusCompleted,
+		}
+		transferId := sql.NullInt64{Int64: 55, Valid: true}
+
 		mock.ExpectBegin()
 
-		mock.ExpectQuery("SELECT balance FROM wallets WHERE id = \\$1").
+		mock.ExpectQuery("INSERT INTO transfers").
+			WithArgs(transfer.SourceWalletId, transfer.TargetWalletId, transfer.SourceAmount, transfer.TargetAmount, transfer.Rate, transfer.RatePath, transfer.Status, transfer.ReversalOf).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(transferId.Int64, time.Now()))
+
+		mock.ExpectQuery("SELECT balance, .+ FROM wallets WHERE id = \\$1 FOR UPDATE").
 			WithArgs(txnOut.WalletId).
-			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(initialBalance))
+			WillReturnRows(sqlmock.NewRows([]string{"balance", "available_balance"}).AddRow(initialBalance, initialBalance))
 
 		mock.ExpectQuery("INSERT INTO transactions").
-			WithArgs(txnOut.WalletId, txnOut.Type, txnOut.Amount, txnOut.CounterpartyWalletId).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(123, time.Now()))
+			WithArgs(txnOut.WalletId, txnOut.Type, txnOut.Amount, txnOut.CounterpartyWalletId, txnOut.Description, txnOut.ExternalReference, transferId, models.TxnStatusCompleted, txnOut.Rate).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "completed_at"}).AddRow(123, time.Now(), time.Now()))
 
 		mock.ExpectExec("UPDATE wallets SET balance = \\$1").
 			WithArgs(initialBalance.Sub(txnOut.Amount), txnOut.WalletId).WillReturnError(errors.New("db failed"))
 
 		mock.ExpectRollback()
 
-		err := db.TransferUpdate(sqlDB, txnOut, txnIn)
+		err := db.TransferUpdate(sqlDB, transfer, txnOut, txnIn)
 		assert.NotNil(t, err)
 		assert.Equal(t, "failed to update outgoing-balance: db failed", err.Error())
 	})
 }
+
+func TestTransferUpdate_FailToCreateTransfer(t *testing.T) {
+	testutils.WithDBMock(t, func(sqlDB *sql.DB, mock sqlmock.Sqlmock) {
+
+		transfer := &models.Transfer{
+			SourceWalletId: 101,
+			TargetWalletId: 102,
+			SourceAmount:   decimal.NewFromFloat(100.0),
+			TargetAmount:   decimal.NewFromFloat(100.0),
+			Rate:           decimal.NewFromInt(1),
+			Status:         models.TransferStatusCompleted,
+		}
+
+		mock.ExpectBegin()
+
+		mock.ExpectQuery("INSERT INTO transfers").
+			WithArgs(transfer.SourceWalletId, transfer.TargetWalletId, transfer.SourceAmount, transfer.TargetAmount, transfer.Rate, transfer.RatePath, transfer.Status, transfer.ReversalOf).
+			WillReturnError(errors.New("db failed"))
+
+		mock.ExpectRollback()
+
+		err := db.TransferUpdate(sqlDB, transfer, &models.Transaction{}, &models.Transaction{})
+		assert.NotNil(t, err)
+		assert.Equal(t, "failed to create transfer: db failed", err.Error())
+	})
+}
+
+func TestReverseTransferUpdate_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(sqlDB *sql.DB, mock sqlmock.Sqlmock) {
+
+		initialBalance := decimal.NewFromFloat(500.00)
+		reversalId := sql.NullInt64{Int64: 56, Valid: true}
+
+		reversal := &models.Transfer{
+			SourceWalletId: 102,
+			TargetWalletId: 101,
+			SourceAmount:   decimal.NewFromFloat(37.04),
+			TargetAmount:   decimal.NewFromFloat(50.0),
+			Rate:           decimal.RequireFromString("1.35"),
+			Status:         models.TransferStatusCompleted,
+		}
+		txnOut := &models.Transaction{
+			WalletId:             102,
+			Type:                 models.TxnTypeTransferOut,
+			Amount:               reversal.SourceAmount,
+			CounterpartyWalletId: sql.NullInt64{Valid: true, Int64: 101},
+		}
+		txnIn := &models.Transaction{
+			WalletId:             101,
+			Type:                 models.TxnTypeTransferIn,
+			Amount:               reversal.TargetAmount,
+			CounterpartyWalletId: sql.NullInt64{Valid: true, Int64: 102},
+		}
+
+		mock.ExpectBegin()
+
+		mock.ExpectQuery("SELECT source_amount - reversed_amount FROM transfers WHERE id = \\$1 FOR UPDATE").
+			WithArgs(int64(55)).
+			WillReturnRows(sqlmock.NewRows([]string{"reversible"}).AddRow(decimal.NewFromFloat(135.0)))
+
+		mock.ExpectQuery("INSERT INTO transfers").
+			WithArgs(reversal.SourceWalletId, reversal.TargetWalletId, reversal.SourceAmount, reversal.TargetAmount, reversal.Rate, reversal.RatePath, reversal.Status,
+				sql.NullInt64{Int64: 55, Valid: true}).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(reversalId.Int64, time.Now()))
+
+		mock.ExpectQuery("SELECT balance, .+ FROM wallets WHERE id = \\$1 FOR UPDATE").
+			WithArgs(txnOut.WalletId).
+			WillReturnRows(sqlmock.NewRows([]string{"balance", "available_balance"}).AddRow(initialBalance, initialBalance))
+
+		mock.ExpectQuery("INSERT INTO transactions").
+			WithArgs(txnOut.WalletId, txnOut.Type, txnOut.Amount, txnOut.CounterpartyWalletId, txnOut.Description, txnOut.ExternalReference, reversalId, models.TxnStatusCompleted, txnOut.Rate).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "completed_at"}).AddRow(124, time.Now(), time.Now()))
+
+		mock.ExpectExec("UPDATE wallets SET balance = \\$1").
+			WithArgs(initialBalance.Sub(txnOut.Amount), txnOut.WalletId).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectQuery("INSERT INTO transactions").
+			WithArgs(txnIn.WalletId, txnIn.Type, txnIn.Amount, txnIn.CounterpartyWalletId, txnIn.Description, txnIn.ExternalReference, reversalId, models.TxnStatusCompleted, txnIn.Rate).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "completed_at"}).AddRow(125, time.Now(), time.Now()))
+
+		mock.ExpectQuery("UPDATE wallets SET balance = balance \\+").
+			WithArgs(txnIn.Amount, txnIn.WalletId).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(txnIn.Amount))
+
+		mock.ExpectExec("UPDATE transfers SET reversed_amount = reversed_amount \\+ \\$1").
+			WithArgs(reversal.TargetAmount, models.TransferStatusReversed, models.TransferStatusPartiallyReversed, int64(55)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectCommit()
+
+		err := db.ReverseTransferUpdate(sqlDB, 55, reversal, txnOut, txnIn)
+		assert.Nil(t, err)
+		assert.Equal(t, reversalId.Int64, reversal.ID)
+		assert.Equal(t, int64(55), reversal.ReversalOf.Int64)
+	})
+}
+
+func TestReverseTransferUpdate_ExceedsReversibleAmount(t *testing.T) {
+	testutils.WithDBMock(t, func(sqlDB *sql.DB, mock sqlmock.Sqlmock) {
+
+		reversal := &models.Transfer{
+			SourceWalletId: 102,
+			TargetWalletId: 101,
+			SourceAmount:   decimal.NewFromFloat(37.04),
+			TargetAmount:   decimal.NewFromFloat(50.0),
+			Rate:           decimal.RequireFromString("1.35"),
+			Status:         models.TransferStatusCompleted,
+		}
+
+		mock.ExpectBegin()
+
+		mock.ExpectQuery("SELECT source_amount - reversed_amount FROM transfers WHERE id = \\$1 FOR UPDATE").
+			WithArgs(int64(55)).
+			WillReturnRows(sqlmock.NewRows([]string{"reversible"}).AddRow(decimal.NewFromFloat(20.0)))
+
+		mock.ExpectRollback()
+
+		err := db.ReverseTransferUpdate(sqlDB, 55, reversal, &models.Transaction{}, &models.Transaction{})
+
+		var appErr *models.AppError
+		assert.True(t, errors.As(err, &appErr))
+		assert.Equal(t, models.ErrCodeNotReversible, appErr.Code)
+	})
+}
+
+func TestDepositUpdate_Pending(t *testing.T) {
+	testutils.WithDBMock(t, func(sqlDB *sql.DB, mock sqlmock.Sqlmock) {
+		txn := &models.Transaction{
+			WalletId: 1,
+			Type:     models.TxnTypeDeposit,
+			Amount:   decimal.NewFromFloat(100.0),
+			Status:   models.TxnStatusPending,
+		}
+
+		mock.ExpectBegin()
+
+		// The balance is not touched until the deposit is completed
+		mock.ExpectQuery("INSERT INTO transactions").
+			WithArgs(txn.WalletId, txn.Type, txn.Amount, txn.CounterpartyWalletId, txn.Description, txn.ExternalReference, txn.TransferId, models.TxnStatusPending, txn.Rate).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "completed_at"}).AddRow(123, time.Now(), nil))
+
+		mock.ExpectCommit()
+
+		err := db.DepositUpdate(sqlDB, txn)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(123), txn.ID)
+		assert.False(t, txn.CompletedAt.Valid)
+	})
+}
+
+func TestUpdateTransactionStatus_CompletePendingWithdraw(t *testing.T) {
+	testutils.WithDBMock(t, func(sqlDB *sql.DB, mock sqlmock.Sqlmock) {
+		stored := models.Transaction{
+			ID:       123,
+			WalletId: 1,
+			Type:     models.TxnTypeWithdraw,
+			Amount:   decimal.NewFromFloat(100.0),
+			Status:   models.TxnStatusPending,
+		}
+		txn := &models.Transaction{ID: stored.ID}
+
+		mock.ExpectBegin()
+
+		mock.ExpectQuery(testutils.TxnSelectQuery + "WHERE id = \\$1 FOR UPDATE").
+			WithArgs(stored.ID).
+			WillReturnRows(testutils.AddTxnRow(testutils.TxnRows(), stored))
+
+		testutils.MockGetPendingFeeNoRecord(mock, stored.ID)
+
+		// The available balance already excludes the pending withdrawal
+		mock.ExpectQuery("SELECT balance, .+ FROM wallets WHERE id = \\$1 FOR UPDATE").
+			WithArgs(stored.WalletId).
+			WillReturnRows(sqlmock.NewRows([]string{"balance", "available_balance"}).AddRow(decimal.NewFromFloat(150.00), decimal.NewFromFloat(50.00)))
+
+		testutils.MockUpdateBalanceByWalletID(mock, decimal.NewFromFloat(50.00), stored.WalletId)
+
+		mock.ExpectQuery("UPDATE transactions SET status = \\$1, completed_at = CURRENT_TIMESTAMP WHERE id = \\$2 RETURNING completed_at").
+			WithArgs(models.TxnStatusCompleted, stored.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"completed_at"}).AddRow(time.Now()))
+
+		mock.ExpectCommit()
+
+		err := db.UpdateTransactionStatus(sqlDB, txn, models.TxnStatusCompleted)
+		assert.Nil(t, err)
+		assert.Equal(t, models.TxnStatusCompleted, txn.Status)
+		assert.True(t, txn.CompletedAt.Valid)
+		assert.True(t, txn.BalanceAfter.Equal(decimal.NewFromFloat(50.00)))
+	})
+}
+
+func TestUpdateTransactionStatus_FailPendingDeposit(t *testing.T) {
+	testutils.WithDBMock(t, func(sqlDB *sql.DB, mock sqlmock.Sqlmock) {
+		stored := models.Transaction{
+			ID:       123,
+			WalletId: 1,
+			Type:     models.TxnTypeDeposit,
+			Amount:   decimal.NewFromFloat(100.0),
+			Status:   models.TxnStatusPending,
+		}
+		txn := &models.Transaction{ID: stored.ID}
+
+		mock.ExpectBegin()
+
+		mock.ExpectQuery(testutils.TxnSelectQuery + "WHERE id = \\$1 FOR UPDATE").
+			WithArgs(stored.ID).
+			WillReturnRows(testutils.AddTxnRow(testutils.TxnRows(), stored))
+
+		mock.ExpectQuery("UPDATE transactions SET status = \\$1, failed_at = CURRENT_TIMESTAMP WHERE id = \\$2 RETURNING failed_at").
+			WithArgs(models.TxnStatusFailed, stored.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"failed_at"}).AddRow(time.Now()))
+
+		mock.ExpectCommit()
+
+		err := db.UpdateTransactionStatus(sqlDB, txn, models.TxnStatusFailed)
+		assert.Nil(t, err)
+		assert.Equal(t, models.TxnStatusFailed, txn.Status)
+		assert.True(t, txn.FailedAt.Valid)
+	})
+}
+
+func TestUpdateTransactionStatus_ReverseDepositInsufficientFunds(t *testing.T) {
+	testutils.WithDBMock(t, func(sqlDB *sql.DB, mock sqlmock.Sqlmock) {
+		stored := models.Transaction{
+			ID:       123,
+			WalletId: 1,
+			Type:     models.TxnTypeDeposit,
+			Amount:   decimal.NewFromFloat(100.0),
+			Status:   models.TxnStatusCompleted,
+		}
+		txn := &models.Transaction{ID: stored.ID}
+
+		mock.ExpectBegin()
+
+		mock.ExpectQuery(testutils.TxnSelectQuery + "WHERE id = \\$1 FOR UPDATE").
+			WithArgs(stored.ID).
+			WillReturnRows(testutils.AddTxnRow(testutils.TxnRows(), stored))
+
+		mock.ExpectQuery("SELECT balance, .+ FROM wallets WHERE id = \\$1 FOR UPDATE").
+			WithArgs(stored.WalletId).
+			WillReturnRows(sqlmock.NewRows([]string{"balance", "available_balance"}).AddRow(decimal.NewFromFloat(120.00), decimal.NewFromFloat(80.00)))
+
+		mock.ExpectRollback()
+
+		err := db.UpdateTransactionStatus(sqlDB, txn, models.TxnStatusReversed)
+
+		var appErr *models.AppError
+		assert.True(t, errors.As(err, &appErr))
+		assert.Equal(t, models.ErrCodeInsufficientFunds, appErr.Code)
+	})
+}
+
+func TestUpdateTransactionStatus_InvalidTransition(t *testing.T) {
+	testutils.WithDBMock(t, func(sqlDB *sql.DB, mock sqlmock.Sqlmock) {
+		stored := models.Transaction{
+			ID:       123,
+			WalletId: 1,
+			Type:     models.TxnTypeWithdraw,
+			Amount:   decimal.NewFromFloat(100.0),
+			Status:   models.TxnStatusFailed,
+		}
+		txn := &models.Transaction{ID: stored.ID}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(testutils.TxnSelectQuery + "WHERE id = \\$1 FOR UPDATE").
+			WithArgs(stored.ID).
+			WillReturnRows(testutils.AddTxnRow(testutils.TxnRows(), stored))
+		mock.ExpectRollback()
+
+		err := db.UpdateTransactionStatus(sqlDB, txn, models.TxnStatusCompleted)
+
+		var appErr *models.AppError
+		assert.True(t, errors.As(err, &appErr))
+		assert.Equal(t, models.ErrCodeInvalidTransition, appErr.Code)
+	})
+}
+
+func TestUpdateTransactionStatus_TransferLeg(t *testing.T) {
+	testutils.WithDBMock(t, func(sqlDB *sql.DB, mock sqlmock.Sqlmock) {
+		stored := models.Transaction{
+			ID:         123,
+			WalletId:   1,
+			Type:       models.TxnTypeTransferOut,
+			Amount:     decimal.NewFromFloat(100.0),
+			Status:     models.TxnStatusCompleted,
+			TransferId: testutils.NullInt64(31, true),
+		}
+		txn := &models.Transaction{ID: stored.ID}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(testutils.TxnSelectQuery + "WHERE id = \\$1 FOR UPDATE").
+			WithArgs(stored.ID).
+			WillReturnRows(testutils.AddTxnRow(testutils.TxnRows(), stored))
+		mock.ExpectRollback()
+
+		err := db.UpdateTransactionStatus(sqlDB, txn, models.TxnStatusReversed)
+
+		var appErr *models.AppError
+		assert.True(t, errors.As(err, &appErr))
+		assert.Equal(t, models.ErrCodeInvalidTransition, appErr.Code)
+	})
+}
+
+// expectStatusRejected expects the status change of the completed transaction of txnType to be rejected without touching the balance.
+func expectStatusRejected(t *testing.T, txnType string) {
+	testutils.WithDBMock(t, func(sqlDB *sql.DB, mock sqlmock.Sqlmock) {
+		stored := models.Transaction{
+			ID:       123,
+			WalletId: 1,
+			Type:     txnType,
+			Amount:   decimal.NewFromFloat(100.0),
+			Status:   models.TxnStatusCompleted,
+		}
+		txn := &models.Transaction{ID: stored.ID}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(testutils.TxnSelectQuery + "WHERE id = \\$1 FOR UPDATE").
+			WithArgs(stored.ID).
+			WillReturnRows(testutils.AddTxnRow(testutils.TxnRows(), stored))
+		mock.ExpectRollback()
+
+		err := db.UpdateTransactionStatus(sqlDB, txn, models.TxnStatusReversed)
+
+		var appErr *models.AppError
+		assert.True(t, errors.As(err, &appErr))
+		assert.Equal(t, models.ErrCodeInvalidTransition, appErr.Code)
+	})
+}
+
+func TestUpdateTransactionStatus_ConversionInLeg(t *testing.T) {
+	expectStatusRejected(t, models.TxnTypeConversionIn)
+}
+
+func TestUpdateTransactionStatus_FeeIncomeLeg(t *testing.T) {
+	expectStatusRejected(t, models.TxnTypeFeeIncome)
+}
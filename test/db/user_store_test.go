@@ -18,10 +18,10 @@ func TestGetUserById_Success(t *testing.T) {
 		expectedName := "Alice"
 		expectedTime := time.Now()
 
-		rows := sqlmock.NewRows([]string{"id", "name", "created_at"}).
-			AddRow(expectedID, expectedName, expectedTime)
+		rows := sqlmock.NewRows([]string{"id", "name", "reporting_currency", "created_at"}).
+			AddRow(expectedID, expectedName, "SGD", expectedTime)
 
-		mock.ExpectQuery("SELECT id, name, created_at FROM users where id=\\$1").
+		mock.ExpectQuery("SELECT id, name, reporting_currency, created_at FROM users where id=\\$1").
 			WithArgs(expectedID).
 			WillReturnRows(rows)
 
@@ -30,13 +30,14 @@ func TestGetUserById_Success(t *testing.T) {
 		assert.NotNil(t, user)
 		assert.Equal(t, expectedID, user.ID)
 		assert.Equal(t, expectedName, user.Name)
+		assert.Equal(t, "SGD", user.ReportingCurrency)
 		assert.WithinDuration(t, expectedTime, user.CreatedAt, time.Second)
 	})
 }
 
 func TestGetUserById_NotFound(t *testing.T) {
 	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
-		mock.ExpectQuery("SELECT id, name, created_at FROM users where id=\\$1").
+		mock.ExpectQuery("SELECT id, name, reporting_currency, created_at FROM users where id=\\$1").
 			WithArgs(int64(2)).
 			WillReturnError(sql.ErrNoRows)
 		user, err := db.GetUserById(dbTest, 2)
@@ -48,7 +49,7 @@ func TestGetUserById_NotFound(t *testing.T) {
 
 func TestGetUserById_DBError(t *testing.T) {
 	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
-		mock.ExpectQuery("SELECT id, name, created_at FROM users where id=\\$1").
+		mock.ExpectQuery("SELECT id, name, reporting_currency, created_at FROM users where id=\\$1").
 			WithArgs(int64(3)).
 			WillReturnError(errors.New("db failed"))
 
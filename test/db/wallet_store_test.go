@@ -8,6 +8,7 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/rudithu/CRYPTO-WalletApp/db"
 	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -178,3 +179,19 @@ func TestGetWalletByUserIDs_DBError(t *testing.T) {
 		assert.Nil(t, wallets)
 	})
 }
+
+func TestGetAvailableBalancesByWalletIDs_ReservesPendingFees(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+
+		// Same deductions as the balance checked by withdrawals: holds, pending withdrawals and their fees
+		mock.ExpectQuery("SELECT id, balance .+ FROM holds h .+ p.type = 'withdraw'.+ JOIN fees f .+ FROM wallets WHERE id IN \\(\\$1, \\$2\\)").
+			WithArgs(1, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "available"}).AddRow(1, decimal.NewFromFloat(48.5)).AddRow(2, decimal.NewFromInt(10)))
+
+		available, err := db.GetAvailableBalancesByWalletIDs(dbTest, []int64{1, 2})
+
+		assert.Nil(t, err)
+		assert.True(t, available[1].Equal(decimal.NewFromFloat(48.5)))
+		assert.True(t, available[2].Equal(decimal.NewFromInt(10)))
+	})
+}
@@ -41,14 +41,23 @@ func TestHandleBalance_Success(t *testing.T) {
 		testutils.MockGetUserById(mock, user)
 
 		// Step 2: Expect GetWalletByUserIDs
-		testutils.MockGetWalletByUserIDs(mock, []models.Wallet{{
+		wallets := []models.Wallet{{
 			ID:        walletID,
 			UserId:    userID,
 			Balance:   balance,
 			Currency:  currency,
 			IsDefault: true,
 			CreatedAt: createdAt,
-		}})
+		}}
+		testutils.MockGetWalletByUserIDs(mock, wallets)
+
+		// Step 3: Expect GetAvailableBalancesByWalletIDs, 30.00 held and 20.00 pending out
+		testutils.MockGetAvailableBalances(mock, wallets, map[int64]decimal.Decimal{walletID: decimal.NewFromFloat(50.00)})
+
+		// Step 4: Expect GetPendingBalancesByWalletIDs
+		testutils.MockGetPendingBalances(mock, wallets, map[int64]models.PendingBalance{
+			walletID: {Incoming: decimal.NewFromFloat(50.00), Outgoing: decimal.NewFromFloat(20.00)},
+		})
 
 		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/users/%d/wallets/balance", userID), nil)
 		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(userID, 10)})
@@ -65,6 +74,13 @@ func TestHandleBalance_Success(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, "Alice", response.UserInfo.Name)
 		assert.NotNil(t, response.Balance)
+		require.Len(t, response.Wallets, 1)
+		assert.True(t, response.Wallets[0].Balance.Equal(balance))
+		require.NotNil(t, response.Wallets[0].Available)
+		assert.True(t, response.Wallets[0].Available.Equal(decimal.NewFromFloat(50.00)))
+		require.NotNil(t, response.Wallets[0].PendingIn)
+		assert.True(t, response.Wallets[0].PendingIn.Equal(decimal.NewFromFloat(50.00)))
+		assert.True(t, response.Wallets[0].PendingOut.Equal(decimal.NewFromFloat(20.00)))
 	})
 
 }
@@ -83,9 +99,13 @@ func TestHandleBalance_FilteredByWalletID(t *testing.T) {
 		// Expect GetWalletById
 		testutils.MockGetWalletById(mock, wallet)
 
-		mock.ExpectQuery(fmt.Sprintf("SELECT to_ccy, rate FROM ccy_conversion WHERE from_ccy = '%s' AND to_ccy IN \\(.+\\)", models.BaseCcy)).
-			WithArgs(wallet.Currency).
-			WillReturnRows(sqlmock.NewRows([]string{"to_ccy", "rate"}).AddRow(wallet.Currency, decimal.NewFromFloat(0.92)))
+		// Expect GetAvailableBalancesByWalletIDs
+		testutils.MockGetAvailableBalances(mock, []models.Wallet{wallet}, nil)
+
+		// Expect GetPendingBalancesByWalletIDs
+		testutils.MockGetPendingBalances(mock, []models.Wallet{wallet}, nil)
+
+		testutils.MockGetCcyConversions(mock, testutils.MockCcyConversions()...)
 
 		// Prepare request
 		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/users/%d/wallets/balance?wallet_id=%d", userID, walletID), nil)
@@ -110,7 +130,99 @@ func TestHandleBalance_FilteredByWalletID(t *testing.T) {
 	})
 }
 
-func TestHandleBalance_NoCurrencyRateNoBalance(t *testing.T) {
+func TestHandleBalance_ReportingCcyOverride(t *testing.T) {
+
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+		userID := int64(1)
+		user := models.User{ID: userID, Name: "Danny", ReportingCurrency: "SGD", CreatedAt: time.Now().AddDate(-1, 0, 0)}
+		wallets := []models.Wallet{
+			{ID: 1001, UserId: userID, Balance: decimal.NewFromInt(100), Currency: "USD", Type: "saving", IsDefault: false},
+			{ID: 1002, UserId: userID, Balance: decimal.NewFromInt(27), Currency: "SGD", Type: "trading", IsDefault: true},
+		}
+
+		testutils.MockGetUserById(mock, user)
+		testutils.MockGetWalletByUserIDs(mock, wallets)
+		testutils.MockGetAvailableBalances(mock, wallets, nil)
+		testutils.MockGetPendingBalances(mock, wallets, nil)
+		testutils.MockGetCcyConversions(mock, testutils.MockCcyConversions()...)
+
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/users/%d/wallets/balance?reporting_ccy=usd", userID), nil)
+		req = mux.SetURLVars(req, map[string]string{"id": fmt.Sprintf("%d", userID)})
+
+		rec := httptest.NewRecorder()
+		handler := &handler.HandlerDB{DB: dbTest}
+
+		handler.HandleBalance(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response models.WalletBalanceResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &response)
+		require.NoError(t, err)
+
+		// The query parameter overrides the SGD reporting currency of the user
+		require.NotNil(t, response.Balance)
+		assert.Equal(t, "USD", response.Balance.Currency)
+		assert.True(t, response.Balance.Amount.Equal(decimal.NewFromInt(120)))
+		require.NotNil(t, response.Wallets[1].ReportingBalance)
+		assert.True(t, response.Wallets[1].ReportingBalance.Equal(decimal.NewFromInt(20)))
+	})
+}
+
+func TestHandleBalance_InvalidReportingCcy(t *testing.T) {
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+		req := httptest.NewRequest(http.MethodGet, "/users/1/wallets/balance?reporting_ccy=us", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+
+		rec := httptest.NewRecorder()
+		handler := &handler.HandlerDB{DB: dbTest}
+
+		handler.HandleBalance(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, models.ErrCodeValidationFailed, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
+
+func TestHandleBalance_StaleRateFlagged(t *testing.T) {
+
+	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
+		userID := int64(1)
+		walletID := int64(1002)
+		user := models.User{ID: userID, Name: "Alice", CreatedAt: time.Now().AddDate(-1, 0, 0)}
+		wallet := models.Wallet{ID: walletID, UserId: userID, Balance: decimal.NewFromInt(46), Currency: "EUR", Type: "secondary", IsDefault: false}
+		conversions := testutils.MockCcyConversions()
+		conversions[0].Age = 48 * time.Hour
+
+		testutils.MockGetUserById(mock, user)
+		testutils.MockGetWalletById(mock, wallet)
+		testutils.MockGetAvailableBalances(mock, []models.Wallet{wallet}, nil)
+		testutils.MockGetPendingBalances(mock, []models.Wallet{wallet}, nil)
+		testutils.MockGetCcyConversions(mock, conversions...)
+
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/users/%d/wallets/balance?wallet_id=%d", userID, walletID), nil)
+		req = mux.SetURLVars(req, map[string]string{"id": fmt.Sprintf("%d", userID)})
+
+		rec := httptest.NewRecorder()
+		handler := &handler.HandlerDB{DB: dbTest}
+
+		handler.HandleBalance(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response models.WalletBalanceResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &response)
+		require.NoError(t, err)
+
+		// The total is still computed, but flagged
+		require.NotNil(t, response.Balance)
+		assert.True(t, response.Balance.Amount.Equal(decimal.NewFromInt(50)))
+		assert.True(t, response.Balance.Stale)
+		assert.Equal(t, []string{"EUR"}, response.Balance.StaleCurrencies)
+	})
+}
+
+func TestHandleBalance_NoCurrencyRatePartialBalance(t *testing.T) {
 
 	testutils.WithDBMock(t, func(dbTest *sql.DB, mock sqlmock.Sqlmock) {
 
@@ -128,9 +240,13 @@ func TestHandleBalance_NoCurrencyRateNoBalance(t *testing.T) {
 		// Expect GetWalletByUserIDs
 		testutils.MockGetWalletByUserIDs(mock, wallets)
 
-		mock.ExpectQuery(fmt.Sprintf("SELECT to_ccy, rate FROM ccy_conversion WHERE from_ccy = '%s' AND to_ccy IN \\(.+\\)", models.BaseCcy)).
-			WithArgs(wallets[1].Currency).
-			WillReturnRows(sqlmock.NewRows([]string{"to_ccy", "rate"}))
+		// Expect GetAvailableBalancesByWalletIDs
+		testutils.MockGetAvailableBalances(mock, wallets, nil)
+
+		// Expect GetPendingBalancesByWalletIDs
+		testutils.MockGetPendingBalances(mock, wallets, nil)
+
+		testutils.MockGetCcyConversions(mock)
 
 		// Prepare request
 		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/users/%d/wallets/balance", userID), nil)
@@ -150,7 +266,13 @@ func TestHandleBalance_NoCurrencyRateNoBalance(t *testing.T) {
 
 		assert.Equal(t, userID, response.UserInfo.ID)
 		assert.Len(t, response.Wallets, 2)
-		assert.Nil(t, response.Balance)
+		assert.Nil(t, response.Wallets[1].ReportingBalance)
+
+		require.NotNil(t, response.Balance)
+		assert.True(t, response.Balance.Amount.Equal(decimal.NewFromInt(100)))
+		assert.True(t, response.Balance.Partial)
+		assert.Equal(t, []string{"EUR"}, response.Balance.UnpricedCurrencies)
+		assert.Equal(t, []int64{walletID}, response.Balance.UnpricedWallets)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
@@ -174,7 +296,9 @@ func TestHandleBalance_InvalidUserID(t *testing.T) {
 
 	// Assertions
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
-	assert.Contains(t, rec.Body.String(), "Invalid User ID")
+	errResp := testutils.DecodeErrorResponse(t, rec)
+	assert.Equal(t, models.ErrCodeValidationFailed, errResp.Code)
+	assert.Equal(t, "invalid user id", errResp.Message)
 }
 
 func TestHandleBalance_InvalidWalletId(t *testing.T) {
@@ -192,5 +316,7 @@ func TestHandleBalance_InvalidWalletId(t *testing.T) {
 	// Call handler
 	handler.HandleBalance(rec, req)
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
-	assert.Contains(t, rec.Body.String(), "Invalid Wallet ID")
+	errResp := testutils.DecodeErrorResponse(t, rec)
+	assert.Equal(t, models.ErrCodeValidationFailed, errResp.Code)
+	assert.Equal(t, "invalid wallet id", errResp.Message)
 }
@@ -0,0 +1,246 @@
+package handler_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/rudithu/CRYPTO-WalletApp/handler"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func batchWallet(id int64, userId int64) models.Wallet {
+	return models.Wallet{ID: id, UserId: userId, Balance: decimal.Zero, Currency: "USD", Type: "primary", IsDefault: true, CreatedAt: time.Now()}
+}
+
+func postBatchTransfer(db *sql.DB, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/wallets/1/batch-transfers", strings.NewReader(body))
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+
+	rec := httptest.NewRecorder()
+	handler := handler.HandlerDB{DB: db}
+	handler.HandleBatchTransfer(rec, req)
+	return rec
+}
+
+func TestHandleBatchTransfer_AllOrNothing(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		now := time.Now()
+
+		testutils.MockGetWalletById(mock, holdWallet())
+		testutils.MockGetWalletsByIDs(mock, []int64{2}, []models.Wallet{batchWallet(2, 2)})
+		testutils.MockGetWalletByUserIDs(mock, []models.Wallet{batchWallet(3, 3)})
+
+		testutils.MockGetFeeRules(mock, models.FeeTypeTransfer)
+
+		mock.ExpectBegin()
+		testutils.MockGetBalance(mock, decimal.NewFromFloat(100), 1)
+		testutils.MockReserveIDs(mock, "transfers", 55, 56)
+		mock.ExpectQuery("INSERT INTO transfers").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(55, now).AddRow(56, now))
+		testutils.MockReserveIDs(mock, "transactions", 101, 102, 103, 104)
+		mock.ExpectQuery("INSERT INTO transactions").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "completed_at"}).
+				AddRow(101, now, now).AddRow(102, now, now).AddRow(103, now, now).AddRow(104, now, now))
+		testutils.MockUpdateBalanceByWalletID(mock, decimal.NewFromFloat(50), 1)
+		mock.ExpectQuery("UPDATE wallets SET balance = wallets.balance \\+ v.amount").
+			WithArgs(int64(2), decimal.NewFromFloat(30), int64(3), decimal.NewFromFloat(20)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "balance"}).AddRow(2, decimal.NewFromFloat(30)).AddRow(3, decimal.NewFromFloat(20)))
+		mock.ExpectCommit()
+
+		rec := postBatchTransfer(db, `{"items": [
+			{"amount": 30, "destination_wallet_id": 2, "description": "salary"},
+			{"amount": 20, "destination_user_id": 3}
+		]}`)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var resp models.BatchTransferResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, models.BatchModeAllOrNothing, resp.Mode)
+		assert.Equal(t, 2, resp.Succeeded)
+		assert.Equal(t, 0, resp.Failed)
+		assert.True(t, resp.TotalAmount.Equal(decimal.NewFromFloat(50)))
+		require.NotNil(t, resp.BalanceAfter)
+		assert.True(t, resp.BalanceAfter.Equal(decimal.NewFromFloat(50)))
+		assert.Equal(t, int64(55), *resp.Items[0].TransferID)
+		assert.Equal(t, int64(3), *resp.Items[1].TargetWalletID)
+	})
+}
+
+func TestHandleBatchTransfer_ExchangeRecordsSpreadRevenue(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		now := time.Now()
+		targetWallet := batchWallet(2, 2)
+		targetWallet.Currency = "SGD"
+		// The sender buys SGD at the bid, 0.5% below the mid rate of 1.35
+		rate := decimal.NewFromFloat(1.35).Mul(decimal.NewFromFloat(99.5)).Div(decimal.NewFromInt(100))
+
+		testutils.MockGetWalletById(mock, holdWallet())
+		testutils.MockGetWalletsByIDs(mock, []int64{2}, []models.Wallet{targetWallet})
+		testutils.MockGetCcyConversions(mock, testutils.MockCcyConversions()...)
+		testutils.MockGetFxSpreadRules(mock, 1, testutils.MockFxSpreadRule())
+		testutils.MockGetFeeRules(mock, models.FeeTypeTransfer)
+
+		mock.ExpectBegin()
+		testutils.MockGetBalance(mock, decimal.NewFromFloat(100), 1)
+		testutils.MockReserveIDs(mock, "transfers", 55)
+		mock.ExpectQuery("INSERT INTO transfers").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(55, now))
+		testutils.MockReserveIDs(mock, "transactions", 101, 102)
+		mock.ExpectQuery("INSERT INTO transactions").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "completed_at"}).
+				AddRow(101, now, now).AddRow(102, now, now))
+		// 13.50 SGD at the mid rate, 13.43 received
+		testutils.MockReserveIDs(mock, "fx_spread_revenue", 7)
+		mock.ExpectQuery("INSERT INTO fx_spread_revenue").
+			WithArgs(int64(7), int64(101), "SGD", decimal.NewFromFloat(1.35), rate, decimal.NewFromFloat(0.07)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, now))
+		testutils.MockUpdateBalanceByWalletID(mock, decimal.NewFromFloat(90), 1)
+		mock.ExpectQuery("UPDATE wallets SET balance = wallets.balance \\+ v.amount").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "balance"}).AddRow(2, rate.Mul(decimal.NewFromInt(10))))
+		mock.ExpectCommit()
+
+		rec := postBatchTransfer(db, `{"items": [{"amount": 10, "destination_wallet_id": 2}]}`)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var resp models.BatchTransferResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, 1, resp.Succeeded)
+	})
+}
+
+func TestHandleBatchTransfer_ExchangeCreditsRoundedAmounts(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		now := time.Now()
+		targetWallet := batchWallet(2, 2)
+		targetWallet.Currency = "SGD"
+		rate := decimal.NewFromFloat(1.35).Mul(decimal.NewFromFloat(99.5)).Div(decimal.NewFromInt(100))
+
+		testutils.MockGetWalletById(mock, holdWallet())
+		testutils.MockGetWalletsByIDs(mock, []int64{2}, []models.Wallet{targetWallet})
+		testutils.MockGetCcyConversions(mock, testutils.MockCcyConversions()...)
+		testutils.MockGetFxSpreadRules(mock, 1, testutils.MockFxSpreadRule())
+		testutils.MockGetFeeRules(mock, models.FeeTypeTransfer)
+
+		mock.ExpectBegin()
+		testutils.MockGetBalance(mock, decimal.NewFromFloat(100), 1)
+		testutils.MockReserveIDs(mock, "transfers", 55, 56)
+		mock.ExpectQuery("INSERT INTO transfers").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(55, now).AddRow(56, now))
+		testutils.MockReserveIDs(mock, "transactions", 101, 102, 103, 104)
+		mock.ExpectQuery("INSERT INTO transactions").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "completed_at"}).
+				AddRow(101, now, now).AddRow(102, now, now).AddRow(103, now, now).AddRow(104, now, now))
+		// 13.5135 SGD at the mid rate, 13.4459325 rounded to the 13.45 received
+		testutils.MockReserveIDs(mock, "fx_spread_revenue", 7, 8)
+		mock.ExpectQuery("INSERT INTO fx_spread_revenue").
+			WithArgs(int64(7), int64(101), "SGD", decimal.NewFromFloat(1.35), rate, decimal.NewFromFloat(0.06),
+				int64(8), int64(102), "SGD", decimal.NewFromFloat(1.35), rate, decimal.NewFromFloat(0.06)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, now).AddRow(8, now))
+		testutils.MockUpdateBalanceByWalletID(mock, decimal.NewFromFloat(79.98), 1)
+		// The wallet is credited the sum of the stored transfer-in amounts
+		mock.ExpectQuery("UPDATE wallets SET balance = wallets.balance \\+ v.amount").
+			WithArgs(int64(2), decimal.NewFromFloat(26.9)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "balance"}).AddRow(2, decimal.NewFromFloat(26.9)))
+		mock.ExpectCommit()
+
+		rec := postBatchTransfer(db, `{"items": [{"amount": 10.01, "destination_wallet_id": 2}, {"amount": 10.01, "destination_wallet_id": 2}]}`)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var resp models.BatchTransferResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, 2, resp.Succeeded)
+		assert.True(t, resp.Items[0].TargetAmount.Equal(decimal.NewFromFloat(13.45)))
+	})
+}
+
+func TestHandleBatchTransfer_BestEffortUnknownWallet(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		now := time.Now()
+
+		testutils.MockGetWalletById(mock, holdWallet())
+		testutils.MockGetWalletsByIDs(mock, []int64{9, 2}, []models.Wallet{batchWallet(2, 2)})
+
+		testutils.MockGetFeeRules(mock, models.FeeTypeTransfer)
+
+		mock.ExpectBegin()
+		testutils.MockGetBalance(mock, decimal.NewFromFloat(100), 1)
+		testutils.MockReserveIDs(mock, "transfers", 55)
+		mock.ExpectQuery("INSERT INTO transfers").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(55, now))
+		testutils.MockReserveIDs(mock, "transactions", 101, 102)
+		mock.ExpectQuery("INSERT INTO transactions").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "completed_at"}).AddRow(101, now, now).AddRow(102, now, now))
+		testutils.MockUpdateBalanceByWalletID(mock, decimal.NewFromFloat(80), 1)
+		mock.ExpectQuery("UPDATE wallets SET balance = wallets.balance \\+ v.amount").
+			WithArgs(int64(2), decimal.NewFromFloat(20)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "balance"}).AddRow(2, decimal.NewFromFloat(20)))
+		mock.ExpectCommit()
+
+		rec := postBatchTransfer(db, `{"mode": "best_effort", "items": [
+			{"amount": 30, "destination_wallet_id": 9},
+			{"amount": 20, "destination_wallet_id": 2}
+		]}`)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var resp models.BatchTransferResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, 1, resp.Succeeded)
+		assert.Equal(t, 1, resp.Failed)
+		assert.Equal(t, models.BatchItemStatusFailed, resp.Items[0].Status)
+		require.NotNil(t, resp.Items[0].Error)
+		assert.Equal(t, models.ErrCodeWalletNotFound, resp.Items[0].Error.Code)
+		assert.Equal(t, models.BatchItemStatusSucceeded, resp.Items[1].Status)
+		assert.True(t, resp.TotalAmount.Equal(decimal.NewFromFloat(20)))
+	})
+}
+
+func TestHandleBatchTransfer_AllOrNothingUnknownWallet(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		testutils.MockGetWalletById(mock, holdWallet())
+		testutils.MockGetWalletsByIDs(mock, []int64{2, 9}, []models.Wallet{batchWallet(2, 2)})
+		testutils.MockGetFeeRules(mock, models.FeeTypeTransfer)
+
+		rec := postBatchTransfer(db, `{"mode": "all_or_nothing", "items": [
+			{"amount": 30, "destination_wallet_id": 2},
+			{"amount": 20, "destination_wallet_id": 9}
+		]}`)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		errResp := testutils.DecodeErrorResponse(t, rec)
+		assert.Equal(t, models.ErrCodeWalletNotFound, errResp.Code)
+		assert.Equal(t, "1", errResp.Details.(map[string]interface{})["item"])
+	})
+}
+
+func TestHandleBatchTransfer_InvalidItem(t *testing.T) {
+	rec := postBatchTransfer(nil, `{"items": [{"amount": 30, "destination_wallet_id": 2}, {"amount": -1, "destination_wallet_id": 3}]}`)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	errResp := testutils.DecodeErrorResponse(t, rec)
+	assert.Equal(t, models.ErrCodeValidationFailed, errResp.Code)
+	assert.True(t, strings.HasPrefix(errResp.Message, "items[1]: "))
+}
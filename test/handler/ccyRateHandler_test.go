@@ -0,0 +1,107 @@
+package handler_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/rudithu/CRYPTO-WalletApp/handler"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func putCcyRate(db *sql.DB, from string, to string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPut, "/fx/rates/"+from+"/"+to, strings.NewReader(body))
+	req = mux.SetURLVars(req, map[string]string{"from": from, "to": to})
+
+	rec := httptest.NewRecorder()
+	handler := handler.HandlerDB{DB: db}
+	handler.HandleUpsertCcyRate(rec, req)
+	return rec
+}
+
+func TestHandleListCcyRates_FlagsStaleRates(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+		conversions := testutils.MockCcyConversions()
+		conversions[1].Age = 48 * time.Hour
+		testutils.MockGetCcyConversions(mock, conversions...)
+
+		req := httptest.NewRequest(http.MethodGet, "/fx/rates", nil)
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleListCcyRates(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.CcyRatesResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		require.Equal(t, 2, len(resp.Rates))
+		assert.Equal(t, "EUR", resp.Rates[0].ToCcy)
+		assert.False(t, resp.Rates[0].Stale)
+		assert.Equal(t, "SGD", resp.Rates[1].ToCcy)
+		assert.True(t, resp.Rates[1].Stale)
+		assert.Equal(t, "24h0m0s", resp.Rates[1].MaxAge)
+	})
+}
+
+func TestHandleUpsertCcyRate_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+		now := time.Now()
+		mock.ExpectQuery("INSERT INTO ccy_conversion \\(from_ccy, to_ccy, rate, created_at\\) .+ ON CONFLICT \\(from_ccy, to_ccy\\) DO UPDATE").
+			WithArgs("EUR", "BTC", decimal.RequireFromString("0.000011")).
+			WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(now))
+
+		rec := putCcyRate(db, "eur", "BTC", `{"rate": "0.000011"}`)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.CcyRateResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, "EUR", resp.FromCcy)
+		assert.Equal(t, "BTC", resp.ToCcy)
+		assert.True(t, resp.Rate.Equal(decimal.RequireFromString("0.000011")))
+		assert.False(t, resp.Stale)
+	})
+}
+
+func TestHandleUpsertCcyRate_InvalidRate(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+		rec := putCcyRate(db, "USD", "SGD", `{"rate": "0"}`)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, models.ErrCodeValidationFailed, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
+
+func TestHandleUpsertCcyRate_TooManyDecimals(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+		// Would be stored as zero
+		rec := putCcyRate(db, "USD", "BTC", `{"rate": "0.00000000001"}`)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		errResp := testutils.DecodeErrorResponse(t, rec)
+		assert.Equal(t, models.ErrCodeValidationFailed, errResp.Code)
+		assert.Equal(t, "rate must not have more than 10 decimals", errResp.Message)
+	})
+}
+
+func TestHandleUpsertCcyRate_SameCurrency(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+		rec := putCcyRate(db, "USD", "usd", `{"rate": "1"}`)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, models.ErrCodeValidationFailed, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
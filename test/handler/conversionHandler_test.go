@@ -0,0 +1,156 @@
+package handler_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/rudithu/CRYPTO-WalletApp/handler"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func conversionWallets() []models.Wallet {
+	return []models.Wallet{
+		{ID: 301, UserId: 7, Balance: decimal.NewFromInt(500), Currency: "USD", Type: "saving", IsDefault: true, CreatedAt: time.Now()},
+		{ID: 302, UserId: 7, Balance: decimal.NewFromInt(10), Currency: "SGD", Type: "trading", CreatedAt: time.Now()},
+	}
+}
+
+func postConversion(db *sql.DB, userId string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/users/"+userId+"/conversions", strings.NewReader(body))
+	req = mux.SetURLVars(req, map[string]string{"id": userId})
+
+	rec := httptest.NewRecorder()
+	handler := handler.HandlerDB{DB: db}
+	handler.HandleCreateConversion(rec, req)
+	return rec
+}
+
+func TestHandleCreateConversion_Sell_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		wallets := conversionWallets()
+		from, to := wallets[0], wallets[1]
+		now := time.Now()
+
+		testutils.MockGetWalletsByIDs(mock, []int64{from.ID, to.ID}, wallets)
+		testutils.MockGetCcyConversions(mock, testutils.MockCcyConversions()...)
+		testutils.MockGetFxSpreadRules(mock, 7, testutils.MockFxSpreadRule())
+		testutils.MockGetFeeRules(mock, models.FeeTypeConversion)
+
+		// 100 USD at 1.35 less the 0.5% bid spread
+		toAmount := decimal.NewFromFloat(134.32)
+		rate := decimal.NewFromFloat(1.34325)
+
+		mock.ExpectBegin()
+		testutils.MockGetBalance(mock, from.Balance, from.ID)
+		testutils.MockCreateTransaction(mock, models.Transaction{ID: 501, WalletId: from.ID, Type: models.TxnTypeConversionOut,
+			Amount: decimal.NewFromInt(100), CreatedAt: now})
+		testutils.MockUpdateBalanceByWalletID(mock, decimal.NewFromInt(400), from.ID)
+		testutils.MockCreateTransaction(mock, models.Transaction{ID: 502, WalletId: to.ID, Type: models.TxnTypeConversionIn,
+			Amount: toAmount, CreatedAt: now})
+		testutils.MockIncrementBalanceByWalletID(mock, toAmount, to.ID, to.Balance.Add(toAmount))
+		testutils.MockCreateSpreadRevenue(mock, 501, models.SpreadRevenue{Currency: "SGD", MidRate: decimal.NewFromFloat(1.35),
+			Rate: rate, Amount: decimal.NewFromFloat(0.68)})
+		mock.ExpectQuery("INSERT INTO conversions").
+			WithArgs(int64(7), from.ID, to.ID, decimal.NewFromInt(100), toAmount, sqlmock.AnyArg(), sqlmock.AnyArg(),
+				sqlmock.AnyArg(), decimal.NewFromFloat(0.68), int64(501), int64(502)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(61, now))
+		mock.ExpectCommit()
+
+		rec := postConversion(db, "7", `{"from_wallet_id": 301, "to_wallet_id": 302, "from_amount": 100}`)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var resp models.ConversionResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, int64(61), resp.ID)
+		assert.True(t, resp.ToAmount.Equal(toAmount))
+		assert.True(t, resp.MidRate.Equal(decimal.NewFromFloat(1.35)))
+		assert.True(t, resp.Rate.Equal(rate))
+		assert.Equal(t, models.TxnTypeConversionOut, resp.ConversionOut.Type)
+		assert.True(t, resp.ConversionOut.Balance.Equal(decimal.NewFromInt(400)))
+		assert.Equal(t, models.TxnTypeConversionIn, resp.ConversionIn.Type)
+	})
+}
+
+func TestHandleCreateConversion_WalletOfOtherUser(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		wallets := conversionWallets()
+		wallets[1].UserId = 8
+
+		testutils.MockGetWalletsByIDs(mock, []int64{301, 302}, wallets)
+
+		rec := postConversion(db, "7", `{"from_wallet_id": 301, "to_wallet_id": 302, "to_amount": 100}`)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, models.ErrCodeWalletNotFound, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
+
+func TestHandleCreateConversion_SameCurrency(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		wallets := conversionWallets()
+		wallets[1].Currency = "USD"
+
+		testutils.MockGetWalletsByIDs(mock, []int64{301, 302}, wallets)
+
+		rec := postConversion(db, "7", `{"from_wallet_id": 301, "to_wallet_id": 302, "from_amount": 100}`)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, models.ErrCodeValidationFailed, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
+
+func TestHandleCreateConversion_BothAmounts(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		rec := postConversion(db, "7", `{"from_wallet_id": 301, "to_wallet_id": 302, "from_amount": 100, "to_amount": 135}`)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, models.ErrCodeValidationFailed, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
+
+func TestHandleCreateConversion_TooManyDecimals(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		rec := postConversion(db, "7", `{"from_wallet_id": 301, "to_wallet_id": 302, "to_amount": 0.001}`)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		errResp := testutils.DecodeErrorResponse(t, rec)
+		assert.Equal(t, models.ErrCodeValidationFailed, errResp.Code)
+		assert.Equal(t, "to_amount must not have more than 2 decimals", errResp.Message)
+	})
+}
+
+func TestHandleCreateConversion_ReceivedAmountRoundsToZero(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		wallets := conversionWallets()
+		testutils.MockGetWalletsByIDs(mock, []int64{301, 302}, wallets)
+		testutils.MockGetCcyConversions(mock, models.CcyConversion{FromCcy: "USD", ToCcy: "SGD", Rate: decimal.NewFromFloat(0.5), CreatedAt: time.Now()})
+		testutils.MockGetFxSpreadRules(mock, 7, testutils.MockFxSpreadRule())
+
+		// 0.01 USD at about 0.5 SGD is less than a cent
+		rec := postConversion(db, "7", `{"from_wallet_id": 301, "to_wallet_id": 302, "from_amount": 0.01}`)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		errResp := testutils.DecodeErrorResponse(t, rec)
+		assert.Equal(t, models.ErrCodeValidationFailed, errResp.Code)
+		assert.Equal(t, "amount is too small to convert", errResp.Message)
+	})
+}
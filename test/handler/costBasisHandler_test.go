@@ -0,0 +1,220 @@
+package handler_test
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/rudithu/CRYPTO-WalletApp/handler"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var costBasisDay = time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// mockBtcCostBasis expects the transactions of the BTC wallet and the rates since the first of them:
+// 1 BTC bought at 50000 USD and 1 BTC at 100000 USD, then 1.5 BTC sold at 40000 USD.
+func mockBtcCostBasis(mock sqlmock.Sqlmock, walletId int64) {
+	testutils.MockGetCostBasisTransactions(mock, walletId,
+		models.CostBasisTxn{ID: 1, Type: models.TxnTypeDeposit, Amount: decimal.NewFromInt(1), At: costBasisDay.Add(time.Hour)},
+		models.CostBasisTxn{ID: 2, Type: models.TxnTypeDeposit, Amount: decimal.NewFromInt(1), At: costBasisDay.AddDate(0, 0, 1).Add(time.Hour)},
+		models.CostBasisTxn{ID: 3, Type: models.TxnTypeWithdraw, Amount: decimal.NewFromFloat(1.5), At: costBasisDay.AddDate(0, 0, 2).Add(time.Hour)},
+	)
+
+	// the history is read up to now
+	rows := sqlmock.NewRows([]string{"from_ccy", "to_ccy", "rate", "created_at"}).
+		AddRow("BTC", "USD", decimal.NewFromInt(50000), costBasisDay).
+		AddRow("BTC", "USD", decimal.NewFromInt(100000), costBasisDay.AddDate(0, 0, 1)).
+		AddRow("BTC", "USD", decimal.NewFromInt(40000), costBasisDay.AddDate(0, 0, 2))
+	mock.ExpectQuery("SELECT h.from_ccy, h.to_ccy, h.rate, h.created_at FROM ccy_conversion_history h").
+		WithArgs(costBasisDay.Add(time.Hour), sqlmock.AnyArg()).
+		WillReturnRows(rows)
+}
+
+func TestHandleWalletCostBasis_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		user := testutils.MockUserModel()
+		wallet := models.Wallet{ID: 7, UserId: user.ID, Currency: "BTC", Type: "trading", Balance: decimal.NewFromFloat(0.5)}
+
+		testutils.MockGetWalletById(mock, wallet)
+		testutils.MockGetUserById(mock, user)
+		mockBtcCostBasis(mock, wallet.ID)
+
+		req := httptest.NewRequest(http.MethodGet, "/wallets/7/cost-basis", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "7"})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleWalletCostBasis(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.WalletCostBasisResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, models.CostBasisFIFO, resp.Method)
+		assert.Equal(t, models.BaseCcy, resp.ReportingCurrency)
+		assert.True(t, resp.Quantity.Equal(decimal.NewFromFloat(0.5)))
+		assert.True(t, resp.CostBasis.Equal(decimal.NewFromInt(50000)))
+		assert.True(t, resp.RealizedGain.Equal(decimal.NewFromInt(-40000)))
+		require.NotNil(t, resp.MarketValue)
+		assert.True(t, resp.MarketValue.Equal(decimal.NewFromInt(20000)))
+		require.NotNil(t, resp.UnrealizedGain)
+		assert.True(t, resp.UnrealizedGain.Equal(decimal.NewFromInt(-30000)))
+		require.Len(t, resp.Lots, 1)
+		assert.Equal(t, int64(2), resp.Lots[0].TransactionID)
+	})
+}
+
+func TestHandleWalletCostBasis_NotCryptoWallet(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		wallet := testutils.MockWallets()[0]
+		testutils.MockGetWalletById(mock, wallet)
+
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/wallets/%d/cost-basis", wallet.ID), nil)
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(wallet.ID, 10)})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleWalletCostBasis(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, models.ErrCodeValidationFailed, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
+
+func TestHandleWalletCostBasis_InvalidMethod(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		req := httptest.NewRequest(http.MethodGet, "/wallets/7/cost-basis?method=hifo", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "7"})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleWalletCostBasis(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, models.ErrCodeValidationFailed, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
+
+func TestHandleGainsReport_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		user := testutils.MockUserModel()
+		wallets := []models.Wallet{
+			{ID: 1, UserId: user.ID, Currency: "USD", Type: "saving", IsDefault: true},
+			{ID: 7, UserId: user.ID, Currency: "BTC", Type: "trading"},
+		}
+
+		testutils.MockGetUserById(mock, user)
+		testutils.MockGetWalletByUserIDs(mock, wallets)
+		mockBtcCostBasis(mock, wallets[1].ID)
+
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/users/%d/gains?year=2025&method=lifo", user.ID), nil)
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(user.ID, 10)})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleGainsReport(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.GainsReportResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, 2025, resp.Year)
+		assert.Equal(t, models.CostBasisLIFO, resp.Method)
+		require.Len(t, resp.Disposals, 1)
+		assert.Equal(t, int64(7), resp.Disposals[0].WalletID)
+		assert.True(t, resp.Disposals[0].Quantity.Equal(decimal.NewFromFloat(1.5)))
+		assert.True(t, resp.TotalProceeds.Equal(decimal.NewFromInt(60000)))
+		assert.True(t, resp.TotalCostBasis.Equal(decimal.NewFromInt(125000)))
+		assert.True(t, resp.TotalGain.Equal(decimal.NewFromInt(-65000)))
+	})
+}
+
+func TestHandleGainsReport_OtherYear(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		user := testutils.MockUserModel()
+		wallets := []models.Wallet{{ID: 7, UserId: user.ID, Currency: "BTC", Type: "trading"}}
+
+		testutils.MockGetUserById(mock, user)
+		testutils.MockGetWalletByUserIDs(mock, wallets)
+		mockBtcCostBasis(mock, wallets[0].ID)
+
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/users/%d/gains?year=2024", user.ID), nil)
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(user.ID, 10)})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleGainsReport(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.GainsReportResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Empty(t, resp.Disposals)
+		assert.True(t, resp.TotalGain.IsZero())
+	})
+}
+
+func TestHandleGainsReport_CSV(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		user := testutils.MockUserModel()
+		wallets := []models.Wallet{{ID: 7, UserId: user.ID, Currency: "BTC", Type: "trading"}}
+
+		testutils.MockGetUserById(mock, user)
+		testutils.MockGetWalletByUserIDs(mock, wallets)
+		mockBtcCostBasis(mock, wallets[0].ID)
+
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/users/%d/gains?year=2025&format=csv", user.ID), nil)
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(user.ID, 10)})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleGainsReport(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
+		assert.Contains(t, rec.Header().Get("Content-Disposition"), fmt.Sprintf("gains-%d-2025.csv", user.ID))
+
+		records, err := csv.NewReader(rec.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		assert.Equal(t, "wallet_id", records[0][0])
+		assert.Equal(t, []string{"7", "BTC", "3", models.TxnTypeWithdraw, "2025-03-01T01:00:00Z", "2025-03-03T01:00:00Z",
+			"1.5", "60000.00", "100000.00", "-40000.00", models.BaseCcy}, records[1])
+	})
+}
+
+func TestHandleGainsReport_InvalidYear(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		req := httptest.NewRequest(http.MethodGet, "/users/101/gains?year=next", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "101"})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleGainsReport(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, models.ErrCodeValidationFailed, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
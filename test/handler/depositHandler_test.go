@@ -2,6 +2,7 @@ package handler_test
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -16,6 +17,7 @@ import (
 	"github.com/rudithu/CRYPTO-WalletApp/models"
 	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHandleDepositMoney_Succes(t *testing.T) {
@@ -26,14 +28,16 @@ func TestHandleDepositMoney_Succes(t *testing.T) {
 
 		txn := testutils.MockTxns()[0]
 		txn.Type = models.TxnTypeDeposit
+		wallet := testutils.MockWallets()[0]
+
+		testutils.MockGetWalletById(mock, wallet)
 
 		mock.ExpectBegin()
-		mock.ExpectQuery("INSERT INTO transactions \\(wallet_id, type, amount, counterparty_wallet_id\\) VALUES \\(\\$1, \\$2, \\$3, \\$4\\) RETURNING id, created_at").
-			WithArgs(txn.WalletId, txn.Type, txn.Amount, txn.CounterpartyWalletId).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(txn.ID, txn.CreatedAt))
+		mock.ExpectQuery("INSERT INTO transactions \\(wallet_id, type, amount, counterparty_wallet_id, description, external_reference, transfer_id, status, rate, completed_at\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7, \\$8, \\$9, .+\\) RETURNING id, created_at, completed_at").
+			WithArgs(txn.WalletId, txn.Type, txn.Amount, txn.CounterpartyWalletId, txn.Description, txn.ExternalReference, txn.TransferId, models.TxnStatusCompleted, txn.Rate).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "completed_at"}).AddRow(txn.ID, txn.CreatedAt, txn.CreatedAt))
 
-		mock.ExpectExec("UPDATE wallets SET balance = balance \\+ \\$1 WHERE id = \\$2").
-			WithArgs(txn.Amount, txn.WalletId).WillReturnResult(sqlmock.NewResult(10, 1))
+		testutils.MockIncrementBalanceByWalletID(mock, txn.Amount, txn.WalletId, wallet.Balance.Add(txn.Amount))
 
 		mock.ExpectCommit()
 
@@ -45,7 +49,17 @@ func TestHandleDepositMoney_Succes(t *testing.T) {
 		rr := httptest.NewRecorder()
 		handler.HandleDepositMoney(rr, req)
 
-		assert.Equal(t, http.StatusNoContent, rr.Code)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, fmt.Sprintf("/transactions/%d", txn.ID), rr.Header().Get("Location"))
+
+		var resp models.TransactionResponse
+		err := json.Unmarshal(rr.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, txn.ID, resp.ID)
+		assert.Equal(t, wallet.Currency, resp.Currency)
+		assert.Equal(t, models.TxnTypeDeposit, resp.Type)
+		require.NotNil(t, resp.Balance)
+		assert.True(t, resp.Balance.Equal(wallet.Balance.Add(txn.Amount)))
 
 	})
 
@@ -59,9 +73,35 @@ func TestHandleDepositMoney_WalletNotExist(t *testing.T) {
 		txn := testutils.MockTxns()[0]
 		txn.Type = models.TxnTypeDeposit
 
+		testutils.MockGetWalletByIdNoRecord(mock, txn.WalletId)
+
+		requestBody := fmt.Sprintf(`{"amount": %s}`, txn.Amount.String())
+
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/wallets/%d/deposit", txn.WalletId), strings.NewReader(requestBody))
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(txn.WalletId, 10)})
+
+		rr := httptest.NewRecorder()
+		handler.HandleDepositMoney(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		errResp := testutils.DecodeErrorResponse(t, rr)
+		assert.Equal(t, models.ErrCodeWalletNotFound, errResp.Code)
+	})
+}
+
+func TestHandleDepositMoney_DBFailed(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		handler := handler.HandlerDB{DB: db}
+
+		txn := testutils.MockTxns()[0]
+		txn.Type = models.TxnTypeDeposit
+
+		testutils.MockGetWalletById(mock, testutils.MockWallets()[0])
+
 		mock.ExpectBegin()
-		mock.ExpectQuery("INSERT INTO transactions \\(wallet_id, type, amount, counterparty_wallet_id\\) VALUES \\(\\$1, \\$2, \\$3, \\$4\\) RETURNING id, created_at").
-			WithArgs(txn.WalletId, txn.Type, txn.Amount, txn.CounterpartyWalletId).WillReturnError(errors.New("wallet id not exist"))
+		mock.ExpectQuery("INSERT INTO transactions \\(wallet_id, type, amount, counterparty_wallet_id, description, external_reference, transfer_id, status, rate, completed_at\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7, \\$8, \\$9, .+\\) RETURNING id, created_at, completed_at").
+			WithArgs(txn.WalletId, txn.Type, txn.Amount, txn.CounterpartyWalletId, txn.Description, txn.ExternalReference, txn.TransferId, models.TxnStatusCompleted, txn.Rate).WillReturnError(errors.New("wallet id not exist"))
 		mock.ExpectRollback()
 
 		requestBody := fmt.Sprintf(`{"amount": %s}`, txn.Amount.String())
@@ -73,6 +113,8 @@ func TestHandleDepositMoney_WalletNotExist(t *testing.T) {
 		handler.HandleDepositMoney(rr, req)
 
 		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		errResp := testutils.DecodeErrorResponse(t, rr)
+		assert.Equal(t, models.ErrCodeInternal, errResp.Code)
 
 	})
 }
@@ -93,3 +135,54 @@ func TestHandleDepositMoney_InvalidWalletId(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, rr.Code)
 
 }
+
+func TestHandleDepositMoney_Pending(t *testing.T) {
+
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		handler := handler.HandlerDB{DB: db}
+
+		txn := testutils.MockTxns()[0]
+		txn.Type = models.TxnTypeDeposit
+		wallet := testutils.MockWallets()[0]
+
+		testutils.MockGetWalletById(mock, wallet)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO transactions").
+			WithArgs(txn.WalletId, txn.Type, txn.Amount, txn.CounterpartyWalletId, txn.Description, txn.ExternalReference, txn.TransferId, models.TxnStatusPending, txn.Rate).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "completed_at"}).AddRow(txn.ID, txn.CreatedAt, nil))
+		mock.ExpectCommit()
+
+		requestBody := fmt.Sprintf(`{"amount": %s, "status": "pending"}`, txn.Amount.String())
+
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/wallets/%d/deposit", txn.WalletId), strings.NewReader(requestBody))
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(txn.WalletId, 10)})
+
+		rr := httptest.NewRecorder()
+		handler.HandleDepositMoney(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+
+		var resp models.TransactionResponse
+		err := json.Unmarshal(rr.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, models.TxnStatusPending, resp.Status)
+		assert.Nil(t, resp.Balance)
+		assert.Nil(t, resp.CompletedAt)
+	})
+}
+
+func TestHandleDepositMoney_InvalidStatus(t *testing.T) {
+	handler := handler.HandlerDB{DB: nil}
+
+	req := httptest.NewRequest(http.MethodPost, "/wallets/1/deposit", strings.NewReader(`{"amount": 10, "status": "failed"}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+
+	rr := httptest.NewRecorder()
+	handler.HandleDepositMoney(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	errResp := testutils.DecodeErrorResponse(t, rr)
+	assert.Equal(t, models.ErrCodeValidationFailed, errResp.Code)
+}
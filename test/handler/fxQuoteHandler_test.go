@@ -0,0 +1,130 @@
+package handler_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/rudithu/CRYPTO-WalletApp/handler"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleCreateFxQuote_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		now := time.Now()
+		midRate := decimal.NewFromFloat(1).Div(decimal.NewFromFloat(1.35))
+		rule := testutils.MockFxSpreadRule()
+		rate := midRate.Mul(decimal.NewFromFloat(99.5)).Div(decimal.NewFromInt(100))
+
+		testutils.MockGetUserById(mock, models.User{ID: 7, Name: "Alice", CreatedAt: now})
+		testutils.MockGetCcyConversions(mock, testutils.MockCcyConversions()...)
+		testutils.MockGetFxSpreadRules(mock, 7, rule)
+		mock.ExpectQuery("INSERT INTO fx_quotes").
+			WithArgs(int64(7), "SGD", "USD", rate, midRate, models.FxSideBid, rule.BidSpread, "SGD>USD", models.FxQuoteStatusOpen, int64(models.DefaultFxQuoteTTLSeconds)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "expires_at", "created_at"}).AddRow(81, now.Add(time.Minute), now))
+
+		req := httptest.NewRequest(http.MethodPost, "/fx/quotes", strings.NewReader(`{"from_ccy": "sgd", "to_ccy": "USD", "user_id": 7}`))
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleCreateFxQuote(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "/fx/quotes/81", rec.Header().Get("Location"))
+
+		var resp models.FxQuoteResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, int64(81), resp.ID)
+		assert.Equal(t, int64(7), resp.UserID)
+		assert.Equal(t, "SGD", resp.FromCcy)
+		assert.Equal(t, models.FxQuoteStatusOpen, resp.Status)
+		assert.True(t, rate.Equal(resp.Rate))
+		assert.True(t, midRate.Equal(resp.MidRate))
+		assert.Equal(t, models.FxSideBid, resp.Side)
+	})
+}
+
+func TestHandleCreateFxQuote_SameCurrency(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		req := httptest.NewRequest(http.MethodPost, "/fx/quotes", strings.NewReader(`{"from_ccy": "USD", "to_ccy": "usd"}`))
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleCreateFxQuote(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, models.ErrCodeValidationFailed, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
+
+func TestHandleCreateFxQuote_UserMissing(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		req := httptest.NewRequest(http.MethodPost, "/fx/quotes", strings.NewReader(`{"from_ccy": "SGD", "to_ccy": "USD"}`))
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleCreateFxQuote(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, models.ErrCodeValidationFailed, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
+
+func TestHandleGetFxQuote_Used(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		quote := testutils.MockFxQuote()
+		quote.Status = models.FxQuoteStatusUsed
+		quote.TransferId = sql.NullInt64{Int64: 31, Valid: true}
+		quote.UsedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		testutils.MockGetFxQuoteById(mock, quote, true)
+
+		req := httptest.NewRequest(http.MethodGet, "/fx/quotes/81", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "81"})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleGetFxQuote(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.FxQuoteResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		// A used quote stays used after its expiry
+		assert.Equal(t, models.FxQuoteStatusUsed, resp.Status)
+		require.NotNil(t, resp.TransferID)
+		assert.Equal(t, int64(31), *resp.TransferID)
+	})
+}
+
+func TestHandleGetFxQuote_NotFound(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		testutils.MockGetFxQuoteByIdNoRecord(mock, int64(99))
+
+		req := httptest.NewRequest(http.MethodGet, "/fx/quotes/99", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "99"})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleGetFxQuote(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, models.ErrCodeQuoteNotFound, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
@@ -0,0 +1,58 @@
+package handler_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rudithu/CRYPTO-WalletApp/handler"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleSpreadRevenue_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		mock.ExpectQuery("SELECT currency, COUNT\\(\\*\\), SUM\\(amount\\) FROM fx_spread_revenue").
+			WillReturnRows(sqlmock.NewRows([]string{"currency", "count", "sum"}).AddRow("SGD", 3, decimal.NewFromFloat(2.04)))
+
+		req := httptest.NewRequest(http.MethodGet, "/fx/spread-revenue", nil)
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleSpreadRevenue(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.SpreadRevenueResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(resp.Totals))
+		assert.Equal(t, "SGD", resp.Totals[0].Currency)
+		assert.Equal(t, int64(3), resp.Totals[0].Count)
+		assert.True(t, resp.Totals[0].Amount.Equal(decimal.NewFromFloat(2.04)))
+	})
+}
+
+func TestHandleSpreadRevenue_Empty(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		mock.ExpectQuery("SELECT currency, COUNT\\(\\*\\), SUM\\(amount\\) FROM fx_spread_revenue").
+			WillReturnRows(sqlmock.NewRows([]string{"currency", "count", "sum"}))
+
+		req := httptest.NewRequest(http.MethodGet, "/fx/spread-revenue", nil)
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleSpreadRevenue(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"totals": []}`, rec.Body.String())
+	})
+}
@@ -0,0 +1,173 @@
+package handler_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/rudithu/CRYPTO-WalletApp/handler"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func holdWallet() models.Wallet {
+	return models.Wallet{
+		ID:        1,
+		UserId:    1,
+		Balance:   decimal.NewFromFloat(100),
+		Currency:  "USD",
+		Type:      "primary",
+		IsDefault: true,
+		CreatedAt: time.Now(),
+	}
+}
+
+func TestHandleCreateHold_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		wallet := holdWallet()
+		now := time.Now()
+
+		testutils.MockGetWalletById(mock, wallet)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT balance, .+ FROM wallets WHERE id = \\$1 FOR UPDATE").
+			WithArgs(wallet.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"balance", "available_balance"}).AddRow(wallet.Balance, wallet.Balance))
+		mock.ExpectQuery("INSERT INTO holds").
+			WithArgs(wallet.ID, decimal.NewFromFloat(40), models.HoldStatusActive, sqlmock.AnyArg(), int64(600)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "expires_at", "created_at", "updated_at"}).AddRow(51, now.Add(10*time.Minute), now, now))
+		mock.ExpectCommit()
+
+		requestBody := `{"amount": 40, "description": "card authorisation", "expires_in_seconds": 600}`
+		req := httptest.NewRequest(http.MethodPost, "/wallets/1/holds", strings.NewReader(requestBody))
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleCreateHold(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "/holds/51", rec.Header().Get("Location"))
+
+		var resp models.HoldResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, int64(51), resp.ID)
+		assert.Equal(t, "USD", resp.Currency)
+		assert.Equal(t, models.HoldStatusActive, resp.Status)
+		assert.Equal(t, "card authorisation", resp.Description)
+		assert.NotNil(t, resp.ExpiresAt)
+	})
+}
+
+func TestHandleCreateHold_InvalidAmount(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/wallets/1/holds", strings.NewReader(`{"amount": 0}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+
+	rec := httptest.NewRecorder()
+	handler := handler.HandlerDB{DB: nil}
+	handler.HandleCreateHold(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	errResp := testutils.DecodeErrorResponse(t, rec)
+	assert.Equal(t, models.ErrCodeValidationFailed, errResp.Code)
+}
+
+func TestHandleCaptureHold_FullAmount(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		wallet := holdWallet()
+		hold := testutils.MockHold()
+
+		testutils.MockGetHoldById(mock, hold)
+		testutils.MockGetWalletById(mock, wallet)
+
+		mock.ExpectBegin()
+		testutils.MockLockActiveHold(mock, hold, false)
+		mock.ExpectQuery("UPDATE holds SET status = \\$1, captured_amount = \\$2").
+			WithArgs(models.HoldStatusCaptured, hold.Amount, hold.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+		testutils.MockGetBalance(mock, wallet.Balance, wallet.ID)
+		testutils.MockCreateTransaction(mock, models.Transaction{ID: 123, WalletId: wallet.ID, Type: models.TxnTypeWithdraw, Amount: hold.Amount, CreatedAt: time.Now()})
+		testutils.MockUpdateBalanceByWalletID(mock, decimal.NewFromFloat(60), wallet.ID)
+		mock.ExpectExec("UPDATE holds SET transaction_id = \\$1 WHERE id = \\$2").
+			WithArgs(int64(123), hold.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		req := httptest.NewRequest(http.MethodPost, "/holds/51/capture", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "51"})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleCaptureHold(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "/transactions/123", rec.Header().Get("Location"))
+
+		var resp models.HoldCaptureResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, models.HoldStatusCaptured, resp.Hold.Status)
+		require.NotNil(t, resp.Hold.CapturedAmount)
+		assert.True(t, resp.Hold.CapturedAmount.Equal(hold.Amount))
+		assert.Equal(t, int64(123), *resp.Hold.TransactionID)
+		assert.Equal(t, int64(123), resp.Transaction.ID)
+		assert.Equal(t, "capture of hold 51", resp.Transaction.Description)
+	})
+}
+
+func TestHandleReleaseHold_NotActive(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		wallet := holdWallet()
+		hold := testutils.MockHold()
+		hold.Status = models.HoldStatusReleased
+
+		testutils.MockGetHoldById(mock, hold)
+		testutils.MockGetWalletById(mock, wallet)
+
+		mock.ExpectBegin()
+		testutils.MockLockActiveHold(mock, hold, false)
+		mock.ExpectRollback()
+
+		req := httptest.NewRequest(http.MethodPost, "/holds/51/release", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "51"})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleReleaseHold(rec, req)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		errResp := testutils.DecodeErrorResponse(t, rec)
+		assert.Equal(t, models.ErrCodeHoldNotActive, errResp.Code)
+	})
+}
+
+func TestHandleGetHold_NotFound(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		testutils.MockGetHoldByIdNoRecord(mock, int64(77))
+
+		req := httptest.NewRequest(http.MethodGet, "/holds/77", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "77"})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleGetHold(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		errResp := testutils.DecodeErrorResponse(t, rec)
+		assert.Equal(t, models.ErrCodeHoldNotFound, errResp.Code)
+	})
+}
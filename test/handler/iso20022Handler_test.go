@@ -0,0 +1,233 @@
+package handler_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/rudithu/CRYPTO-WalletApp/handler"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var dailyStatementDay = time.Date(2025, 5, 3, 0, 0, 0, 0, time.UTC)
+
+func dailyStatementRequest(walletId string, date string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/wallets/"+walletId+"/statements/daily/"+date, nil)
+	return mux.SetURLVars(req, map[string]string{"id": walletId, "date": date})
+}
+
+func postPain001(db *sql.DB, query string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/wallets/1/batch-transfers/pain001"+query, strings.NewReader(body))
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+
+	rec := httptest.NewRecorder()
+	handler := handler.HandlerDB{DB: db}
+	handler.HandlePain001Upload(rec, req)
+	return rec
+}
+
+// painFile is a pain.001.001.09 file paying 30.50 USD to wallet 2 and 20 USD to wallet 3 from wallet 1 today.
+func painFile() string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.001.001.09">
+  <CstmrCdtTrfInitn>
+    <GrpHdr><MsgId>MSG-1</MsgId><CreDtTm>2025-06-02T08:00:00Z</CreDtTm><NbOfTxs>2</NbOfTxs><CtrlSum>50.50</CtrlSum></GrpHdr>
+    <PmtInf>
+      <PmtInfId>PMT-1</PmtInfId>
+      <PmtMtd>TRF</PmtMtd>
+      <ReqdExctnDt><Dt>%s</Dt></ReqdExctnDt>
+      <DbtrAcct><Id><Othr><Id>1</Id></Othr></Id></DbtrAcct>
+      <CdtTrfTxInf>
+        <PmtId><EndToEndId>E2E-1</EndToEndId></PmtId>
+        <Amt><InstdAmt Ccy="USD">30.50</InstdAmt></Amt>
+        <CdtrAcct><Id><Othr><Id>2</Id></Othr></Id></CdtrAcct>
+        <RmtInf><Ustrd>salary</Ustrd></RmtInf>
+      </CdtTrfTxInf>
+      <CdtTrfTxInf>
+        <PmtId><EndToEndId>E2E-2</EndToEndId></PmtId>
+        <Amt><InstdAmt Ccy="USD">20</InstdAmt></Amt>
+        <CdtrAcct><Id><Othr><Id>3</Id></Othr></Id></CdtrAcct>
+      </CdtTrfTxInf>
+    </PmtInf>
+  </CstmrCdtTrfInitn>
+</Document>`, time.Now().UTC().Format(time.DateOnly))
+}
+
+func TestHandleDailyStatement_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		wallet := testutils.MockWallets()[0]
+		testutils.MockGetWalletById(mock, wallet)
+		testutils.MockGetWalletBalanceAt(mock, wallet.ID, dailyStatementDay, decimal.NewFromInt(100))
+		testutils.MockGetStatementEntries(mock, wallet.ID, dailyStatementDay, dailyStatementDay.AddDate(0, 0, 1),
+			models.StatementEntry{TransactionID: 10, Type: models.TxnTypeWithdraw, At: dailyStatementDay.Add(time.Hour), Amount: decimal.NewFromInt(-40)})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleDailyStatement(rec, dailyStatementRequest("101", "2025-05-03"))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/xml", rec.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="camt053-101-20250503.xml"`, rec.Header().Get("Content-Disposition"))
+		body := rec.Body.String()
+		assert.True(t, strings.HasPrefix(body, `<?xml version="1.0" encoding="UTF-8"?>`))
+		assert.Contains(t, body, `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.08">`)
+		assert.Contains(t, body, "<FrDtTm>2025-05-03T00:00:00Z</FrDtTm>")
+		assert.Contains(t, body, `<Amt Ccy="USD">60.00</Amt>`)
+		assert.Contains(t, body, "<NtryRef>10</NtryRef>")
+	})
+}
+
+func TestHandleDailyStatement_DayNotEnded(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		today := time.Now().UTC().Format(time.DateOnly)
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleDailyStatement(rec, dailyStatementRequest("101", today))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, models.ErrCodeValidationFailed, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
+
+func TestHandleDailyStatement_WalletCreatedLater(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		wallet := testutils.MockWallets()[0]
+		wallet.CreatedAt = dailyStatementDay.AddDate(0, 0, 1)
+		testutils.MockGetWalletById(mock, wallet)
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleDailyStatement(rec, dailyStatementRequest("101", "2025-05-03"))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, models.ErrCodeStatementNotFound, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
+
+func TestHandlePain001Upload_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		now := time.Now()
+
+		testutils.MockGetWalletById(mock, holdWallet())
+		testutils.MockGetPaymentFileProcessedAt(mock, 1, "MSG-1", nil)
+		testutils.MockGetWalletById(mock, holdWallet())
+		testutils.MockGetWalletsByIDs(mock, []int64{2, 3}, []models.Wallet{batchWallet(2, 2), batchWallet(3, 3)})
+
+		testutils.MockGetFeeRules(mock, models.FeeTypeTransfer)
+
+		mock.ExpectBegin()
+		testutils.MockGetBalance(mock, decimal.NewFromFloat(100), 1)
+		testutils.MockRecordPaymentFile(mock, 1, "MSG-1", true)
+		testutils.MockReserveIDs(mock, "transfers", 55, 56)
+		mock.ExpectQuery("INSERT INTO transfers").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(55, now).AddRow(56, now))
+		testutils.MockReserveIDs(mock, "transactions", 101, 102, 103, 104)
+		mock.ExpectQuery("INSERT INTO transactions").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "completed_at"}).
+				AddRow(101, now, now).AddRow(102, now, now).AddRow(103, now, now).AddRow(104, now, now))
+		testutils.MockUpdateBalanceByWalletID(mock, decimal.NewFromFloat(49.5), 1)
+		mock.ExpectQuery("UPDATE wallets SET balance = wallets.balance \\+ v.amount").
+			WithArgs(int64(2), decimal.NewFromFloat(30.5), int64(3), decimal.NewFromFloat(20)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "balance"}).AddRow(2, decimal.NewFromFloat(30.5)).AddRow(3, decimal.NewFromFloat(20)))
+		mock.ExpectCommit()
+
+		rec := postPain001(db, "", painFile())
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var resp models.BatchTransferResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, models.BatchModeAllOrNothing, resp.Mode)
+		assert.Equal(t, 2, resp.Succeeded)
+		assert.True(t, resp.TotalAmount.Equal(decimal.NewFromFloat(50.5)))
+		assert.Equal(t, int64(3), *resp.Items[1].TargetWalletID)
+	})
+}
+
+func TestHandlePain001Upload_DuplicateMsgId(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		processedAt := time.Date(2025, 6, 2, 8, 5, 0, 0, time.UTC)
+
+		testutils.MockGetWalletById(mock, holdWallet())
+		testutils.MockGetPaymentFileProcessedAt(mock, 1, "MSG-1", &processedAt)
+
+		rec := postPain001(db, "", painFile())
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		errResp := testutils.DecodeErrorResponse(t, rec)
+		assert.Equal(t, models.ErrCodeDuplicateFile, errResp.Code)
+		assert.Equal(t, "2025-06-02T08:05:00Z", errResp.Details.(map[string]interface{})["processed_at"])
+	})
+}
+
+func TestHandlePain001Upload_FileTooLarge(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		testutils.MockGetWalletById(mock, holdWallet())
+
+		// Padding the document past the limit, the file is not read any further
+		padding := "<!--" + strings.Repeat("x", models.MaxPaymentFileBytes) + "-->"
+		rec := postPain001(db, "", strings.Replace(painFile(), "<CstmrCdtTrfInitn>", "<CstmrCdtTrfInitn>"+padding, 1))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		errResp := testutils.DecodeErrorResponse(t, rec)
+		assert.Equal(t, models.ErrCodeValidationFailed, errResp.Code)
+		assert.Equal(t, "payment file must not be larger than 2097152 bytes", errResp.Message)
+	})
+}
+
+func TestHandlePain001Upload_InvalidFile(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		testutils.MockGetWalletById(mock, holdWallet())
+
+		rec := postPain001(db, "", strings.Replace(painFile(), `<InstdAmt Ccy="USD">20</InstdAmt>`, `<InstdAmt Ccy="EUR">20</InstdAmt>`, 1))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		errResp := testutils.DecodeErrorResponse(t, rec)
+		assert.Equal(t, models.ErrCodeValidationFailed, errResp.Code)
+		assert.Equal(t, "payment PMT-1, transaction E2E-2: InstdAmt must be in USD", errResp.Message)
+	})
+}
+
+func TestHandlePain001Upload_InvalidMode(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		testutils.MockGetWalletById(mock, holdWallet())
+
+		rec := postPain001(db, "?mode=sometimes", painFile())
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, models.ErrCodeValidationFailed, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
+
+func TestHandlePain001Upload_MalformedXML(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		testutils.MockGetWalletById(mock, holdWallet())
+
+		rec := postPain001(db, "", `<Document><CstmrCdtTrfInitn>`)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, models.ErrCodeMalformedRequest, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
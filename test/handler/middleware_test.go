@@ -0,0 +1,53 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/rudithu/CRYPTO-WalletApp/handler"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDMiddleware_ReusesClientRequestID(t *testing.T) {
+	h := handler.HandlerDB{DB: nil}
+
+	r := mux.NewRouter()
+	r.Use(handler.RequestIDMiddleware)
+	r.HandleFunc("/users/{id}/wallets/balance", h.HandleBalance).Methods("GET")
+
+	req := httptest.NewRequest(http.MethodGet, "/users/invalidId/wallets/balance", nil)
+	req.Header.Set(handler.RequestIDHeader, "req-123")
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "req-123", rec.Header().Get(handler.RequestIDHeader))
+
+	errResp := testutils.DecodeErrorResponse(t, rec)
+	assert.Equal(t, models.ErrCodeValidationFailed, errResp.Code)
+	assert.Equal(t, "req-123", errResp.RequestID)
+}
+
+func TestRequestIDMiddleware_GeneratesRequestID(t *testing.T) {
+	h := handler.HandlerDB{DB: nil}
+
+	r := mux.NewRouter()
+	r.Use(handler.RequestIDMiddleware)
+	r.HandleFunc("/wallets/{id}/deposit", h.HandleDepositMoney).Methods("POST")
+
+	req := httptest.NewRequest(http.MethodPost, "/wallets/invalidId/deposit", nil)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	errResp := testutils.DecodeErrorResponse(t, rec)
+	assert.NotEmpty(t, errResp.RequestID)
+	assert.Equal(t, errResp.RequestID, rec.Header().Get(handler.RequestIDHeader))
+}
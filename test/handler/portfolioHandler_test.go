@@ -0,0 +1,98 @@
+package handler_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/rudithu/CRYPTO-WalletApp/handler"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlePortfolioHistory_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		user := testutils.MockUserModel()
+		wallets := []models.Wallet{
+			{ID: 1, UserId: user.ID, Currency: "USD", Type: "saving", IsDefault: true},
+			{ID: 2, UserId: user.ID, Currency: "BTC", Type: "trading"},
+		}
+		from := time.Date(2025, 5, 30, 0, 0, 0, 0, time.UTC)
+		until := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+		testutils.MockGetUserById(mock, user)
+		testutils.MockGetWalletByUserIDs(mock, wallets)
+		testutils.MockGetWalletBalanceAt(mock, wallets[0].ID, from, decimal.NewFromFloat(100))
+		testutils.MockGetBalanceEvents(mock, wallets[0].ID, from, until)
+		testutils.MockGetWalletBalanceAt(mock, wallets[1].ID, from, decimal.NewFromFloat(0.01))
+		testutils.MockGetBalanceEvents(mock, wallets[1].ID, from, until,
+			models.BalanceEvent{At: from.AddDate(0, 0, 1).Add(8 * time.Hour), Delta: decimal.NewFromFloat(0.01)})
+		testutils.MockGetCcyRateHistory(mock, from, until,
+			models.CcyConversion{FromCcy: "USD", ToCcy: "BTC", Rate: decimal.NewFromFloat(0.00001), CreatedAt: from.Add(6 * time.Hour)},
+			models.CcyConversion{FromCcy: "USD", ToCcy: "BTC", Rate: decimal.NewFromFloat(0.000008), CreatedAt: from.AddDate(0, 0, 1).Add(10 * time.Hour)},
+		)
+
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/users/%d/portfolio/history?from=2025-05-30&to=2025-05-31", user.ID), nil)
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(user.ID, 10)})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandlePortfolioHistory(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.PortfolioHistoryResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, resp.UserInfo.ID)
+		assert.Equal(t, models.BaseCcy, resp.Currency)
+		require.Len(t, resp.Values, 2)
+
+		// 0.01 BTC at 0.00001 BTC per USD
+		day := resp.Values[0]
+		assert.Equal(t, "2025-05-30", day.Date)
+		assert.True(t, day.Total.Equal(decimal.NewFromFloat(1100)))
+		assert.False(t, day.Partial)
+		assert.False(t, day.Stale)
+		require.Len(t, day.Allocations, 2)
+		assert.Equal(t, "BTC", day.Allocations[0].Currency)
+		assert.True(t, day.Allocations[0].Value.Equal(decimal.NewFromFloat(1000)))
+		assert.True(t, day.Allocations[0].Percentage.Equal(decimal.NewFromFloat(90.91)))
+
+		// 0.02 BTC at the rate set on the day
+		day = resp.Values[1]
+		assert.Equal(t, "2025-05-31", day.Date)
+		assert.True(t, day.Total.Equal(decimal.NewFromFloat(2600)))
+		assert.True(t, day.Allocations[0].Balance.Equal(decimal.NewFromFloat(0.02)))
+	})
+}
+
+func TestHandlePortfolioHistory_UserNotFound(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		mock.ExpectQuery("SELECT id, name, reporting_currency, created_at FROM users where id=\\$1").
+			WithArgs(int64(99)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "reporting_currency", "created_at"}))
+
+		req := httptest.NewRequest(http.MethodGet, "/users/99/portfolio/history?from=2025-05-30&to=2025-05-31", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "99"})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandlePortfolioHistory(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, models.ErrCodeUserNotFound, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
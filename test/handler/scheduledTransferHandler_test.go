@@ -0,0 +1,145 @@
+package handler_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/rudithu/CRYPTO-WalletApp/handler"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleCreateScheduledTransfer_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		source := holdWallet()
+		target := models.Wallet{ID: 2, UserId: 2, Balance: decimal.Zero, Currency: "USD", Type: "primary", IsDefault: true, CreatedAt: time.Now()}
+		startAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+		now := time.Now()
+
+		testutils.MockGetWalletById(mock, source)
+		testutils.MockGetWalletById(mock, target)
+		mock.ExpectQuery("INSERT INTO scheduled_transfers").
+			WithArgs(source.ID, sql.NullInt64{Int64: 2, Valid: true}, sql.NullInt64{}, decimal.NewFromFloat(25), models.NullString("rent"),
+				sql.NullString{}, models.ScheduleFrequencyMonthly, startAt, sql.NullTime{}, sql.NullInt64{Int64: 12, Valid: true},
+				sql.NullTime{Time: startAt, Valid: true}, models.InsufficientFundsSkip, models.ScheduleStatusActive).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(61, now, now))
+
+		requestBody := `{"amount": 25, "destination_wallet_id": 2, "description": "rent", "frequency": "monthly",
+			"start_at": "` + startAt.Format(time.RFC3339) + `", "max_occurrences": 12, "on_insufficient_funds": "skip"}`
+		req := httptest.NewRequest(http.MethodPost, "/wallets/1/scheduled-transfers", strings.NewReader(requestBody))
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleCreateScheduledTransfer(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "/scheduled-transfers/61", rec.Header().Get("Location"))
+
+		var resp models.ScheduledTransferResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, int64(61), resp.ID)
+		assert.Equal(t, "USD", resp.Currency)
+		assert.Equal(t, models.ScheduleStatusActive, resp.Status)
+		require.NotNil(t, resp.NextRunAt)
+		assert.True(t, startAt.Equal(*resp.NextRunAt))
+	})
+}
+
+func TestHandleCreateScheduledTransfer_InvalidFrequency(t *testing.T) {
+	requestBody := `{"amount": 25, "destination_wallet_id": 2, "frequency": "hourly"}`
+	req := httptest.NewRequest(http.MethodPost, "/wallets/1/scheduled-transfers", strings.NewReader(requestBody))
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+
+	rec := httptest.NewRecorder()
+	handler := handler.HandlerDB{DB: nil}
+	handler.HandleCreateScheduledTransfer(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	errResp := testutils.DecodeErrorResponse(t, rec)
+	assert.Equal(t, models.ErrCodeValidationFailed, errResp.Code)
+}
+
+func TestHandleUpdateScheduledTransfer_Pause(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		s := testutils.MockScheduledTransfer()
+
+		testutils.MockGetScheduledTransferById(mock, s)
+		testutils.MockGetWalletById(mock, holdWallet())
+		mock.ExpectBegin()
+		testutils.MockLockScheduledTransfer(mock, s)
+		testutils.MockSaveScheduledTransfer(mock, s, models.ScheduleStatusPaused, 0, 0)
+		mock.ExpectCommit()
+
+		req := httptest.NewRequest(http.MethodPatch, "/scheduled-transfers/61", strings.NewReader(`{"status": "paused"}`))
+		req = mux.SetURLVars(req, map[string]string{"id": "61"})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleUpdateScheduledTransfer(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.ScheduledTransferResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, models.ScheduleStatusPaused, resp.Status)
+	})
+}
+
+func TestHandleCancelScheduledTransfer_Completed(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		s := testutils.MockScheduledTransfer()
+		s.Status = models.ScheduleStatusCompleted
+		s.NextRunAt = sql.NullTime{}
+
+		testutils.MockGetScheduledTransferById(mock, s)
+		testutils.MockGetWalletById(mock, holdWallet())
+		mock.ExpectBegin()
+		testutils.MockLockScheduledTransfer(mock, s)
+		mock.ExpectRollback()
+
+		req := httptest.NewRequest(http.MethodDelete, "/scheduled-transfers/61", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "61"})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleCancelScheduledTransfer(rec, req)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		errResp := testutils.DecodeErrorResponse(t, rec)
+		assert.Equal(t, models.ErrCodeInvalidTransition, errResp.Code)
+	})
+}
+
+func TestHandleGetScheduledTransfer_NotFound(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		testutils.MockGetScheduledTransferByIdNoRecord(mock, int64(77))
+
+		req := httptest.NewRequest(http.MethodGet, "/scheduled-transfers/77", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "77"})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleGetScheduledTransfer(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		errResp := testutils.DecodeErrorResponse(t, rec)
+		assert.Equal(t, models.ErrCodeScheduleNotFound, errResp.Code)
+	})
+}
@@ -0,0 +1,185 @@
+package handler_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/rudithu/CRYPTO-WalletApp/handler"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var statementPeriod = time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+
+func statementRequest(walletId string, period string, query string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/wallets/"+walletId+"/statements/"+period+query, nil)
+	return mux.SetURLVars(req, map[string]string{"id": walletId, "period": period})
+}
+
+// storedStatement is the May 2025 statement of the first mock wallet: a deposit and a withdrawal with its fee.
+func storedStatement(wallet models.Wallet) models.Statement {
+	withdrawId, counterparty := int64(11), int64(102)
+	return models.BuildStatement(wallet, statementPeriod, decimal.NewFromInt(100), []models.StatementEntry{
+		{TransactionID: 10, Type: models.TxnTypeTransferIn, At: statementPeriod.Add(time.Hour), Amount: decimal.NewFromInt(25),
+			Description: "refund <b>", CounterpartyWalletID: &counterparty, Rate: decimal.NewNullDecimal(decimal.NewFromFloat(1.25)), CounterCcy: "EUR"},
+		{TransactionID: 11, Type: models.TxnTypeWithdraw, At: statementPeriod.AddDate(0, 0, 2), Amount: decimal.NewFromInt(-50)},
+		{TransactionID: 12, Type: models.TxnTypeFee, At: statementPeriod.AddDate(0, 0, 2), Amount: decimal.NewFromInt(-1), FeeFor: &withdrawId},
+	}, statementPeriod.AddDate(0, 1, 0))
+}
+
+func TestHandleWalletStatement_Stored(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		wallet := testutils.MockWallets()[0]
+		stored := storedStatement(wallet)
+		testutils.MockGetWalletById(mock, wallet)
+		testutils.MockGetStatement(mock, wallet.ID, statementPeriod, &stored)
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleWalletStatement(rec, statementRequest("101", "2025-05", ""))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.StatementResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, "2025-05", resp.Period)
+		assert.Equal(t, "2025-05-01", resp.From)
+		assert.Equal(t, "2025-05-31", resp.To)
+		assert.True(t, resp.OpeningBalance.Equal(decimal.NewFromInt(100)))
+		assert.True(t, resp.ClosingBalance.Equal(decimal.NewFromInt(74)))
+		assert.True(t, resp.TotalCredits.Equal(decimal.NewFromInt(25)))
+		assert.True(t, resp.TotalDebits.Equal(decimal.NewFromInt(51)))
+		assert.True(t, resp.TotalFees.Equal(decimal.NewFromInt(1)))
+		require.Len(t, resp.Entries, 3)
+		require.NotNil(t, resp.Entries[0].FX)
+		assert.Equal(t, "EUR", resp.Entries[0].FX.CounterCurrency)
+		assert.True(t, resp.Entries[0].FX.CounterAmount.Equal(decimal.NewFromInt(20)))
+		require.NotNil(t, resp.Entries[2].FeeFor)
+		assert.Equal(t, int64(11), *resp.Entries[2].FeeFor)
+	})
+}
+
+func TestHandleWalletStatement_GeneratedOnDemand(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		wallet := testutils.MockWallets()[0]
+		testutils.MockGetWalletById(mock, wallet)
+		testutils.MockGetStatement(mock, wallet.ID, statementPeriod, nil)
+		testutils.MockGetWalletBalanceAt(mock, wallet.ID, statementPeriod, decimal.NewFromInt(100))
+		testutils.MockGetStatementEntries(mock, wallet.ID, statementPeriod, statementPeriod.AddDate(0, 1, 0),
+			models.StatementEntry{TransactionID: 10, Type: models.TxnTypeDeposit, At: statementPeriod.Add(time.Hour), Amount: decimal.NewFromInt(50)})
+		testutils.MockSaveStatement(mock, wallet.ID, statementPeriod, true)
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleWalletStatement(rec, statementRequest("101", "2025-05", ""))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.StatementResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.True(t, resp.ClosingBalance.Equal(decimal.NewFromInt(150)))
+		require.Len(t, resp.Entries, 1)
+		assert.True(t, resp.Entries[0].Balance.Equal(decimal.NewFromInt(150)))
+	})
+}
+
+func TestHandleWalletStatement_HTML(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		wallet := testutils.MockWallets()[0]
+		stored := storedStatement(wallet)
+		testutils.MockGetWalletById(mock, wallet)
+		testutils.MockGetStatement(mock, wallet.ID, statementPeriod, &stored)
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleWalletStatement(rec, statementRequest("101", "2025-05", "?format=html"))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+		body := rec.Body.String()
+		assert.Contains(t, body, "<h1>Statement 2025-05</h1>")
+		assert.Contains(t, body, "74.00 USD")
+		assert.Contains(t, body, "wallet 102")
+		assert.Contains(t, body, "fee for transaction 11")
+		assert.Contains(t, body, "20.00 EUR at 1.25")
+		// user input is escaped
+		assert.Contains(t, body, "refund &lt;b&gt;")
+	})
+}
+
+func TestHandleWalletStatement_MonthNotEnded(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		period := time.Now().UTC().Format(models.StatementPeriodLayout)
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleWalletStatement(rec, statementRequest("101", period, ""))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, models.ErrCodeValidationFailed, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
+
+func TestHandleWalletStatement_InvalidPeriod(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleWalletStatement(rec, statementRequest("101", "2025-13", ""))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, models.ErrCodeValidationFailed, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
+
+func TestHandleWalletStatement_WalletCreatedLater(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		wallet := testutils.MockWallets()[0]
+		wallet.CreatedAt = statementPeriod.AddDate(0, 2, 0)
+		testutils.MockGetWalletById(mock, wallet)
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleWalletStatement(rec, statementRequest("101", "2025-05", ""))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, models.ErrCodeStatementNotFound, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
+
+func TestHandleWalletStatement_Camt053(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		wallet := testutils.MockWallets()[0]
+		stored := storedStatement(wallet)
+		testutils.MockGetWalletById(mock, wallet)
+		testutils.MockGetStatement(mock, wallet.ID, statementPeriod, &stored)
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleWalletStatement(rec, statementRequest("101", "2025-05", "?format=camt053"))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `attachment; filename="camt053-101-202505.xml"`, rec.Header().Get("Content-Disposition"))
+		body := rec.Body.String()
+		assert.Contains(t, body, "<MsgId>101-202505</MsgId>")
+		assert.Contains(t, body, "<ToDtTm>2025-06-01T00:00:00Z</ToDtTm>")
+		assert.Contains(t, body, "<NbOfNtries>3</NbOfNtries>")
+	})
+}
@@ -0,0 +1,286 @@
+package handler_test
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/rudithu/CRYPTO-WalletApp/handler"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGetTransaction_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		txn := testutils.MockTxns()[0]
+		wallet := testutils.MockWallets()[0]
+
+		testutils.MockGetTransactionById(mock, txn)
+		testutils.MockGetWalletById(mock, wallet)
+
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/transactions/%d", txn.ID), nil)
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(txn.ID, 10)})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleGetTransaction(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.TransactionResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, txn.ID, resp.ID)
+		assert.Equal(t, wallet.Currency, resp.Currency)
+		assert.Nil(t, resp.Balance)
+	})
+}
+
+func TestHandleGetTransaction_TransferEnriched(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		wallet := testutils.MockWallets()[0]
+		txn := models.Transaction{
+			ID:                   int64(401),
+			WalletId:             wallet.ID,
+			Type:                 models.TxnTypeTransferOut,
+			Amount:               decimal.NewFromFloat(100),
+			CounterpartyWalletId: testutils.NullInt64(210, true),
+			CreatedAt:            time.Now(),
+		}
+
+		testutils.MockGetTransactionById(mock, txn)
+		testutils.MockGetWalletById(mock, wallet)
+		testutils.MockGetTransferCounterparties(mock, []models.TransferCounterparty{{
+			TransactionID:       txn.ID,
+			LinkedTransactionID: testutils.NullInt64(402, true),
+			LinkedAmount:        decimal.NewNullDecimal(decimal.NewFromFloat(135)),
+			WalletID:            int64(210),
+			Currency:            "SGD",
+			UserID:              int64(9),
+			UserName:            "Bob",
+		}})
+
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/transactions/%d", txn.ID), nil)
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(txn.ID, 10)})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleGetTransaction(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.TransactionResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		require.NotNil(t, resp.TransferDetail)
+		assert.Equal(t, "B**", resp.Counterparty.Name)
+		assert.Equal(t, "SGD", resp.Counterparty.Currency)
+		assert.True(t, resp.Counterparty.Amount.Equal(decimal.NewFromFloat(135)))
+		assert.Equal(t, int64(402), *resp.LinkedTransactionID)
+		assert.True(t, resp.Rate.Equal(decimal.NewFromFloat(1.35)))
+	})
+}
+
+func TestHandleGetTransaction_NotFound(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		testutils.MockGetTransactionByIdNoRecord(mock, int64(77))
+
+		req := httptest.NewRequest(http.MethodGet, "/transactions/77", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "77"})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleGetTransaction(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		errResp := testutils.DecodeErrorResponse(t, rec)
+		assert.Equal(t, models.ErrCodeTxnNotFound, errResp.Code)
+	})
+}
+
+func TestHandleGetTransaction_InvalidId(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/transactions/invalidId", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "invalidId"})
+
+	rec := httptest.NewRecorder()
+	handler := handler.HandlerDB{DB: nil}
+	handler.HandleGetTransaction(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	errResp := testutils.DecodeErrorResponse(t, rec)
+	assert.Equal(t, models.ErrCodeValidationFailed, errResp.Code)
+}
+
+func TestHandleUpdateTransactionNotes_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		txn := testutils.MockTxns()[0]
+		wallet := testutils.MockWallets()[0]
+
+		testutils.MockGetTransactionById(mock, txn)
+		testutils.MockGetWalletById(mock, wallet)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE transactions SET description").
+			WithArgs(models.NullString("groceries"), txn.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM transaction_tags").
+			WithArgs(txn.ID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO transaction_tags").
+			WithArgs(txn.ID, "food", "weekly").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		body := []byte(`{"description":"groceries","tags":["Food"," weekly","food"]}`)
+		req := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/transactions/%d", txn.ID), bytes.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(txn.ID, 10)})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleUpdateTransactionNotes(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.TransactionResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, "groceries", resp.Description)
+		assert.Equal(t, []string{"food", "weekly"}, resp.Tags)
+	})
+}
+
+func TestHandleUpdateTransactionNotes_InvalidTag(t *testing.T) {
+	body := []byte(`{"tags":["not a tag!"]}`)
+	req := httptest.NewRequest(http.MethodPatch, "/transactions/5", bytes.NewReader(body))
+	req = mux.SetURLVars(req, map[string]string{"id": "5"})
+
+	rec := httptest.NewRecorder()
+	handler := handler.HandlerDB{DB: nil}
+	handler.HandleUpdateTransactionNotes(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	errResp := testutils.DecodeErrorResponse(t, rec)
+	assert.Equal(t, models.ErrCodeValidationFailed, errResp.Code)
+}
+
+func TestHandleUpdateTransactionNotes_NotFound(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		testutils.MockGetTransactionByIdNoRecord(mock, int64(77))
+
+		body := []byte(`{"description":"groceries"}`)
+		req := httptest.NewRequest(http.MethodPatch, "/transactions/77", bytes.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"id": "77"})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleUpdateTransactionNotes(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		errResp := testutils.DecodeErrorResponse(t, rec)
+		assert.Equal(t, models.ErrCodeTxnNotFound, errResp.Code)
+	})
+}
+
+func TestHandleUpdateTransactionStatus_CompletePendingDeposit(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		wallet := testutils.MockWallets()[0]
+		txn := models.Transaction{
+			ID:        int64(301),
+			WalletId:  wallet.ID,
+			Type:      models.TxnTypeDeposit,
+			Amount:    decimal.NewFromFloat(25),
+			Status:    models.TxnStatusPending,
+			CreatedAt: time.Now(),
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(testutils.TxnSelectQuery + "WHERE id = \\$1 FOR UPDATE").
+			WithArgs(txn.ID).
+			WillReturnRows(testutils.AddTxnRow(testutils.TxnRows(), txn))
+		testutils.MockIncrementBalanceByWalletID(mock, txn.Amount, wallet.ID, wallet.Balance.Add(txn.Amount))
+		mock.ExpectQuery("UPDATE transactions SET status = \\$1, completed_at = CURRENT_TIMESTAMP WHERE id = \\$2 RETURNING completed_at").
+			WithArgs(models.TxnStatusCompleted, txn.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"completed_at"}).AddRow(time.Now()))
+		mock.ExpectCommit()
+
+		testutils.MockGetWalletById(mock, wallet)
+
+		req := httptest.NewRequest(http.MethodPost, "/transactions/301/status", bytes.NewBufferString(`{"status": "completed"}`))
+		req = mux.SetURLVars(req, map[string]string{"id": "301"})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleUpdateTransactionStatus(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.TransactionResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, models.TxnStatusCompleted, resp.Status)
+		assert.NotNil(t, resp.CompletedAt)
+		require.NotNil(t, resp.Balance)
+		assert.True(t, resp.Balance.Equal(wallet.Balance.Add(txn.Amount)))
+	})
+}
+
+func TestHandleUpdateTransactionStatus_InvalidTransition(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		txn := models.Transaction{
+			ID:        int64(301),
+			WalletId:  int64(101),
+			Type:      models.TxnTypeWithdraw,
+			Amount:    decimal.NewFromFloat(25),
+			Status:    models.TxnStatusReversed,
+			CreatedAt: time.Now(),
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(testutils.TxnSelectQuery + "WHERE id = \\$1 FOR UPDATE").
+			WithArgs(txn.ID).
+			WillReturnRows(testutils.AddTxnRow(testutils.TxnRows(), txn))
+		mock.ExpectRollback()
+
+		req := httptest.NewRequest(http.MethodPost, "/transactions/301/status", bytes.NewBufferString(`{"status": "completed"}`))
+		req = mux.SetURLVars(req, map[string]string{"id": "301"})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleUpdateTransactionStatus(rec, req)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		errResp := testutils.DecodeErrorResponse(t, rec)
+		assert.Equal(t, models.ErrCodeInvalidTransition, errResp.Code)
+	})
+}
+
+func TestHandleUpdateTransactionStatus_UnknownStatus(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/transactions/301/status", bytes.NewBufferString(`{"status": "pending"}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "301"})
+
+	rec := httptest.NewRecorder()
+	handler := handler.HandlerDB{DB: nil}
+	handler.HandleUpdateTransactionStatus(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	errResp := testutils.DecodeErrorResponse(t, rec)
+	assert.Equal(t, models.ErrCodeValidationFailed, errResp.Code)
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -36,19 +37,26 @@ func TestHandleTxHistory_Success(t *testing.T) {
 		testutils.MockGetWalletByUserIDs(mock, wallets)
 
 		//GetTransactionsByWalletIDs
-		mock.ExpectQuery("SELECT id, wallet_id, type, amount, counterparty_wallet_id, created_at FROM transactions WHERE wallet_id in \\(.*\\) ORDER BY created_at DESC").
+		mock.ExpectQuery(testutils.TxnSelectQuery+"WHERE wallet_id in \\(.*\\) ORDER BY created_at DESC").
 			WithArgs(wallets[0].ID, wallets[1].ID).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "wallet_id", "type", "amount", "counterparty_wallet_id", "created_at"}).
-				AddRow(int64(201), wallets[0].ID, models.TxnTypeWithdraw, decimal.NewFromFloat(100), sql.NullInt64{Valid: false}, time.Now().AddDate(0, -1, -3)).
-				AddRow(int64(202), wallets[0].ID, models.TxnTypeTransferIn, decimal.NewFromFloat(100), int64(209), time.Now().AddDate(0, 0, -30)).
-				AddRow(int64(203), wallets[1].ID, models.TxnTypeDeposit, decimal.NewFromFloat(100), sql.NullInt64{Valid: false}, time.Now().AddDate(0, -2, -10)).
-				AddRow(int64(204), wallets[1].ID, models.TxnTypeDeposit, decimal.NewFromFloat(60), sql.NullInt64{Valid: false}, time.Now().AddDate(0, -1, -25)))
-
-		mock.ExpectQuery(fmt.Sprintf("SELECT to_ccy, rate FROM ccy_conversion WHERE from_ccy = '%s' AND to_ccy IN \\(.+\\)", models.BaseCcy)).
-			WithArgs(wallets[0].Currency, wallets[1].Currency).
-			WillReturnRows(sqlmock.NewRows([]string{"to_ccy", "rate"}).
-				AddRow(wallets[0].Currency, decimal.NewFromFloat(1.35)).
-				AddRow(wallets[1].Currency, decimal.NewFromFloat(0.92)))
+			WillReturnRows(testutils.TxnRows().
+				AddRow(int64(201), wallets[0].ID, models.TxnTypeWithdraw, decimal.NewFromFloat(100), sql.NullInt64{Valid: false}, time.Now().AddDate(0, -1, -3), nil, nil, nil, models.TxnStatusCompleted, time.Now(), nil, nil, nil, nil).
+				AddRow(int64(202), wallets[0].ID, models.TxnTypeTransferIn, decimal.NewFromFloat(100), int64(209), time.Now().AddDate(0, 0, -30), nil, nil, int64(31), models.TxnStatusCompleted, time.Now(), nil, nil, nil, nil).
+				AddRow(int64(203), wallets[1].ID, models.TxnTypeDeposit, decimal.NewFromFloat(100), sql.NullInt64{Valid: false}, time.Now().AddDate(0, -2, -10), nil, nil, nil, models.TxnStatusCompleted, time.Now(), nil, nil, nil, nil).
+				AddRow(int64(204), wallets[1].ID, models.TxnTypeDeposit, decimal.NewFromFloat(60), sql.NullInt64{Valid: false}, time.Now().AddDate(0, -1, -25), nil, nil, nil, models.TxnStatusPending, nil, nil, nil, nil, nil))
+
+		//GetTransferCounterparties
+		testutils.MockGetTransferCounterparties(mock, []models.TransferCounterparty{{
+			TransactionID:       int64(202),
+			LinkedTransactionID: sql.NullInt64{Int64: 198, Valid: true},
+			LinkedAmount:        decimal.NewNullDecimal(decimal.NewFromFloat(74.07)),
+			WalletID:            int64(209),
+			Currency:            "USD",
+			UserID:              int64(7),
+			UserName:            "Charlie",
+		}})
+
+		testutils.MockGetCcyConversions(mock, testutils.MockCcyConversions()...)
 
 		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/users/%d/wallets/transactions", user.ID), nil)
 		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(user.ID, 10)})
@@ -69,11 +77,24 @@ func TestHandleTxHistory_Success(t *testing.T) {
 		// assert.Equal(t, 2, len(response.Wallets[0].Transactions))
 		// assert.Equal(t, 2, len(response.Wallets[1].Transactions))
 		assert.NotNil(t, response.Balance)
+
+		transferIn := response.Wallets[0].Transactions[1]
+		assert.Equal(t, int64(202), transferIn.ID)
+		require.NotNil(t, transferIn.TransferDetail)
+		require.NotNil(t, transferIn.Counterparty)
+		assert.Equal(t, "C******", transferIn.Counterparty.Name)
+		assert.Nil(t, transferIn.Counterparty.UserID)
+		assert.Equal(t, "USD", transferIn.Counterparty.Currency)
+		assert.Equal(t, int64(198), *transferIn.LinkedTransactionID)
+		assert.NotNil(t, transferIn.Rate)
+
+		assert.Equal(t, models.TxnStatusCompleted, response.Wallets[1].Transactions[0].Status)
+		assert.Equal(t, models.TxnStatusPending, response.Wallets[1].Transactions[1].Status)
 	})
 
 }
 
-func TestHandleTxHistory_NoBalance_FilteredByWalletID(t *testing.T) {
+func TestHandleTxHistory_PartialBalance_FilteredByWalletID(t *testing.T) {
 
 	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
 
@@ -89,15 +110,13 @@ func TestHandleTxHistory_NoBalance_FilteredByWalletID(t *testing.T) {
 		testutils.MockGetWalletById(mock, wallets[1])
 
 		//GetTransactionsByWalletIDs
-		mock.ExpectQuery("SELECT id, wallet_id, type, amount, counterparty_wallet_id, created_at FROM transactions WHERE wallet_id in \\(.*\\) ORDER BY created_at DESC").
+		mock.ExpectQuery(testutils.TxnSelectQuery + "WHERE wallet_id in \\(.*\\) ORDER BY created_at DESC").
 			WithArgs(wallets[1].ID).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "wallet_id", "type", "amount", "counterparty_wallet_id", "created_at"}).
-				AddRow(int64(203), wallets[1].ID, models.TxnTypeDeposit, decimal.NewFromFloat(100), sql.NullInt64{Valid: false}, time.Now().AddDate(0, -2, -10)).
-				AddRow(int64(204), wallets[1].ID, models.TxnTypeDeposit, decimal.NewFromFloat(60), sql.NullInt64{Valid: false}, time.Now().AddDate(0, -1, -25)))
+			WillReturnRows(testutils.TxnRows().
+				AddRow(int64(203), wallets[1].ID, models.TxnTypeDeposit, decimal.NewFromFloat(100), sql.NullInt64{Valid: false}, time.Now().AddDate(0, -2, -10), nil, nil, nil, models.TxnStatusCompleted, time.Now(), nil, nil, nil, nil).
+				AddRow(int64(204), wallets[1].ID, models.TxnTypeDeposit, decimal.NewFromFloat(60), sql.NullInt64{Valid: false}, time.Now().AddDate(0, -1, -25), nil, nil, nil, models.TxnStatusCompleted, time.Now(), nil, nil, nil, nil))
 
-		mock.ExpectQuery(fmt.Sprintf("SELECT to_ccy, rate FROM ccy_conversion WHERE from_ccy = '%s' AND to_ccy IN \\(.+\\)", models.BaseCcy)).
-			WithArgs(wallets[1].Currency).
-			WillReturnRows(sqlmock.NewRows([]string{"to_ccy", "rate"}))
+		testutils.MockGetCcyConversions(mock)
 
 		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/users/%d/wallets/transactions?wallet_id=%d", user.ID, wallets[1].ID), nil)
 		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(user.ID, 10)})
@@ -117,7 +136,11 @@ func TestHandleTxHistory_NoBalance_FilteredByWalletID(t *testing.T) {
 		assert.Equal(t, 1, len(response.Wallets))
 		assert.Equal(t, 2, len(response.Wallets[0].Transactions))
 
-		assert.Nil(t, response.Balance)
+		require.NotNil(t, response.Balance)
+		assert.True(t, response.Balance.Amount.IsZero())
+		assert.True(t, response.Balance.Partial)
+		assert.Equal(t, []string{wallets[1].Currency}, response.Balance.UnpricedCurrencies)
+		assert.Equal(t, []int64{wallets[1].ID}, response.Balance.UnpricedWallets)
 	})
 }
 
@@ -153,3 +176,101 @@ func TestHandleTxHistory_InvalidWalletId(t *testing.T) {
 	assert.Contains(t, rec.Body.String(), "invalid wallet id")
 
 }
+
+func txHistoryRequest(userId int64, query string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/users/%d/wallets/transactions%s", userId, query), nil)
+	return mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(userId, 10)})
+}
+
+func TestHandleTxHistory_ExportCSV(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		user := testutils.MockUserModel()
+		wallet := testutils.MockWallets()[0]
+		wallet.UserId = user.ID
+		from := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+		createdAt := from.Add(time.Hour)
+
+		testutils.MockGetUserById(mock, user)
+		testutils.MockGetWalletByUserIDs(mock, []models.Wallet{wallet})
+		mock.ExpectQuery(testutils.TxnSelectQuery+"WHERE wallet_id in \\(\\$1\\) AND created_at >= \\$2 AND created_at < \\$3 ORDER BY created_at, id").
+			WithArgs(wallet.ID, from, from.AddDate(0, 1, 0)).
+			WillReturnRows(testutils.TxnRows().
+				AddRow(int64(201), wallet.ID, models.TxnTypeDeposit, decimal.NewFromFloat(100), nil, createdAt, nil, nil, nil, models.TxnStatusCompleted, createdAt, nil, nil, nil, nil).
+				AddRow(int64(202), wallet.ID, models.TxnTypeWithdraw, decimal.NewFromFloat(40), nil, createdAt, "cash", nil, nil, models.TxnStatusCompleted, createdAt, nil, nil, nil, "atm"))
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleTxHistory(rec, txHistoryRequest(user.ID, "?format=csv&from=2025-05-01&to=2025-05-31"))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="transactions-101.csv"`, rec.Header().Get("Content-Disposition"))
+		assert.Equal(t,
+			"created_at,completed_at,wallet_id,currency,transaction_id,type,status,amount,description,external_reference,counterparty_wallet_id,transfer_id,rate,tags\n"+
+				"2025-05-01T01:00:00Z,2025-05-01T01:00:00Z,101,USD,201,deposit,completed,100.00,,,,,,\n"+
+				"2025-05-01T01:00:00Z,2025-05-01T01:00:00Z,101,USD,202,withdraw,completed,-40.00,cash,,,,,atm\n",
+			rec.Body.String())
+	})
+}
+
+func TestHandleTxHistory_ExportOFXByAccept(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		user := testutils.MockUserModel()
+		wallet := testutils.MockWallets()[0]
+		wallet.UserId = user.ID
+		until := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+		createdAt := time.Date(2025, 5, 3, 9, 0, 0, 0, time.UTC)
+
+		testutils.MockGetUserById(mock, user)
+		testutils.MockGetWalletByUserIDs(mock, []models.Wallet{wallet})
+		testutils.MockGetWalletBalanceAt(mock, wallet.ID, until, decimal.NewFromFloat(60))
+		mock.ExpectQuery(testutils.TxnSelectQuery+"WHERE wallet_id in \\(\\$1\\) AND created_at < \\$2 ORDER BY created_at, id").
+			WithArgs(wallet.ID, until).
+			WillReturnRows(testutils.TxnRows().
+				AddRow(int64(201), wallet.ID, models.TxnTypeTransferOut, decimal.NewFromFloat(40), int64(209), createdAt, "rent & bills", nil, int64(31), models.TxnStatusCompleted, createdAt, nil, nil, nil, nil).
+				AddRow(int64(202), wallet.ID, models.TxnTypeDeposit, decimal.NewFromFloat(10), nil, createdAt, nil, nil, nil, models.TxnStatusPending, nil, nil, nil, nil, nil))
+
+		req := txHistoryRequest(user.ID, "?to=2025-05-31")
+		req.Header.Set("Accept", "application/x-ofx")
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleTxHistory(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/x-ofx", rec.Header().Get("Content-Type"))
+		body := rec.Body.String()
+		assert.Contains(t, body, `<?OFX OFXHEADER="200" VERSION="220"`)
+		assert.Contains(t, body, "<CURDEF>USD</CURDEF><BANKACCTFROM><BANKID>CRYPTOWALLET</BANKID><ACCTID>101</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE>")
+		assert.Contains(t, body, "<DTEND>20250601000000.000[0:GMT]</DTEND>")
+		assert.Contains(t, body, "<STMTTRN><TRNTYPE>XFER</TRNTYPE><DTPOSTED>20250503090000.000[0:GMT]</DTPOSTED><TRNAMT>-40.00</TRNAMT><FITID>201</FITID><NAME>rent &amp; bills</NAME></STMTTRN>")
+		assert.NotContains(t, body, "<FITID>202</FITID>")
+		assert.Contains(t, body, "<LEDGERBAL><BALAMT>60.00</BALAMT>")
+		assert.True(t, strings.HasSuffix(body, "</OFX>\n"))
+	})
+}
+
+func TestHandleTxHistory_InvalidFormat(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleTxHistory(rec, txHistoryRequest(101, "?format=xls"))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, models.ErrCodeValidationFailed, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
+
+func TestHandleTxHistory_FromAfterTo(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleTxHistory(rec, txHistoryRequest(101, "?from=2025-06-01&to=2025-05-31"))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, models.ErrCodeValidationFailed, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
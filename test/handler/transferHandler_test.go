@@ -2,6 +2,7 @@ package handler_test
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -17,6 +18,7 @@ import (
 	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func getSourceWallet() models.Wallet {
@@ -62,12 +64,18 @@ func TestHandleTransferMoney_WalletToUser_DefaultWallet_Success(t *testing.T) {
 				AddRow(targetWalletId, targetUserId, decimal.NewFromFloat(100), "USD", "saving", true, time.Now()))
 
 		// GetCcyRate
-		mock.ExpectQuery("SELECT to_ccy, rate FROM ccy_conversion WHERE from_ccy = \\$1 AND to_ccy in \\(\\$2, \\$3\\)").
-			WithArgs(models.BaseCcy, sourceWallet.Currency, "USD").
-			WillReturnRows(sqlmock.NewRows([]string{"to_ccy", "rate"}).AddRow("SGD", decimal.NewFromFloat(1.35)))
+		testutils.MockGetCcyConversions(mock, testutils.MockCcyConversions()...)
+		testutils.MockGetFxSpreadRules(mock, sourceWallet.UserId)
+
+		testutils.MockGetFeeRules(mock, models.FeeTypeTransfer)
 
 		mock.ExpectBegin()
 
+		//createTransfer
+		testutils.MockCreateTransfer(mock, models.Transfer{
+			ID: int64(31), SourceWalletId: sourceWallet.ID, TargetWalletId: targetWalletId,
+			SourceAmount: sourceTxnAmount, RatePath: "SGD>USD", Status: models.TransferStatusCompleted, CreatedAt: time.Now()})
+
 		testutils.MockGetBalance(mock, sourceWallet.Balance, sourceWallet.ID)
 
 		//createTransaction source
@@ -79,14 +87,14 @@ func TestHandleTransferMoney_WalletToUser_DefaultWallet_Success(t *testing.T) {
 		testutils.MockUpdateBalanceByWalletID(mock, sourceWallet.Balance.Sub(sourceTxnAmount), sourceWallet.ID)
 
 		rate := decimal.NewFromFloat(1).Div(decimal.NewFromFloat(1.35))
-		targetTxnAmount := sourceTxnAmount.Mul(rate)
+		targetTxnAmount := sourceTxnAmount.Mul(rate).Round(models.AmountScale)
 		//createTransaction target
 		testutils.MockCreateTransaction(mock, models.Transaction{
 			ID: int64(402), WalletId: targetWalletId, Type: models.TxnTypeTransferIn,
 			Amount: targetTxnAmount, CounterpartyWalletId: sql.NullInt64{Valid: true, Int64: sourceWallet.ID}, CreatedAt: time.Now()})
 
 		//updateBalanceByWalletID target
-		testutils.MockIncrementBalanceByWalletID(mock, targetTxnAmount, targetWalletId)
+		testutils.MockIncrementBalanceByWalletID(mock, targetTxnAmount, targetWalletId, decimal.NewFromFloat(100).Add(targetTxnAmount))
 
 		mock.ExpectCommit()
 
@@ -98,7 +106,7 @@ func TestHandleTransferMoney_WalletToUser_DefaultWallet_Success(t *testing.T) {
 		handler := handler.HandlerDB{DB: db}
 		handler.HandleTransferMoney(rec, req)
 
-		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, http.StatusCreated, rec.Code)
 	})
 }
 
@@ -121,8 +129,15 @@ func TestHandleTransferMoney_WalletToUser_SameCcy_Success(t *testing.T) {
 				AddRow(int64(210), targetUserId, decimal.NewFromFloat(100), "USD", "saving", true, time.Now()).
 				AddRow(targetWalletId, targetUserId, decimal.NewFromFloat(50), "SGD", "saving", false, time.Now()))
 
+		testutils.MockGetFeeRules(mock, models.FeeTypeTransfer)
+
 		mock.ExpectBegin()
 
+		//createTransfer
+		testutils.MockCreateTransfer(mock, models.Transfer{
+			ID: int64(31), SourceWalletId: sourceWallet.ID, TargetWalletId: targetWalletId,
+			SourceAmount: sourceTxnAmount, Status: models.TransferStatusCompleted, CreatedAt: time.Now()})
+
 		testutils.MockGetBalance(mock, sourceWallet.Balance, sourceWallet.ID)
 
 		//createTransaction source
@@ -140,7 +155,7 @@ func TestHandleTransferMoney_WalletToUser_SameCcy_Success(t *testing.T) {
 			Amount: targetTxnAmount, CounterpartyWalletId: sql.NullInt64{Valid: true, Int64: sourceWallet.ID}, CreatedAt: time.Now()})
 
 		//updateBalanceByWalletID target
-		testutils.MockIncrementBalanceByWalletID(mock, targetTxnAmount, targetWalletId)
+		testutils.MockIncrementBalanceByWalletID(mock, targetTxnAmount, targetWalletId, decimal.NewFromFloat(100).Add(targetTxnAmount))
 
 		mock.ExpectCommit()
 
@@ -152,7 +167,7 @@ func TestHandleTransferMoney_WalletToUser_SameCcy_Success(t *testing.T) {
 		handler := handler.HandlerDB{DB: db}
 		handler.HandleTransferMoney(rec, req)
 
-		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, http.StatusCreated, rec.Code)
 	})
 }
 
@@ -192,12 +207,18 @@ func TestHandleTransferMoney_WalletToWallet_Success(t *testing.T) {
 		testutils.MockGetWalletById(mock, targetWallet)
 
 		// GetCcyRate
-		mock.ExpectQuery("SELECT to_ccy, rate FROM ccy_conversion WHERE from_ccy = \\$1 AND to_ccy in \\(\\$2, \\$3\\)").
-			WithArgs(models.BaseCcy, sourceWallet.Currency, targetWallet.Currency).
-			WillReturnRows(sqlmock.NewRows([]string{"to_ccy", "rate"}).AddRow("SGD", decimal.NewFromFloat(1.35)))
+		testutils.MockGetCcyConversions(mock, testutils.MockCcyConversions()...)
+		testutils.MockGetFxSpreadRules(mock, sourceWallet.UserId)
+
+		testutils.MockGetFeeRules(mock, models.FeeTypeTransfer)
 
 		mock.ExpectBegin()
 
+		//createTransfer
+		testutils.MockCreateTransfer(mock, models.Transfer{
+			ID: int64(31), SourceWalletId: sourceWallet.ID, TargetWalletId: targetWallet.ID,
+			SourceAmount: sourceTxnAmount, RatePath: "SGD>USD", Status: models.TransferStatusCompleted, CreatedAt: time.Now()})
+
 		testutils.MockGetBalance(mock, sourceWallet.Balance, sourceWallet.ID)
 
 		//createTransaction source
@@ -209,14 +230,14 @@ func TestHandleTransferMoney_WalletToWallet_Success(t *testing.T) {
 		testutils.MockUpdateBalanceByWalletID(mock, sourceWallet.Balance.Sub(sourceTxnAmount), sourceWallet.ID)
 
 		rate := decimal.NewFromFloat(1).Div(decimal.NewFromFloat(1.35))
-		targetTxnAmount := sourceTxnAmount.Mul(rate)
+		targetTxnAmount := sourceTxnAmount.Mul(rate).Round(models.AmountScale)
 		//createTransaction target
 		testutils.MockCreateTransaction(mock, models.Transaction{
 			ID: int64(402), WalletId: targetWallet.ID, Type: models.TxnTypeTransferIn,
 			Amount: targetTxnAmount, CounterpartyWalletId: sql.NullInt64{Valid: true, Int64: sourceWallet.ID}, CreatedAt: time.Now()})
 
 		//updateBalanceByWalletID target
-		testutils.MockIncrementBalanceByWalletID(mock, targetTxnAmount, targetWallet.ID)
+		testutils.MockIncrementBalanceByWalletID(mock, targetTxnAmount, targetWallet.ID, targetWallet.Balance.Add(targetTxnAmount))
 
 		mock.ExpectCommit()
 
@@ -228,7 +249,23 @@ func TestHandleTransferMoney_WalletToWallet_Success(t *testing.T) {
 		handler := handler.HandlerDB{DB: db}
 		handler.HandleTransferMoney(rec, req)
 
-		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "/transfers/31", rec.Header().Get("Location"))
+
+		var resp models.TransferResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, int64(31), resp.ID)
+		assert.Equal(t, models.TransferStatusCompleted, resp.Status)
+		assert.True(t, resp.Rate.Equal(rate))
+		assert.Equal(t, "SGD>USD", resp.RatePath)
+		assert.Equal(t, int64(401), resp.TransferOut.ID)
+		assert.Equal(t, int64(31), *resp.TransferOut.TransferID)
+		assert.Equal(t, sourceWallet.Currency, resp.TransferOut.Currency)
+		assert.True(t, resp.TransferOut.Balance.Equal(sourceWallet.Balance.Sub(sourceTxnAmount)))
+		assert.Equal(t, int64(402), resp.TransferIn.ID)
+		assert.Equal(t, targetWallet.Currency, resp.TransferIn.Currency)
+		assert.True(t, resp.TransferIn.Amount.Equal(targetTxnAmount.Round(2)))
 	})
 }
 
@@ -250,8 +287,9 @@ func TestHandleTransferMoney_BalanceNotEnough(t *testing.T) {
 		handler := handler.HandlerDB{DB: db}
 		handler.HandleTransferMoney(rec, req)
 
-		assert.Equal(t, http.StatusBadRequest, rec.Code)
-		assert.Contains(t, rec.Body.String(), "transferred is not allowed")
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		errResp := testutils.DecodeErrorResponse(t, rec)
+		assert.Equal(t, models.ErrCodeInsufficientFunds, errResp.Code)
 	})
 }
 
@@ -272,7 +310,8 @@ func TestHandleTransferMoney_SourceWalletNotFound(t *testing.T) {
 		handler.HandleTransferMoney(rec, req)
 
 		assert.Equal(t, http.StatusNotFound, rec.Code)
-		assert.Contains(t, rec.Body.String(), "source wallet not found")
+		errResp := testutils.DecodeErrorResponse(t, rec)
+		assert.Equal(t, models.ErrCodeWalletNotFound, errResp.Code)
 	})
 
 }
@@ -326,3 +365,334 @@ func TestHandleTransferMoney_InvalidSourceWalletId(t *testing.T) {
 	assert.Contains(t, rec.Body.String(), "invalid wallet id")
 
 }
+
+func postTransferPreview(db *sql.DB, walletId int64, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/wallets/%d/transfer/preview", walletId), strings.NewReader(body))
+	req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(walletId, 10)})
+
+	rec := httptest.NewRecorder()
+	handler := handler.HandlerDB{DB: db}
+	handler.HandlePreviewTransfer(rec, req)
+	return rec
+}
+
+func TestHandlePreviewTransfer_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		sourceWallet := getSourceWallet()
+		targetWallet := getTargetWallet()
+		house := testutils.MockHouseWallet()
+		house.Currency = sourceWallet.Currency
+		rule := models.FeeRule{ID: 72, FeeType: models.FeeTypeTransfer, Percentage: decimal.NewFromInt(1)}
+
+		testutils.MockGetWalletById(mock, sourceWallet)
+		testutils.MockGetWalletBalance(mock, sourceWallet.Balance, decimal.NewFromFloat(1400), sourceWallet.ID)
+		testutils.MockGetWalletById(mock, targetWallet)
+		testutils.MockGetCcyConversions(mock, testutils.MockCcyConversions()...)
+		testutils.MockGetFxSpreadRules(mock, sourceWallet.UserId)
+		testutils.MockGetFeeRules(mock, models.FeeTypeTransfer, rule)
+		testutils.MockGetWalletByUserIDs(mock, []models.Wallet{house})
+
+		rec := postTransferPreview(db, sourceWallet.ID, `{"amount": 135, "destination_wallet_id": 210}`)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.TransferPreviewResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.True(t, resp.Valid)
+		assert.Empty(t, resp.Errors)
+		assert.Equal(t, targetWallet.ID, *resp.TargetWalletID)
+		assert.Equal(t, targetWallet.UserId, *resp.TargetUserID)
+		assert.Equal(t, "USD", resp.TargetCurrency)
+		assert.True(t, resp.TargetAmount.Equal(decimal.NewFromInt(100)))
+		require.NotNil(t, resp.Fee)
+		assert.Equal(t, models.FeeStatusQuoted, resp.Fee.Status)
+		assert.True(t, resp.Fee.Amount.Equal(decimal.NewFromFloat(1.35)))
+		assert.True(t, resp.TotalDebit.Equal(decimal.NewFromFloat(136.35)))
+	})
+}
+
+func TestHandlePreviewTransfer_InsufficientAvailableBalance(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		sourceWallet := getSourceWallet()
+		targetWallet := getTargetWallet()
+		targetWallet.Currency = sourceWallet.Currency
+
+		testutils.MockGetWalletById(mock, sourceWallet)
+		// Holds reserve most of the ledger balance
+		testutils.MockGetWalletBalance(mock, sourceWallet.Balance, decimal.NewFromFloat(100), sourceWallet.ID)
+		testutils.MockGetWalletById(mock, targetWallet)
+		testutils.MockGetFeeRules(mock, models.FeeTypeTransfer)
+
+		rec := postTransferPreview(db, sourceWallet.ID, `{"amount": 500, "destination_wallet_id": 210}`)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.TransferPreviewResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.False(t, resp.Valid)
+		require.Equal(t, 1, len(resp.Errors))
+		assert.Equal(t, models.ErrCodeInsufficientFunds, resp.Errors[0].Code)
+		assert.Nil(t, resp.Fee)
+		assert.True(t, resp.TargetAmount.Equal(decimal.NewFromInt(500)))
+	})
+}
+
+func TestHandlePreviewTransfer_TargetNotFound(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		sourceWallet := getSourceWallet()
+
+		testutils.MockGetWalletById(mock, sourceWallet)
+		testutils.MockGetWalletBalance(mock, sourceWallet.Balance, sourceWallet.Balance, sourceWallet.ID)
+		testutils.MockGetWalletByIdNoRecord(mock, int64(999))
+
+		rec := postTransferPreview(db, sourceWallet.ID, `{"amount": 50, "destination_wallet_id": 999}`)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.TransferPreviewResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.False(t, resp.Valid)
+		require.Equal(t, 1, len(resp.Errors))
+		assert.Equal(t, models.ErrCodeWalletNotFound, resp.Errors[0].Code)
+		assert.Nil(t, resp.TargetWalletID)
+		assert.Nil(t, resp.Rate)
+	})
+}
+
+func TestHandlePreviewTransfer_InvalidRequest(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		sourceWallet := getSourceWallet()
+
+		testutils.MockGetWalletById(mock, sourceWallet)
+		testutils.MockGetWalletBalance(mock, sourceWallet.Balance, sourceWallet.Balance, sourceWallet.ID)
+
+		rec := postTransferPreview(db, sourceWallet.ID, `{"amount": 50}`)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.TransferPreviewResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.False(t, resp.Valid)
+		require.Equal(t, 1, len(resp.Errors))
+		assert.Equal(t, models.ErrCodeValidationFailed, resp.Errors[0].Code)
+	})
+}
+
+func TestHandleTransferMoney_CrossCcy_AppliesSpread(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		sourceTxnAmount := decimal.NewFromFloat(50)
+		sourceWallet := getSourceWallet()
+		targetWallet := getTargetWallet()
+		midRate := decimal.NewFromFloat(1).Div(decimal.NewFromFloat(1.35))
+		// The sender sells SGD at the bid, 0.5% below the mid rate
+		rate := midRate.Mul(decimal.NewFromFloat(99.5)).Div(decimal.NewFromInt(100))
+		targetTxnAmount := sourceTxnAmount.Mul(rate).Round(models.AmountScale)
+
+		testutils.MockGetWalletById(mock, sourceWallet)
+		testutils.MockGetWalletById(mock, targetWallet)
+		testutils.MockGetCcyConversions(mock, testutils.MockCcyConversions()...)
+		testutils.MockGetFxSpreadRules(mock, sourceWallet.UserId, testutils.MockFxSpreadRule())
+		testutils.MockGetFeeRules(mock, models.FeeTypeTransfer)
+
+		mock.ExpectBegin()
+		testutils.MockCreateTransfer(mock, models.Transfer{
+			ID: int64(31), SourceWalletId: sourceWallet.ID, TargetWalletId: targetWallet.ID,
+			SourceAmount: sourceTxnAmount, RatePath: "SGD>USD", Status: models.TransferStatusCompleted, CreatedAt: time.Now()})
+		testutils.MockGetBalance(mock, sourceWallet.Balance, sourceWallet.ID)
+		testutils.MockCreateTransaction(mock, models.Transaction{
+			ID: int64(401), WalletId: sourceWallet.ID, Type: models.TxnTypeTransferOut,
+			Amount: sourceTxnAmount, CreatedAt: time.Now()})
+		testutils.MockUpdateBalanceByWalletID(mock, sourceWallet.Balance.Sub(sourceTxnAmount), sourceWallet.ID)
+		testutils.MockCreateTransaction(mock, models.Transaction{
+			ID: int64(402), WalletId: targetWallet.ID, Type: models.TxnTypeTransferIn,
+			Amount: targetTxnAmount, CreatedAt: time.Now()})
+		testutils.MockIncrementBalanceByWalletID(mock, targetTxnAmount, targetWallet.ID, targetWallet.Balance.Add(targetTxnAmount))
+		// 37.04 USD at the mid rate, 36.85 received
+		testutils.MockCreateSpreadRevenue(mock, 401, models.SpreadRevenue{Currency: "USD", MidRate: midRate, Rate: rate,
+			Amount: decimal.NewFromFloat(0.19)})
+		mock.ExpectCommit()
+
+		requestBody := fmt.Sprintf(`{"amount": %s, "destination_wallet_id": %d}`, sourceTxnAmount.String(), targetWallet.ID)
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/wallets/%d/transfer", sourceWallet.ID), strings.NewReader(requestBody))
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(sourceWallet.ID, 10)})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleTransferMoney(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var resp models.TransferResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.True(t, rate.Equal(resp.Rate))
+		require.NotNil(t, resp.TransferOut.EffectiveRate)
+		assert.True(t, rate.Equal(*resp.TransferOut.EffectiveRate))
+		require.NotNil(t, resp.TransferIn.EffectiveRate)
+	})
+}
+
+func TestHandleTransferMoney_WithQuote_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		sourceTxnAmount := decimal.NewFromFloat(100)
+		sourceWallet := getSourceWallet()
+		targetWallet := getTargetWallet()
+		quote := testutils.MockFxQuote()
+		targetTxnAmount := sourceTxnAmount.Mul(quote.Rate).Round(models.AmountScale)
+
+		testutils.MockGetWalletById(mock, sourceWallet)
+		testutils.MockGetWalletById(mock, targetWallet)
+		// The quoted rate is used instead of the current rate
+		testutils.MockGetFxQuoteById(mock, quote, false)
+		testutils.MockGetFeeRules(mock, models.FeeTypeTransfer)
+
+		mock.ExpectBegin()
+		testutils.MockCreateTransfer(mock, models.Transfer{
+			ID: int64(31), SourceWalletId: sourceWallet.ID, TargetWalletId: targetWallet.ID,
+			SourceAmount: sourceTxnAmount, RatePath: "SGD>USD", Status: models.TransferStatusCompleted, CreatedAt: time.Now()})
+		testutils.MockUseFxQuote(mock, quote, int64(31))
+		testutils.MockGetBalance(mock, sourceWallet.Balance, sourceWallet.ID)
+		testutils.MockCreateTransaction(mock, models.Transaction{
+			ID: int64(401), WalletId: sourceWallet.ID, Type: models.TxnTypeTransferOut,
+			Amount: sourceTxnAmount, CreatedAt: time.Now()})
+		testutils.MockUpdateBalanceByWalletID(mock, sourceWallet.Balance.Sub(sourceTxnAmount), sourceWallet.ID)
+		testutils.MockCreateTransaction(mock, models.Transaction{
+			ID: int64(402), WalletId: targetWallet.ID, Type: models.TxnTypeTransferIn,
+			Amount: targetTxnAmount, CreatedAt: time.Now()})
+		testutils.MockIncrementBalanceByWalletID(mock, targetTxnAmount, targetWallet.ID, targetWallet.Balance.Add(targetTxnAmount))
+		// The spread is taken from the mid rate locked by the quote: 76.00 at the mid rate, 75.00 received
+		testutils.MockCreateSpreadRevenue(mock, 401, models.SpreadRevenue{Currency: "USD", MidRate: quote.MidRate, Rate: quote.Rate,
+			Amount: decimal.NewFromInt(1)})
+		mock.ExpectCommit()
+
+		requestBody := fmt.Sprintf(`{"amount": 100, "destination_wallet_id": %d, "quote_id": %d}`, targetWallet.ID, quote.ID)
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/wallets/%d/transfer", sourceWallet.ID), strings.NewReader(requestBody))
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(sourceWallet.ID, 10)})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleTransferMoney(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var resp models.TransferResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.True(t, quote.Rate.Equal(resp.Rate))
+		require.NotNil(t, resp.QuoteID)
+		assert.Equal(t, quote.ID, *resp.QuoteID)
+		assert.True(t, resp.TransferIn.Amount.Equal(targetTxnAmount))
+	})
+}
+
+func TestHandleTransferMoney_WithQuote_Expired(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		sourceWallet := getSourceWallet()
+		targetWallet := getTargetWallet()
+		quote := testutils.MockFxQuote()
+
+		testutils.MockGetWalletById(mock, sourceWallet)
+		testutils.MockGetWalletById(mock, targetWallet)
+		testutils.MockGetFxQuoteById(mock, quote, true)
+
+		requestBody := fmt.Sprintf(`{"amount": 100, "destination_wallet_id": %d, "quote_id": %d}`, targetWallet.ID, quote.ID)
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/wallets/%d/transfer", sourceWallet.ID), strings.NewReader(requestBody))
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(sourceWallet.ID, 10)})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleTransferMoney(rec, req)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, models.ErrCodeQuoteExpired, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
+
+func TestHandleTransferMoney_WithQuote_CurrencyMismatch(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		sourceWallet := getSourceWallet()
+		targetWallet := getTargetWallet()
+		targetWallet.Currency = "AUD"
+		quote := testutils.MockFxQuote()
+
+		testutils.MockGetWalletById(mock, sourceWallet)
+		testutils.MockGetWalletById(mock, targetWallet)
+		testutils.MockGetFxQuoteById(mock, quote, false)
+
+		requestBody := fmt.Sprintf(`{"amount": 100, "destination_wallet_id": %d, "quote_id": %d}`, targetWallet.ID, quote.ID)
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/wallets/%d/transfer", sourceWallet.ID), strings.NewReader(requestBody))
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(sourceWallet.ID, 10)})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleTransferMoney(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, models.ErrCodeValidationFailed, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
+
+func TestHandleTransferMoney_WithQuote_OtherUser(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		sourceWallet := getSourceWallet()
+		targetWallet := getTargetWallet()
+		// The quote was priced for another user, whose tier may get a narrower spread
+		quote := testutils.MockFxQuote()
+		quote.UserId = 102
+
+		testutils.MockGetWalletById(mock, sourceWallet)
+		testutils.MockGetWalletById(mock, targetWallet)
+		testutils.MockGetFxQuoteById(mock, quote, false)
+
+		requestBody := fmt.Sprintf(`{"amount": 100, "destination_wallet_id": %d, "quote_id": %d}`, targetWallet.ID, quote.ID)
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/wallets/%d/transfer", sourceWallet.ID), strings.NewReader(requestBody))
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(sourceWallet.ID, 10)})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleTransferMoney(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		errResp := testutils.DecodeErrorResponse(t, rec)
+		assert.Equal(t, models.ErrCodeValidationFailed, errResp.Code)
+		assert.Equal(t, "102", errResp.Details.(map[string]interface{})["user_id"])
+	})
+}
+
+func TestHandleTransferMoney_CrossCcy_RateStale(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		sourceWallet := getSourceWallet()
+		targetWallet := getTargetWallet()
+		conversions := testutils.MockCcyConversions()
+		conversions[1].Age = 48 * time.Hour
+
+		testutils.MockGetWalletById(mock, sourceWallet)
+		testutils.MockGetWalletById(mock, targetWallet)
+		testutils.MockGetCcyConversions(mock, conversions...)
+
+		requestBody := fmt.Sprintf(`{"amount": 100, "destination_wallet_id": %d}`, targetWallet.ID)
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/wallets/%d/transfer", sourceWallet.ID), strings.NewReader(requestBody))
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(sourceWallet.ID, 10)})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleTransferMoney(rec, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Equal(t, models.ErrCodeRateStale, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
@@ -0,0 +1,319 @@
+package handler_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/rudithu/CRYPTO-WalletApp/handler"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGetTransfer_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		transfer := testutils.MockTransfer()
+		testutils.MockGetTransferById(mock, transfer)
+
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/transfers/%d", transfer.ID), nil)
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(transfer.ID, 10)})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleGetTransfer(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.TransferSummary
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, transfer.ID, resp.ID)
+		assert.Equal(t, models.TransferStatusCompleted, resp.Status)
+		assert.Equal(t, "SGD>USD", resp.RatePath)
+		assert.Equal(t, transfer.SourceWalletId, resp.Source.WalletID)
+		assert.Equal(t, "SGD", resp.Source.Currency)
+		assert.Equal(t, int64(401), *resp.Source.TransactionID)
+		assert.Equal(t, transfer.TargetWalletId, resp.Target.WalletID)
+		assert.Equal(t, "USD", resp.Target.Currency)
+		assert.Equal(t, int64(402), *resp.Target.TransactionID)
+		assert.True(t, resp.Target.Amount.Equal(transfer.TargetAmount))
+	})
+}
+
+func TestHandleGetTransfer_NotFound(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		testutils.MockGetTransferByIdNoRecord(mock, int64(77))
+
+		req := httptest.NewRequest(http.MethodGet, "/transfers/77", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "77"})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleGetTransfer(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		errResp := testutils.DecodeErrorResponse(t, rec)
+		assert.Equal(t, models.ErrCodeTransferNotFound, errResp.Code)
+	})
+}
+
+func TestHandleGetTransfer_InvalidId(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/transfers/invalidId", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "invalidId"})
+
+	rec := httptest.NewRecorder()
+	handler := handler.HandlerDB{DB: nil}
+	handler.HandleGetTransfer(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	errResp := testutils.DecodeErrorResponse(t, rec)
+	assert.Equal(t, models.ErrCodeValidationFailed, errResp.Code)
+}
+
+func TestHandleUserTransfers_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		user := *testutils.MockUser()
+		wallets := testutils.MockWallets()
+		transfer := testutils.MockTransfer()
+		transfer.TargetWalletId = wallets[0].ID
+
+		testutils.MockGetUserById(mock, user)
+		testutils.MockGetWalletByUserIDs(mock, wallets)
+
+		mock.ExpectQuery(testutils.TransferSelectQuery+".+ WHERE t.source_wallet_id IN \\(\\$1, \\$2\\) OR t.target_wallet_id IN \\(\\$1, \\$2\\)").
+			WithArgs(wallets[0].ID, wallets[1].ID).
+			WillReturnRows(testutils.AddTransferRow(testutils.TransferRows(), transfer))
+
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/users/%d/transfers", user.ID), nil)
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(user.ID, 10)})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleUserTransfers(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.UserTransfersResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, resp.UserInfo.ID)
+		require.Equal(t, 1, len(resp.Transfers))
+		assert.Equal(t, transfer.ID, resp.Transfers[0].ID)
+		assert.Equal(t, wallets[0].ID, resp.Transfers[0].Target.WalletID)
+	})
+}
+
+func TestHandleUserTransfers_UserNotFound(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		mock.ExpectQuery("SELECT id, name, reporting_currency, created_at FROM users where id=\\$1").
+			WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "reporting_currency", "created_at"}))
+
+		req := httptest.NewRequest(http.MethodGet, "/users/5/transfers", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "5"})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleUserTransfers(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		errResp := testutils.DecodeErrorResponse(t, rec)
+		assert.Equal(t, models.ErrCodeUserNotFound, errResp.Code)
+	})
+}
+
+func TestHandleReverseTransfer_Full_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		original := testutils.MockTransfer()
+		testutils.MockGetTransferById(mock, original)
+
+		debitAmount := decimal.NewFromFloat(100)
+		recipientBalance := decimal.NewFromFloat(250)
+
+		mock.ExpectBegin()
+
+		mock.ExpectQuery("SELECT source_amount - reversed_amount FROM transfers WHERE id = \\$1 FOR UPDATE").
+			WithArgs(original.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"reversible"}).AddRow(original.SourceAmount))
+
+		testutils.MockCreateTransfer(mock, models.Transfer{
+			ID: int64(32), SourceWalletId: original.TargetWalletId, TargetWalletId: original.SourceWalletId,
+			SourceAmount: debitAmount, Status: models.TransferStatusCompleted, CreatedAt: time.Now()})
+
+		testutils.MockGetBalance(mock, recipientBalance, original.TargetWalletId)
+
+		testutils.MockCreateTransaction(mock, models.Transaction{
+			ID: int64(403), WalletId: original.TargetWalletId, Type: models.TxnTypeTransferOut, Amount: debitAmount, CreatedAt: time.Now()})
+
+		testutils.MockUpdateBalanceByWalletID(mock, recipientBalance.Sub(debitAmount), original.TargetWalletId)
+
+		testutils.MockCreateTransaction(mock, models.Transaction{
+			ID: int64(404), WalletId: original.SourceWalletId, Type: models.TxnTypeTransferIn, Amount: original.SourceAmount, CreatedAt: time.Now()})
+
+		testutils.MockIncrementBalanceByWalletID(mock, original.SourceAmount, original.SourceWalletId, decimal.NewFromFloat(1500))
+
+		mock.ExpectExec("UPDATE transfers SET reversed_amount").
+			WithArgs(original.SourceAmount, models.TransferStatusReversed, models.TransferStatusPartiallyReversed, original.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectCommit()
+
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/transfers/%d/reverse", original.ID), nil)
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(original.ID, 10)})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleReverseTransfer(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "/transfers/32", rec.Header().Get("Location"))
+
+		var resp models.TransferResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, int64(32), resp.ID)
+		assert.Equal(t, original.ID, *resp.ReversalOf)
+		assert.Equal(t, original.TargetWalletId, resp.TransferOut.WalletID)
+		assert.Equal(t, "USD", resp.TransferOut.Currency)
+		assert.True(t, resp.TransferOut.Amount.Equal(debitAmount))
+		assert.Equal(t, original.SourceWalletId, resp.TransferIn.WalletID)
+		assert.True(t, resp.TransferIn.Amount.Equal(original.SourceAmount))
+		assert.Equal(t, "reversal of transfer 31", resp.TransferIn.Description)
+	})
+}
+
+func TestHandleReverseTransfer_Partial_FeeNotRefunded(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		// The sender paid a fee on top of the 135 SGD, only the requested amount is refunded
+		original := testutils.MockTransfer()
+		testutils.MockGetTransferById(mock, original)
+
+		refundAmount := decimal.NewFromFloat(27)
+		debitAmount := decimal.NewFromFloat(20)
+		recipientBalance := decimal.NewFromFloat(250)
+
+		mock.ExpectBegin()
+
+		mock.ExpectQuery("SELECT source_amount - reversed_amount FROM transfers WHERE id = \\$1 FOR UPDATE").
+			WithArgs(original.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"reversible"}).AddRow(original.SourceAmount))
+
+		testutils.MockCreateTransfer(mock, models.Transfer{
+			ID: int64(32), SourceWalletId: original.TargetWalletId, TargetWalletId: original.SourceWalletId,
+			SourceAmount: debitAmount, Status: models.TransferStatusCompleted, CreatedAt: time.Now()})
+
+		testutils.MockGetBalance(mock, recipientBalance, original.TargetWalletId)
+
+		testutils.MockCreateTransaction(mock, models.Transaction{
+			ID: int64(403), WalletId: original.TargetWalletId, Type: models.TxnTypeTransferOut, Amount: debitAmount, CreatedAt: time.Now()})
+
+		testutils.MockUpdateBalanceByWalletID(mock, recipientBalance.Sub(debitAmount), original.TargetWalletId)
+
+		testutils.MockCreateTransaction(mock, models.Transaction{
+			ID: int64(404), WalletId: original.SourceWalletId, Type: models.TxnTypeTransferIn, Amount: refundAmount, CreatedAt: time.Now()})
+
+		testutils.MockIncrementBalanceByWalletID(mock, refundAmount, original.SourceWalletId, decimal.NewFromFloat(1392))
+
+		mock.ExpectExec("UPDATE transfers SET reversed_amount").
+			WithArgs(refundAmount, models.TransferStatusReversed, models.TransferStatusPartiallyReversed, original.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectCommit()
+
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/transfers/%d/reverse", original.ID), strings.NewReader(`{"amount": 27}`))
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(original.ID, 10)})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleReverseTransfer(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var resp models.TransferResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.True(t, resp.TransferOut.Amount.Equal(debitAmount))
+		assert.True(t, resp.TransferIn.Amount.Equal(refundAmount))
+		assert.Nil(t, resp.TransferOut.Fee)
+		assert.Nil(t, resp.TransferIn.Fee)
+	})
+}
+
+func TestHandleReverseTransfer_AmountTooLarge(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		original := testutils.MockTransfer()
+		original.Status = models.TransferStatusPartiallyReversed
+		original.ReversedAmount = decimal.NewFromFloat(100)
+		testutils.MockGetTransferById(mock, original)
+
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/transfers/%d/reverse", original.ID), strings.NewReader(`{"amount": 50}`))
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(original.ID, 10)})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleReverseTransfer(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		errResp := testutils.DecodeErrorResponse(t, rec)
+		assert.Equal(t, models.ErrCodeValidationFailed, errResp.Code)
+	})
+}
+
+func TestHandleReverseTransfer_AlreadyReversed(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		original := testutils.MockTransfer()
+		original.Status = models.TransferStatusReversed
+		original.ReversedAmount = original.SourceAmount
+		testutils.MockGetTransferById(mock, original)
+
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/transfers/%d/reverse", original.ID), nil)
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(original.ID, 10)})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleReverseTransfer(rec, req)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		errResp := testutils.DecodeErrorResponse(t, rec)
+		assert.Equal(t, models.ErrCodeNotReversible, errResp.Code)
+	})
+}
+
+func TestHandleReverseTransfer_ReversalOfReversal(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		reversal := testutils.MockTransfer()
+		reversal.ReversalOf = testutils.NullInt64(30, true)
+		testutils.MockGetTransferById(mock, reversal)
+
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/transfers/%d/reverse", reversal.ID), nil)
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatInt(reversal.ID, 10)})
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleReverseTransfer(rec, req)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		errResp := testutils.DecodeErrorResponse(t, rec)
+		assert.Equal(t, models.ErrCodeNotReversible, errResp.Code)
+	})
+}
@@ -0,0 +1,70 @@
+package handler_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/rudithu/CRYPTO-WalletApp/handler"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func patchUser(db *sql.DB, userId string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPatch, "/users/"+userId, strings.NewReader(body))
+	req = mux.SetURLVars(req, map[string]string{"id": userId})
+
+	rec := httptest.NewRecorder()
+	handler := handler.HandlerDB{DB: db}
+	handler.HandleUpdateUser(rec, req)
+	return rec
+}
+
+func TestHandleUpdateUser_ReportingCurrency(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+		mock.ExpectQuery("UPDATE users SET reporting_currency = \\$1 WHERE id = \\$2").
+			WithArgs("JPY", int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "reporting_currency", "created_at"}).
+				AddRow(int64(3), "Charlie", "JPY", time.Now()))
+
+		rec := patchUser(db, "3", `{"reporting_currency": "jpy"}`)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.UserResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), resp.ID)
+		assert.Equal(t, "JPY", resp.ReportingCurrency)
+	})
+}
+
+func TestHandleUpdateUser_InvalidCurrency(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+		rec := patchUser(db, "3", `{"reporting_currency": "yen!"}`)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, models.ErrCodeValidationFailed, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
+
+func TestHandleUpdateUser_NotFound(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+		mock.ExpectQuery("UPDATE users SET reporting_currency").
+			WithArgs("SGD", int64(99)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "reporting_currency", "created_at"}))
+
+		rec := patchUser(db, "99", `{"reporting_currency": "SGD"}`)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, models.ErrCodeUserNotFound, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
@@ -0,0 +1,212 @@
+package handler_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/rudithu/CRYPTO-WalletApp/handler"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func balanceWallet() models.Wallet {
+	return models.Wallet{
+		ID:        1,
+		UserId:    1,
+		Balance:   decimal.NewFromFloat(250),
+		Currency:  "USD",
+		Type:      "primary",
+		IsDefault: true,
+		CreatedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func walletBalanceRequest(url string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	return mux.SetURLVars(req, map[string]string{"id": "1"})
+}
+
+func TestHandleWalletBalance_Current(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		wallet := balanceWallet()
+		testutils.MockGetWalletById(mock, wallet)
+		testutils.MockGetWalletBalance(mock, wallet.Balance, decimal.NewFromFloat(200), wallet.ID)
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleWalletBalance(rec, walletBalanceRequest("/wallets/1/balance"))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.WalletBalanceAtResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, wallet.ID, resp.WalletID)
+		assert.True(t, resp.Balance.Equal(decimal.NewFromFloat(250)))
+		if assert.NotNil(t, resp.AvailableBalance) {
+			assert.True(t, resp.AvailableBalance.Equal(decimal.NewFromFloat(200)))
+		}
+	})
+}
+
+func TestHandleWalletBalance_AsOfDate(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		wallet := balanceWallet()
+		// a date stands for the end of that day
+		asOf := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+		testutils.MockGetWalletById(mock, wallet)
+		testutils.MockGetWalletBalanceAt(mock, wallet.ID, asOf, decimal.NewFromFloat(180))
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleWalletBalance(rec, walletBalanceRequest("/wallets/1/balance?as_of=2025-05-31"))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.WalletBalanceAtResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.True(t, resp.Balance.Equal(decimal.NewFromFloat(180)))
+		assert.Nil(t, resp.AvailableBalance)
+		assert.True(t, asOf.Equal(resp.AsOf))
+	})
+}
+
+func TestHandleWalletBalance_AsOfTimestamp(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		wallet := balanceWallet()
+		asOf := time.Date(2025, 5, 31, 15, 0, 0, 0, time.UTC)
+		testutils.MockGetWalletById(mock, wallet)
+		testutils.MockGetWalletBalanceAt(mock, wallet.ID, asOf, decimal.NewFromFloat(90))
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleWalletBalance(rec, walletBalanceRequest("/wallets/1/balance?as_of=2025-05-31T23:00:00%2B08:00"))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestHandleWalletBalance_InvalidAsOf(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		testutils.MockGetWalletById(mock, balanceWallet())
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleWalletBalance(rec, walletBalanceRequest("/wallets/1/balance?as_of=yesterday"))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, models.ErrCodeValidationFailed, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
+
+func TestHandleWalletBalance_AsOfInFuture(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		testutils.MockGetWalletById(mock, balanceWallet())
+		asOf := time.Now().UTC().Add(time.Hour).Format(time.RFC3339)
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleWalletBalance(rec, walletBalanceRequest("/wallets/1/balance?as_of="+asOf))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, models.ErrCodeValidationFailed, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
+
+func TestHandleWalletBalance_WalletNotFound(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		testutils.MockGetWalletByIdNoRecord(mock, int64(1))
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleWalletBalance(rec, walletBalanceRequest("/wallets/1/balance"))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, models.ErrCodeWalletNotFound, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
+
+func TestHandleWalletBalanceHistory_Success(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		wallet := balanceWallet()
+		from := time.Date(2025, 5, 30, 0, 0, 0, 0, time.UTC)
+		until := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+
+		testutils.MockGetWalletById(mock, wallet)
+		testutils.MockGetWalletBalanceAt(mock, wallet.ID, from, decimal.NewFromFloat(100))
+		testutils.MockGetBalanceEvents(mock, wallet.ID, from, until,
+			models.BalanceEvent{At: from.Add(10 * time.Hour), Delta: decimal.NewFromFloat(50)},
+			models.BalanceEvent{At: until.Add(-time.Hour), Delta: decimal.NewFromFloat(-30)},
+		)
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleWalletBalanceHistory(rec, walletBalanceRequest("/wallets/1/balance/history?from=2025-05-30&to=2025-06-01"))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp models.BalanceHistoryResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, "2025-05-30", resp.From)
+		assert.Equal(t, "2025-06-01", resp.To)
+		if assert.Len(t, resp.Balances, 3) {
+			assert.Equal(t, "2025-05-30", resp.Balances[0].Date)
+			assert.True(t, resp.Balances[0].Balance.Equal(decimal.NewFromFloat(150)))
+			assert.True(t, resp.Balances[1].Balance.Equal(decimal.NewFromFloat(150)))
+			assert.True(t, resp.Balances[2].Balance.Equal(decimal.NewFromFloat(120)))
+		}
+	})
+}
+
+func assertBalanceHistoryRejected(t *testing.T, query string) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		testutils.MockGetWalletById(mock, balanceWallet())
+
+		rec := httptest.NewRecorder()
+		handler := handler.HandlerDB{DB: db}
+		handler.HandleWalletBalanceHistory(rec, walletBalanceRequest("/wallets/1/balance/history"+query))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, models.ErrCodeValidationFailed, testutils.DecodeErrorResponse(t, rec).Code)
+	})
+}
+
+func TestHandleWalletBalanceHistory_MissingFrom(t *testing.T) {
+	assertBalanceHistoryRejected(t, "")
+}
+
+func TestHandleWalletBalanceHistory_InvalidFrom(t *testing.T) {
+	assertBalanceHistoryRejected(t, "?from=31-05-2025")
+}
+
+func TestHandleWalletBalanceHistory_FromAfterTo(t *testing.T) {
+	assertBalanceHistoryRejected(t, "?from=2025-06-01&to=2025-05-01")
+}
+
+func TestHandleWalletBalanceHistory_ToInFuture(t *testing.T) {
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	assertBalanceHistoryRejected(t, "?from=2025-06-01&to="+tomorrow)
+}
+
+func TestHandleWalletBalanceHistory_RangeTooLong(t *testing.T) {
+	assertBalanceHistoryRejected(t, "?from=2023-01-01&to=2025-01-01")
+}
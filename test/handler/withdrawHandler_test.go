@@ -2,6 +2,7 @@ package handler_test
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -17,6 +18,7 @@ import (
 	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHandleWithdrawMoney_Success(t *testing.T) {
@@ -37,14 +39,16 @@ func TestHandleWithdrawMoney_Success(t *testing.T) {
 			CreatedAt: time.Now(),
 		})
 
+		testutils.MockGetFeeRules(mock, models.FeeTypeWithdraw)
+
 		mock.ExpectBegin()
 
 		testutils.MockGetBalance(mock, decimal.NewFromFloat(100.00), walletId)
 
 		// Insert transaction
 		mock.ExpectQuery("INSERT INTO transactions").
-			WithArgs(walletId, models.TxnTypeWithdraw, amount, sqlmock.AnyArg()).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(10, time.Now()))
+			WithArgs(walletId, models.TxnTypeWithdraw, amount, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "completed_at"}).AddRow(10, time.Now(), time.Now()))
 
 		// Update wallet balance
 		mock.ExpectExec("UPDATE wallets SET balance = \\$1 WHERE id = \\$2").
@@ -62,7 +66,8 @@ func TestHandleWithdrawMoney_Success(t *testing.T) {
 
 		handler.HandleWithdrawMoney(rr, req)
 
-		assert.Equal(t, http.StatusNoContent, rr.Code)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, "/transactions/10", rr.Header().Get("Location"))
 		assert.NoError(t, mock.ExpectationsWereMet())
 
 	})
@@ -88,9 +93,81 @@ func TestHandleWithdrawMoney_WalletNotFound(t *testing.T) {
 
 		handler.HandleWithdrawMoney(rec, req)
 		assert.Equal(t, http.StatusNotFound, rec.Code)
-		assert.Contains(t, rec.Body.String(), "wallet not found")
+		errResp := testutils.DecodeErrorResponse(t, rec)
+		assert.Equal(t, models.ErrCodeWalletNotFound, errResp.Code)
+	})
+
+}
+
+func TestHandleWithdrawMoney_WithFee(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+		handler := &handler.HandlerDB{DB: db}
+
+		walletId := int64(1)
+		amount := decimal.NewFromInt(300)
+		house := testutils.MockHouseWallet()
+		now := time.Now()
+
+		testutils.MockGetWalletById(mock, models.Wallet{ID: walletId, UserId: 123, Balance: decimal.NewFromInt(500), Currency: "USD", Type: "saving", CreatedAt: now})
+		testutils.MockGetFeeRules(mock, models.FeeTypeWithdraw, testutils.MockFeeRule())
+		testutils.MockGetWalletByUserIDs(mock, []models.Wallet{house})
+
+		mock.ExpectBegin()
+		testutils.MockGetBalance(mock, decimal.NewFromInt(500), walletId)
+		testutils.MockCreateTransaction(mock, models.Transaction{ID: 10, WalletId: walletId, Type: models.TxnTypeWithdraw, Amount: amount, CreatedAt: now})
+		testutils.MockUpdateBalanceByWalletID(mock, decimal.NewFromInt(196), walletId)
+		testutils.MockCreateTransaction(mock, models.Transaction{ID: 11, WalletId: walletId, Type: models.TxnTypeFee, Amount: decimal.NewFromInt(4), CreatedAt: now})
+		testutils.MockCreateTransaction(mock, models.Transaction{ID: 12, WalletId: house.ID, Type: models.TxnTypeFeeIncome, Amount: decimal.NewFromInt(4), CreatedAt: now})
+		testutils.MockIncrementBalanceByWalletID(mock, decimal.NewFromInt(4), house.ID, decimal.NewFromInt(4))
+		mock.ExpectQuery("INSERT INTO fees").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(81, now))
+		mock.ExpectCommit()
+
+		req := httptest.NewRequest(http.MethodPost, "/wallets/1/withdraw", strings.NewReader(`{"amount": 300}`))
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+
+		rr := httptest.NewRecorder()
+
+		handler.HandleWithdrawMoney(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+
+		var resp models.TransactionResponse
+		err := json.Unmarshal(rr.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.True(t, resp.Balance.Equal(decimal.NewFromInt(200)))
+		require.NotNil(t, resp.Fee)
+		assert.Equal(t, models.FeeStatusCharged, resp.Fee.Status)
+		assert.Equal(t, int64(71), resp.Fee.FeeRuleID)
+		assert.True(t, resp.Fee.Amount.Equal(decimal.NewFromInt(4)))
+		assert.True(t, resp.Fee.FlatFee.Equal(decimal.NewFromInt(1)))
+		assert.True(t, resp.Fee.PercentageFee.Equal(decimal.NewFromInt(3)))
+		assert.Equal(t, int64(11), *resp.Fee.TransactionID)
+		assert.True(t, resp.Fee.Balance.Equal(decimal.NewFromInt(196)))
 	})
+}
+
+func TestHandleWithdrawMoney_NoHouseWalletForFee(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+		handler := &handler.HandlerDB{DB: db}
 
+		// The house only collects fees in USD
+		testutils.MockGetWalletById(mock, models.Wallet{ID: 1, UserId: 123, Balance: decimal.NewFromInt(500), Currency: "EUR", Type: "saving", CreatedAt: time.Now()})
+		testutils.MockGetFeeRules(mock, models.FeeTypeWithdraw, testutils.MockFeeRule())
+		testutils.MockGetWalletByUserIDs(mock, []models.Wallet{testutils.MockHouseWallet()})
+
+		req := httptest.NewRequest(http.MethodPost, "/wallets/1/withdraw", strings.NewReader(`{"amount": 300}`))
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+
+		rr := httptest.NewRecorder()
+
+		handler.HandleWithdrawMoney(rr, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		errResp := testutils.DecodeErrorResponse(t, rr)
+		assert.Equal(t, models.ErrCodeFeeNotConfigured, errResp.Code)
+		assert.Equal(t, "EUR", errResp.Details.(map[string]interface{})["currency"])
+	})
 }
 
 func TestHandleWithdrawMoney_AmountIsGreaterThanBalance(t *testing.T) {
@@ -118,8 +195,9 @@ func TestHandleWithdrawMoney_AmountIsGreaterThanBalance(t *testing.T) {
 
 		handler.HandleWithdrawMoney(rec, req)
 
-		assert.Equal(t, http.StatusBadRequest, rec.Code)
-		assert.Contains(t, rec.Body.String(), "withdrawal is not allowed")
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		errResp := testutils.DecodeErrorResponse(t, rec)
+		assert.Equal(t, models.ErrCodeInsufficientFunds, errResp.Code)
 	})
 }
 
@@ -0,0 +1,45 @@
+package jobs_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rudithu/CRYPTO-WalletApp/jobs"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockCreateBalanceSnapshots(mock sqlmock.Sqlmock, at time.Time, recorded int64) {
+	mock.ExpectExec("INSERT INTO wallet_balance_snapshots").
+		WithArgs(at).
+		WillReturnResult(sqlmock.NewResult(0, recorded))
+}
+
+func TestTakeBalanceSnapshots_StartOfDay(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		now := time.Date(2025, 6, 1, 10, 30, 0, 0, time.UTC)
+		mockCreateBalanceSnapshots(mock, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), 4)
+
+		recorded, err := jobs.TakeBalanceSnapshots(db, now)
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(4), recorded)
+	})
+}
+
+func TestTakeBalanceSnapshots_JustAfterMidnightTakesPreviousDay(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		// transactions completing just before midnight may not be committed yet
+		now := time.Date(2025, 6, 1, 0, 1, 0, 0, time.UTC)
+		mockCreateBalanceSnapshots(mock, time.Date(2025, 5, 31, 0, 0, 0, 0, time.UTC), 0)
+
+		recorded, err := jobs.TakeBalanceSnapshots(db, now)
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), recorded)
+	})
+}
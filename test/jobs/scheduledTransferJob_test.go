@@ -0,0 +1,131 @@
+package jobs_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rudithu/CRYPTO-WalletApp/jobs"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func lowBalanceWallet() models.Wallet {
+	return models.Wallet{
+		ID:        1,
+		UserId:    1,
+		Balance:   decimal.NewFromFloat(10),
+		Currency:  "USD",
+		Type:      "primary",
+		IsDefault: true,
+		CreatedAt: time.Now(),
+	}
+}
+
+func mockDueScheduledTransfers(mock sqlmock.Sqlmock, now time.Time, s models.ScheduledTransfer) {
+	mock.ExpectQuery(testutils.ScheduledTransferSelectQuery+".+ WHERE st.status = \\$1").
+		WithArgs(models.ScheduleStatusActive, now, sqlmock.AnyArg()).
+		WillReturnRows(testutils.AddScheduledTransferRow(testutils.ScheduledTransferRows(), s))
+}
+
+func TestRunDueScheduledTransfers_InsufficientFundsRetry(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		s := testutils.MockScheduledTransfer()
+		now := s.NextRunAt.Time.Add(time.Minute)
+
+		mockDueScheduledTransfers(mock, now, s)
+		testutils.MockGetWalletById(mock, lowBalanceWallet())
+		mock.ExpectBegin()
+		testutils.MockLockScheduledTransfer(mock, s)
+		testutils.MockCreateScheduledTransferRun(mock, s, 1, models.RunStatusRetryScheduled)
+		testutils.MockSaveScheduledTransfer(mock, s, models.ScheduleStatusActive, 0, 1)
+		mock.ExpectCommit()
+
+		recorded, err := jobs.RunDueScheduledTransfers(db, now)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, recorded)
+	})
+}
+
+func TestRunDueScheduledTransfers_CatchesUpLatestOccurrenceOnly(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		// Due since January, the job finds it in April: January to March are skipped, April runs
+		s := testutils.MockScheduledTransfer()
+		s.StartAt = time.Date(2025, 1, 10, 9, 0, 0, 0, time.UTC)
+		s.NextRunAt = sql.NullTime{Time: s.StartAt, Valid: true}
+		now := time.Date(2025, 4, 10, 10, 0, 0, 0, time.UTC)
+
+		caughtUp := s
+		caughtUp.Occurrences = 3
+		caughtUp.NextRunAt = sql.NullTime{Time: time.Date(2025, 4, 10, 9, 0, 0, 0, time.UTC), Valid: true}
+
+		mockDueScheduledTransfers(mock, now, s)
+		mock.ExpectBegin()
+		testutils.MockLockScheduledTransfer(mock, s)
+		testutils.MockSaveScheduledTransfer(mock, s, models.ScheduleStatusActive, 3, 0)
+		mock.ExpectCommit()
+
+		testutils.MockGetWalletById(mock, lowBalanceWallet())
+		mock.ExpectBegin()
+		testutils.MockLockScheduledTransfer(mock, caughtUp)
+		testutils.MockCreateScheduledTransferRun(mock, caughtUp, 1, models.RunStatusRetryScheduled)
+		testutils.MockSaveScheduledTransfer(mock, caughtUp, models.ScheduleStatusActive, 3, 1)
+		mock.ExpectCommit()
+
+		recorded, err := jobs.RunDueScheduledTransfers(db, now)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, recorded)
+	})
+}
+
+func TestRunDueScheduledTransfers_InsufficientFundsSkip(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		s := testutils.MockScheduledTransfer()
+		s.OnInsufficientFunds = models.InsufficientFundsSkip
+		now := s.NextRunAt.Time.Add(time.Minute)
+
+		mockDueScheduledTransfers(mock, now, s)
+		testutils.MockGetWalletById(mock, lowBalanceWallet())
+		mock.ExpectBegin()
+		testutils.MockLockScheduledTransfer(mock, s)
+		testutils.MockCreateScheduledTransferRun(mock, s, 1, models.RunStatusSkipped)
+		testutils.MockSaveScheduledTransfer(mock, s, models.ScheduleStatusActive, 1, 0)
+		mock.ExpectCommit()
+
+		recorded, err := jobs.RunDueScheduledTransfers(db, now)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, recorded)
+	})
+}
+
+func TestRunDueScheduledTransfers_RetriesExhausted(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		s := testutils.MockScheduledTransfer()
+		s.Attempts = models.DefaultScheduleMaxRetries
+		s.RetryAt = sql.NullTime{Time: s.NextRunAt.Time.Add(time.Hour), Valid: true}
+		now := s.RetryAt.Time.Add(time.Minute)
+
+		mockDueScheduledTransfers(mock, now, s)
+		testutils.MockGetWalletById(mock, lowBalanceWallet())
+		mock.ExpectBegin()
+		testutils.MockLockScheduledTransfer(mock, s)
+		testutils.MockCreateScheduledTransferRun(mock, s, models.DefaultScheduleMaxRetries+1, models.RunStatusSkipped)
+		testutils.MockSaveScheduledTransfer(mock, s, models.ScheduleStatusActive, 1, 0)
+		mock.ExpectCommit()
+
+		recorded, err := jobs.RunDueScheduledTransfers(db, now)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, recorded)
+	})
+}
@@ -0,0 +1,68 @@
+package jobs_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rudithu/CRYPTO-WalletApp/jobs"
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/rudithu/CRYPTO-WalletApp/test/testutils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockGetWalletsWithoutStatement(mock sqlmock.Sqlmock, period time.Time, wallets ...models.Wallet) {
+	rows := sqlmock.NewRows([]string{"id", "user_id", "balance", "currency", "type", "is_default", "created_at"})
+	for _, w := range wallets {
+		rows = rows.AddRow(w.ID, w.UserId, w.Balance, w.Currency, w.Type, w.IsDefault, w.CreatedAt)
+	}
+
+	mock.ExpectQuery("SELECT w.id, w.user_id, .+ FROM wallets w").
+		WithArgs(period, period.AddDate(0, 1, 0)).
+		WillReturnRows(rows)
+}
+
+func TestGenerateStatements_PreviousMonth(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		now := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+		period := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+		wallets := []models.Wallet{
+			{ID: 1, UserId: 3, Currency: "USD"},
+			{ID: 2, UserId: 3, Currency: "EUR"},
+		}
+		mockGetWalletsWithoutStatement(mock, period, wallets...)
+
+		testutils.MockGetWalletBalanceAt(mock, wallets[0].ID, period, decimal.NewFromInt(100))
+		testutils.MockGetStatementEntries(mock, wallets[0].ID, period, period.AddDate(0, 1, 0),
+			models.StatementEntry{TransactionID: 10, Type: models.TxnTypeDeposit, At: period.Add(time.Hour), Amount: decimal.NewFromInt(50)})
+		testutils.MockSaveStatement(mock, wallets[0].ID, period, true)
+
+		// the second wallet fails and is left for the next run
+		mock.ExpectQuery("SELECT w.id AS wallet_id").
+			WithArgs(period, wallets[1].ID).
+			WillReturnError(errors.New("db down"))
+
+		generated, err := jobs.GenerateStatements(db, now)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, generated)
+	})
+}
+
+func TestGenerateStatements_JustAfterMonthEndTakesMonthBefore(t *testing.T) {
+	testutils.WithDBMock(t, func(db *sql.DB, mock sqlmock.Sqlmock) {
+
+		// transactions completing just before midnight may not be committed yet
+		now := time.Date(2025, 6, 1, 0, 1, 0, 0, time.UTC)
+		mockGetWalletsWithoutStatement(mock, time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC))
+
+		generated, err := jobs.GenerateStatements(db, now)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 0, generated)
+	})
+}
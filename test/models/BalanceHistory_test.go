@@ -0,0 +1,44 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDailyClosingBalances(t *testing.T) {
+	from := time.Date(2025, 5, 30, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	events := []models.BalanceEvent{
+		{At: from.Add(9 * time.Hour), Delta: decimal.NewFromFloat(50)},
+		{At: from.Add(23 * time.Hour), Delta: decimal.NewFromFloat(-20)},
+		// exactly midnight belongs to the next day
+		{At: from.AddDate(0, 0, 2), Delta: decimal.NewFromFloat(5)},
+		// after the range
+		{At: to.AddDate(0, 0, 1), Delta: decimal.NewFromFloat(1000)},
+	}
+
+	balances := models.DailyClosingBalances(decimal.NewFromFloat(100), from, to, events)
+
+	if assert.Len(t, balances, 3) {
+		assert.Equal(t, from, balances[0].Date)
+		assert.True(t, balances[0].Balance.Equal(decimal.NewFromFloat(130)))
+		// no event on the 31st, the balance carries over
+		assert.True(t, balances[1].Balance.Equal(decimal.NewFromFloat(130)))
+		assert.Equal(t, to, balances[2].Date)
+		assert.True(t, balances[2].Balance.Equal(decimal.NewFromFloat(135)))
+	}
+}
+
+func TestDailyClosingBalances_NoEvents(t *testing.T) {
+	day := time.Date(2025, 5, 30, 0, 0, 0, 0, time.UTC)
+
+	balances := models.DailyClosingBalances(decimal.NewFromFloat(7), day, day, nil)
+
+	if assert.Len(t, balances, 1) {
+		assert.True(t, balances[0].Balance.Equal(decimal.NewFromFloat(7)))
+	}
+}
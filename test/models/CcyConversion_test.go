@@ -0,0 +1,185 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var rateDay = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func conversion(from string, to string, rate float64, daysOld int) models.CcyConversion {
+	return models.CcyConversion{FromCcy: from, ToCcy: to, Rate: decimal.NewFromFloat(rate), CreatedAt: rateDay.AddDate(0, 0, -daysOld)}
+}
+
+func TestResolveCcyRate_SameCcy(t *testing.T) {
+	path := models.ResolveCcyRate(nil, "EUR", "EUR")
+
+	require.NotNil(t, path)
+	assert.True(t, path.Rate.Equal(decimal.NewFromInt(1)))
+	assert.Empty(t, path.Hops)
+	assert.Equal(t, "EUR", path.String())
+}
+
+func TestResolveCcyRate_InverseRate(t *testing.T) {
+	conversions := []models.CcyConversion{conversion("BTC", "USD", 100000, 0)}
+
+	path := models.ResolveCcyRate(conversions, "USD", "BTC")
+
+	require.NotNil(t, path)
+	assert.True(t, path.Rate.Equal(decimal.NewFromFloat(0.00001)))
+	require.Len(t, path.Hops, 1)
+	assert.True(t, path.Hops[0].Inverse)
+	assert.Equal(t, "USD>BTC", path.String())
+}
+
+func TestResolveCcyRate_MultiHop(t *testing.T) {
+	conversions := []models.CcyConversion{
+		conversion("USD", "EUR", 0.92, 1),
+		conversion("USD", "SGD", 1.35, 0),
+	}
+
+	path := models.ResolveCcyRate(conversions, "EUR", "SGD")
+
+	require.NotNil(t, path)
+	assert.Equal(t, "EUR>USD>SGD", path.String())
+	assert.True(t, path.Rate.Equal(decimal.NewFromInt(1).Div(decimal.NewFromFloat(0.92)).Mul(decimal.NewFromFloat(1.35))))
+	// The path is as old as its oldest hop
+	assert.Equal(t, rateDay.AddDate(0, 0, -1), path.AsOf)
+}
+
+func TestResolveCcyRate_PrefersFewestHops(t *testing.T) {
+	conversions := []models.CcyConversion{
+		conversion("EUR", "BTC", 0.000011, 5),
+		conversion("USD", "EUR", 0.92, 0),
+		conversion("USD", "BTC", 0.00001, 0),
+	}
+
+	path := models.ResolveCcyRate(conversions, "EUR", "BTC")
+
+	require.NotNil(t, path)
+	assert.Equal(t, "EUR>BTC", path.String())
+}
+
+func TestResolveCcyRate_PrefersFreshestPath(t *testing.T) {
+	conversions := []models.CcyConversion{
+		conversion("EUR", "GBP", 0.85, 3),
+		conversion("GBP", "JPY", 190, 0),
+		conversion("EUR", "USD", 1.08, 0),
+		conversion("USD", "JPY", 155, 1),
+	}
+
+	path := models.ResolveCcyRate(conversions, "EUR", "JPY")
+
+	require.NotNil(t, path)
+	assert.Equal(t, "EUR>USD>JPY", path.String())
+	assert.Equal(t, rateDay.AddDate(0, 0, -1), path.AsOf)
+}
+
+func TestResolveCcyRate_PrefersFresherOfDirectAndInverse(t *testing.T) {
+	conversions := []models.CcyConversion{
+		conversion("USD", "SGD", 1.35, 2),
+		conversion("SGD", "USD", 0.75, 0),
+	}
+
+	path := models.ResolveCcyRate(conversions, "USD", "SGD")
+
+	require.NotNil(t, path)
+	require.Len(t, path.Hops, 1)
+	assert.True(t, path.Hops[0].Inverse)
+}
+
+func TestResolveCcyRate_NotConnected(t *testing.T) {
+	conversions := []models.CcyConversion{conversion("USD", "EUR", 0.92, 0)}
+
+	assert.Nil(t, models.ResolveCcyRate(conversions, "EUR", "JPY"))
+}
+
+func TestResolveFreshCcyRate_PrefersFreshPathOverStaleDirectRate(t *testing.T) {
+	limits := models.RateAgeLimits{Default: 24 * time.Hour}
+	direct := conversion("EUR", "JPY", 165, 3)
+	direct.Age = 72 * time.Hour
+	conversions := []models.CcyConversion{
+		direct,
+		conversion("EUR", "USD", 1.08, 0),
+		conversion("USD", "JPY", 155, 0),
+	}
+
+	path := models.ResolveFreshCcyRate(conversions, "EUR", "JPY", limits)
+
+	require.NotNil(t, path)
+	assert.Equal(t, "EUR>USD>JPY", path.String())
+	assert.NoError(t, path.CheckFresh(limits))
+}
+
+func TestResolveFreshCcyRate_OnlyStalePath(t *testing.T) {
+	limits := models.RateAgeLimits{Default: 24 * time.Hour}
+	direct := conversion("EUR", "JPY", 165, 3)
+	direct.Age = 72 * time.Hour
+
+	path := models.ResolveFreshCcyRate([]models.CcyConversion{direct, conversion("EUR", "USD", 1.08, 0)}, "EUR", "JPY", limits)
+
+	require.NotNil(t, path)
+	assert.Equal(t, "EUR>JPY", path.String())
+	assert.NotNil(t, path.StaleHop(limits))
+}
+
+func TestRateAgeLimits_MaxAgeIsStricterOfBothCcys(t *testing.T) {
+	limits := models.RateAgeLimits{Default: 24 * time.Hour, ByCcy: map[string]time.Duration{"BTC": time.Hour, "JPY": 0}}
+
+	assert.Equal(t, time.Hour, limits.MaxAge("USD", "BTC"))
+	assert.Equal(t, 24*time.Hour, limits.MaxAge("USD", "EUR"))
+	// JPY is not limited, the limit of the other currency applies
+	assert.Equal(t, 24*time.Hour, limits.MaxAge("JPY", "USD"))
+	assert.Equal(t, time.Duration(0), models.RateAgeLimits{}.MaxAge("USD", "EUR"))
+}
+
+func TestCcyRatePath_CheckFresh(t *testing.T) {
+	limits := models.RateAgeLimits{Default: 24 * time.Hour, ByCcy: map[string]time.Duration{"BTC": time.Hour}}
+	btc := conversion("BTC", "USD", 100000, 0)
+	btc.Age = 2 * time.Hour
+	eur := conversion("USD", "EUR", 0.92, 0)
+	eur.Age = 2 * time.Hour
+
+	path := models.ResolveCcyRate([]models.CcyConversion{btc, eur}, "EUR", "BTC")
+	require.NotNil(t, path)
+
+	err := path.CheckFresh(limits)
+	require.Error(t, err)
+	var appErr *models.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, models.ErrCodeRateStale, appErr.Code)
+
+	// The same rates are fresh enough between currencies without a stricter limit
+	path = models.ResolveCcyRate([]models.CcyConversion{btc, eur}, "USD", "EUR")
+	require.NotNil(t, path)
+	assert.NoError(t, path.CheckFresh(limits))
+}
+
+func TestCcyRateHistory_At(t *testing.T) {
+	history := models.CcyRateHistory{
+		conversion("USD", "SGD", 1.30, 3),
+		conversion("USD", "EUR", 0.90, 2),
+		conversion("USD", "SGD", 1.35, 1),
+		conversion("USD", "SGD", 1.40, 0),
+	}
+
+	conversions := history.At(rateDay)
+
+	require.Len(t, conversions, 2)
+	assert.Equal(t, "SGD", conversions[0].ToCcy)
+	assert.True(t, conversions[0].Rate.Equal(decimal.NewFromFloat(1.35)))
+	assert.Equal(t, 24*time.Hour, conversions[0].Age)
+	assert.Equal(t, "EUR", conversions[1].ToCcy)
+	assert.Equal(t, 48*time.Hour, conversions[1].Age)
+}
+
+func TestCcyRateHistory_AtBeforeFirstRate(t *testing.T) {
+	history := models.CcyRateHistory{conversion("USD", "SGD", 1.30, 0)}
+
+	assert.Empty(t, history.At(rateDay))
+}
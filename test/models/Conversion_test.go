@@ -0,0 +1,49 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConversionSell(t *testing.T) {
+	var c models.Conversion
+	c.ApplyPrice(bidPrice(decimal.NewFromFloat(1.35), decimal.NewFromInt(1)))
+
+	c.Sell(decimal.NewFromInt(100))
+
+	assert.True(t, c.Rate.Equal(decimal.NewFromFloat(1.3365)))
+	// 133.65 received, 135.00 at the mid rate
+	assert.True(t, c.ToAmount.Equal(decimal.NewFromFloat(133.65)))
+	assert.True(t, c.SpreadAmount.Equal(decimal.NewFromFloat(1.35)))
+}
+
+func TestConversionSell_RoundsReceivedAmountDown(t *testing.T) {
+	var c models.Conversion
+	c.ApplyPrice(bidPrice(decimal.NewFromFloat(0.92), decimal.NewFromFloat(0.5)))
+
+	c.Sell(decimal.NewFromFloat(10.01))
+
+	// 10.01 * 0.9154 = 9.163154
+	assert.True(t, c.ToAmount.Equal(decimal.NewFromFloat(9.16)))
+}
+
+func TestConversionBuy_RoundsPaidAmountUp(t *testing.T) {
+	var c models.Conversion
+	c.ApplyPrice(bidPrice(decimal.NewFromFloat(155.25), decimal.NewFromInt(1)))
+
+	c.Buy(decimal.NewFromInt(1000))
+
+	// 1000 / 153.6975 = 6.50628...
+	assert.True(t, c.FromAmount.Equal(decimal.NewFromFloat(6.51)))
+	assert.True(t, c.ToAmount.Equal(decimal.NewFromInt(1000)))
+	assert.True(t, c.SpreadAmount.Equal(c.FromAmount.Mul(c.MidRate).Sub(c.ToAmount).Round(2)))
+}
+
+// bidPrice returns the price of selling at midRate less a bid spread of spread percent.
+func bidPrice(midRate decimal.Decimal, spread decimal.Decimal) models.FxPrice {
+	rule := models.FxSpreadRule{BidSpread: spread, AskSpread: spread}
+	return models.PriceFx([]models.FxSpreadRule{rule}, "USD", "SGD", midRate)
+}
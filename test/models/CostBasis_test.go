@@ -0,0 +1,132 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var btcWallet = models.Wallet{ID: 7, UserId: 101, Currency: "BTC", Type: "trading"}
+
+// btcHistory sets BTC at 50000 USD, then 100000 USD a day later and 40000 USD the day after.
+func btcHistory() models.CcyRateHistory {
+	return models.CcyRateHistory{
+		{FromCcy: "BTC", ToCcy: "USD", Rate: decimal.NewFromInt(50000), CreatedAt: rateDay},
+		{FromCcy: "BTC", ToCcy: "USD", Rate: decimal.NewFromInt(100000), CreatedAt: rateDay.AddDate(0, 0, 1)},
+		{FromCcy: "BTC", ToCcy: "USD", Rate: decimal.NewFromInt(40000), CreatedAt: rateDay.AddDate(0, 0, 2)},
+	}
+}
+
+// btcTxns buys 1 BTC at every rate of btcHistory but the last, then sells 1.5 BTC at 40000.
+func btcTxns() []models.CostBasisTxn {
+	return []models.CostBasisTxn{
+		{ID: 1, Type: models.TxnTypeDeposit, Amount: decimal.NewFromInt(1), At: rateDay.Add(time.Hour)},
+		{ID: 2, Type: models.TxnTypeDeposit, Amount: decimal.NewFromInt(1), At: rateDay.AddDate(0, 0, 1).Add(time.Hour)},
+		{ID: 3, Type: models.TxnTypeWithdraw, Amount: decimal.NewFromFloat(1.5), At: rateDay.AddDate(0, 0, 2).Add(time.Hour)},
+	}
+}
+
+func TestTrackCostBasis_FIFO(t *testing.T) {
+	cb := models.TrackCostBasis(btcWallet, "USD", models.CostBasisFIFO, btcTxns(), btcHistory())
+
+	require.Len(t, cb.Disposals, 1)
+	disposal := cb.Disposals[0]
+	assert.Equal(t, int64(3), disposal.TransactionID)
+	assert.True(t, disposal.Proceeds.Equal(decimal.NewFromInt(60000)))
+	// the first lot and half of the second
+	assert.True(t, disposal.CostBasis.Equal(decimal.NewFromInt(100000)))
+	assert.True(t, disposal.Gain.Equal(decimal.NewFromInt(-40000)))
+	assert.Equal(t, rateDay.Add(time.Hour), disposal.AcquiredAt)
+
+	require.Len(t, cb.Lots, 1)
+	assert.Equal(t, int64(2), cb.Lots[0].TransactionID)
+	quantity, cost := cb.Holding()
+	assert.True(t, quantity.Equal(decimal.NewFromFloat(0.5)))
+	assert.True(t, cost.Equal(decimal.NewFromInt(50000)))
+	assert.Empty(t, cb.UnpricedTxns)
+}
+
+func TestTrackCostBasis_LIFO(t *testing.T) {
+	cb := models.TrackCostBasis(btcWallet, "USD", models.CostBasisLIFO, btcTxns(), btcHistory())
+
+	require.Len(t, cb.Disposals, 1)
+	// the second lot and half of the first
+	assert.True(t, cb.Disposals[0].CostBasis.Equal(decimal.NewFromInt(125000)))
+	assert.True(t, cb.Disposals[0].Gain.Equal(decimal.NewFromInt(-65000)))
+	assert.Equal(t, rateDay.Add(time.Hour), cb.Disposals[0].AcquiredAt)
+
+	require.Len(t, cb.Lots, 1)
+	assert.Equal(t, int64(1), cb.Lots[0].TransactionID)
+	assert.True(t, cb.Lots[0].Remaining.Equal(decimal.NewFromFloat(0.5)))
+	assert.True(t, cb.Lots[0].Cost.Equal(decimal.NewFromInt(25000)))
+}
+
+func TestTrackCostBasis_Average(t *testing.T) {
+	cb := models.TrackCostBasis(btcWallet, "USD", models.CostBasisAverage, btcTxns(), btcHistory())
+
+	require.Len(t, cb.Disposals, 1)
+	// 1.5 BTC at an average of 75000
+	assert.True(t, cb.Disposals[0].CostBasis.Equal(decimal.NewFromInt(112500)))
+	assert.True(t, cb.Disposals[0].Gain.Equal(decimal.NewFromInt(-52500)))
+
+	require.Len(t, cb.Lots, 2)
+	assert.True(t, cb.Lots[0].Remaining.Equal(decimal.NewFromFloat(0.25)))
+	assert.True(t, cb.Lots[1].Remaining.Equal(decimal.NewFromFloat(0.25)))
+	quantity, cost := cb.Holding()
+	assert.True(t, quantity.Equal(decimal.NewFromFloat(0.5)))
+	assert.True(t, cost.Equal(decimal.NewFromInt(37500)))
+}
+
+func TestTrackCostBasis_DisposalBeyondHolding(t *testing.T) {
+	txns := []models.CostBasisTxn{
+		{ID: 1, Type: models.TxnTypeDeposit, Amount: decimal.NewFromInt(1), At: rateDay.Add(time.Hour)},
+		{ID: 2, Type: models.TxnTypeWithdraw, Amount: decimal.NewFromInt(2), At: rateDay.Add(2 * time.Hour)},
+	}
+
+	cb := models.TrackCostBasis(btcWallet, "USD", models.CostBasisFIFO, txns, btcHistory())
+
+	require.Len(t, cb.Disposals, 1)
+	assert.True(t, cb.Disposals[0].Proceeds.Equal(decimal.NewFromInt(100000)))
+	assert.True(t, cb.Disposals[0].CostBasis.Equal(decimal.NewFromInt(50000)))
+	assert.Empty(t, cb.Lots)
+}
+
+func TestTrackCostBasis_ExchangeValuedAtRecordedRate(t *testing.T) {
+	// 500 USD converted into 0.01 BTC at 0.00002 BTC per USD, half of it converted back at 60000 USD per BTC
+	txns := []models.CostBasisTxn{
+		{ID: 1, Type: models.TxnTypeConversionIn, Amount: decimal.NewFromFloat(0.01), Rate: decimal.NewNullDecimal(decimal.NewFromFloat(0.00002)),
+			CounterCcy: "USD", At: rateDay.Add(time.Hour)},
+		{ID: 2, Type: models.TxnTypeConversionOut, Amount: decimal.NewFromFloat(0.005), Rate: decimal.NewNullDecimal(decimal.NewFromInt(60000)),
+			CounterCcy: "USD", At: rateDay.Add(2 * time.Hour)},
+	}
+
+	cb := models.TrackCostBasis(btcWallet, "USD", models.CostBasisFIFO, txns, nil)
+
+	assert.Empty(t, cb.UnpricedTxns)
+	require.Len(t, cb.Disposals, 1)
+	assert.True(t, cb.Disposals[0].Proceeds.Equal(decimal.NewFromInt(300)))
+	assert.True(t, cb.Disposals[0].CostBasis.Equal(decimal.NewFromInt(250)))
+	assert.True(t, cb.Disposals[0].Gain.Equal(decimal.NewFromInt(50)))
+}
+
+func TestTrackCostBasis_Unpriced(t *testing.T) {
+	txns := []models.CostBasisTxn{
+		{ID: 1, Type: models.TxnTypeDeposit, Amount: decimal.NewFromInt(1), At: rateDay.Add(-time.Hour)},
+		{ID: 2, Type: models.TxnTypeWithdraw, Amount: decimal.NewFromFloat(0.5), At: rateDay.Add(time.Hour)},
+	}
+
+	cb := models.TrackCostBasis(btcWallet, "USD", models.CostBasisFIFO, txns, btcHistory())
+
+	// no rate was known when the deposit completed
+	assert.Equal(t, []int64{1}, cb.UnpricedTxns)
+	require.Len(t, cb.Lots, 1)
+	assert.True(t, cb.Lots[0].Unpriced)
+	assert.True(t, cb.Lots[0].Cost.IsZero())
+	require.Len(t, cb.Disposals, 1)
+	assert.False(t, cb.Disposals[0].Unpriced)
+	assert.True(t, cb.Disposals[0].Gain.Equal(decimal.NewFromInt(25000)))
+}
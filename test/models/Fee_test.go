@@ -0,0 +1,68 @@
+package models_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeeRuleCalculate_FlatAndPercentage(t *testing.T) {
+	rule := models.FeeRule{FlatFee: decimal.NewFromInt(1), Percentage: decimal.NewFromFloat(1.5)}
+
+	fee := rule.Calculate(decimal.NewFromInt(200))
+
+	assert.True(t, fee.FlatFee.Equal(decimal.NewFromInt(1)))
+	assert.True(t, fee.PercentageFee.Equal(decimal.NewFromInt(3)))
+	assert.True(t, fee.Adjustment.IsZero())
+	assert.True(t, fee.Amount.Equal(decimal.NewFromInt(4)))
+}
+
+func TestFeeRuleCalculate_MinAndMaxFee(t *testing.T) {
+	rule := models.FeeRule{
+		Percentage: decimal.NewFromInt(1),
+		MinFee:     decimal.NullDecimal{Decimal: decimal.NewFromInt(2), Valid: true},
+		MaxFee:     decimal.NullDecimal{Decimal: decimal.NewFromInt(5), Valid: true},
+	}
+
+	low := rule.Calculate(decimal.NewFromInt(50))
+	assert.True(t, low.Amount.Equal(decimal.NewFromInt(2)))
+	assert.True(t, low.Adjustment.Equal(decimal.NewFromFloat(1.5)))
+
+	high := rule.Calculate(decimal.NewFromInt(1000))
+	assert.True(t, high.Amount.Equal(decimal.NewFromInt(5)))
+	assert.True(t, high.Adjustment.Equal(decimal.NewFromInt(-5)))
+}
+
+func TestMatchFeeRule_MostSpecificThenHighestTier(t *testing.T) {
+	usd := sql.NullString{String: "USD", Valid: true}
+	trading := sql.NullString{String: "trading", Valid: true}
+	rules := []models.FeeRule{
+		{ID: 1, FeeType: models.FeeTypeWithdraw, FlatFee: decimal.NewFromInt(1)},
+		{ID: 2, FeeType: models.FeeTypeWithdraw, FromCcy: usd, WalletType: trading},
+		{ID: 3, FeeType: models.FeeTypeWithdraw, FromCcy: usd, WalletType: trading, MinAmount: decimal.NewFromInt(10000)},
+		{ID: 4, FeeType: models.FeeTypeWithdraw, FromCcy: usd},
+		{ID: 5, FeeType: models.FeeTypeTransfer, FromCcy: usd, WalletType: trading},
+	}
+
+	rule := models.MatchFeeRule(rules, models.FeeTypeWithdraw, "USD", "USD", "trading", decimal.NewFromInt(500))
+	require.NotNil(t, rule)
+	assert.Equal(t, int64(2), rule.ID)
+
+	rule = models.MatchFeeRule(rules, models.FeeTypeWithdraw, "USD", "USD", "trading", decimal.NewFromInt(20000))
+	require.NotNil(t, rule)
+	assert.Equal(t, int64(3), rule.ID)
+
+	rule = models.MatchFeeRule(rules, models.FeeTypeWithdraw, "USD", "USD", "saving", decimal.NewFromInt(500))
+	require.NotNil(t, rule)
+	assert.Equal(t, int64(4), rule.ID)
+
+	rule = models.MatchFeeRule(rules, models.FeeTypeWithdraw, "SGD", "SGD", "saving", decimal.NewFromInt(500))
+	require.NotNil(t, rule)
+	assert.Equal(t, int64(1), rule.ID)
+
+	assert.Nil(t, models.MatchFeeRule(rules, models.FeeTypeConversion, "USD", "BTC", "trading", decimal.NewFromInt(500)))
+}
@@ -0,0 +1,75 @@
+package models_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceFx_BidWhenSellingBaseCcy(t *testing.T) {
+	rules := []models.FxSpreadRule{
+		{ID: 1, BidSpread: decimal.NewFromFloat(0.5), AskSpread: decimal.NewFromFloat(0.5)},
+		{ID: 2, BaseCcy: sql.NullString{String: "BTC", Valid: true}, BidSpread: decimal.NewFromInt(1), AskSpread: decimal.NewFromInt(2)},
+	}
+
+	price := models.PriceFx(rules, "BTC", "USD", decimal.NewFromInt(60000))
+
+	assert.Equal(t, models.FxSideBid, price.Side)
+	assert.True(t, price.Spread.Equal(decimal.NewFromInt(1)))
+	assert.True(t, price.Rate.Equal(decimal.NewFromInt(59400)))
+}
+
+func TestPriceFx_AskWhenBuyingBaseCcy(t *testing.T) {
+	rules := []models.FxSpreadRule{
+		{ID: 2, BaseCcy: sql.NullString{String: "BTC", Valid: true}, BidSpread: decimal.NewFromInt(1), AskSpread: decimal.NewFromInt(2)},
+	}
+
+	price := models.PriceFx(rules, "USD", "BTC", decimal.NewFromFloat(0.00002))
+
+	// A BTC costs 51000 USD at the ask of 50000 plus 2%
+	assert.Equal(t, models.FxSideAsk, price.Side)
+	assert.True(t, price.Spread.Equal(decimal.NewFromInt(2)))
+	require.True(t, price.Rate.IsPositive())
+	assert.True(t, decimal.NewFromInt(1).Div(price.Rate).Round(6).Equal(decimal.NewFromInt(51000)))
+}
+
+func TestPriceFx_TierRuleIsMoreSpecific(t *testing.T) {
+	rules := []models.FxSpreadRule{
+		{ID: 1, BidSpread: decimal.NewFromFloat(0.5), AskSpread: decimal.NewFromFloat(0.5)},
+		{ID: 2, Tier: sql.NullString{String: "premium", Valid: true}, BidSpread: decimal.NewFromFloat(0.25), AskSpread: decimal.NewFromFloat(0.25)},
+	}
+
+	price := models.PriceFx(rules, "USD", "SGD", decimal.NewFromFloat(1.35))
+
+	assert.True(t, price.Spread.Equal(decimal.NewFromFloat(0.25)))
+	assert.True(t, price.Rate.Equal(decimal.NewFromFloat(1.3466250)))
+}
+
+func TestPriceFx_MidRateWithoutRule(t *testing.T) {
+	rules := []models.FxSpreadRule{
+		{ID: 2, BaseCcy: sql.NullString{String: "BTC", Valid: true}, BidSpread: decimal.NewFromInt(1), AskSpread: decimal.NewFromInt(2)},
+	}
+
+	price := models.PriceFx(rules, "USD", "SGD", decimal.NewFromFloat(1.35))
+
+	assert.Equal(t, models.FxSideMid, price.Side)
+	assert.True(t, price.Spread.IsZero())
+	assert.True(t, price.Rate.Equal(decimal.NewFromFloat(1.35)))
+	assert.Nil(t, price.Revenue("SGD", decimal.NewFromInt(100), decimal.NewFromInt(135)))
+}
+
+func TestFxPriceRevenue(t *testing.T) {
+	price := models.FxPrice{Side: models.FxSideBid, MidRate: decimal.NewFromFloat(1.35), Spread: decimal.NewFromFloat(0.5), Rate: decimal.NewFromFloat(1.34325)}
+
+	revenue := price.Revenue("SGD", decimal.NewFromInt(100), decimal.NewFromFloat(134.325))
+
+	// 135.00 at the mid rate, 134.325 received
+	require.NotNil(t, revenue)
+	assert.Equal(t, "SGD", revenue.Currency)
+	assert.True(t, revenue.Amount.Equal(decimal.NewFromFloat(0.68)))
+	assert.True(t, revenue.Rate.Equal(price.Rate))
+}
@@ -0,0 +1,152 @@
+package models_test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var painToday = time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+
+var painWallet = models.Wallet{ID: 1, UserId: 3, Currency: "USD"}
+
+// painDocument is a pain.001.001.09 file paying wallets 2 and 3 from wallet 1, with the given totals
+// of the group header and execution date.
+func painDocument(nbOfTxs string, ctrlSum string, executionDate string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.001.001.09">
+  <CstmrCdtTrfInitn>
+    <GrpHdr><MsgId>MSG-1</MsgId><CreDtTm>2025-06-02T08:00:00Z</CreDtTm><NbOfTxs>%s</NbOfTxs><CtrlSum>%s</CtrlSum></GrpHdr>
+    <PmtInf>
+      <PmtInfId>PMT-1</PmtInfId>
+      <PmtMtd>TRF</PmtMtd>
+      <ReqdExctnDt><Dt>%s</Dt></ReqdExctnDt>
+      <Dbtr><Nm>Charlie</Nm></Dbtr>
+      <DbtrAcct><Id><Othr><Id>1</Id></Othr></Id><Ccy>USD</Ccy></DbtrAcct>
+      <CdtTrfTxInf>
+        <PmtId><EndToEndId>E2E-1</EndToEndId></PmtId>
+        <Amt><InstdAmt Ccy="USD">30.50</InstdAmt></Amt>
+        <CdtrAcct><Id><Othr><Id>2</Id></Othr></Id></CdtrAcct>
+        <RmtInf><Ustrd>salary</Ustrd><Ustrd>May</Ustrd></RmtInf>
+      </CdtTrfTxInf>
+      <CdtTrfTxInf>
+        <PmtId><EndToEndId>NOTPROVIDED</EndToEndId></PmtId>
+        <Amt><InstdAmt Ccy="USD">20</InstdAmt></Amt>
+        <CdtrAcct><Id><Othr><Id>3</Id></Othr></Id></CdtrAcct>
+      </CdtTrfTxInf>
+    </PmtInf>
+  </CstmrCdtTrfInitn>
+</Document>`, nbOfTxs, ctrlSum, executionDate)
+}
+
+func parsePain(t *testing.T, doc string) models.Pain001Document {
+	var pain models.Pain001Document
+	require.NoError(t, xml.Unmarshal([]byte(doc), &pain))
+	return pain
+}
+
+func TestPain001ToBatchTransferRequest(t *testing.T) {
+	pain := parsePain(t, painDocument("2", "50.5", "2025-06-02"))
+
+	msg, err := pain.ToBatchTransferRequest(painWallet, "", painToday)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.BatchModeAllOrNothing, msg.Mode)
+	require.Len(t, msg.Items, 2)
+	assert.True(t, msg.Items[0].Amount.Equal(decimal.NewFromFloat(30.5)))
+	assert.Equal(t, int64(2), *msg.Items[0].DestinationWalletID)
+	assert.Equal(t, "salary May", msg.Items[0].Description)
+	assert.Equal(t, "E2E-1", msg.Items[0].ExternalReference)
+	assert.Equal(t, int64(3), *msg.Items[1].DestinationWalletID)
+	assert.Equal(t, "", msg.Items[1].ExternalReference)
+}
+
+func TestPain001ToBatchTransferRequest_Version3Date(t *testing.T) {
+	pain := parsePain(t, `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.001.001.03"><CstmrCdtTrfInitn>
+		<GrpHdr><MsgId>MSG-1</MsgId><NbOfTxs>1</NbOfTxs></GrpHdr>
+		<PmtInf><PmtInfId>PMT-1</PmtInfId><PmtMtd>TRF</PmtMtd><ReqdExctnDt>2025-06-03</ReqdExctnDt>
+			<DbtrAcct><Id><Othr><Id>1</Id></Othr></Id></DbtrAcct>
+			<CdtTrfTxInf><PmtId><EndToEndId>E2E-1</EndToEndId></PmtId><Amt><InstdAmt Ccy="USD">10</InstdAmt></Amt>
+				<CdtrAcct><Id><Othr><Id>2</Id></Othr></Id></CdtrAcct></CdtTrfTxInf>
+		</PmtInf></CstmrCdtTrfInitn></Document>`)
+
+	_, err := pain.ToBatchTransferRequest(painWallet, "", painToday)
+
+	assertValidationFailed(t, err, "payment PMT-1: ReqdExctnDt must not be after today, use scheduled transfers for later payments")
+}
+
+func TestPain001ToBatchTransferRequest_OtherDebtor(t *testing.T) {
+	pain := parsePain(t, painDocument("2", "", "2025-06-02"))
+
+	_, err := pain.ToBatchTransferRequest(models.Wallet{ID: 7, Currency: "USD"}, "", painToday)
+
+	assertValidationFailed(t, err, "payment PMT-1: DbtrAcct must be wallet 7")
+}
+
+func TestPain001ToBatchTransferRequest_OtherCurrency(t *testing.T) {
+	pain := parsePain(t, painDocument("2", "", "2025-06-02"))
+
+	_, err := pain.ToBatchTransferRequest(models.Wallet{ID: 1, Currency: "EUR"}, "", painToday)
+
+	assertValidationFailed(t, err, "payment PMT-1: DbtrAcct currency must be EUR")
+}
+
+func TestPain001ToBatchTransferRequest_WrongTotals(t *testing.T) {
+	_, err := parsePain(t, painDocument("3", "", "2025-06-02")).ToBatchTransferRequest(painWallet, "", painToday)
+	assertValidationFailed(t, err, "GrpHdr: NbOfTxs must be the number of transactions, 2")
+
+	_, err = parsePain(t, painDocument("2", "60", "2025-06-02")).ToBatchTransferRequest(painWallet, "", painToday)
+	assertValidationFailed(t, err, "GrpHdr: CtrlSum must be the sum of the amounts, 50.5")
+}
+
+func TestPain001ToBatchTransferRequest_TooManyTransactions(t *testing.T) {
+	tx := `<CdtTrfTxInf><PmtId><EndToEndId>E2E</EndToEndId></PmtId><Amt><InstdAmt Ccy="USD">1</InstdAmt></Amt>
+		<CdtrAcct><Id><Othr><Id>2</Id></Othr></Id></CdtrAcct></CdtTrfTxInf>`
+	pain := parsePain(t, `<Document><CstmrCdtTrfInitn>
+		<GrpHdr><MsgId>MSG-1</MsgId><NbOfTxs>501</NbOfTxs></GrpHdr>
+		<PmtInf><PmtInfId>PMT-1</PmtInfId><PmtMtd>TRF</PmtMtd>
+			<DbtrAcct><Id><Othr><Id>1</Id></Othr></Id></DbtrAcct>`+strings.Repeat(tx, 300)+`</PmtInf>
+		<PmtInf><PmtInfId>PMT-2</PmtInfId><PmtMtd>TRF</PmtMtd>
+			<DbtrAcct><Id><Othr><Id>1</Id></Othr></Id></DbtrAcct>`+strings.Repeat(tx, 201)+`</PmtInf>
+		</CstmrCdtTrfInitn></Document>`)
+
+	_, err := pain.ToBatchTransferRequest(painWallet, "", painToday)
+
+	assertValidationFailed(t, err, "a payment file must not have more than 500 transactions")
+}
+
+func TestPain001ToBatchTransferRequest_IBANCreditor(t *testing.T) {
+	pain := parsePain(t, `<Document><CstmrCdtTrfInitn>
+		<GrpHdr><MsgId>MSG-1</MsgId><NbOfTxs>1</NbOfTxs></GrpHdr>
+		<PmtInf><PmtInfId>PMT-1</PmtInfId><PmtMtd>TRF</PmtMtd>
+			<DbtrAcct><Id><Othr><Id>1</Id></Othr></Id></DbtrAcct>
+			<CdtTrfTxInf><PmtId><EndToEndId>E2E-1</EndToEndId></PmtId><Amt><InstdAmt Ccy="USD">10</InstdAmt></Amt>
+				<CdtrAcct><Id><IBAN>DE89370400440532013000</IBAN></Id></CdtrAcct></CdtTrfTxInf>
+		</PmtInf></CstmrCdtTrfInitn></Document>`)
+
+	_, err := pain.ToBatchTransferRequest(painWallet, "", painToday)
+
+	assertValidationFailed(t, err, "payment PMT-1, transaction E2E-1: CdtrAcct must be a wallet ID as other identification")
+}
+
+func TestPain001ToBatchTransferRequest_NotPain001(t *testing.T) {
+	pain := parsePain(t, `<Document><BkToCstmrStmt/></Document>`)
+
+	_, err := pain.ToBatchTransferRequest(painWallet, "", painToday)
+
+	assertValidationFailed(t, err, "the document is not a pain.001 customer credit transfer initiation")
+}
+
+func assertValidationFailed(t *testing.T, err error, message string) {
+	var appErr *models.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, models.ErrCodeValidationFailed, appErr.Code)
+	assert.Equal(t, message, appErr.Message)
+}
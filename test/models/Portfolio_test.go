@@ -0,0 +1,50 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValuePortfolio(t *testing.T) {
+	conversions := []models.CcyConversion{
+		{FromCcy: "USD", ToCcy: "SGD", Rate: decimal.NewFromFloat(1.25), Age: time.Hour},
+		{FromCcy: "USD", ToCcy: "BTC", Rate: decimal.NewFromFloat(0.00001), Age: 3 * time.Hour},
+	}
+	balances := map[string]decimal.Decimal{
+		"USD": decimal.NewFromFloat(100),
+		"SGD": decimal.NewFromFloat(250),
+		"BTC": decimal.NewFromFloat(0.002),
+		"JPY": decimal.NewFromFloat(1000),
+		"EUR": decimal.Zero,
+	}
+
+	value := models.ValuePortfolio(rateDay, balances, conversions, "USD", models.RateAgeLimits{Default: 2 * time.Hour})
+
+	assert.Equal(t, rateDay, value.Date)
+	assert.Equal(t, "USD", value.Currency)
+	// 200 from BTC, 200 from SGD, 100 in USD
+	assert.True(t, value.Total.Equal(decimal.NewFromFloat(500)))
+	assert.Equal(t, []string{"JPY"}, value.UnpricedCurrencies)
+	assert.Equal(t, []string{"BTC"}, value.StaleCurrencies)
+
+	require.Len(t, value.Allocations, 3)
+	assert.Equal(t, "BTC", value.Allocations[0].Currency)
+	assert.True(t, value.Allocations[0].Value.Equal(decimal.NewFromFloat(200)))
+	assert.True(t, value.Allocations[0].Percentage.Equal(decimal.NewFromFloat(40)))
+	assert.Equal(t, "SGD", value.Allocations[1].Currency)
+	assert.Equal(t, "USD", value.Allocations[2].Currency)
+	assert.True(t, value.Allocations[2].Percentage.Equal(decimal.NewFromFloat(20)))
+}
+
+func TestValuePortfolio_Empty(t *testing.T) {
+	value := models.ValuePortfolio(rateDay, map[string]decimal.Decimal{"USD": decimal.Zero}, nil, "USD", models.RateAgeLimits{})
+
+	assert.True(t, value.Total.IsZero())
+	assert.Empty(t, value.Allocations)
+	assert.Empty(t, value.UnpricedCurrencies)
+}
@@ -0,0 +1,81 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// subCent is an amount a wallet cannot store, it would be rounded to nothing
+var subCent = decimal.RequireFromString("0.005")
+
+func assertTooManyDecimals(t *testing.T, err error) {
+	var appErr *models.AppError
+	assert.True(t, errors.As(err, &appErr))
+	assert.Equal(t, models.ErrCodeValidationFailed, appErr.Code)
+	assert.ErrorContains(t, err, "amount must not have more than 2 decimals")
+}
+
+func TestIsStorableAmount(t *testing.T) {
+	assert.True(t, models.IsStorableAmount(decimal.RequireFromString("10.5")))
+	assert.True(t, models.IsStorableAmount(decimal.RequireFromString("10.50")))
+	assert.True(t, models.IsStorableAmount(decimal.RequireFromString("0.010")))
+	assert.False(t, models.IsStorableAmount(subCent))
+}
+
+func TestTransactionRequestValidate_SubCentAmount(t *testing.T) {
+	walletId := int64(2)
+	req := models.TransactionRequest{Amount: subCent, DestinationWalletID: &walletId}
+
+	assertTooManyDecimals(t, req.ValidateRequest(models.TxnTypeTransferOut))
+}
+
+func TestTransactionRequestValidate_SubCentDeposit(t *testing.T) {
+	req := models.TransactionRequest{Amount: decimal.RequireFromString("10.001")}
+
+	assertTooManyDecimals(t, req.ValidateRequest(models.TxnTypeDeposit))
+}
+
+func TestTransferReversalRequestValidate_SubCentAmount(t *testing.T) {
+	req := models.TransferReversalRequest{Amount: &subCent}
+
+	assertTooManyDecimals(t, req.ValidateRequest())
+}
+
+func TestHoldRequestValidate_SubCentAmount(t *testing.T) {
+	req := models.HoldRequest{Amount: subCent}
+
+	assertTooManyDecimals(t, req.ValidateRequest())
+}
+
+func TestHoldCaptureRequestValidate_SubCentAmount(t *testing.T) {
+	req := models.HoldCaptureRequest{Amount: &subCent}
+
+	assertTooManyDecimals(t, req.ValidateRequest())
+}
+
+func TestBatchTransferRequestValidate_SubCentItem(t *testing.T) {
+	walletId := int64(2)
+	req := models.BatchTransferRequest{Items: []models.BatchTransferItemRequest{
+		{Amount: decimal.NewFromInt(10), DestinationWalletID: &walletId},
+		{Amount: subCent, DestinationWalletID: &walletId},
+	}}
+
+	assertTooManyDecimals(t, req.ValidateRequest())
+}
+
+func TestScheduledTransferRequestValidate_SubCentAmount(t *testing.T) {
+	walletId := int64(2)
+	req := models.ScheduledTransferRequest{Amount: subCent, DestinationWalletID: &walletId, Frequency: models.ScheduleFrequencyOnce}
+
+	assertTooManyDecimals(t, req.ValidateRequest())
+}
+
+func TestScheduledTransferUpdateRequestValidate_SubCentAmount(t *testing.T) {
+	req := models.ScheduledTransferUpdateRequest{Amount: &subCent}
+
+	assertTooManyDecimals(t, req.ValidateRequest())
+}
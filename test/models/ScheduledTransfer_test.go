@@ -0,0 +1,151 @@
+package models_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOccurrenceAt_MonthlyClampsToLastDayOfMonth(t *testing.T) {
+	s := models.ScheduledTransfer{
+		Frequency: models.ScheduleFrequencyMonthly,
+		StartAt:   time.Date(2024, time.January, 31, 9, 0, 0, 0, time.UTC),
+	}
+
+	assert.Equal(t, time.Date(2024, time.February, 29, 9, 0, 0, 0, time.UTC), s.OccurrenceAt(1))
+	assert.Equal(t, time.Date(2024, time.March, 31, 9, 0, 0, 0, time.UTC), s.OccurrenceAt(2))
+	assert.Equal(t, time.Date(2024, time.April, 30, 9, 0, 0, 0, time.UTC), s.OccurrenceAt(3))
+	assert.Equal(t, time.Date(2025, time.January, 31, 9, 0, 0, 0, time.UTC), s.OccurrenceAt(12))
+}
+
+func TestOccurrenceAt_Weekly(t *testing.T) {
+	s := models.ScheduledTransfer{
+		Frequency: models.ScheduleFrequencyWeekly,
+		StartAt:   time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC),
+	}
+
+	assert.Equal(t, time.Date(2024, time.January, 15, 9, 0, 0, 0, time.UTC), s.OccurrenceAt(2))
+}
+
+func TestAdvance_CompletesOnceAfterFirstOccurrence(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	s := models.ScheduledTransfer{
+		Frequency: models.ScheduleFrequencyOnce,
+		StartAt:   start,
+		NextRunAt: sql.NullTime{Time: start, Valid: true},
+		Status:    models.ScheduleStatusActive,
+	}
+
+	s.Advance()
+
+	assert.Equal(t, models.ScheduleStatusCompleted, s.Status)
+	assert.False(t, s.NextRunAt.Valid)
+	assert.Equal(t, int64(1), s.Occurrences)
+}
+
+func TestAdvance_CompletesAtMaxOccurrences(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	s := models.ScheduledTransfer{
+		Frequency:      models.ScheduleFrequencyDaily,
+		StartAt:        start,
+		MaxOccurrences: sql.NullInt64{Int64: 2, Valid: true},
+		NextRunAt:      sql.NullTime{Time: start, Valid: true},
+		Status:         models.ScheduleStatusActive,
+	}
+
+	s.Advance()
+	assert.Equal(t, models.ScheduleStatusActive, s.Status)
+	assert.Equal(t, start.AddDate(0, 0, 1), s.NextRunAt.Time)
+
+	s.Advance()
+	assert.Equal(t, models.ScheduleStatusCompleted, s.Status)
+	assert.False(t, s.NextRunAt.Valid)
+}
+
+func TestAdvance_CompletesAfterEndAt(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	s := models.ScheduledTransfer{
+		Frequency: models.ScheduleFrequencyWeekly,
+		StartAt:   start,
+		EndAt:     sql.NullTime{Time: start.AddDate(0, 0, 10), Valid: true},
+		NextRunAt: sql.NullTime{Time: start, Valid: true},
+		Attempts:  2,
+		RetryAt:   sql.NullTime{Time: start.Add(2 * time.Hour), Valid: true},
+		Status:    models.ScheduleStatusActive,
+	}
+
+	s.Advance()
+	assert.Equal(t, start.AddDate(0, 0, 7), s.NextRunAt.Time)
+	assert.Equal(t, int64(0), s.Attempts)
+	assert.False(t, s.RetryAt.Valid)
+
+	s.Advance()
+	assert.Equal(t, models.ScheduleStatusCompleted, s.Status)
+}
+
+func TestSkipMissed_SkipsOccurrencesBeforeNow(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	s := models.ScheduledTransfer{
+		Frequency: models.ScheduleFrequencyDaily,
+		StartAt:   start,
+		NextRunAt: sql.NullTime{Time: start, Valid: true},
+		Status:    models.ScheduleStatusActive,
+	}
+
+	s.SkipMissed(start.AddDate(0, 0, 3).Add(time.Hour))
+
+	assert.Equal(t, int64(4), s.Occurrences)
+	assert.Equal(t, start.AddDate(0, 0, 4), s.NextRunAt.Time)
+}
+
+func TestSkipMissedDue_KeepsLatestDueOccurrence(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	s := models.ScheduledTransfer{
+		Frequency: models.ScheduleFrequencyDaily,
+		StartAt:   start,
+		NextRunAt: sql.NullTime{Time: start, Valid: true},
+		Status:    models.ScheduleStatusActive,
+	}
+
+	skipped := s.SkipMissedDue(start.AddDate(0, 0, 3).Add(time.Hour))
+
+	assert.Equal(t, 3, skipped)
+	assert.Equal(t, int64(3), s.Occurrences)
+	assert.Equal(t, start.AddDate(0, 0, 3), s.NextRunAt.Time)
+	assert.Equal(t, models.ScheduleStatusActive, s.Status)
+}
+
+func TestSkipMissedDue_NothingMissed(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	s := models.ScheduledTransfer{
+		Frequency: models.ScheduleFrequencyDaily,
+		StartAt:   start,
+		NextRunAt: sql.NullTime{Time: start, Valid: true},
+		Status:    models.ScheduleStatusActive,
+	}
+
+	skipped := s.SkipMissedDue(start.Add(23 * time.Hour))
+
+	assert.Equal(t, 0, skipped)
+	assert.Equal(t, start, s.NextRunAt.Time)
+}
+
+func TestSkipMissedDue_KeepsLastOccurrenceBeforeEndAt(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	s := models.ScheduledTransfer{
+		Frequency: models.ScheduleFrequencyDaily,
+		StartAt:   start,
+		EndAt:     sql.NullTime{Time: start.AddDate(0, 0, 1), Valid: true},
+		NextRunAt: sql.NullTime{Time: start, Valid: true},
+		Status:    models.ScheduleStatusActive,
+	}
+
+	skipped := s.SkipMissedDue(start.AddDate(0, 0, 5))
+
+	assert.Equal(t, 1, skipped)
+	assert.Equal(t, start.AddDate(0, 0, 1), s.NextRunAt.Time)
+	assert.Equal(t, models.ScheduleStatusActive, s.Status)
+}
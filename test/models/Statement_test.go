@@ -0,0 +1,84 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rudithu/CRYPTO-WalletApp/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var statementPeriod = time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+
+func TestStartOfMonth(t *testing.T) {
+	assert.Equal(t, statementPeriod, models.StartOfMonth(time.Date(2025, 5, 31, 23, 59, 0, 0, time.UTC)))
+}
+
+func TestStatementAvailableAt(t *testing.T) {
+	// Transactions completing just before midnight get time to commit
+	assert.Equal(t, time.Date(2025, 6, 1, 0, 5, 0, 0, time.UTC), models.StatementAvailableAt(statementPeriod))
+}
+
+func TestBuildStatement(t *testing.T) {
+	wallet := models.Wallet{ID: 1, UserId: 3, Currency: "USD"}
+	withdrawId := int64(11)
+	entries := []models.StatementEntry{
+		{TransactionID: 10, Type: models.TxnTypeDeposit, At: statementPeriod.Add(time.Hour), Amount: decimal.NewFromInt(200)},
+		{TransactionID: 11, Type: models.TxnTypeWithdraw, At: statementPeriod.Add(2 * time.Hour), Amount: decimal.NewFromInt(-50)},
+		{TransactionID: 12, Type: models.TxnTypeFee, At: statementPeriod.Add(2 * time.Hour), Amount: decimal.NewFromInt(-1), FeeFor: &withdrawId},
+		{TransactionID: 10, Type: models.TxnTypeDeposit, At: statementPeriod.AddDate(0, 0, 3), Amount: decimal.NewFromInt(-200), Reversal: true},
+	}
+
+	s := models.BuildStatement(wallet, statementPeriod, decimal.NewFromInt(100), entries, statementPeriod.AddDate(0, 1, 0))
+
+	assert.Equal(t, int64(3), s.UserID)
+	assert.True(t, s.OpeningBalance.Equal(decimal.NewFromInt(100)))
+	assert.True(t, s.ClosingBalance.Equal(decimal.NewFromInt(49)))
+	assert.True(t, s.TotalCredits.Equal(decimal.NewFromInt(200)))
+	assert.True(t, s.TotalDebits.Equal(decimal.NewFromInt(251)))
+	assert.True(t, s.TotalFees.Equal(decimal.NewFromInt(1)))
+
+	require.Len(t, s.Entries, 4)
+	assert.True(t, s.Entries[0].Balance.Equal(decimal.NewFromInt(300)))
+	assert.True(t, s.Entries[2].Balance.Equal(decimal.NewFromInt(249)))
+	assert.True(t, s.Entries[3].Balance.Equal(decimal.NewFromInt(49)))
+	assert.Nil(t, s.Entries[0].FX)
+}
+
+func TestBuildStatement_NoEntries(t *testing.T) {
+	wallet := models.Wallet{ID: 1, UserId: 3, Currency: "USD"}
+
+	s := models.BuildStatement(wallet, statementPeriod, decimal.NewFromInt(100), nil, statementPeriod.AddDate(0, 1, 0))
+
+	assert.True(t, s.ClosingBalance.Equal(decimal.NewFromInt(100)))
+	assert.True(t, s.TotalDebits.IsZero())
+	assert.NotNil(t, s.Entries)
+}
+
+func TestBuildStatement_FXDetails(t *testing.T) {
+	wallet := models.Wallet{ID: 1, UserId: 3, Currency: "USD"}
+	entries := []models.StatementEntry{
+		// 92 EUR received for 100 USD at 0.92 EUR per USD
+		{TransactionID: 20, Type: models.TxnTypeConversionOut, At: statementPeriod.Add(time.Hour), Amount: decimal.NewFromInt(-100),
+			Rate: decimal.NewNullDecimal(decimal.NewFromFloat(0.92)), CounterCcy: "EUR"},
+		// 25 USD received for 20 GBP at 1.25 USD per GBP
+		{TransactionID: 21, Type: models.TxnTypeTransferIn, At: statementPeriod.Add(2 * time.Hour), Amount: decimal.NewFromInt(25),
+			Rate: decimal.NewNullDecimal(decimal.NewFromFloat(1.25)), CounterCcy: "GBP"},
+		// the conversion reversed, the USD coming back
+		{TransactionID: 20, Type: models.TxnTypeConversionOut, At: statementPeriod.Add(3 * time.Hour), Amount: decimal.NewFromInt(100),
+			Reversal: true, Rate: decimal.NewNullDecimal(decimal.NewFromFloat(0.92)), CounterCcy: "EUR"},
+	}
+
+	s := models.BuildStatement(wallet, statementPeriod, decimal.Zero, entries, statementPeriod.AddDate(0, 1, 0))
+
+	require.Len(t, s.Entries, 3)
+	require.NotNil(t, s.Entries[0].FX)
+	assert.Equal(t, "EUR", s.Entries[0].FX.CounterCcy)
+	assert.True(t, s.Entries[0].FX.CounterAmount.Equal(decimal.NewFromInt(92)))
+	require.NotNil(t, s.Entries[1].FX)
+	assert.True(t, s.Entries[1].FX.CounterAmount.Equal(decimal.NewFromInt(20)))
+	require.NotNil(t, s.Entries[2].FX)
+	assert.True(t, s.Entries[2].FX.CounterAmount.Equal(decimal.NewFromInt(92)))
+}
@@ -2,7 +2,11 @@ package testutils
 
 import (
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,9 +18,10 @@ import (
 
 func MockUser() *models.User {
 	return &models.User{
-		ID:        1,
-		Name:      "Alice",
-		CreatedAt: time.Now().AddDate(-1, 0, 0),
+		ID:                1,
+		Name:              "Alice",
+		ReportingCurrency: models.BaseCcy,
+		CreatedAt:         time.Now().AddDate(-1, 0, 0),
 	}
 }
 
@@ -62,11 +67,11 @@ func MockTxns() []models.Transaction {
 	}
 }
 
-func MockCcyMapWithRate() map[string]models.CcyRateToBaseCcy {
-	return map[string]models.CcyRateToBaseCcy{
+func MockCcyMapWithRate() map[string]models.CcyRateToReportingCcy {
+	return map[string]models.CcyRateToReportingCcy{
 		"EUR": {
 			Ccy:  "EUR",
-			Rate: decimal.NewFromFloat(0.5),
+			Rate: decimal.NewFromFloat(2),
 		},
 	}
 }
@@ -75,6 +80,13 @@ func NullInt64(val int64, valid bool) sql.NullInt64 {
 	return sql.NullInt64{Int64: val, Valid: valid}
 }
 
+func DecodeErrorResponse(t *testing.T, rec *httptest.ResponseRecorder) models.ErrorResponse {
+	var errResp models.ErrorResponse
+	err := json.Unmarshal(rec.Body.Bytes(), &errResp)
+	assert.Nil(t, err)
+	return errResp
+}
+
 func WithDBMock(t *testing.T, testFunc func(dbTest *sql.DB, mock sqlmock.Sqlmock)) {
 	dbTest, mock, err := sqlmock.New()
 	assert.Nil(t, err)
@@ -86,9 +98,12 @@ func WithDBMock(t *testing.T, testFunc func(dbTest *sql.DB, mock sqlmock.Sqlmock
 }
 
 func MockGetUserById(mock sqlmock.Sqlmock, user models.User) {
-	mock.ExpectQuery("SELECT id, name, created_at FROM users where id=\\$1").
+	if user.ReportingCurrency == "" {
+		user.ReportingCurrency = models.BaseCcy
+	}
+	mock.ExpectQuery("SELECT id, name, reporting_currency, created_at FROM users where id=\\$1").
 		WithArgs(user.ID).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at"}).AddRow(user.ID, user.Name, user.CreatedAt))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "reporting_currency", "created_at"}).AddRow(user.ID, user.Name, user.ReportingCurrency, user.CreatedAt))
 }
 
 func MockGetWalletByUserIDs(mock sqlmock.Sqlmock, wallets []models.Wallet) {
@@ -103,6 +118,24 @@ func MockGetWalletByUserIDs(mock sqlmock.Sqlmock, wallets []models.Wallet) {
 		WillReturnRows(rows)
 }
 
+// MockGetWalletsByIDs expects the wallets with walletIds to be read in one query and returns wallets.
+func MockGetWalletsByIDs(mock sqlmock.Sqlmock, walletIds []int64, wallets []models.Wallet) {
+	rows := sqlmock.NewRows([]string{"id", "user_id", "balance", "currency", "type", "is_default", "created_at"})
+
+	for _, w := range wallets {
+		rows = rows.AddRow(w.ID, w.UserId, w.Balance, w.Currency, w.Type, w.IsDefault, w.CreatedAt)
+	}
+
+	args := make([]driver.Value, len(walletIds))
+	for i, id := range walletIds {
+		args[i] = id
+	}
+
+	mock.ExpectQuery("SELECT id, user_id, balance, currency, type, is_default, created_at FROM wallets WHERE id IN \\(").
+		WithArgs(args...).
+		WillReturnRows(rows)
+}
+
 func MockGetWalletById(mock sqlmock.Sqlmock, wallet models.Wallet) {
 	mock.ExpectQuery("SELECT id, user_id, balance, currency, type, is_default, created_at FROM wallets WHERE id = \\$1").
 		WithArgs(wallet.ID).
@@ -118,16 +151,34 @@ func MockGetWalletByIdNoRecord(mock sqlmock.Sqlmock, walletId int64) {
 
 func MockCreateTransaction(mock sqlmock.Sqlmock, txn models.Transaction) {
 	mock.ExpectQuery("INSERT INTO transactions").
-		WithArgs(txn.WalletId, txn.Type, txn.Amount, sqlmock.AnyArg()).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(txn.ID, txn.CreatedAt))
+		WithArgs(txn.WalletId, txn.Type, txn.Amount, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "completed_at"}).AddRow(txn.ID, txn.CreatedAt, txn.CreatedAt))
 }
 
 func MockCreateTransactionDBFailed(mock sqlmock.Sqlmock, txn models.Transaction) {
 	mock.ExpectQuery("INSERT INTO transactions").
-		WithArgs(txn.WalletId, txn.Type, txn.Amount, sqlmock.AnyArg()).
+		WithArgs(txn.WalletId, txn.Type, txn.Amount, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnError(errors.New("db failed"))
 }
 
+func MockCreateTransfer(mock sqlmock.Sqlmock, transfer models.Transfer) {
+	mock.ExpectQuery("INSERT INTO transfers").
+		WithArgs(transfer.SourceWalletId, transfer.TargetWalletId, transfer.SourceAmount, sqlmock.AnyArg(), sqlmock.AnyArg(), transfer.RatePath, transfer.Status, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(transfer.ID, transfer.CreatedAt))
+}
+
+// MockReserveIDs expects ids to be taken from the id sequence of table before a bulk insert.
+func MockReserveIDs(mock sqlmock.Sqlmock, table string, ids ...int64) {
+	rows := sqlmock.NewRows([]string{"nextval"})
+	for _, id := range ids {
+		rows = rows.AddRow(id)
+	}
+
+	mock.ExpectQuery("SELECT nextval\\(pg_get_serial_sequence\\(\\$1, 'id'\\)\\) FROM generate_series\\(1, \\$2\\)").
+		WithArgs(table, len(ids)).
+		WillReturnRows(rows)
+}
+
 func MockUpdateBalanceByWalletID(mock sqlmock.Sqlmock, amount decimal.Decimal, walletId int64) {
 	mock.ExpectExec("UPDATE wallets SET balance = \\$1 WHERE id = \\$2").
 		WithArgs(amount, walletId).
@@ -140,14 +191,569 @@ func MockUpdateBalanceByWalletIDDBFailed(mock sqlmock.Sqlmock, amount decimal.De
 		WillReturnError(errors.New("db failed"))
 }
 
-func MockIncrementBalanceByWalletID(mock sqlmock.Sqlmock, amount decimal.Decimal, walletId int64) {
-	mock.ExpectExec("UPDATE wallets SET balance = balance \\+ \\$1 WHERE id = \\$2").
+func MockIncrementBalanceByWalletID(mock sqlmock.Sqlmock, amount decimal.Decimal, walletId int64, newBalance decimal.Decimal) {
+	mock.ExpectQuery("UPDATE wallets SET balance = balance \\+ \\$1 WHERE id = \\$2 RETURNING balance").
 		WithArgs(amount, walletId).
-		WillReturnResult(sqlmock.NewResult(0, 1))
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(newBalance))
 }
 
 func MockGetBalance(mock sqlmock.Sqlmock, amount decimal.Decimal, walletId int64) {
-	mock.ExpectQuery("SELECT balance FROM wallets WHERE id = \\$1").
+	mock.ExpectQuery("SELECT balance, .+ FROM wallets WHERE id = \\$1 FOR UPDATE").
+		WithArgs(walletId).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "available_balance"}).AddRow(amount, amount))
+}
+
+// MockGetWalletBalance expects the ledger and the available balance of the wallet to be read without locking it.
+func MockGetWalletBalance(mock sqlmock.Sqlmock, balance decimal.Decimal, available decimal.Decimal, walletId int64) {
+	mock.ExpectQuery("SELECT balance, .+ FROM wallets WHERE id = \\$1").
 		WithArgs(walletId).
-		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(amount))
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "available_balance"}).AddRow(balance, available))
+}
+
+// MockGetHeldBalances expects the query for the amounts reserved by holds on wallets.
+// held is keyed by wallet ID; wallets without an entry have no active hold.
+func MockGetHeldBalances(mock sqlmock.Sqlmock, wallets []models.Wallet, held map[int64]decimal.Decimal) {
+	rows := sqlmock.NewRows([]string{"wallet_id", "sum"})
+	args := make([]driver.Value, len(wallets))
+
+	for i, w := range wallets {
+		args[i] = w.ID
+		if amount, ok := held[w.ID]; ok {
+			rows = rows.AddRow(w.ID, amount)
+		}
+	}
+
+	mock.ExpectQuery("SELECT h.wallet_id, SUM\\(h.amount\\) FROM holds h").
+		WithArgs(args...).
+		WillReturnRows(rows)
+}
+
+// MockGetAvailableBalances expects the query for the available balance of wallets.
+// available is keyed by wallet ID; wallets without an entry have their ledger balance available.
+func MockGetAvailableBalances(mock sqlmock.Sqlmock, wallets []models.Wallet, available map[int64]decimal.Decimal) {
+	rows := sqlmock.NewRows([]string{"id", "available"})
+	args := make([]driver.Value, len(wallets))
+
+	for i, w := range wallets {
+		args[i] = w.ID
+		amount, ok := available[w.ID]
+		if !ok {
+			amount = w.Balance
+		}
+		rows = rows.AddRow(w.ID, amount)
+	}
+
+	mock.ExpectQuery("SELECT id, balance .+ FROM wallets WHERE id IN").
+		WithArgs(args...).
+		WillReturnRows(rows)
+}
+
+// MockGetPendingBalances expects the query for the pending deposit and withdrawal totals of wallets.
+// pending is keyed by wallet ID; wallets without an entry have no pending transaction.
+func MockGetPendingBalances(mock sqlmock.Sqlmock, wallets []models.Wallet, pending map[int64]models.PendingBalance) {
+	rows := sqlmock.NewRows([]string{"wallet_id", "incoming", "outgoing"})
+	args := make([]driver.Value, len(wallets))
+
+	for i, w := range wallets {
+		args[i] = w.ID
+		if p, ok := pending[w.ID]; ok {
+			rows = rows.AddRow(w.ID, p.Incoming, p.Outgoing)
+		}
+	}
+
+	mock.ExpectQuery("SELECT wallet_id, .+ FROM transactions WHERE wallet_id IN .+ AND status = 'pending' GROUP BY wallet_id").
+		WithArgs(args...).
+		WillReturnRows(rows)
+}
+
+// TxnSelectQuery matches the select list used by the db package to read transactions.
+const TxnSelectQuery = "SELECT id, wallet_id, type, amount, counterparty_wallet_id, created_at, description, external_reference, transfer_id, status, completed_at, failed_at, reversed_at, .+ FROM transactions "
+
+// TxnRows returns empty result rows with the columns read by the db package for transactions.
+func TxnRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "wallet_id", "type", "amount", "counterparty_wallet_id", "created_at", "description", "external_reference", "transfer_id",
+		"status", "completed_at", "failed_at", "reversed_at", "rate", "tags"})
+}
+
+// AddTxnRow appends txn to rows created by TxnRows.
+func AddTxnRow(rows *sqlmock.Rows, txn models.Transaction) *sqlmock.Rows {
+	var tags interface{}
+	if len(txn.Tags) > 0 {
+		tags = strings.Join(txn.Tags, ",")
+	}
+	status := txn.Status
+	if status == "" {
+		status = models.TxnStatusCompleted
+	}
+	return rows.AddRow(txn.ID, txn.WalletId, txn.Type, txn.Amount, txn.CounterpartyWalletId, txn.CreatedAt,
+		txn.Description, txn.ExternalReference, txn.TransferId, status, txn.CompletedAt, txn.FailedAt, txn.ReversedAt, txn.Rate, tags)
+}
+
+func MockGetTransactionById(mock sqlmock.Sqlmock, txn models.Transaction) {
+	mock.ExpectQuery(TxnSelectQuery + "WHERE id = \\$1").
+		WithArgs(txn.ID).
+		WillReturnRows(AddTxnRow(TxnRows(), txn))
+}
+
+func MockGetTransactionByIdNoRecord(mock sqlmock.Sqlmock, txnId int64) {
+	mock.ExpectQuery(TxnSelectQuery + "WHERE id = \\$1").
+		WithArgs(txnId).
+		WillReturnRows(TxnRows())
+}
+
+func MockGetTransferCounterparties(mock sqlmock.Sqlmock, cps []models.TransferCounterparty) {
+	rows := sqlmock.NewRows([]string{"id", "linked_id", "linked_amount", "wallet_id", "currency", "user_id", "name", "transfer_status", "reversal_of"})
+	args := make([]driver.Value, len(cps))
+
+	for i, cp := range cps {
+		rows = rows.AddRow(cp.TransactionID, cp.LinkedTransactionID, cp.LinkedAmount, cp.WalletID, cp.Currency, cp.UserID, cp.UserName, cp.TransferStatus, cp.ReversalOf)
+		args[i] = cp.TransactionID
+	}
+
+	mock.ExpectQuery("SELECT t.id, o.id, o.amount, w.id, w.currency, u.id, u.name, tr.status, tr.reversal_of FROM transactions t").
+		WithArgs(args...).
+		WillReturnRows(rows)
+}
+
+// TransferSelectQuery matches the select list used by the db package to read transfers.
+const TransferSelectQuery = "SELECT t.id, t.source_wallet_id, t.target_wallet_id, t.source_amount, t.target_amount, t.rate, t.rate_path, t.status, t.created_at, .+ FROM transfers t "
+
+// TransferRows returns empty result rows with the columns read by the db package for transfers.
+func TransferRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "source_wallet_id", "target_wallet_id", "source_amount", "target_amount", "rate", "rate_path", "status", "created_at",
+		"reversal_of", "reversed_amount", "source_currency", "target_currency", "source_transaction_id", "target_transaction_id"})
+}
+
+// AddTransferRow appends transfer to rows created by TransferRows.
+func AddTransferRow(rows *sqlmock.Rows, transfer models.Transfer) *sqlmock.Rows {
+	return rows.AddRow(transfer.ID, transfer.SourceWalletId, transfer.TargetWalletId, transfer.SourceAmount, transfer.TargetAmount,
+		transfer.Rate, transfer.RatePath, transfer.Status, transfer.CreatedAt, transfer.ReversalOf, transfer.ReversedAmount, transfer.SourceCurrency, transfer.TargetCurrency,
+		transfer.SourceTransactionId, transfer.TargetTransactionId)
+}
+
+func MockTransfer() models.Transfer {
+	return models.Transfer{
+		ID:                  int64(31),
+		SourceWalletId:      int64(201),
+		TargetWalletId:      int64(210),
+		SourceAmount:        decimal.NewFromFloat(135),
+		TargetAmount:        decimal.NewFromFloat(100),
+		Rate:                decimal.RequireFromString("0.7407407407"),
+		RatePath:            "SGD>USD",
+		Status:              models.TransferStatusCompleted,
+		CreatedAt:           time.Now(),
+		SourceCurrency:      "SGD",
+		TargetCurrency:      "USD",
+		SourceTransactionId: NullInt64(401, true),
+		TargetTransactionId: NullInt64(402, true),
+	}
+}
+
+func MockGetTransferById(mock sqlmock.Sqlmock, transfer models.Transfer) {
+	mock.ExpectQuery(TransferSelectQuery + ".+ WHERE t.id = \\$1").
+		WithArgs(transfer.ID).
+		WillReturnRows(AddTransferRow(TransferRows(), transfer))
+}
+
+func MockGetTransferByIdNoRecord(mock sqlmock.Sqlmock, transferId int64) {
+	mock.ExpectQuery(TransferSelectQuery + ".+ WHERE t.id = \\$1").
+		WithArgs(transferId).
+		WillReturnRows(TransferRows())
+}
+
+// HoldSelectQuery matches the start of the SELECT issued by the db package for holds.
+const HoldSelectQuery = "SELECT id, wallet_id, amount, captured_amount, status, description, transaction_id, expires_at, created_at, updated_at"
+
+// HoldRows returns empty result rows with the columns read by the db package for holds.
+func HoldRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "wallet_id", "amount", "captured_amount", "status", "description", "transaction_id", "expires_at", "created_at", "updated_at"})
+}
+
+// AddHoldRow appends hold to rows created by HoldRows.
+func AddHoldRow(rows *sqlmock.Rows, hold models.Hold) *sqlmock.Rows {
+	return rows.AddRow(hold.ID, hold.WalletId, hold.Amount, hold.CapturedAmount, hold.Status, hold.Description, hold.TransactionId,
+		hold.ExpiresAt, hold.CreatedAt, hold.UpdatedAt)
+}
+
+func MockHold() models.Hold {
+	now := time.Now()
+	return models.Hold{
+		ID:             int64(51),
+		WalletId:       int64(1),
+		Amount:         decimal.NewFromFloat(40),
+		CapturedAmount: decimal.Zero,
+		Status:         models.HoldStatusActive,
+		ExpiresAt:      sql.NullTime{Time: now.Add(time.Hour), Valid: true},
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+}
+
+func MockGetHoldById(mock sqlmock.Sqlmock, hold models.Hold) {
+	mock.ExpectQuery(HoldSelectQuery + " FROM holds WHERE id = \\$1").
+		WithArgs(hold.ID).
+		WillReturnRows(AddHoldRow(HoldRows(), hold))
+}
+
+func MockGetHoldByIdNoRecord(mock sqlmock.Sqlmock, holdId int64) {
+	mock.ExpectQuery(HoldSelectQuery + " FROM holds WHERE id = \\$1").
+		WithArgs(holdId).
+		WillReturnRows(HoldRows())
+}
+
+// MockLockActiveHold expects the locking read of hold issued before it is captured or released.
+func MockLockActiveHold(mock sqlmock.Sqlmock, hold models.Hold, expired bool) {
+	mock.ExpectQuery(HoldSelectQuery + ", .+ FROM holds WHERE id = \\$1 FOR UPDATE").
+		WithArgs(hold.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "wallet_id", "amount", "captured_amount", "status", "description", "transaction_id",
+			"expires_at", "created_at", "updated_at", "expired"}).AddRow(hold.ID, hold.WalletId, hold.Amount, hold.CapturedAmount, hold.Status, hold.Description,
+			hold.TransactionId, hold.ExpiresAt, hold.CreatedAt, hold.UpdatedAt, expired))
+}
+
+// ScheduledTransferSelectQuery matches the start of the SELECT issued by the db package for scheduled transfers.
+const ScheduledTransferSelectQuery = "SELECT st.id, st.source_wallet_id, st.destination_wallet_id, st.destination_user_id, st.amount"
+
+// ScheduledTransferRows returns empty result rows with the columns read by the db package for scheduled transfers.
+func ScheduledTransferRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "source_wallet_id", "destination_wallet_id", "destination_user_id", "amount", "description",
+		"external_reference", "frequency", "start_at", "end_at", "max_occurrences", "occurrences", "next_run_at", "retry_at", "attempts",
+		"on_insufficient_funds", "status", "created_at", "updated_at"})
+}
+
+// AddScheduledTransferRow appends s to rows created by ScheduledTransferRows.
+func AddScheduledTransferRow(rows *sqlmock.Rows, s models.ScheduledTransfer) *sqlmock.Rows {
+	return rows.AddRow(s.ID, s.SourceWalletId, s.DestinationWalletId, s.DestinationUserId, s.Amount, s.Description,
+		s.ExternalReference, s.Frequency, s.StartAt, s.EndAt, s.MaxOccurrences, s.Occurrences, s.NextRunAt, s.RetryAt, s.Attempts,
+		s.OnInsufficientFunds, s.Status, s.CreatedAt, s.UpdatedAt)
+}
+
+// MockScheduledTransfer returns an active monthly transfer of 25 from wallet 1 to wallet 2, due now.
+func MockScheduledTransfer() models.ScheduledTransfer {
+	now := time.Now().UTC().Truncate(time.Second)
+	return models.ScheduledTransfer{
+		ID:                  int64(61),
+		SourceWalletId:      int64(1),
+		DestinationWalletId: sql.NullInt64{Int64: 2, Valid: true},
+		Amount:              decimal.NewFromFloat(25),
+		Frequency:           models.ScheduleFrequencyMonthly,
+		StartAt:             now,
+		NextRunAt:           sql.NullTime{Time: now, Valid: true},
+		OnInsufficientFunds: models.InsufficientFundsRetry,
+		Status:              models.ScheduleStatusActive,
+		CreatedAt:           now,
+		UpdatedAt:           now,
+	}
+}
+
+func MockGetScheduledTransferById(mock sqlmock.Sqlmock, s models.ScheduledTransfer) {
+	mock.ExpectQuery(ScheduledTransferSelectQuery + ".+ FROM scheduled_transfers st WHERE st.id = \\$1$").
+		WithArgs(s.ID).
+		WillReturnRows(AddScheduledTransferRow(ScheduledTransferRows(), s))
+}
+
+func MockGetScheduledTransferByIdNoRecord(mock sqlmock.Sqlmock, scheduleId int64) {
+	mock.ExpectQuery(ScheduledTransferSelectQuery + ".+ FROM scheduled_transfers st WHERE st.id = \\$1$").
+		WithArgs(scheduleId).
+		WillReturnRows(ScheduledTransferRows())
+}
+
+// MockLockScheduledTransfer expects the locking read of s issued before it is run or changed.
+func MockLockScheduledTransfer(mock sqlmock.Sqlmock, s models.ScheduledTransfer) {
+	mock.ExpectQuery(ScheduledTransferSelectQuery + ".+ FROM scheduled_transfers st WHERE st.id = \\$1 FOR UPDATE").
+		WithArgs(s.ID).
+		WillReturnRows(AddScheduledTransferRow(ScheduledTransferRows(), s))
+}
+
+// MockSaveScheduledTransfer expects s to be saved with the given status, occurrences and attempts.
+func MockSaveScheduledTransfer(mock sqlmock.Sqlmock, s models.ScheduledTransfer, status string, occurrences int64, attempts int64) {
+	mock.ExpectQuery("UPDATE scheduled_transfers SET").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), occurrences,
+			sqlmock.AnyArg(), sqlmock.AnyArg(), attempts, sqlmock.AnyArg(), status, s.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+}
+
+// MockCreateScheduledTransferRun expects a run of s to be recorded with the given attempt and status.
+func MockCreateScheduledTransferRun(mock sqlmock.Sqlmock, s models.ScheduledTransfer, attempt int64, status string) {
+	mock.ExpectQuery("INSERT INTO scheduled_transfer_runs").
+		WithArgs(s.ID, s.NextRunAt.Time, attempt, status, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(int64(71), time.Now()))
+}
+
+// FeeRuleRows returns the columns of a fee rule query.
+func FeeRuleRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "fee_type", "from_ccy", "to_ccy", "wallet_type", "min_amount", "flat_fee", "percentage", "min_fee", "max_fee"})
+}
+
+// MockFeeRule returns a withdraw fee of 1 plus 1%, at least 2 and at most 5.
+func MockFeeRule() models.FeeRule {
+	return models.FeeRule{
+		ID:         71,
+		FeeType:    models.FeeTypeWithdraw,
+		FlatFee:    decimal.NewFromInt(1),
+		Percentage: decimal.NewFromInt(1),
+		MinFee:     decimal.NullDecimal{Decimal: decimal.NewFromInt(2), Valid: true},
+		MaxFee:     decimal.NullDecimal{Decimal: decimal.NewFromInt(5), Valid: true},
+	}
+}
+
+// MockGetFeeRules expects the rules of the fee schedule for feeType to be read and returns rules.
+func MockGetFeeRules(mock sqlmock.Sqlmock, feeType string, rules ...models.FeeRule) {
+	rows := FeeRuleRows()
+	for _, r := range rules {
+		rows = rows.AddRow(r.ID, r.FeeType, r.FromCcy, r.ToCcy, r.WalletType, r.MinAmount, r.FlatFee, r.Percentage, r.MinFee, r.MaxFee)
+	}
+
+	mock.ExpectQuery("SELECT id, fee_type, .+ FROM fee_rules WHERE fee_type = \\$1").
+		WithArgs(feeType).
+		WillReturnRows(rows)
+}
+
+// MockGetPendingFeeNoRecord expects the fee reserved by the pending transaction with txnId to be locked, and finds none.
+func MockGetPendingFeeNoRecord(mock sqlmock.Sqlmock, txnId int64) {
+	mock.ExpectQuery("SELECT id, transaction_id, .+ FROM fees WHERE transaction_id = \\$1 AND debit_transaction_id IS NULL FOR UPDATE").
+		WithArgs(txnId).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "transaction_id", "fee_rule_id", "amount", "flat_fee", "percentage_fee", "house_wallet_id", "created_at"}))
+}
+
+// MockHouseWallet returns the house revenue wallet collecting fees in USD.
+func MockHouseWallet() models.Wallet {
+	return models.Wallet{ID: 90, UserId: models.DefaultHouseUserId, Balance: decimal.Zero, Currency: "USD", Type: "house", IsDefault: true, CreatedAt: time.Now()}
+}
+
+// MockCcyConversions returns the rates from the base currency to SGD at 1.35 and to EUR at 0.92,
+// as in the sample data, set a minute ago.
+func MockCcyConversions() []models.CcyConversion {
+	asOf := time.Now().Add(-time.Minute)
+	return []models.CcyConversion{
+		{FromCcy: models.BaseCcy, ToCcy: "EUR", Rate: decimal.NewFromFloat(0.92), CreatedAt: asOf, Age: time.Minute},
+		{FromCcy: models.BaseCcy, ToCcy: "SGD", Rate: decimal.NewFromFloat(1.35), CreatedAt: asOf, Age: time.Minute},
+	}
+}
+
+// MockGetCcyConversions expects the stored conversion rates to be read, returning conversions.
+func MockGetCcyConversions(mock sqlmock.Sqlmock, conversions ...models.CcyConversion) {
+	rows := sqlmock.NewRows([]string{"from_ccy", "to_ccy", "rate", "created_at", "age_seconds"})
+	for _, c := range conversions {
+		rows = rows.AddRow(c.FromCcy, c.ToCcy, c.Rate, c.CreatedAt, int64(c.Age/time.Second))
+	}
+
+	mock.ExpectQuery("SELECT from_ccy, to_ccy, rate, created_at, FLOOR\\(EXTRACT\\(EPOCH FROM CURRENT_TIMESTAMP - created_at\\)\\)::BIGINT FROM ccy_conversion ORDER BY from_ccy, to_ccy").
+		WillReturnRows(rows)
+}
+
+// MockFxSpreadRule returns a spread rule of 0.5% on both sides for any currency pair and tier.
+func MockFxSpreadRule() models.FxSpreadRule {
+	return models.FxSpreadRule{ID: 91, BidSpread: decimal.NewFromFloat(0.5), AskSpread: decimal.NewFromFloat(0.5)}
+}
+
+// MockGetFxSpreadRules expects the spread rules for the tier of the user with userId to be read.
+func MockGetFxSpreadRules(mock sqlmock.Sqlmock, userId int64, rules ...models.FxSpreadRule) {
+	rows := sqlmock.NewRows([]string{"id", "base_ccy", "quote_ccy", "tier", "bid_spread", "ask_spread"})
+	for _, r := range rules {
+		rows = rows.AddRow(r.ID, r.BaseCcy, r.QuoteCcy, r.Tier, r.BidSpread, r.AskSpread)
+	}
+
+	mock.ExpectQuery("SELECT id, base_ccy, .+ FROM fx_spreads WHERE tier IS NULL OR tier = COALESCE\\(\\(SELECT tier FROM users WHERE id = \\$1\\), \\$2\\)").
+		WithArgs(userId, models.DefaultFxTier).
+		WillReturnRows(rows)
+}
+
+// MockCreateSpreadRevenue expects the spread revenue of the transaction with txnId to be recorded.
+func MockCreateSpreadRevenue(mock sqlmock.Sqlmock, txnId int64, revenue models.SpreadRevenue) {
+	mock.ExpectQuery("INSERT INTO fx_spread_revenue").
+		WithArgs(txnId, revenue.Currency, revenue.MidRate, revenue.Rate, revenue.Amount).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(int64(95), time.Now()))
+}
+
+// FxQuoteSelectQuery matches the start of the SELECT issued by the db package for FX quotes.
+const FxQuoteSelectQuery = "SELECT id, user_id, from_ccy, to_ccy, rate, mid_rate, side, spread, rate_path, status, transfer_id, expires_at, created_at, used_at, expires_at <= CURRENT_TIMESTAMP"
+
+// FxQuoteRows returns empty result rows with the columns read by the db package for FX quotes.
+func FxQuoteRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "user_id", "from_ccy", "to_ccy", "rate", "mid_rate", "side", "spread", "rate_path", "status", "transfer_id", "expires_at", "created_at", "used_at", "expired"})
+}
+
+// AddFxQuoteRow appends quote to rows created by FxQuoteRows. expired tells whether the quote is past its expiry.
+func AddFxQuoteRow(rows *sqlmock.Rows, quote models.FxQuote, expired bool) *sqlmock.Rows {
+	return rows.AddRow(quote.ID, quote.UserId, quote.FromCcy, quote.ToCcy, quote.Rate, quote.MidRate, quote.Side, quote.Spread, quote.RatePath, quote.Status, quote.TransferId, quote.ExpiresAt, quote.CreatedAt, quote.UsedAt, expired)
+}
+
+// MockFxQuote returns an open quote of user 101 from SGD to USD at 0.75, the mid rate 0.76 less the bid spread.
+func MockFxQuote() models.FxQuote {
+	now := time.Now()
+	return models.FxQuote{
+		ID:        int64(81),
+		UserId:    int64(101),
+		FromCcy:   "SGD",
+		ToCcy:     "USD",
+		Rate:      decimal.NewFromFloat(0.75),
+		MidRate:   decimal.NewFromFloat(0.76),
+		Side:      models.FxSideBid,
+		Spread:    decimal.NewFromFloat(1.3158),
+		RatePath:  "SGD>USD",
+		Status:    models.FxQuoteStatusOpen,
+		ExpiresAt: now.Add(time.Minute),
+		CreatedAt: now,
+	}
+}
+
+func MockGetFxQuoteById(mock sqlmock.Sqlmock, quote models.FxQuote, expired bool) {
+	mock.ExpectQuery(FxQuoteSelectQuery + " FROM fx_quotes WHERE id = \\$1").
+		WithArgs(quote.ID).
+		WillReturnRows(AddFxQuoteRow(FxQuoteRows(), quote, expired))
+}
+
+func MockGetFxQuoteByIdNoRecord(mock sqlmock.Sqlmock, quoteId int64) {
+	mock.ExpectQuery(FxQuoteSelectQuery + " FROM fx_quotes WHERE id = \\$1").
+		WithArgs(quoteId).
+		WillReturnRows(FxQuoteRows())
+}
+
+// MockUseFxQuote expects the quote to be locked and marked as used by the transfer with transferId.
+func MockUseFxQuote(mock sqlmock.Sqlmock, quote models.FxQuote, transferId int64) {
+	mock.ExpectQuery(FxQuoteSelectQuery + " FROM fx_quotes WHERE id = \\$1 FOR UPDATE").
+		WithArgs(quote.ID).
+		WillReturnRows(AddFxQuoteRow(FxQuoteRows(), quote, false))
+	mock.ExpectExec("UPDATE fx_quotes SET status = \\$1, transfer_id = \\$2, used_at = CURRENT_TIMESTAMP WHERE id = \\$3").
+		WithArgs(models.FxQuoteStatusUsed, transferId, quote.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+// MockConversion returns a conversion of USD 100 from wallet 101 to EUR in wallet 102 of user 1,
+// with its conversion-out transaction 11 and conversion-in transaction 12.
+func MockConversion() models.Conversion {
+	c := models.Conversion{
+		ID:               int64(61),
+		UserId:           int64(1),
+		FromWalletId:     int64(101),
+		ToWalletId:       int64(102),
+		FromCurrency:     "USD",
+		ToCurrency:       "EUR",
+		OutTransactionId: int64(11),
+		InTransactionId:  int64(12),
+		CreatedAt:        time.Now(),
+	}
+	c.ApplyPrice(models.PriceFx([]models.FxSpreadRule{MockFxSpreadRule()}, c.FromCurrency, c.ToCurrency, decimal.NewFromFloat(0.92)))
+	c.Sell(decimal.NewFromInt(100))
+	return c
+}
+
+// MockConversionTxns returns both transactions of conversion.
+func MockConversionTxns(c models.Conversion) []models.Transaction {
+	return []models.Transaction{
+		{ID: c.OutTransactionId, WalletId: c.FromWalletId, Type: models.TxnTypeConversionOut, Amount: c.FromAmount,
+			CounterpartyWalletId: NullInt64(c.ToWalletId, true), Status: models.TxnStatusCompleted, CreatedAt: c.CreatedAt},
+		{ID: c.InTransactionId, WalletId: c.ToWalletId, Type: models.TxnTypeConversionIn, Amount: c.ToAmount,
+			CounterpartyWalletId: NullInt64(c.FromWalletId, true), Status: models.TxnStatusCompleted, CreatedAt: c.CreatedAt},
+	}
+}
+
+// MockGetWalletBalanceAt expects the balance of the wallet at the given time to be rebuilt from its snapshots and transactions.
+func MockGetWalletBalanceAt(mock sqlmock.Sqlmock, walletId int64, at time.Time, balance decimal.Decimal) {
+	mock.ExpectQuery("SELECT w.id AS wallet_id, .+ FROM wallets w LEFT JOIN LATERAL .+ WHERE w.id = \\$2").
+		WithArgs(at, walletId).
+		WillReturnRows(sqlmock.NewRows([]string{"wallet_id", "balance"}).AddRow(walletId, balance))
+}
+
+// MockGetBalanceEvents expects the balance events of the wallet from from up to until.
+func MockGetBalanceEvents(mock sqlmock.Sqlmock, walletId int64, from time.Time, until time.Time, events ...models.BalanceEvent) {
+	rows := sqlmock.NewRows([]string{"at", "delta"})
+	for _, e := range events {
+		rows.AddRow(e.At, e.Delta)
+	}
+	mock.ExpectQuery("SELECT e.at, e.delta FROM \\(.+\\) e WHERE e.wallet_id = \\$1 AND e.at >= \\$2 AND e.at < \\$3 ORDER BY e.at").
+		WithArgs(walletId, from, until).
+		WillReturnRows(rows)
+}
+
+// MockGetCcyRateHistory expects the rate history from from up to until to be read, returning history.
+func MockGetCcyRateHistory(mock sqlmock.Sqlmock, from time.Time, until time.Time, history ...models.CcyConversion) {
+	rows := sqlmock.NewRows([]string{"from_ccy", "to_ccy", "rate", "created_at"})
+	for _, c := range history {
+		rows = rows.AddRow(c.FromCcy, c.ToCcy, c.Rate, c.CreatedAt)
+	}
+
+	mock.ExpectQuery("SELECT h.from_ccy, h.to_ccy, h.rate, h.created_at FROM ccy_conversion_history h").
+		WithArgs(from, until).
+		WillReturnRows(rows)
+}
+
+// MockGetCostBasisTransactions expects the completed transactions of the wallet to be read for its cost basis.
+func MockGetCostBasisTransactions(mock sqlmock.Sqlmock, walletId int64, txns ...models.CostBasisTxn) {
+	rows := sqlmock.NewRows([]string{"id", "type", "amount", "rate", "counter_ccy", "at"})
+	for _, t := range txns {
+		rows = rows.AddRow(t.ID, t.Type, t.Amount, t.Rate, t.CounterCcy, t.At)
+	}
+
+	mock.ExpectQuery("SELECT t.id, t.type, t.amount, t.rate, COALESCE\\(cw.currency, ''\\), .+ FROM transactions t LEFT JOIN wallets cw .+ WHERE t.wallet_id = \\$1 AND t.status = 'completed'").
+		WithArgs(walletId).
+		WillReturnRows(rows)
+}
+
+// MockGetStatementEntries expects the statement entries of the wallet from from up to until.
+func MockGetStatementEntries(mock sqlmock.Sqlmock, walletId int64, from time.Time, until time.Time, entries ...models.StatementEntry) {
+	rows := sqlmock.NewRows([]string{"id", "type", "at", "delta", "reversal", "description", "external_reference",
+		"counterparty_wallet_id", "counter_ccy", "rate", "fee_for"})
+	for _, e := range entries {
+		var counterpartyWalletId, feeFor sql.NullInt64
+		if e.CounterpartyWalletID != nil {
+			counterpartyWalletId = NullInt64(*e.CounterpartyWalletID, true)
+		}
+		if e.FeeFor != nil {
+			feeFor = NullInt64(*e.FeeFor, true)
+		}
+		rows = rows.AddRow(e.TransactionID, e.Type, e.At, e.Amount, e.Reversal, e.Description, e.ExternalReference,
+			counterpartyWalletId, e.CounterCcy, e.Rate, feeFor)
+	}
+
+	mock.ExpectQuery("SELECT e.id, t.type, e.at, e.delta, e.reversal, .+ FROM \\(.+\\) e JOIN transactions t .+ WHERE e.wallet_id = \\$1 AND e.at >= \\$2 AND e.at < \\$3").
+		WithArgs(walletId, from, until).
+		WillReturnRows(rows)
+}
+
+// MockGetStatement expects the stored statement of the wallet for period to be read, returning statement when not nil.
+func MockGetStatement(mock sqlmock.Sqlmock, walletId int64, period time.Time, statement *models.Statement) {
+	rows := sqlmock.NewRows([]string{"statement"})
+	if statement != nil {
+		content, _ := json.Marshal(statement)
+		rows = rows.AddRow(content)
+	}
+
+	mock.ExpectQuery("SELECT statement FROM wallet_statements WHERE wallet_id = \\$1 AND period = \\$2").
+		WithArgs(walletId, period).
+		WillReturnRows(rows)
+}
+
+// MockSaveStatement expects a statement of the wallet for period to be stored, stored telling whether none was already.
+func MockSaveStatement(mock sqlmock.Sqlmock, walletId int64, period time.Time, stored bool) {
+	var affected int64
+	if stored {
+		affected = 1
+	}
+
+	mock.ExpectExec("INSERT INTO wallet_statements .+ ON CONFLICT \\(wallet_id, period\\) DO NOTHING").
+		WithArgs(walletId, period, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, affected))
+}
+
+func MockGetPaymentFileProcessedAt(mock sqlmock.Sqlmock, walletId int64, msgId string, processedAt *time.Time) {
+	rows := sqlmock.NewRows([]string{"processed_at"})
+	if processedAt != nil {
+		rows.AddRow(*processedAt)
+	}
+
+	mock.ExpectQuery("SELECT processed_at FROM payment_files WHERE wallet_id = \\$1 AND msg_id = \\$2").
+		WithArgs(walletId, msgId).
+		WillReturnRows(rows)
+}
+
+func MockRecordPaymentFile(mock sqlmock.Sqlmock, walletId int64, msgId string, recorded bool) {
+	rows := sqlmock.NewRows([]string{"processed_at"})
+	if recorded {
+		rows.AddRow(time.Now())
+	}
+
+	mock.ExpectQuery("INSERT INTO payment_files .+ ON CONFLICT \\(wallet_id, msg_id\\) DO NOTHING").
+		WithArgs(walletId, msgId).
+		WillReturnRows(rows)
 }